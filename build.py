@@ -550,13 +550,15 @@ def build(version=None,
         else:
             # Starting with Go 1.5, the linker flag arguments changed to 'name=value' from 'name value'
             if static:
-                build_command += "-ldflags=\"-s -X main.version={} -X main.branch={} -X main.commit={}\" ".format(version,
-                                                                                                                  get_current_branch(),
-                                                                                                                  get_current_commit())
+                build_command += "-ldflags=\"-s -X main.version={} -X main.branch={} -X main.commit={} -X main.buildTime={}\" ".format(version,
+                                                                                                                                      get_current_branch(),
+                                                                                                                                      get_current_commit(),
+                                                                                                                                      datetime.utcnow().strftime("%Y-%m-%dT%H:%M:%SZ"))
             else:
-                build_command += "-ldflags=\"-X main.version={} -X main.branch={} -X main.commit={}\" ".format(version,
-                                                                                                               get_current_branch(),
-                                                                                                               get_current_commit())
+                build_command += "-ldflags=\"-X main.version={} -X main.branch={} -X main.commit={} -X main.buildTime={}\" ".format(version,
+                                                                                                                                   get_current_branch(),
+                                                                                                                                   get_current_commit(),
+                                                                                                                                   datetime.utcnow().strftime("%Y-%m-%dT%H:%M:%SZ"))
         if static:
             build_command += "-a -installsuffix cgo "
         build_command += path
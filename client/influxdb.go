@@ -69,7 +69,8 @@ func ParseConnectionString(path string, ssl bool) (url.URL, error) {
 		if path == "" {
 			host = DefaultHost
 		} else {
-			host = path
+			// An IPv6 address without a port may be in brackets.
+			host = strings.TrimSuffix(strings.TrimPrefix(path, "["), "]")
 		}
 		// If they didn't specify a port, always use the default port
 		port = DefaultPort
@@ -757,6 +757,16 @@ func TestClient_ParseConnectionString_IPv6(t *testing.T) {
 	}
 }
 
+func TestClient_ParseConnectionString_IPv6NoPort(t *testing.T) {
+	u, err := client.ParseConnectionString("[::1]", false)
+	if err != nil {
+		t.Fatalf("unexpected error, expected %v, actual %v", nil, err)
+	}
+	if exp := "[::1]:8086"; u.Host != exp {
+		t.Fatalf("ipv6 parse failed, expected %s, actual %s", exp, u.Host)
+	}
+}
+
 func TestClient_CustomCertificates(t *testing.T) {
 	// generated with:
 	// openssl req -x509 -newkey rsa:2048 -keyout key.pem -out cert.pem -days 3650 -nodes -config influx.cnf
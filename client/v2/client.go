@@ -3,6 +3,7 @@ package client // import "github.com/influxdata/influxdb/client/v2"
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
@@ -10,6 +11,7 @@ import (
 	"io"
 	"io/ioutil"
 	"mime"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -44,6 +46,20 @@ type HTTPConfig struct {
 	// TLSConfig allows the user to set their own TLS config for the HTTP
 	// Client. If set, this option overrides InsecureSkipVerify.
 	TLSConfig *tls.Config
+
+	// MaxIdleConns is the number of idle connections kept open to the
+	// server, defaults to http.DefaultMaxIdleConnsPerHost. Clients writing
+	// concurrently should keep as many connections as they have writers, so
+	// connections are reused instead of opened for each write.
+	MaxIdleConns int
+
+	// IdleConnTimeout is how long an idle connection is kept open, defaults
+	// to no limit.
+	IdleConnTimeout time.Duration
+
+	// DialContext opens the connections to the server, defaults to
+	// net.Dialer.DialContext.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
 }
 
 // BatchPointsConfig is the config data needed to create an instance of the BatchPoints struct.
@@ -98,6 +114,9 @@ func NewHTTPClient(conf HTTPConfig) (Client, error) {
 		TLSClientConfig: &tls.Config{
 			InsecureSkipVerify: conf.InsecureSkipVerify,
 		},
+		MaxIdleConnsPerHost: conf.MaxIdleConns,
+		IdleConnTimeout:     conf.IdleConnTimeout,
+		DialContext:         conf.DialContext,
 	}
 	if conf.TLSConfig != nil {
 		tr.TLSClientConfig = conf.TLSConfig
@@ -222,7 +222,7 @@ func (c *CommandLine) mainLoop() error {
 			c.exit()
 			return nil
 		default:
-			l, e := c.Line.Prompt("> ")
+			l, e := readCommand(c.Line.Prompt)
 			if e == io.EOF {
 				// Instead of die, register that someone exited the program gracefully
 				l = "exit"
@@ -239,6 +239,18 @@ func (c *CommandLine) mainLoop() error {
 	}
 }
 
+// readCommand reads a command with prompt. A line ending with a backslash
+// continues on the next line, so long queries can be split over several.
+func readCommand(prompt func(string) (string, error)) (string, error) {
+	l, err := prompt("> ")
+	for err == nil && strings.HasSuffix(l, `\`) {
+		var next string
+		next, err = prompt("... ")
+		l = strings.TrimSuffix(l, `\`) + " " + next
+	}
+	return l, err
+}
+
 // ParseCommand parses an instruction and calls the related method
 // or executes the command as a query against InfluxDB.
 func (c *CommandLine) ParseCommand(cmd string) error {
@@ -1037,6 +1049,8 @@ func (c *CommandLine) help() {
         clear                 clears settings such as database or retention policy.  run 'clear' for help
         exit/quit/ctrl+d      quits the influx shell
 
+        End a line with \ to continue a command on the next line.
+
         show databases        show database names
         show series           show series information
         show measurements     show measurement information
@@ -1,6 +1,36 @@
 package cli
 
-import "testing"
+import (
+	"io"
+	"testing"
+)
+
+func TestReadCommand_Continuation(t *testing.T) {
+	lines := []string{`SELECT mean(value) \`, `FROM cpu \`, `WHERE time > now() - 1h`}
+	var prompts []string
+	prompt := func(p string) (string, error) {
+		prompts = append(prompts, p)
+		if len(lines) == 0 {
+			return "", io.EOF
+		}
+		l := lines[0]
+		lines = lines[1:]
+		return l, nil
+	}
+
+	l, err := readCommand(prompt)
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := `SELECT mean(value)  FROM cpu  WHERE time > now() - 1h`; l != exp {
+		t.Fatalf("unexpected command: %q, exp %q", l, exp)
+	} else if len(prompts) != 3 || prompts[0] != "> " || prompts[1] != "... " {
+		t.Fatalf("unexpected prompts: %q", prompts)
+	}
+
+	if _, err := readCommand(prompt); err != io.EOF {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
 
 func TestParseCommand_InsertInto(t *testing.T) {
 	t.Parallel()
@@ -6,6 +6,7 @@ import (
 	"compress/gzip"
 	"flag"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"math"
 	"os"
@@ -33,6 +34,7 @@ type Command struct {
 	out             string
 	database        string
 	retentionPolicy string
+	measurements    map[string]struct{}
 	startTime       int64
 	endTime         int64
 	compress        bool
@@ -56,7 +58,7 @@ func NewCommand() *Command {
 
 // Run executes the command.
 func (cmd *Command) Run(args ...string) error {
-	var start, end string
+	var start, end, measurements string
 	fs := flag.NewFlagSet("export", flag.ExitOnError)
 	fs.StringVar(&cmd.dataDir, "datadir", os.Getenv("HOME")+"/.influxdb/data", "Data storage path")
 	fs.StringVar(&cmd.walDir, "waldir", os.Getenv("HOME")+"/.influxdb/wal", "WAL storage path")
@@ -65,6 +67,7 @@ func (cmd *Command) Run(args ...string) error {
 	fs.StringVar(&cmd.retentionPolicy, "retention", "", "Optional: the retention policy to export (requires -database)")
 	fs.StringVar(&start, "start", "", "Optional: the start time to export (RFC3339 format)")
 	fs.StringVar(&end, "end", "", "Optional: the end time to export (RFC3339 format)")
+	fs.StringVar(&measurements, "measurement", "", "Optional: comma-separated list of the measurements to export")
 	fs.BoolVar(&cmd.compress, "compress", false, "Compress the output")
 
 	fs.SetOutput(cmd.Stdout)
@@ -99,6 +102,13 @@ func (cmd *Command) Run(args ...string) error {
 		cmd.endTime = math.MaxInt64
 	}
 
+	if measurements != "" {
+		cmd.measurements = make(map[string]struct{})
+		for _, m := range strings.Split(measurements, ",") {
+			cmd.measurements[strings.TrimSpace(m)] = struct{}{}
+		}
+	}
+
 	if err := cmd.validate(); err != nil {
 		return err
 	}
@@ -282,9 +292,8 @@ func (cmd *Command) exportTSMFile(tsmFilePath string, w io.Writer) error {
 
 	for i := 0; i < r.KeyCount(); i++ {
 		key, _ := r.KeyAt(i)
-		values, err := r.ReadAll(key)
-		if err != nil {
-			fmt.Fprintf(cmd.Stderr, "unable to read key %q in %s, skipping: %s\n", string(key), tsmFilePath, err.Error())
+		values := cmd.readValues(r, key, tsmFilePath)
+		if len(values) == 0 {
 			continue
 		}
 		measurement, field := tsm1.SeriesAndFieldFromCompositeKey(key)
@@ -298,6 +307,56 @@ func (cmd *Command) exportTSMFile(tsmFilePath string, w io.Writer) error {
 	return nil
 }
 
+// readValues returns the values of key that are not deleted. The blocks are
+// read one at a time and blocks that fail their checksum or do not decode are
+// skipped, so the rest of a damaged file can still be exported.
+func (cmd *Command) readValues(r *tsm1.TSMReader, key []byte, tsmFilePath string) []tsm1.Value {
+	tombstones := r.TombstoneRange(key)
+
+	var values []tsm1.Value
+	for _, entry := range r.Entries(key) {
+		if deleted(tombstones, entry.MinTime, entry.MaxTime) {
+			continue
+		}
+
+		block, err := readBlock(r, &entry, nil)
+		if err != nil {
+			fmt.Fprintf(cmd.Stderr, "unable to read block of key %q at offset %d in %s, skipping: %s\n", string(key), entry.Offset, tsmFilePath, err.Error())
+			continue
+		}
+		for _, t := range tombstones {
+			block = tsm1.Values(block).Exclude(t.Min, t.Max)
+		}
+		values = append(values, block...)
+	}
+	return values
+}
+
+// readBlock decodes the block of entry into values after verifying its
+// checksum.
+func readBlock(r *tsm1.TSMReader, entry *tsm1.IndexEntry, values []tsm1.Value) ([]tsm1.Value, error) {
+	if entry.Size < 4 {
+		return nil, fmt.Errorf("invalid block size %d", entry.Size)
+	}
+	checksum, buf, err := r.ReadBytes(entry, nil)
+	if err != nil {
+		return nil, err
+	} else if exp := crc32.ChecksumIEEE(buf); checksum != exp {
+		return nil, fmt.Errorf("checksum mismatch: got %d, exp %d", checksum, exp)
+	}
+	return tsm1.DecodeBlock(buf, values)
+}
+
+// deleted returns true if a tombstone covers all of min to max.
+func deleted(tombstones []tsm1.TimeRange, min, max int64) bool {
+	for _, t := range tombstones {
+		if t.Min <= min && t.Max >= max {
+			return true
+		}
+	}
+	return false
+}
+
 func (cmd *Command) writeWALFiles(w io.Writer, files []string, key string) error {
 	fmt.Fprintln(w, "# writing wal data")
 
@@ -368,9 +427,26 @@ func (cmd *Command) exportWALFile(walFilePath string, w io.Writer, warnDelete fu
 	return nil
 }
 
+// exportSeries returns true if the measurement of seriesKey is exported.
+func (cmd *Command) exportSeries(seriesKey []byte) bool {
+	if cmd.measurements == nil {
+		return true
+	}
+	name, err := models.ParseName(seriesKey)
+	if err != nil {
+		return false
+	}
+	_, ok := cmd.measurements[string(escape.Unescape(name))]
+	return ok
+}
+
 // writeValues writes every value in values to w, using the given series key and field name.
 // If any call to w.Write fails, that error is returned.
 func (cmd *Command) writeValues(w io.Writer, seriesKey []byte, field string, values []tsm1.Value) error {
+	if !cmd.exportSeries(seriesKey) {
+		return nil
+	}
+
 	buf := []byte(string(seriesKey) + " " + field + "=")
 	prefixLen := len(buf)
 
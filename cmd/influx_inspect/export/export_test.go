@@ -148,6 +148,58 @@ func Test_exportTSMFile(t *testing.T) {
 	}
 }
 
+func Test_exportTSMFile_Measurement(t *testing.T) {
+	tsmFile := writeCorpusToTSMFile(corpus{
+		tsm1.SeriesFieldKey("cpu,k=1", "x"):     []tsm1.Value{tsm1.NewValue(1, float64(1.5))},
+		tsm1.SeriesFieldKey(`my\ mem,k=1`, "x"): []tsm1.Value{tsm1.NewValue(2, float64(2.5))},
+		tsm1.SeriesFieldKey("disk,k=1", "x"):    []tsm1.Value{tsm1.NewValue(3, float64(3.5))},
+	})
+	defer os.Remove(tsmFile.Name())
+
+	var out bytes.Buffer
+	cmd := newCommand()
+	cmd.measurements = map[string]struct{}{"cpu": {}, "my mem": {}}
+	if err := cmd.exportTSMFile(tsmFile.Name(), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := "cpu,k=1 x=1.5 1\nmy\\ mem,k=1 x=2.5 2\n"; out.String() != exp {
+		t.Fatalf("unexpected output: %q, exp %q", out.String(), exp)
+	}
+}
+
+// Ensure the readable blocks of a damaged file are exported.
+func Test_exportTSMFile_Corrupt(t *testing.T) {
+	tsmFile := writeCorpusToTSMFile(corpus{
+		tsm1.SeriesFieldKey("a,k=1", "x"): []tsm1.Value{tsm1.NewValue(1, float64(1.5))},
+		tsm1.SeriesFieldKey("b,k=1", "x"): []tsm1.Value{tsm1.NewValue(2, float64(2.5))},
+	})
+	defer os.Remove(tsmFile.Name())
+
+	// Flip a byte of the first block, after the 5 byte header and the checksum.
+	b, err := ioutil.ReadFile(tsmFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[5+4+1] ^= 0xff
+	if err := ioutil.WriteFile(tsmFile.Name(), b, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, stderr bytes.Buffer
+	cmd := newCommand()
+	cmd.Stderr = &stderr
+	if err := cmd.exportTSMFile(tsmFile.Name(), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := "b,k=1 x=2.5 2\n"; out.String() != exp {
+		t.Fatalf("unexpected output: %q, exp %q", out.String(), exp)
+	} else if !strings.Contains(stderr.String(), "checksum mismatch") {
+		t.Fatalf("unexpected stderr: %q", stderr.String())
+	}
+}
+
 var sink interface{}
 
 func benchmarkExportTSM(c corpus, b *testing.B) {
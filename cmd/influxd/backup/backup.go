@@ -3,7 +3,9 @@ package backup
 
 import (
 	"compress/gzip"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -58,6 +60,11 @@ type Command struct {
 	manifest         backup_util.Manifest
 	portableFileBase string
 
+	// incremental skips the shards that did not change since the backups
+	// listed by the manifests in path, which are kept in previous.
+	incremental bool
+	previous    map[uint64]*backup_util.Entry
+
 	BackupFiles []string
 }
 
@@ -86,7 +93,7 @@ func (cmd *Command) Run(args ...string) error {
 		if err := cmd.backupMetastore(); err != nil {
 			return err
 		}
-		err = cmd.backupShard(cmd.database, cmd.retentionPolicy, cmd.shardID)
+		err = cmd.backupShard(cmd.database, cmd.retentionPolicy, cmd.shardID, 0)
 
 	} else if cmd.retentionPolicy != "" {
 		// always backup the metastore
@@ -119,12 +126,11 @@ func (cmd *Command) Run(args ...string) error {
 	}
 
 	if cmd.portable {
-		filename := cmd.portableFileBase + ".manifest"
-		if err := cmd.manifest.Save(filepath.Join(cmd.path, filename)); err != nil {
+		if err := cmd.saveManifest(); err != nil {
 			cmd.StderrLogger.Printf("manifest save failed: %v", err)
 			return err
 		}
-		cmd.BackupFiles = append(cmd.BackupFiles, filename)
+		cmd.BackupFiles = append(cmd.BackupFiles, cmd.portableFileBase+".manifest")
 	}
 
 	if err != nil {
@@ -154,6 +160,7 @@ func (cmd *Command) parseFlags(args []string) (err error) {
 	fs.StringVar(&startArg, "start", "", "")
 	fs.StringVar(&endArg, "end", "", "")
 	fs.BoolVar(&cmd.portable, "portable", false, "")
+	fs.BoolVar(&cmd.incremental, "incremental", false, "")
 
 	fs.SetOutput(cmd.Stderr)
 	fs.Usage = cmd.printUsage
@@ -208,12 +215,49 @@ func (cmd *Command) parseFlags(args []string) (err error) {
 	}
 	cmd.path = fs.Arg(0)
 
-	err = os.MkdirAll(cmd.path, 0700)
+	if err := os.MkdirAll(cmd.path, 0700); err != nil {
+		return err
+	}
 
-	return err
+	if cmd.incremental {
+		if !cmd.portable {
+			return errors.New("-incremental requires -portable")
+		} else if !cmd.isBackup || !cmd.since.IsZero() {
+			return errors.New("-incremental is not compatible with -since, -start or -end")
+		}
+		if _, cmd.previous, err = backup_util.LoadIncremental(cmd.path); err != nil {
+			return fmt.Errorf("read previous manifests: %s", err)
+		}
+	}
+	return nil
 }
 
-func (cmd *Command) backupShard(db, rp, sid string) error {
+// saveManifest writes the manifest of the portable backup. It is saved after
+// every shard too, so the shards of an interrupted backup are skipped when
+// it is run again with -incremental.
+func (cmd *Command) saveManifest() error {
+	return cmd.manifest.Save(filepath.Join(cmd.path, cmd.portableFileBase+".manifest"))
+}
+
+// unchangedShard returns the entry of the previous backup of shard id if the
+// shard was not modified since and the file of that backup is intact.
+func (cmd *Command) unchangedShard(db, rp string, id uint64, lastModified int64) *backup_util.Entry {
+	prev := cmd.previous[id]
+	if prev == nil || lastModified == 0 || prev.LastModified != lastModified {
+		return nil
+	} else if prev.Database != db || prev.Policy != rp {
+		return nil
+	}
+	if err := prev.Verify(cmd.path); err != nil {
+		cmd.StderrLogger.Printf("previous backup of shard %d cannot be reused: %s", id, err)
+		return nil
+	}
+	return prev
+}
+
+// backupShard backs up shard sid of db and rp. lastModified is the last time
+// the shard was modified as reported by the server, or 0 if it is unknown.
+func (cmd *Command) backupShard(db, rp, sid string, lastModified int64) error {
 	reqType := snapshotter.RequestShardBackup
 	if !cmd.isBackup {
 		reqType = snapshotter.RequestShardExport
@@ -224,6 +268,14 @@ func (cmd *Command) backupShard(db, rp, sid string) error {
 		return err
 	}
 
+	if cmd.incremental {
+		if prev := cmd.unchangedShard(db, rp, id, lastModified); prev != nil {
+			cmd.StdoutLogger.Printf("skipping db=%v rp=%v shard=%v, unchanged since backup %s", db, rp, sid, prev.FileName)
+			cmd.manifest.Files = append(cmd.manifest.Files, *prev)
+			return cmd.saveManifest()
+		}
+	}
+
 	shardArchivePath, err := cmd.nextPath(filepath.Join(cmd.path, fmt.Sprintf(backup_util.BackupFilePattern, db, rp, id)))
 	if err != nil {
 		return err
@@ -267,7 +319,8 @@ func (cmd *Command) backupShard(db, rp, sid string) error {
 			return err
 		}
 
-		zw := gzip.NewWriter(out)
+		h := sha256.New()
+		zw := gzip.NewWriter(io.MultiWriter(out, h))
 		zw.Name = filePrefix + ".tar"
 
 		cw := backup_util.CountingWriter{Writer: zw}
@@ -295,15 +348,6 @@ func (cmd *Command) backupShard(db, rp, sid string) error {
 			}
 			return err
 		}
-		cmd.manifest.Files = append(cmd.manifest.Files, backup_util.Entry{
-			Database:     db,
-			Policy:       rp,
-			ShardID:      shardid,
-			FileName:     filename,
-			Size:         cw.Total,
-			LastModified: 0,
-		})
-
 		if err := zw.Close(); err != nil {
 			return err
 		}
@@ -312,7 +356,17 @@ func (cmd *Command) backupShard(db, rp, sid string) error {
 			return err
 		}
 
+		cmd.manifest.Files = append(cmd.manifest.Files, backup_util.Entry{
+			Database:     db,
+			Policy:       rp,
+			ShardID:      shardid,
+			FileName:     filename,
+			Size:         cw.Total,
+			LastModified: lastModified,
+			Checksum:     hex.EncodeToString(h.Sum(nil)),
+		})
 		cmd.BackupFiles = append(cmd.BackupFiles, filename)
+		return cmd.saveManifest()
 	}
 	return nil
 
@@ -359,13 +413,17 @@ func (cmd *Command) backupRetentionPolicy() error {
 func (cmd *Command) backupResponsePaths(response *snapshotter.Response) error {
 
 	// loop through the returned paths and back up each shard
-	for _, path := range response.Paths {
+	for i, path := range response.Paths {
 		db, rp, id, err := backup_util.DBRetentionAndShardFromPath(path)
 		if err != nil {
 			return err
 		}
 
-		err = cmd.backupShard(db, rp, id)
+		var lastModified int64
+		if i < len(response.LastModified) {
+			lastModified = response.LastModified[i]
+		}
+		err = cmd.backupShard(db, rp, id, lastModified)
 
 		if err != nil {
 			return err
@@ -591,6 +649,10 @@ Usage: influxd backup [flags] PATH
             All points later than this time stamp will be excluded from the export. Not compatible with -since.
 	-portable
 	        Generate backup files in a format that is portable between different influxdb products.
+	-incremental
+	        Optional. Requires -portable. Skip the shards that have not changed since the
+	        portable backups already in PATH, which are listed again in the new manifest.
+	        An interrupted backup is resumed by running it again with -incremental.
 
 `)
 
@@ -2,13 +2,16 @@ package backup_util
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/gogo/protobuf/proto"
 	internal "github.com/influxdata/influxdb/cmd/influxd/backup_util/internal"
@@ -106,6 +109,10 @@ type Entry struct {
 	FileName     string `json:"fileName"`
 	Size         int64  `json:"size"`
 	LastModified int64  `json:"lastModified"`
+
+	// Checksum is the hex encoded SHA-256 of the file, if it was computed
+	// when the backup was made.
+	Checksum string `json:"checksum,omitempty"`
 }
 
 func (e *Entry) SizeOrZero() int64 {
@@ -115,6 +122,28 @@ func (e *Entry) SizeOrZero() int64 {
 	return e.Size
 }
 
+// Verify returns an error if the file of the entry in dir is missing or does
+// not match its checksum. Entries without a checksum only need to exist.
+func (e *Entry) Verify(dir string) error {
+	f, err := os.Open(filepath.Join(dir, e.FileName))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if e.Checksum == "" {
+		return nil
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != e.Checksum {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", e.FileName, e.Checksum, sum)
+	}
+	return nil
+}
+
 // MetaEntry contains the meta store information for a backup.
 type MetaEntry struct {
 	FileName string `json:"fileName"`
@@ -146,10 +175,20 @@ func (manifest *Manifest) Save(filename string) error {
 
 // LoadIncremental loads multiple manifest files from a given directory.
 func LoadIncremental(dir string) (*MetaEntry, map[uint64]*Entry, error) {
+	return LoadIncrementalUntil(dir, time.Time{})
+}
+
+// LoadIncrementalUntil loads the manifest files from a given directory that
+// were made at or before until, to restore the backup as of that time. All
+// the manifest files are loaded if until is zero.
+func LoadIncrementalUntil(dir string, until time.Time) (*MetaEntry, map[uint64]*Entry, error) {
 	manifests, err := filepath.Glob(filepath.Join(dir, "*.manifest"))
 	if err != nil {
 		return nil, nil, err
 	}
+	if !until.IsZero() {
+		manifests = manifestsUntil(manifests, until)
+	}
 	shards := make(map[uint64]*Entry)
 
 	if len(manifests) == 0 {
@@ -202,6 +241,21 @@ func LoadIncremental(dir string) (*MetaEntry, map[uint64]*Entry, error) {
 	return &metaEntry, shards, nil
 }
 
+// manifestsUntil returns the manifest files whose name is a time at or
+// before until. Manifests that are not named after their time are dropped.
+func manifestsUntil(manifests []string, until time.Time) []string {
+	var a []string
+	for _, fileName := range manifests {
+		base := strings.TrimSuffix(filepath.Base(fileName), ".manifest")
+		t, err := time.Parse(PortableFileNamePattern, base)
+		if err != nil || t.After(until) {
+			continue
+		}
+		a = append(a, fileName)
+	}
+	return a
+}
+
 type CountingWriter struct {
 	io.Writer
 	Total int64 // Total # of bytes transferred
@@ -0,0 +1,117 @@
+package backup_util_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd/influxd/backup_util"
+)
+
+// Ensure the manifests of incremental backups resolve each shard to its most
+// recently modified backup that still exists.
+func TestLoadIncremental(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	MustSaveManifest(t, dir, "20180101T000000Z", []backup_util.Entry{
+		{ShardID: 1, FileName: "20180101T000000Z.s1.tar.gz", LastModified: 10},
+		{ShardID: 2, FileName: "20180101T000000Z.s2.tar.gz", LastModified: 10},
+		{ShardID: 3, FileName: "20180101T000000Z.s3.tar.gz", LastModified: 10},
+	})
+	// Shard 1 is unchanged, so the second backup lists the first file again.
+	MustSaveManifest(t, dir, "20180102T000000Z", []backup_util.Entry{
+		{ShardID: 1, FileName: "20180101T000000Z.s1.tar.gz", LastModified: 10},
+		{ShardID: 2, FileName: "20180102T000000Z.s2.tar.gz", LastModified: 20},
+		{ShardID: 3, FileName: "20180102T000000Z.s3.tar.gz", LastModified: 20},
+	})
+	for _, name := range []string{"20180101T000000Z.s1.tar.gz", "20180101T000000Z.s2.tar.gz", "20180101T000000Z.s3.tar.gz", "20180102T000000Z.s2.tar.gz"} {
+		MustWriteFile(t, filepath.Join(dir, name))
+	}
+
+	meta, shards, err := backup_util.LoadIncremental(dir)
+	if err != nil {
+		t.Fatal(err)
+	} else if meta.FileName != "20180102T000000Z.meta" {
+		t.Fatalf("unexpected meta file: %s", meta.FileName)
+	}
+	for id, exp := range map[uint64]string{
+		1: "20180101T000000Z.s1.tar.gz",
+		2: "20180102T000000Z.s2.tar.gz",
+		// The newer file of shard 3 is missing.
+		3: "20180101T000000Z.s3.tar.gz",
+	} {
+		if e := shards[id]; e == nil || e.FileName != exp {
+			t.Errorf("unexpected file for shard %d: %+v", id, e)
+		}
+	}
+}
+
+// Ensure a restore as of a point in time only uses the backups made by then.
+func TestLoadIncrementalUntil(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	MustSaveManifest(t, dir, "20180101T000000Z", []backup_util.Entry{
+		{ShardID: 1, FileName: "20180101T000000Z.s1.tar.gz", LastModified: 10},
+	})
+	MustSaveManifest(t, dir, "20180102T000000Z", []backup_util.Entry{
+		{ShardID: 1, FileName: "20180102T000000Z.s1.tar.gz", LastModified: 20},
+		{ShardID: 2, FileName: "20180102T000000Z.s2.tar.gz", LastModified: 20},
+	})
+	for _, name := range []string{"20180101T000000Z.s1.tar.gz", "20180102T000000Z.s1.tar.gz", "20180102T000000Z.s2.tar.gz"} {
+		MustWriteFile(t, filepath.Join(dir, name))
+	}
+
+	meta, shards, err := backup_util.LoadIncrementalUntil(dir, time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	} else if meta.FileName != "20180101T000000Z.meta" {
+		t.Fatalf("unexpected meta file: %s", meta.FileName)
+	} else if len(shards) != 1 || shards[1] == nil || shards[1].FileName != "20180101T000000Z.s1.tar.gz" {
+		t.Fatalf("unexpected shards: %+v", shards)
+	}
+
+	// A backup made exactly at the time given is included.
+	if _, shards, err := backup_util.LoadIncrementalUntil(dir, time.Date(2018, 1, 2, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	} else if len(shards) != 2 {
+		t.Fatalf("unexpected shards: %+v", shards)
+	}
+
+	// There is nothing to restore before the first backup.
+	if meta, _, err := backup_util.LoadIncrementalUntil(dir, time.Date(2017, 12, 31, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	} else if meta != nil {
+		t.Fatalf("unexpected meta: %+v", meta)
+	}
+}
+
+// MustTempDir returns a temporary directory. Panic on error.
+func MustTempDir() string {
+	dir, err := ioutil.TempDir("", "backup-util-")
+	if err != nil {
+		panic(err)
+	}
+	return dir
+}
+
+// MustSaveManifest saves the manifest of the portable backup base in dir.
+func MustSaveManifest(t *testing.T, dir, base string, files []backup_util.Entry) {
+	m := backup_util.Manifest{
+		Meta:  backup_util.MetaEntry{FileName: base + ".meta"},
+		Files: files,
+	}
+	if err := m.Save(filepath.Join(dir, base+".manifest")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// MustWriteFile creates an empty file at path.
+func MustWriteFile(t *testing.T, path string) {
+	if err := ioutil.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -36,8 +36,10 @@ The commands are:
     backup               downloads a snapshot of a data node and saves it to disk
     config               display the default configuration
     help                 display this help message
+    relay                accept writes and forward them to other servers
     restore              uses a snapshot of a data node to rebuild a cluster
     run                  run node with existing configuration
+    service              install and control influxd as a Windows service
     version              displays the InfluxDB version
 
 "run" is the default command.
@@ -2,29 +2,34 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"math/rand"
 	"os"
 	"os/signal"
+	"runtime"
 	"syscall"
 	"time"
 
 	"github.com/influxdata/influxdb/cmd"
 	"github.com/influxdata/influxdb/cmd/influxd/backup"
 	"github.com/influxdata/influxdb/cmd/influxd/help"
+	"github.com/influxdata/influxdb/cmd/influxd/relay"
 	"github.com/influxdata/influxdb/cmd/influxd/restore"
 	"github.com/influxdata/influxdb/cmd/influxd/run"
+	"github.com/influxdata/influxdb/cmd/influxd/service"
 	"github.com/influxdata/influxdb/logger"
 	"go.uber.org/zap"
 )
 
 // These variables are populated via the Go linker.
 var (
-	version string
-	commit  string
-	branch  string
+	version   string
+	commit    string
+	branch    string
+	buildTime string
 )
 
 func init() {
@@ -38,6 +43,9 @@ func init() {
 	if branch == "" {
 		branch = "unknown"
 	}
+	if buildTime == "" {
+		buildTime = "unknown"
+	}
 }
 
 func main() {
@@ -82,31 +90,38 @@ func (m *Main) Run(args ...string) error {
 		cmd.Version = version
 		cmd.Commit = commit
 		cmd.Branch = branch
+		cmd.BuildTime = buildTime
 		cmd.Logger = m.Logger
 
+		// Started by the service control manager, the service is stopped
+		// by it rather than with signals.
+		if service.IsService() {
+			return service.Serve(cmd, args)
+		}
+
 		if err := cmd.Run(args...); err != nil {
 			return fmt.Errorf("run: %s", err)
 		}
 
 		signalCh := make(chan os.Signal, 1)
 		signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
-		m.Logger.Info("Listening for signals")
+		cmd.Logger.Info("Listening for signals")
 
 		// Block until one of the signals above is received
 		<-signalCh
-		m.Logger.Info("Signal received, initializing clean shutdown...")
+		cmd.Logger.Info("Signal received, initializing clean shutdown...")
 		go cmd.Close()
 
 		// Block again until another signal is received, a shutdown timeout elapses,
 		// or the Command is gracefully closed
-		m.Logger.Info("Waiting for clean shutdown...")
+		cmd.Logger.Info("Waiting for clean shutdown...")
 		select {
 		case <-signalCh:
-			m.Logger.Info("second signal received, initializing hard shutdown")
+			cmd.Logger.Info("second signal received, initializing hard shutdown")
 		case <-time.After(time.Second * 30):
-			m.Logger.Info("time limit reached, initializing hard shutdown")
+			cmd.Logger.Info("time limit reached, initializing hard shutdown")
 		case <-cmd.Closed:
-			m.Logger.Info("server shutdown completed")
+			cmd.Logger.Info("server shutdown completed")
 		}
 
 		// goodbye.
@@ -121,10 +136,18 @@ func (m *Main) Run(args ...string) error {
 		if err := name.Run(args...); err != nil {
 			return fmt.Errorf("restore: %s", err)
 		}
+	case "relay":
+		if err := relay.NewCommand().Run(args...); err != nil {
+			return fmt.Errorf("relay: %s", err)
+		}
 	case "config":
 		if err := run.NewPrintConfigCommand().Run(args...); err != nil {
 			return fmt.Errorf("config: %s", err)
 		}
+	case "service":
+		if err := service.NewCommand().Run(args...); err != nil {
+			return fmt.Errorf("service: %s", err)
+		}
 	case "version":
 		if err := NewVersionCommand().Run(args...); err != nil {
 			return fmt.Errorf("version: %s", err)
@@ -158,11 +181,24 @@ func NewVersionCommand() *VersionCommand {
 func (cmd *VersionCommand) Run(args ...string) error {
 	// Parse flags in case -h is specified.
 	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "")
 	fs.Usage = func() { fmt.Fprintln(cmd.Stderr, versionUsage) }
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
+	if *asJSON {
+		return json.NewEncoder(cmd.Stdout).Encode(struct {
+			Version   string `json:"version"`
+			Commit    string `json:"commit"`
+			Branch    string `json:"branch"`
+			BuildTime string `json:"build_time"`
+			GoVersion string `json:"go_version"`
+			OS        string `json:"os"`
+			Arch      string `json:"arch"`
+		}{version, commit, branch, buildTime, runtime.Version(), runtime.GOOS, runtime.GOARCH})
+	}
+
 	// Print version info.
 	fmt.Fprintf(cmd.Stdout, "InfluxDB v%s (git: %s %s)\n", version, branch, commit)
 
@@ -171,5 +207,9 @@ func (cmd *VersionCommand) Run(args ...string) error {
 
 var versionUsage = `Displays the InfluxDB version, build branch and git commit hash.
 
-Usage: influxd version
+Usage: influxd version [flags]
+
+    -json
+            Print the version, the git commit and branch, the build time and
+            the Go version as a JSON object.
 `
@@ -0,0 +1,72 @@
+package relay_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb/cmd/influxd/relay"
+)
+
+// Ensure the relay only accepts writes with its token, and does not start
+// without one if it forwards writes with credentials.
+func TestCommand_Token(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer upstream.Close()
+
+	dir, err := ioutil.TempDir("", "influxd-relay-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	u := strings.Replace(upstream.URL, "http://", "http://admin:secret@", 1)
+	cmd := relay.NewCommand()
+	cmd.Stdout, cmd.Stderr = ioutil.Discard, ioutil.Discard
+	if err := cmd.Open("-bind", "127.0.0.1:0", "-dir", dir, "-upstream", u); err == nil || err.Error() != "-token-file is required when an -upstream has credentials" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tokenFile := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("t0ken\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cmd = relay.NewCommand()
+	cmd.Stdout, cmd.Stderr = ioutil.Discard, ioutil.Discard
+	if err := cmd.Open("-bind", "127.0.0.1:0", "-dir", filepath.Join(dir, "queue"), "-upstream", u, "-token-file", tokenFile); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Close()
+
+	for _, tt := range []struct {
+		auth string
+		code int
+	}{
+		{auth: "", code: http.StatusUnauthorized},
+		{auth: "Token wrong", code: http.StatusUnauthorized},
+		{auth: "Basic YWRtaW46c2VjcmV0", code: http.StatusUnauthorized},
+		{auth: "Token t0ken", code: http.StatusNoContent},
+	} {
+		req, err := http.NewRequest("POST", "http://"+cmd.Addr().String()+"/write?db=db0", strings.NewReader("cpu value=1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.code {
+			t.Errorf("%q: unexpected status: got %d, exp %d", tt.auth, resp.StatusCode, tt.code)
+		}
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"compress/gzip"
 
@@ -46,6 +47,7 @@ type Command struct {
 	restoreRetention    string
 	shard               uint64
 	portable            bool
+	until               time.Time
 	online              bool
 	manifestMeta        *backup_util.MetaEntry
 	manifestFiles       map[uint64]*backup_util.Entry
@@ -143,6 +145,8 @@ func (cmd *Command) parseFlags(args []string) error {
 	fs.Uint64Var(&cmd.shard, "shard", 0, "")
 	fs.BoolVar(&cmd.online, "online", false, "")
 	fs.BoolVar(&cmd.portable, "portable", false, "")
+	var timeArg string
+	fs.StringVar(&timeArg, "time", "", "")
 	fs.SetOutput(cmd.Stdout)
 	fs.Usage = cmd.printUsage
 	if err := fs.Parse(args); err != nil {
@@ -179,11 +183,22 @@ func (cmd *Command) parseFlags(args []string) error {
 			cmd.restoreRetention = cmd.backupRetention
 		}
 
+		if timeArg != "" {
+			if !cmd.portable {
+				return fmt.Errorf("-time requires -portable")
+			}
+			if cmd.until, err = time.Parse(time.RFC3339, timeArg); err != nil {
+				return err
+			}
+		}
+
 		if cmd.portable {
 			var err error
-			cmd.manifestMeta, cmd.manifestFiles, err = backup_util.LoadIncremental(cmd.backupFilesPath)
+			cmd.manifestMeta, cmd.manifestFiles, err = backup_util.LoadIncrementalUntil(cmd.backupFilesPath, cmd.until)
 			if err != nil {
 				return fmt.Errorf("restore failed while processing manifest files: %s", err.Error())
+			} else if cmd.manifestMeta == nil {
+				return fmt.Errorf("no backup found in %s", cmd.backupFilesPath)
 			}
 		}
 	} else {
@@ -402,7 +417,17 @@ func (cmd *Command) uploadShardsPortable() error {
 		if cmd.sourceDatabase == "" || cmd.sourceDatabase == file.Database {
 			if cmd.backupRetention == "" || cmd.backupRetention == file.Policy {
 				if cmd.shard == 0 || cmd.shard == file.ShardID {
+					// A shard of an older backup that was deleted by the
+					// time of the restored metadata is not restored.
+					newShardID, ok := cmd.shardIDMap[file.ShardID]
+					if !ok {
+						cmd.StdoutLogger.Printf("Skipping shard %d, not in the restored metadata\n", file.ShardID)
+						continue
+					}
 					cmd.StdoutLogger.Printf("Restoring shard %d live from backup %s\n", file.ShardID, file.FileName)
+					if err := file.Verify(cmd.backupFilesPath); err != nil {
+						return err
+					}
 					f, err := os.Open(filepath.Join(cmd.backupFilesPath, file.FileName))
 					if err != nil {
 						f.Close()
@@ -419,7 +444,7 @@ func (cmd *Command) uploadShardsPortable() error {
 						targetDB = file.Database
 					}
 
-					if err := cmd.client.UploadShard(file.ShardID, cmd.shardIDMap[file.ShardID], targetDB, cmd.restoreRetention, tr); err != nil {
+					if err := cmd.client.UploadShard(file.ShardID, newShardID, targetDB, cmd.restoreRetention, tr); err != nil {
 						f.Close()
 						return err
 					}
@@ -585,6 +610,10 @@ Options:
 	        If not given, the value of -rp is used.
 	-shard <id>
 	        Optional.  If given, -db and -rp are required.  Will restore the single shard's data.
+	-time  <2015-12-24T08:12:23Z>
+	        Optional.  Restore the data as it was backed up at the passed in RFC3339 formatted time,
+	        using only the portable backups in PATH made at or before that time.  Combined with -newdb,
+	        this restores a point in time copy of a database next to the current one.
 
 `)
 }
@@ -6,13 +6,13 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"time"
 
+	"github.com/influxdata/influxdb/pkg/systemd"
 	"go.uber.org/zap"
 )
 
@@ -73,11 +73,6 @@ func (cmd *Command) Run(args ...string) error {
 	// Print sweet InfluxDB logo.
 	fmt.Fprint(cmd.Stdout, logo)
 
-	// Mark start-up in log.
-	cmd.Logger.Info(fmt.Sprintf("InfluxDB starting, version %s, branch %s, commit %s",
-		cmd.Version, cmd.Branch, cmd.Commit))
-	cmd.Logger.Info(fmt.Sprintf("Go version %s, GOMAXPROCS set to %d", runtime.Version(), runtime.GOMAXPROCS(0)))
-
 	// Write the PID file.
 	if err := cmd.writePIDFile(options.PIDFile); err != nil {
 		return fmt.Errorf("write pid file: %s", err)
@@ -100,6 +95,24 @@ func (cmd *Command) Run(args ...string) error {
 		return fmt.Errorf("%s. To generate a valid configuration file run `influxd config > influxdb.generated.conf`", err)
 	}
 
+	// Replace the default logger with the configured one.
+	logger, err := config.Logging.New(cmd.Stderr)
+	if err != nil {
+		return fmt.Errorf("create logger: %s", err)
+	}
+	cmd.Logger = logger
+	for _, msg := range config.deprecations {
+		cmd.Logger.Warn(msg)
+	}
+
+	// Mark start-up in log.
+	cmd.Logger.Info(fmt.Sprintf("InfluxDB starting, version %s, branch %s, commit %s",
+		cmd.Version, cmd.Branch, cmd.Commit))
+	if n := config.Performance.maxProcs(cmd.Getenv); n > 0 {
+		runtime.GOMAXPROCS(n)
+	}
+	cmd.Logger.Info(fmt.Sprintf("Go version %s, GOMAXPROCS set to %d", runtime.Version(), runtime.GOMAXPROCS(0)))
+
 	if config.HTTPD.PprofEnabled {
 		// Turn on block and mutex profiling.
 		runtime.SetBlockProfileRate(int(1 * time.Second))
@@ -125,6 +138,14 @@ func (cmd *Command) Run(args ...string) error {
 	}
 	cmd.Server = s
 
+	// Tell systemd the server is ready only now the meta data, the shards and
+	// their WAL, and the services are open.
+	if ok, err := systemd.Notify(systemd.Ready); err != nil {
+		cmd.Logger.Info(fmt.Sprintf("error notifying systemd: %s", err))
+	} else if ok {
+		cmd.Logger.Info("Notified systemd the server is ready")
+	}
+
 	// Begin monitoring the server's error channel.
 	go cmd.monitorServerErrors()
 
@@ -136,6 +157,7 @@ func (cmd *Command) Close() error {
 	defer close(cmd.Closed)
 	defer cmd.removePIDFile()
 	close(cmd.closing)
+	systemd.Notify(systemd.Stopping)
 	if cmd.Server != nil {
 		return cmd.Server.Close()
 	}
@@ -143,11 +165,10 @@ func (cmd *Command) Close() error {
 }
 
 func (cmd *Command) monitorServerErrors() {
-	logger := log.New(cmd.Stderr, "", log.LstdFlags)
 	for {
 		select {
 		case err := <-cmd.Server.Err():
-			logger.Println(err)
+			cmd.Logger.Error(err.Error())
 		case <-cmd.closing:
 			return
 		}
@@ -3,7 +3,7 @@ package run
 import (
 	"fmt"
 	"io/ioutil"
-	"log"
+	"net"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -15,19 +15,25 @@ import (
 
 	"github.com/BurntSushi/toml"
 	"github.com/influxdata/influxdb/coordinator"
+	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/monitor"
 	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/services/audit"
 	"github.com/influxdata/influxdb/services/collectd"
 	"github.com/influxdata/influxdb/services/continuous_querier"
 	"github.com/influxdata/influxdb/services/graphite"
 	"github.com/influxdata/influxdb/services/httpd"
+	"github.com/influxdata/influxdb/services/ldap"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/services/opentsdb"
 	"github.com/influxdata/influxdb/services/precreator"
+	"github.com/influxdata/influxdb/services/replication"
 	"github.com/influxdata/influxdb/services/retention"
+	"github.com/influxdata/influxdb/services/rpc"
 	"github.com/influxdata/influxdb/services/storage"
 	"github.com/influxdata/influxdb/services/subscriber"
 	"github.com/influxdata/influxdb/services/udp"
+	"github.com/influxdata/influxdb/services/zipkin"
 	"github.com/influxdata/influxdb/tsdb"
 	"golang.org/x/text/encoding/unicode"
 	"golang.org/x/text/transform"
@@ -45,11 +51,16 @@ type Config struct {
 	Coordinator coordinator.Config `toml:"coordinator"`
 	Retention   retention.Config   `toml:"retention"`
 	Precreator  precreator.Config  `toml:"shard-precreation"`
+	Logging     logger.Config      `toml:"logging"`
+	Performance PerformanceConfig  `toml:"performance"`
 
 	Monitor        monitor.Config    `toml:"monitor"`
 	Subscriber     subscriber.Config `toml:"subscriber"`
 	HTTPD          httpd.Config      `toml:"http"`
+	LDAP           ldap.Config       `toml:"ldap"`
+	Audit          audit.Config      `toml:"audit"`
 	Storage        storage.Config    `toml:"ifql"`
+	RPC            rpc.Config        `toml:"rpc"`
 	GraphiteInputs []graphite.Config `toml:"graphite"`
 	CollectdInputs []collectd.Config `toml:"collectd"`
 	OpenTSDBInputs []opentsdb.Config `toml:"opentsdb"`
@@ -57,11 +68,24 @@ type Config struct {
 
 	ContinuousQuery continuous_querier.Config `toml:"continuous_queries"`
 
+	// Databases are created at startup if they do not exist.
+	Databases DatabaseConfigs `toml:"database"`
+
+	// Replication sends the writes to databases to remote servers.
+	Replication replication.Config `toml:"replication"`
+
+	// Tracing sends the traces of sampled queries to a Zipkin collector.
+	Tracing zipkin.Config `toml:"tracing"`
+
 	// Server reporting
 	ReportingDisabled bool `toml:"reporting-disabled"`
 
 	// BindAddress is the address that all TCP services use (Raft, Snapshot, Cluster, etc.)
 	BindAddress string `toml:"bind-address"`
+
+	// deprecations are the warnings about deprecated settings found while
+	// decoding the config, which is done before the logger exists.
+	deprecations []string
 }
 
 // NewConfig returns an instance of Config with reasonable defaults.
@@ -71,11 +95,16 @@ func NewConfig() *Config {
 	c.Data = tsdb.NewConfig()
 	c.Coordinator = coordinator.NewConfig()
 	c.Precreator = precreator.NewConfig()
+	c.Logging = logger.NewConfig()
+	c.Performance = NewPerformanceConfig()
 
 	c.Monitor = monitor.NewConfig()
 	c.Subscriber = subscriber.NewConfig()
 	c.HTTPD = httpd.NewConfig()
+	c.LDAP = ldap.NewConfig()
+	c.Audit = audit.NewConfig()
 	c.Storage = storage.NewConfig()
+	c.RPC = rpc.NewConfig()
 
 	c.GraphiteInputs = []graphite.Config{graphite.NewConfig()}
 	c.CollectdInputs = []collectd.Config{collectd.NewConfig()}
@@ -84,6 +113,8 @@ func NewConfig() *Config {
 
 	c.ContinuousQuery = continuous_querier.NewConfig()
 	c.Retention = retention.NewConfig()
+	c.Replication = replication.NewConfig()
+	c.Tracing = zipkin.NewConfig()
 	c.BindAddress = DefaultBindAddress
 
 	return c
@@ -107,16 +138,26 @@ func NewDemoConfig() (*Config, error) {
 	c.Meta.Dir = filepath.Join(homeDir, ".influxdb/meta")
 	c.Data.Dir = filepath.Join(homeDir, ".influxdb/data")
 	c.Data.WALDir = filepath.Join(homeDir, ".influxdb/wal")
+	c.Replication.Dir = filepath.Join(homeDir, ".influxdb/replication")
 
 	return c, nil
 }
 
 // FromTomlFile loads the config from a TOML file.
 func (c *Config) FromTomlFile(fpath string) error {
-	bs, err := ioutil.ReadFile(fpath)
+	input, err := readTomlFile(fpath)
 	if err != nil {
 		return err
 	}
+	return c.FromToml(input)
+}
+
+// readTomlFile returns the contents of the TOML file at fpath.
+func readTomlFile(fpath string) (string, error) {
+	bs, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return "", err
+	}
 
 	// Handle any potential Byte-Order-Marks that may be in the config file.
 	// This is for Windows compatibility only.
@@ -125,24 +166,30 @@ func (c *Config) FromTomlFile(fpath string) error {
 	bom := unicode.BOMOverride(transform.Nop)
 	bs, _, err = transform.Bytes(bom, bs)
 	if err != nil {
-		return err
+		return "", err
 	}
-	return c.FromToml(string(bs))
+	return string(bs), nil
 }
 
 // FromToml loads the config from TOML.
 func (c *Config) FromToml(input string) error {
+	_, err := c.decodeToml(input)
+	return err
+}
+
+// decodeToml loads the config from TOML and returns the metadata of the
+// decoding, such as the keys that did not match any setting.
+func (c *Config) decodeToml(input string) (toml.MetaData, error) {
 	// Replace deprecated [cluster] with [coordinator]
 	re := regexp.MustCompile(`(?m)^\s*\[cluster\]`)
 	input = re.ReplaceAllStringFunc(input, func(in string) string {
 		in = strings.TrimSpace(in)
 		out := "[coordinator]"
-		log.Printf("deprecated config option %s replaced with %s; %s will not be supported in a future release\n", in, out, in)
+		c.deprecations = append(c.deprecations, fmt.Sprintf("deprecated config option %s replaced with %s; %s will not be supported in a future release", in, out, in))
 		return out
 	})
 
-	_, err := toml.Decode(input, c)
-	return err
+	return toml.Decode(input, c)
 }
 
 // Validate returns an error if the config is invalid.
@@ -155,6 +202,10 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if err := c.Coordinator.Validate(); err != nil {
+		return err
+	}
+
 	if err := c.Monitor.Validate(); err != nil {
 		return err
 	}
@@ -175,6 +226,32 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if err := c.Logging.Validate(); err != nil {
+		return fmt.Errorf("invalid logging config: %v", err)
+	}
+
+	if err := c.Performance.Validate(); err != nil {
+		return fmt.Errorf("invalid performance config: %v", err)
+	}
+
+	if err := c.HTTPD.Validate(); err != nil {
+		return fmt.Errorf("invalid http config: %v", err)
+	}
+
+	if err := c.RPC.Validate(); err != nil {
+		return fmt.Errorf("invalid rpc config: %v", err)
+	} else if c.RPC.Enabled && !c.HTTPD.Enabled {
+		return fmt.Errorf("invalid rpc config: the http service must be enabled, as it authenticates the requests")
+	}
+
+	if err := c.LDAP.Validate(); err != nil {
+		return fmt.Errorf("invalid ldap config: %v", err)
+	}
+
+	if err := c.Audit.Validate(); err != nil {
+		return fmt.Errorf("invalid audit config: %v", err)
+	}
+
 	for _, graphite := range c.GraphiteInputs {
 		if err := graphite.Validate(); err != nil {
 			return fmt.Errorf("invalid graphite config: %v", err)
@@ -187,6 +264,30 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	for _, opentsdb := range c.OpenTSDBInputs {
+		if err := opentsdb.Validate(); err != nil {
+			return fmt.Errorf("invalid opentsdb config: %v", err)
+		}
+	}
+
+	for _, udp := range c.UDPInputs {
+		if err := udp.Validate(); err != nil {
+			return fmt.Errorf("invalid udp config: %v", err)
+		}
+	}
+
+	if err := c.Databases.Validate(); err != nil {
+		return fmt.Errorf("invalid database config: %v", err)
+	}
+
+	if err := c.Replication.Validate(); err != nil {
+		return fmt.Errorf("invalid replication config: %v", err)
+	}
+
+	if err := c.Tracing.Validate(); err != nil {
+		return fmt.Errorf("invalid tracing config: %v", err)
+	}
+
 	return nil
 }
 
@@ -332,12 +433,20 @@ func (c *Config) diagnosticsClients() map[string]diagnostics.Client {
 		"config-coordinator": c.Coordinator,
 		"config-retention":   c.Retention,
 		"config-precreator":  c.Precreator,
+		"config-logging":     c.Logging,
+		"config-performance": c.Performance,
 
 		"config-monitor":    c.Monitor,
 		"config-subscriber": c.Subscriber,
 		"config-httpd":      c.HTTPD,
+		"config-ldap":       c.LDAP,
+		"config-audit":      c.Audit,
+		"config-rpc":        c.RPC,
 
 		"config-cqs": c.ContinuousQuery,
+
+		"config-replication": c.Replication,
+		"config-tracing":     c.Tracing,
 	}
 
 	// Config settings that can be repeated and can be disabled.
@@ -353,6 +462,9 @@ func (c *Config) diagnosticsClients() map[string]diagnostics.Client {
 	if u := udp.Configs(c.UDPInputs); u.Enabled() {
 		m["config-udp"] = u
 	}
+	if len(c.Databases) > 0 {
+		m["config-databases"] = c.Databases
+	}
 
 	return m
 }
@@ -370,3 +482,94 @@ func (c *Config) deregisterDiagnostics(m *monitor.Monitor) {
 		m.DeregisterDiagnosticsClient(name)
 	}
 }
+
+// portConflicts returns a problem for each pair of enabled listeners that
+// cannot both bind their address.
+func (c *Config) portConflicts() []string {
+	type listener struct {
+		name    string
+		network string
+		addr    string
+	}
+	listeners := []listener{{"bind-address", "tcp", c.BindAddress}}
+	if c.HTTPD.Enabled {
+		listeners = append(listeners, listener{"http bind-address", "tcp", c.HTTPD.BindAddress})
+		for _, addr := range c.HTTPD.AdditionalBindAddresses {
+			listeners = append(listeners, listener{"http additional-bind-addresses", "tcp", addr})
+		}
+		if c.HTTPD.HTTPSEnabled && c.HTTPD.HTTPSACMEEnabled {
+			listeners = append(listeners, listener{"http https-acme-challenge-bind-address", "tcp", c.HTTPD.HTTPSACMEChallengeBindAddress})
+		}
+	}
+	if c.RPC.Enabled {
+		listeners = append(listeners, listener{"rpc bind-address", "tcp", c.RPC.BindAddress})
+	}
+	if c.Storage.Enabled {
+		listeners = append(listeners, listener{"ifql bind-address", "tcp", c.Storage.BindAddress})
+	}
+	for i, g := range c.GraphiteInputs {
+		if g.Enabled {
+			network := g.Protocol
+			if network == "" {
+				network = graphite.DefaultProtocol
+			}
+			listeners = append(listeners, listener{fmt.Sprintf("graphite[%d] bind-address", i), network, g.BindAddress})
+		}
+	}
+	for i, cc := range c.CollectdInputs {
+		if cc.Enabled {
+			listeners = append(listeners, listener{fmt.Sprintf("collectd[%d] bind-address", i), "udp", cc.BindAddress})
+		}
+	}
+	for i, o := range c.OpenTSDBInputs {
+		if o.Enabled {
+			listeners = append(listeners, listener{fmt.Sprintf("opentsdb[%d] bind-address", i), "tcp", o.BindAddress})
+		}
+	}
+	for i, u := range c.UDPInputs {
+		if u.Enabled {
+			listeners = append(listeners, listener{fmt.Sprintf("udp[%d] bind-address", i), "udp", u.BindAddress})
+		}
+	}
+
+	var problems []string
+	for i, a := range listeners {
+		for _, b := range listeners[i+1:] {
+			if a.network == b.network && addrsConflict(a.addr, b.addr) {
+				problems = append(problems, fmt.Sprintf("%s %q conflicts with %s %q", a.name, a.addr, b.name, b.addr))
+			}
+		}
+	}
+	return problems
+}
+
+// addrsConflict returns true if a and b are the same port on hosts that
+// overlap. An empty host or "::" listens on every address, "0.0.0.0" on
+// every IPv4 address.
+func addrsConflict(a, b string) bool {
+	ahost, aport, err := net.SplitHostPort(a)
+	if err != nil {
+		return false
+	}
+	bhost, bport, err := net.SplitHostPort(b)
+	if err != nil {
+		return false
+	}
+	if aport != bport || aport == "0" {
+		return false
+	}
+
+	isIPv6 := func(host string) bool {
+		ip := net.ParseIP(host)
+		return ip != nil && ip.To4() == nil
+	}
+	switch {
+	case ahost == bhost, ahost == "", bhost == "", ahost == "::", bhost == "::":
+		return true
+	case ahost == "0.0.0.0":
+		return !isIPv6(bhost)
+	case bhost == "0.0.0.0":
+		return !isIPv6(ahost)
+	}
+	return false
+}
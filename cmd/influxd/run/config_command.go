@@ -30,6 +30,7 @@ func (cmd *PrintConfigCommand) Run(args ...string) error {
 	// Parse command flags.
 	fs := flag.NewFlagSet("", flag.ContinueOnError)
 	configPath := fs.String("config", "", "")
+	test := fs.Bool("test", false, "")
 	fs.Usage = func() { fmt.Fprintln(cmd.Stderr, printConfigUsage) }
 	if err := fs.Parse(args); err != nil {
 		return err
@@ -37,6 +38,9 @@ func (cmd *PrintConfigCommand) Run(args ...string) error {
 
 	// Parse config from path.
 	opt := Options{ConfigPath: *configPath}
+	if *test {
+		return cmd.test(opt.GetConfigPath())
+	}
 	config, err := cmd.parseConfig(opt.GetConfigPath())
 	if err != nil {
 		return fmt.Errorf("parse config: %s", err)
@@ -78,7 +82,52 @@ func (cmd *PrintConfigCommand) parseConfig(path string) (*Config, error) {
 	return config, nil
 }
 
-var printConfigUsage = `Displays the default configuration.
+// test checks the config at path and prints every problem found, instead of
+// stopping at the first one like the server does.
+func (cmd *PrintConfigCommand) test(path string) error {
+	config, err := NewDemoConfig()
+	if err != nil {
+		config = NewConfig()
+	}
+
+	var problems []string
+	if path != "" {
+		fmt.Fprintf(cmd.Stderr, "Testing configuration at: %s\n", path)
+
+		input, err := readTomlFile(path)
+		if err != nil {
+			return err
+		}
+		// A value of the wrong type, such as a duration without a unit,
+		// stops the decoding, so there is nothing more to check.
+		md, err := config.decodeToml(input)
+		if err != nil {
+			return fmt.Errorf("parse config: %s", err)
+		}
+		for _, key := range md.Undecoded() {
+			problems = append(problems, fmt.Sprintf("unknown setting %s", key))
+		}
+	}
+
+	if err := config.ApplyEnvOverrides(os.Getenv); err != nil {
+		problems = append(problems, fmt.Sprintf("apply env config: %v", err))
+	}
+	if err := config.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	problems = append(problems, config.portConflicts()...)
+
+	for _, p := range problems {
+		fmt.Fprintln(cmd.Stdout, p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d problem(s) in the configuration", len(problems))
+	}
+	fmt.Fprintln(cmd.Stdout, "configuration is valid")
+	return nil
+}
+
+var printConfigUsage = `Displays the default configuration, or checks a configuration file.
 
 Usage: influxd config [flags]
 
@@ -89,4 +138,9 @@ Usage: influxd config [flags]
             is present at any of these locations.
             Disable the automatic loading of a configuration file using
             the null device (such as /dev/null).
+
+    -test
+            Check the configuration instead of printing it. Every unknown
+            setting, invalid value and listener bound to the same port as
+            another one is reported, and the command fails if there are any.
 `
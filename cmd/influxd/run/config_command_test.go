@@ -0,0 +1,67 @@
+package run_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb/cmd/influxd/run"
+)
+
+func TestPrintConfigCommand_Test(t *testing.T) {
+	tmpdir, err := ioutil.TempDir(os.TempDir(), "influxd-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	path := filepath.Join(tmpdir, "influxdb.conf")
+	if err := ioutil.WriteFile(path, []byte(`
+[meta]
+dir = "/tmp/meta"
+no-such-setting = true
+
+[http]
+bind-address = ":8086"
+
+[[graphite]]
+enabled = true
+bind-address = "127.0.0.1:8086"
+
+[[udp]]
+enabled = true
+bind-address = ":8086"
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := run.NewPrintConfigCommand()
+	cmd.Stdout, cmd.Stderr = &stdout, ioutil.Discard
+	if err := cmd.Run("-config", path, "-test"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected problems: %q", lines)
+	} else if !strings.Contains(lines[0], "meta.no-such-setting") {
+		t.Fatalf("unexpected unknown setting: %s", lines[0])
+	} else if !strings.Contains(lines[1], "http bind-address") || !strings.Contains(lines[1], "graphite[0] bind-address") {
+		t.Fatalf("unexpected conflict: %s", lines[1])
+	}
+}
+
+func TestPrintConfigCommand_Test_Valid(t *testing.T) {
+	var stdout bytes.Buffer
+	cmd := run.NewPrintConfigCommand()
+	cmd.Stdout, cmd.Stderr = &stdout, ioutil.Discard
+	if err := cmd.Run("-config", os.DevNull, "-test"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if s := stdout.String(); s != "configuration is valid\n" {
+		t.Fatalf("unexpected output: %q", s)
+	}
+}
@@ -0,0 +1,16 @@
+package run
+
+import "testing"
+
+// Ensure deprecated sections are reported once the logger exists instead of
+// being logged while the config is decoded.
+func TestConfig_Deprecations(t *testing.T) {
+	c := NewConfig()
+	if err := c.FromToml("[cluster]\nmax-select-point = 100\n"); err != nil {
+		t.Fatal(err)
+	} else if len(c.deprecations) != 1 {
+		t.Fatalf("unexpected deprecations: %q", c.deprecations)
+	} else if exp := "deprecated config option [cluster] replaced with [coordinator]; [cluster] will not be supported in a future release"; c.deprecations[0] != exp {
+		t.Fatalf("unexpected deprecation: %s", c.deprecations[0])
+	}
+}
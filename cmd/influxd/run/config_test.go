@@ -6,9 +6,11 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/influxdata/influxdb/cmd/influxd/run"
+	itoml "github.com/influxdata/influxdb/toml"
 	"golang.org/x/text/encoding/unicode"
 	"golang.org/x/text/transform"
 )
@@ -315,6 +317,48 @@ func TestConfig_InvalidSubsections(t *testing.T) {
 	}
 }
 
+// Ensure the declared databases can be parsed and are validated.
+func TestConfig_Parse_Databases(t *testing.T) {
+	var c run.Config
+	if err := c.FromToml(`
+[[database]]
+name = "db0"
+default-retention-policy = "rp1"
+
+[[database.retention-policy]]
+name = "rp0"
+
+[[database.retention-policy]]
+name = "rp1"
+duration = "168h"
+shard-group-duration = "24h"
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.Databases) != 1 {
+		t.Fatalf("unexpected databases: %d", len(c.Databases))
+	} else if db := c.Databases[0]; db.Name != "db0" || db.DefaultRetentionPolicy != "rp1" || len(db.RetentionPolicies) != 2 {
+		t.Fatalf("unexpected database: %+v", db)
+	} else if rp := db.RetentionPolicies[1]; rp.Name != "rp1" || time.Duration(rp.Duration) != 168*time.Hour || time.Duration(rp.ShardGroupDuration) != 24*time.Hour {
+		t.Fatalf("unexpected retention policy: %+v", rp)
+	} else if err := c.Databases.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dbs := range []run.DatabaseConfigs{
+		{{}},
+		{{Name: "db0"}, {Name: "db0"}},
+		{{Name: "db0", DefaultRetentionPolicy: "rp0"}},
+		{{Name: "db0", RetentionPolicies: []run.RetentionPolicyConfig{{Name: "rp0"}, {Name: "rp0"}}}},
+		{{Name: "db0", RetentionPolicies: []run.RetentionPolicyConfig{{Name: "rp0", Duration: itoml.Duration(time.Minute)}}}},
+	} {
+		if err := dbs.Validate(); err == nil {
+			t.Errorf("expected error for %+v", dbs)
+		}
+	}
+}
+
 // Ensure the configuration can be parsed when a Byte-Order-Mark is present.
 func TestConfig_Parse_UTF8_ByteOrderMark(t *testing.T) {
 	// Parse configuration.
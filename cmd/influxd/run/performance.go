@@ -0,0 +1,107 @@
+package run
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+)
+
+// PerformanceConfig represents the configuration of the Go runtime.
+type PerformanceConfig struct {
+	// MaxProcs is the maximum number of CPUs executing Go code at the same
+	// time. When it is 0, the GOMAXPROCS environment variable is used if it
+	// is set, and otherwise the CPU limit of the cgroup of the process.
+	MaxProcs int `toml:"max-procs"`
+}
+
+// NewPerformanceConfig returns a new instance of PerformanceConfig with
+// defaults.
+func NewPerformanceConfig() PerformanceConfig {
+	return PerformanceConfig{}
+}
+
+// Validate returns an error if the config is invalid.
+func (c PerformanceConfig) Validate() error {
+	if c.MaxProcs < 0 {
+		return errors.New("max-procs must be non-negative")
+	}
+	return nil
+}
+
+// Diagnostics returns a diagnostics representation of the config.
+func (c PerformanceConfig) Diagnostics() (*diagnostics.Diagnostics, error) {
+	return diagnostics.RowFromMap(map[string]interface{}{
+		"max-procs": c.MaxProcs,
+	}), nil
+}
+
+// maxProcs returns the value GOMAXPROCS should be set to, or 0 to leave it
+// as it is.
+func (c PerformanceConfig) maxProcs(getenv func(string) string) int {
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+
+	if c.MaxProcs > 0 {
+		return c.MaxProcs
+	} else if getenv("GOMAXPROCS") != "" {
+		return 0
+	}
+
+	// The Go runtime already honors the CPU affinity of the process, so only
+	// lower the number of CPUs to the quota of the cgroup.
+	if n := cgroupCPULimit("/sys/fs/cgroup"); n > 0 && n < runtime.NumCPU() {
+		return n
+	}
+	return 0
+}
+
+// cgroupCPULimit returns the CPU quota of the cgroup mounted at root,
+// rounded up to a whole number of CPUs, or 0 if there is no quota.
+func cgroupCPULimit(root string) int {
+	// cgroup v2 has the quota and the period in one file, such as
+	// "200000 100000" or "max 100000".
+	if b, err := ioutil.ReadFile(filepath.Join(root, "cpu.max")); err == nil {
+		fields := strings.Fields(string(b))
+		if len(fields) != 2 {
+			return 0
+		}
+		return cpusFromQuota(fields[0], fields[1])
+	}
+
+	// cgroup v1 has them in separate files, with -1 for no quota.
+	for _, dir := range []string{"cpu", "cpu,cpuacct"} {
+		quota, err := ioutil.ReadFile(filepath.Join(root, dir, "cpu.cfs_quota_us"))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return 0
+		}
+		period, err := ioutil.ReadFile(filepath.Join(root, dir, "cpu.cfs_period_us"))
+		if err != nil {
+			return 0
+		}
+		return cpusFromQuota(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+	}
+	return 0
+}
+
+// cpusFromQuota returns quota divided by period rounded up, or 0 if either
+// is not a positive number.
+func cpusFromQuota(quota, period string) int {
+	q, err := strconv.ParseInt(quota, 10, 64)
+	if err != nil || q <= 0 {
+		return 0
+	}
+	p, err := strconv.ParseInt(period, 10, 64)
+	if err != nil || p <= 0 {
+		return 0
+	}
+	return int((q + p - 1) / p)
+}
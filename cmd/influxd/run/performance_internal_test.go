@@ -0,0 +1,62 @@
+package run
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCgroupCPULimit(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		files map[string]string
+		exp   int
+	}{
+		{name: "none", exp: 0},
+		{name: "v2", files: map[string]string{"cpu.max": "250000 100000\n"}, exp: 3},
+		{name: "v2 unlimited", files: map[string]string{"cpu.max": "max 100000\n"}, exp: 0},
+		{name: "v1", files: map[string]string{
+			"cpu/cpu.cfs_quota_us":  "100000\n",
+			"cpu/cpu.cfs_period_us": "100000\n",
+		}, exp: 1},
+		{name: "v1 unlimited", files: map[string]string{
+			"cpu,cpuacct/cpu.cfs_quota_us":  "-1\n",
+			"cpu,cpuacct/cpu.cfs_period_us": "100000\n",
+		}, exp: 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := ioutil.TempDir("", "influxd-cgroup")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(root)
+
+			for name, data := range tt.files {
+				path := filepath.Join(root, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+					t.Fatal(err)
+				} else if err := ioutil.WriteFile(path, []byte(data), 0666); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := cgroupCPULimit(root); got != tt.exp {
+				t.Fatalf("unexpected limit: got %d, exp %d", got, tt.exp)
+			}
+		})
+	}
+}
+
+func TestPerformanceConfig_MaxProcs(t *testing.T) {
+	c := PerformanceConfig{MaxProcs: 2}
+	if n := c.maxProcs(func(string) string { return "8" }); n != 2 {
+		t.Fatalf("unexpected max procs: %d", n)
+	}
+
+	// GOMAXPROCS in the environment is left to the runtime.
+	c.MaxProcs = 0
+	if n := c.maxProcs(func(string) string { return "8" }); n != 0 {
+		t.Fatalf("unexpected max procs: %d", n)
+	}
+}
@@ -0,0 +1,154 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/toml"
+)
+
+// DatabaseConfig declares a database and retention policies that the server
+// creates at startup if they do not exist.
+type DatabaseConfig struct {
+	Name string `toml:"name"`
+
+	// DefaultRetentionPolicy is made the default retention policy of the
+	// database. It must be one of RetentionPolicies. When it is empty, the
+	// default retention policy is left as it is.
+	DefaultRetentionPolicy string `toml:"default-retention-policy"`
+
+	RetentionPolicies []RetentionPolicyConfig `toml:"retention-policy"`
+}
+
+// RetentionPolicyConfig declares a retention policy of a database.
+type RetentionPolicyConfig struct {
+	Name string `toml:"name"`
+
+	// Duration is how long data is kept, or 0 to keep it forever.
+	Duration toml.Duration `toml:"duration"`
+
+	// ShardGroupDuration is the time range of a shard group. When it is 0,
+	// it is derived from Duration as with CREATE RETENTION POLICY.
+	ShardGroupDuration toml.Duration `toml:"shard-group-duration"`
+}
+
+// Validate returns an error if the config is invalid.
+func (c DatabaseConfig) Validate() error {
+	if c.Name == "" {
+		return errors.New("name must not be empty")
+	}
+
+	names := make(map[string]bool, len(c.RetentionPolicies))
+	for _, rp := range c.RetentionPolicies {
+		if rp.Name == "" {
+			return errors.New("retention policy name must not be empty")
+		} else if names[rp.Name] {
+			return fmt.Errorf("duplicate retention policy %s", rp.Name)
+		} else if rp.Duration != 0 && time.Duration(rp.Duration) < meta.MinRetentionPolicyDuration {
+			return fmt.Errorf("retention policy %s: %s", rp.Name, meta.ErrRetentionPolicyDurationTooLow)
+		} else if rp.ShardGroupDuration < 0 {
+			return fmt.Errorf("retention policy %s: shard-group-duration must be non-negative", rp.Name)
+		}
+		names[rp.Name] = true
+	}
+	if c.DefaultRetentionPolicy != "" && !names[c.DefaultRetentionPolicy] {
+		return fmt.Errorf("default retention policy %s is not declared", c.DefaultRetentionPolicy)
+	}
+	return nil
+}
+
+// DatabaseConfigs wraps a slice of DatabaseConfig to aggregate diagnostics.
+type DatabaseConfigs []DatabaseConfig
+
+// Validate returns an error if any database is invalid or declared twice.
+func (c DatabaseConfigs) Validate() error {
+	names := make(map[string]bool, len(c))
+	for _, db := range c {
+		if err := db.Validate(); err != nil {
+			return err
+		} else if names[db.Name] {
+			return fmt.Errorf("duplicate database %s", db.Name)
+		}
+		names[db.Name] = true
+	}
+	return nil
+}
+
+// Diagnostics returns one set of diagnostics for all of the declared
+// databases.
+func (c DatabaseConfigs) Diagnostics() (*diagnostics.Diagnostics, error) {
+	d := diagnostics.NewDiagnostics([]string{"name", "default-retention-policy", "retention-policies"})
+	for _, db := range c {
+		d.AddRow([]interface{}{db.Name, db.DefaultRetentionPolicy, len(db.RetentionPolicies)})
+	}
+	return d, nil
+}
+
+// provisionDatabases creates the declared databases and retention policies
+// that do not exist and updates the declared retention policies that differ
+// from their config. Databases and retention policies that are not declared
+// are never dropped.
+func (s *Server) provisionDatabases() error {
+	for _, db := range s.config.Databases {
+		if _, err := s.MetaClient.CreateDatabase(db.Name); err != nil {
+			return fmt.Errorf("database %s: %s", db.Name, err)
+		}
+
+		for _, rp := range db.RetentionPolicies {
+			if err := s.provisionRetentionPolicy(db.Name, rp, rp.Name == db.DefaultRetentionPolicy); err != nil {
+				return fmt.Errorf("retention policy %s on %s: %s", rp.Name, db.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// provisionRetentionPolicy ensures the retention policy rp of database
+// exists and matches its config.
+func (s *Server) provisionRetentionPolicy(database string, rp RetentionPolicyConfig, makeDefault bool) error {
+	duration := time.Duration(rp.Duration)
+	sgDuration := time.Duration(rp.ShardGroupDuration)
+
+	rpi, err := s.MetaClient.RetentionPolicy(database, rp.Name)
+	if err != nil {
+		return err
+	} else if rpi == nil {
+		spec := &meta.RetentionPolicySpec{
+			Name:               rp.Name,
+			Duration:           &duration,
+			ShardGroupDuration: sgDuration,
+		}
+		if _, err := s.MetaClient.CreateRetentionPolicy(database, spec, makeDefault); err != nil {
+			return err
+		}
+		s.Logger.Info(fmt.Sprintf("created retention policy %s on database %s", rp.Name, database))
+		return nil
+	}
+
+	var rpu meta.RetentionPolicyUpdate
+	changed := false
+	if rpi.Duration != duration {
+		rpu.SetDuration(duration)
+		changed = true
+	}
+	if sgDuration != 0 && rpi.ShardGroupDuration != sgDuration {
+		rpu.SetShardGroupDuration(sgDuration)
+		changed = true
+	}
+	if makeDefault {
+		dbi := s.MetaClient.Database(database)
+		changed = changed || dbi == nil || dbi.DefaultRetentionPolicy != rp.Name
+	}
+	if !changed {
+		return nil
+	}
+
+	if err := s.MetaClient.UpdateRetentionPolicy(database, rp.Name, &rpu, makeDefault); err != nil {
+		return err
+	}
+	s.Logger.Info(fmt.Sprintf("updated retention policy %s on database %s", rp.Name, database))
+	return nil
+}
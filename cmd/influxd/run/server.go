@@ -3,7 +3,6 @@ package run
 import (
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"os"
 	"path/filepath"
@@ -16,24 +15,32 @@ import (
 	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/monitor"
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/pkg/systemd"
 	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/services/audit"
 	"github.com/influxdata/influxdb/services/collectd"
 	"github.com/influxdata/influxdb/services/continuous_querier"
 	"github.com/influxdata/influxdb/services/graphite"
 	"github.com/influxdata/influxdb/services/httpd"
+	"github.com/influxdata/influxdb/services/ldap"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/services/opentsdb"
 	"github.com/influxdata/influxdb/services/precreator"
+	"github.com/influxdata/influxdb/services/replication"
 	"github.com/influxdata/influxdb/services/retention"
 	"github.com/influxdata/influxdb/services/snapshotter"
 	"github.com/influxdata/influxdb/services/subscriber"
 	"github.com/influxdata/influxdb/services/udp"
+	"github.com/influxdata/influxdb/services/zipkin"
 	"github.com/influxdata/influxdb/tcp"
 	"github.com/influxdata/influxdb/tsdb"
 	client "github.com/influxdata/usage-client/v1"
 	"go.uber.org/zap"
 
-	// Initialize the engine & index packages
+	// Initialize the write hook, engine & index packages
+	_ "github.com/influxdata/influxdb/coordinator/hooks"
+	"github.com/influxdata/influxdb/services/rpc"
 	"github.com/influxdata/influxdb/services/storage"
 	_ "github.com/influxdata/influxdb/tsdb/engine"
 	_ "github.com/influxdata/influxdb/tsdb/index"
@@ -79,6 +86,9 @@ type Server struct {
 	// These references are required for the tcp muxer.
 	SnapshotterService *snapshotter.Service
 
+	// httpdService is opened before the store, to report on its opening.
+	httpdService *httpd.Service
+
 	Monitor *monitor.Monitor
 
 	// Server reporting and registration
@@ -164,19 +174,56 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 
 	s.TSDBStore = tsdb.NewStore(c.Data.Dir)
 	s.TSDBStore.EngineOptions.Config = c.Data
+	s.Monitor.RegisterDiagnosticsClient("disk", s.TSDBStore)
 
 	// Copy TSDB configuration.
 	s.TSDBStore.EngineOptions.EngineVersion = c.Data.Engine
 	s.TSDBStore.EngineOptions.IndexVersion = c.Data.Index
 
+	// Only count writes by measurement when the statistics are stored.
+	if c.Monitor.StoreEnabled {
+		s.TSDBStore.TopMeasurementsN = c.Monitor.TopMeasurements
+	}
+
 	// Create the Subscriber service
 	s.Subscriber = subscriber.NewService(c.Subscriber)
 
 	// Initialize points writer.
 	s.PointsWriter = coordinator.NewPointsWriter()
 	s.PointsWriter.WriteTimeout = time.Duration(c.Coordinator.WriteTimeout)
+	s.PointsWriter.MaxFutureWrite = time.Duration(c.Coordinator.MaxFutureWrite)
+	s.PointsWriter.MaxPastWrite = time.Duration(c.Coordinator.MaxPastWrite)
 	s.PointsWriter.TSDBStore = s.TSDBStore
 
+	var recentSeries *coordinator.RecentSeries
+	if d := time.Duration(c.Coordinator.RecentSeriesWindow); d > 0 {
+		recentSeries = coordinator.NewRecentSeries(d)
+		s.PointsWriter.RecentSeries = recentSeries
+	}
+
+	var resultCache *coordinator.ResultCache
+	if d := time.Duration(c.Coordinator.ResultCacheTTL); d > 0 {
+		resultCache = coordinator.NewResultCache(d, c.Coordinator.ResultCacheEntries, int64(c.Coordinator.ResultCacheMaxSize))
+		s.PointsWriter.ResultCache = resultCache
+	}
+
+	for _, hc := range c.Coordinator.WriteHooks {
+		h, err := coordinator.NewWriteHook(hc)
+		if err != nil {
+			return nil, err
+		}
+		s.PointsWriter.WriteHooks = append(s.PointsWriter.WriteHooks, h)
+	}
+
+	var history *coordinator.MetaHistory
+	if d := time.Duration(c.Coordinator.MetaHistoryRetention); d > 0 {
+		history, err = coordinator.OpenMetaHistory(filepath.Join(c.Meta.Dir, "history.json"), d)
+		if err != nil {
+			return nil, fmt.Errorf("open metadata history: %s", err)
+		}
+		s.Monitor.RegisterDiagnosticsClient("meta-history", history)
+	}
+
 	// Initialize query executor.
 	s.QueryExecutor = query.NewQueryExecutor()
 	s.QueryExecutor.StatementExecutor = &coordinator.StatementExecutor{
@@ -192,10 +239,24 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 		MaxSelectPointN:   c.Coordinator.MaxSelectPointN,
 		MaxSelectSeriesN:  c.Coordinator.MaxSelectSeriesN,
 		MaxSelectBucketsN: c.Coordinator.MaxSelectBucketsN,
+		RecentSeries:      recentSeries,
+		MetaHistory:       history,
+		ResultCache:       resultCache,
+
+		MaxUnboundedSelectSize: int64(c.Coordinator.UnboundedSelectSize),
+		UnboundedSelectRange:   time.Duration(c.Coordinator.UnboundedSelectRange),
+
+		WarnSelectSeriesN: c.Coordinator.WarnSelectSeriesN,
+		WarnSelectBlocksN: c.Coordinator.WarnSelectBlocksN,
 	}
 	s.QueryExecutor.TaskManager.QueryTimeout = time.Duration(c.Coordinator.QueryTimeout)
 	s.QueryExecutor.TaskManager.LogQueriesAfter = time.Duration(c.Coordinator.LogQueriesAfter)
+	s.QueryExecutor.TaskManager.MaxSlowQueries = c.Coordinator.MaxSlowQueries
+	s.Monitor.RegisterDiagnosticsClient("slow-queries", diagnostics.ClientFunc(s.QueryExecutor.TaskManager.SlowQueryDiagnostics))
 	s.QueryExecutor.TaskManager.MaxConcurrentQueries = c.Coordinator.MaxConcurrentQueries
+	if c.Monitor.StoreEnabled && c.Monitor.QueryAuditSampleRate > 0 {
+		s.QueryExecutor.TaskManager.Auditor = s.Monitor
+	}
 
 	// Initialize the monitor
 	s.Monitor.Version = s.buildInfo.Version
@@ -246,6 +307,7 @@ func (s *Server) appendRetentionPolicyService(c retention.Config) {
 	srv := retention.NewService(c)
 	srv.MetaClient = s.MetaClient
 	srv.TSDBStore = s.TSDBStore
+	srv.Monitor = s.Monitor
 	s.Services = append(s.Services, srv)
 }
 
@@ -257,12 +319,30 @@ func (s *Server) appendHTTPDService(c httpd.Config) {
 	srv.Handler.MetaClient = s.MetaClient
 	srv.Handler.QueryAuthorizer = meta.NewQueryAuthorizer(s.MetaClient)
 	srv.Handler.WriteAuthorizer = meta.NewWriteAuthorizer(s.MetaClient)
+	if s.config.LDAP.Enabled {
+		a := ldap.NewAuthenticator(s.config.LDAP)
+		a.MetaClient = s.MetaClient
+		a.WriteAuthorizer = srv.Handler.WriteAuthorizer
+		srv.Handler.Authenticator = a
+		srv.Handler.WriteAuthorizer = a
+		s.Services = append(s.Services, a)
+	}
+	if s.config.Audit.Enabled {
+		l := audit.NewLog(s.config.Audit)
+		srv.Handler.AuditLog = l
+		s.Services = append(s.Services, l)
+	}
 	srv.Handler.QueryExecutor = s.QueryExecutor
 	srv.Handler.Monitor = s.Monitor
 	srv.Handler.PointsWriter = s.PointsWriter
 	srv.Handler.Version = s.buildInfo.Version
 	srv.Handler.BuildType = "OSS"
+	srv.Handler.HealthChecks = []httpd.HealthCheck{
+		{Name: "meta", Check: s.MetaClient.Ready},
+		{Name: "data", Check: s.TSDBStore.Ready},
+	}
 
+	s.httpdService = srv
 	s.Services = append(s.Services, srv)
 }
 
@@ -276,6 +356,22 @@ func (s *Server) appendStorageService(c storage.Config) {
 	s.Services = append(s.Services, srv)
 }
 
+func (s *Server) appendRPCService(c rpc.Config) {
+	if !c.Enabled {
+		return
+	}
+	srv := rpc.NewService(c)
+	srv.MetaClient = s.MetaClient
+	if s.httpdService != nil {
+		srv.HTTPHandler = s.httpdService.Handler
+		srv.AuditLog = s.httpdService.Handler.AuditLog
+	}
+	srv.QueryExecutor = s.QueryExecutor
+	srv.PointsWriter = s.PointsWriter
+
+	s.Services = append(s.Services, srv)
+}
+
 func (s *Server) appendCollectdService(c collectd.Config) {
 	if !c.Enabled {
 		return
@@ -344,6 +440,25 @@ func (s *Server) appendContinuousQueryService(c continuous_querier.Config) {
 	srv.MetaClient = s.MetaClient
 	srv.QueryExecutor = s.QueryExecutor
 	srv.Monitor = s.Monitor
+	s.Monitor.RegisterDiagnosticsClient("cq-status", diagnostics.ClientFunc(srv.StatusDiagnostics))
+	s.Services = append(s.Services, srv)
+}
+
+func (s *Server) appendReplicationService(c replication.Config) {
+	if !c.Enabled {
+		return
+	}
+	srv := replication.NewService(c)
+	s.PointsWriter.Replicator = srv
+	s.Services = append(s.Services, srv)
+}
+
+func (s *Server) appendZipkinService(c zipkin.Config) {
+	if !c.Enabled {
+		return
+	}
+	srv := zipkin.NewService(c)
+	s.QueryExecutor.StatementExecutor.(*coordinator.StatementExecutor).TraceExporter = srv
 	s.Services = append(s.Services, srv)
 }
 
@@ -353,10 +468,12 @@ func (s *Server) Err() <-chan error { return s.err }
 // Open opens the meta and data store and all services.
 func (s *Server) Open() error {
 	// Start profiling, if set.
-	startProfile(s.CPUProfile, s.MemProfile)
+	if err := s.startProfile(); err != nil {
+		return err
+	}
 
 	// Open shared TCP connection.
-	ln, err := net.Listen("tcp", s.BindAddress)
+	ln, err := systemd.Listen("tcp", s.BindAddress)
 	if err != nil {
 		return fmt.Errorf("listen: %s", err)
 	}
@@ -373,6 +490,7 @@ func (s *Server) Open() error {
 	s.appendContinuousQueryService(s.config.ContinuousQuery)
 	s.appendHTTPDService(s.config.HTTPD)
 	s.appendStorageService(s.config.Storage)
+	s.appendRPCService(s.config.RPC)
 	s.appendRetentionPolicyService(s.config.Retention)
 	for _, i := range s.config.GraphiteInputs {
 		if err := s.appendGraphiteService(i); err != nil {
@@ -390,6 +508,8 @@ func (s *Server) Open() error {
 	for _, i := range s.config.UDPInputs {
 		s.appendUDPService(i)
 	}
+	s.appendReplicationService(s.config.Replication)
+	s.appendZipkinService(s.config.Tracing)
 
 	s.Subscriber.MetaClient = s.MetaClient
 	s.PointsWriter.MetaClient = s.MetaClient
@@ -413,6 +533,22 @@ func (s *Server) Open() error {
 	s.SnapshotterService.WithLogger(s.Logger)
 	s.Monitor.WithLogger(s.Logger)
 
+	// Create the databases declared in the config before the services that
+	// write to them are opened.
+	if err := s.provisionDatabases(); err != nil {
+		return fmt.Errorf("provision databases: %s", err)
+	}
+
+	// Open the HTTP service before the store, so /health tells how far
+	// opening the shards has got. Other requests fail until the server is
+	// open.
+	if s.httpdService != nil {
+		s.httpdService.Handler.SetStarting(true)
+		if err := s.httpdService.Open(); err != nil {
+			return fmt.Errorf("open service: %s", err)
+		}
+	}
+
 	// Open TSDB store.
 	if err := s.TSDBStore.Open(); err != nil {
 		return fmt.Errorf("open tsdb store: %s", err)
@@ -431,10 +567,16 @@ func (s *Server) Open() error {
 	s.PointsWriter.AddWriteSubscriber(s.Subscriber.Points())
 
 	for _, service := range s.Services {
+		if service == Service(s.httpdService) {
+			continue
+		}
 		if err := service.Open(); err != nil {
 			return fmt.Errorf("open service: %s", err)
 		}
 	}
+	if s.httpdService != nil {
+		s.httpdService.Handler.SetStarting(false)
+	}
 
 	// Start the reporting service, if not disabled.
 	if !s.reportingDisabled {
@@ -446,7 +588,7 @@ func (s *Server) Open() error {
 
 // Close shuts down the meta and data stores and all services.
 func (s *Server) Close() error {
-	stopProfile()
+	s.stopProfile()
 
 	// Close the listener first to stop any new connections
 	if s.Listener != nil {
@@ -460,6 +602,9 @@ func (s *Server) Close() error {
 	}
 
 	s.config.deregisterDiagnostics(s.Monitor)
+	s.Monitor.DeregisterDiagnosticsClient("disk")
+	s.Monitor.DeregisterDiagnosticsClient("slow-queries")
+	s.Monitor.DeregisterDiagnosticsClient("meta-history")
 
 	if s.PointsWriter != nil {
 		s.PointsWriter.Close()
@@ -567,41 +712,43 @@ var prof struct {
 	mem *os.File
 }
 
-// StartProfile initializes the cpu and memory profile, if specified.
-func startProfile(cpuprofile, memprofile string) {
-	if cpuprofile != "" {
-		f, err := os.Create(cpuprofile)
+// startProfile initializes the cpu and memory profile, if specified.
+func (s *Server) startProfile() error {
+	if s.CPUProfile != "" {
+		f, err := os.Create(s.CPUProfile)
 		if err != nil {
-			log.Fatalf("cpuprofile: %v", err)
+			return fmt.Errorf("cpuprofile: %v", err)
 		}
-		log.Printf("writing CPU profile to: %s\n", cpuprofile)
+		s.Logger.Info(fmt.Sprintf("writing CPU profile to: %s", s.CPUProfile))
 		prof.cpu = f
 		pprof.StartCPUProfile(prof.cpu)
 	}
 
-	if memprofile != "" {
-		f, err := os.Create(memprofile)
+	if s.MemProfile != "" {
+		f, err := os.Create(s.MemProfile)
 		if err != nil {
-			log.Fatalf("memprofile: %v", err)
+			return fmt.Errorf("memprofile: %v", err)
 		}
-		log.Printf("writing mem profile to: %s\n", memprofile)
+		s.Logger.Info(fmt.Sprintf("writing mem profile to: %s", s.MemProfile))
 		prof.mem = f
 		runtime.MemProfileRate = 4096
 	}
-
+	return nil
 }
 
-// StopProfile closes the cpu and memory profiles if they are running.
-func stopProfile() {
+// stopProfile closes the cpu and memory profiles if they are running.
+func (s *Server) stopProfile() {
 	if prof.cpu != nil {
 		pprof.StopCPUProfile()
 		prof.cpu.Close()
-		log.Println("CPU profile stopped")
+		prof.cpu = nil
+		s.Logger.Info("CPU profile stopped")
 	}
 	if prof.mem != nil {
 		pprof.Lookup("heap").WriteTo(prof.mem, 0)
 		prof.mem.Close()
-		log.Println("mem profile stopped")
+		prof.mem = nil
+		s.Logger.Info("mem profile stopped")
 	}
 }
 
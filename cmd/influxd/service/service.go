@@ -0,0 +1,125 @@
+// Package service is the service subcommand of the influxd command, which
+// runs influxd as a Windows service.
+package service
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultName is the default name the service is installed under.
+	DefaultName = "influxdb"
+
+	// displayName is the name services.msc shows the service under.
+	displayName = "InfluxDB"
+
+	// description is the description of the service in services.msc.
+	description = "InfluxDB time series database"
+
+	// stopTimeout is the time the server has to shut down cleanly once the
+	// service is stopped, as when stopping influxd with a signal.
+	stopTimeout = 30 * time.Second
+)
+
+// errUnsupported is returned by the actions of the command outside Windows.
+var errUnsupported = errors.New("services are only supported on Windows")
+
+// Command represents the command executed by "influxd service".
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// NewCommand returns a new instance of Command.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run executes the action given in args on the service.
+func (cmd *Command) Run(args ...string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(cmd.Stderr, usage)
+		return errors.New("missing action")
+	}
+	action, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet(action, flag.ContinueOnError)
+	name := fs.String("name", DefaultName, "")
+	configPath := fs.String("config", "", "")
+	fs.SetOutput(cmd.Stderr)
+	fs.Usage = func() { fmt.Fprintln(cmd.Stderr, usage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch action {
+	case "install":
+		// The service is started from another directory, so the paths it
+		// is started with must be absolute.
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		runArgs := []string{"run"}
+		if *configPath != "" {
+			path, err := filepath.Abs(*configPath)
+			if err != nil {
+				return err
+			}
+			runArgs = append(runArgs, "-config", path)
+		}
+		if err := install(*name, exe, runArgs); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.Stdout, "Installed service %s.\n", *name)
+	case "uninstall":
+		if err := uninstall(*name); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.Stdout, "Uninstalled service %s.\n", *name)
+	case "start":
+		if err := start(*name); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.Stdout, "Started service %s.\n", *name)
+	case "stop":
+		if err := stop(*name); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.Stdout, "Stopped service %s.\n", *name)
+	default:
+		fmt.Fprintln(cmd.Stderr, usage)
+		return fmt.Errorf("unknown action %q", action)
+	}
+	return nil
+}
+
+const usage = `Manages influxd as a Windows service.
+
+Usage: influxd service <action> [flags]
+
+The actions are:
+
+    install              install influxd as a service started at boot
+    uninstall            remove the service
+    start                start the service
+    stop                 stop the service, shutting the server down cleanly
+
+    -name <name>
+            The name of the service. Defaults to "influxdb".
+    -config <path>
+            The configuration file the service runs with, for install.
+            Defaults to the default configuration path.
+
+The service logs to the Windows event log, under the name of the service.
+`
@@ -0,0 +1,16 @@
+// +build !windows
+
+package service
+
+import "github.com/influxdata/influxdb/cmd/influxd/run"
+
+// IsService returns false, influxd only runs as a service on Windows.
+func IsService() bool { return false }
+
+// Serve returns an error, influxd only runs as a service on Windows.
+func Serve(cmd *run.Command, args []string) error { return errUnsupported }
+
+func install(name, exe string, args []string) error { return errUnsupported }
+func uninstall(name string) error                   { return errUnsupported }
+func start(name string) error                       { return errUnsupported }
+func stop(name string) error                        { return errUnsupported }
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd/influxd/run"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// IsService returns true if influxd was started by the service control
+// manager rather than from a console.
+func IsService() bool {
+	interactive, err := svc.IsAnInteractiveSession()
+	return err == nil && !interactive
+}
+
+// Serve runs cmd with args as a service, until the service control manager
+// stops it. The server logs to the event log rather than to stderr, which
+// services don't have.
+func Serve(cmd *run.Command, args []string) error {
+	// The name is only used by processes running several services.
+	return svc.Run(DefaultName, &handler{cmd: cmd, args: args})
+}
+
+// handler runs the server on behalf of the service control manager.
+type handler struct {
+	cmd  *run.Command
+	args []string
+}
+
+// Execute opens the server and closes it once the service is stopped, or
+// the system shuts down.
+func (h *handler) Execute(args []string, r <-chan svc.ChangeRequest, s chan<- svc.Status) (bool, uint32) {
+	s <- svc.Status{State: svc.StartPending}
+
+	// The first argument is the name the service was installed under, which
+	// is the source of its events.
+	elog, err := eventlog.Open(args[0])
+	if err != nil {
+		return true, 1
+	}
+	defer elog.Close()
+
+	h.cmd.Stdout = ioutil.Discard
+	h.cmd.Stderr = &eventLogWriter{log: elog}
+	if err := h.cmd.Run(h.args...); err != nil {
+		elog.Error(1, fmt.Sprintf("run: %s", err))
+		return true, 1
+	}
+	s <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for c := range r {
+		switch c.Cmd {
+		case svc.Interrogate:
+			s <- c.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			s <- svc.Status{State: svc.StopPending, WaitHint: uint32(stopTimeout / time.Millisecond)}
+			go h.cmd.Close()
+
+			select {
+			case <-h.cmd.Closed:
+			case <-time.After(stopTimeout):
+				elog.Warning(1, "time limit reached, stopping before server shutdown completed")
+			}
+			return false, 0
+		}
+	}
+	return false, 0
+}
+
+// eventLogWriter writes each line logged to the event log.
+type eventLogWriter struct {
+	log *eventlog.Log
+}
+
+func (w *eventLogWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if err := w.log.Info(1, line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func install(name, exe string, args []string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(name); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s already exists", name)
+	}
+
+	s, err := m.CreateService(name, exe, mgr.Config{
+		DisplayName: displayName,
+		Description: description,
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if err := eventlog.InstallAsEventCreate(name, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		s.Delete()
+		return fmt.Errorf("install event log source: %s", err)
+	}
+	return nil
+}
+
+func uninstall(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed", name)
+	}
+	defer s.Close()
+
+	if err := s.Delete(); err != nil {
+		return err
+	}
+	return eventlog.Remove(name)
+}
+
+func start(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed", name)
+	}
+	defer s.Close()
+
+	return s.Start()
+}
+
+// stop stops the service and waits for the server to shut down.
+func stop(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed", name)
+	}
+	defer s.Close()
+
+	status, err := s.Control(svc.Stop)
+	if err != nil {
+		return err
+	}
+
+	// Wait a little longer than the server has to shut down.
+	timeout := time.Now().Add(stopTimeout + 5*time.Second)
+	for status.State != svc.Stopped {
+		if time.Now().After(timeout) {
+			return fmt.Errorf("timed out waiting for service %s to stop", name)
+		}
+		time.Sleep(300 * time.Millisecond)
+
+		if status, err = s.Query(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@
 package coordinator
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/influxdata/influxdb/monitor/diagnostics"
@@ -18,6 +19,14 @@ const (
 	// A value of zero will make the maximum query limit unlimited.
 	DefaultMaxConcurrentQueries = 0
 
+	// DefaultMaxSlowQueries is the number of the most recent slow queries
+	// kept in memory.
+	DefaultMaxSlowQueries = 100
+
+	// DefaultMetaHistoryRetention is how long changes to the metadata are
+	// kept in the metadata history.
+	DefaultMetaHistoryRetention = 7 * 24 * time.Hour
+
 	// DefaultMaxSelectPointN is the maximum number of points a SELECT can process.
 	// A value of zero will make the maximum point count unlimited.
 	DefaultMaxSelectPointN = 0
@@ -25,6 +34,22 @@ const (
 	// DefaultMaxSelectSeriesN is the maximum number of series a SELECT can run.
 	// A value of zero will make the maximum series count unlimited.
 	DefaultMaxSelectSeriesN = 0
+
+	// DefaultWarnSelectSeriesN is the number of series above which a SELECT
+	// gets a warning. A value of zero disables the warning.
+	DefaultWarnSelectSeriesN = 0
+
+	// DefaultWarnSelectBlocksN is the number of TSM blocks above which a
+	// SELECT gets a warning. A value of zero disables the warning.
+	DefaultWarnSelectBlocksN = 0
+
+	// DefaultResultCacheEntries is the maximum number of SELECT results kept
+	// in the result cache.
+	DefaultResultCacheEntries = 1000
+
+	// DefaultResultCacheMaxSize is the maximum memory, in bytes, used by the
+	// results kept in the result cache.
+	DefaultResultCacheMaxSize = 64 * 1024 * 1024
 )
 
 // Config represents the configuration for the coordinator service.
@@ -33,9 +58,54 @@ type Config struct {
 	MaxConcurrentQueries int           `toml:"max-concurrent-queries"`
 	QueryTimeout         toml.Duration `toml:"query-timeout"`
 	LogQueriesAfter      toml.Duration `toml:"log-queries-after"`
+	MaxSlowQueries       int           `toml:"max-slow-queries"`
+	MetaHistoryRetention toml.Duration `toml:"meta-history-retention"`
 	MaxSelectPointN      int           `toml:"max-select-point"`
 	MaxSelectSeriesN     int           `toml:"max-select-series"`
 	MaxSelectBucketsN    int           `toml:"max-select-buckets"`
+	RecentSeriesWindow   toml.Duration `toml:"recent-series-window"`
+
+	// UnboundedSelectSize is the maximum number of bytes a SELECT without a
+	// lower time bound can read. A SELECT that would read more is bounded
+	// to UnboundedSelectRange before its end time, or rejected if
+	// UnboundedSelectRange is zero. A value of zero disables the limit.
+	UnboundedSelectSize  toml.Size     `toml:"unbounded-select-size"`
+	UnboundedSelectRange toml.Duration `toml:"unbounded-select-range"`
+
+	// WarnSelectSeriesN and WarnSelectBlocksN are the number of series and
+	// TSM blocks above which a SELECT is still run, but gets a warning in
+	// its results. A value of zero disables the warning.
+	WarnSelectSeriesN int `toml:"warn-select-series"`
+	WarnSelectBlocksN int `toml:"warn-select-blocks"`
+
+	// ResultCacheTTL is how long the results of SELECT statements are kept
+	// to answer the same statements again. A value of zero disables the
+	// cache.
+	ResultCacheTTL     toml.Duration `toml:"result-cache-ttl"`
+	ResultCacheEntries int           `toml:"result-cache-entries"`
+	ResultCacheMaxSize toml.Size     `toml:"result-cache-max-size"`
+
+	// MaxFutureWrite and MaxPastWrite reject the points whose time is more
+	// than this far ahead of or behind the current time, so points with bad
+	// timestamps do not create shard groups far from the others. A value of
+	// zero disables the limit.
+	MaxFutureWrite toml.Duration `toml:"max-future-write"`
+	MaxPastWrite   toml.Duration `toml:"max-past-write"`
+
+	// WriteHooks transform points before they are stored, in order.
+	WriteHooks []WriteHookConfig `toml:"write-hook"`
+}
+
+// WriteHookConfig selects a registered write hook.
+type WriteHookConfig struct {
+	Name string `toml:"name"`
+
+	// Databases limits the hook to writes to these databases. The hook
+	// applies to all databases when it is empty.
+	Databases []string `toml:"databases"`
+
+	// Options are passed to the hook when it is created.
+	Options map[string]string `toml:"options"`
 }
 
 // NewConfig returns an instance of Config with defaults.
@@ -44,9 +114,44 @@ func NewConfig() Config {
 		WriteTimeout:         toml.Duration(DefaultWriteTimeout),
 		QueryTimeout:         toml.Duration(query.DefaultQueryTimeout),
 		MaxConcurrentQueries: DefaultMaxConcurrentQueries,
+		MaxSlowQueries:       DefaultMaxSlowQueries,
+		MetaHistoryRetention: toml.Duration(DefaultMetaHistoryRetention),
 		MaxSelectPointN:      DefaultMaxSelectPointN,
 		MaxSelectSeriesN:     DefaultMaxSelectSeriesN,
+		ResultCacheEntries:   DefaultResultCacheEntries,
+		ResultCacheMaxSize:   DefaultResultCacheMaxSize,
+	}
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	if c.MaxSlowQueries < 0 {
+		return fmt.Errorf("max-slow-queries must be non-negative")
+	} else if c.MetaHistoryRetention < 0 {
+		return fmt.Errorf("meta-history-retention must be non-negative")
+	} else if c.UnboundedSelectRange < 0 {
+		return fmt.Errorf("unbounded-select-range must be non-negative")
+	} else if c.WarnSelectSeriesN < 0 {
+		return fmt.Errorf("warn-select-series must be non-negative")
+	} else if c.WarnSelectBlocksN < 0 {
+		return fmt.Errorf("warn-select-blocks must be non-negative")
+	} else if c.ResultCacheTTL < 0 {
+		return fmt.Errorf("result-cache-ttl must be non-negative")
+	} else if c.ResultCacheTTL > 0 && c.ResultCacheEntries <= 0 {
+		return fmt.Errorf("result-cache-entries must be positive")
+	} else if c.ResultCacheTTL > 0 && c.ResultCacheMaxSize == 0 {
+		return fmt.Errorf("result-cache-max-size must be positive")
+	} else if c.MaxFutureWrite < 0 {
+		return fmt.Errorf("max-future-write must be non-negative")
+	} else if c.MaxPastWrite < 0 {
+		return fmt.Errorf("max-past-write must be non-negative")
+	}
+	for _, h := range c.WriteHooks {
+		if _, ok := newWriteHookFuncs[h.Name]; !ok {
+			return fmt.Errorf("unknown write hook %q, registered hooks are %v", h.Name, RegisteredWriteHooks())
+		}
 	}
+	return nil
 }
 
 // Diagnostics returns a diagnostics representation of a subset of the Config.
@@ -56,8 +161,21 @@ func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
 		"max-concurrent-queries": c.MaxConcurrentQueries,
 		"query-timeout":          c.QueryTimeout,
 		"log-queries-after":      c.LogQueriesAfter,
+		"max-slow-queries":       c.MaxSlowQueries,
+		"meta-history-retention": c.MetaHistoryRetention,
 		"max-select-point":       c.MaxSelectPointN,
 		"max-select-series":      c.MaxSelectSeriesN,
 		"max-select-buckets":     c.MaxSelectBucketsN,
+		"recent-series-window":   c.RecentSeriesWindow,
+		"write-hooks":            len(c.WriteHooks),
+		"unbounded-select-size":  c.UnboundedSelectSize,
+		"unbounded-select-range": c.UnboundedSelectRange,
+		"result-cache-ttl":       c.ResultCacheTTL,
+		"result-cache-entries":   c.ResultCacheEntries,
+		"result-cache-max-size":  c.ResultCacheMaxSize,
+		"max-future-write":       c.MaxFutureWrite,
+		"max-past-write":         c.MaxPastWrite,
+		"warn-select-series":     c.WarnSelectSeriesN,
+		"warn-select-blocks":     c.WarnSelectBlocksN,
 	}), nil
 }
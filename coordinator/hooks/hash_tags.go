@@ -0,0 +1,71 @@
+// Package hooks contains the write hooks compiled into influxd. Hooks are
+// enabled with a [[coordinator.write-hook]] section naming them.
+package hooks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/influxdata/influxdb/coordinator"
+	"github.com/influxdata/influxdb/models"
+)
+
+func init() {
+	coordinator.RegisterWriteHook("hash-tags", NewHashTags)
+}
+
+// HashTags replaces the values of some tags with a salted SHA-256 hash, so
+// series stay distinct and can be grouped by without storing values such as
+// user names or addresses.
+type HashTags struct {
+	keys [][]byte
+	salt []byte
+}
+
+// NewHashTags returns a HashTags hook. The "tags" option is a comma
+// separated list of the tag keys to hash and the optional "salt" option is
+// prepended to every value before it is hashed.
+func NewHashTags(options map[string]string) (coordinator.WriteHook, error) {
+	h := &HashTags{salt: []byte(options["salt"])}
+	for _, k := range strings.Split(options["tags"], ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			h.keys = append(h.keys, []byte(k))
+		}
+	}
+	if len(h.keys) == 0 {
+		return nil, errors.New("the tags option must list at least one tag key")
+	}
+	return h, nil
+}
+
+// WritePoints hashes the configured tags of points in place.
+func (h *HashTags) WritePoints(database, retentionPolicy string, points []models.Point) ([]models.Point, error) {
+	for _, p := range points {
+		var tags models.Tags
+		for _, k := range h.keys {
+			if !p.HasTag(k) {
+				continue
+			}
+			if tags == nil {
+				tags = p.Tags().Clone()
+			}
+			tags.Set(k, h.hash(tags.Get(k)))
+		}
+		if tags != nil {
+			p.SetTags(tags)
+		}
+	}
+	return points, nil
+}
+
+func (h *HashTags) hash(v []byte) []byte {
+	sum := sha256.New()
+	sum.Write(h.salt)
+	sum.Write(v)
+
+	dst := make([]byte, hex.EncodedLen(sha256.Size))
+	hex.Encode(dst, sum.Sum(nil))
+	return dst
+}
@@ -0,0 +1,66 @@
+package hooks_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/coordinator"
+	"github.com/influxdata/influxdb/coordinator/hooks"
+	"github.com/influxdata/influxdb/models"
+)
+
+func TestHashTags_WritePoints(t *testing.T) {
+	h, err := hooks.NewHashTags(map[string]string{"tags": "user, email", "salt": "pepper"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(0, 0)
+	points := []models.Point{
+		models.MustNewPoint("login", models.NewTags(map[string]string{"user": "alice", "host": "a"}), models.Fields{"value": 1.0}, now),
+		models.MustNewPoint("login", models.NewTags(map[string]string{"user": "bob", "host": "a"}), models.Fields{"value": 1.0}, now),
+		models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "a"}), models.Fields{"value": 1.0}, now),
+	}
+
+	points, err = h.WritePoints("db0", "rp0", points)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(points) != 3 {
+		t.Fatalf("unexpected points: %d", len(points))
+	}
+
+	alice, bob := points[0].Tags().GetString("user"), points[1].Tags().GetString("user")
+	if len(alice) != 64 || strings.Contains(points[0].String(), "alice") {
+		t.Fatalf("expected user to be hashed: %s", points[0])
+	} else if alice == bob {
+		t.Fatal("expected distinct hashes")
+	} else if v := points[0].Tags().GetString("host"); v != "a" {
+		t.Fatalf("unexpected host: %s", v)
+	} else if s := points[2].String(); s != "cpu,host=a value=1 0" {
+		t.Fatalf("unexpected point: %s", s)
+	}
+}
+
+func TestHashTags_Registered(t *testing.T) {
+	if _, err := coordinator.NewWriteHook(coordinator.WriteHookConfig{Name: "hash-tags"}); err == nil {
+		t.Fatal("expected error without tags option")
+	}
+
+	h, err := coordinator.NewWriteHook(coordinator.WriteHookConfig{
+		Name:      "hash-tags",
+		Databases: []string{"db0"},
+		Options:   map[string]string{"tags": "user"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Writes to other databases are left untouched.
+	pt := models.MustNewPoint("login", models.NewTags(map[string]string{"user": "alice"}), models.Fields{"value": 1.0}, time.Unix(0, 0))
+	if points, err := h.WritePoints("db1", "rp0", []models.Point{pt}); err != nil {
+		t.Fatal(err)
+	} else if v := points[0].Tags().GetString("user"); v != "alice" {
+		t.Fatalf("unexpected user: %s", v)
+	}
+}
@@ -0,0 +1,193 @@
+package coordinator
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxql"
+)
+
+// MetaChange is a statement that changed the metadata.
+type MetaChange struct {
+	Time      time.Time `json:"time"`
+	User      string    `json:"user,omitempty"`
+	Database  string    `json:"database,omitempty"`
+	Statement string    `json:"statement"`
+}
+
+// MetaHistory keeps the changes made to the metadata within a retention
+// period, such as who created or dropped which database, retention policy or
+// user. The changes are appended to a file, one JSON object per line, so the
+// history survives restarts.
+type MetaHistory struct {
+	mu        sync.Mutex
+	path      string
+	retention time.Duration
+	changes   []MetaChange
+
+	now func() time.Time
+}
+
+// OpenMetaHistory returns the history stored at path, dropping the changes
+// older than retention.
+func OpenMetaHistory(path string, retention time.Duration) (*MetaHistory, error) {
+	h := &MetaHistory{
+		path:      path,
+		retention: retention,
+		now:       time.Now,
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var c MetaChange
+		// Skip a line cut short by a crash while it was written.
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			continue
+		}
+		h.changes = append(h.changes, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.expire(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Add records a statement executed by user.
+func (h *MetaHistory) Add(user, database string, stmt influxql.Statement) error {
+	c := MetaChange{
+		Time:      h.now().UTC(),
+		User:      user,
+		Database:  database,
+		Statement: influxql.Sanitize(stmt.String()),
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.changes = append(h.changes, c)
+	if err := h.expire(); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// expire drops the changes older than the retention period. The file is
+// rewritten at most once a day, so the expired changes are dropped in
+// buckets of a day instead of on every change.
+func (h *MetaHistory) expire() error {
+	if len(h.changes) == 0 || h.changes[0].Time.After(h.now().Add(-h.retention-24*time.Hour)) {
+		return nil
+	}
+
+	cutoff := h.now().Add(-h.retention)
+	i := 0
+	for i < len(h.changes) && h.changes[i].Time.Before(cutoff) {
+		i++
+	}
+	h.changes = append([]MetaChange(nil), h.changes[i:]...)
+
+	tmp := h.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, c := range h.changes {
+		if err := enc.Encode(c); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	} else if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, h.path)
+}
+
+// Changes returns the changes within the retention period, oldest first.
+func (h *MetaHistory) Changes() []MetaChange {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cutoff := h.now().Add(-h.retention)
+	changes := make([]MetaChange, 0, len(h.changes))
+	for _, c := range h.changes {
+		if !c.Time.Before(cutoff) {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// Diagnostics returns the changes as diagnostics, for SHOW DIAGNOSTICS.
+func (h *MetaHistory) Diagnostics() (*diagnostics.Diagnostics, error) {
+	d := diagnostics.NewDiagnostics([]string{"time", "user", "database", "statement"})
+	for _, c := range h.Changes() {
+		d.AddRow([]interface{}{c.Time.Format(time.RFC3339Nano), c.User, c.Database, c.Statement})
+	}
+	return d, nil
+}
+
+// isMetaChange returns true if stmt changes the metadata.
+func isMetaChange(stmt influxql.Statement) bool {
+	switch stmt.(type) {
+	case *influxql.AlterRetentionPolicyStatement,
+		*influxql.CreateContinuousQueryStatement,
+		*influxql.CreateDatabaseStatement,
+		*influxql.CreateRetentionPolicyStatement,
+		*influxql.CreateSubscriptionStatement,
+		*influxql.CreateUserStatement,
+		*influxql.DeleteSeriesStatement,
+		*influxql.DropContinuousQueryStatement,
+		*influxql.DropDatabaseStatement,
+		*influxql.DropMeasurementStatement,
+		*influxql.DropSeriesStatement,
+		*influxql.DropRetentionPolicyStatement,
+		*influxql.DropShardStatement,
+		*influxql.DropSubscriptionStatement,
+		*influxql.DropUserStatement,
+		*influxql.GrantStatement,
+		*influxql.GrantAdminStatement,
+		*influxql.RevokeStatement,
+		*influxql.RevokeAdminStatement,
+		*influxql.SetPasswordUserStatement:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package coordinator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxql"
+)
+
+func TestMetaHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meta-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "history.json")
+
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	h, err := OpenMetaHistory(path, 7*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.now = func() time.Time { return now }
+
+	if err := h.Add("alice", "", &influxql.CreateDatabaseStatement{Name: "db0"}); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(24 * time.Hour)
+	if err := h.Add("", "db0", &influxql.CreateUserStatement{Name: "bob", Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The history is read back from the file.
+	h, err = OpenMetaHistory(path, 7*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.now = func() time.Time { return now }
+	changes := h.Changes()
+	if len(changes) != 2 {
+		t.Fatalf("unexpected changes: %v", changes)
+	} else if c := changes[0]; c.User != "alice" || c.Statement != "CREATE DATABASE db0" || !c.Time.Equal(now.Add(-24*time.Hour)) {
+		t.Fatalf("unexpected change: %+v", c)
+	} else if c := changes[1]; c.Database != "db0" || c.Statement != "CREATE USER bob WITH PASSWORD [REDACTED]" {
+		t.Fatalf("unexpected change: %+v", c)
+	}
+
+	// Changes older than the retention period are dropped.
+	now = now.Add(7 * 24 * time.Hour)
+	if changes := h.Changes(); len(changes) != 1 || changes[0].User != "" {
+		t.Fatalf("unexpected changes: %v", changes)
+	}
+}
@@ -1,14 +1,17 @@
 package coordinator
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/tsdb"
@@ -58,8 +61,29 @@ type PointsWriter struct {
 	TSDBStore interface {
 		CreateShard(database, retentionPolicy string, shardID uint64, enabled bool) error
 		WriteToShard(shardID uint64, points []models.Point) error
+		ValidateToShard(shardID uint64, points []models.Point) error
 	}
 
+	// RecentSeries, if set, records the series of every successful write.
+	RecentSeries *RecentSeries
+
+	// ResultCache, if set, drops the cached results that a write changes.
+	ResultCache *ResultCache
+
+	// Replicator, if set, replicates the points written to each shard. A
+	// write returns once the points are replicated.
+	Replicator interface {
+		ReplicatePoints(database, retentionPolicy string, points []models.Point)
+	}
+
+	// WriteHooks transform points before they are stored, in order.
+	WriteHooks []WriteHook
+
+	// MaxFutureWrite and MaxPastWrite, if set, reject the points more than
+	// this far ahead of or behind the current time.
+	MaxFutureWrite time.Duration
+	MaxPastWrite   time.Duration
+
 	subPoints []chan<- *WritePointsRequest
 
 	stats *WriteStatistics
@@ -99,6 +123,10 @@ type ShardMapping struct {
 	Points  map[uint64][]models.Point  // The points associated with a shard ID
 	Shards  map[uint64]*meta.ShardInfo // The shards that have been mapped, keyed by shard ID
 	Dropped []models.Point             // Points that were dropped
+
+	// Rejected are the points outside the time window allowed by
+	// max-past-write and max-future-write. They are not part of Dropped.
+	Rejected []models.Point
 }
 
 // NewShardMapping creates an empty ShardMapping.
@@ -197,15 +225,16 @@ func (w *PointsWriter) MapShards(wp *WritePointsRequest) (*ShardMapping, error)
 
 	// Holds all the shard groups and shards that are required for writes.
 	list := make(sgList, 0, 8)
+	now := time.Now()
 	min := time.Unix(0, models.MinNanoTime)
 	if rp.Duration > 0 {
-		min = time.Now().Add(-rp.Duration)
+		min = now.Add(-rp.Duration)
 	}
 
 	for _, p := range wp.Points {
 		// Either the point is outside the scope of the RP, or we already have
 		// a suitable shard group for the point.
-		if p.Time().Before(min) || list.Covers(p.Time()) {
+		if p.Time().Before(min) || w.rejectTime(p.Time(), now) || list.Covers(p.Time()) {
 			continue
 		}
 
@@ -224,6 +253,12 @@ func (w *PointsWriter) MapShards(wp *WritePointsRequest) (*ShardMapping, error)
 
 	mapping := NewShardMapping(len(wp.Points))
 	for _, p := range wp.Points {
+		if w.rejectTime(p.Time(), now) {
+			mapping.Rejected = append(mapping.Rejected, p)
+			atomic.AddInt64(&w.stats.WriteDropped, 1)
+			continue
+		}
+
 		sg := list.ShardGroupAt(p.Time())
 		if sg == nil {
 			// We didn't create a shard group because the point was outside the
@@ -239,6 +274,15 @@ func (w *PointsWriter) MapShards(wp *WritePointsRequest) (*ShardMapping, error)
 	return mapping, nil
 }
 
+// rejectTime returns true if a point at t is outside the time window allowed
+// by MaxPastWrite and MaxFutureWrite.
+func (w *PointsWriter) rejectTime(t, now time.Time) bool {
+	if w.MaxFutureWrite > 0 && t.After(now.Add(w.MaxFutureWrite)) {
+		return true
+	}
+	return w.MaxPastWrite > 0 && t.Before(now.Add(-w.MaxPastWrite))
+}
+
 // sgList is a wrapper around a meta.ShardGroupInfos where we can also check
 // if a given time is covered by any of the shard groups in the list.
 type sgList meta.ShardGroupInfos
@@ -276,6 +320,74 @@ func (l sgList) Append(sgi meta.ShardGroupInfo) sgList {
 	return next
 }
 
+// ValidatePoints returns the error writing points would return, without
+// writing them or creating shard groups. The points are checked against the
+// fields of the shards they would be written to. Points in a time range
+// without a shard group are only checked against the retention policy and
+// the write time window, since they would be written to a new shard.
+func (w *PointsWriter) ValidatePoints(database, retentionPolicy string, points []models.Point) error {
+	db := w.MetaClient.Database(database)
+	if db == nil {
+		return influxdb.ErrDatabaseNotFound(database)
+	} else if retentionPolicy == "" {
+		retentionPolicy = db.DefaultRetentionPolicy
+	}
+	rp, err := w.MetaClient.RetentionPolicy(database, retentionPolicy)
+	if err != nil {
+		return err
+	} else if rp == nil {
+		return influxdb.ErrRetentionPolicyNotFound(retentionPolicy)
+	}
+
+	for _, h := range w.WriteHooks {
+		if points, err = h.WritePoints(database, retentionPolicy, points); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	min := time.Unix(0, models.MinNanoTime)
+	if rp.Duration > 0 {
+		min = now.Add(-rp.Duration)
+	}
+
+	var dropped, rejected int
+	shards := make(map[uint64][]models.Point)
+	for _, p := range points {
+		if w.rejectTime(p.Time(), now) {
+			rejected++
+		} else if p.Time().Before(min) {
+			dropped++
+		} else if sg := rp.ShardGroupByTimestamp(p.Time()); sg != nil {
+			sh := sg.ShardFor(p.HashID())
+			shards[sh.ID] = append(shards[sh.ID], p)
+		}
+	}
+
+	var reasons []string
+	if dropped > 0 {
+		reasons = append(reasons, "points beyond retention policy")
+	}
+	if rejected > 0 {
+		reasons = append(reasons, "points outside max-past-write and max-future-write")
+		dropped += rejected
+	}
+	for id, points := range shards {
+		err := w.TSDBStore.ValidateToShard(id, points)
+		if perr, ok := err.(tsdb.PartialWriteError); ok {
+			reasons = append(reasons, perr.Reason)
+			dropped += perr.Dropped
+		} else if err != nil && err != tsdb.ErrShardNotFound {
+			return err
+		}
+	}
+
+	if dropped > 0 {
+		return tsdb.PartialWriteError{Reason: strings.Join(reasons, "; "), Dropped: dropped}
+	}
+	return nil
+}
+
 // WritePointsInto is a copy of WritePoints that uses a tsdb structure instead of
 // a cluster structure for information. This is to avoid a circular dependency.
 func (w *PointsWriter) WritePointsInto(p *IntoWriteRequest) error {
@@ -284,11 +396,21 @@ func (w *PointsWriter) WritePointsInto(p *IntoWriteRequest) error {
 
 // WritePoints writes the data to the underlying storage. consitencyLevel and user are only used for clustered scenarios
 func (w *PointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error {
-	return w.WritePointsPrivileged(database, retentionPolicy, consistencyLevel, points)
+	return w.writePoints(context.Background(), database, retentionPolicy, consistencyLevel, points)
+}
+
+// WritePointsWithContext is like WritePoints, and adds the request ID of ctx
+// to the log lines of the write.
+func (w *PointsWriter) WritePointsWithContext(ctx context.Context, database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error {
+	return w.writePoints(ctx, database, retentionPolicy, consistencyLevel, points)
 }
 
 // WritePointsPrivileged writes the data to the underlying storage, consitencyLevel is only used for clustered scenarios
 func (w *PointsWriter) WritePointsPrivileged(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	return w.writePoints(context.Background(), database, retentionPolicy, consistencyLevel, points)
+}
+
+func (w *PointsWriter) writePoints(ctx context.Context, database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
 	atomic.AddInt64(&w.stats.WriteReq, 1)
 	atomic.AddInt64(&w.stats.PointWriteReq, int64(len(points)))
 
@@ -300,16 +422,38 @@ func (w *PointsWriter) WritePointsPrivileged(database, retentionPolicy string, c
 		retentionPolicy = db.DefaultRetentionPolicy
 	}
 
+	for _, h := range w.WriteHooks {
+		var err error
+		if points, err = h.WritePoints(database, retentionPolicy, points); err != nil {
+			return err
+		}
+	}
+	if len(points) == 0 {
+		return nil
+	}
+
 	shardMappings, err := w.MapShards(&WritePointsRequest{Database: database, RetentionPolicy: retentionPolicy, Points: points})
 	if err != nil {
 		return err
 	}
 
 	// Write each shard in it's own goroutine and return as soon as one fails.
+	log := logger.WithRequestID(w.Logger, logger.RequestIDFromContext(ctx))
 	ch := make(chan error, len(shardMappings.Points))
 	for shardID, points := range shardMappings.Points {
 		go func(shard *meta.ShardInfo, database, retentionPolicy string, points []models.Point) {
-			ch <- w.writeToShard(shard, database, retentionPolicy, points)
+			err := w.writeToShard(log, shard, database, retentionPolicy, points)
+			if err == nil && w.RecentSeries != nil {
+				w.RecentSeries.Add(database, points)
+			}
+			if err == nil && w.Replicator != nil {
+				w.Replicator.ReplicatePoints(database, retentionPolicy, points)
+			}
+			// Some points may have been stored even if the write failed.
+			if w.ResultCache != nil {
+				w.ResultCache.Invalidate(database, points)
+			}
+			ch <- err
 		}(shardMappings.Shards[shardID], database, retentionPolicy, points)
 	}
 
@@ -340,6 +484,13 @@ func (w *PointsWriter) WritePointsPrivileged(database, retentionPolicy string, c
 		err = tsdb.PartialWriteError{Reason: "points beyond retention policy", Dropped: len(shardMappings.Dropped)}
 
 	}
+	if err == nil && len(shardMappings.Rejected) > 0 {
+		err = tsdb.PartialWriteError{Reason: "points outside max-past-write and max-future-write", Dropped: len(shardMappings.Rejected)}
+	} else if perr, ok := err.(tsdb.PartialWriteError); ok && len(shardMappings.Rejected) > 0 {
+		perr.Reason += " or outside max-past-write and max-future-write"
+		perr.Dropped += len(shardMappings.Rejected)
+		err = perr
+	}
 	timeout := time.NewTimer(w.WriteTimeout)
 	defer timeout.Stop()
 	for range shardMappings.Points {
@@ -359,8 +510,8 @@ func (w *PointsWriter) WritePointsPrivileged(database, retentionPolicy string, c
 	return err
 }
 
-// writeToShards writes points to a shard.
-func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPolicy string, points []models.Point) error {
+// writeToShards writes points to a shard, logging failures to log.
+func (w *PointsWriter) writeToShard(log *zap.Logger, shard *meta.ShardInfo, database, retentionPolicy string, points []models.Point) error {
 	atomic.AddInt64(&w.stats.PointWriteReqLocal, int64(len(points)))
 
 	err := w.TSDBStore.WriteToShard(shard.ID, points)
@@ -380,7 +531,7 @@ func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPo
 	if err == tsdb.ErrShardNotFound {
 		err = w.TSDBStore.CreateShard(database, retentionPolicy, shard.ID, true)
 		if err != nil {
-			w.Logger.Info(fmt.Sprintf("write failed for shard %d: %v", shard.ID, err))
+			log.Info(fmt.Sprintf("write failed for shard %d: %v", shard.ID, err))
 
 			atomic.AddInt64(&w.stats.WriteErr, 1)
 			return err
@@ -388,7 +539,7 @@ func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPo
 	}
 	err = w.TSDBStore.WriteToShard(shard.ID, points)
 	if err != nil {
-		w.Logger.Info(fmt.Sprintf("write failed for shard %d: %v", shard.ID, err))
+		log.Info(fmt.Sprintf("write failed for shard %d: %v", shard.ID, err))
 		atomic.AddInt64(&w.stats.WriteErr, 1)
 		return err
 	}
@@ -1,6 +1,7 @@
 package coordinator_test
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"sync"
@@ -244,6 +245,56 @@ func TestPointsWriter_MapShards_Invalid(t *testing.T) {
 	}
 }
 
+// Ensures the points writer rejects points outside max-past-write and
+// max-future-write without creating shard groups for them.
+func TestPointsWriter_MapShards_WriteWindow(t *testing.T) {
+	ms := PointsWriterMetaClient{}
+	rp := NewRetentionPolicy("myp", 0, 3)
+
+	ms.RetentionPolicyFn = func(db, retentionPolicy string) (*meta.RetentionPolicyInfo, error) {
+		return rp, nil
+	}
+
+	var created int
+	ms.CreateShardGroupIfNotExistsFn = func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+		created++
+		return &meta.ShardGroupInfo{
+			ID:        uint64(created),
+			StartTime: timestamp.Truncate(time.Hour),
+			EndTime:   timestamp.Truncate(time.Hour).Add(time.Hour),
+			Shards:    []meta.ShardInfo{{ID: uint64(created)}},
+		}, nil
+	}
+
+	c := coordinator.NewPointsWriter()
+	c.MetaClient = ms
+	c.MaxFutureWrite = time.Hour
+	c.MaxPastWrite = 24 * time.Hour
+	defer c.Close()
+	pr := &coordinator.WritePointsRequest{
+		Database:        "mydb",
+		RetentionPolicy: "myrp",
+	}
+
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+	pr.AddPoint("cpu", 2.0, time.Unix(0, 0), nil)
+	pr.AddPoint("cpu", 3.0, time.Now().Add(100*365*24*time.Hour), nil)
+
+	shardMappings, err := c.MapShards(pr)
+	if err != nil {
+		t.Fatalf("unexpected an error: %v", err)
+	}
+
+	if got, exp := created, 1; got != exp {
+		t.Errorf("created shard groups mismatch: got %v, exp %v", got, exp)
+	}
+	if got, exp := len(shardMappings.Rejected), 2; got != exp {
+		t.Fatalf("MapShards() rejected mismatch: got %v, exp %v", got, exp)
+	} else if got, exp := len(shardMappings.Dropped), 0; got != exp {
+		t.Fatalf("MapShards() dropped mismatch: got %v, exp %v", got, exp)
+	}
+}
+
 func TestPointsWriter_WritePoints(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -428,6 +479,113 @@ func TestPointsWriter_WritePoints_Dropped(t *testing.T) {
 	}
 }
 
+// Ensure write hooks run in order and the points they drop are not stored.
+func TestPointsWriter_WritePoints_WriteHooks(t *testing.T) {
+	pr := &coordinator.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+	pr.AddPoint("secret", 2.0, time.Now(), nil)
+
+	var mu sync.Mutex
+	var written []string
+	store := &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, p := range points {
+				written = append(written, string(p.Name()))
+			}
+			return nil
+		},
+	}
+
+	c := coordinator.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.TSDBStore = store
+	c.WriteHooks = []coordinator.WriteHook{
+		WriteHookFunc(func(database, retentionPolicy string, points []models.Point) ([]models.Point, error) {
+			var a []models.Point
+			for _, p := range points {
+				if string(p.Name()) != "secret" {
+					a = append(a, p)
+				}
+			}
+			return a, nil
+		}),
+		WriteHookFunc(func(database, retentionPolicy string, points []models.Point) ([]models.Point, error) {
+			if len(points) != 1 {
+				t.Fatalf("unexpected points passed to second hook: %d", len(points))
+			}
+			return points, nil
+		}),
+	}
+	c.Open()
+	defer c.Close()
+
+	if err := c.WritePointsPrivileged(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(written, []string{"cpu"}) {
+		t.Fatalf("unexpected points written: %v", written)
+	}
+
+	// A hook error fails the write.
+	c.WriteHooks = []coordinator.WriteHook{
+		WriteHookFunc(func(database, retentionPolicy string, points []models.Point) ([]models.Point, error) {
+			return nil, errors.New("rejected")
+		}),
+	}
+	if err := c.WritePointsPrivileged(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err == nil || err.Error() != "rejected" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure only the points written to the local shards are replicated.
+func TestPointsWriter_WritePoints_Replicator(t *testing.T) {
+	pr := &coordinator.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+
+	var writeErr error
+	c := coordinator.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error { return writeErr },
+	}
+	var replicated []string
+	c.Replicator = ReplicatorFunc(func(database, retentionPolicy string, points []models.Point) {
+		for _, p := range points {
+			replicated = append(replicated, database+"."+retentionPolicy+"."+string(p.Name()))
+		}
+	})
+	c.Open()
+	defer c.Close()
+
+	if err := c.WritePointsPrivileged(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(replicated, []string{"mydb.myrp.cpu"}) {
+		t.Fatalf("unexpected points replicated: %v", replicated)
+	}
+
+	writeErr = errors.New("disk full")
+	if err := c.WritePointsPrivileged(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err != writeErr {
+		t.Fatalf("unexpected error: %v", err)
+	} else if len(replicated) != 1 {
+		t.Fatalf("unexpected points replicated: %v", replicated)
+	}
+}
+
+// ReplicatorFunc is a replicator implemented by a function.
+type ReplicatorFunc func(database, retentionPolicy string, points []models.Point)
+
+func (fn ReplicatorFunc) ReplicatePoints(database, retentionPolicy string, points []models.Point) {
+	fn(database, retentionPolicy, points)
+}
+
+// WriteHookFunc is a write hook implemented by a function.
+type WriteHookFunc func(database, retentionPolicy string, points []models.Point) ([]models.Point, error)
+
+func (fn WriteHookFunc) WritePoints(database, retentionPolicy string, points []models.Point) ([]models.Point, error) {
+	return fn(database, retentionPolicy, points)
+}
+
 type fakePointsWriter struct {
 	WritePointsIntoFn func(*coordinator.IntoWriteRequest) error
 }
@@ -551,12 +709,17 @@ var shardID uint64
 type fakeStore struct {
 	WriteFn       func(shardID uint64, points []models.Point) error
 	CreateShardfn func(database, retentionPolicy string, shardID uint64, enabled bool) error
+	ValidateFn    func(shardID uint64, points []models.Point) error
 }
 
 func (f *fakeStore) WriteToShard(shardID uint64, points []models.Point) error {
 	return f.WriteFn(shardID, points)
 }
 
+func (f *fakeStore) ValidateToShard(shardID uint64, points []models.Point) error {
+	return f.ValidateFn(shardID, points)
+}
+
 func (f *fakeStore) CreateShard(database, retentionPolicy string, shardID uint64, enabled bool) error {
 	return f.CreateShardfn(database, retentionPolicy, shardID, enabled)
 }
@@ -0,0 +1,282 @@
+package coordinator
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cespare/xxhash"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/escape"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxql"
+)
+
+// recentSeriesPartitions is the number of partitions the series of a
+// database are spread over, so concurrent writes to a database rarely wait
+// on each other.
+const recentSeriesPartitions = 16
+
+// RecentSeries is a rolling window index of the series written to each
+// database. It records the latest point time seen for every series so that
+// SHOW TAG KEYS and SHOW TAG VALUES statements bounded by a recent lower time
+// limit can be answered without walking the shard indexes.
+type RecentSeries struct {
+	mu     sync.RWMutex
+	window time.Duration
+	dbs    map[string]*recentDatabase
+
+	// started is the time the index was created. Databases that have not
+	// been written to since are covered from this time.
+	started time.Time
+
+	// lastPrune is the last time expired series were removed, in
+	// nanoseconds. It is accessed atomically.
+	lastPrune int64
+
+	now func() time.Time
+}
+
+// recentDatabase holds the recently written series for a single database.
+type recentDatabase struct {
+	// since is the time the database started being tracked. Only writes
+	// that happened after this time are present in the index.
+	since      time.Time
+	partitions [recentSeriesPartitions]recentPartition
+}
+
+// recentPartition holds the latest point time of a part of the series of a
+// database, by series key. The tags are parsed from the key when queried, so
+// writes only copy the key of new series.
+type recentPartition struct {
+	mu     sync.RWMutex
+	series map[string]int64
+}
+
+func newRecentDatabase(since time.Time) *recentDatabase {
+	db := &recentDatabase{since: since}
+	for i := range db.partitions {
+		db.partitions[i].series = make(map[string]int64)
+	}
+	return db
+}
+
+// partition returns the partition holding the series with key.
+func (db *recentDatabase) partition(key []byte) *recentPartition {
+	return &db.partitions[xxhash.Sum64(key)%recentSeriesPartitions]
+}
+
+// NewRecentSeries returns a new RecentSeries index that retains series which
+// received a point within window.
+func NewRecentSeries(window time.Duration) *RecentSeries {
+	r := &RecentSeries{
+		window: window,
+		dbs:    make(map[string]*recentDatabase),
+		now:    time.Now,
+	}
+	r.started = r.now()
+	r.lastPrune = r.started.UnixNano()
+	return r
+}
+
+// database returns the series of database, creating them if needed.
+func (r *RecentSeries) database(database string) *recentDatabase {
+	r.mu.RLock()
+	db := r.dbs[database]
+	r.mu.RUnlock()
+	if db != nil {
+		return db
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if db = r.dbs[database]; db == nil {
+		db = newRecentDatabase(r.started)
+		r.dbs[database] = db
+	}
+	return db
+}
+
+// Add records points written to database.
+func (r *RecentSeries) Add(database string, points []models.Point) {
+	now := r.now()
+	db := r.database(database)
+
+	for _, p := range points {
+		t, key := p.UnixNano(), p.Key()
+		part := db.partition(key)
+
+		part.mu.Lock()
+		if latest, ok := part.series[string(key)]; !ok || t > latest {
+			part.series[string(key)] = t
+		}
+		part.mu.Unlock()
+	}
+
+	last := atomic.LoadInt64(&r.lastPrune)
+	if now.UnixNano()-last >= int64(r.window) && atomic.CompareAndSwapInt64(&r.lastPrune, last, now.UnixNano()) {
+		r.prune(now)
+	}
+}
+
+// prune removes all series without a point inside the window.
+func (r *RecentSeries) prune(now time.Time) {
+	min := now.Add(-r.window).UnixNano()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, db := range r.dbs {
+		for i := range db.partitions {
+			part := &db.partitions[i]
+			part.mu.Lock()
+			for key, latest := range part.series {
+				if latest < min {
+					delete(part.series, key)
+				}
+			}
+			part.mu.Unlock()
+		}
+	}
+}
+
+// Reset removes all series for database. Only writes received after the reset
+// are used to answer lookups for the database again.
+func (r *RecentSeries) Reset(database string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dbs[database] = newRecentDatabase(r.now())
+}
+
+// Covers returns true if every series in database that has a point at or
+// after min is present in the index.
+func (r *RecentSeries) Covers(database string, min time.Time) bool {
+	now := r.now()
+	if min.Before(now.Add(-r.window)) {
+		return false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	since := r.started
+	if db := r.dbs[database]; db != nil {
+		since = db.since
+	}
+	return !min.Before(since)
+}
+
+// forEach calls fn with the name and tags of each series in database with a
+// point at or after min that auth may read.
+func (r *RecentSeries) forEach(auth query.Authorizer, database string, min time.Time, fn func(name string, tags models.Tags)) {
+	tmin := min.UnixNano()
+
+	r.mu.RLock()
+	db := r.dbs[database]
+	r.mu.RUnlock()
+	if db == nil {
+		return
+	}
+
+	for i := range db.partitions {
+		part := &db.partitions[i]
+		part.mu.RLock()
+		for key, latest := range part.series {
+			if latest < tmin {
+				continue
+			}
+			name, tags := models.ParseKeyBytes([]byte(key))
+			name = escape.Unescape(name)
+			if auth != nil && !auth.AuthorizeSeriesRead(database, name, tags) {
+				continue
+			}
+			fn(string(name), tags)
+		}
+		part.mu.RUnlock()
+	}
+}
+
+// TagKeys returns the tag keys of the series in database with a point at or
+// after min that match cond. The condition is evaluated in the same form as
+// the one passed to tsdb.Store.TagKeys.
+func (r *RecentSeries) TagKeys(auth query.Authorizer, database string, min time.Time, cond influxql.Expr) []tsdb.TagKeys {
+	keys := make(map[string]map[string]struct{})
+	m := make(map[string]interface{})
+	r.forEach(auth, database, min, func(name string, tags models.Tags) {
+		seriesValuer(m, name, tags)
+		for _, t := range tags {
+			m["_tagKey"] = string(t.Key)
+			if cond != nil && !influxql.EvalBool(cond, m) {
+				continue
+			}
+
+			set := keys[name]
+			if set == nil {
+				set = make(map[string]struct{})
+				keys[name] = set
+			}
+			set[string(t.Key)] = struct{}{}
+		}
+	})
+
+	a := make([]tsdb.TagKeys, 0, len(keys))
+	for name, set := range keys {
+		ks := make([]string, 0, len(set))
+		for k := range set {
+			ks = append(ks, k)
+		}
+		sort.Strings(ks)
+		a = append(a, tsdb.TagKeys{Measurement: name, Keys: ks})
+	}
+	sort.Slice(a, func(i, j int) bool { return a[i].Measurement < a[j].Measurement })
+	return a
+}
+
+// seriesValuer resets m to the measurement name and tags of a series, for
+// evaluating conditions.
+func seriesValuer(m map[string]interface{}, name string, tags models.Tags) {
+	for k := range m {
+		delete(m, k)
+	}
+	m["_name"] = name
+	for _, t := range tags {
+		m[string(t.Key)] = string(t.Value)
+	}
+}
+
+// TagValues returns the tag values of the series in database with a point at
+// or after min that match cond. The condition is evaluated in the same form
+// as the one passed to tsdb.Store.TagValues.
+func (r *RecentSeries) TagValues(auth query.Authorizer, database string, min time.Time, cond influxql.Expr) []tsdb.TagValues {
+	values := make(map[string]map[tsdb.KeyValue]struct{})
+	m := make(map[string]interface{})
+	r.forEach(auth, database, min, func(name string, tags models.Tags) {
+		seriesValuer(m, name, tags)
+		for _, t := range tags {
+			m["_tagKey"] = string(t.Key)
+			if cond != nil && !influxql.EvalBool(cond, m) {
+				continue
+			}
+
+			set := values[name]
+			if set == nil {
+				set = make(map[tsdb.KeyValue]struct{})
+				values[name] = set
+			}
+			set[tsdb.KeyValue{Key: string(t.Key), Value: string(t.Value)}] = struct{}{}
+		}
+	})
+
+	a := make([]tsdb.TagValues, 0, len(values))
+	for name, set := range values {
+		kvs := make(tsdb.KeyValues, 0, len(set))
+		for kv := range set {
+			kvs = append(kvs, kv)
+		}
+		sort.Sort(kvs)
+		a = append(a, tsdb.TagValues{Measurement: name, Values: kvs})
+	}
+	sort.Sort(tsdb.TagValuesSlice(a))
+	return a
+}
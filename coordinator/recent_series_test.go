@@ -0,0 +1,131 @@
+package coordinator_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/coordinator"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxql"
+)
+
+func TestRecentSeries_TagValues(t *testing.T) {
+	now := time.Now()
+	r := coordinator.NewRecentSeries(time.Hour)
+	r.Add("db0", []models.Point{
+		models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "serverA", "region": "east"}), models.Fields{"value": 1.0}, now),
+		models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "serverB", "region": "west"}), models.Fields{"value": 1.0}, now.Add(-30*time.Minute)),
+		models.MustNewPoint("mem", models.NewTags(map[string]string{"host": "serverC"}), models.Fields{"value": 1.0}, now),
+	})
+	r.Add("db1", []models.Point{
+		models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "serverD"}), models.Fields{"value": 1.0}, now),
+	})
+
+	for _, tt := range []struct {
+		cond string
+		min  time.Time
+		exp  []tsdb.TagValues
+	}{
+		{
+			cond: `_tagKey = 'host'`,
+			min:  now.Add(-time.Hour),
+			exp: []tsdb.TagValues{
+				{Measurement: "cpu", Values: []tsdb.KeyValue{{Key: "host", Value: "serverA"}, {Key: "host", Value: "serverB"}}},
+				{Measurement: "mem", Values: []tsdb.KeyValue{{Key: "host", Value: "serverC"}}},
+			},
+		},
+		{
+			cond: `_tagKey = 'host'`,
+			min:  now.Add(-10 * time.Minute),
+			exp: []tsdb.TagValues{
+				{Measurement: "cpu", Values: []tsdb.KeyValue{{Key: "host", Value: "serverA"}}},
+				{Measurement: "mem", Values: []tsdb.KeyValue{{Key: "host", Value: "serverC"}}},
+			},
+		},
+		{
+			cond: `_name = 'cpu' AND _tagKey =~ /.*/ AND region = 'west'`,
+			min:  now.Add(-time.Hour),
+			exp: []tsdb.TagValues{
+				{Measurement: "cpu", Values: []tsdb.KeyValue{{Key: "host", Value: "serverB"}, {Key: "region", Value: "west"}}},
+			},
+		},
+	} {
+		cond := influxql.MustParseExpr(tt.cond)
+		if got := r.TagValues(nil, "db0", tt.min, cond); !reflect.DeepEqual(got, tt.exp) {
+			t.Errorf("%s: unexpected tag values:\n\ngot=%#v\n\nexp=%#v", tt.cond, got, tt.exp)
+		}
+	}
+}
+
+func TestRecentSeries_TagKeys(t *testing.T) {
+	now := time.Now()
+	r := coordinator.NewRecentSeries(time.Hour)
+	r.Add("db0", []models.Point{
+		models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "serverA", "region": "east"}), models.Fields{"value": 1.0}, now),
+		models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "serverB", "zone": "b"}), models.Fields{"value": 1.0}, now.Add(-30*time.Minute)),
+		models.MustNewPoint("m em", models.NewTags(map[string]string{"host": "serverC"}), models.Fields{"value": 1.0}, now),
+	})
+
+	for _, tt := range []struct {
+		cond string
+		min  time.Time
+		exp  []tsdb.TagKeys
+	}{
+		{
+			min: now.Add(-time.Hour),
+			exp: []tsdb.TagKeys{
+				{Measurement: "cpu", Keys: []string{"host", "region", "zone"}},
+				{Measurement: "m em", Keys: []string{"host"}},
+			},
+		},
+		{
+			min: now.Add(-10 * time.Minute),
+			exp: []tsdb.TagKeys{
+				{Measurement: "cpu", Keys: []string{"host", "region"}},
+				{Measurement: "m em", Keys: []string{"host"}},
+			},
+		},
+		{
+			cond: `_name = 'cpu' AND host = 'serverB'`,
+			min:  now.Add(-time.Hour),
+			exp: []tsdb.TagKeys{
+				{Measurement: "cpu", Keys: []string{"host", "zone"}},
+			},
+		},
+	} {
+		var cond influxql.Expr
+		if tt.cond != "" {
+			cond = influxql.MustParseExpr(tt.cond)
+		}
+		if got := r.TagKeys(nil, "db0", tt.min, cond); !reflect.DeepEqual(got, tt.exp) {
+			t.Errorf("%s: unexpected tag keys:\n\ngot=%#v\n\nexp=%#v", tt.cond, got, tt.exp)
+		}
+	}
+}
+
+func TestRecentSeries_Covers(t *testing.T) {
+	r := coordinator.NewRecentSeries(time.Hour)
+	now := time.Now()
+
+	// Writes from before the index was created are not known.
+	if r.Covers("db0", now.Add(-time.Minute)) {
+		t.Fatal("expected time before creation to not be covered")
+	} else if !r.Covers("db0", now.Add(time.Second)) {
+		t.Fatal("expected time after creation to be covered")
+	}
+
+	// Dropping data resets the database.
+	r.Add("db0", []models.Point{
+		models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "serverA"}), models.Fields{"value": 1.0}, now),
+	})
+	r.Reset("db0")
+	if got := r.TagValues(nil, "db0", now.Add(-time.Minute), influxql.MustParseExpr(`_tagKey = 'host'`)); len(got) != 0 {
+		t.Fatalf("unexpected tag values after reset: %#v", got)
+	} else if r.Covers("db0", now) {
+		t.Fatal("expected time before reset to not be covered")
+	} else if !r.Covers("db1", now.Add(time.Second)) {
+		t.Fatal("expected reset to only affect db0")
+	}
+}
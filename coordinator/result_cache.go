@@ -0,0 +1,262 @@
+package coordinator
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxql"
+)
+
+// ResultCache keeps the results of SELECT statements for a short time, so a
+// dashboard refreshing the same query every few seconds does not read the
+// same shards again. Time is divided in buckets of the TTL and a result is
+// only reused within the bucket it was computed in. A write to a database
+// drops the results of the statements reading the time range of its points.
+type ResultCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	maxSize    int64
+	size       int64 // estimated memory used by the results of entries
+	entries    map[resultCacheKey]*resultCacheEntry
+
+	// pending are the entries of the statements being executed. A write
+	// marks them stale so results computed before the write are not stored.
+	pending map[*resultCacheEntry]struct{}
+
+	now func() time.Time
+}
+
+// resultCacheKey identifies the results of a statement. The chunk size is
+// part of the key because it changes how the rows are split in results.
+type resultCacheKey struct {
+	stmt      string
+	chunkSize int
+	bucket    int64
+}
+
+// resultCacheEntry is the results of a statement and the data they cover.
+type resultCacheEntry struct {
+	key       resultCacheKey
+	databases map[string]struct{}
+	min, max  int64
+	results   []*query.Result
+	size      int64
+	stale     bool
+
+	// tooLarge is set once the results do not fit in the cache. They are
+	// not kept from then on.
+	tooLarge bool
+}
+
+// NewResultCache returns a new ResultCache that keeps at most maxEntries
+// results, using about maxSize bytes, for up to ttl.
+func NewResultCache(ttl time.Duration, maxEntries int, maxSize int64) *ResultCache {
+	return &ResultCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		maxSize:    maxSize,
+		entries:    make(map[resultCacheKey]*resultCacheEntry),
+		pending:    make(map[*resultCacheEntry]struct{}),
+		now:        time.Now,
+	}
+}
+
+// lookup returns the cached results of stmt. If there are none, it returns
+// an entry that the results are passed to with add as they are sent, and to
+// store once the statement has been executed, or nil if the results of stmt
+// cannot be cached.
+func (c *ResultCache) lookup(stmt *influxql.SelectStatement, chunkSize int) ([]*query.Result, *resultCacheEntry) {
+	now := c.now()
+	key := resultCacheKey{
+		stmt:      stmt.String(),
+		chunkSize: chunkSize,
+		bucket:    now.UnixNano() / int64(c.ttl),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e := c.entries[key]; e != nil {
+		return e.results, nil
+	}
+
+	_, t, err := influxql.ConditionExpr(stmt.Condition, &influxql.NowValuer{Now: now, Location: stmt.Location})
+	if err != nil {
+		return nil, nil
+	}
+	e := &resultCacheEntry{
+		key:       key,
+		databases: make(map[string]struct{}),
+		min:       t.MinTimeNano(),
+		max:       t.MaxTimeNano(),
+	}
+	sourceDatabases(stmt.Sources, e.databases)
+	c.pending[e] = struct{}{}
+	return nil, e
+}
+
+// add keeps a copy of a result of the statement of e, as the result sent
+// may be changed by the receiver. Once the results are larger than the whole
+// cache they are dropped, so a large result is not held in memory until the
+// statement completes.
+func (c *ResultCache) add(e *resultCacheEntry, r *query.Result) {
+	if e.tooLarge {
+		return
+	}
+	e.size += resultSize(r)
+	if e.size > c.maxSize {
+		e.results, e.tooLarge = nil, true
+		return
+	}
+	e.results = append(e.results, copyResult(r, 0))
+}
+
+// store caches the results of the statement of e, unless the statement
+// failed or a write changed the data they cover while it was executed.
+func (c *ResultCache) store(e *resultCacheEntry, failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.pending, e)
+	if e.stale || e.tooLarge || failed {
+		return
+	}
+
+	// Drop the results of the previous buckets before giving up for lack of
+	// room, since they cannot be used anymore.
+	if len(c.entries) >= c.maxEntries || c.size+e.size > c.maxSize {
+		bucket := c.now().UnixNano() / int64(c.ttl)
+		for key, old := range c.entries {
+			if key.bucket < bucket {
+				c.remove(key, old)
+			}
+		}
+		if len(c.entries) >= c.maxEntries || c.size+e.size > c.maxSize {
+			return
+		}
+	}
+	c.entries[e.key] = e
+	c.size += e.size
+}
+
+// remove drops the entry with key. The caller must hold the lock.
+func (c *ResultCache) remove(key resultCacheKey, e *resultCacheEntry) {
+	delete(c.entries, key)
+	c.size -= e.size
+}
+
+// Invalidate drops the results that cover the time of points written to
+// database.
+func (c *ResultCache) Invalidate(database string, points []models.Point) {
+	if len(points) == 0 {
+		return
+	}
+	min, max := points[0].UnixNano(), points[0].UnixNano()
+	for _, p := range points[1:] {
+		if t := p.UnixNano(); t < min {
+			min = t
+		} else if t > max {
+			max = t
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidate(database, func(e *resultCacheEntry) bool {
+		return e.min <= max && e.max >= min
+	})
+}
+
+// Reset drops all results for database, after data has been removed from it.
+func (c *ResultCache) Reset(database string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidate(database, func(*resultCacheEntry) bool { return true })
+}
+
+// invalidate drops the results reading database for which fn returns true.
+// The caller must hold the lock.
+func (c *ResultCache) invalidate(database string, fn func(e *resultCacheEntry) bool) {
+	for key, e := range c.entries {
+		if _, ok := e.databases[database]; ok && fn(e) {
+			c.remove(key, e)
+		}
+	}
+	for e := range c.pending {
+		if _, ok := e.databases[database]; ok && fn(e) {
+			e.stale = true
+		}
+	}
+}
+
+// sourceDatabases adds the databases read by sources to m, including those
+// of subqueries.
+func sourceDatabases(sources influxql.Sources, m map[string]struct{}) {
+	for _, source := range sources {
+		switch source := source.(type) {
+		case *influxql.Measurement:
+			m[source.Database] = struct{}{}
+		case *influxql.SubQuery:
+			sourceDatabases(source.Statement.Sources, m)
+		}
+	}
+}
+
+// cacheableResults returns true if the results of a statement executed with
+// auth do not depend on the user, because every series can be read.
+func cacheableResults(auth query.Authorizer) bool {
+	switch auth.(type) {
+	case nil, *meta.UserInfo:
+		return true
+	}
+	return auth == query.OpenAuthorizer
+}
+
+// copyResult returns a copy of r for statement id that can be changed
+// without changing r, as the HTTP handler does to merge and truncate rows
+// and to convert times to epochs.
+func copyResult(r *query.Result, id int) *query.Result {
+	other := *r
+	other.StatementID = id
+	other.Series = make(models.Rows, len(r.Series))
+	for i, row := range r.Series {
+		cp := *row
+		cp.Values = make([][]interface{}, len(row.Values))
+		for j, values := range row.Values {
+			cp.Values[j] = append([]interface{}(nil), values...)
+		}
+		other.Series[i] = &cp
+	}
+	return &other
+}
+
+// resultSize estimates the memory used by the rows of r, in bytes.
+func resultSize(r *query.Result) int64 {
+	var n int64
+	for _, row := range r.Series {
+		n += int64(len(row.Name)) + 64
+		for k, v := range row.Tags {
+			n += int64(len(k) + len(v))
+		}
+		for _, c := range row.Columns {
+			n += int64(len(c)) + 16
+		}
+		for _, values := range row.Values {
+			n += 24
+			for _, v := range values {
+				n += 16
+				switch v := v.(type) {
+				case string:
+					n += int64(len(v))
+				case time.Time:
+					n += 24
+				}
+			}
+		}
+	}
+	return n
+}
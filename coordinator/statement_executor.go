@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/monitor"
 	"github.com/influxdata/influxdb/pkg/tracing"
@@ -49,17 +50,41 @@ type StatementExecutor struct {
 	// Used for rewriting points back into system for SELECT INTO statements.
 	PointsWriter pointsWriter
 
+	// RecentSeries answers SHOW TAG VALUES for recent time ranges, if set.
+	RecentSeries *RecentSeries
+
+	// MetaHistory records the statements that change the metadata, if set.
+	MetaHistory *MetaHistory
+
+	// ResultCache keeps the results of recent SELECT statements, if set.
+	ResultCache *ResultCache
+
+	// TraceExporter is given the traces of the SELECT statements it samples,
+	// if set.
+	TraceExporter interface {
+		Sample() bool
+		Export(t *tracing.Trace)
+	}
+
 	// Select statement limits
 	MaxSelectPointN   int
 	MaxSelectSeriesN  int
 	MaxSelectBucketsN int
+
+	// Limits of SELECT statements without a lower time bound.
+	MaxUnboundedSelectSize int64
+	UnboundedSelectRange   time.Duration
+
+	// Number of series and blocks above which a SELECT gets a warning.
+	WarnSelectSeriesN int
+	WarnSelectBlocksN int
 }
 
 // ExecuteStatement executes the given statement with the given execution context.
 func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement, ctx query.ExecutionContext) error {
 	// Select statements are handled separately so that they can be streamed.
 	if stmt, ok := stmt.(*influxql.SelectStatement); ok {
-		return e.executeSelectStatement(context.Background(), stmt, &ctx)
+		return e.executeSelectStatement(logger.NewContextWithRequestID(context.Background(), ctx.RequestID), stmt, &ctx)
 	}
 
 	var rows models.Rows
@@ -210,6 +235,19 @@ func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement, ctx query.
 		return err
 	}
 
+	if e.MetaHistory != nil && isMetaChange(stmt) {
+		var user string
+		if u, ok := ctx.Authorizer.(meta.User); ok {
+			user = u.ID()
+		}
+		if err := e.MetaHistory.Add(user, ctx.Database, stmt); err != nil {
+			messages = append(messages, &query.Message{
+				Level: query.WarningLevel,
+				Text:  fmt.Sprintf("metadata history not recorded: %s", err),
+			})
+		}
+	}
+
 	return ctx.Send(&query.Result{
 		StatementID: ctx.StatementID,
 		Series:      rows,
@@ -324,6 +362,7 @@ func (e *StatementExecutor) executeDeleteSeriesStatement(stmt *influxql.DeleteSe
 	stmt.Condition = influxql.Reduce(stmt.Condition, &influxql.NowValuer{Now: time.Now().UTC()})
 
 	// Locally delete the series.
+	defer e.invalidateWriteCaches(database)
 	return e.TSDBStore.DeleteSeries(database, stmt.Sources, stmt.Condition)
 }
 
@@ -343,6 +382,7 @@ func (e *StatementExecutor) executeDropDatabaseStatement(stmt *influxql.DropData
 	if err := e.TSDBStore.DeleteDatabase(stmt.Name); err != nil {
 		return err
 	}
+	e.invalidateWriteCaches(stmt.Name)
 
 	// Remove the database from the Meta Store.
 	return e.MetaClient.DropDatabase(stmt.Name)
@@ -354,6 +394,7 @@ func (e *StatementExecutor) executeDropMeasurementStatement(stmt *influxql.DropM
 	}
 
 	// Locally drop the measurement
+	defer e.invalidateWriteCaches(database)
 	return e.TSDBStore.DeleteMeasurement(database, stmt.Name)
 }
 
@@ -368,14 +409,21 @@ func (e *StatementExecutor) executeDropSeriesStatement(stmt *influxql.DropSeries
 	}
 
 	// Locally drop the series.
+	defer e.invalidateWriteCaches(database)
 	return e.TSDBStore.DeleteSeries(database, stmt.Sources, stmt.Condition)
 }
 
 func (e *StatementExecutor) executeDropShardStatement(stmt *influxql.DropShardStatement) error {
+	// Find the database of the shard before its meta data is removed.
+	database := e.shardDatabase(stmt.ID)
+
 	// Locally delete the shard.
 	if err := e.TSDBStore.DeleteShard(stmt.ID); err != nil {
 		return err
 	}
+	if database != "" {
+		e.invalidateWriteCaches(database)
+	}
 
 	// Remove the shard reference from the Meta Store.
 	return e.MetaClient.DropShard(stmt.ID)
@@ -395,10 +443,39 @@ func (e *StatementExecutor) executeDropRetentionPolicyStatement(stmt *influxql.D
 	if err := e.TSDBStore.DeleteRetentionPolicy(stmt.Database, stmt.Name); err != nil {
 		return err
 	}
+	e.invalidateWriteCaches(stmt.Database)
 
 	return e.MetaClient.DropRetentionPolicy(stmt.Database, stmt.Name)
 }
 
+// shardDatabase returns the name of the database holding a shard, or an empty
+// string if the shard is not known.
+func (e *StatementExecutor) shardDatabase(id uint64) string {
+	for _, di := range e.MetaClient.Databases() {
+		for _, rpi := range di.RetentionPolicies {
+			for _, sgi := range rpi.ShardGroups {
+				for _, si := range sgi.Shards {
+					if si.ID == id {
+						return di.Name
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// invalidateWriteCaches discards the recently written series and the cached
+// results of database after data has been removed from it.
+func (e *StatementExecutor) invalidateWriteCaches(database string) {
+	if e.RecentSeries != nil {
+		e.RecentSeries.Reset(database)
+	}
+	if e.ResultCache != nil {
+		e.ResultCache.Reset(database)
+	}
+}
+
 func (e *StatementExecutor) executeDropSubscriptionStatement(q *influxql.DropSubscriptionStatement) error {
 	return e.MetaClient.DropSubscription(q.Database, q.RetentionPolicy, q.Name)
 }
@@ -414,6 +491,9 @@ func (e *StatementExecutor) executeExplainStatement(q *influxql.ExplainStatement
 		MaxSeriesN:  e.MaxSelectSeriesN,
 		MaxBucketsN: e.MaxSelectBucketsN,
 		Authorizer:  ectx.Authorizer,
+
+		MaxUnboundedSize: e.MaxUnboundedSelectSize,
+		UnboundedRange:   e.UnboundedSelectRange,
 	}
 
 	// Prepare the query for execution, but do not actually execute it.
@@ -442,13 +522,13 @@ func (e *StatementExecutor) executeExplainStatement(q *influxql.ExplainStatement
 func (e *StatementExecutor) executeExplainAnalyzeStatement(q *influxql.ExplainStatement, ectx *query.ExecutionContext) (models.Rows, error) {
 	stmt := q.Statement
 	t, span := tracing.NewTrace("select")
-	ctx := tracing.NewContextWithTrace(context.Background(), t)
+	ctx := tracing.NewContextWithTrace(logger.NewContextWithRequestID(context.Background(), ectx.RequestID), t)
 	ctx = tracing.NewContextWithSpan(ctx, span)
 	var aux query.Iterators
 	ctx = query.NewContextWithIterators(ctx, &aux)
 	start := time.Now()
 
-	itrs, columns, err := e.createIterators(ctx, stmt, ectx)
+	itrs, columns, _, err := e.createIterators(ctx, stmt, ectx)
 	if err != nil {
 		return nil, err
 	}
@@ -486,6 +566,12 @@ func (e *StatementExecutor) executeExplainAnalyzeStatement(q *influxql.ExplainSt
 	}
 
 CLEANUP:
+	// Read the statistics before the iterators are closed. They count the
+	// points the same way max-select-point does. The points of raw queries
+	// are read by the auxiliary iterators, since the iterators of their
+	// fields have no statistics of their own.
+	stats := query.Iterators(itrs).Stats()
+	stats.Add(aux.Stats())
 	em.Close()
 	if err != nil {
 		return nil, err
@@ -499,6 +585,9 @@ CLEANUP:
 		fields.Duration("total_time", totalTime),
 		fields.Duration("planning_time", iterTime),
 		fields.Duration("execution_time", totalTime-iterTime),
+		fields.Int64("series_n", int64(stats.SeriesN)),
+		fields.Int64("points_n", int64(stats.PointN)),
+		fields.Int64("rows_n", writeN),
 	)
 	span.Finish()
 
@@ -544,8 +633,54 @@ func (e *StatementExecutor) executeSetPasswordUserStatement(q *influxql.SetPassw
 	return e.MetaClient.UpdateUser(q.Name, q.Password)
 }
 
-func (e *StatementExecutor) executeSelectStatement(ctx context.Context, stmt *influxql.SelectStatement, ectx *query.ExecutionContext) error {
-	itrs, columns, err := e.createIterators(ctx, stmt, ectx)
+func (e *StatementExecutor) executeSelectStatement(ctx context.Context, stmt *influxql.SelectStatement, ectx *query.ExecutionContext) (err error) {
+	// Send the cached results of the statement if there are any, and
+	// otherwise keep the results sent to cache them.
+	var cached *resultCacheEntry
+	if e.ResultCache != nil && stmt.Target == nil && cacheableResults(ectx.Authorizer) {
+		var hit []*query.Result
+		if hit, cached = e.ResultCache.lookup(stmt, ectx.ChunkSize); hit != nil {
+			for _, r := range hit {
+				if err := ectx.Send(copyResult(r, ectx.StatementID)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	if cached != nil {
+		defer func() { e.ResultCache.store(cached, err != nil) }()
+	}
+
+	// The trace is exported once the iterators are closed, since the spans
+	// reading the shards are finished when their iterators are.
+	var span *tracing.Span
+	if e.TraceExporter != nil && e.TraceExporter.Sample() {
+		var t *tracing.Trace
+		t, span = tracing.NewTrace("select")
+		labels := []string{"statement", stmt.String()}
+		if id := logger.RequestIDFromContext(ctx); id != "" {
+			labels = append(labels, "request_id", id)
+		}
+		span.SetLabels(labels...)
+		ctx = tracing.NewContextWithTrace(ctx, t)
+		ctx = tracing.NewContextWithSpan(ctx, span)
+		defer func() {
+			span.Finish()
+			e.TraceExporter.Export(t)
+		}()
+	}
+
+	planCtx := ctx
+	var plan *tracing.Span
+	if span != nil {
+		plan = span.StartSpan("plan")
+		planCtx = tracing.NewContextWithSpan(ctx, plan)
+	}
+	itrs, columns, messages, err := e.createIterators(planCtx, stmt, ectx)
+	if plan != nil {
+		plan.Finish()
+	}
 	if err != nil {
 		return err
 	}
@@ -560,6 +695,20 @@ func (e *StatementExecutor) executeSelectStatement(ctx context.Context, stmt *in
 	em.EmitName = stmt.EmitName
 	defer em.Close()
 
+	// The emit span covers reading the points, merging them into rows and
+	// sending the rows to be encoded.
+	if span != nil {
+		emit := span.StartSpan("emit")
+		defer emit.Finish()
+	}
+
+	// Record the series and points read by the statement on the query.
+	if ectx.Query != nil {
+		defer func() {
+			ectx.Query.AddIteratorStats(query.Iterators(itrs).Stats())
+		}()
+	}
+
 	// Emit rows to the results channel.
 	var writeN int64
 	var emitted bool
@@ -592,11 +741,18 @@ func (e *StatementExecutor) executeSelectStatement(ctx context.Context, stmt *in
 			continue
 		}
 
+		// The warnings are sent with the first result.
 		result := &query.Result{
 			StatementID: ectx.StatementID,
 			Series:      []*models.Row{row},
+			Messages:    messages,
 			Partial:     partial,
 		}
+		messages = nil
+
+		if cached != nil {
+			e.ResultCache.add(cached, result)
+		}
 
 		// Send results or exit if closing.
 		if err := ectx.Send(result); err != nil {
@@ -612,7 +768,6 @@ func (e *StatementExecutor) executeSelectStatement(ctx context.Context, stmt *in
 			return err
 		}
 
-		var messages []*query.Message
 		if ectx.ReadOnly {
 			messages = append(messages, query.ReadOnlyWarning(stmt.String()))
 		}
@@ -630,35 +785,55 @@ func (e *StatementExecutor) executeSelectStatement(ctx context.Context, stmt *in
 
 	// Always emit at least one result.
 	if !emitted {
-		return ectx.Send(&query.Result{
+		result := &query.Result{
 			StatementID: ectx.StatementID,
 			Series:      make([]*models.Row, 0),
-		})
+			Messages:    messages,
+		}
+		if cached != nil {
+			e.ResultCache.add(cached, result)
+		}
+		return ectx.Send(result)
 	}
 
 	return nil
 }
 
-func (e *StatementExecutor) createIterators(ctx context.Context, stmt *influxql.SelectStatement, ectx *query.ExecutionContext) ([]query.Iterator, []string, error) {
+// createIterators creates the iterators of a SELECT statement. It also
+// returns the warnings to send with the results of the statement.
+func (e *StatementExecutor) createIterators(ctx context.Context, stmt *influxql.SelectStatement, ectx *query.ExecutionContext) ([]query.Iterator, []string, []*query.Message, error) {
 	opt := query.SelectOptions{
 		InterruptCh: ectx.InterruptCh,
 		NodeID:      ectx.ExecutionOptions.NodeID,
 		MaxSeriesN:  e.MaxSelectSeriesN,
 		MaxBucketsN: e.MaxSelectBucketsN,
 		Authorizer:  ectx.Authorizer,
+
+		MaxUnboundedSize: e.MaxUnboundedSelectSize,
+		UnboundedRange:   e.UnboundedSelectRange,
+
+		WarnSeriesN: e.WarnSelectSeriesN,
+		WarnBlocksN: e.WarnSelectBlocksN,
 	}
 
 	// Create a set of iterators from a selection.
-	itrs, columns, err := query.Select(ctx, stmt, e.ShardMapper, opt)
+	p, err := query.Prepare(stmt, e.ShardMapper, opt)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+	// Must be deferred so it runs after Select.
+	defer p.Close()
+
+	itrs, columns, err := p.Select(ctx)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
 	if e.MaxSelectPointN > 0 {
 		monitor := query.PointLimitMonitor(itrs, query.DefaultStatsInterval, e.MaxSelectPointN)
 		ectx.Query.Monitor(monitor)
 	}
-	return itrs, columns, nil
+	return itrs, columns, p.Warnings(), nil
 }
 
 func (e *StatementExecutor) executeShowContinuousQueriesStatement(stmt *influxql.ShowContinuousQueriesStatement) (models.Rows, error) {
@@ -812,7 +987,7 @@ func (e *StatementExecutor) executeShowShardsStatement(stmt *influxql.ShowShards
 
 	rows := []*models.Row{}
 	for _, di := range dis {
-		row := &models.Row{Columns: []string{"id", "database", "retention_policy", "shard_group", "start_time", "end_time", "expiry_time", "owners"}, Name: di.Name}
+		row := &models.Row{Columns: []string{"id", "database", "retention_policy", "shard_group", "start_time", "end_time", "expiry_time", "owners", "disk_bytes"}, Name: di.Name}
 		for _, rpi := range di.RetentionPolicies {
 			for _, sgi := range rpi.ShardGroups {
 				// Shards associated with deleted shard groups are effectively deleted.
@@ -827,6 +1002,12 @@ func (e *StatementExecutor) executeShowShardsStatement(stmt *influxql.ShowShards
 						ownerIDs[i] = owner.NodeID
 					}
 
+					// The size is unknown for shards not open on this node.
+					var size interface{}
+					if n, err := e.TSDBStore.ShardDiskSize(si.ID); err == nil {
+						size = n
+					}
+
 					row.Values = append(row.Values, []interface{}{
 						si.ID,
 						di.Name,
@@ -836,6 +1017,7 @@ func (e *StatementExecutor) executeShowShardsStatement(stmt *influxql.ShowShards
 						sgi.EndTime.UTC().Format(time.RFC3339),
 						sgi.EndTime.Add(rpi.Duration).UTC().Format(time.RFC3339),
 						joinUint64(ownerIDs),
+						size,
 					})
 				}
 			}
@@ -947,6 +1129,12 @@ func (e *StatementExecutor) executeShowTagKeys(q *influxql.ShowTagKeysStatement,
 		return err
 	}
 
+	// Series that received a point within the recent series window can be
+	// looked up without touching the shards.
+	if e.recentSeriesCover(q.Database, timeRange) {
+		return sendTagKeys(ctx, e.RecentSeries.TagKeys(ctx.Authorizer, q.Database, timeRange.Min, cond), q.Limit, q.Offset)
+	}
+
 	// Get all shards for all retention policies.
 	var allGroups []meta.ShardGroupInfo
 	for _, rpi := range di.RetentionPolicies {
@@ -972,19 +1160,25 @@ func (e *StatementExecutor) executeShowTagKeys(q *influxql.ShowTagKeysStatement,
 		})
 	}
 
+	return sendTagKeys(ctx, tagKeys, q.Limit, q.Offset)
+}
+
+// sendTagKeys applies limit and offset to the keys of each measurement in
+// tagKeys and sends the resulting rows.
+func sendTagKeys(ctx *query.ExecutionContext, tagKeys []tsdb.TagKeys, limit, offset int) error {
 	emitted := false
 	for _, m := range tagKeys {
 		keys := m.Keys
 
-		if q.Offset > 0 {
-			if q.Offset >= len(keys) {
+		if offset > 0 {
+			if offset >= len(keys) {
 				keys = nil
 			} else {
-				keys = keys[q.Offset:]
+				keys = keys[offset:]
 			}
 		}
-		if q.Limit > 0 && q.Limit < len(keys) {
-			keys = keys[:q.Limit]
+		if limit > 0 && limit < len(keys) {
+			keys = keys[:limit]
 		}
 
 		if len(keys) == 0 {
@@ -1018,6 +1212,13 @@ func (e *StatementExecutor) executeShowTagKeys(q *influxql.ShowTagKeysStatement,
 	return nil
 }
 
+// recentSeriesCover returns true if the series of database written within
+// timeRange can be looked up in the recent series index. Only ranges with a
+// lower bound and no upper bound are looked up.
+func (e *StatementExecutor) recentSeriesCover(database string, timeRange influxql.TimeRange) bool {
+	return e.RecentSeries != nil && !timeRange.Min.IsZero() && timeRange.Max.IsZero() && e.RecentSeries.Covers(database, timeRange.Min)
+}
+
 func (e *StatementExecutor) executeShowTagValues(q *influxql.ShowTagValuesStatement, ctx *query.ExecutionContext) error {
 	if q.Database == "" {
 		return ErrDatabaseNameRequired
@@ -1038,6 +1239,12 @@ func (e *StatementExecutor) executeShowTagValues(q *influxql.ShowTagValuesStatem
 		return err
 	}
 
+	// Series that received a point within the recent series window can be
+	// looked up without touching the shards.
+	if e.recentSeriesCover(q.Database, timeRange) {
+		return sendTagValues(ctx, e.RecentSeries.TagValues(ctx.Authorizer, q.Database, timeRange.Min, cond), q.Limit, q.Offset)
+	}
+
 	// Get all shards for all retention policies.
 	var allGroups []meta.ShardGroupInfo
 	for _, rpi := range di.RetentionPolicies {
@@ -1062,22 +1269,27 @@ func (e *StatementExecutor) executeShowTagValues(q *influxql.ShowTagValuesStatem
 			Err:         err,
 		})
 	}
+	return sendTagValues(ctx, tagValues, q.Limit, q.Offset)
+}
 
+// sendTagValues applies limit and offset to the values of each measurement
+// in tagValues and sends the resulting rows.
+func sendTagValues(ctx *query.ExecutionContext, tagValues []tsdb.TagValues, limit, offset int) error {
 	emitted := false
 	for _, m := range tagValues {
 		values := m.Values
 
-		if q.Offset > 0 {
-			if q.Offset >= len(values) {
+		if offset > 0 {
+			if offset >= len(values) {
 				values = nil
 			} else {
-				values = values[q.Offset:]
+				values = values[offset:]
 			}
 		}
 
-		if q.Limit > 0 {
-			if q.Limit < len(values) {
-				values = values[:q.Limit]
+		if limit > 0 {
+			if limit < len(values) {
+				values = values[:limit]
 			}
 		}
 
@@ -1370,6 +1582,7 @@ type TSDBStore interface {
 	DeleteRetentionPolicy(database, name string) error
 	DeleteSeries(database string, sources []influxql.Source, condition influxql.Expr) error
 	DeleteShard(id uint64) error
+	ShardDiskSize(id uint64) (int64, error)
 
 	MeasurementNames(auth query.Authorizer, database string, cond influxql.Expr) ([][]byte, error)
 	TagKeys(auth query.Authorizer, shardIDs []uint64, cond influxql.Expr) ([]tsdb.TagKeys, error)
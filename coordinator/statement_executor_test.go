@@ -8,6 +8,7 @@ import (
 	"os"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -84,6 +85,56 @@ func TestQueryExecutor_ExecuteQuery_SelectStatement(t *testing.T) {
 	}
 }
 
+// Ensure EXPLAIN ANALYZE reports the series, points and rows a query reads.
+func TestQueryExecutor_ExecuteQuery_ExplainAnalyze(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.MetaClient.ShardGroupsByTimeRangeFn = func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error) {
+		return []meta.ShardGroupInfo{
+			{ID: 1, Shards: []meta.ShardInfo{
+				{ID: 100, Owners: []meta.ShardOwner{{NodeID: 0}}},
+			}},
+		}, nil
+	}
+	e.TSDBStore.ShardGroupFn = func(ids []uint64) tsdb.ShardGroup {
+		var sh MockShard
+		sh.CreateIteratorFn = func(_ context.Context, _ *influxql.Measurement, _ query.IteratorOptions) (query.Iterator, error) {
+			return &FloatIterator{
+				Points: []query.FloatPoint{
+					{Name: "cpu", Time: int64(0 * time.Second), Aux: []interface{}{float64(100)}},
+					{Name: "cpu", Time: int64(1 * time.Second), Aux: []interface{}{float64(200)}},
+					{Name: "cpu", Time: int64(2 * time.Second), Aux: []interface{}{float64(300)}},
+				},
+				stats: query.IteratorStats{SeriesN: 2, PointN: 3},
+			}, nil
+		}
+		sh.FieldDimensionsFn = func(measurements []string) (fields map[string]influxql.DataType, dimensions map[string]struct{}, err error) {
+			return map[string]influxql.DataType{"value": influxql.Float}, nil, nil
+		}
+		return &sh
+	}
+
+	a := ReadAllResults(e.ExecuteQuery(`EXPLAIN ANALYZE SELECT value FROM cpu LIMIT 2`, "db0", 0))
+	if len(a) != 1 || a[0].Err != nil || len(a[0].Series) != 1 {
+		t.Fatalf("unexpected results: %s", spew.Sdump(a))
+	}
+
+	var lines []string
+	for _, v := range a[0].Series[0].Values {
+		lines = append(lines, v[0].(string))
+	}
+	for _, exp := range []string{"series_n: 2", "points_n: 3", "rows_n: 2"} {
+		var found bool
+		for _, line := range lines {
+			if strings.HasSuffix(line, exp) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected %q in:\n%s", exp, strings.Join(lines, "\n"))
+		}
+	}
+}
+
 // Ensure query executor can enforce a maximum bucket selection count.
 func TestQueryExecutor_ExecuteQuery_MaxSelectBucketsN(t *testing.T) {
 	e := DefaultQueryExecutor()
@@ -129,6 +180,157 @@ func TestQueryExecutor_ExecuteQuery_MaxSelectBucketsN(t *testing.T) {
 	}
 }
 
+// Ensure query executor reuses cached results until a write changes them.
+func TestQueryExecutor_ExecuteQuery_ResultCache(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.StatementExecutor.ResultCache = coordinator.NewResultCache(24*time.Hour, 10, 1<<20)
+
+	e.MetaClient.ShardGroupsByTimeRangeFn = func(database, policy string, min, max time.Time) (a []meta.ShardGroupInfo, err error) {
+		return []meta.ShardGroupInfo{
+			{ID: 1, Shards: []meta.ShardInfo{
+				{ID: 100, Owners: []meta.ShardOwner{{NodeID: 0}}},
+			}},
+		}, nil
+	}
+
+	var n int
+	e.TSDBStore.ShardGroupFn = func(ids []uint64) tsdb.ShardGroup {
+		var sh MockShard
+		sh.CreateIteratorFn = func(_ context.Context, _ *influxql.Measurement, _ query.IteratorOptions) (query.Iterator, error) {
+			n++
+			return &FloatIterator{Points: []query.FloatPoint{
+				{Name: "cpu", Time: int64(0 * time.Second), Aux: []interface{}{float64(100)}},
+			}}, nil
+		}
+		sh.FieldDimensionsFn = func(measurements []string) (fields map[string]influxql.DataType, dimensions map[string]struct{}, err error) {
+			return map[string]influxql.DataType{"value": influxql.Float}, nil, nil
+		}
+		return &sh
+	}
+
+	exp := []*query.Result{
+		{
+			StatementID: 0,
+			Series: []*models.Row{{
+				Name:    "cpu",
+				Columns: []string{"time", "value"},
+				Values:  [][]interface{}{{time.Unix(0, 0).UTC(), float64(100)}},
+			}},
+		},
+	}
+	for i := 0; i < 3; i++ {
+		a := ReadAllResults(e.ExecuteQuery(`SELECT * FROM cpu WHERE time < 10s`, "db0", 0))
+		if !reflect.DeepEqual(a, exp) {
+			t.Fatalf("unexpected results: %s", spew.Sdump(a))
+		}
+
+		// Converting the times to epochs, as the HTTP handler does for
+		// epoch=, does not change the cached results.
+		for _, row := range a[0].Series {
+			for _, v := range row.Values {
+				v[0] = v[0].(time.Time).UnixNano()
+			}
+		}
+	}
+	if n != 1 {
+		t.Fatalf("unexpected number of iterators: %d", n)
+	}
+
+	// A write outside of the time range of the statement keeps the results.
+	e.StatementExecutor.ResultCache.Invalidate("db0", []models.Point{models.MustNewPoint("cpu", nil, models.Fields{"value": 1.0}, time.Unix(20, 0))})
+	ReadAllResults(e.ExecuteQuery(`SELECT * FROM cpu WHERE time < 10s`, "db0", 0))
+	if n != 1 {
+		t.Fatalf("unexpected number of iterators: %d", n)
+	}
+
+	e.StatementExecutor.ResultCache.Invalidate("db0", []models.Point{models.MustNewPoint("cpu", nil, models.Fields{"value": 1.0}, time.Unix(5, 0))})
+	ReadAllResults(e.ExecuteQuery(`SELECT * FROM cpu WHERE time < 10s`, "db0", 0))
+	if n != 2 {
+		t.Fatalf("unexpected number of iterators: %d", n)
+	}
+
+	// Dropping a shard of the database drops its results.
+	e.MetaClient.DatabasesFn = func() []meta.DatabaseInfo {
+		return []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name: "rp0",
+				ShardGroups: []meta.ShardGroupInfo{{
+					ID:     1,
+					Shards: []meta.ShardInfo{{ID: 100}},
+				}},
+			}},
+		}}
+	}
+	e.TSDBStore.DeleteShardFn = func(id uint64) error { return nil }
+	e.MetaClient.DropShardFn = func(id uint64) error { return nil }
+	if err := ReadAllResults(e.ExecuteQuery(`DROP SHARD 100`, "", 0))[0].Err; err != nil {
+		t.Fatal(err)
+	}
+	ReadAllResults(e.ExecuteQuery(`SELECT * FROM cpu WHERE time < 10s`, "db0", 0))
+	if n != 3 {
+		t.Fatalf("unexpected number of iterators: %d", n)
+	}
+
+	// Results larger than the cache are not kept.
+	e.StatementExecutor.ResultCache = coordinator.NewResultCache(24*time.Hour, 10, 64)
+	for i := 0; i < 2; i++ {
+		ReadAllResults(e.ExecuteQuery(`SELECT * FROM cpu WHERE time < 10s`, "db0", 0))
+	}
+	if n != 5 {
+		t.Fatalf("unexpected number of iterators: %d", n)
+	}
+}
+
+// Ensure SHOW TAG KEYS and SHOW TAG VALUES are answered from the recent
+// series index for recent time ranges, without reading the shards.
+func TestQueryExecutor_ExecuteQuery_ShowTags_RecentSeries(t *testing.T) {
+	e := DefaultQueryExecutor()
+	e.StatementExecutor.RecentSeries = coordinator.NewRecentSeries(time.Hour)
+	e.MetaClient.ShardGroupsByTimeRangeFn = func(database, policy string, min, max time.Time) ([]meta.ShardGroupInfo, error) {
+		t.Fatal("unexpected shard lookup")
+		return nil, nil
+	}
+
+	// The index only covers times after it was created, so the points are
+	// written ahead of the time range of the statements.
+	ts := time.Now().Add(time.Minute)
+	e.StatementExecutor.RecentSeries.Add("db0", []models.Point{
+		models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "serverA", "region": "east"}), models.Fields{"value": 1.0}, ts),
+		models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "serverB", "region": "west"}), models.Fields{"value": 1.0}, ts),
+	})
+
+	for _, tt := range []struct {
+		q   string
+		exp []*query.Result
+	}{
+		{
+			q: `SHOW TAG KEYS FROM cpu WHERE time > now()`,
+			exp: []*query.Result{{
+				Series: []*models.Row{{
+					Name:    "cpu",
+					Columns: []string{"tagKey"},
+					Values:  [][]interface{}{{"host"}, {"region"}},
+				}},
+			}},
+		},
+		{
+			q: `SHOW TAG VALUES FROM cpu WITH KEY = "host" WHERE time > now() LIMIT 1 OFFSET 1`,
+			exp: []*query.Result{{
+				Series: []*models.Row{{
+					Name:    "cpu",
+					Columns: []string{"key", "value"},
+					Values:  [][]interface{}{{"host", "serverB"}},
+				}},
+			}},
+		},
+	} {
+		if a := ReadAllResults(e.ExecuteQuery(tt.q, "db0", 0)); !reflect.DeepEqual(a, tt.exp) {
+			t.Errorf("%s: unexpected results: %s", tt.q, spew.Sdump(a))
+		}
+	}
+}
+
 func TestStatementExecutor_NormalizeDropSeries(t *testing.T) {
 	q, err := influxql.ParseQuery("DROP SERIES FROM cpu")
 	if err != nil {
@@ -204,7 +406,7 @@ func (a *mockAuthorizer) AuthorizeDatabase(p influxql.Privilege, name string) bo
 }
 
 func (m *mockAuthorizer) AuthorizeQuery(database string, query *influxql.Query) error {
-	panic("fail")
+	return nil
 }
 
 func (m *mockAuthorizer) AuthorizeSeriesRead(database string, measurement []byte, tags models.Tags) bool {
@@ -0,0 +1,76 @@
+package coordinator
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// WriteHook transforms points before they are stored. A hook may modify or
+// replace points and drops them by leaving them out of the returned slice.
+// Returning an error fails the whole write.
+type WriteHook interface {
+	WritePoints(database, retentionPolicy string, points []models.Point) ([]models.Point, error)
+}
+
+// NewWriteHookFunc creates a write hook from the options in its config.
+type NewWriteHookFunc func(options map[string]string) (WriteHook, error)
+
+// newWriteHookFuncs is a lookup of write hook constructors by name.
+var newWriteHookFuncs = make(map[string]NewWriteHookFunc)
+
+// RegisterWriteHook registers a write hook under name. Write hooks are
+// registered when their package is compiled in and selected by name in the
+// configuration.
+func RegisterWriteHook(name string, fn NewWriteHookFunc) {
+	if _, ok := newWriteHookFuncs[name]; ok {
+		panic("write hook already registered: " + name)
+	}
+	newWriteHookFuncs[name] = fn
+}
+
+// RegisteredWriteHooks returns the names of the registered write hooks.
+func RegisteredWriteHooks() []string {
+	a := make([]string, 0, len(newWriteHookFuncs))
+	for k := range newWriteHookFuncs {
+		a = append(a, k)
+	}
+	sort.Strings(a)
+	return a
+}
+
+// NewWriteHook returns the write hook described by c.
+func NewWriteHook(c WriteHookConfig) (WriteHook, error) {
+	fn := newWriteHookFuncs[c.Name]
+	if fn == nil {
+		return nil, fmt.Errorf("unknown write hook: %q", c.Name)
+	}
+
+	h, err := fn(c.Options)
+	if err != nil {
+		return nil, fmt.Errorf("write hook %q: %s", c.Name, err)
+	}
+	if len(c.Databases) == 0 {
+		return h, nil
+	}
+
+	databases := make(map[string]struct{}, len(c.Databases))
+	for _, db := range c.Databases {
+		databases[db] = struct{}{}
+	}
+	return &databaseWriteHook{hook: h, databases: databases}, nil
+}
+
+// databaseWriteHook only applies a hook to writes to some databases.
+type databaseWriteHook struct {
+	hook      WriteHook
+	databases map[string]struct{}
+}
+
+func (h *databaseWriteHook) WritePoints(database, retentionPolicy string, points []models.Point) ([]models.Point, error) {
+	if _, ok := h.databases[database]; !ok {
+		return points, nil
+	}
+	return h.hook.WritePoints(database, retentionPolicy, points)
+}
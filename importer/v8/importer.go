@@ -4,6 +4,7 @@ package v8 // import "github.com/influxdata/influxdb/importer/v8"
 import (
 	"bufio"
 	"compress/gzip"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -252,7 +253,13 @@ func (i *Importer) batchWrite() {
 	}
 
 	_, e := i.client.WriteLineProtocol(strings.Join(i.batch, "\n"), i.database, i.retentionPolicy, i.config.Precision, i.config.WriteConsistency)
-	if e != nil {
+	if n := droppedPoints(e); n > 0 && n < len(i.batch) {
+		// The server wrote the other lines of the batch, so only the
+		// dropped ones are reported as failed.
+		i.stderrLogger.Println("error writing batch: ", e)
+		i.failedInserts += n
+		i.totalInserts += len(i.batch) - n
+	} else if e != nil {
 		i.stderrLogger.Println("error writing batch: ", e)
 		i.stderrLogger.Println(strings.Join(i.batch, "\n"))
 		i.failedInserts += len(i.batch)
@@ -262,3 +269,19 @@ func (i *Importer) batchWrite() {
 	i.throttlePointsWritten = 0
 	i.lastWrite = time.Now()
 }
+
+// droppedPoints returns the number of points a partial write error says
+// were dropped, or 0 for other errors. The count comes from the dropped field
+// of the response, as the errors it lists are capped.
+func droppedPoints(err error) int {
+	if err == nil || !strings.Contains(err.Error(), "partial write") {
+		return 0
+	}
+	var resp struct {
+		Dropped int `json:"dropped"`
+	}
+	if json.Unmarshal([]byte(err.Error()), &resp) != nil {
+		return 0
+	}
+	return resp.Dropped
+}
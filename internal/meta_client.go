@@ -50,7 +50,13 @@ type MetaClientMock struct {
 	UserPrivilegeFn          func(username, database string) (*influxql.Privilege, error)
 	UserPrivilegesFn         func(username string) (map[string]influxql.Privilege, error)
 	UserFn                   func(username string) (meta.User, error)
+	UserCountFn              func() int
 	UsersFn                  func() []meta.UserInfo
+
+	AuthenticateTokenFn func(token string) (meta.User, error)
+	CreateTokenFn       func(database, retentionPolicy string, p influxql.Privilege) (*meta.TokenInfo, string, error)
+	DropTokenFn         func(id string) error
+	TokensFn            func() []meta.TokenInfo
 }
 
 func (c *MetaClientMock) Close() error {
@@ -167,8 +173,18 @@ func (c *MetaClientMock) Authenticate(username, password string) (meta.User, err
 func (c *MetaClientMock) AdminUserExists() bool { return c.AdminUserExistsFn() }
 
 func (c *MetaClientMock) User(username string) (meta.User, error) { return c.UserFn(username) }
+func (c *MetaClientMock) UserCount() int                          { return c.UserCountFn() }
 func (c *MetaClientMock) Users() []meta.UserInfo                  { return c.UsersFn() }
 
+func (c *MetaClientMock) AuthenticateToken(token string) (meta.User, error) {
+	return c.AuthenticateTokenFn(token)
+}
+func (c *MetaClientMock) CreateToken(database, retentionPolicy string, p influxql.Privilege) (*meta.TokenInfo, string, error) {
+	return c.CreateTokenFn(database, retentionPolicy, p)
+}
+func (c *MetaClientMock) DropToken(id string) error { return c.DropTokenFn(id) }
+func (c *MetaClientMock) Tokens() []meta.TokenInfo  { return c.TokensFn() }
+
 func (c *MetaClientMock) Open() error                { return c.OpenFn() }
 func (c *MetaClientMock) Data() meta.Data            { return c.DataFn() }
 func (c *MetaClientMock) SetData(d *meta.Data) error { return c.SetDataFn(d) }
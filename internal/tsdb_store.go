@@ -24,6 +24,7 @@ type TSDBStoreMock struct {
 	DeleteMeasurementFn       func(database, name string) error
 	DeleteRetentionPolicyFn   func(database, name string) error
 	DeleteSeriesFn            func(database string, sources []influxql.Source, condition influxql.Expr) error
+	DeleteSeriesFromShardsFn  func(database string, shardIDs []uint64, sources []influxql.Source, condition influxql.Expr) error
 	DeleteShardFn             func(id uint64) error
 	DiskSizeFn                func() (int64, error)
 	ExpandSourcesFn           func(sources influxql.Sources) (influxql.Sources, error)
@@ -37,14 +38,17 @@ type TSDBStoreMock struct {
 	SeriesCardinalityFn       func(database string) (int64, error)
 	SetShardEnabledFn         func(shardID uint64, enabled bool) error
 	ShardFn                   func(id uint64) *tsdb.Shard
+	ShardDiskSizeFn           func(id uint64) (int64, error)
 	ShardGroupFn              func(ids []uint64) tsdb.ShardGroup
 	ShardIDsFn                func() []uint64
+	ShardLastModifiedFn       func(id uint64) time.Time
 	ShardNFn                  func() int
 	ShardRelativePathFn       func(id uint64) (string, error)
 	ShardsFn                  func(ids []uint64) []*tsdb.Shard
 	StatisticsFn              func(tags map[string]string) []models.Statistic
 	TagKeysFn                 func(auth query.Authorizer, shardIDs []uint64, cond influxql.Expr) ([]tsdb.TagKeys, error)
 	TagValuesFn               func(auth query.Authorizer, shardIDs []uint64, cond influxql.Expr) ([]tsdb.TagValues, error)
+	ValidateToShardFn         func(shardID uint64, points []models.Point) error
 	WithLoggerFn              func(log *zap.Logger)
 	WriteToShardFn            func(shardID uint64, points []models.Point) error
 }
@@ -80,6 +84,9 @@ func (s *TSDBStoreMock) DeleteRetentionPolicy(database string, name string) erro
 func (s *TSDBStoreMock) DeleteSeries(database string, sources []influxql.Source, condition influxql.Expr) error {
 	return s.DeleteSeriesFn(database, sources, condition)
 }
+func (s *TSDBStoreMock) DeleteSeriesFromShards(database string, shardIDs []uint64, sources []influxql.Source, condition influxql.Expr) error {
+	return s.DeleteSeriesFromShardsFn(database, shardIDs, sources, condition)
+}
 func (s *TSDBStoreMock) DeleteShard(shardID uint64) error {
 	return s.DeleteShardFn(shardID)
 }
@@ -119,12 +126,18 @@ func (s *TSDBStoreMock) SetShardEnabled(shardID uint64, enabled bool) error {
 func (s *TSDBStoreMock) Shard(id uint64) *tsdb.Shard {
 	return s.ShardFn(id)
 }
+func (s *TSDBStoreMock) ShardDiskSize(id uint64) (int64, error) {
+	return s.ShardDiskSizeFn(id)
+}
 func (s *TSDBStoreMock) ShardGroup(ids []uint64) tsdb.ShardGroup {
 	return s.ShardGroupFn(ids)
 }
 func (s *TSDBStoreMock) ShardIDs() []uint64 {
 	return s.ShardIDsFn()
 }
+func (s *TSDBStoreMock) ShardLastModified(id uint64) time.Time {
+	return s.ShardLastModifiedFn(id)
+}
 func (s *TSDBStoreMock) ShardN() int {
 	return s.ShardNFn()
 }
@@ -143,6 +156,9 @@ func (s *TSDBStoreMock) TagKeys(auth query.Authorizer, shardIDs []uint64, cond i
 func (s *TSDBStoreMock) TagValues(auth query.Authorizer, shardIDs []uint64, cond influxql.Expr) ([]tsdb.TagValues, error) {
 	return s.TagValuesFn(auth, shardIDs, cond)
 }
+func (s *TSDBStoreMock) ValidateToShard(shardID uint64, points []models.Point) error {
+	return s.ValidateToShardFn(shardID, points)
+}
 func (s *TSDBStoreMock) WithLogger(log *zap.Logger) {
 	s.WithLoggerFn(log)
 }
@@ -0,0 +1,168 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultLevel is the lowest level logged by default.
+const DefaultLevel = "info"
+
+// Log formats.
+const (
+	// FormatConsole writes the message followed by its fields, for reading
+	// by a person.
+	FormatConsole = "console"
+
+	// FormatLogfmt writes each entry as key=value pairs.
+	FormatLogfmt = "logfmt"
+
+	// FormatJSON writes each entry as a JSON object.
+	FormatJSON = "json"
+)
+
+// Config represents the configuration of the server log.
+type Config struct {
+	Format string `toml:"format"`
+	Level  string `toml:"level"`
+
+	// Levels overrides the level for individual services, keyed by the name
+	// the service logs with, such as "httpd" or "graphite".
+	Levels map[string]string `toml:"levels"`
+}
+
+// NewConfig returns a new instance of Config with defaults.
+func NewConfig() Config {
+	return Config{
+		Format: FormatConsole,
+		Level:  DefaultLevel,
+	}
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	switch c.Format {
+	case "", FormatConsole, FormatLogfmt, FormatJSON:
+	default:
+		return fmt.Errorf("unrecognized log format %q", c.Format)
+	}
+	_, _, err := c.levels()
+	return err
+}
+
+// levels returns the default level and the levels of individual services.
+func (c Config) levels() (zapcore.Level, map[string]zapcore.Level, error) {
+	level, err := parseLevel(c.Level)
+	if err != nil {
+		return level, nil, err
+	}
+
+	levels := make(map[string]zapcore.Level, len(c.Levels))
+	for service, s := range c.Levels {
+		l, err := parseLevel(s)
+		if err != nil {
+			return level, nil, fmt.Errorf("%s: %s", service, err)
+		}
+		levels[service] = l
+	}
+	return level, levels, nil
+}
+
+// parseLevel parses a level name such as "debug" or "warn". An empty name is
+// the default level.
+func parseLevel(s string) (zapcore.Level, error) {
+	var level zapcore.Level
+	if s == "" {
+		s = DefaultLevel
+	}
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return level, fmt.Errorf("unrecognized log level %q", s)
+	}
+	return level, nil
+}
+
+// New returns a logger writing to w as described by the config.
+func (c Config) New(w io.Writer) (*zap.Logger, error) {
+	level, levels, err := c.levels()
+	if err != nil {
+		return nil, err
+	}
+
+	var encoder zapcore.Encoder
+	switch config := newEncoderConfig(); c.Format {
+	case "", FormatConsole:
+		encoder = zapcore.NewConsoleEncoder(config)
+	case FormatLogfmt:
+		encoder = newLogfmtEncoder()
+	case FormatJSON:
+		encoder = zapcore.NewJSONEncoder(config)
+	default:
+		return nil, fmt.Errorf("unrecognized log format %q", c.Format)
+	}
+
+	core := zapcore.NewCore(encoder, zapcore.Lock(zapcore.AddSync(w)), zapcore.DebugLevel)
+	return zap.New(&levelCore{
+		Core:   core,
+		level:  level,
+		levels: levels,
+	}), nil
+}
+
+// Diagnostics returns a diagnostics representation of a subset of the Config.
+func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
+	m := map[string]interface{}{
+		"format": c.Format,
+		"level":  c.Level,
+	}
+	if len(c.Levels) > 0 {
+		a := make([]string, 0, len(c.Levels))
+		for service, level := range c.Levels {
+			a = append(a, service+"="+level)
+		}
+		sort.Strings(a)
+		m["levels"] = strings.Join(a, ",")
+	}
+	return diagnostics.RowFromMap(m), nil
+}
+
+// levelCore filters entries by level. Loggers created for a service with a
+// level of its own use that level instead of the default one.
+type levelCore struct {
+	zapcore.Core
+	level  zapcore.Level
+	levels map[string]zapcore.Level
+}
+
+func (c *levelCore) Enabled(level zapcore.Level) bool {
+	return c.level.Enabled(level)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	level := c.level
+	for _, f := range fields {
+		if f.Key != "service" || f.Type != zapcore.StringType {
+			continue
+		}
+		if l, ok := c.levels[f.String]; ok {
+			level = l
+		}
+	}
+	return &levelCore{
+		Core:   c.Core.With(fields),
+		level:  level,
+		levels: c.levels,
+	}
+}
+
+func (c *levelCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
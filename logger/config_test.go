@@ -0,0 +1,58 @@
+package logger_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb/logger"
+	"go.uber.org/zap"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	for _, c := range []logger.Config{
+		{Format: "xml"},
+		{Level: "loud"},
+		{Levels: map[string]string{"httpd": "loud"}},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected error for %+v", c)
+		}
+	}
+
+	if err := logger.NewConfig().Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestConfig_New_Levels(t *testing.T) {
+	c := logger.NewConfig()
+	c.Format = logger.FormatLogfmt
+	c.Levels = map[string]string{"httpd": "warn", "graphite": "debug"}
+
+	var buf bytes.Buffer
+	log, err := c.New(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log.Debug("default debug")
+	log.Info("default info")
+	log.With(zap.String("service", "httpd")).Info("httpd info")
+	log.With(zap.String("service", "httpd")).Warn("httpd warn")
+	log.With(zap.String("service", "graphite")).Debug("graphite debug")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("unexpected log:\n%s", buf.String())
+	}
+	for i, exp := range []string{
+		`lvl=info msg="default info"`,
+		`lvl=warn msg="httpd warn" service=httpd`,
+		`lvl=debug msg="graphite debug" service=graphite`,
+	} {
+		if !strings.Contains(lines[i], exp) {
+			t.Errorf("line %d: got %q, expected it to contain %q", i, lines[i], exp)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type key int
+
+const requestIDKey key = iota
+
+// NewContextWithRequestID returns a new context with the ID of the request
+// it is used for added.
+func NewContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFromContext returns the request ID of ctx, or an empty string if
+// it has none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithRequestID returns log with the request ID id added to the fields of
+// its lines, so the lines logged for a request can be found by its ID. log is
+// returned as is if id is empty.
+func WithRequestID(log *zap.Logger, id string) *zap.Logger {
+	if id == "" {
+		return log
+	}
+	return log.With(zap.String("request_id", id))
+}
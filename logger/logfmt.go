@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var bufferPool = buffer.NewPool()
+
+// logfmtEncoder encodes entries as a line of key=value pairs. Fields are
+// collected with a map encoder and written in key order after the time,
+// level and message.
+type logfmtEncoder struct {
+	*zapcore.MapObjectEncoder
+}
+
+func newLogfmtEncoder() zapcore.Encoder {
+	return &logfmtEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder()}
+}
+
+func (enc *logfmtEncoder) Clone() zapcore.Encoder {
+	clone := zapcore.NewMapObjectEncoder()
+	for k, v := range enc.Fields {
+		clone.Fields[k] = v
+	}
+	return &logfmtEncoder{MapObjectEncoder: clone}
+}
+
+func (enc *logfmtEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	m := enc.Clone().(*logfmtEncoder)
+	for _, f := range fields {
+		f.AddTo(m)
+	}
+
+	buf := bufferPool.Get()
+	buf.AppendString("ts=")
+	buf.AppendString(entry.Time.UTC().Format(time.RFC3339))
+	buf.AppendString(" lvl=")
+	buf.AppendString(entry.Level.String())
+	if entry.LoggerName != "" {
+		buf.AppendString(" logger=")
+		appendLogfmtValue(buf, entry.LoggerName)
+	}
+	buf.AppendString(" msg=")
+	appendLogfmtValue(buf, entry.Message)
+
+	keys := make([]string, 0, len(m.Fields))
+	for k := range m.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		buf.AppendByte(' ')
+		buf.AppendString(k)
+		buf.AppendByte('=')
+		appendLogfmtValue(buf, m.Fields[k])
+	}
+	if entry.Stack != "" {
+		buf.AppendString(" stack=")
+		appendLogfmtValue(buf, entry.Stack)
+	}
+	buf.AppendByte('\n')
+	return buf, nil
+}
+
+// appendLogfmtValue appends v to buf, quoting it if it has spaces, quotes,
+// equal signs or control characters.
+func appendLogfmtValue(buf *buffer.Buffer, v interface{}) {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case time.Time:
+		s = v.UTC().Format(time.RFC3339)
+	case time.Duration:
+		s = v.String()
+	case []byte:
+		s = string(v)
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if s == "" || strings.IndexFunc(s, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || r == unicode.ReplacementChar || !unicode.IsPrint(r)
+	}) >= 0 {
+		buf.AppendString(strconv.Quote(s))
+		return
+	}
+	buf.AppendString(s)
+}
@@ -8,7 +8,16 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
+// New returns a logger writing every level to w in the console format.
 func New(w io.Writer) *zap.Logger {
+	return zap.New(zapcore.NewCore(
+		zapcore.NewConsoleEncoder(newEncoderConfig()),
+		zapcore.Lock(zapcore.AddSync(w)),
+		zapcore.DebugLevel,
+	))
+}
+
+func newEncoderConfig() zapcore.EncoderConfig {
 	config := zap.NewProductionEncoderConfig()
 	config.EncodeTime = func(ts time.Time, encoder zapcore.PrimitiveArrayEncoder) {
 		encoder.AppendString(ts.UTC().Format(time.RFC3339))
@@ -16,9 +25,5 @@ func New(w io.Writer) *zap.Logger {
 	config.EncodeDuration = func(d time.Duration, encoder zapcore.PrimitiveArrayEncoder) {
 		encoder.AppendString(d.String())
 	}
-	return zap.New(zapcore.NewCore(
-		zapcore.NewConsoleEncoder(config),
-		zapcore.Lock(zapcore.AddSync(w)),
-		zapcore.DebugLevel,
-	))
+	return config
 }
@@ -44,11 +44,12 @@ const (
 )
 
 // enableUint64Support will enable uint64 support if set to true.
-var enableUint64Support = false
+var enableUint64Support = true
 
 // EnableUintSupport manually enables uint support for the point parser.
-// This function will be removed in the future and only exists for unit tests during the
-// transition.
+//
+// Deprecated: uint support is enabled by default. This function no longer
+// needs to be called and will be removed in the future.
 func EnableUintSupport() {
 	enableUint64Support = true
 }
@@ -341,12 +342,35 @@ func ParsePointsWithPrecision(buf []byte, defaultTime time.Time, precision strin
 
 	}
 	if len(failed) > 0 {
-		return points, fmt.Errorf("%s", strings.Join(failed, "\n"))
+		return points, &ParseError{Lines: failed}
 	}
 	return points, nil
 
 }
 
+// ParseError is returned when lines of a batch fail to parse. The points of
+// the other lines are returned along with it.
+type ParseError struct {
+	// Lines has an error message for each line that failed to parse.
+	Lines []string
+}
+
+// Error returns the messages of the lines, one per line.
+func (e *ParseError) Error() string {
+	return strings.Join(e.Lines, "\n")
+}
+
+// ParseFailures returns the number of lines of a batch that failed to parse
+// according to err, the error returned by ParsePoints.
+func ParseFailures(err error) int {
+	if err == nil {
+		return 0
+	} else if perr, ok := err.(*ParseError); ok {
+		return len(perr.Lines)
+	}
+	return 1
+}
+
 func parsePoint(buf []byte, defaultTime time.Time, precision string) (Point, error) {
 	// scan the first block which is measurement[,tag1=value1,tag2=value=2...]
 	pos, key, err := scanKey(buf, 0)
@@ -740,6 +764,11 @@ func scanFields(buf []byte, i int) (int, []byte, error) {
 		if buf[i] == '=' && !quoted {
 			equals++
 
+			// check for "=123" at the start of the fields
+			if i == start {
+				return i, buf[start:i], fmt.Errorf("missing field key")
+			}
+
 			// check for "... =123" but allow "a\ =123"
 			if buf[i-1] == ' ' && buf[i-2] != '\\' {
 				return i, buf[start:i], fmt.Errorf("missing field key")
@@ -1128,6 +1157,24 @@ func scanTo(buf []byte, i int, stop byte) (int, []byte) {
 	return i, buf[start:i]
 }
 
+// scanFieldKey returns the position of the '=' ending the field key starting
+// at i, along with the key. Unlike scanTo, a backslash escapes the byte after
+// it, the same way scanFields reads keys, so a key ending in an escaped
+// backslash ends at the '=' that follows.
+func scanFieldKey(buf []byte, i int) (int, []byte) {
+	start := i
+	for i < len(buf) && buf[i] != '=' {
+		if buf[i] == '\\' {
+			i++
+		}
+		i++
+	}
+	if i > len(buf) {
+		i = len(buf)
+	}
+	return i, buf[start:i]
+}
+
 // scanTo returns the end position in buf and the next consecutive block
 // of bytes, starting from i and ending with stop byte.  If there are leading
 // spaces, they are skipped.
@@ -1508,7 +1555,12 @@ func walkFields(buf []byte, fn func(key, value []byte) bool) {
 	var i int
 	var key, val []byte
 	for len(buf) > 0 {
-		i, key = scanTo(buf, 0, '=')
+		i, key = scanFieldKey(buf, 0)
+		if i >= len(buf) {
+			// The fields were checked when the point was parsed, so this
+			// only happens with a corrupt point.
+			return
+		}
 		buf = buf[i+1:]
 		i, val = scanFieldValue(buf, 0)
 		buf = buf[i:]
@@ -1784,7 +1836,7 @@ func (p *point) Split(size int) []Point {
 	var start, cur int
 
 	for cur < len(p.fields) {
-		end, _ := scanTo(p.fields, cur, '=')
+		end, _ := scanFieldKey(p.fields, cur)
 		end, _ = scanFieldValue(p.fields, end+1)
 
 		if cur > start && end-start > size {
@@ -2139,7 +2191,7 @@ func (p *point) Next() bool {
 		return false
 	}
 
-	p.it.end, p.it.key = scanTo(p.fields, p.it.start, '=')
+	p.it.end, p.it.key = scanFieldKey(p.fields, p.it.start)
 	if escape.IsEscaped(p.it.key) {
 		p.it.keybuf = escape.AppendUnescaped(p.it.keybuf[:0], p.it.key)
 		p.it.key = p.it.keybuf
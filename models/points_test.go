@@ -525,6 +525,61 @@ func TestParsePointMissingFieldName(t *testing.T) {
 	}
 }
 
+// Ensure lines found by fuzzing return an error or a point that can be read,
+// instead of panicking.
+func TestParsePoint_Malformed(t *testing.T) {
+	pts, err := models.ParsePointsString(`cpu \\=1`)
+	if err != nil {
+		t.Fatal(err)
+	} else if fields, err := pts[0].Fields(); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(fields, models.Fields{`\\`: 1.0}) {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+
+	if _, err := models.ParsePointsString("cpu \x00=1"); err == nil || !strings.HasSuffix(err.Error(), "missing field key") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure a point is split between its fields when a field key ends in an
+// escaped backslash.
+func TestPoint_Split_EscapedFieldKey(t *testing.T) {
+	pts, err := models.ParsePointsString(`cpu \\=1,b=2 1000000000`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	split := pts[0].Split(1)
+	if len(split) != 2 {
+		t.Fatalf("unexpected points: %v", split)
+	}
+	for i, exp := range []models.Fields{{`\\`: 1.0}, {"b": 2.0}} {
+		if fields, err := split[i].Fields(); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(fields, exp) {
+			t.Fatalf("unexpected fields(%d): %v", i, fields)
+		}
+	}
+}
+
+func TestParsePoints_ParseError(t *testing.T) {
+	pts, err := models.ParsePointsString("cpu value=1\ncpu value=\ncpu value=2\ncpu\n")
+	if len(pts) != 2 {
+		t.Fatalf("unexpected points: %d", len(pts))
+	} else if perr, ok := err.(*models.ParseError); !ok || len(perr.Lines) != 2 {
+		t.Fatalf("unexpected error: %#v", err)
+	} else if n := models.ParseFailures(err); n != 2 {
+		t.Fatalf("unexpected failures: %d", n)
+	}
+
+	if _, err := models.ParsePointsString("cpu value=1\n"); err != nil {
+		t.Fatal(err)
+	} else if n := models.ParseFailures(err); n != 0 {
+		t.Fatalf("unexpected failures: %d", n)
+	}
+}
+
 func TestParsePointMissingFieldValue(t *testing.T) {
 	_, err := models.ParsePointsString(`cpu,host=serverA,region=us-west value=`)
 	if err == nil {
@@ -2387,8 +2442,3 @@ func BenchmarkParseTags(b *testing.B) {
 		models.ParseTags(tags)
 	}
 }
-
-func init() {
-	// Force uint support to be enabled for testing.
-	models.EnableUintSupport()
-}
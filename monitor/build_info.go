@@ -1,6 +1,10 @@
 package monitor
 
-import "github.com/influxdata/influxdb/monitor/diagnostics"
+import (
+	"runtime"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+)
 
 // build holds information of the build of the current executable.
 type build struct {
@@ -16,6 +20,7 @@ func (b *build) Diagnostics() (*diagnostics.Diagnostics, error) {
 		"Commit":     b.Commit,
 		"Branch":     b.Branch,
 		"Build Time": b.Time,
+		"Go Version": runtime.Version(),
 	}
 
 	return diagnostics.RowFromMap(d), nil
@@ -2,6 +2,7 @@ package monitor_test
 
 import (
 	"reflect"
+	"runtime"
 	"testing"
 
 	"github.com/influxdata/influxdb/monitor"
@@ -31,12 +32,12 @@ func TestDiagnostics_BuildInfo(t *testing.T) {
 		return
 	}
 
-	if got, exp := diags.Columns, []string{"Branch", "Build Time", "Commit", "Version"}; !reflect.DeepEqual(got, exp) {
+	if got, exp := diags.Columns, []string{"Branch", "Build Time", "Commit", "Go Version", "Version"}; !reflect.DeepEqual(got, exp) {
 		t.Errorf("unexpected columns: got=%v exp=%v", got, exp)
 	}
 
 	if got, exp := diags.Rows, [][]interface{}{
-		[]interface{}{"1.2", "10m30s", "b7bb7e8359642b6e071735b50ae41f5eb343fd42", "1.2.0"},
+		[]interface{}{"1.2", "10m30s", "b7bb7e8359642b6e071735b50ae41f5eb343fd42", runtime.Version(), "1.2.0"},
 	}; !reflect.DeepEqual(got, exp) {
 		t.Errorf("unexpected rows: got=%v exp=%v", got, exp)
 	}
@@ -18,6 +18,10 @@ const (
 
 	// DefaultStoreInterval is the period between storing gathered information.
 	DefaultStoreInterval = 10 * time.Second
+
+	// DefaultQueryAuditMaxLength is the default number of bytes of query text
+	// kept for an audited query.
+	DefaultQueryAuditMaxLength = 1024
 )
 
 // Config represents the configuration for the monitor service.
@@ -25,6 +29,16 @@ type Config struct {
 	StoreEnabled  bool          `toml:"store-enabled"`
 	StoreDatabase string        `toml:"store-database"`
 	StoreInterval toml.Duration `toml:"store-interval"`
+
+	// QueryAuditSampleRate is the fraction of finished queries recorded in
+	// the store database. A value of zero disables query auditing.
+	QueryAuditSampleRate float64 `toml:"query-audit-sample-rate"`
+	QueryAuditMaxLength  int     `toml:"query-audit-max-length"`
+
+	// TopMeasurements is the number of measurements of each database, with
+	// the most points written, whose writes are recorded. A value of zero
+	// records the writes of databases only.
+	TopMeasurements int `toml:"top-measurements"`
 }
 
 // NewConfig returns an instance of Config with defaults.
@@ -33,6 +47,8 @@ func NewConfig() Config {
 		StoreEnabled:  true,
 		StoreDatabase: DefaultStoreDatabase,
 		StoreInterval: toml.Duration(DefaultStoreInterval),
+
+		QueryAuditMaxLength: DefaultQueryAuditMaxLength,
 	}
 }
 
@@ -44,6 +60,15 @@ func (c Config) Validate() error {
 	if c.StoreDatabase == "" {
 		return errors.New("monitor store database name must not be empty")
 	}
+	if c.QueryAuditSampleRate < 0 || c.QueryAuditSampleRate > 1 {
+		return errors.New("monitor query audit sample rate must be between 0 and 1")
+	}
+	if c.QueryAuditMaxLength < 0 {
+		return errors.New("monitor query audit max length must not be negative")
+	}
+	if c.TopMeasurements < 0 {
+		return errors.New("monitor top measurements must not be negative")
+	}
 	return nil
 }
 
@@ -59,5 +84,8 @@ func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
 		"store-enabled":  true,
 		"store-database": c.StoreDatabase,
 		"store-interval": c.StoreInterval,
+
+		"query-audit-sample-rate": c.QueryAuditSampleRate,
+		"top-measurements":        c.TopMeasurements,
 	}), nil
 }
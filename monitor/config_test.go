@@ -15,6 +15,8 @@ func TestConfig_Parse(t *testing.T) {
 store-enabled=true
 store-database="the_db"
 store-interval="10m"
+query-audit-sample-rate=0.25
+query-audit-max-length=100
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -26,6 +28,10 @@ store-interval="10m"
 		t.Fatalf("unexpected store-database: %s", c.StoreDatabase)
 	} else if time.Duration(c.StoreInterval) != 10*time.Minute {
 		t.Fatalf("unexpected store-interval:  %s", c.StoreInterval)
+	} else if c.QueryAuditSampleRate != 0.25 {
+		t.Fatalf("unexpected query-audit-sample-rate: %v", c.QueryAuditSampleRate)
+	} else if c.QueryAuditMaxLength != 100 {
+		t.Fatalf("unexpected query-audit-max-length: %d", c.QueryAuditMaxLength)
 	}
 }
 
@@ -49,4 +55,11 @@ func TestConfig_Validate(t *testing.T) {
 	if err := c.Validate(); err == nil {
 		t.Fatalf("unexpected successful validation for %#v", c)
 	}
+
+	// Sample rates above one are invalid.
+	c = monitor.NewConfig()
+	c.QueryAuditSampleRate = 1.5
+	if err := c.Validate(); err == nil {
+		t.Fatalf("unexpected successful validation for %#v", c)
+	}
 }
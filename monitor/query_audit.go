@@ -0,0 +1,143 @@
+package monitor
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxql"
+)
+
+const (
+	// queryAuditMeasurement is the measurement audited queries are written to.
+	queryAuditMeasurement = "queryAudit"
+
+	// queryAuditBatchSize is the number of audited queries written at once.
+	queryAuditBatchSize = 100
+
+	// queryAuditFlushInterval is the longest an audited query is buffered.
+	queryAuditFlushInterval = time.Second
+)
+
+// AuditQuery records a sample of finished queries in the monitor database.
+// Queries are dropped rather than slowing down the query path when the
+// buffer is full.
+func (m *Monitor) AuditQuery(q query.FinishedQuery) {
+	if m.audits == nil {
+		return
+	} else if m.queryAuditSampleRate < 1 && rand.Float64() >= m.queryAuditSampleRate {
+		return
+	}
+
+	tags := make(map[string]string, 2)
+	if q.Database != "" {
+		tags["database"] = q.Database
+	}
+	if q.User != "" {
+		tags["user"] = q.User
+	}
+
+	pt, err := models.NewPoint(queryAuditMeasurement, models.NewTags(tags), models.Fields{
+		"query":      redactQuery(q.Query, m.queryAuditMaxLength),
+		"durationNs": q.Duration.Nanoseconds(),
+		"seriesN":    int64(q.Stats.SeriesN),
+		"pointN":     int64(q.Stats.PointN),
+	}, time.Now())
+	if err != nil {
+		return
+	}
+
+	select {
+	case m.audits <- pt:
+	default:
+	}
+}
+
+// storeQueryAudits writes audited queries to the monitor database in batches.
+func (m *Monitor) storeQueryAudits() {
+	defer m.wg.Done()
+	m.Logger.Info(fmt.Sprintf("Storing a %.2f%% sample of queries in database '%s' retention policy '%s'",
+		m.queryAuditSampleRate*100, m.storeDatabase, m.storeRetentionPolicy))
+
+	tick := time.NewTicker(queryAuditFlushInterval)
+	defer tick.Stop()
+
+	batch := make(models.Points, 0, queryAuditBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.createInternalStorage()
+		}()
+		m.WritePoints(batch)
+		batch = make(models.Points, 0, queryAuditBatchSize)
+	}
+
+	for {
+		select {
+		case pt := <-m.audits:
+			batch = append(batch, pt)
+			if len(batch) == cap(batch) {
+				flush()
+			}
+		case <-tick.C:
+			flush()
+		case <-m.done:
+			flush()
+			return
+		}
+	}
+}
+
+// redactedRegex replaces the regular expressions of redacted queries.
+var redactedRegex = regexp.MustCompile(`\?`)
+
+// redactQuery returns the text of q with all string, regular expression and
+// number literals replaced, so values such as user names, emails or account
+// numbers in conditions are not recorded, and truncated after max bytes.
+func redactQuery(q *influxql.Query, max int) string {
+	if q == nil {
+		return ""
+	}
+
+	// Work on a copy so the query is left untouched. A query that cannot be
+	// parsed back, such as one with a redacted password, is not recorded.
+	clone, err := influxql.ParseQuery(q.String())
+	if err != nil {
+		return ""
+	}
+	influxql.WalkFunc(clone, func(n influxql.Node) {
+		switch lit := n.(type) {
+		case *influxql.StringLiteral:
+			lit.Val = "?"
+		case *influxql.RegexLiteral:
+			lit.Val = redactedRegex
+		case *influxql.NumberLiteral:
+			lit.Val = 0
+		case *influxql.IntegerLiteral:
+			lit.Val = 0
+		case *influxql.UnsignedLiteral:
+			lit.Val = 0
+		}
+	})
+	return truncateQuery(clone.String(), max)
+}
+
+// truncateQuery cuts s after n bytes without splitting a character and marks
+// the cut with an ellipsis. A value of zero for n leaves s unchanged.
+func truncateQuery(s string, n int) string {
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "..."
+}
@@ -53,6 +53,10 @@ type Monitor struct {
 	storeRetentionPolicy string
 	storeInterval        time.Duration
 
+	queryAuditSampleRate float64
+	queryAuditMaxLength  int
+	audits               chan models.Point
+
 	MetaClient interface {
 		CreateDatabaseWithRetentionPolicy(name string, spec *meta.RetentionPolicySpec) (*meta.DatabaseInfo, error)
 		Database(name string) *meta.DatabaseInfo
@@ -71,7 +75,7 @@ type PointsWriter interface {
 
 // New returns a new instance of the monitor system.
 func New(r Reporter, c Config) *Monitor {
-	return &Monitor{
+	m := &Monitor{
 		globalTags:           make(map[string]string),
 		diagRegistrations:    make(map[string]diagnostics.Client),
 		reporter:             r,
@@ -79,8 +83,14 @@ func New(r Reporter, c Config) *Monitor {
 		storeDatabase:        c.StoreDatabase,
 		storeInterval:        time.Duration(c.StoreInterval),
 		storeRetentionPolicy: MonitorRetentionPolicy,
+		queryAuditSampleRate: c.QueryAuditSampleRate,
+		queryAuditMaxLength:  c.QueryAuditMaxLength,
 		Logger:               zap.NewNop(),
 	}
+	if c.StoreEnabled && c.QueryAuditSampleRate > 0 {
+		m.audits = make(chan models.Point, queryAuditBatchSize*10)
+	}
+	return m
 }
 
 // open returns whether the monitor service is open.
@@ -123,6 +133,11 @@ func (m *Monitor) Open() error {
 		// Start periodic writes to system.
 		m.wg.Add(1)
 		go m.storeStatistics()
+
+		if m.audits != nil {
+			m.wg.Add(1)
+			go m.storeQueryAudits()
+		}
 	}
 
 	return nil
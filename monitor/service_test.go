@@ -12,8 +12,10 @@ import (
 
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/monitor"
+	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/influxql"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest/observer"
 )
@@ -174,6 +176,76 @@ func TestMonitor_StoreStatistics(t *testing.T) {
 	}
 }
 
+func TestMonitor_AuditQuery(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	ch := make(chan models.Points)
+
+	var mc MetaClient
+	mc.CreateDatabaseWithRetentionPolicyFn = func(name string, spec *meta.RetentionPolicySpec) (*meta.DatabaseInfo, error) {
+		return &meta.DatabaseInfo{Name: name}, nil
+	}
+
+	var pw PointsWriter
+	pw.WritePointsFn = func(database, policy string, points models.Points) error {
+		select {
+		case <-done:
+		case ch <- points:
+		}
+		return nil
+	}
+
+	config := monitor.NewConfig()
+	config.StoreInterval = toml.Duration(time.Hour)
+	config.QueryAuditSampleRate = 1
+	config.QueryAuditMaxLength = 100
+	s := monitor.New(nil, config)
+	s.MetaClient = &mc
+	s.PointsWriter = &pw
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer s.Close()
+
+	s.AuditQuery(query.FinishedQuery{
+		Query:    influxql.MustParseQuery(`SELECT mean(value) FROM cpu WHERE "user" = 'jane@example.com' AND host =~ /server\d+/ AND value > 90 AND load < 1.5 GROUP BY time(1m)`),
+		Database: "db0",
+		User:     "admin",
+		Duration: time.Second,
+		Stats:    query.IteratorStats{SeriesN: 2, PointN: 10},
+	})
+
+	timer := time.NewTimer(5 * time.Second)
+	defer timer.Stop()
+	select {
+	case points := <-ch:
+		if len(points) != 1 {
+			t.Fatalf("unexpected points: %v", points)
+		}
+		pt := points[0]
+		if got, want := string(pt.Name()), "queryAudit"; got != want {
+			t.Errorf("unexpected name: got=%q want=%q", got, want)
+		}
+		if got := pt.Tags().GetString("user"); got != "admin" {
+			t.Errorf("unexpected user: %q", got)
+		}
+
+		fields, err := pt.Fields()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := fields["query"], `SELECT mean(value) FROM cpu WHERE "user" = '?' AND host =~ /\?/ AND value > 0 AND load < 0.000 GROUP...`; got != want {
+			t.Errorf("unexpected query: got=%q want=%q", got, want)
+		}
+		if got, want := fields["seriesN"], int64(2); got != want {
+			t.Errorf("unexpected seriesN: got=%v want=%v", got, want)
+		}
+	case <-timer.C:
+		t.Fatal("timeout while waiting for audited query to be written")
+	}
+}
+
 func TestMonitor_Reporter(t *testing.T) {
 	reporter := ReporterFunc(func(tags map[string]string) []models.Statistic {
 		return []models.Statistic{
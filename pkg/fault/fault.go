@@ -0,0 +1,61 @@
+// Package fault provides fault injection points for testing how the server
+// fails and recovers.
+//
+// The points do nothing unless the server is built with the faultinject tag,
+// which makes the faults settable through the /debug/faults endpoint:
+//
+//	go build -tags faultinject ./cmd/influxd
+//	curl -XPOST 'localhost:8086/debug/faults?point=shard-write&delay=2s'
+//	curl -XPOST 'localhost:8086/debug/faults?point=wal-write&crash=true'
+//	curl -XDELETE 'localhost:8086/debug/faults?point=shard-write'
+package fault
+
+import (
+	"errors"
+	"time"
+)
+
+// Injection points.
+const (
+	// ShardWrite is hit before points are written to a shard's engine.
+	ShardWrite = "shard-write"
+
+	// WALWrite is hit after points are written to the WAL, but before the
+	// write returns.
+	WALWrite = "wal-write"
+
+	// SubscriberWrite is hit before points are sent to a subscriber
+	// destination.
+	SubscriberWrite = "subscriber-write"
+)
+
+// Points is the list of injection points.
+var Points = []string{ShardWrite, WALWrite, SubscriberWrite}
+
+// ErrNotEnabled is returned when faults are set on a server built without
+// the faultinject tag.
+var ErrNotEnabled = errors.New("fault injection is not enabled in this build")
+
+// Fault is what happens when an injection point is hit. The delay comes
+// first, then the crash or the error.
+type Fault struct {
+	// Delay is how long the caller is held at the point.
+	Delay time.Duration `json:"delay,omitempty"`
+
+	// Error is the error returned to the caller, if not empty.
+	Error string `json:"error,omitempty"`
+
+	// Crash exits the process at once, without shutting down, as if it had
+	// been killed.
+	Crash bool `json:"crash,omitempty"`
+}
+
+// validPoint returns true if name is one of the injection points.
+func validPoint(name string) bool {
+	for _, p := range Points {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,18 @@
+// +build !faultinject
+
+package fault
+
+// Enabled is true when the server is built with the faultinject tag.
+const Enabled = false
+
+// Inject runs the fault set for point name, if any, and returns its error.
+func Inject(name string) error { return nil }
+
+// Set sets the fault of point name.
+func Set(name string, f Fault) error { return ErrNotEnabled }
+
+// Clear removes the fault of point name.
+func Clear(name string) {}
+
+// Faults returns the faults that are set, by injection point.
+func Faults() map[string]Fault { return nil }
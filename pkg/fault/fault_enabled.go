@@ -0,0 +1,70 @@
+// +build faultinject
+
+package fault
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Enabled is true when the server is built with the faultinject tag.
+const Enabled = true
+
+var (
+	mu     sync.RWMutex
+	faults = make(map[string]Fault)
+)
+
+// Inject runs the fault set for point name, if any, and returns its error.
+func Inject(name string) error {
+	mu.RLock()
+	f, ok := faults[name]
+	mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	if f.Delay > 0 {
+		time.Sleep(f.Delay)
+	}
+	if f.Crash {
+		fmt.Fprintf(os.Stderr, "fault injection: crashing at %s\n", name)
+		os.Exit(2)
+	}
+	if f.Error != "" {
+		return errors.New(f.Error)
+	}
+	return nil
+}
+
+// Set sets the fault of point name.
+func Set(name string, f Fault) error {
+	if !validPoint(name) {
+		return fmt.Errorf("unknown fault injection point %q", name)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	faults[name] = f
+	return nil
+}
+
+// Clear removes the fault of point name.
+func Clear(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(faults, name)
+}
+
+// Faults returns the faults that are set, by injection point.
+func Faults() map[string]Fault {
+	mu.RLock()
+	defer mu.RUnlock()
+	m := make(map[string]Fault, len(faults))
+	for k, v := range faults {
+		m[k] = v
+	}
+	return m
+}
@@ -0,0 +1,46 @@
+// +build faultinject
+
+package fault_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/pkg/fault"
+)
+
+func TestInject(t *testing.T) {
+	if err := fault.Inject(fault.ShardWrite); err != nil {
+		t.Fatalf("unexpected error without a fault: %s", err)
+	}
+
+	if err := fault.Set(fault.ShardWrite, fault.Fault{Delay: 10 * time.Millisecond, Error: "disk on fire"}); err != nil {
+		t.Fatal(err)
+	}
+	defer fault.Clear(fault.ShardWrite)
+
+	start := time.Now()
+	if err := fault.Inject(fault.ShardWrite); err == nil || err.Error() != "disk on fire" {
+		t.Fatalf("unexpected error: %v", err)
+	} else if d := time.Since(start); d < 10*time.Millisecond {
+		t.Fatalf("unexpected delay: %s", d)
+	}
+	if err := fault.Inject(fault.WALWrite); err != nil {
+		t.Fatalf("unexpected error at another point: %s", err)
+	}
+
+	if faults := fault.Faults(); len(faults) != 1 || faults[fault.ShardWrite].Error != "disk on fire" {
+		t.Fatalf("unexpected faults: %v", faults)
+	}
+
+	fault.Clear(fault.ShardWrite)
+	if err := fault.Inject(fault.ShardWrite); err != nil {
+		t.Fatalf("unexpected error after clear: %s", err)
+	}
+}
+
+func TestSet_UnknownPoint(t *testing.T) {
+	if err := fault.Set("no-such-point", fault.Fault{Error: "x"}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -2,7 +2,10 @@
 
 package file
 
-import "os"
+import (
+	"os"
+	"syscall"
+)
 
 func SyncDir(dirName string) error {
 	// fsync the dir to flush the rename
@@ -18,3 +21,13 @@ func SyncDir(dirName string) error {
 func RenameFile(oldpath, newpath string) error {
 	return os.Rename(oldpath, newpath)
 }
+
+// DiskFree returns the number of bytes available to unprivileged users on
+// the filesystem containing path.
+func DiskFree(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
@@ -1,6 +1,12 @@
 package file
 
-import "os"
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
 
 func SyncDir(dirName string) error {
 	return nil
@@ -16,3 +22,18 @@ func RenameFile(oldpath, newpath string) error {
 
 	return os.Rename(oldpath, newpath)
 }
+
+// DiskFree returns the number of bytes available to the current user on the
+// volume containing path.
+func DiskFree(path string) (uint64, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var free uint64
+	if r, _, err := procGetDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&free)), 0, 0); r == 0 {
+		return 0, err
+	}
+	return free, nil
+}
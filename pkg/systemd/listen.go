@@ -0,0 +1,107 @@
+// Package systemd implements the parts of the systemd protocols influxd
+// takes part in: socket activation and readiness notification.
+package systemd // import "github.com/influxdata/influxdb/pkg/systemd"
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd.
+const listenFDsStart = 3
+
+var (
+	activatedOnce sync.Once
+	activatedMu   sync.Mutex
+	activated     []net.Listener
+)
+
+// Listen returns the listener passed by systemd socket activation that is
+// bound to addr, or a new listener on addr if none was passed. Each listener
+// passed is returned once, so a process reopening a service listens again
+// itself.
+func Listen(network, addr string) (net.Listener, error) {
+	activatedOnce.Do(func() {
+		activated = filesListeners(listenFiles())
+	})
+
+	activatedMu.Lock()
+	for i, ln := range activated {
+		if ln.Addr().Network() == network && matchAddr(ln.Addr(), addr) {
+			activated = append(activated[:i], activated[i+1:]...)
+			activatedMu.Unlock()
+			return ln, nil
+		}
+	}
+	activatedMu.Unlock()
+
+	return net.Listen(network, addr)
+}
+
+// listenFiles returns the files of the sockets passed by systemd. The
+// environment variables passing them are unset, so the processes started
+// by influxd don't take them for their own.
+func listenFiles() []*os.File {
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+	defer os.Unsetenv("LISTEN_FDNAMES")
+
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil
+	}
+
+	files := make([]*os.File, 0, n)
+	for fd := listenFDsStart; fd < listenFDsStart+n; fd++ {
+		files = append(files, os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd)))
+	}
+	return files
+}
+
+// filesListeners returns the listeners of the stream sockets of files.
+// Other sockets, such as datagram ones, are closed.
+func filesListeners(files []*os.File) []net.Listener {
+	var listeners []net.Listener
+	for _, f := range files {
+		// FileListener duplicates the descriptor, so f is closed either way.
+		if ln, err := net.FileListener(f); err == nil {
+			listeners = append(listeners, ln)
+		}
+		f.Close()
+	}
+	return listeners
+}
+
+// matchAddr returns true if a is the address addr, as given in the config,
+// resolves to. An addr with no host matches a on any host, as systemd binds
+// a ListenStream= of only a port to all of them.
+func matchAddr(a net.Addr, addr string) bool {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	tcp, ok := a.(*net.TCPAddr)
+	if !ok {
+		return a.String() == addr
+	} else if strconv.Itoa(tcp.Port) != port {
+		return false
+	} else if host == "" {
+		return true
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return false
+	}
+	for _, ip := range ips {
+		if ip.Equal(tcp.IP) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package systemd
+
+import (
+	"net"
+	"os"
+)
+
+const (
+	// Ready tells systemd the service has started and is ready to serve.
+	Ready = "READY=1"
+
+	// Stopping tells systemd the service is shutting down.
+	Stopping = "STOPPING=1"
+)
+
+// Notify sends state to the service manager, such as Ready once the service
+// is ready. It returns false if no manager is listening, as when influxd is
+// not run by systemd or the unit is not of Type=notify.
+func Notify(state string) (bool, error) {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return false, nil
+	}
+	// A name starting with @ is in the abstract namespace.
+	if addr[0] == '@' {
+		addr = "\x00" + addr[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
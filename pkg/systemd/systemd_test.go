@@ -0,0 +1,94 @@
+package systemd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilesListeners(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	pf, err := pc.(*net.UDPConn).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The datagram socket is not a listener and is left out.
+	listeners := filesListeners([]*os.File{f, pf})
+	if len(listeners) != 1 {
+		t.Fatalf("unexpected number of listeners: %d", len(listeners))
+	}
+	defer listeners[0].Close()
+	if got, exp := listeners[0].Addr().String(), ln.Addr().String(); got != exp {
+		t.Fatalf("unexpected address: got %s, exp %s", got, exp)
+	}
+}
+
+func TestMatchAddr(t *testing.T) {
+	for _, tt := range []struct {
+		addr string
+		exp  bool
+	}{
+		{addr: ":8086", exp: true},
+		{addr: "127.0.0.1:8086", exp: true},
+		{addr: "localhost:8086", exp: true},
+		{addr: "10.0.0.1:8086", exp: false},
+		{addr: ":8088", exp: false},
+		{addr: "8086", exp: false},
+	} {
+		a := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8086}
+		if got := matchAddr(a, tt.addr); got != tt.exp {
+			t.Errorf("%s: got %v, exp %v", tt.addr, got, tt.exp)
+		}
+	}
+}
+
+func TestNotify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "systemd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	defer os.Unsetenv("NOTIFY_SOCKET")
+	os.Unsetenv("NOTIFY_SOCKET")
+	if ok, err := Notify(Ready); ok || err != nil {
+		t.Fatalf("unexpected notification without a socket: %v, %v", ok, err)
+	}
+
+	os.Setenv("NOTIFY_SOCKET", path)
+	if ok, err := Notify(Ready); !ok || err != nil {
+		t.Fatalf("unexpected result: %v, %v", ok, err)
+	}
+
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	} else if got := string(buf[:n]); got != Ready {
+		t.Fatalf("unexpected state: %q", got)
+	}
+}
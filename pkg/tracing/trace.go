@@ -80,6 +80,18 @@ func (t *Trace) Tree() *TreeNode {
 	return nil
 }
 
+// Spans returns the finished spans of the trace, in no particular order.
+func (t *Trace) Spans() []RawSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	spans := make([]RawSpan, 0, len(t.spans))
+	for _, s := range t.spans {
+		spans = append(spans, s)
+	}
+	return spans
+}
+
 // Merge combines other with the current trace. This is
 // typically necessary when traces are transferred from a remote.
 func (t *Trace) Merge(other *Trace) {
@@ -97,6 +97,44 @@ func newCountIterator(input Iterator, opt IteratorOptions) (Iterator, error) {
 	}
 }
 
+// newCountHLLIterator returns an iterator for operating on a count_hll() call.
+func newCountHLLIterator(input Iterator, opt IteratorOptions) (Iterator, error) {
+	switch input := input.(type) {
+	case FloatIterator:
+		createFn := func() (FloatPointAggregator, IntegerPointEmitter) {
+			fn := NewCountHLLReducer()
+			return fn, fn
+		}
+		return newFloatReduceIntegerIterator(input, opt, createFn), nil
+	case IntegerIterator:
+		createFn := func() (IntegerPointAggregator, IntegerPointEmitter) {
+			fn := NewCountHLLReducer()
+			return fn, fn
+		}
+		return newIntegerReduceIntegerIterator(input, opt, createFn), nil
+	case UnsignedIterator:
+		createFn := func() (UnsignedPointAggregator, IntegerPointEmitter) {
+			fn := NewCountHLLReducer()
+			return fn, fn
+		}
+		return newUnsignedReduceIntegerIterator(input, opt, createFn), nil
+	case StringIterator:
+		createFn := func() (StringPointAggregator, IntegerPointEmitter) {
+			fn := NewCountHLLReducer()
+			return fn, fn
+		}
+		return newStringReduceIntegerIterator(input, opt, createFn), nil
+	case BooleanIterator:
+		createFn := func() (BooleanPointAggregator, IntegerPointEmitter) {
+			fn := NewCountHLLReducer()
+			return fn, fn
+		}
+		return newBooleanReduceIntegerIterator(input, opt, createFn), nil
+	default:
+		return nil, fmt.Errorf("unsupported count_hll iterator type: %T", input)
+	}
+}
+
 // FloatCountReduce returns the count of points.
 func FloatCountReduce(prev *IntegerPoint, curr *FloatPoint) (int64, int64, []interface{}) {
 	if prev == nil {
@@ -1221,6 +1259,44 @@ func newElapsedIterator(input Iterator, opt IteratorOptions, interval Interval)
 	}
 }
 
+// newGapsIterator returns an iterator for operating on a gaps() call.
+func newGapsIterator(input Iterator, opt IteratorOptions, threshold, unit Interval) (Iterator, error) {
+	switch input := input.(type) {
+	case FloatIterator:
+		createFn := func() (FloatPointAggregator, IntegerPointEmitter) {
+			fn := NewGapsReducer(threshold, unit)
+			return fn, fn
+		}
+		return newFloatStreamIntegerIterator(input, createFn, opt), nil
+	case IntegerIterator:
+		createFn := func() (IntegerPointAggregator, IntegerPointEmitter) {
+			fn := NewGapsReducer(threshold, unit)
+			return fn, fn
+		}
+		return newIntegerStreamIntegerIterator(input, createFn, opt), nil
+	case UnsignedIterator:
+		createFn := func() (UnsignedPointAggregator, IntegerPointEmitter) {
+			fn := NewGapsReducer(threshold, unit)
+			return fn, fn
+		}
+		return newUnsignedStreamIntegerIterator(input, createFn, opt), nil
+	case BooleanIterator:
+		createFn := func() (BooleanPointAggregator, IntegerPointEmitter) {
+			fn := NewGapsReducer(threshold, unit)
+			return fn, fn
+		}
+		return newBooleanStreamIntegerIterator(input, createFn, opt), nil
+	case StringIterator:
+		createFn := func() (StringPointAggregator, IntegerPointEmitter) {
+			fn := NewGapsReducer(threshold, unit)
+			return fn, fn
+		}
+		return newStringStreamIntegerIterator(input, createFn, opt), nil
+	default:
+		return nil, fmt.Errorf("unsupported gaps iterator type: %T", input)
+	}
+}
+
 // newMovingAverageIterator returns an iterator for operating on a moving_average() call.
 func newMovingAverageIterator(input Iterator, n int, opt IteratorOptions) (Iterator, error) {
 	switch input := input.(type) {
@@ -3,6 +3,7 @@ package query
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
@@ -122,8 +123,13 @@ func (c *compiledStatement) preprocess(stmt *influxql.SelectStatement) error {
 	c.Limit = stmt.Limit
 	c.HasTarget = stmt.Target != nil
 
+	cond, err := rewriteContains(stmt.Condition)
+	if err != nil {
+		return err
+	}
+
 	valuer := influxql.NowValuer{Now: c.Options.Now, Location: stmt.Location}
-	cond, t, err := influxql.ConditionExpr(stmt.Condition, &valuer)
+	cond, t, err := influxql.ConditionExpr(cond, &valuer)
 	if err != nil {
 		return err
 	}
@@ -265,6 +271,8 @@ func (c *compiledField) compileExpr(expr influxql.Expr) error {
 			return c.compileMovingAverage(expr.Args)
 		case "elapsed":
 			return c.compileElapsed(expr.Args)
+		case "gaps":
+			return c.compileGaps(expr.Args)
 		case "integral":
 			return c.compileIntegral(expr.Args)
 		case "holt_winters", "holt_winters_with_fit":
@@ -334,7 +342,7 @@ func (c *compiledField) compileFunction(expr *influxql.Call) error {
 	switch expr.Name {
 	case "max", "min", "first", "last":
 		// top/bottom are not included here since they are not typical functions.
-	case "count", "sum", "mean", "median", "mode", "stddev", "spread":
+	case "count", "count_hll", "sum", "mean", "median", "mode", "stddev", "spread":
 		// These functions are not considered selectors.
 		c.global.OnlySelectors = false
 	default:
@@ -459,6 +467,42 @@ func (c *compiledField) compileElapsed(args []influxql.Expr) error {
 	}
 }
 
+func (c *compiledField) compileGaps(args []influxql.Expr) error {
+	if min, max, got := 2, 3, len(args); got > max || got < min {
+		return fmt.Errorf("invalid number of arguments for gaps, expected at least %d but no more than %d, got %d", min, max, got)
+	}
+
+	// The threshold is required and the unit is optional.
+	for i, arg := range args[1:] {
+		switch arg := arg.(type) {
+		case *influxql.DurationLiteral:
+			if arg.Val <= 0 {
+				return fmt.Errorf("duration argument must be positive, got %s", influxql.FormatDuration(arg.Val))
+			}
+		default:
+			if i == 0 {
+				return fmt.Errorf("second argument to gaps must be a duration, got %T", arg)
+			}
+			return fmt.Errorf("third argument to gaps must be a duration, got %T", arg)
+		}
+	}
+	c.global.OnlySelectors = false
+
+	// Must be a variable reference, function, wildcard, or regexp.
+	switch arg0 := args[0].(type) {
+	case *influxql.Call:
+		if c.global.Interval.IsZero() {
+			return fmt.Errorf("gaps aggregate requires a GROUP BY interval")
+		}
+		return c.compileExpr(arg0)
+	default:
+		if !c.global.Interval.IsZero() {
+			return fmt.Errorf("aggregate function required inside the call to gaps")
+		}
+		return c.compileSymbol("gaps", arg0)
+	}
+}
+
 func (c *compiledField) compileDifference(args []influxql.Expr, isNonNegative bool) error {
 	name := "difference"
 	if isNonNegative {
@@ -749,6 +793,45 @@ func (c *compiledStatement) validateFields() error {
 	return nil
 }
 
+// rewriteContains rewrites the calls to contains() in a condition into the
+// regular expressions matching the same values. This lets the storage engine
+// filter string fields and tags by substring while it scans them, as it does
+// with any other regular expression.
+func rewriteContains(cond influxql.Expr) (influxql.Expr, error) {
+	if cond == nil {
+		return nil, nil
+	}
+
+	var err error
+	cond = influxql.RewriteExpr(cond, func(expr influxql.Expr) influxql.Expr {
+		call, ok := expr.(*influxql.Call)
+		if !ok || call.Name != "contains" || err != nil {
+			return expr
+		}
+
+		if exp, got := 2, len(call.Args); got != exp {
+			err = fmt.Errorf("invalid number of arguments for contains, expected %d, got %d", exp, got)
+			return expr
+		}
+		ref, ok := call.Args[0].(*influxql.VarRef)
+		if !ok {
+			err = errors.New("expected field or tag argument in contains()")
+			return expr
+		}
+		lit, ok := call.Args[1].(*influxql.StringLiteral)
+		if !ok {
+			err = errors.New("expected string argument in contains()")
+			return expr
+		}
+		return &influxql.BinaryExpr{
+			Op:  influxql.EQREGEX,
+			LHS: ref,
+			RHS: &influxql.RegexLiteral{Val: regexp.MustCompile(regexp.QuoteMeta(lit.Val))},
+		}
+	})
+	return cond, err
+}
+
 // subquery compiles and validates a compiled statement for the subquery using
 // this compiledStatement as the parent.
 func (c *compiledStatement) subquery(stmt *influxql.SelectStatement) error {
@@ -760,8 +843,12 @@ func (c *compiledStatement) subquery(stmt *influxql.SelectStatement) error {
 	// Substitute now() into the subquery condition. Then use ConditionExpr to
 	// validate the expression. Do not store the results. We have no way to store
 	// and read those results at the moment.
+	cond, err := rewriteContains(stmt.Condition)
+	if err != nil {
+		return err
+	}
 	valuer := influxql.NowValuer{Now: c.Options.Now, Location: stmt.Location}
-	stmt.Condition = influxql.Reduce(stmt.Condition, &valuer)
+	stmt.Condition = influxql.Reduce(cond, &valuer)
 
 	// If the ordering is different and the sort field was specified for the subquery,
 	// throw an error.
@@ -789,6 +876,60 @@ func (c *compiledStatement) subquery(stmt *influxql.SelectStatement) error {
 	return subquery.compile(stmt)
 }
 
+// boundTimeRange limits the start time of a statement without a lower time
+// bound if it would read more than the maximum unbounded size, and returns a
+// warning telling the user so. The measurements of subqueries are counted
+// too, unless the subquery has a lower time bound of its own.
+func (c *compiledStatement) boundTimeRange(stmt *influxql.SelectStatement, ic IteratorCreator, opt *IteratorOptions, sopt SelectOptions) (*Message, error) {
+	size, err := unboundedSize(stmt.Sources, ic, *opt)
+	if err != nil {
+		return nil, err
+	}
+	if size <= sopt.MaxUnboundedSize {
+		return nil, nil
+	} else if sopt.UnboundedRange <= 0 {
+		return nil, fmt.Errorf("unbounded-select-size limit exceeded: (%d/%d), add a time condition to the query", size, sopt.MaxUnboundedSize)
+	}
+
+	end := opt.EndTime
+	if now := c.Options.Now.UnixNano(); end > now {
+		end = now
+	}
+	opt.StartTime = end - int64(sopt.UnboundedRange)
+	return BoundedTimeRangeWarning(time.Unix(0, opt.StartTime).UTC(), size, sopt.MaxUnboundedSize), nil
+}
+
+// unboundedSize returns the size of the blocks read from the measurements of
+// sources, and of the subqueries among them that read from the same start
+// time, using the options of the statement reading them.
+func unboundedSize(sources influxql.Sources, ic IteratorCreator, opt IteratorOptions) (int64, error) {
+	var size int64
+	for _, source := range sources {
+		switch source := source.(type) {
+		case *influxql.Measurement:
+			cost, err := ic.IteratorCost(source, opt)
+			if err != nil {
+				return 0, err
+			}
+			size += cost.BlockSize
+		case *influxql.SubQuery:
+			subOpt, err := newIteratorOptionsSubstatement(source.Statement, opt)
+			if err != nil {
+				return 0, err
+			} else if subOpt.StartTime > opt.StartTime {
+				continue
+			}
+
+			n, err := unboundedSize(source.Statement.Sources, ic, subOpt)
+			if err != nil {
+				return 0, err
+			}
+			size += n
+		}
+	}
+	return size, nil
+}
+
 func (c *compiledStatement) Prepare(shardMapper ShardMapper, sopt SelectOptions) (PreparedStatement, error) {
 	// If this is a query with a grouping, there is a bucket limit, and the minimum time has not been specified,
 	// we need to limit the possible time range that can be used when mapping shards but not when actually executing
@@ -848,6 +989,17 @@ func (c *compiledStatement) Prepare(shardMapper ShardMapper, sopt SelectOptions)
 	opt.StartTime, opt.EndTime = c.TimeRange.MinTimeNano(), c.TimeRange.MaxTimeNano()
 	opt.Ascending = c.Ascending
 
+	var warnings []*Message
+	if sopt.MaxUnboundedSize > 0 && c.TimeRange.MinTimeNano() == influxql.MinTime {
+		warning, err := c.boundTimeRange(stmt, shards, &opt, sopt)
+		if err != nil {
+			shards.Close()
+			return nil, err
+		} else if warning != nil {
+			warnings = append(warnings, warning)
+		}
+	}
+
 	if sopt.MaxBucketsN > 0 && !stmt.IsRawQuery && c.TimeRange.MinTimeNano() > influxql.MinTime {
 		interval, err := stmt.GroupByInterval()
 		if err != nil {
@@ -869,11 +1021,48 @@ func (c *compiledStatement) Prepare(shardMapper ShardMapper, sopt SelectOptions)
 		}
 	}
 
+	if sopt.WarnSeriesN > 0 || sopt.WarnBlocksN > 0 {
+		a, err := costWarnings(stmt, shards, opt, sopt)
+		if err != nil {
+			shards.Close()
+			return nil, err
+		}
+		warnings = append(warnings, a...)
+	}
+
 	columns := stmt.ColumnNames()
 	return &preparedStatement{
-		stmt:    stmt,
-		opt:     opt,
-		ic:      shards,
-		columns: columns,
+		stmt:     stmt,
+		opt:      opt,
+		ic:       shards,
+		columns:  columns,
+		warnings: warnings,
 	}, nil
 }
+
+// costWarnings returns the warnings for a statement reading more series or
+// blocks than the warning thresholds. Only the measurements of the statement
+// itself are counted.
+func costWarnings(stmt *influxql.SelectStatement, ic IteratorCreator, opt IteratorOptions, sopt SelectOptions) ([]*Message, error) {
+	var cost IteratorCost
+	for _, source := range stmt.Sources {
+		m, ok := source.(*influxql.Measurement)
+		if !ok {
+			continue
+		}
+		c, err := ic.IteratorCost(m, opt)
+		if err != nil {
+			return nil, err
+		}
+		cost = cost.Combine(c)
+	}
+
+	var warnings []*Message
+	if sopt.WarnSeriesN > 0 && cost.NumSeries > int64(sopt.WarnSeriesN) {
+		warnings = append(warnings, ExpensiveQueryWarning("series", cost.NumSeries, int64(sopt.WarnSeriesN)))
+	}
+	if sopt.WarnBlocksN > 0 && cost.BlocksRead > int64(sopt.WarnBlocksN) {
+		warnings = append(warnings, ExpensiveQueryWarning("blocks", cost.BlocksRead, int64(sopt.WarnBlocksN)))
+	}
+	return warnings, nil
+}
@@ -38,6 +38,7 @@ func TestCompile_Success(t *testing.T) {
 		`SELECT count(value) FROM cpu`,
 		`SELECT count(distinct(value)) FROM cpu`,
 		`SELECT count(distinct value) FROM cpu`,
+		`SELECT count_hll(value) FROM cpu`,
 		`SELECT count(*) FROM cpu`,
 		`SELECT count(/val/) FROM cpu`,
 		`SELECT mean(value) FROM cpu`,
@@ -70,6 +71,9 @@ func TestCompile_Success(t *testing.T) {
 		`SELECT sample(/val/, 2) FROM cpu`,
 		`SELECT elapsed(value) FROM cpu`,
 		`SELECT elapsed(value, 10s) FROM cpu`,
+		`SELECT gaps(value, 1m) FROM cpu`,
+		`SELECT gaps(value, 1m, 1s) FROM cpu`,
+		`SELECT gaps(count(value), 1m) FROM cpu WHERE time >= now() - 1h GROUP BY time(10s)`,
 		`SELECT integral(value) FROM cpu`,
 		`SELECT integral(value, 10s) FROM cpu`,
 		`SELECT max(value) FROM cpu WHERE time >= now() - 1m GROUP BY time(10s, 5s)`,
@@ -81,6 +85,9 @@ func TestCompile_Success(t *testing.T) {
 		`SELECT value FROM cpu WHERE time >= '2000-01-01T00:00:00Z' AND time <= '2000-01-01T01:00:00Z'`,
 		`SELECT value FROM (SELECT value FROM cpu) ORDER BY time DESC`,
 		`SELECT count(distinct(value)), max(value) FROM cpu`,
+		`SELECT value FROM cpu WHERE contains(message, 'error')`,
+		`SELECT value FROM cpu WHERE contains(message, 'error') AND time >= now() - 1m`,
+		`SELECT value FROM (SELECT value, message FROM cpu WHERE contains(message, 'error'))`,
 	} {
 		t.Run(tt, func(t *testing.T) {
 			stmt, err := influxql.ParseStatement(tt)
@@ -109,6 +116,7 @@ func TestCompile_Failures(t *testing.T) {
 		{s: `SELECT bottom(value, 10), max(value) FROM cpu`, err: `selector function bottom() cannot be combined with other functions`},
 		{s: `SELECT count() FROM cpu`, err: `invalid number of arguments for count, expected 1, got 0`},
 		{s: `SELECT count(value, host) FROM cpu`, err: `invalid number of arguments for count, expected 1, got 2`},
+		{s: `SELECT count_hll() FROM cpu`, err: `invalid number of arguments for count_hll, expected 1, got 0`},
 		{s: `SELECT min() FROM cpu`, err: `invalid number of arguments for min, expected 1, got 0`},
 		{s: `SELECT min(value, host) FROM cpu`, err: `invalid number of arguments for min, expected 1, got 2`},
 		{s: `SELECT max() FROM cpu`, err: `invalid number of arguments for max, expected 1, got 0`},
@@ -263,6 +271,12 @@ func TestCompile_Failures(t *testing.T) {
 		{s: `SELECT elapsed(max()) FROM myseries where time < now() and time > now() - 1d group by time(1h)`, err: `invalid number of arguments for max, expected 1, got 0`},
 		{s: `SELECT elapsed(percentile(value)) FROM myseries where time < now() and time > now() - 1d group by time(1h)`, err: `invalid number of arguments for percentile, expected 2, got 1`},
 		{s: `SELECT elapsed(mean(value)) FROM myseries where time < now() and time > now() - 1d`, err: `elapsed aggregate requires a GROUP BY interval`},
+		{s: `SELECT gaps(value) FROM myseries`, err: `invalid number of arguments for gaps, expected at least 2 but no more than 3, got 1`},
+		{s: `SELECT gaps(value, 1m) FROM myseries group by time(1h)`, err: `aggregate function required inside the call to gaps`},
+		{s: `SELECT gaps(value, 0s) FROM myseries`, err: `duration argument must be positive, got 0s`},
+		{s: `SELECT gaps(value, 10) FROM myseries`, err: `second argument to gaps must be a duration, got *influxql.IntegerLiteral`},
+		{s: `SELECT gaps(value, 1m, 10) FROM myseries`, err: `third argument to gaps must be a duration, got *influxql.IntegerLiteral`},
+		{s: `SELECT gaps(mean(value), 1m) FROM myseries where time < now() and time > now() - 1d`, err: `gaps aggregate requires a GROUP BY interval`},
 		{s: `SELECT moving_average(field1, 2), field1 FROM myseries`, err: `mixing aggregate and non-aggregate queries is not supported`},
 		{s: `SELECT moving_average(field1, 1), field1 FROM myseries`, err: `moving_average window must be greater than 1, got 1`},
 		{s: `SELECT moving_average(field1, 0), field1 FROM myseries`, err: `moving_average window must be greater than 1, got 0`},
@@ -317,6 +331,10 @@ func TestCompile_Failures(t *testing.T) {
 		{s: `SELECT value FROM myseries WHERE value OR time >= now() - 1m`, err: `invalid condition expression: value`},
 		{s: `SELECT value FROM myseries WHERE time >= now() - 1m OR value`, err: `invalid condition expression: value`},
 		{s: `SELECT value FROM (SELECT value FROM cpu ORDER BY time DESC) ORDER BY time ASC`, err: `subqueries must be ordered in the same direction as the query itself`},
+		{s: `SELECT value FROM cpu WHERE contains(message)`, err: `invalid number of arguments for contains, expected 2, got 1`},
+		{s: `SELECT value FROM cpu WHERE contains('error', message)`, err: `expected field or tag argument in contains()`},
+		{s: `SELECT value FROM cpu WHERE contains(message, 1)`, err: `expected string argument in contains()`},
+		{s: `SELECT value FROM (SELECT value FROM cpu WHERE contains(message, 1))`, err: `expected string argument in contains()`},
 	} {
 		t.Run(tt.s, func(t *testing.T) {
 			stmt, err := influxql.ParseStatement(tt.s)
@@ -2,10 +2,12 @@ package query
 
 import (
 	"container/heap"
+	"encoding/binary"
 	"math"
 	"sort"
 	"time"
 
+	"github.com/influxdata/influxdb/pkg/estimator/hll"
 	"github.com/influxdata/influxdb/query/neldermead"
 	"github.com/influxdata/influxql"
 )
@@ -1572,3 +1574,117 @@ func (r *UnsignedBottomReducer) Emit() []UnsignedPoint {
 	sort.Sort(sort.Reverse(&h))
 	return points
 }
+
+// GapsReducer finds the gaps between consecutive points that are longer
+// than a threshold. Only the times of the points are used, so it aggregates
+// points of any type.
+type GapsReducer struct {
+	threshold      int64
+	unitConversion int64
+	prev           int64
+	curr           int64
+	n              int
+}
+
+// NewGapsReducer creates a new GapsReducer.
+func NewGapsReducer(threshold, unit Interval) *GapsReducer {
+	return &GapsReducer{
+		threshold:      int64(threshold.Duration),
+		unitConversion: int64(unit.Duration),
+	}
+}
+
+// AggregateFloat aggregates a point into the reducer.
+func (r *GapsReducer) AggregateFloat(p *FloatPoint) { r.aggregate(p.Time) }
+
+// AggregateInteger aggregates a point into the reducer.
+func (r *GapsReducer) AggregateInteger(p *IntegerPoint) { r.aggregate(p.Time) }
+
+// AggregateUnsigned aggregates a point into the reducer.
+func (r *GapsReducer) AggregateUnsigned(p *UnsignedPoint) { r.aggregate(p.Time) }
+
+// AggregateString aggregates a point into the reducer.
+func (r *GapsReducer) AggregateString(p *StringPoint) { r.aggregate(p.Time) }
+
+// AggregateBoolean aggregates a point into the reducer.
+func (r *GapsReducer) AggregateBoolean(p *BooleanPoint) { r.aggregate(p.Time) }
+
+func (r *GapsReducer) aggregate(t int64) {
+	r.prev, r.curr = r.curr, t
+	if r.n < 2 {
+		r.n++
+	}
+}
+
+// Emit emits the gap between the previous and the current point if it is
+// longer than the threshold. The gap is reported at the time it starts, in
+// both ascending and descending order, and its length is in the unit of the
+// reducer.
+func (r *GapsReducer) Emit() []IntegerPoint {
+	if r.n < 2 {
+		return nil
+	}
+
+	start, end := r.prev, r.curr
+	if start > end {
+		start, end = end, start
+	}
+	if end-start <= r.threshold {
+		return nil
+	}
+	return []IntegerPoint{
+		{Time: start, Value: (end - start) / r.unitConversion},
+	}
+}
+
+// CountHLLReducer estimates the number of distinct values of the aggregated
+// points with a HyperLogLog++ sketch. Its memory use is bounded no matter
+// how many distinct values there are, unlike count(distinct()).
+type CountHLLReducer struct {
+	sketch *hll.Plus
+	buf    [8]byte
+}
+
+// NewCountHLLReducer creates a new CountHLLReducer.
+func NewCountHLLReducer() *CountHLLReducer {
+	return &CountHLLReducer{sketch: hll.NewDefaultPlus()}
+}
+
+// AggregateFloat aggregates a point into the reducer.
+func (r *CountHLLReducer) AggregateFloat(p *FloatPoint) {
+	r.addUint64(math.Float64bits(p.Value))
+}
+
+// AggregateInteger aggregates a point into the reducer.
+func (r *CountHLLReducer) AggregateInteger(p *IntegerPoint) {
+	r.addUint64(uint64(p.Value))
+}
+
+// AggregateUnsigned aggregates a point into the reducer.
+func (r *CountHLLReducer) AggregateUnsigned(p *UnsignedPoint) {
+	r.addUint64(p.Value)
+}
+
+// AggregateString aggregates a point into the reducer.
+func (r *CountHLLReducer) AggregateString(p *StringPoint) {
+	r.sketch.Add([]byte(p.Value))
+}
+
+// AggregateBoolean aggregates a point into the reducer.
+func (r *CountHLLReducer) AggregateBoolean(p *BooleanPoint) {
+	if p.Value {
+		r.addUint64(1)
+	} else {
+		r.addUint64(0)
+	}
+}
+
+func (r *CountHLLReducer) addUint64(v uint64) {
+	binary.BigEndian.PutUint64(r.buf[:], v)
+	r.sketch.Add(r.buf[:])
+}
+
+// Emit emits the estimated number of distinct values.
+func (r *CountHLLReducer) Emit() []IntegerPoint {
+	return []IntegerPoint{{Time: ZeroTime, Value: int64(r.sketch.Count())}}
+}
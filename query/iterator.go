@@ -982,6 +982,19 @@ func (opt IteratorOptions) ElapsedInterval() Interval {
 	return Interval{Duration: time.Nanosecond}
 }
 
+// GapsInterval returns the threshold and the time unit for the gaps function.
+func (opt IteratorOptions) GapsInterval() (threshold, unit Interval) {
+	unit = Interval{Duration: time.Nanosecond}
+	if expr, ok := opt.Expr.(*influxql.Call); ok {
+		threshold = Interval{Duration: expr.Args[1].(*influxql.DurationLiteral).Val}
+		// Use the unit on the gaps() call, if specified.
+		if len(expr.Args) == 3 {
+			unit = Interval{Duration: expr.Args[2].(*influxql.DurationLiteral).Val}
+		}
+	}
+	return threshold, unit
+}
+
 // IntegralInterval returns the time interval for the integral function.
 func (opt IteratorOptions) IntegralInterval() Interval {
 	// Use the interval on the integral() call, if specified.
@@ -1401,6 +1414,100 @@ func (itr *unsignedFloatCastIterator) Next() (*FloatPoint, error) {
 	return &itr.point, nil
 }
 
+// newRenameIterator returns an iterator that names all of the points of input
+// name.
+func newRenameIterator(input Iterator, name string) Iterator {
+	switch input := input.(type) {
+	case FloatIterator:
+		return &floatRenameIterator{input: input, name: name}
+	case IntegerIterator:
+		return &integerRenameIterator{input: input, name: name}
+	case UnsignedIterator:
+		return &unsignedRenameIterator{input: input, name: name}
+	case StringIterator:
+		return &stringRenameIterator{input: input, name: name}
+	case BooleanIterator:
+		return &booleanRenameIterator{input: input, name: name}
+	default:
+		return input
+	}
+}
+
+type floatRenameIterator struct {
+	input FloatIterator
+	name  string
+}
+
+func (itr *floatRenameIterator) Stats() IteratorStats { return itr.input.Stats() }
+func (itr *floatRenameIterator) Close() error         { return itr.input.Close() }
+func (itr *floatRenameIterator) Next() (*FloatPoint, error) {
+	p, err := itr.input.Next()
+	if p != nil {
+		p.Name = itr.name
+	}
+	return p, err
+}
+
+type integerRenameIterator struct {
+	input IntegerIterator
+	name  string
+}
+
+func (itr *integerRenameIterator) Stats() IteratorStats { return itr.input.Stats() }
+func (itr *integerRenameIterator) Close() error         { return itr.input.Close() }
+func (itr *integerRenameIterator) Next() (*IntegerPoint, error) {
+	p, err := itr.input.Next()
+	if p != nil {
+		p.Name = itr.name
+	}
+	return p, err
+}
+
+type unsignedRenameIterator struct {
+	input UnsignedIterator
+	name  string
+}
+
+func (itr *unsignedRenameIterator) Stats() IteratorStats { return itr.input.Stats() }
+func (itr *unsignedRenameIterator) Close() error         { return itr.input.Close() }
+func (itr *unsignedRenameIterator) Next() (*UnsignedPoint, error) {
+	p, err := itr.input.Next()
+	if p != nil {
+		p.Name = itr.name
+	}
+	return p, err
+}
+
+type stringRenameIterator struct {
+	input StringIterator
+	name  string
+}
+
+func (itr *stringRenameIterator) Stats() IteratorStats { return itr.input.Stats() }
+func (itr *stringRenameIterator) Close() error         { return itr.input.Close() }
+func (itr *stringRenameIterator) Next() (*StringPoint, error) {
+	p, err := itr.input.Next()
+	if p != nil {
+		p.Name = itr.name
+	}
+	return p, err
+}
+
+type booleanRenameIterator struct {
+	input BooleanIterator
+	name  string
+}
+
+func (itr *booleanRenameIterator) Stats() IteratorStats { return itr.input.Stats() }
+func (itr *booleanRenameIterator) Close() error         { return itr.input.Close() }
+func (itr *booleanRenameIterator) Next() (*BooleanPoint, error) {
+	p, err := itr.input.Next()
+	if p != nil {
+		p.Name = itr.name
+	}
+	return p, err
+}
+
 // IteratorStats represents statistics about an iterator.
 // Some statistics are available immediately upon iterator creation while
 // some are derived as the iterator processes data.
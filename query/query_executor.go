@@ -11,6 +11,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxql"
 	"go.uber.org/zap"
@@ -135,6 +136,10 @@ type ExecutionOptions struct {
 	// Quiet suppresses non-essential output from the query executor.
 	Quiet bool
 
+	// RequestID is the ID of the request the query was received with. It is
+	// added to the log lines of the query.
+	RequestID string
+
 	// AbortCh is a channel that signals when results are no longer desired by the caller.
 	AbortCh <-chan struct{}
 }
@@ -308,6 +313,18 @@ func (e *QueryExecutor) executeQuery(query *influxql.Query, opt ExecutionOptions
 	}
 	defer e.TaskManager.DetachQuery(qid)
 
+	if u, ok := opt.Authorizer.(interface {
+		ID() string
+	}); ok {
+		task.mu.Lock()
+		task.user = u.ID()
+		task.mu.Unlock()
+	}
+	task.mu.Lock()
+	task.requestID = opt.RequestID
+	task.mu.Unlock()
+	log := logger.WithRequestID(e.Logger, opt.RequestID)
+
 	// Setup the execution context that will be used when executing statements.
 	ctx := ExecutionContext{
 		QueryID:          qid,
@@ -361,6 +378,18 @@ LOOP:
 			}
 		}
 
+		// Check the statement against the authorizer. Callers such as the
+		// HTTP handler authorize the whole query up front, but this ensures
+		// every statement type is checked no matter how it was submitted.
+		if !AuthorizerIsOpen(opt.Authorizer) {
+			if err := opt.Authorizer.AuthorizeQuery(defaultDB, &influxql.Query{Statements: influxql.Statements{stmt}}); err != nil {
+				if err := ctx.send(&Result{StatementID: i, Err: err}); err == ErrQueryAborted {
+					return
+				}
+				break
+			}
+		}
+
 		// Rewrite statements, if necessary.
 		// This can occur on meta read statements which convert to SELECT statements.
 		newStmt, err := RewriteStatement(stmt)
@@ -382,7 +411,7 @@ LOOP:
 
 		// Log each normalized statement.
 		if !ctx.Quiet {
-			e.Logger.Info(stmt.String())
+			log.Info(stmt.String())
 		}
 
 		// Send any other statements to the underlying statement executor.
@@ -472,12 +501,16 @@ type QueryMonitorFunc func(<-chan struct{}) error
 // For the public use data structure that gets returned, see QueryTask.
 type QueryTask struct {
 	query     string
+	stmt      *influxql.Query
 	database  string
+	user      string
+	requestID string
 	status    TaskStatus
 	startTime time.Time
 	closing   chan struct{}
 	monitorCh chan error
 	err       error
+	stats     IteratorStats
 	mu        sync.Mutex
 }
 
@@ -497,6 +530,14 @@ func (q *QueryTask) Error() error {
 	return q.err
 }
 
+// AddIteratorStats adds the statistics of the iterators used by a statement
+// of the query.
+func (q *QueryTask) AddIteratorStats(stats IteratorStats) {
+	q.mu.Lock()
+	q.stats.Add(stats)
+	q.mu.Unlock()
+}
+
 func (q *QueryTask) setError(err error) {
 	q.mu.Lock()
 	q.err = err
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/influxdata/influxdb/internal"
 	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxql"
 )
@@ -308,6 +309,54 @@ func TestQueryExecutor_Abort(t *testing.T) {
 	discardOutput(results)
 }
 
+func TestQueryExecutor_Unauthorized(t *testing.T) {
+	q, err := influxql.ParseQuery(`SHOW MEASUREMENTS; DROP DATABASE db0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewQueryExecutor()
+	e.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+			if _, ok := stmt.(*influxql.DropDatabaseStatement); ok {
+				t.Error("unauthorized statement was executed")
+			}
+			return nil
+		},
+	}
+
+	errForbidden := errors.New("forbidden")
+	opt := query.ExecutionOptions{
+		Database: "db0",
+		Authorizer: &internal.AuthorizerMock{
+			AuthorizeQueryFn: func(database string, q *influxql.Query) error {
+				if database != "db0" {
+					t.Errorf("unexpected database: %s", database)
+				}
+				if _, ok := q.Statements[0].(*influxql.DropDatabaseStatement); ok {
+					return errForbidden
+				}
+				return nil
+			},
+		},
+	}
+
+	var n int
+	for result := range e.ExecuteQuery(q, opt, nil) {
+		if result.StatementID == 1 {
+			if result.Err != errForbidden {
+				t.Errorf("unexpected error: %v", result.Err)
+			}
+		} else if result.Err != nil {
+			t.Errorf("unexpected error: %s", result.Err)
+		}
+		n++
+	}
+	if n != 1 {
+		t.Errorf("unexpected number of results: %d", n)
+	}
+}
+
 func TestQueryExecutor_ShowQueries(t *testing.T) {
 	e := NewQueryExecutor()
 	e.StatementExecutor = &StatementExecutor{
@@ -405,6 +454,37 @@ func TestQueryExecutor_Limit_ConcurrentQueries(t *testing.T) {
 	}
 }
 
+func TestTaskManager_SlowQueries(t *testing.T) {
+	tm := query.NewTaskManager()
+	tm.LogQueriesAfter = time.Nanosecond
+	tm.MaxSlowQueries = 2
+
+	for _, s := range []string{`SELECT * FROM cpu`, `SELECT * FROM mem`, `SELECT * FROM disk`} {
+		q, err := influxql.ParseQuery(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		qid, _, err := tm.AttachQuery(q, "db0", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+		if err := tm.DetachQuery(qid); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the newest slow queries are kept.
+	queries := tm.SlowQueries()
+	if len(queries) != 2 {
+		t.Fatalf("unexpected slow queries: %v", queries)
+	} else if queries[0].Query != `SELECT * FROM disk` || queries[1].Query != `SELECT * FROM mem` {
+		t.Fatalf("unexpected slow queries: %v", queries)
+	} else if queries[0].Database != "db0" || queries[0].Duration < time.Millisecond {
+		t.Fatalf("unexpected slow query: %v", queries[0])
+	}
+}
+
 func TestQueryExecutor_Close(t *testing.T) {
 	q, err := influxql.ParseQuery(`SELECT count(value) FROM cpu`)
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxql"
@@ -81,6 +82,25 @@ func ReadOnlyWarning(stmt string) *Message {
 	}
 }
 
+// ExpensiveQueryWarning generates a warning message that tells the user the
+// statement reads n series or blocks, more than the threshold of the server.
+func ExpensiveQueryWarning(what string, n, threshold int64) *Message {
+	return &Message{
+		Level: WarningLevel,
+		Text:  fmt.Sprintf("expensive query: reads %d %s, more than the warning threshold of %d", n, what, threshold),
+	}
+}
+
+// BoundedTimeRangeWarning generates a warning message that tells the user
+// the statement, which has no lower time bound, would have read size bytes,
+// more than the maximum of the server, so only the data since start is read.
+func BoundedTimeRangeWarning(start time.Time, size, max int64) *Message {
+	return &Message{
+		Level: WarningLevel,
+		Text:  fmt.Sprintf("unbounded-select-size limit exceeded: (%d/%d), only reading data since %s, add a time condition to the query", size, max, start.Format(time.RFC3339Nano)),
+	}
+}
+
 // Result represents a resultset returned from a single statement.
 // Rows represents a list of rows that can be sorted consistently by name/tag.
 type Result struct {
@@ -7,6 +7,8 @@ import (
 	"io"
 	"math"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/influxdata/influxdb/pkg/tracing"
 	"github.com/influxdata/influxql"
@@ -30,6 +32,17 @@ type SelectOptions struct {
 
 	// Maximum number of buckets for a statement.
 	MaxBucketsN int
+
+	// Maximum number of bytes a statement without a lower time bound can
+	// read. If it is exceeded, the statement is bounded to UnboundedRange
+	// before its end time, or an error is returned if UnboundedRange is zero.
+	MaxUnboundedSize int64
+	UnboundedRange   time.Duration
+
+	// Number of series and TSM blocks above which a statement is returned
+	// with a warning that it is expensive. Zero disables the warning.
+	WarnSeriesN int
+	WarnBlocksN int
 }
 
 // ShardMapper retrieves and maps shards into an IteratorCreator that can later be
@@ -61,6 +74,10 @@ type PreparedStatement interface {
 	// Explain outputs the explain plan for this statement.
 	Explain() (string, error)
 
+	// Warnings returns the warnings found while preparing the statement,
+	// such as the statement being expensive.
+	Warnings() []*Message
+
 	// Close closes the resources associated with this prepared statement.
 	// This must be called as the mapped shards may hold open resources such
 	// as network connections.
@@ -96,7 +113,8 @@ type preparedStatement struct {
 		IteratorCreator
 		io.Closer
 	}
-	columns []string
+	columns  []string
+	warnings []*Message
 }
 
 func (p *preparedStatement) Select(ctx context.Context) ([]Iterator, []string, error) {
@@ -111,6 +129,10 @@ func (p *preparedStatement) Close() error {
 	return p.ic.Close()
 }
 
+func (p *preparedStatement) Warnings() []*Message {
+	return p.warnings
+}
+
 func buildIterators(ctx context.Context, stmt *influxql.SelectStatement, ic IteratorCreator, opt IteratorOptions) ([]Iterator, error) {
 	span := tracing.SpanFromContext(ctx)
 	// Retrieve refs for each call and var ref.
@@ -485,6 +507,10 @@ func (b *exprIteratorBuilder) buildVarRefIterator(ctx context.Context, expr *inf
 
 func (b *exprIteratorBuilder) buildCallIterator(ctx context.Context, expr *influxql.Call) (Iterator, error) {
 	// TODO(jsternberg): Refactor this. This section needs to die in a fire.
+	if m, call := b.joinedCall(expr); m != nil {
+		return b.buildJoinedCallIterator(ctx, m, call)
+	}
+
 	opt := b.opt
 	// Eliminate limits and offsets if they were previously set. These are handled by the caller.
 	opt.Limit, opt.Offset = 0, 0
@@ -527,7 +553,7 @@ func (b *exprIteratorBuilder) buildCallIterator(ctx context.Context, expr *influ
 		opt.Interval = Interval{}
 
 		return newHoltWintersIterator(input, opt, int(h.Val), int(m.Val), includeFitData, interval)
-	case "derivative", "non_negative_derivative", "difference", "non_negative_difference", "moving_average", "elapsed":
+	case "derivative", "non_negative_derivative", "difference", "non_negative_difference", "moving_average", "elapsed", "gaps":
 		if !opt.Interval.IsZero() {
 			if opt.Ascending {
 				opt.StartTime -= int64(opt.Interval.Duration)
@@ -550,6 +576,9 @@ func (b *exprIteratorBuilder) buildCallIterator(ctx context.Context, expr *influ
 		case "elapsed":
 			interval := opt.ElapsedInterval()
 			return newElapsedIterator(input, opt, interval)
+		case "gaps":
+			threshold, unit := opt.GapsInterval()
+			return newGapsIterator(input, opt, threshold, unit)
 		case "difference", "non_negative_difference":
 			isNonNegative := (expr.Name == "non_negative_difference")
 			return newDifferenceIterator(input, opt, isNonNegative)
@@ -717,6 +746,14 @@ func (b *exprIteratorBuilder) buildCallIterator(ctx context.Context, expr *influ
 				return nil, err
 			}
 			return NewModeIterator(input, opt)
+		case "count_hll":
+			// The sketch is built from the raw points of all of the shards,
+			// so a value found in several shards is only counted once.
+			input, err := buildExprIterator(ctx, expr.Args[0].(*influxql.VarRef), b.ic, b.sources, opt, false, false)
+			if err != nil {
+				return nil, err
+			}
+			return newCountHLLIterator(input, opt)
 		case "stddev":
 			input, err := buildExprIterator(ctx, expr.Args[0].(*influxql.VarRef), b.ic, b.sources, opt, false, false)
 			if err != nil {
@@ -799,6 +836,78 @@ func (b *exprIteratorBuilder) buildBinaryExprIterator(ctx context.Context, expr
 	}
 }
 
+// joinedCall returns the measurement a call reads from when the statement
+// selects from several measurements and the field of the call is qualified by
+// the name of one of them, such as mean(errors.value) in:
+//
+//	SELECT mean(errors.value) / mean(requests.value) FROM errors, requests GROUP BY time(1m)
+//
+// The call is returned with the qualifier removed from its field. A nil
+// measurement is returned for the other calls, and for calls on a field or tag
+// that exists with the qualified name, such as a field named "errors.value".
+func (b *exprIteratorBuilder) joinedCall(expr *influxql.Call) (*influxql.Measurement, *influxql.Call) {
+	if len(expr.Args) == 0 {
+		return nil, nil
+	}
+	ref, ok := expr.Args[0].(*influxql.VarRef)
+	if !ok {
+		return nil, nil
+	}
+
+	var m *influxql.Measurement
+	n := 0
+	for _, source := range b.sources {
+		source, ok := source.(*influxql.Measurement)
+		if !ok || source.Regex != nil {
+			continue
+		}
+		n++
+
+		// Use the longest name, since measurement names may have dots.
+		if strings.HasPrefix(ref.Val, source.Name+".") && (m == nil || len(source.Name) > len(m.Name)) {
+			m = source
+		}
+	}
+	if m == nil || n < 2 {
+		return nil, nil
+	}
+
+	// A field whose name literally contains the qualifier is read as is.
+	if fm, ok := b.ic.(influxql.FieldMapper); ok {
+		for _, source := range b.sources {
+			if source, ok := source.(*influxql.Measurement); ok && fm.MapType(source, ref.Val) != influxql.Unknown {
+				return nil, nil
+			}
+		}
+	}
+
+	call := &influxql.Call{Name: expr.Name, Args: make([]influxql.Expr, len(expr.Args))}
+	copy(call.Args, expr.Args)
+	call.Args[0] = &influxql.VarRef{Val: strings.TrimPrefix(ref.Val, m.Name+"."), Type: ref.Type}
+	return m, call
+}
+
+// buildJoinedCallIterator creates the iterator of a call reading from only
+// one of the measurements of the statement. Its points are named after the
+// first measurement of the statement, so the points of calls on different
+// measurements are joined by their tags and time by binary expressions.
+func (b *exprIteratorBuilder) buildJoinedCallIterator(ctx context.Context, m *influxql.Measurement, call *influxql.Call) (Iterator, error) {
+	builder := *b
+	builder.sources = influxql.Sources{m}
+	builder.opt.Expr = call
+	itr, err := builder.buildCallIterator(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, source := range b.sources {
+		if source, ok := source.(*influxql.Measurement); ok {
+			return newRenameIterator(itr, source.Name), nil
+		}
+	}
+	return itr, nil
+}
+
 func (b *exprIteratorBuilder) callIterator(ctx context.Context, expr *influxql.Call, opt IteratorOptions) (Iterator, error) {
 	inputs := make([]Iterator, 0, len(b.sources))
 	if err := func() error {
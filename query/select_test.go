@@ -1675,6 +1675,49 @@ func TestSelect(t *testing.T) {
 				{&query.UnsignedPoint{Name: "cpu", Tags: ParseTags("host=B"), Time: 50 * Second, Value: 4}},
 			},
 		},
+		{
+			name: "CountHLL_Float",
+			q:    `SELECT count_hll(value) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-02T00:00:00Z' GROUP BY time(10s), host fill(none)`,
+			typ:  influxql.Float,
+			itrs: []query.Iterator{
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 0 * Second, Value: 20},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 1 * Second, Value: 20},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 11 * Second, Value: 3},
+				}},
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 5 * Second, Value: 10},
+					{Name: "cpu", Tags: ParseTags("region=west,host=B"), Time: 6 * Second, Value: 11},
+				}},
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 9 * Second, Value: 19},
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 12 * Second, Value: 3},
+				}},
+			},
+			points: [][]query.Point{
+				{&query.IntegerPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 2}},
+				{&query.IntegerPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 10 * Second, Value: 1}},
+				{&query.IntegerPoint{Name: "cpu", Tags: ParseTags("host=B"), Time: 0 * Second, Value: 2}},
+			},
+		},
+		{
+			name: "CountHLL_String",
+			q:    `SELECT count_hll(value) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-02T00:00:00Z' GROUP BY time(10s), host fill(none)`,
+			typ:  influxql.String,
+			itrs: []query.Iterator{
+				&StringIterator{Points: []query.StringPoint{
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 0 * Second, Value: "a"},
+					{Name: "cpu", Tags: ParseTags("region=west,host=A"), Time: 1 * Second, Value: "b"},
+				}},
+				&StringIterator{Points: []query.StringPoint{
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 2 * Second, Value: "a"},
+					{Name: "cpu", Tags: ParseTags("region=east,host=A"), Time: 3 * Second, Value: "c"},
+				}},
+			},
+			points: [][]query.Point{
+				{&query.IntegerPoint{Name: "cpu", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 3}},
+			},
+		},
 		{
 			name: "Percentile_Float",
 			q:    `SELECT percentile(value, 90) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-02T00:00:00Z' GROUP BY time(10s), host fill(none)`,
@@ -2432,6 +2475,39 @@ func TestSelect(t *testing.T) {
 				{&query.IntegerPoint{Name: "cpu", Time: 11 * Second, Value: 3}},
 			},
 		},
+		{
+			name: "Gaps_Float",
+			q:    `SELECT gaps(value, 3s, 1s) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:30Z'`,
+			typ:  influxql.Float,
+			itrs: []query.Iterator{
+				&FloatIterator{Points: []query.FloatPoint{
+					{Name: "cpu", Time: 0 * Second, Value: 20},
+					{Name: "cpu", Time: 2 * Second, Value: 10},
+					{Name: "cpu", Time: 8 * Second, Value: 19},
+					{Name: "cpu", Time: 11 * Second, Value: 3},
+					{Name: "cpu", Time: 25 * Second, Value: 7},
+				}},
+			},
+			points: [][]query.Point{
+				{&query.IntegerPoint{Name: "cpu", Time: 2 * Second, Value: 6}},
+				{&query.IntegerPoint{Name: "cpu", Time: 11 * Second, Value: 14}},
+			},
+		},
+		{
+			name: "Gaps_String",
+			q:    `SELECT gaps(value, 3s) FROM cpu WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:30Z'`,
+			typ:  influxql.String,
+			itrs: []query.Iterator{
+				&StringIterator{Points: []query.StringPoint{
+					{Name: "cpu", Time: 0 * Second, Value: "a"},
+					{Name: "cpu", Time: 4 * Second, Value: "b"},
+					{Name: "cpu", Time: 6 * Second, Value: "c"},
+				}},
+			},
+			points: [][]query.Point{
+				{&query.IntegerPoint{Name: "cpu", Time: 0 * Second, Value: 4 * Second}},
+			},
+		},
 		{
 			name: "Integral_Float",
 			q:    `SELECT integral(value) FROM cpu`,
@@ -2879,6 +2955,74 @@ func TestSelect_Raw(t *testing.T) {
 	}
 }
 
+// Ensure a SELECT without a lower time bound that reads too much data is
+// rejected, or bounded if an unbounded range is set.
+func TestSelect_UnboundedSize(t *testing.T) {
+	var start int64
+	shardMapper := ShardMapper{
+		MapShardsFn: func(sources influxql.Sources, _ influxql.TimeRange) query.ShardGroup {
+			return &ShardGroup{
+				Fields: map[string]influxql.DataType{"value": influxql.Float},
+				CreateIteratorFn: func(ctx context.Context, m *influxql.Measurement, opt query.IteratorOptions) (query.Iterator, error) {
+					start = opt.StartTime
+					return &FloatIterator{}, nil
+				},
+				IteratorCostFn: func(m *influxql.Measurement, opt query.IteratorOptions) (query.IteratorCost, error) {
+					return query.IteratorCost{BlockSize: 1000}, nil
+				},
+			}
+		},
+	}
+
+	opt := query.SelectOptions{MaxUnboundedSize: 100}
+	stmt := MustParseSelectStatement(`SELECT value FROM cpu`)
+	if _, _, err := query.Select(context.Background(), stmt, &shardMapper, opt); err == nil || err.Error() != `unbounded-select-size limit exceeded: (1000/100), add a time condition to the query` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A statement with a lower time bound is not limited.
+	stmt = MustParseSelectStatement(`SELECT value FROM cpu WHERE time >= '1970-01-01T00:00:10Z'`)
+	if _, _, err := query.Select(context.Background(), stmt, &shardMapper, opt); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if start != 10*Second {
+		t.Fatalf("unexpected start time: %d", start)
+	}
+
+	// The measurements of subqueries are counted.
+	stmt = MustParseSelectStatement(`SELECT max(value) FROM (SELECT value FROM cpu)`)
+	if _, _, err := query.Select(context.Background(), stmt, &shardMapper, opt); err == nil || err.Error() != `unbounded-select-size limit exceeded: (1000/100), add a time condition to the query` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Unless the subquery has a lower time bound.
+	stmt = MustParseSelectStatement(`SELECT max(value) FROM (SELECT value FROM cpu WHERE time >= '1970-01-01T00:00:10Z')`)
+	if _, _, err := query.Select(context.Background(), stmt, &shardMapper, opt); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if start != 10*Second {
+		t.Fatalf("unexpected start time: %d", start)
+	}
+
+	opt.UnboundedRange = time.Minute
+	stmt = MustParseSelectStatement(`SELECT value FROM cpu WHERE time < '1970-01-01T01:00:00Z'`)
+	p, err := query.Prepare(stmt, &shardMapper, opt)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer p.Close()
+	if _, _, err := p.Select(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if exp := int64(time.Hour - time.Minute - 1); start != exp {
+		t.Fatalf("unexpected start time: got %d, exp %d", start, exp)
+	}
+
+	// The user is told that only part of the data is read.
+	if warnings := p.Warnings(); len(warnings) != 1 {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	} else if exp := `unbounded-select-size limit exceeded: (1000/100), only reading data since 1970-01-01T00:58:59.999999999Z, add a time condition to the query`; warnings[0].Level != query.WarningLevel || warnings[0].Text != exp {
+		t.Fatalf("unexpected warning: %s: %s", warnings[0].Level, warnings[0].Text)
+	}
+}
+
 // Ensure a SELECT binary expr queries can be executed as floats.
 func TestSelect_BinaryExpr(t *testing.T) {
 	shardMapper := ShardMapper{
@@ -3932,6 +4076,112 @@ func TestSelect_BinaryExpr_NilValues(t *testing.T) {
 	}
 }
 
+// Ensure a statement reading more than the warning thresholds gets warnings.
+func TestSelect_CostWarnings(t *testing.T) {
+	shardMapper := ShardMapper{
+		MapShardsFn: func(sources influxql.Sources, _ influxql.TimeRange) query.ShardGroup {
+			return &ShardGroup{
+				Fields: map[string]influxql.DataType{
+					"value": influxql.Float,
+				},
+				CreateIteratorFn: func(ctx context.Context, m *influxql.Measurement, opt query.IteratorOptions) (query.Iterator, error) {
+					return &FloatIterator{}, nil
+				},
+				IteratorCostFn: func(m *influxql.Measurement, opt query.IteratorOptions) (query.IteratorCost, error) {
+					return query.IteratorCost{NumSeries: 20, BlocksRead: 100}, nil
+				},
+			}
+		},
+	}
+
+	stmt := MustParseSelectStatement(`SELECT value FROM cpu`)
+	for _, tt := range []struct {
+		opt      query.SelectOptions
+		warnings []string
+	}{
+		{opt: query.SelectOptions{}},
+		{opt: query.SelectOptions{WarnSeriesN: 20, WarnBlocksN: 100}},
+		{
+			opt:      query.SelectOptions{WarnSeriesN: 10},
+			warnings: []string{"expensive query: reads 20 series, more than the warning threshold of 10"},
+		},
+		{
+			opt: query.SelectOptions{WarnSeriesN: 10, WarnBlocksN: 50},
+			warnings: []string{
+				"expensive query: reads 20 series, more than the warning threshold of 10",
+				"expensive query: reads 100 blocks, more than the warning threshold of 50",
+			},
+		},
+	} {
+		p, err := query.Prepare(stmt, &shardMapper, tt.opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var warnings []string
+		for _, m := range p.Warnings() {
+			if m.Level != query.WarningLevel {
+				t.Errorf("unexpected level: %s", m.Level)
+			}
+			warnings = append(warnings, m.Text)
+		}
+		if !reflect.DeepEqual(warnings, tt.warnings) {
+			t.Errorf("unexpected warnings with %+v: %q", tt.opt, warnings)
+		}
+		p.Close()
+	}
+}
+
+// Ensure calls on fields qualified by their measurement are joined by time.
+func TestSelect_Join(t *testing.T) {
+	shardMapper := ShardMapper{
+		MapShardsFn: func(sources influxql.Sources, _ influxql.TimeRange) query.ShardGroup {
+			return &ShardGroup{
+				Fields: map[string]influxql.DataType{
+					"value": influxql.Float,
+				},
+				Dimensions: []string{"host"},
+				CreateIteratorFn: func(ctx context.Context, m *influxql.Measurement, opt query.IteratorOptions) (query.Iterator, error) {
+					if !reflect.DeepEqual(opt.Expr, MustParseExpr(`sum(value)`)) {
+						t.Fatalf("unexpected expr: %s", spew.Sdump(opt.Expr))
+					}
+
+					var itr query.Iterator
+					switch m.Name {
+					case "errors":
+						itr = &FloatIterator{Points: []query.FloatPoint{
+							{Name: "errors", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 1},
+							{Name: "errors", Tags: ParseTags("host=A"), Time: 5 * Second, Value: 2},
+							{Name: "errors", Tags: ParseTags("host=A"), Time: 12 * Second, Value: 1},
+						}}
+					case "requests":
+						itr = &FloatIterator{Points: []query.FloatPoint{
+							{Name: "requests", Tags: ParseTags("host=A"), Time: 1 * Second, Value: 30},
+							{Name: "requests", Tags: ParseTags("host=A"), Time: 11 * Second, Value: 10},
+						}}
+					default:
+						t.Fatalf("unexpected source: %s", m.Name)
+					}
+					return query.NewCallIterator(itr, opt)
+				},
+			}
+		},
+	}
+
+	stmt := MustParseSelectStatement(`SELECT sum(errors.value) / sum(requests.value) FROM errors, requests WHERE time >= '1970-01-01T00:00:00Z' AND time < '1970-01-01T00:00:20Z' GROUP BY time(10s), host fill(none)`)
+	itrs, _, err := query.Select(context.Background(), stmt, &shardMapper, query.SelectOptions{})
+	if err != nil {
+		t.Fatal(err)
+	} else if a, err := Iterators(itrs).ReadAll(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if diff := cmp.Diff(a, [][]query.Point{
+		{&query.FloatPoint{Name: "errors", Tags: ParseTags("host=A"), Time: 0 * Second, Value: 0.1, Aggregated: 2}},
+		{&query.FloatPoint{Name: "errors", Tags: ParseTags("host=A"), Time: 10 * Second, Value: 0.1, Aggregated: 1}},
+	}); diff != "" {
+		t.Errorf("unexpected points:\n%s", diff)
+	}
+}
+
 type ShardMapper struct {
 	MapShardsFn func(sources influxql.Sources, t influxql.TimeRange) query.ShardGroup
 }
@@ -3943,6 +4193,7 @@ func (m *ShardMapper) MapShards(sources influxql.Sources, t influxql.TimeRange,
 
 type ShardGroup struct {
 	CreateIteratorFn func(ctx context.Context, m *influxql.Measurement, opt query.IteratorOptions) (query.Iterator, error)
+	IteratorCostFn   func(m *influxql.Measurement, opt query.IteratorOptions) (query.IteratorCost, error)
 	Fields           map[string]influxql.DataType
 	Dimensions       []string
 }
@@ -3952,6 +4203,9 @@ func (sh *ShardGroup) CreateIterator(ctx context.Context, m *influxql.Measuremen
 }
 
 func (sh *ShardGroup) IteratorCost(m *influxql.Measurement, opt query.IteratorOptions) (query.IteratorCost, error) {
+	if sh.IteratorCostFn != nil {
+		return sh.IteratorCostFn(m, opt)
+	}
 	return query.IteratorCost{}, nil
 }
 
@@ -5,7 +5,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/monitor/diagnostics"
 	"github.com/influxdata/influxql"
 	"go.uber.org/zap"
 )
@@ -46,9 +48,16 @@ type TaskManager struct {
 	// If zero, slow queries will never be logged.
 	LogQueriesAfter time.Duration
 
+	// MaxSlowQueries is the number of the most recent slow queries kept
+	// for SlowQueries. If zero, no slow queries are kept.
+	MaxSlowQueries int
+
 	// Maximum number of concurrent queries.
 	MaxConcurrentQueries int
 
+	// Auditor is given each query when it finishes, if set.
+	Auditor QueryAuditor
+
 	// Logger to use for all logging.
 	// Defaults to discarding all log output.
 	Logger *zap.Logger
@@ -58,6 +67,11 @@ type TaskManager struct {
 	nextID   uint64
 	mu       sync.RWMutex
 	shutdown bool
+
+	// slow is a ring buffer of the most recent slow queries. slowNext is
+	// the index the next one is stored at.
+	slow     []SlowQuery
+	slowNext int
 }
 
 // NewTaskManager creates a new TaskManager.
@@ -165,6 +179,7 @@ func (t *TaskManager) AttachQuery(q *influxql.Query, database string, interrupt
 	qid := t.nextID
 	query := &QueryTask{
 		query:     q.String(),
+		stmt:      q,
 		database:  database,
 		status:    RunningTask,
 		startTime: time.Now(),
@@ -181,8 +196,11 @@ func (t *TaskManager) AttachQuery(q *influxql.Query, database string, interrupt
 
 			select {
 			case <-timer.C:
-				t.Logger.Warn(fmt.Sprintf("Detected slow query: %s (qid: %d, database: %s, threshold: %s)",
-					query.query, qid, query.database, t.LogQueriesAfter))
+				query.mu.Lock()
+				user, requestID := query.user, query.requestID
+				query.mu.Unlock()
+				logger.WithRequestID(t.Logger, requestID).Warn(fmt.Sprintf("Detected slow query: %s (qid: %d, database: %s, user: %s, threshold: %s)",
+					query.query, qid, query.database, user, t.LogQueriesAfter))
 			case <-closing:
 			}
 			return nil
@@ -210,18 +228,112 @@ func (t *TaskManager) KillQuery(qid uint64) error {
 // killed state, this will also close the related channel.
 func (t *TaskManager) DetachQuery(qid uint64) error {
 	t.mu.Lock()
-	defer t.mu.Unlock()
-
 	query := t.queries[qid]
 	if query == nil {
+		t.mu.Unlock()
 		return fmt.Errorf("no such query id: %d", qid)
 	}
 
 	query.close()
 	delete(t.queries, qid)
+	t.mu.Unlock()
+
+	query.mu.Lock()
+	user, requestID, stats := query.user, query.requestID, query.stats
+	query.mu.Unlock()
+
+	d := time.Since(query.startTime)
+	if t.LogQueriesAfter != 0 && d >= t.LogQueriesAfter {
+		logger.WithRequestID(t.Logger, requestID).Warn(fmt.Sprintf("Slow query finished: %s (qid: %d, database: %s, user: %s, duration: %s)",
+			query.query, qid, query.database, user, d))
+		t.addSlowQuery(SlowQuery{
+			ID:       qid,
+			Query:    query.query,
+			Database: query.database,
+			User:     user,
+			Duration: d,
+			Finished: query.startTime.Add(d),
+		})
+	}
+
+	if t.Auditor != nil {
+		t.Auditor.AuditQuery(FinishedQuery{
+			ID:       qid,
+			Query:    query.stmt,
+			Database: query.database,
+			User:     user,
+			Duration: d,
+			Stats:    stats,
+		})
+	}
 	return nil
 }
 
+// SlowQuery is a query that took longer than LogQueriesAfter to finish.
+type SlowQuery struct {
+	ID       uint64
+	Query    string
+	Database string
+	User     string
+	Duration time.Duration
+	Finished time.Time
+}
+
+// addSlowQuery stores q in the ring buffer of slow queries, replacing the
+// oldest one once the buffer is full.
+func (t *TaskManager) addSlowQuery(q SlowQuery) {
+	if t.MaxSlowQueries <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.slow) < t.MaxSlowQueries {
+		t.slow = append(t.slow, q)
+		return
+	}
+	t.slow[t.slowNext] = q
+	t.slowNext = (t.slowNext + 1) % len(t.slow)
+}
+
+// SlowQueries returns the most recent slow queries, newest first.
+func (t *TaskManager) SlowQueries() []SlowQuery {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	a := make([]SlowQuery, 0, len(t.slow))
+	for i := 0; i < len(t.slow); i++ {
+		j := (t.slowNext - 1 - i + 2*len(t.slow)) % len(t.slow)
+		a = append(a, t.slow[j])
+	}
+	return a
+}
+
+// SlowQueryDiagnostics returns the most recent slow queries as diagnostics.
+func (t *TaskManager) SlowQueryDiagnostics() (*diagnostics.Diagnostics, error) {
+	d := diagnostics.NewDiagnostics([]string{"qid", "query", "database", "user", "duration", "finished"})
+	for _, q := range t.SlowQueries() {
+		d.AddRow([]interface{}{q.ID, q.Query, q.Database, q.User, q.Duration.String(), q.Finished.UTC().Format(time.RFC3339Nano)})
+	}
+	return d, nil
+}
+
+// FinishedQuery represents a query that has finished executing.
+type FinishedQuery struct {
+	ID       uint64
+	Query    *influxql.Query
+	Database string
+	User     string
+	Duration time.Duration
+	Stats    IteratorStats
+}
+
+// QueryAuditor records queries once they finish.
+type QueryAuditor interface {
+	AuditQuery(q FinishedQuery)
+}
+
 // QueryInfo represents the information for a query.
 type QueryInfo struct {
 	ID       uint64        `json:"id"`
@@ -0,0 +1,74 @@
+// Package audit records who queried and wrote to the server.
+package audit
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+)
+
+const (
+	// OutputFile writes audit events to a file.
+	OutputFile = "file"
+
+	// OutputSyslog sends audit events to the local syslog daemon.
+	OutputSyslog = "syslog"
+
+	// DefaultSyslogTag is the tag audit events are sent to syslog with.
+	DefaultSyslogTag = "influxdb-audit"
+)
+
+// Config represents the configuration for the audit log.
+type Config struct {
+	Enabled bool   `toml:"enabled"`
+	Output  string `toml:"output"`
+
+	// Path is the file events are appended to when the output is a file.
+	Path string `toml:"path"`
+
+	// SyslogTag is the tag events are sent with when the output is syslog.
+	SyslogTag string `toml:"syslog-tag"`
+}
+
+// NewConfig returns a new instance of Config with defaults.
+func NewConfig() Config {
+	return Config{
+		Output:    OutputFile,
+		SyslogTag: DefaultSyslogTag,
+	}
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	switch c.Output {
+	case OutputFile:
+		if c.Path == "" {
+			return errors.New("path must be specified when output is file")
+		}
+	case OutputSyslog:
+	default:
+		return fmt.Errorf("unrecognized output %q", c.Output)
+	}
+	return nil
+}
+
+// Diagnostics returns a diagnostics representation of a subset of the Config.
+func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
+	if !c.Enabled {
+		return diagnostics.RowFromMap(map[string]interface{}{
+			"enabled": false,
+		}), nil
+	}
+
+	return diagnostics.RowFromMap(map[string]interface{}{
+		"enabled":    true,
+		"output":     c.Output,
+		"path":       c.Path,
+		"syslog-tag": c.SyslogTag,
+	}), nil
+}
@@ -0,0 +1,48 @@
+package audit_test
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/influxdb/services/audit"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	c := audit.NewConfig()
+	if _, err := toml.Decode(`
+enabled = true
+path = "/var/log/influxdb/audit.log"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if !c.Enabled {
+		t.Fatalf("unexpected enabled: %v", c.Enabled)
+	} else if c.Output != audit.OutputFile {
+		t.Fatalf("unexpected output: %s", c.Output)
+	} else if c.Path != "/var/log/influxdb/audit.log" {
+		t.Fatalf("unexpected path: %s", c.Path)
+	} else if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validate error: %s", err)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c := audit.NewConfig()
+	c.Enabled = true
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for missing path")
+	}
+
+	c.Output = "kafka"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for unrecognized output")
+	}
+
+	c.Output = audit.OutputSyslog
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxql"
+	"go.uber.org/zap"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionQuery = "query"
+	ActionAdmin = "admin"
+	ActionWrite = "write"
+)
+
+// Event is a single request recorded in the audit log. Status is the HTTP
+// status of the response. It is 0 for requests of the RPC API, which has no
+// status codes and does not expose the address of the client.
+type Event struct {
+	Time            time.Time     `json:"time"`
+	Action          string        `json:"action"`
+	User            string        `json:"user,omitempty"`
+	Addr            string        `json:"addr"`
+	Database        string        `json:"database,omitempty"`
+	RetentionPolicy string        `json:"rp,omitempty"`
+	Query           string        `json:"query,omitempty"`
+	Points          int           `json:"points,omitempty"`
+	Status          int           `json:"status"`
+	Error           string        `json:"error,omitempty"`
+	Duration        time.Duration `json:"duration_ns"`
+}
+
+// QueryAction returns the action a query is recorded as. Queries with
+// statements requiring admin privileges are admin actions.
+func QueryAction(q *influxql.Query) string {
+	for _, stmt := range q.Statements {
+		privs, _ := stmt.RequiredPrivileges()
+		for _, p := range privs {
+			if p.Admin {
+				return ActionAdmin
+			}
+		}
+	}
+	return ActionQuery
+}
+
+// Log writes events to the configured output as one JSON object per line.
+type Log struct {
+	config Config
+
+	mu     sync.Mutex
+	w      io.WriteCloser
+	failed bool
+
+	Logger *zap.Logger
+}
+
+// NewLog returns a new instance of Log.
+func NewLog(c Config) *Log {
+	return &Log{
+		config: c,
+		Logger: zap.NewNop(),
+	}
+}
+
+// WithLogger sets the logger for the audit log.
+func (l *Log) WithLogger(log *zap.Logger) {
+	l.Logger = log.With(zap.String("service", "audit"))
+}
+
+// Open opens the output.
+func (l *Log) Open() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.w != nil {
+		return nil
+	}
+
+	switch l.config.Output {
+	case OutputSyslog:
+		w, err := newSyslogWriter(l.config.SyslogTag)
+		if err != nil {
+			return err
+		}
+		l.w = w
+		l.Logger.Info("Writing audit log to syslog")
+	default:
+		if err := os.MkdirAll(filepath.Dir(l.config.Path), 0700); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(l.config.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		l.w = f
+		l.Logger.Info(fmt.Sprintf("Writing audit log to %s", l.config.Path))
+	}
+	return nil
+}
+
+// Close closes the output.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.w == nil {
+		return nil
+	}
+	err := l.w.Close()
+	l.w = nil
+	return err
+}
+
+// Log records e. Failures to write are logged once until a write succeeds
+// again, since the requests themselves have already been served.
+func (l *Log) Log(e Event) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	b = append(b, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.w == nil {
+		err = errors.New("audit log is closed")
+	} else {
+		_, err = l.w.Write(b)
+	}
+
+	if err != nil && !l.failed {
+		l.Logger.Error("Unable to write audit log", zap.Error(err))
+	}
+	l.failed = err != nil
+}
@@ -0,0 +1,51 @@
+package audit_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/audit"
+)
+
+func TestLog_File(t *testing.T) {
+	dir, err := ioutil.TempDir("", "influxdb-audit-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := audit.NewConfig()
+	c.Enabled = true
+	c.Path = filepath.Join(dir, "log", "audit.log")
+
+	l := audit.NewLog(c)
+	if err := l.Open(); err != nil {
+		t.Fatal(err)
+	}
+	l.Log(audit.Event{Action: audit.ActionWrite, User: "alice", Database: "db0", Points: 2, Status: 204})
+	l.Log(audit.Event{Action: audit.ActionAdmin, User: "bob", Query: "DROP DATABASE db0", Status: 200, Duration: time.Millisecond})
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(c.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected lines: %q", lines)
+	}
+
+	var e audit.Event
+	if err := json.Unmarshal([]byte(lines[1]), &e); err != nil {
+		t.Fatal(err)
+	} else if e.Action != audit.ActionAdmin || e.User != "bob" || e.Query != "DROP DATABASE db0" || e.Duration != time.Millisecond {
+		t.Fatalf("unexpected event: %#v", e)
+	}
+}
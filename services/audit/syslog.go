@@ -0,0 +1,14 @@
+// +build !windows
+
+package audit
+
+import (
+	"io"
+	"log/syslog"
+)
+
+// newSyslogWriter returns a writer sending each write to the local syslog
+// daemon.
+func newSyslogWriter(tag string) (io.WriteCloser, error) {
+	return syslog.New(syslog.LOG_INFO|syslog.LOG_AUTH, tag)
+}
@@ -0,0 +1,10 @@
+package audit
+
+import (
+	"errors"
+	"io"
+)
+
+func newSyslogWriter(tag string) (io.WriteCloser, error) {
+	return nil, errors.New("syslog output is not supported on windows")
+}
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"time"
 
+	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/services/internal/ingest"
 	"github.com/influxdata/influxdb/toml"
 )
 
@@ -67,6 +69,7 @@ type Config struct {
 	SecurityLevel         string        `toml:"security-level"`
 	AuthFile              string        `toml:"auth-file"`
 	ParseMultiValuePlugin string        `toml:"parse-multivalue-plugin"`
+	Tags                  []string      `toml:"tags"`
 }
 
 // NewConfig returns a new instance of Config with defaults.
@@ -141,7 +144,12 @@ func (c *Config) Validate() error {
 		return errors.New(`Invalid value for parse-multivalue-plugin. Valid options are "split" and "join"`)
 	}
 
-	return nil
+	return ingest.ValidateTags(c.Tags)
+}
+
+// DefaultTags returns the config's tags.
+func (c *Config) DefaultTags() models.Tags {
+	return ingest.DefaultTags(c.Tags)
 }
 
 // Configs wraps a slice of Config to aggregate diagnostics.
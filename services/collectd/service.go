@@ -16,7 +16,7 @@ import (
 	"collectd.org/api"
 	"collectd.org/network"
 	"github.com/influxdata/influxdb/models"
-	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/services/internal/ingest"
 	"github.com/influxdata/influxdb/tsdb"
 	"go.uber.org/zap"
 )
@@ -30,6 +30,7 @@ const (
 	statBatchesTransmitted   = "batchesTx"
 	statPointsTransmitted    = "pointsTx"
 	statBatchesTransmitFail  = "batchesTxFail"
+	statPointsDropped        = "pointsDropped"
 	statDroppedPointsInvalid = "droppedPointsInvalid"
 )
 
@@ -38,11 +39,6 @@ type pointsWriter interface {
 	WritePointsPrivileged(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
 }
 
-// metaClient is an internal interface to make testing easier.
-type metaClient interface {
-	CreateDatabase(name string) (*meta.DatabaseInfo, error)
-}
-
 // TypesDBFile reads a collectd types db from a file.
 func TypesDBFile(path string) (typesdb *api.TypesDB, err error) {
 	var reader *os.File
@@ -57,7 +53,7 @@ func TypesDBFile(path string) (typesdb *api.TypesDB, err error) {
 // protocol and stores them in InfluxDB.
 type Service struct {
 	Config       *Config
-	MetaClient   metaClient
+	MetaClient   ingest.MetaClient
 	PointsWriter pointsWriter
 	Logger       *zap.Logger
 
@@ -66,10 +62,11 @@ type Service struct {
 	batcher *tsdb.PointBatcher
 	popts   network.ParseOpts
 	addr    net.Addr
+	tags    models.Tags // Tags added to every point that doesn't have them.
 
-	mu    sync.RWMutex
-	ready bool          // Has the required database been created?
-	done  chan struct{} // Is the service closing or closed?
+	mu      sync.RWMutex
+	done    chan struct{}  // Is the service closing or closed?
+	storage ingest.Storage // Has the required database been created?
 
 	// expvar-based stats.
 	stats       *Statistics
@@ -81,6 +78,7 @@ func NewService(c Config) *Service {
 	s := Service{
 		// Use defaults where necessary.
 		Config: c.WithDefaults(),
+		tags:   c.DefaultTags(),
 
 		Logger:      zap.NewNop(),
 		stats:       &Statistics{},
@@ -256,24 +254,12 @@ func (s *Service) closed() bool {
 	return s.done == nil
 }
 
-// createInternalStorage ensures that the required database has been created.
+// createInternalStorage ensures that the required database and retention
+// policy have been created.
 func (s *Service) createInternalStorage() error {
-	s.mu.RLock()
-	ready := s.ready
-	s.mu.RUnlock()
-	if ready {
-		return nil
-	}
-
-	if _, err := s.MetaClient.CreateDatabase(s.Config.Database); err != nil {
-		return err
-	}
-
-	// The service is now ready.
-	s.mu.Lock()
-	s.ready = true
-	s.mu.Unlock()
-	return nil
+	return s.storage.Ensure(func() error {
+		return ingest.CreateStorage(s.MetaClient, s.Config.Database, s.Config.RetentionPolicy)
+	})
 }
 
 // WithLogger sets the service's logger.
@@ -290,6 +276,7 @@ type Statistics struct {
 	BatchesTransmitted   int64
 	PointsTransmitted    int64
 	BatchesTransmitFail  int64
+	PointsDropped        int64
 	InvalidDroppedPoints int64
 }
 
@@ -306,6 +293,7 @@ func (s *Service) Statistics(tags map[string]string) []models.Statistic {
 			statBatchesTransmitted:   atomic.LoadInt64(&s.stats.BatchesTransmitted),
 			statPointsTransmitted:    atomic.LoadInt64(&s.stats.PointsTransmitted),
 			statBatchesTransmitFail:  atomic.LoadInt64(&s.stats.BatchesTransmitFail),
+			statPointsDropped:        atomic.LoadInt64(&s.stats.PointsDropped),
 			statDroppedPointsInvalid: atomic.LoadInt64(&s.stats.InvalidDroppedPoints),
 		},
 	}}
@@ -389,9 +377,11 @@ func (s *Service) writePoints() {
 				continue
 			}
 
-			if err := s.PointsWriter.WritePointsPrivileged(s.Config.Database, s.Config.RetentionPolicy, models.ConsistencyLevelAny, batch); err == nil {
+			err := s.PointsWriter.WritePointsPrivileged(s.Config.Database, s.Config.RetentionPolicy, models.ConsistencyLevelAny, batch)
+			if dropped, ok := ingest.PartialWrite(s.Logger, s.Config.Database, err); err == nil || ok {
 				atomic.AddInt64(&s.stats.BatchesTransmitted, 1)
-				atomic.AddInt64(&s.stats.PointsTransmitted, int64(len(batch)))
+				atomic.AddInt64(&s.stats.PointsTransmitted, int64(len(batch)-dropped))
+				atomic.AddInt64(&s.stats.PointsDropped, int64(dropped))
 			} else {
 				s.Logger.Info(fmt.Sprintf("failed to write point batch to database %q: %s", s.Config.Database, err))
 				atomic.AddInt64(&s.stats.BatchesTransmitFail, 1)
@@ -400,6 +390,16 @@ func (s *Service) writePoints() {
 	}
 }
 
+// setDefaultTags sets the configured default tags in tags if they are not
+// already set.
+func (s *Service) setDefaultTags(tags map[string]string) {
+	for _, t := range s.tags {
+		if _, ok := tags[string(t.Key)]; !ok {
+			tags[string(t.Key)] = string(t.Value)
+		}
+	}
+}
+
 // UnmarshalValueListPacked is an alternative to the original UnmarshalValueList.
 // The difference is that the original provided measurements like (PLUGIN_DSNAME, ["value",xxx])
 // while this one will provide measurements like (PLUGIN, {["DSNAME",xxx]}).
@@ -424,6 +424,7 @@ func (s *Service) UnmarshalValueListPacked(vl *api.ValueList) []models.Point {
 	if vl.Identifier.TypeInstance != "" {
 		tags["type_instance"] = vl.Identifier.TypeInstance
 	}
+	s.setDefaultTags(tags)
 
 	for i, v := range vl.Values {
 		fieldName := vl.DSName(i)
@@ -479,6 +480,7 @@ func (s *Service) UnmarshalValueList(vl *api.ValueList) []models.Point {
 		if vl.Identifier.TypeInstance != "" {
 			tags["type_instance"] = vl.Identifier.TypeInstance
 		}
+		s.setDefaultTags(tags)
 
 		// Drop invalid points
 		p, err := models.NewPoint(name, models.NewTags(tags), fields, timestamp)
@@ -147,14 +147,18 @@ func TestService_CreatesDatabase(t *testing.T) {
 	}
 
 	// ready status should not have been switched due to meta client error.
-	s.Service.mu.RLock()
-	ready := s.Service.ready
-	s.Service.mu.RUnlock()
+	ready := s.Service.storage.Ready()
 
 	if got, exp := ready, false; got != exp {
 		t.Fatalf("got %v, expected %v", got, exp)
 	}
 
+	// The failure delays the next attempt, so don't wait for it here.
+	if s.Service.storage.RetryAt().IsZero() {
+		t.Fatal("expected a retry time after the failure")
+	}
+	s.Service.storage.Reset()
+
 	// This time MC won't cause an error.
 	s.MetaClient.CreateDatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
 		// Allow some time for the caller to return and the ready status to
@@ -173,9 +177,7 @@ func TestService_CreatesDatabase(t *testing.T) {
 	}
 
 	// ready status should not have been switched due to meta client error.
-	s.Service.mu.RLock()
-	ready = s.Service.ready
-	s.Service.mu.RUnlock()
+	ready = s.Service.storage.Ready()
 
 	if got, exp := ready, true; got != exp {
 		t.Fatalf("got %v, expected %v", got, exp)
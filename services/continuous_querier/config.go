@@ -2,6 +2,7 @@ package continuous_querier
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/influxdata/influxdb/monitor/diagnostics"
@@ -31,6 +32,11 @@ type Config struct {
 	// every minute, this should be set to 1 minute. The default is set to '1s' so the interval
 	// is compatible with most aggregations.
 	RunInterval toml.Duration `toml:"run-interval"`
+
+	// Templates create continuous queries for new measurements, checked
+	// every TemplateCheckInterval.
+	Templates             []Template    `toml:"template"`
+	TemplateCheckInterval toml.Duration `toml:"template-check-interval"`
 }
 
 // NewConfig returns a new instance of Config with defaults.
@@ -40,6 +46,8 @@ func NewConfig() Config {
 		Enabled:           true,
 		QueryStatsEnabled: false,
 		RunInterval:       toml.Duration(DefaultRunInterval),
+
+		TemplateCheckInterval: toml.Duration(DefaultTemplateCheckInterval),
 	}
 }
 
@@ -55,6 +63,15 @@ func (c Config) Validate() error {
 		return errors.New("run-interval must be positive")
 	}
 
+	if len(c.Templates) > 0 && c.TemplateCheckInterval <= 0 {
+		return errors.New("template-check-interval must be positive")
+	}
+	for i, t := range c.Templates {
+		if err := t.Validate(); err != nil {
+			return fmt.Errorf("invalid template %d: %s", i, err)
+		}
+	}
+
 	return nil
 }
 
@@ -70,5 +87,8 @@ func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
 		"enabled":             true,
 		"query-stats-enabled": c.QueryStatsEnabled,
 		"run-interval":        c.RunInterval,
+
+		"templates":               len(c.Templates),
+		"template-check-interval": c.TemplateCheckInterval,
 	}), nil
 }
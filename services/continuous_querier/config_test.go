@@ -44,3 +44,42 @@ func TestConfig_Validate(t *testing.T) {
 		t.Fatal("expected error for negative run-interval, got nil")
 	}
 }
+
+func TestConfig_Validate_Templates(t *testing.T) {
+	var c continuous_querier.Config
+	if _, err := toml.Decode(`
+enabled = true
+run-interval = "1s"
+template-check-interval = "1m"
+
+[[template]]
+name = "downsample"
+database = "telegraf"
+measurement = "^cpu"
+select = "mean(*)"
+interval = "5m"
+retention-policy = "one_year"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+
+	c.Templates[0].Measurement = "(cpu"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for invalid measurement pattern, got nil")
+	}
+
+	c.Templates[0].Measurement = "^cpu"
+	c.Templates[0].Select = "mean(*) FROM"
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for invalid select, got nil")
+	}
+
+	c.Templates[0].Select = "mean(*)"
+	c.Templates[0].RetentionPolicy = ""
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for template without retention policy, got nil")
+	}
+}
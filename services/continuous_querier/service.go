@@ -4,6 +4,7 @@ package continuous_querier // import "github.com/influxdata/influxdb/services/co
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -31,6 +32,8 @@ const (
 const (
 	statQueryOK   = "queryOk"
 	statQueryFail = "queryFail"
+
+	statTemplateQueryCreated = "templateQueryCreated"
 )
 
 // ContinuousQuerier represents a service that executes continuous queries.
@@ -44,6 +47,7 @@ type metaClient interface {
 	AcquireLease(name string) (l *meta.Lease, err error)
 	Databases() []meta.DatabaseInfo
 	Database(name string) *meta.DatabaseInfo
+	CreateContinuousQuery(database, name, query string) error
 }
 
 // RunRequest is a request to run one or more CQs.
@@ -96,6 +100,14 @@ type Service struct {
 	lastRuns map[string]time.Time
 	stop     chan struct{}
 	wg       *sync.WaitGroup
+
+	// templates are the measurement patterns of Config.Templates.
+	templates []*regexp.Regexp
+
+	// statuses maps CQ name to the outcome of its last run. It has its own
+	// lock since mu is held while queries run.
+	statusMu sync.Mutex
+	statuses map[string]*runStatus
 }
 
 // NewService returns a new instance of Service.
@@ -110,6 +122,7 @@ func NewService(c Config) *Service {
 		Logger:            zap.NewNop(),
 		stats:             &Statistics{},
 		lastRuns:          map[string]time.Time{},
+		statuses:          map[string]*runStatus{},
 	}
 
 	return s
@@ -126,6 +139,15 @@ func (s *Service) Open() error {
 	assert(s.MetaClient != nil, "MetaClient is nil")
 	assert(s.QueryExecutor != nil, "QueryExecutor is nil")
 
+	s.templates = nil
+	for _, t := range s.Config.Templates {
+		re, err := regexp.Compile(t.Measurement)
+		if err != nil {
+			return fmt.Errorf("invalid measurement pattern of template %s: %s", t.Name, err)
+		}
+		s.templates = append(s.templates, re)
+	}
+
 	s.stop = make(chan struct{})
 	s.wg = &sync.WaitGroup{}
 	s.wg.Add(1)
@@ -154,6 +176,8 @@ func (s *Service) WithLogger(log *zap.Logger) {
 type Statistics struct {
 	QueryOK   int64
 	QueryFail int64
+
+	TemplateQueryCreated int64
 }
 
 // Statistics returns statistics for periodic monitoring.
@@ -164,6 +188,8 @@ func (s *Service) Statistics(tags map[string]string) []models.Statistic {
 		Values: map[string]interface{}{
 			statQueryOK:   atomic.LoadInt64(&s.stats.QueryOK),
 			statQueryFail: atomic.LoadInt64(&s.stats.QueryFail),
+
+			statTemplateQueryCreated: atomic.LoadInt64(&s.stats.TemplateQueryCreated),
 		},
 	}}
 }
@@ -210,6 +236,16 @@ func (s *Service) backgroundLoop() {
 	t := time.NewTimer(s.RunInterval)
 	defer t.Stop()
 	defer s.wg.Done()
+
+	// Templates are checked on a timer of their own, since listing the
+	// measurements is too expensive to do every run interval.
+	var templateCh <-chan time.Time
+	if len(s.templates) > 0 {
+		ticker := time.NewTicker(time.Duration(s.Config.TemplateCheckInterval))
+		defer ticker.Stop()
+		templateCh = ticker.C
+	}
+
 	for {
 		select {
 		case <-s.stop:
@@ -232,6 +268,10 @@ func (s *Service) backgroundLoop() {
 				s.runContinuousQueries(&RunRequest{Now: time.Now()})
 			}
 			t.Reset(s.RunInterval)
+		case <-templateCh:
+			if _, err := s.MetaClient.AcquireLease(leaseName); err == nil {
+				s.applyTemplates()
+			}
 		}
 	}
 }
@@ -360,11 +400,7 @@ func (s *Service) ExecuteContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.Conti
 		return false, err
 	}
 
-	var start time.Time
-	if s.loggingEnabled || s.queryStatsEnabled {
-		start = time.Now()
-	}
-
+	start := time.Now()
 	if s.loggingEnabled {
 		s.Logger.Info(fmt.Sprintf("executing continuous query %s (%v to %v)", cq.Info.Name, startTime, endTime))
 	}
@@ -373,13 +409,12 @@ func (s *Service) ExecuteContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.Conti
 	res := s.runContinuousQueryAndWriteResult(cq)
 	if res.Err != nil {
 		s.Logger.Info(fmt.Sprintf("error: %s. running: %s", res.Err, cq.q.String()))
+		s.recordRun(dbi.Name, cqi.Name, start, 0, res.Err)
+		s.writeQueryStats(dbi.Name, cq.Info.Name, map[string]interface{}{"durationNs": int64(time.Since(start)), "startTime": startTime.UnixNano(), "endTime": endTime.UnixNano(), "error": res.Err.Error()})
 		return false, res.Err
 	}
 
-	var execDuration time.Duration
-	if s.loggingEnabled || s.queryStatsEnabled {
-		execDuration = time.Since(start)
-	}
+	execDuration := time.Since(start)
 
 	// extract number of points written from SELECT ... INTO result
 	var written int64 = -1
@@ -392,16 +427,23 @@ func (s *Service) ExecuteContinuousQuery(dbi *meta.DatabaseInfo, cqi *meta.Conti
 		s.Logger.Info(fmt.Sprintf("finished continuous query %s, %d points(s) written (%v to %v) in %s", cq.Info.Name, written, startTime, endTime, execDuration))
 	}
 
-	if s.queryStatsEnabled && s.Monitor.Enabled() {
-		tags := map[string]string{"db": dbi.Name, "cq": cq.Info.Name}
-		fields := map[string]interface{}{"durationNs": int64(execDuration), "pointsWrittenOK": written, "startTime": startTime.UnixNano(), "endTime": endTime.UnixNano()}
-		p, _ := models.NewPoint("cq_query", models.NewTags(tags), fields, time.Now())
-		s.Monitor.WritePoints(models.Points{p})
-	}
+	s.recordRun(dbi.Name, cqi.Name, start, written, nil)
+	s.writeQueryStats(dbi.Name, cq.Info.Name, map[string]interface{}{"durationNs": int64(execDuration), "pointsWrittenOK": written, "startTime": startTime.UnixNano(), "endTime": endTime.UnixNano()})
 
 	return true, nil
 }
 
+// writeQueryStats writes the statistics of a run of continuous query name of
+// database to the monitor database, if query statistics are enabled.
+func (s *Service) writeQueryStats(database, name string, fields map[string]interface{}) {
+	if !s.queryStatsEnabled || !s.Monitor.Enabled() {
+		return
+	}
+	tags := map[string]string{"db": database, "cq": name}
+	p, _ := models.NewPoint("cq_query", models.NewTags(tags), fields, time.Now())
+	s.Monitor.WritePoints(models.Points{p})
+}
+
 // runContinuousQueryAndWriteResult will run the query against the cluster and write the results back in
 func (s *Service) runContinuousQueryAndWriteResult(cq *ContinuousQuery) *query.Result {
 	// Wrap the CQ's inner SELECT statement in a Query for the QueryExecutor.
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
 	"sync"
 	"testing"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/toml"
 	"github.com/influxdata/influxql"
 )
 
@@ -607,6 +609,62 @@ func TestExecuteContinuousQuery_QueryExecutor_Error(t *testing.T) {
 	}
 }
 
+// Ensure the outcome of the last run of each CQ is reported.
+func TestService_StatusDiagnostics(t *testing.T) {
+	s := NewTestService(t)
+	fail := true
+	s.QueryExecutor.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+			if fail {
+				return errExpected
+			}
+			ctx.Results <- &query.Result{
+				Series: []*models.Row{{
+					Name:    "result",
+					Columns: []string{"time", "written"},
+					Values:  [][]interface{}{{time.Time{}, int64(5)}},
+				}},
+			}
+			return nil
+		},
+	}
+
+	dbis := s.MetaClient.Databases()
+	dbi := dbis[0]
+	cqi := dbi.ContinuousQueries[0]
+
+	now := time.Now().Truncate(10 * time.Minute)
+	if _, err := s.ExecuteContinuousQuery(&dbi, &cqi, now); err != errExpected {
+		t.Fatalf("exp = %s, got = %v", errExpected, err)
+	}
+
+	d, err := s.StatusDiagnostics()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(d.Rows) != 3 {
+		t.Fatalf("unexpected rows: %v", d.Rows)
+	}
+	if row := d.Rows[0]; row[0] != "db" || row[1] != "cq" || row[5] != errExpected.Error() || row[6] != "" || row[7] != 1 {
+		t.Errorf("unexpected status of failed run: %v", row)
+	}
+	if row := d.Rows[1]; row[1] != "cq2" || row[2] != "" {
+		t.Errorf("unexpected status of query that has not run: %v", row)
+	}
+
+	fail = false
+	if ok, err := s.ExecuteContinuousQuery(&dbi, &cqi, now.Add(time.Minute)); !ok || err != nil {
+		t.Fatalf("ExecuteContinuousQuery failed, ok=%t, err=%v", ok, err)
+	}
+
+	d, err = s.StatusDiagnostics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row := d.Rows[0]; row[4] != int64(5) || row[5] != "" || row[6] == "" || row[7] != 0 {
+		t.Errorf("unexpected status of successful run: %v", row)
+	}
+}
+
 func TestService_ExecuteContinuousQuery_LogsToMonitor(t *testing.T) {
 	s := NewTestService(t)
 	const writeN = int64(50)
@@ -681,6 +739,63 @@ func TestService_ExecuteContinuousQuery_LogToMonitor_DisabledByDefault(t *testin
 	}
 }
 
+func TestService_Templates(t *testing.T) {
+	s := NewTestService(t)
+	s.Config.Templates = []Template{{
+		Name:            "downsample",
+		Database:        "db",
+		Measurement:     "^cpu",
+		Select:          "mean(*)",
+		Interval:        toml.Duration(5 * time.Minute),
+		RetentionPolicy: "rp_1y",
+	}}
+	s.templates = []*regexp.Regexp{regexp.MustCompile("^cpu")}
+	s.MetaClient.CreateContinuousQuery("db", "downsample_cpu2", "existing")
+
+	s.QueryExecutor.StatementExecutor = &StatementExecutor{
+		ExecuteStatementFn: func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+			if stmt, ok := stmt.(*influxql.ShowMeasurementsStatement); !ok || stmt.Database != "db" {
+				t.Errorf("unexpected statement: %s", stmt)
+				return errUnexpected
+			}
+			ctx.Results <- &query.Result{Series: models.Rows{{
+				Name:    "measurements",
+				Columns: []string{"name"},
+				Values:  [][]interface{}{{"cpu"}, {"cpu2"}, {"mem"}},
+			}}}
+			return nil
+		},
+	}
+
+	s.applyTemplates()
+
+	// Only cpu gets a new query: cpu2 already has one and mem does not match.
+	dbi := s.MetaClient.Database("db")
+	if len(dbi.ContinuousQueries) != 3 {
+		t.Fatalf("unexpected continuous queries: %v", dbi.ContinuousQueries)
+	}
+	cqi := dbi.ContinuousQueries[2]
+	if cqi.Name != "downsample_cpu" {
+		t.Fatalf("unexpected name: %s", cqi.Name)
+	}
+	cq, err := NewContinuousQuery("db", &cqi)
+	if err != nil {
+		t.Fatal(err)
+	} else if rp := cq.intoRP(); rp != "rp_1y" {
+		t.Fatalf("unexpected into retention policy: %s", rp)
+	} else if interval, _ := cq.q.GroupByInterval(); interval != 5*time.Minute {
+		t.Fatalf("unexpected interval: %s", interval)
+	}
+
+	// Running it again creates nothing.
+	s.applyTemplates()
+	if n := len(s.MetaClient.Database("db").ContinuousQueries); n != 3 {
+		t.Fatalf("unexpected continuous queries: %d", n)
+	} else if n := s.stats.TemplateQueryCreated; n != 1 {
+		t.Fatalf("unexpected created queries: %d", n)
+	}
+}
+
 // NewTestService returns a new *Service with default mock object members.
 func NewTestService(t *testing.T) *Service {
 	s := NewService(NewConfig())
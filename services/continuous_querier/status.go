@@ -0,0 +1,82 @@
+package continuous_querier
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+)
+
+// runStatus is the outcome of the last run of a continuous query.
+type runStatus struct {
+	start       time.Time
+	duration    time.Duration
+	written     int64
+	err         string
+	lastSuccess time.Time
+
+	// failures is the number of runs that failed in a row.
+	failures int
+}
+
+// recordRun records the outcome of the run of continuous query name of
+// database that started at start.
+func (s *Service) recordRun(database, name string, start time.Time, written int64, err error) {
+	id := fmt.Sprintf("%s%s%s", database, idDelimiter, name)
+
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+
+	st := s.statuses[id]
+	if st == nil {
+		st = &runStatus{}
+		s.statuses[id] = st
+	}
+	st.start = start
+	st.duration = time.Since(start)
+	st.written = written
+	if err != nil {
+		st.err = err.Error()
+		st.failures++
+		return
+	}
+	st.err = ""
+	st.failures = 0
+	st.lastSuccess = start
+}
+
+// StatusDiagnostics returns the outcome of the last run of every continuous
+// query, so queries that keep failing, such as those writing to a dropped
+// retention policy, can be found with SHOW DIAGNOSTICS FOR 'cq-status'.
+func (s *Service) StatusDiagnostics() (*diagnostics.Diagnostics, error) {
+	d := diagnostics.NewDiagnostics([]string{"database", "name", "last_run", "duration", "points_written", "error", "last_success", "failures"})
+
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+
+	// Statuses of dropped queries are removed, so they do not pile up.
+	seen := make(map[string]bool, len(s.statuses))
+	for _, db := range s.MetaClient.Databases() {
+		for _, cq := range db.ContinuousQueries {
+			id := fmt.Sprintf("%s%s%s", db.Name, idDelimiter, cq.Name)
+			seen[id] = true
+
+			st := s.statuses[id]
+			if st == nil {
+				d.AddRow([]interface{}{db.Name, cq.Name, "", "", int64(0), "", "", 0})
+				continue
+			}
+			var lastSuccess string
+			if !st.lastSuccess.IsZero() {
+				lastSuccess = st.lastSuccess.UTC().Format(time.RFC3339Nano)
+			}
+			d.AddRow([]interface{}{db.Name, cq.Name, st.start.UTC().Format(time.RFC3339Nano), st.duration.String(), st.written, st.err, lastSuccess, st.failures})
+		}
+	}
+	for id := range s.statuses {
+		if !seen[id] {
+			delete(s.statuses, id)
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,153 @@
+package continuous_querier
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/influxql"
+)
+
+// DefaultTemplateCheckInterval is the default time between two checks for
+// measurements that match a template.
+const DefaultTemplateCheckInterval = time.Minute
+
+// Template is a continuous query that is created for every measurement of a
+// database whose name matches a pattern, so a new measurement is downsampled
+// without creating its continuous query by hand.
+type Template struct {
+	// Name is the prefix of the names of the continuous queries. The query of
+	// measurement cpu of template downsample is named downsample_cpu.
+	Name string `toml:"name"`
+
+	Database string `toml:"database"`
+
+	// Measurement is a regular expression matching the measurement names.
+	Measurement string `toml:"measurement"`
+
+	// Select is the list of fields of the SELECT, such as "mean(*)".
+	Select string `toml:"select"`
+
+	// Interval is the GROUP BY time interval.
+	Interval toml.Duration `toml:"interval"`
+
+	// RetentionPolicy is where the results are written, to a measurement of
+	// the same name. It must not be the retention policy the data is read
+	// from, which is the default retention policy.
+	RetentionPolicy string `toml:"retention-policy"`
+}
+
+// Validate returns an error if the template is invalid.
+func (t Template) Validate() error {
+	if t.Name == "" {
+		return errors.New("name must not be empty")
+	} else if t.Database == "" {
+		return errors.New("database must not be empty")
+	} else if t.RetentionPolicy == "" {
+		return errors.New("retention-policy must not be empty")
+	} else if t.Interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+	if _, err := regexp.Compile(t.Measurement); err != nil {
+		return fmt.Errorf("invalid measurement pattern: %s", err)
+	}
+	if _, err := t.statement("m"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// queryName returns the name of the continuous query of measurement.
+func (t Template) queryName(measurement string) string {
+	return t.Name + "_" + measurement
+}
+
+// statement returns the continuous query of measurement.
+func (t Template) statement(measurement string) (*influxql.CreateContinuousQueryStatement, error) {
+	s := fmt.Sprintf("CREATE CONTINUOUS QUERY %s ON %s BEGIN SELECT %s INTO %s FROM %s GROUP BY time(%s), * END",
+		influxql.QuoteIdent(t.queryName(measurement)),
+		influxql.QuoteIdent(t.Database),
+		t.Select,
+		influxql.QuoteIdent(t.RetentionPolicy, measurement),
+		influxql.QuoteIdent(measurement),
+		influxql.FormatDuration(time.Duration(t.Interval)),
+	)
+	stmt, err := influxql.NewParser(strings.NewReader(s)).ParseStatement()
+	if err != nil {
+		return nil, fmt.Errorf("invalid select: %s", err)
+	}
+	cq, ok := stmt.(*influxql.CreateContinuousQueryStatement)
+	if !ok {
+		return nil, errors.New("invalid select")
+	}
+	return cq, nil
+}
+
+// applyTemplates creates the continuous queries of the templates for the
+// measurements that do not have one yet. A continuous query that is dropped
+// is created again while its template exists.
+func (s *Service) applyTemplates() {
+	for i, t := range s.Config.Templates {
+		dbi := s.MetaClient.Database(t.Database)
+		if dbi == nil {
+			continue
+		}
+
+		names, err := s.measurementNames(t.Database)
+		if err != nil {
+			s.Logger.Info(fmt.Sprintf("error listing measurements of %s for template %s: %s", t.Database, t.Name, err))
+			continue
+		}
+
+		exists := make(map[string]bool, len(dbi.ContinuousQueries))
+		for _, cq := range dbi.ContinuousQueries {
+			exists[cq.Name] = true
+		}
+		for _, name := range names {
+			if !s.templates[i].MatchString(name) || exists[t.queryName(name)] {
+				continue
+			}
+
+			stmt, err := t.statement(name)
+			if err == nil {
+				err = s.MetaClient.CreateContinuousQuery(t.Database, stmt.Name, stmt.String())
+			}
+			if err != nil {
+				s.Logger.Info(fmt.Sprintf("error creating continuous query %s from template %s: %s", t.queryName(name), t.Name, err))
+				continue
+			}
+			atomic.AddInt64(&s.stats.TemplateQueryCreated, 1)
+			s.Logger.Info(fmt.Sprintf("created continuous query %s on %s from template %s", stmt.Name, t.Database, t.Name))
+		}
+	}
+}
+
+// measurementNames returns the names of the measurements of database.
+func (s *Service) measurementNames(database string) ([]string, error) {
+	q := &influxql.Query{
+		Statements: influxql.Statements{&influxql.ShowMeasurementsStatement{Database: database}},
+	}
+
+	closing := make(chan struct{})
+	defer close(closing)
+
+	var names []string
+	for res := range s.QueryExecutor.ExecuteQuery(q, query.ExecutionOptions{Database: database}, closing) {
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		for _, row := range res.Series {
+			for _, values := range row.Values {
+				if name, ok := values[0].(string); ok {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	return names, nil
+}
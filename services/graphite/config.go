@@ -1,6 +1,7 @@
 package graphite
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -65,6 +66,11 @@ type Config struct {
 	Tags             []string      `toml:"tags"`
 	Separator        string        `toml:"separator"`
 	UDPReadBuffer    int           `toml:"udp-read-buffer"`
+
+	// DedupWindow drops the points identical to a point received less than
+	// DedupWindow ago, such as those of packets duplicated by relays. A value
+	// of 0 disables it.
+	DedupWindow toml.Duration `toml:"dedup-window"`
 }
 
 // NewConfig returns a new instance of Config with defaults.
@@ -135,6 +141,10 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if c.DedupWindow < 0 {
+		return errors.New("dedup-window must be non-negative")
+	}
+
 	return nil
 }
 
@@ -261,7 +271,7 @@ type Configs []Config
 // Diagnostics returns one set of diagnostics for all of the Configs.
 func (c Configs) Diagnostics() (*diagnostics.Diagnostics, error) {
 	d := &diagnostics.Diagnostics{
-		Columns: []string{"enabled", "bind-address", "protocol", "database", "retention-policy", "batch-size", "batch-pending", "batch-timeout"},
+		Columns: []string{"enabled", "bind-address", "protocol", "database", "retention-policy", "batch-size", "batch-pending", "batch-timeout", "dedup-window"},
 	}
 
 	for _, cc := range c {
@@ -270,7 +280,7 @@ func (c Configs) Diagnostics() (*diagnostics.Diagnostics, error) {
 			continue
 		}
 
-		r := []interface{}{true, cc.BindAddress, cc.Protocol, cc.Database, cc.RetentionPolicy, cc.BatchSize, cc.BatchPending, cc.BatchTimeout}
+		r := []interface{}{true, cc.BindAddress, cc.Protocol, cc.Database, cc.RetentionPolicy, cc.BatchSize, cc.BatchPending, cc.BatchTimeout, cc.DedupWindow}
 		d.AddRow(r)
 	}
 
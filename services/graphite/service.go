@@ -13,6 +13,8 @@ import (
 
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/pkg/systemd"
+	"github.com/influxdata/influxdb/services/internal/ingest"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/tsdb"
 	"go.uber.org/zap"
@@ -29,8 +31,10 @@ const (
 	statBatchesTransmitted  = "batchesTx"
 	statPointsTransmitted   = "pointsTx"
 	statBatchesTransmitFail = "batchesTxFail"
+	statPointsDropped       = "pointsDropped"
 	statConnectionsActive   = "connsActive"
 	statConnectionsHandled  = "connsHandled"
+	statPointsDeduplicated  = "pointsDeduplicated"
 )
 
 type tcpConnection struct {
@@ -52,6 +56,7 @@ type Service struct {
 	batchPending    int
 	batchTimeout    time.Duration
 	udpReadBuffer   int
+	dedupWindow     time.Duration
 
 	batcher *tsdb.PointBatcher
 	parser  *Parser
@@ -70,9 +75,9 @@ type Service struct {
 
 	wg sync.WaitGroup
 
-	mu    sync.RWMutex
-	ready bool          // Has the required database been created?
-	done  chan struct{} // Is the service closing or closed?
+	mu      sync.RWMutex
+	done    chan struct{}  // Is the service closing or closed?
+	storage ingest.Storage // Has the required database been created?
 
 	Monitor interface {
 		RegisterDiagnosticsClient(name string, client diagnostics.Client)
@@ -103,6 +108,7 @@ func NewService(c Config) (*Service, error) {
 		batchPending:    d.BatchPending,
 		udpReadBuffer:   d.UDPReadBuffer,
 		batchTimeout:    time.Duration(d.BatchTimeout),
+		dedupWindow:     time.Duration(d.DedupWindow),
 		logger:          zap.NewNop(),
 		stats:           &Statistics{},
 		defaultTags:     models.StatisticTags{"proto": d.Protocol, "bind": d.BindAddress},
@@ -141,6 +147,7 @@ func (s *Service) Open() error {
 	}
 
 	s.batcher = tsdb.NewPointBatcher(s.batchSize, s.batchPending, s.batchTimeout)
+	s.batcher.DedupWindow = s.dedupWindow
 	s.batcher.Start()
 
 	// Start processing batches.
@@ -230,32 +237,23 @@ func (s *Service) closed() bool {
 
 // createInternalStorage ensures that the required database has been created.
 func (s *Service) createInternalStorage() error {
-	s.mu.RLock()
-	ready := s.ready
-	s.mu.RUnlock()
-	if ready {
-		return nil
-	}
-
-	if db := s.MetaClient.Database(s.database); db != nil {
-		if rp, _ := s.MetaClient.RetentionPolicy(s.database, s.retentionPolicy); rp == nil {
+	return s.storage.Ensure(func() error {
+		if db := s.MetaClient.Database(s.database); db != nil {
+			if rp, _ := s.MetaClient.RetentionPolicy(s.database, s.retentionPolicy); rp == nil {
+				spec := meta.RetentionPolicySpec{Name: s.retentionPolicy}
+				if _, err := s.MetaClient.CreateRetentionPolicy(s.database, &spec, true); err != nil {
+					return err
+				}
+			}
+		} else {
 			spec := meta.RetentionPolicySpec{Name: s.retentionPolicy}
-			if _, err := s.MetaClient.CreateRetentionPolicy(s.database, &spec, true); err != nil {
+			if _, err := s.MetaClient.CreateDatabaseWithRetentionPolicy(s.database, &spec); err != nil {
 				return err
 			}
 		}
-	} else {
-		spec := meta.RetentionPolicySpec{Name: s.retentionPolicy}
-		if _, err := s.MetaClient.CreateDatabaseWithRetentionPolicy(s.database, &spec); err != nil {
-			return err
-		}
-	}
 
-	// The service is now ready.
-	s.mu.Lock()
-	s.ready = true
-	s.mu.Unlock()
-	return nil
+		return nil
+	})
 }
 
 // WithLogger sets the logger on the service.
@@ -275,12 +273,20 @@ type Statistics struct {
 	BatchesTransmitted  int64
 	PointsTransmitted   int64
 	BatchesTransmitFail int64
+	PointsDropped       int64
 	ActiveConnections   int64
 	HandledConnections  int64
 }
 
 // Statistics returns statistics for periodic monitoring.
 func (s *Service) Statistics(tags map[string]string) []models.Statistic {
+	var deduplicated uint64
+	s.mu.RLock()
+	if s.batcher != nil {
+		deduplicated = s.batcher.Stats().DedupTotal
+	}
+	s.mu.RUnlock()
+
 	return []models.Statistic{{
 		Name: "graphite",
 		Tags: s.defaultTags.Merge(tags),
@@ -292,8 +298,10 @@ func (s *Service) Statistics(tags map[string]string) []models.Statistic {
 			statBatchesTransmitted:  atomic.LoadInt64(&s.stats.BatchesTransmitted),
 			statPointsTransmitted:   atomic.LoadInt64(&s.stats.PointsTransmitted),
 			statBatchesTransmitFail: atomic.LoadInt64(&s.stats.BatchesTransmitFail),
+			statPointsDropped:       atomic.LoadInt64(&s.stats.PointsDropped),
 			statConnectionsActive:   atomic.LoadInt64(&s.stats.ActiveConnections),
 			statConnectionsHandled:  atomic.LoadInt64(&s.stats.HandledConnections),
+			statPointsDeduplicated:  int64(deduplicated),
 		},
 	}}
 }
@@ -305,7 +313,7 @@ func (s *Service) Addr() net.Addr {
 
 // openTCPServer opens the Graphite input in TCP mode and starts processing data.
 func (s *Service) openTCPServer() (net.Addr, error) {
-	ln, err := net.Listen("tcp", s.bindAddress)
+	ln, err := systemd.Listen("tcp", s.bindAddress)
 	if err != nil {
 		return nil, err
 	}
@@ -452,9 +460,11 @@ func (s *Service) processBatches(batcher *tsdb.PointBatcher) {
 				continue
 			}
 
-			if err := s.PointsWriter.WritePointsPrivileged(s.database, s.retentionPolicy, models.ConsistencyLevelAny, batch); err == nil {
+			err := s.PointsWriter.WritePointsPrivileged(s.database, s.retentionPolicy, models.ConsistencyLevelAny, batch)
+			if dropped, ok := ingest.PartialWrite(s.logger, s.database, err); err == nil || ok {
 				atomic.AddInt64(&s.stats.BatchesTransmitted, 1)
-				atomic.AddInt64(&s.stats.PointsTransmitted, int64(len(batch)))
+				atomic.AddInt64(&s.stats.PointsTransmitted, int64(len(batch)-dropped))
+				atomic.AddInt64(&s.stats.PointsDropped, int64(dropped))
 			} else {
 				s.logger.Info(fmt.Sprintf("failed to write point batch to database %q: %s", s.database, err))
 				atomic.AddInt64(&s.stats.BatchesTransmitFail, 1)
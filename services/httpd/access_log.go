@@ -0,0 +1,148 @@
+package httpd
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// accessLogTimeFormat is the timestamp appended to the name of a rotated
+// access log. It sorts in the order the files were rotated.
+const accessLogTimeFormat = "20060102T150405.000"
+
+// accessLogFile is an access log file that is rotated by size and age.
+type accessLogFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	interval   time.Duration
+	maxBackups int
+
+	f      *os.File
+	size   int64
+	opened time.Time
+
+	now func() time.Time
+}
+
+// openAccessLogFile opens the access log at path for appending. A maxSize,
+// interval or maxBackups of zero disables that limit.
+func openAccessLogFile(path string, maxSize int64, interval time.Duration, maxBackups int) (*accessLogFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return nil, err
+	}
+
+	l := &accessLogFile{
+		path:       path,
+		maxSize:    maxSize,
+		interval:   interval,
+		maxBackups: maxBackups,
+		now:        time.Now,
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// open opens the file at l.path. The caller must hold l.mu unless l is not
+// yet shared.
+func (l *accessLogFile) open() error {
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	l.f = f
+	l.size = fi.Size()
+	l.opened = l.now()
+	return nil
+}
+
+// Write appends p to the log, rotating the file first if it is full or old.
+func (l *accessLogFile) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		return 0, os.ErrClosed
+	}
+
+	if l.size > 0 && l.shouldRotate(len(p)) {
+		// Keep logging to the current file if it cannot be rotated.
+		if err := l.rotate(); err != nil && l.f == nil {
+			return 0, err
+		}
+	}
+
+	n, err := l.f.Write(p)
+	l.size += int64(n)
+	return n, err
+}
+
+// shouldRotate returns true if writing n bytes would exceed the limits.
+func (l *accessLogFile) shouldRotate(n int) bool {
+	if l.maxSize > 0 && l.size+int64(n) > l.maxSize {
+		return true
+	}
+	return l.interval > 0 && l.now().Sub(l.opened) >= l.interval
+}
+
+// rotate renames the current file and opens a new one in its place. The
+// caller must hold l.mu.
+func (l *accessLogFile) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	l.f = nil
+
+	backup := l.path + "." + l.now().UTC().Format(accessLogTimeFormat)
+	renameErr := os.Rename(l.path, backup)
+	if err := l.open(); err != nil {
+		return err
+	} else if renameErr != nil {
+		return renameErr
+	}
+	return l.removeBackups()
+}
+
+// removeBackups removes the oldest rotated files beyond the maximum number
+// of backups. The caller must hold l.mu.
+func (l *accessLogFile) removeBackups() error {
+	if l.maxBackups <= 0 {
+		return nil
+	}
+
+	backups, err := filepath.Glob(l.path + ".[0-9]*")
+	if err != nil {
+		return err
+	}
+	sort.Strings(backups)
+	for len(backups) > l.maxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// Close closes the log file.
+func (l *accessLogFile) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
@@ -0,0 +1,84 @@
+package httpd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAccessLogFile_Rotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpd-access-log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "access.log")
+	l, err := openAccessLogFile(path, 10, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	now := time.Unix(0, 0)
+	l.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	for _, s := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		if _, err := l.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if b, err := ioutil.ReadFile(path); err != nil {
+		t.Fatal(err)
+	} else if string(b) != "dddddd\n" {
+		t.Fatalf("unexpected log contents: %q", b)
+	}
+
+	// Only the newest backups are kept.
+	backups, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(backups) != 2 {
+		t.Fatalf("unexpected backups: %v", backups)
+	} else if b, err := ioutil.ReadFile(backups[0]); err != nil {
+		t.Fatal(err)
+	} else if string(b) != "bbbbbb\n" {
+		t.Fatalf("unexpected backup contents: %q", b)
+	}
+}
+
+func TestAccessLogFile_RotateInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpd-access-log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "access.log")
+	l, err := openAccessLogFile(path, 0, time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	l.opened = now
+
+	l.Write([]byte("a\n"))
+	l.Write([]byte("b\n"))
+	now = now.Add(time.Hour)
+	l.Write([]byte("c\n"))
+
+	if b, err := ioutil.ReadFile(path); err != nil {
+		t.Fatal(err)
+	} else if string(b) != "c\n" {
+		t.Fatalf("unexpected log contents: %q", b)
+	}
+}
@@ -0,0 +1,403 @@
+package httpd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// ArrowStreamContentType is the media type of query results in the Arrow IPC
+// streaming format.
+const ArrowStreamContentType = "application/vnd.apache.arrow.stream"
+
+// Arrow IPC constants, from Message.fbs and Schema.fbs.
+const (
+	arrowMetadataV5 = 4
+
+	arrowHeaderSchema      = 1
+	arrowHeaderRecordBatch = 3
+
+	arrowTypeInt           = 2
+	arrowTypeFloatingPoint = 3
+	arrowTypeUtf8          = 5
+	arrowTypeBool          = 6
+	arrowTypeTimestamp     = 10
+
+	arrowPrecisionDouble = 2
+	arrowUnitNanosecond  = 3
+)
+
+// arrowType is the type of a column of an Arrow record batch.
+type arrowType int
+
+const (
+	arrowFloat arrowType = iota
+	arrowInteger
+	arrowUnsigned
+	arrowString
+	arrowBoolean
+	arrowTimestamp
+)
+
+// arrowTypeOf returns the type of column values of v.
+func arrowTypeOf(v interface{}) (arrowType, bool) {
+	switch v.(type) {
+	case float64:
+		return arrowFloat, true
+	case int64:
+		return arrowInteger, true
+	case uint64:
+		return arrowUnsigned, true
+	case string:
+		return arrowString, true
+	case bool:
+		return arrowBoolean, true
+	case time.Time:
+		return arrowTimestamp, true
+	}
+	return 0, false
+}
+
+// arrowFormatter writes query results as a single Arrow IPC stream. The
+// first series determines the schema: a string column with the series name,
+// a string column for each tag and the columns of the series. Every series
+// is then written as a record batch, so all series in a response must have
+// the same columns and tags. Errors are sent in the "error" metadata of a
+// message, since the format has no other place for them.
+type arrowFormatter struct {
+	io.Writer
+
+	schemaWritten bool
+	tags          []string
+	columns       []string
+	types         []arrowType
+}
+
+func (f *arrowFormatter) WriteResponse(resp Response) (n int, err error) {
+	if resp.Err != nil {
+		return f.writeError(resp.Err.Error())
+	}
+
+	for _, result := range resp.Results {
+		if result.Err != nil {
+			nn, err := f.writeError(result.Err.Error())
+			n += nn
+			if err != nil {
+				return n, err
+			}
+			continue
+		}
+
+		for _, row := range result.Series {
+			if !f.schemaWritten {
+				nn, err := f.writeSchema(row, nil)
+				n += nn
+				if err != nil {
+					return n, err
+				}
+			}
+
+			nn, err := f.writeRecordBatch(row)
+			n += nn
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+
+	// An empty result is an empty table.
+	if !f.schemaWritten {
+		nn, err := f.writeSchema(nil, nil)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// writeError sends msg in the metadata of a message.
+func (f *arrowFormatter) writeError(msg string) (int, error) {
+	metadata := map[string]string{"error": msg}
+	if !f.schemaWritten {
+		return f.writeSchema(nil, metadata)
+	}
+	return f.writeMessage(arrowHeaderRecordBatch, f.recordBatch(0, nil, nil), nil, metadata)
+}
+
+// writeSchema sets the schema from row and writes it.
+func (f *arrowFormatter) writeSchema(row *models.Row, metadata map[string]string) (int, error) {
+	f.schemaWritten = true
+
+	fields := fbVector{}
+	if row != nil {
+		f.tags = make([]string, 0, len(row.Tags))
+		for k := range row.Tags {
+			f.tags = append(f.tags, k)
+		}
+		sort.Strings(f.tags)
+		f.columns = row.Columns
+
+		f.types = make([]arrowType, len(row.Columns))
+		for i := range row.Columns {
+			f.types[i] = arrowFloat
+			for _, values := range row.Values {
+				if typ, ok := arrowTypeOf(values[i]); ok {
+					f.types[i] = typ
+					break
+				}
+			}
+		}
+
+		fields = append(fields, arrowField("name", arrowString))
+		for _, k := range f.tags {
+			fields = append(fields, arrowField(k, arrowString))
+		}
+		for i, c := range f.columns {
+			fields = append(fields, arrowField(c, f.types[i]))
+		}
+	}
+
+	schema := fbTable{
+		{},
+		fbRef(fields),
+	}
+	return f.writeMessage(arrowHeaderSchema, schema, nil, metadata)
+}
+
+// writeRecordBatch writes the values of row as a record batch.
+func (f *arrowFormatter) writeRecordBatch(row *models.Row) (int, error) {
+	if err := f.checkSchema(row); err != nil {
+		return f.writeError(err.Error())
+	}
+
+	var body arrowBody
+	name := make([]interface{}, len(row.Values))
+	for i := range name {
+		name[i] = row.Name
+	}
+	body.appendColumn(name, arrowString)
+	for _, k := range f.tags {
+		for i := range name {
+			name[i] = row.Tags[k]
+		}
+		body.appendColumn(name, arrowString)
+	}
+
+	column := make([]interface{}, len(row.Values))
+	for i, typ := range f.types {
+		for j, values := range row.Values {
+			column[j] = values[i]
+		}
+		body.appendColumn(column, typ)
+	}
+
+	batch := f.recordBatch(len(row.Values), body.nodes, body.buffers)
+	return f.writeMessage(arrowHeaderRecordBatch, batch, body.data, nil)
+}
+
+// checkSchema returns an error if row does not fit the schema.
+func (f *arrowFormatter) checkSchema(row *models.Row) error {
+	if len(row.Tags) != len(f.tags) || !reflect.DeepEqual(row.Columns, f.columns) {
+		return fmt.Errorf("series %s has different columns or tags than the first series", row.Name)
+	}
+	for _, k := range f.tags {
+		if _, ok := row.Tags[k]; !ok {
+			return fmt.Errorf("series %s has different columns or tags than the first series", row.Name)
+		}
+	}
+	for _, values := range row.Values {
+		for i, v := range values {
+			if typ, ok := arrowTypeOf(v); ok && typ != f.types[i] {
+				return fmt.Errorf("column %s of series %s has values of different types", f.columns[i], row.Name)
+			} else if !ok && v != nil {
+				return fmt.Errorf("column %s of series %s has values of unsupported type %T", f.columns[i], row.Name, v)
+			}
+		}
+	}
+	return nil
+}
+
+// recordBatch returns a RecordBatch table. A batch without nodes gets an
+// empty node and empty buffers for every field.
+func (f *arrowFormatter) recordBatch(length int, nodes, buffers []byte) fbTable {
+	if nodes == nil {
+		nodes = make([]byte, 16*(1+len(f.tags)+len(f.columns)))
+		n := arrowBufferCount(arrowString) * (1 + len(f.tags))
+		for _, typ := range f.types {
+			n += arrowBufferCount(typ)
+		}
+		buffers = make([]byte, 16*n)
+	}
+	return fbTable{
+		fbScalar(8, uint64(length)),
+		fbRef(fbStructs{n: len(nodes) / 16, data: nodes}),
+		fbRef(fbStructs{n: len(buffers) / 16, data: buffers}),
+	}
+}
+
+// writeMessage writes an encapsulated IPC message: a continuation marker,
+// the length of the metadata, the metadata and the body.
+func (f *arrowFormatter) writeMessage(headerType byte, header fbTable, body []byte, metadata map[string]string) (int, error) {
+	message := fbTable{
+		fbScalar(2, arrowMetadataV5),
+		fbScalar(1, uint64(headerType)),
+		fbRef(header),
+		fbScalar(8, uint64(len(body))),
+	}
+	if len(metadata) > 0 {
+		keys := make([]string, 0, len(metadata))
+		for k := range metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		kvs := fbVector{}
+		for _, k := range keys {
+			kvs = append(kvs, fbTable{fbRef(fbString(k)), fbRef(fbString(metadata[k]))})
+		}
+		message = append(message, fbRef(kvs))
+	}
+	meta := fbFinish(message)
+
+	prefix := make([]byte, 8)
+	binary.LittleEndian.PutUint32(prefix, 0xFFFFFFFF)
+	binary.LittleEndian.PutUint32(prefix[4:], uint32(len(meta)))
+
+	var n int
+	for _, b := range [][]byte{prefix, meta, body} {
+		nn, err := f.Writer.Write(b)
+		n += nn
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// arrowField returns a Field table for a nullable column.
+func arrowField(name string, typ arrowType) fbTable {
+	var typeType byte
+	var t fbTable
+	switch typ {
+	case arrowFloat:
+		typeType, t = arrowTypeFloatingPoint, fbTable{fbScalar(2, arrowPrecisionDouble)}
+	case arrowInteger:
+		typeType, t = arrowTypeInt, fbTable{fbScalar(4, 64), fbBool(true)}
+	case arrowUnsigned:
+		typeType, t = arrowTypeInt, fbTable{fbScalar(4, 64), fbBool(false)}
+	case arrowString:
+		typeType, t = arrowTypeUtf8, fbTable{}
+	case arrowBoolean:
+		typeType, t = arrowTypeBool, fbTable{}
+	case arrowTimestamp:
+		typeType, t = arrowTypeTimestamp, fbTable{fbScalar(2, arrowUnitNanosecond), fbRef(fbString("UTC"))}
+	}
+	return fbTable{
+		fbRef(fbString(name)),
+		fbBool(true),
+		fbScalar(1, uint64(typeType)),
+		fbRef(t),
+		{},
+		fbRef(fbVector{}),
+	}
+}
+
+// arrowBufferCount returns the number of buffers of a column of type typ.
+func arrowBufferCount(typ arrowType) int {
+	if typ == arrowString {
+		return 3
+	}
+	return 2
+}
+
+// arrowBody builds the body of a record batch along with the field nodes
+// and buffer descriptions that go in its metadata.
+type arrowBody struct {
+	data    []byte
+	nodes   []byte
+	buffers []byte
+}
+
+// appendColumn appends the values of a column. Values must be of type typ
+// or nil.
+func (b *arrowBody) appendColumn(values []interface{}, typ arrowType) {
+	var nulls int
+	validity := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v == nil {
+			nulls++
+		} else {
+			validity[i/8] |= 1 << uint(i%8)
+		}
+	}
+
+	node := make([]byte, 16)
+	binary.LittleEndian.PutUint64(node, uint64(len(values)))
+	binary.LittleEndian.PutUint64(node[8:], uint64(nulls))
+	b.nodes = append(b.nodes, node...)
+
+	// The validity bitmap may be left out when there are no nulls.
+	if nulls == 0 {
+		validity = nil
+	}
+	b.appendBuffer(validity)
+
+	switch typ {
+	case arrowString:
+		offsets := make([]byte, 4*(len(values)+1))
+		var data []byte
+		for i, v := range values {
+			if s, ok := v.(string); ok {
+				data = append(data, s...)
+			}
+			binary.LittleEndian.PutUint32(offsets[4*(i+1):], uint32(len(data)))
+		}
+		b.appendBuffer(offsets)
+		b.appendBuffer(data)
+	case arrowBoolean:
+		bits := make([]byte, (len(values)+7)/8)
+		for i, v := range values {
+			if v, ok := v.(bool); ok && v {
+				bits[i/8] |= 1 << uint(i%8)
+			}
+		}
+		b.appendBuffer(bits)
+	default:
+		data := make([]byte, 8*len(values))
+		for i, v := range values {
+			var u uint64
+			switch v := v.(type) {
+			case float64:
+				u = math.Float64bits(v)
+			case int64:
+				u = uint64(v)
+			case uint64:
+				u = v
+			case time.Time:
+				u = uint64(v.UnixNano())
+			}
+			binary.LittleEndian.PutUint64(data[8*i:], u)
+		}
+		b.appendBuffer(data)
+	}
+}
+
+// appendBuffer appends buf to the body, padded to 8 bytes.
+func (b *arrowBody) appendBuffer(buf []byte) {
+	desc := make([]byte, 16)
+	binary.LittleEndian.PutUint64(desc, uint64(len(b.data)))
+	binary.LittleEndian.PutUint64(desc[8:], uint64(len(buf)))
+	b.buffers = append(b.buffers, desc...)
+
+	b.data = append(b.data, buf...)
+	for len(b.data)%8 != 0 {
+		b.data = append(b.data, 0)
+	}
+}
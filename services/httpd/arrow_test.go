@@ -0,0 +1,259 @@
+package httpd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query"
+)
+
+func TestArrowFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	f := &arrowFormatter{Writer: &buf}
+	if _, err := f.WriteResponse(Response{Results: []*query.Result{{
+		Series: models.Rows{
+			{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": "a"},
+				Columns: []string{"time", "value", "count", "ok"},
+				Values: [][]interface{}{
+					{time.Unix(0, 10), 1.5, int64(3), true},
+					{time.Unix(0, 20), nil, int64(4), false},
+				},
+			},
+			{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": "bb"},
+				Columns: []string{"time", "value", "count", "ok"},
+				Values:  [][]interface{}{{time.Unix(0, 30), 2.5, int64(5), true}},
+			},
+		},
+	}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteResponse(Response{Results: []*query.Result{{Err: errors.New("oops")}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := readArrowMessages(t, buf.Bytes())
+	if len(messages) != 4 {
+		t.Fatalf("unexpected messages: %d", len(messages))
+	}
+
+	// The schema has the series name, the tags and the columns.
+	schema := messages[0]
+	if schema.headerType != arrowHeaderSchema {
+		t.Fatalf("unexpected header type: %d", schema.headerType)
+	}
+	type field struct {
+		name     string
+		typeType byte
+	}
+	var fields []field
+	for _, fl := range schema.header.vector(1) {
+		if !fl.scalarBool(1) {
+			t.Fatal("expected a nullable field")
+		} else if fl.vector(5) == nil {
+			t.Fatal("expected empty children")
+		}
+		fields = append(fields, field{fl.str(0), fl.scalar8(2)})
+	}
+	if exp := []field{
+		{"name", arrowTypeUtf8},
+		{"host", arrowTypeUtf8},
+		{"time", arrowTypeTimestamp},
+		{"value", arrowTypeFloatingPoint},
+		{"count", arrowTypeInt},
+		{"ok", arrowTypeBool},
+	}; !reflect.DeepEqual(fields, exp) {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+
+	// The first series is the first batch.
+	batch := messages[1]
+	if batch.headerType != arrowHeaderRecordBatch {
+		t.Fatalf("unexpected header type: %d", batch.headerType)
+	} else if n := batch.header.scalar64(0); n != 2 {
+		t.Fatalf("unexpected length: %d", n)
+	}
+	nodes, buffers := batch.header.structs(1), batch.header.structs(2)
+	if len(nodes) != 6*2 || len(buffers) != 2*(3+3+2*4) {
+		t.Fatalf("unexpected nodes or buffers: %d, %d", len(nodes), len(buffers))
+	} else if nodes[6] != 2 || nodes[7] != 1 {
+		t.Fatalf("unexpected value node: %v", nodes[6:8])
+	}
+	buffer := func(i int) []byte {
+		return batch.body[buffers[2*i] : buffers[2*i]+buffers[2*i+1]]
+	}
+	if s := string(buffer(5)); s != "aa" {
+		t.Fatalf("unexpected host data: %q", s)
+	} else if v := binary.LittleEndian.Uint64(buffer(7)[8:]); v != 20 {
+		t.Fatalf("unexpected time: %d", v)
+	} else if b := buffer(8); !bytes.Equal(b, []byte{1}) {
+		t.Fatalf("unexpected value validity: %v", b)
+	} else if v := math.Float64frombits(binary.LittleEndian.Uint64(buffer(9))); v != 1.5 {
+		t.Fatalf("unexpected value: %v", v)
+	} else if b := buffer(13); !bytes.Equal(b, []byte{1}) {
+		t.Fatalf("unexpected ok bits: %v", b)
+	}
+	for i := 0; i < len(buffers); i += 2 {
+		if buffers[i]%8 != 0 {
+			t.Fatalf("unaligned buffer %d at %d", i/2, buffers[i])
+		}
+	}
+
+	if n := messages[2].header.scalar64(0); n != 1 {
+		t.Fatalf("unexpected length: %d", n)
+	}
+
+	// Errors are sent in the metadata of an empty batch.
+	if m := messages[3]; m.headerType != arrowHeaderRecordBatch || m.header.scalar64(0) != 0 || m.metadata["error"] != "oops" {
+		t.Fatalf("unexpected error message: %+v", m)
+	}
+}
+
+func TestArrowFormatter_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	f := &arrowFormatter{Writer: &buf}
+	if _, err := f.WriteResponse(Response{Results: []*query.Result{{}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := readArrowMessages(t, buf.Bytes())
+	if len(messages) != 1 || messages[0].headerType != arrowHeaderSchema || len(messages[0].header.vector(1)) != 0 {
+		t.Fatalf("unexpected messages: %+v", messages)
+	}
+}
+
+type arrowMessage struct {
+	headerType byte
+	header     fbTestTable
+	metadata   map[string]string
+	body       []byte
+}
+
+// readArrowMessages decodes an Arrow IPC stream.
+func readArrowMessages(t *testing.T, b []byte) []arrowMessage {
+	var messages []arrowMessage
+	for len(b) > 0 {
+		if binary.LittleEndian.Uint32(b) != 0xFFFFFFFF {
+			t.Fatal("missing continuation marker")
+		}
+		size := int(binary.LittleEndian.Uint32(b[4:]))
+		if size%8 != 0 {
+			t.Fatalf("unaligned metadata size: %d", size)
+		}
+		buf := b[8 : 8+size]
+		root := fbTestTable{buf, int(binary.LittleEndian.Uint32(buf))}
+
+		if v := root.scalar16(0); v != arrowMetadataV5 {
+			t.Fatalf("unexpected version: %d", v)
+		}
+		m := arrowMessage{
+			headerType: root.scalar8(1),
+			header:     root.table(2),
+			metadata:   make(map[string]string),
+		}
+		for _, kv := range root.vector(4) {
+			m.metadata[kv.str(0)] = kv.str(1)
+		}
+		bodyLength := int(root.scalar64(3))
+		m.body = b[8+size : 8+size+bodyLength]
+		messages = append(messages, m)
+		b = b[8+size+bodyLength:]
+	}
+	return messages
+}
+
+// fbTestTable reads a flatbuffer table at pos in buf.
+type fbTestTable struct {
+	buf []byte
+	pos int
+}
+
+// field returns the position of field i, or 0 if it is absent.
+func (t fbTestTable) field(i int) int {
+	vtable := t.pos - int(int32(binary.LittleEndian.Uint32(t.buf[t.pos:])))
+	if 4+2*i >= int(binary.LittleEndian.Uint16(t.buf[vtable:])) {
+		return 0
+	}
+	off := int(binary.LittleEndian.Uint16(t.buf[vtable+4+2*i:]))
+	if off == 0 {
+		return 0
+	}
+	return t.pos + off
+}
+
+func (t fbTestTable) deref(pos int) int {
+	return pos + int(binary.LittleEndian.Uint32(t.buf[pos:]))
+}
+
+func (t fbTestTable) scalar8(i int) byte {
+	if p := t.field(i); p != 0 {
+		return t.buf[p]
+	}
+	return 0
+}
+
+func (t fbTestTable) scalarBool(i int) bool { return t.scalar8(i) != 0 }
+
+func (t fbTestTable) scalar16(i int) uint16 {
+	if p := t.field(i); p != 0 {
+		return binary.LittleEndian.Uint16(t.buf[p:])
+	}
+	return 0
+}
+
+func (t fbTestTable) scalar64(i int) uint64 {
+	if p := t.field(i); p != 0 {
+		if p%8 != 0 {
+			panic("unaligned field")
+		}
+		return binary.LittleEndian.Uint64(t.buf[p:])
+	}
+	return 0
+}
+
+func (t fbTestTable) table(i int) fbTestTable {
+	return fbTestTable{t.buf, t.deref(t.field(i))}
+}
+
+func (t fbTestTable) str(i int) string {
+	p := t.deref(t.field(i))
+	n := int(binary.LittleEndian.Uint32(t.buf[p:]))
+	return string(t.buf[p+4 : p+4+n])
+}
+
+func (t fbTestTable) vector(i int) []fbTestTable {
+	f := t.field(i)
+	if f == 0 {
+		return nil
+	}
+	p := t.deref(f)
+	n := int(binary.LittleEndian.Uint32(t.buf[p:]))
+	a := make([]fbTestTable, n)
+	for j := range a {
+		a[j] = fbTestTable{t.buf, t.deref(p + 4 + 4*j)}
+	}
+	return a
+}
+
+// structs returns the int64 values of a vector of structs of int64s.
+func (t fbTestTable) structs(i int) []int {
+	p := t.deref(t.field(i))
+	n := int(binary.LittleEndian.Uint32(t.buf[p:]))
+	if (p+4)%8 != 0 {
+		panic("unaligned structs")
+	}
+	a := make([]int, 2*n)
+	for j := range a {
+		a[j] = int(binary.LittleEndian.Uint64(t.buf[p+4+8*j:]))
+	}
+	return a
+}
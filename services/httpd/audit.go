@@ -0,0 +1,106 @@
+package httpd
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/influxdata/influxdb/services/audit"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxql"
+)
+
+// auditedRoutes maps the names of the routes recorded in the audit log to
+// the action they are recorded as.
+var auditedRoutes = map[string]string{
+	"query":            audit.ActionQuery,
+	"write":            audit.ActionWrite,
+	"write-multi":      audit.ActionWrite,
+	"prometheus-write": audit.ActionWrite,
+	"prometheus-read":  audit.ActionQuery,
+	"users-create":     audit.ActionAdmin,
+	"users-drop":       audit.ActionAdmin,
+	"users-grant":      audit.ActionAdmin,
+	"tokens-create":    audit.ActionAdmin,
+	"tokens-drop":      audit.ActionAdmin,
+}
+
+// auditKey is the request context key of the event being audited.
+type auditKey struct{}
+
+// audited wraps a handler so that a record of each request is sent to the
+// audit log once the response is complete. The handler fills in the details
+// of the event it finds in the request context.
+func (h *Handler) audited(inner http.Handler, action string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.AuditLog == nil {
+			inner.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		e := &audit.Event{
+			Time:   start.UTC(),
+			Action: action,
+			Addr:   host,
+		}
+
+		l := &responseLogger{w: w}
+		inner.ServeHTTP(l, r.WithContext(context.WithValue(r.Context(), auditKey{}, e)))
+
+		// The username of a request that failed authentication is still
+		// worth recording.
+		if e.User == "" {
+			e.User = parseUsername(r)
+		}
+		if e.Error == "" {
+			e.Error = l.Header().Get("X-InfluxDB-Error")
+		}
+		e.Status = l.Status()
+		e.Duration = time.Since(start)
+		h.AuditLog.Log(*e)
+	})
+}
+
+// auditEvent returns the event being audited for r, or nil if the request
+// is not audited.
+func auditEvent(r *http.Request) *audit.Event {
+	e, _ := r.Context().Value(auditKey{}).(*audit.Event)
+	return e
+}
+
+// auditQuery records the user and query of the audited request r. Queries
+// with statements requiring admin privileges are recorded as admin actions.
+func auditQuery(r *http.Request, user meta.User, q *influxql.Query, database string) {
+	e := auditEvent(r)
+	if e == nil {
+		return
+	}
+
+	if user != nil {
+		e.User = user.ID()
+	}
+	// The string form of a query redacts passwords.
+	e.Query = q.String()
+	e.Database = database
+	e.Action = audit.QueryAction(q)
+}
+
+// auditWrite records the user and destination of the audited write request r.
+func auditWrite(r *http.Request, user meta.User, database, retentionPolicy string) {
+	e := auditEvent(r)
+	if e == nil {
+		return
+	}
+
+	if user != nil {
+		e.User = user.ID()
+	}
+	e.Database = database
+	e.RetentionPolicy = retentionPolicy
+}
@@ -1,6 +1,14 @@
 package httpd
 
-import "github.com/influxdata/influxdb/monitor/diagnostics"
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/toml"
+)
 
 const (
 	// DefaultBindAddress is the default address to bind to.
@@ -14,6 +22,25 @@ const (
 
 	// DefaultMaxBodySize is the default maximum size of a client request body, in bytes. Specify 0 for no limit.
 	DefaultMaxBodySize = 25e6
+
+	// DefaultACMEChallengeBindAddress is the default address the ACME HTTP-01 challenge listener binds to.
+	DefaultACMEChallengeBindAddress = ":80"
+
+	// DefaultAccessLogFormat is the default format of access log lines.
+	DefaultAccessLogFormat = AccessLogFormatCommon
+
+	// DefaultParseCacheSize is the default number of parsed queries kept.
+	DefaultParseCacheSize = 1000
+)
+
+// Formats of the access log.
+const (
+	// AccessLogFormatCommon logs requests in the Common Log Format, extended
+	// with the referrer, user agent, request ID and response time.
+	AccessLogFormatCommon = "common"
+
+	// AccessLogFormatJSON logs each request as a JSON object on its own line.
+	AccessLogFormatJSON = "json"
 )
 
 // Config represents a configuration for a HTTP service.
@@ -22,8 +49,11 @@ type Config struct {
 	BindAddress        string `toml:"bind-address"`
 	AuthEnabled        bool   `toml:"auth-enabled"`
 	LogEnabled         bool   `toml:"log-enabled"`
+	SuppressWriteLog   bool   `toml:"suppress-write-log"`
 	WriteTracing       bool   `toml:"write-tracing"`
 	PprofEnabled       bool   `toml:"pprof-enabled"`
+	PprofAuthEnabled   bool   `toml:"pprof-auth-enabled"`
+	DebugBindAddress   string `toml:"debug-bind-address"`
 	HTTPSEnabled       bool   `toml:"https-enabled"`
 	HTTPSCertificate   string `toml:"https-certificate"`
 	HTTPSPrivateKey    string `toml:"https-private-key"`
@@ -34,6 +64,38 @@ type Config struct {
 	UnixSocketEnabled  bool   `toml:"unix-socket-enabled"`
 	BindSocket         string `toml:"bind-socket"`
 	MaxBodySize        int    `toml:"max-body-size"`
+
+	// The access log is written to stderr unless a path is set. A file is
+	// rotated once it reaches the maximum size or has been open for the
+	// rotate interval, and only the newest rotated files are kept.
+	AccessLogPath           string        `toml:"access-log-path"`
+	AccessLogFormat         string        `toml:"access-log-format"`
+	AccessLogMaxSize        toml.Size     `toml:"access-log-max-size"`
+	AccessLogRotateInterval toml.Duration `toml:"access-log-rotate-interval"`
+	AccessLogMaxBackups     int           `toml:"access-log-max-backups"`
+
+	// Quotas limit what a single user or database may consume. A value of 0
+	// disables the limit. Requests over a quota are answered with 429.
+	UserWritePointsPerSecond     int `toml:"user-write-points-per-second"`
+	UserQueriesPerSecond         int `toml:"user-queries-per-second"`
+	UserMaxConcurrentQueries     int `toml:"user-max-concurrent-queries"`
+	DatabaseWritePointsPerSecond int `toml:"database-write-points-per-second"`
+	DatabaseQueriesPerSecond     int `toml:"database-queries-per-second"`
+	DatabaseMaxConcurrentQueries int `toml:"database-max-concurrent-queries"`
+
+	HTTPSACMEEnabled              bool     `toml:"https-acme-enabled"`
+	HTTPSACMEDomains              []string `toml:"https-acme-domains"`
+	HTTPSACMEEmail                string   `toml:"https-acme-email"`
+	HTTPSACMECacheDir             string   `toml:"https-acme-cache-dir"`
+	HTTPSACMEChallengeBindAddress string   `toml:"https-acme-challenge-bind-address"`
+
+	// AdditionalBindAddresses are served the same way as BindAddress, such
+	// as an IPv6 address next to an IPv4 one.
+	AdditionalBindAddresses []string `toml:"additional-bind-addresses"`
+
+	// ParseCacheSize is the number of parsed SELECT queries kept, so the
+	// queries dashboards repeat are not parsed each time. 0 disables it.
+	ParseCacheSize int `toml:"parse-cache-size"`
 }
 
 // NewConfig returns a new Config with default settings.
@@ -50,7 +112,65 @@ func NewConfig() Config {
 		UnixSocketEnabled: false,
 		BindSocket:        DefaultBindSocket,
 		MaxBodySize:       DefaultMaxBodySize,
+		AccessLogFormat:   DefaultAccessLogFormat,
+		ParseCacheSize:    DefaultParseCacheSize,
+
+		HTTPSACMEChallengeBindAddress: DefaultACMEChallengeBindAddress,
+	}
+}
+
+// Validate returns an error if the Config is invalid.
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	for _, addr := range c.AdditionalBindAddresses {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("invalid additional-bind-addresses entry %q: %s", addr, err)
+		}
+	}
+
+	for name, v := range map[string]int{
+		"user-write-points-per-second":     c.UserWritePointsPerSecond,
+		"user-queries-per-second":          c.UserQueriesPerSecond,
+		"user-max-concurrent-queries":      c.UserMaxConcurrentQueries,
+		"database-write-points-per-second": c.DatabaseWritePointsPerSecond,
+		"database-queries-per-second":      c.DatabaseQueriesPerSecond,
+		"database-max-concurrent-queries":  c.DatabaseMaxConcurrentQueries,
+	} {
+		if v < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+
+	switch c.AccessLogFormat {
+	case "", AccessLogFormatCommon, AccessLogFormatJSON:
+	default:
+		return fmt.Errorf("unknown access-log-format: %q", c.AccessLogFormat)
+	}
+	if c.AccessLogRotateInterval < 0 {
+		return errors.New("access-log-rotate-interval must not be negative")
+	} else if c.AccessLogMaxBackups < 0 {
+		return errors.New("access-log-max-backups must not be negative")
+	} else if c.ParseCacheSize < 0 {
+		return errors.New("parse-cache-size must not be negative")
+	}
+
+	if !c.HTTPSACMEEnabled {
+		return nil
 	}
+
+	if !c.HTTPSEnabled {
+		return errors.New("https-acme-enabled requires https-enabled")
+	} else if len(c.HTTPSACMEDomains) == 0 {
+		return errors.New("https-acme-domains must contain at least one domain")
+	} else if c.HTTPSACMECacheDir == "" {
+		return errors.New("https-acme-cache-dir must be specified")
+	} else if c.HTTPSACMEChallengeBindAddress == "" {
+		return errors.New("https-acme-challenge-bind-address must be specified")
+	}
+	return nil
 }
 
 // Diagnostics returns a diagnostics representation of a subset of the Config.
@@ -65,7 +185,25 @@ func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
 		"enabled":              true,
 		"bind-address":         c.BindAddress,
 		"https-enabled":        c.HTTPSEnabled,
+		"pprof-enabled":        c.PprofEnabled,
+		"pprof-auth-enabled":   c.PprofAuthEnabled,
+		"debug-bind-address":   c.DebugBindAddress,
+		"https-acme-enabled":   c.HTTPSACMEEnabled,
 		"max-row-limit":        c.MaxRowLimit,
 		"max-connection-limit": c.MaxConnectionLimit,
+		"log-enabled":          c.LogEnabled,
+		"suppress-write-log":   c.SuppressWriteLog,
+		"access-log-path":      c.AccessLogPath,
+		"access-log-format":    c.AccessLogFormat,
+
+		"user-write-points-per-second":     c.UserWritePointsPerSecond,
+		"user-queries-per-second":          c.UserQueriesPerSecond,
+		"user-max-concurrent-queries":      c.UserMaxConcurrentQueries,
+		"database-write-points-per-second": c.DatabaseWritePointsPerSecond,
+		"database-queries-per-second":      c.DatabaseQueriesPerSecond,
+		"database-max-concurrent-queries":  c.DatabaseMaxConcurrentQueries,
+
+		"additional-bind-addresses": strings.Join(c.AdditionalBindAddresses, ","),
+		"parse-cache-size":          c.ParseCacheSize,
 	}), nil
 }
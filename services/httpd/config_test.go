@@ -49,6 +49,42 @@ max-body-size = 100
 	}
 }
 
+func TestConfig_Validate_ACME(t *testing.T) {
+	c := httpd.NewConfig()
+	c.HTTPSACMEEnabled = true
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for acme without https, got nil")
+	}
+
+	c.HTTPSEnabled = true
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for acme without domains, got nil")
+	}
+
+	c.HTTPSACMEDomains = []string{"influxdb.example.com"}
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for acme without cache dir, got nil")
+	}
+
+	c.HTTPSACMECacheDir = "/var/lib/influxdb/acme"
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+}
+
+func TestConfig_Validate_AdditionalBindAddresses(t *testing.T) {
+	c := httpd.NewConfig()
+	c.AdditionalBindAddresses = []string{"127.0.0.1:8086", "[::1]:8086"}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %s", err)
+	}
+
+	c.AdditionalBindAddresses = []string{"::1"}
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for an address without a port, got nil")
+	}
+}
+
 func TestConfig_WriteTracing(t *testing.T) {
 	c := httpd.Config{WriteTracing: true}
 	s := httpd.NewService(c)
@@ -0,0 +1,95 @@
+package httpd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/pkg/fault"
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// isDebugPath returns true if path is one of the profiling and runtime
+// statistics endpoints.
+func (h *Handler) isDebugPath(path string) bool {
+	switch {
+	case strings.HasPrefix(path, "/debug/pprof"):
+		return h.Config.PprofEnabled
+	case strings.HasPrefix(path, "/debug/vars"), strings.HasPrefix(path, "/debug/requests"):
+		return true
+	case strings.HasPrefix(path, "/debug/faults"):
+		return fault.Enabled
+	}
+	return false
+}
+
+// serveDebug serves the profiling and runtime statistics endpoints. When
+// pprof-auth-enabled is set, the user must be an admin.
+func (h *Handler) serveDebug(w http.ResponseWriter, r *http.Request, user meta.User) {
+	if user != nil && !user.IsAdmin() {
+		h.httpError(w, "admin privileges required", http.StatusForbidden)
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/debug/pprof"):
+		h.handleProfiles(w, r)
+	case strings.HasPrefix(r.URL.Path, "/debug/vars"):
+		h.serveExpvar(w, r)
+	case strings.HasPrefix(r.URL.Path, "/debug/requests"):
+		h.serveDebugRequests(w, r)
+	case strings.HasPrefix(r.URL.Path, "/debug/faults"):
+		h.serveDebugFaults(w, r)
+	}
+}
+
+// serveDebugFaults lists the faults of the injection points, sets one with
+// POST or clears one with DELETE, on a server built with the faultinject
+// tag.
+func (h *Handler) serveDebugFaults(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	switch r.Method {
+	case "GET":
+	case "POST":
+		var f fault.Fault
+		if s := q.Get("delay"); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				h.httpError(w, fmt.Sprintf("invalid delay: %s", err), http.StatusBadRequest)
+				return
+			}
+			f.Delay = d
+		}
+		f.Error = q.Get("error")
+		f.Crash = q.Get("crash") == "true"
+		if err := fault.Set(q.Get("point"), f); err != nil {
+			h.httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case "DELETE":
+		fault.Clear(q.Get("point"))
+	default:
+		h.httpError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fault.Faults())
+}
+
+// debugHandler returns a handler serving only the debug endpoints, for the
+// listener on the debug bind address.
+func (h *Handler) debugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-Influxdb-Version", h.Version)
+		w.Header().Add("X-Influxdb-Build", h.BuildType)
+
+		if !h.isDebugPath(r.URL.Path) {
+			http.NotFound(w, r)
+			return
+		}
+		h.debug.ServeHTTP(w, r)
+	})
+}
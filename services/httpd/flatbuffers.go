@@ -0,0 +1,158 @@
+package httpd
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// fbBuilder writes a flatbuffer front to back. Offsets in a flatbuffer
+// always point forward, so an object is written before the objects it refers
+// to and each reference is filled in once the object it points at has been
+// written. This is all the Arrow encoder needs; there is no reader.
+type fbBuilder struct {
+	buf []byte
+}
+
+// fbObject is a table, vector or string that can be written to a flatbuffer.
+type fbObject interface {
+	// fbWrite writes the object and returns the position offsets referring
+	// to it point at.
+	fbWrite(b *fbBuilder) int
+}
+
+// fbFinish returns a flatbuffer with root as its root table, padded to a
+// multiple of 8 bytes.
+func fbFinish(root fbObject) []byte {
+	b := &fbBuilder{buf: make([]byte, 4, 256)}
+	b.putOffset(0, root.fbWrite(b))
+	b.pad(8)
+	return b.buf
+}
+
+func (b *fbBuilder) pad(align int) {
+	for len(b.buf)%align != 0 {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+func (b *fbBuilder) grow(n int) int {
+	pos := len(b.buf)
+	b.buf = append(b.buf, make([]byte, n)...)
+	return pos
+}
+
+// putOffset stores at pos the offset from pos to target.
+func (b *fbBuilder) putOffset(pos, target int) {
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(target-pos))
+}
+
+// fbField is a field of a table. The zero value is an absent field.
+type fbField struct {
+	size  int      // size in bytes of a scalar field
+	value uint64   // value of a scalar field
+	ref   fbObject // object a reference field points at
+}
+
+func fbScalar(size int, v uint64) fbField { return fbField{size: size, value: v} }
+func fbBool(v bool) fbField {
+	if v {
+		return fbScalar(1, 1)
+	}
+	return fbScalar(1, 0)
+}
+func fbRef(o fbObject) fbField { return fbField{ref: o} }
+
+// fbTable is a table with its fields in schema order.
+type fbTable []fbField
+
+func (t fbTable) fbWrite(b *fbBuilder) int {
+	// Lay out the fields after the vtable offset, largest first, so each
+	// one is aligned to its size.
+	type slot struct{ i, size int }
+	var slots []slot
+	for i, f := range t {
+		if f.ref != nil {
+			slots = append(slots, slot{i, 4})
+		} else if f.size > 0 {
+			slots = append(slots, slot{i, f.size})
+		}
+	}
+	sort.SliceStable(slots, func(i, j int) bool { return slots[i].size > slots[j].size })
+
+	offsets := make([]int, len(t))
+	size := 4
+	for _, s := range slots {
+		for size%s.size != 0 {
+			size++
+		}
+		offsets[s.i] = size
+		size += s.size
+	}
+
+	b.pad(2)
+	vtable := b.grow(4 + 2*len(t))
+	binary.LittleEndian.PutUint16(b.buf[vtable:], uint16(4+2*len(t)))
+	binary.LittleEndian.PutUint16(b.buf[vtable+2:], uint16(size))
+	for i, off := range offsets {
+		binary.LittleEndian.PutUint16(b.buf[vtable+4+2*i:], uint16(off))
+	}
+
+	b.pad(8)
+	start := b.grow(size)
+	binary.LittleEndian.PutUint32(b.buf[start:], uint32(int32(start-vtable)))
+	for i, f := range t {
+		if f.ref == nil && f.size > 0 {
+			for j := 0; j < f.size; j++ {
+				b.buf[start+offsets[i]+j] = byte(f.value >> (8 * uint(j)))
+			}
+		}
+	}
+	for i, f := range t {
+		if f.ref != nil {
+			b.putOffset(start+offsets[i], f.ref.fbWrite(b))
+		}
+	}
+	return start
+}
+
+// fbVector is a vector of tables, vectors or strings.
+type fbVector []fbObject
+
+func (v fbVector) fbWrite(b *fbBuilder) int {
+	b.pad(4)
+	start := b.grow(4 + 4*len(v))
+	binary.LittleEndian.PutUint32(b.buf[start:], uint32(len(v)))
+	for i, o := range v {
+		b.putOffset(start+4+4*i, o.fbWrite(b))
+	}
+	return start
+}
+
+// fbStructs is a vector of n structs that are 8 byte aligned, encoded in
+// data.
+type fbStructs struct {
+	n    int
+	data []byte
+}
+
+func (v fbStructs) fbWrite(b *fbBuilder) int {
+	for (len(b.buf)+4)%8 != 0 {
+		b.buf = append(b.buf, 0)
+	}
+	start := b.grow(4)
+	binary.LittleEndian.PutUint32(b.buf[start:], uint32(v.n))
+	b.buf = append(b.buf, v.data...)
+	return start
+}
+
+// fbString is a string.
+type fbString string
+
+func (s fbString) fbWrite(b *fbBuilder) int {
+	b.pad(4)
+	start := b.grow(4)
+	binary.LittleEndian.PutUint32(b.buf[start:], uint32(len(s)))
+	b.buf = append(b.buf, s...)
+	b.buf = append(b.buf, 0)
+	return start
+}
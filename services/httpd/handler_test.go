@@ -2,6 +2,8 @@ package httpd_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -21,10 +23,14 @@ import (
 	"github.com/golang/snappy"
 	"github.com/influxdata/influxdb/internal"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/monitor"
+	"github.com/influxdata/influxdb/monitor/diagnostics"
 	"github.com/influxdata/influxdb/prometheus/remote"
 	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/services/audit"
 	"github.com/influxdata/influxdb/services/httpd"
 	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
 	"github.com/influxdata/influxql"
 )
 
@@ -51,6 +57,86 @@ func TestHandler_Query(t *testing.T) {
 	}
 }
 
+// Ensure repeated queries are taken from the parse cache, and that a query
+// changed while it is executed does not change the cached one.
+func TestHandler_Query_ParseCache(t *testing.T) {
+	h := NewHandler(false)
+	var stmts []string
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		stmts = append(stmts, stmt.String())
+		stmt.(*influxql.SelectStatement).Sources[0].(*influxql.Measurement).Database = "changed"
+		ctx.Results <- &query.Result{StatementID: 0}
+		return nil
+	}
+
+	for _, q := range []string{
+		"q=SELECT+*+FROM+bar",
+		"q=SELECT+*+FROM+bar",
+		"q=SELECT+*+FROM+bar+WHERE+host%3D%24host&params=%7B%22host%22%3A%22a%22%7D",
+		"q=SELECT+*+FROM+bar+WHERE+host%3D%24host&params=%7B%22host%22%3A%22b%22%7D",
+		"q=SELECT+*+FROM+bar+WHERE+host%3D%24host&params=%7B%22host%22%3A%22a%22%7D",
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&"+q, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	exp := []string{
+		`SELECT * FROM bar`,
+		`SELECT * FROM bar`,
+		`SELECT * FROM bar WHERE host = 'a'`,
+		`SELECT * FROM bar WHERE host = 'b'`,
+		`SELECT * FROM bar WHERE host = 'a'`,
+	}
+	if !reflect.DeepEqual(stmts, exp) {
+		t.Fatalf("unexpected statements: %v", stmts)
+	}
+
+	stats := h.Statistics(nil)[0].Values
+	if hits, misses := stats["parseCacheHits"], stats["parseCacheMisses"]; hits != int64(2) || misses != int64(3) {
+		t.Fatalf("unexpected hits and misses: %v, %v", hits, misses)
+	}
+}
+
+// Ensure the handler returns integer timestamps in the requested epoch.
+func TestHandler_Query_Epoch(t *testing.T) {
+	h := NewHandler(false)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		ctx.Results <- &query.Result{StatementID: 0, Series: models.Rows([]*models.Row{{
+			Name:    "cpu",
+			Columns: []string{"time", "value"},
+			Values:  [][]interface{}{{time.Unix(2, 0), 1}},
+		}})}
+		return nil
+	}
+
+	for _, tt := range []struct {
+		params string
+		code   int
+		time   string
+	}{
+		{params: "", code: http.StatusOK, time: `"1970-01-01T00:00:02Z"`},
+		{params: "&epoch=s", code: http.StatusOK, time: "2"},
+		{params: "&epoch=ms", code: http.StatusOK, time: "2000"},
+		{params: "&epoch=us", code: http.StatusOK, time: "2000000"},
+		{params: "&epoch=ns", code: http.StatusOK, time: "2000000000"},
+		{params: "&precision=s", code: http.StatusOK, time: "2"},
+		{params: "&epoch=d", code: http.StatusBadRequest},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+cpu"+tt.params, nil))
+		if w.Code != tt.code {
+			t.Errorf("%q: unexpected status: got %d, exp %d", tt.params, w.Code, tt.code)
+		} else if tt.code != http.StatusOK {
+			continue
+		} else if exp := `"values":[[` + tt.time + `,1]]`; !strings.Contains(w.Body.String(), exp) {
+			t.Errorf("%q: unexpected body: %s", tt.params, w.Body.String())
+		}
+	}
+}
+
 // Ensure the handler returns results from a query passed as a file.
 func TestHandler_Query_File(t *testing.T) {
 	h := NewHandler(false)
@@ -222,6 +308,20 @@ func TestHandler_Query_Auth(t *testing.T) {
 		t.Fatalf("unexpected body: %s", body)
 	}
 
+	// Test handler with JWT token when no shared secret is configured.
+	secret := h.Config.SharedSecret
+	h.Config.SharedSecret = ""
+	_, signedToken = MustJWTToken("user1", "", false)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", signedToken))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected status: %d: %s", w.Code, w.Body.String())
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"error":"bearer auth disabled"}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+	h.Config.SharedSecret = secret
+
 	// Test the handler with valid user and password in the url and invalid in
 	// basic auth (prioritize url).
 	w = httptest.NewRecorder()
@@ -288,6 +388,79 @@ func TestHandler_Query_MergeEmptyResults(t *testing.T) {
 	}
 }
 
+// Ensure the handler sends the warnings of the results in the response and
+// in a header.
+func TestHandler_Query_Warnings(t *testing.T) {
+	h := NewHandler(false)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		ctx.Results <- &query.Result{
+			StatementID: 1,
+			Series:      models.Rows([]*models.Row{{Name: "series0"}}),
+			Messages:    []*query.Message{query.ExpensiveQueryWarning("series", 20, 10)},
+		}
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"results":[{"statement_id":1,"series":[{"name":"series0"}],"messages":[{"level":"warning","text":"expensive query: reads 20 series, more than the warning threshold of 10"}]}],"warnings":["expensive query: reads 20 series, more than the warning threshold of 10"]}` {
+		t.Fatalf("unexpected body: %s", body)
+	} else if got, exp := w.Result().Header.Get("X-Influxdb-Warnings"), "expensive query: reads 20 series, more than the warning threshold of 10"; got != exp {
+		t.Fatalf("unexpected header: got %q, exp %q", got, exp)
+	} else if trailer := w.Result().Header.Get("Trailer"); trailer != "" {
+		t.Fatalf("unexpected trailer: %s", trailer)
+	}
+}
+
+// Ensure the handler sends the warnings of a chunked response with their
+// chunk and in a trailer.
+func TestHandler_Query_Warnings_Chunked(t *testing.T) {
+	h := NewHandler(false)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		ctx.Results <- &query.Result{
+			StatementID: 1,
+			Series:      models.Rows([]*models.Row{{Name: "series0"}}),
+			Messages:    []*query.Message{query.ExpensiveQueryWarning("series", 20, 10)},
+		}
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar&chunked=true", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"results":[{"statement_id":1,"series":[{"name":"series0"}],"messages":[{"level":"warning","text":"expensive query: reads 20 series, more than the warning threshold of 10"}]}],"warnings":["expensive query: reads 20 series, more than the warning threshold of 10"]}` {
+		t.Fatalf("unexpected body: %s", body)
+	} else if got, exp := w.Result().Trailer.Get("X-Influxdb-Warnings"), "expensive query: reads 20 series, more than the warning threshold of 10"; got != exp {
+		t.Fatalf("unexpected trailer: got %q, exp %q", got, exp)
+	}
+}
+
+// Ensure the handler truncates a non-chunked response at the max row limit
+// and marks it as partial.
+func TestHandler_Query_MaxRowLimit(t *testing.T) {
+	config := httpd.NewConfig()
+	config.MaxRowLimit = 2
+	h := NewHandlerWithConfig(config)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		ctx.Results <- &query.Result{StatementID: 0, Series: models.Rows([]*models.Row{
+			{Name: "series0", Columns: []string{"value"}, Values: [][]interface{}{{1}, {2}}},
+			{Name: "series1", Columns: []string{"value"}, Values: [][]interface{}{{3}}},
+		})}
+		return nil
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"results":[{"statement_id":0,"series":[{"name":"series0","columns":["value"],"values":[[1],[2]],"partial":true}]}]}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
 // Ensure the handler can parse chunked and chunk size query parameters.
 func TestHandler_Query_Chunked(t *testing.T) {
 	h := NewHandler(false)
@@ -675,6 +848,115 @@ func TestHandler_Ping(t *testing.T) {
 	}
 }
 
+// Ensure the handler returns the version from a verbose ping.
+func TestHandler_Ping_Verbose(t *testing.T) {
+	h := NewHandler(false)
+	h.Version = "1.2.3"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/ping?verbose=true", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"version":"1.2.3"}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+// Ensure the handler reports failing health checks.
+func TestHandler_Health(t *testing.T) {
+	h := NewHandler(false)
+	h.Version = "1.2.3"
+
+	var dataErr error
+	h.HealthChecks = []httpd.HealthCheck{
+		{Name: "meta", Check: func() error { return nil }},
+		{Name: "data", Check: func() error { return dataErr }},
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/health", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"name":"influxdb","status":"pass","version":"1.2.3","checks":[{"name":"meta","status":"pass"},{"name":"data","status":"pass"}]}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	dataErr = errors.New("store is closed")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/health", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if body := strings.TrimSpace(w.Body.String()); body != `{"name":"influxdb","status":"fail","version":"1.2.3","checks":[{"name":"meta","status":"pass"},{"name":"data","status":"fail","message":"store is closed"}]}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+// Ensure only /ping and /health are served while the server is starting.
+func TestHandler_Starting(t *testing.T) {
+	h := NewHandler(false)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		return nil
+	}
+	h.SetStarting(true)
+
+	for _, tt := range []struct {
+		url  string
+		code int
+	}{
+		{url: "/ping", code: http.StatusNoContent},
+		{url: "/health", code: http.StatusOK},
+		{url: "/query?q=SHOW+DATABASES", code: http.StatusServiceUnavailable},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewRequest("GET", tt.url, nil))
+		if w.Code != tt.code {
+			t.Errorf("%s: unexpected status: got %d, exp %d", tt.url, w.Code, tt.code)
+		}
+	}
+
+	h.SetStarting(false)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/query?q=SHOW+DATABASES", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status once started: %d", w.Code)
+	}
+}
+
+// Ensure the handler serves internal statistics in the Prometheus format.
+func TestHandler_Metrics(t *testing.T) {
+	h := NewHandler(false)
+	h.Monitor = &HandlerMonitor{
+		StatisticsFn: func(tags map[string]string) ([]*monitor.Statistic, error) {
+			return []*monitor.Statistic{
+				{Statistic: models.Statistic{
+					Name:   "shard",
+					Tags:   map[string]string{"database": "db0", "id": "1"},
+					Values: map[string]interface{}{"writePointsOk": int64(10), "engine": "tsm1"},
+				}},
+			}, nil
+		},
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	body := w.Body.String()
+	for _, exp := range []string{
+		`influxdb_shard_write_points_ok{database="db0",id="1"} 10`,
+		`influxdb_httpd_query_duration_seconds_count 0`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(body, exp) {
+			t.Errorf("missing %q in body:\n%s", exp, body)
+		}
+	}
+	if strings.Contains(body, "engine") {
+		t.Errorf("unexpected string value in body:\n%s", body)
+	}
+}
+
 // Ensure the handler returns the version correctly from the different endpoints.
 func TestHandler_Version(t *testing.T) {
 	h := NewHandler(false)
@@ -817,6 +1099,614 @@ func TestHandler_Write_NegativeMaxBodySize(t *testing.T) {
 	}
 }
 
+// Ensure writes over a database quota are rejected with a retry hint.
+func TestHandler_Write_Quota(t *testing.T) {
+	config := httpd.NewConfig()
+	config.DatabaseWritePointsPerSecond = 2
+	h := NewHandlerWithConfig(config)
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{}
+	}
+	h.PointsWriter.WritePointsFn = func(_, _ string, _ models.ConsistencyLevel, _ meta.User, _ []models.Point) error {
+		return nil
+	}
+
+	// A batch larger than the quota is accepted, and later writes pay for it.
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo", strings.NewReader("cpu v=1 1\ncpu v=2 2\ncpu v=3 3")))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo", strings.NewReader("cpu v=1")))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if ra := w.Header().Get("Retry-After"); ra != "1" {
+		t.Fatalf("unexpected Retry-After: %q", ra)
+	}
+
+	// Other databases have their own quota.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=bar", strings.NewReader("cpu v=1")))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
+// Ensure a partial write persists the valid points and returns the counts
+// and the first errors.
+func TestHandler_Write_Partial(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{}
+	}
+	var written int
+	h.PointsWriter.WritePointsFn = func(_, _ string, _ models.ConsistencyLevel, _ meta.User, points []models.Point) error {
+		written = len(points)
+		return tsdb.PartialWriteError{Reason: "points beyond retention policy", Dropped: 1}
+	}
+
+	lines := []string{"cpu v=1 1", "cpu v=2 2", "cpu v=3 3"}
+	for i := 0; i < 12; i++ {
+		lines = append(lines, "cpu v=")
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo", strings.NewReader(strings.Join(lines, "\n"))))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if written != 3 {
+		t.Fatalf("unexpected points written: %d", written)
+	}
+
+	var resp struct {
+		Err     string   `json:"error"`
+		Written int      `json:"written"`
+		Dropped int      `json:"dropped"`
+		Errors  []string `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Written != 2 || resp.Dropped != 13 {
+		t.Fatalf("unexpected counts: written=%d dropped=%d", resp.Written, resp.Dropped)
+	} else if !strings.HasPrefix(resp.Err, "partial write: ") || !strings.HasSuffix(resp.Err, " dropped=13") {
+		t.Fatalf("unexpected error: %s", resp.Err)
+	} else if len(resp.Errors) != 11 {
+		t.Fatalf("unexpected errors: %q", resp.Errors)
+	} else if exp := "and 3 more errors"; resp.Errors[10] != exp {
+		t.Fatalf("unexpected last error: got %q, exp %q", resp.Errors[10], exp)
+	} else if w.Header().Get("X-InfluxDB-Error") == "" {
+		t.Fatal("expected X-InfluxDB-Error header")
+	}
+}
+
+// Ensure the precision of written timestamps accepts the long forms and
+// rejects unknown values.
+func TestHandler_Write_Precision(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{}
+	}
+	var ts int64
+	h.PointsWriter.WritePointsFn = func(_, _ string, _ models.ConsistencyLevel, _ meta.User, points []models.Point) error {
+		ts = points[0].UnixNano()
+		return nil
+	}
+
+	for _, tt := range []struct {
+		precision string
+		code      int
+		ts        int64
+	}{
+		{precision: "", code: http.StatusNoContent, ts: 2},
+		{precision: "ns", code: http.StatusNoContent, ts: 2},
+		{precision: "us", code: http.StatusNoContent, ts: 2000},
+		{precision: "u", code: http.StatusNoContent, ts: 2000},
+		{precision: "s", code: http.StatusNoContent, ts: 2000000000},
+		{precision: "d", code: http.StatusBadRequest},
+	} {
+		ts = 0
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo&precision="+tt.precision, strings.NewReader("cpu v=1 2")))
+		if w.Code != tt.code {
+			t.Errorf("precision %q: unexpected status: got %d, exp %d", tt.precision, w.Code, tt.code)
+		} else if ts != tt.ts {
+			t.Errorf("precision %q: unexpected time: got %d, exp %d", tt.precision, ts, tt.ts)
+		}
+	}
+}
+
+// Ensure a dry run write validates the points without writing them.
+func TestHandler_Write_DryRun(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{}
+	}
+	h.PointsWriter.WritePointsFn = func(_, _ string, _ models.ConsistencyLevel, _ meta.User, _ []models.Point) error {
+		t.Fatal("unexpected write")
+		return nil
+	}
+	h.PointsWriter.ValidatePointsFn = func(db, rp string, points []models.Point) error {
+		if db != "foo" || rp != "bar" {
+			t.Fatalf("unexpected destination: %s.%s", db, rp)
+		}
+		for _, p := range points {
+			if string(p.Name()) == "conflict" {
+				return tsdb.PartialWriteError{Reason: "field type conflict", Dropped: 1}
+			}
+		}
+		return nil
+	}
+
+	for _, tt := range []struct {
+		body string
+		code int
+		err  string
+	}{
+		{body: "cpu v=1", code: http.StatusNoContent},
+		{body: "cpu v=1\nconflict v=1", code: http.StatusBadRequest, err: "field type conflict"},
+		{body: "cpu v=1\ncpu v=", code: http.StatusBadRequest, err: "unable to parse"},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewRequest("POST", "/write?db=foo&rp=bar&dry_run=true", strings.NewReader(tt.body)))
+		if w.Code != tt.code {
+			t.Errorf("%q: unexpected status: got %d, exp %d", tt.body, w.Code, tt.code)
+		} else if !strings.Contains(w.Body.String(), tt.err) {
+			t.Errorf("%q: unexpected body: %s", tt.body, w.Body.String())
+		}
+	}
+}
+
+// Ensure a multi-database write routes each section to its database and
+// writes nothing when a section is invalid.
+func TestHandler_Write_Multi(t *testing.T) {
+	h := NewHandler(false)
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		if name == "missing" {
+			return nil
+		}
+		return &meta.DatabaseInfo{Name: name, RetentionPolicies: []meta.RetentionPolicyInfo{{Name: "rp0"}, {Name: "rp1"}}}
+	}
+	var writes []string
+	h.PointsWriter.WritePointsFn = func(db, rp string, _ models.ConsistencyLevel, _ meta.User, points []models.Point) error {
+		writes = append(writes, fmt.Sprintf("%s.%s:%d", db, rp, len(points)))
+		return nil
+	}
+
+	for _, tt := range []struct {
+		url    string
+		body   string
+		code   int
+		writes []string
+	}{
+		{
+			url:    "/write/multi",
+			body:   "db=db0\ncpu v=1\ncpu v=2\ndb=db1 rp=rp1\nmem v=1\n",
+			code:   http.StatusNoContent,
+			writes: []string{"db0.:2", "db1.rp1:1"},
+		},
+		{
+			url:    "/write/multi?db=db0&rp=rp0",
+			body:   "cpu v=1\ndb=db1\nmem v=1",
+			code:   http.StatusNoContent,
+			writes: []string{"db0.rp0:1", "db1.:1"},
+		},
+		{url: "/write/multi", body: "cpu v=1\n", code: http.StatusBadRequest},
+		{url: "/write/multi", body: "db=db0 foo=bar\ncpu v=1\n", code: http.StatusBadRequest},
+		{
+			url:    "/write/multi?db=db0",
+			body:   "db=cpu v=1\ndb=mem v=2\n",
+			code:   http.StatusNoContent,
+			writes: []string{"db0.:2"},
+		},
+		{url: "/write/multi", body: "db=db0\ncpu v=1\ndb=missing\nmem v=1\n", code: http.StatusNotFound},
+		{url: "/write/multi", body: "db=db0\ncpu v=1\ndb=db1 rp=missing\nmem v=1\n", code: http.StatusNotFound},
+		{url: "/write/multi", body: "db=db0\ncpu v=1\ndb=db1\nmem v=\n", code: http.StatusBadRequest},
+	} {
+		writes = nil
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewRequest("POST", tt.url, strings.NewReader(tt.body)))
+		if w.Code != tt.code {
+			t.Errorf("%q: unexpected status: got %d, exp %d: %s", tt.body, w.Code, tt.code, w.Body.String())
+		} else if !reflect.DeepEqual(writes, tt.writes) {
+			t.Errorf("%q: unexpected writes: got %v, exp %v", tt.body, writes, tt.writes)
+		}
+	}
+}
+
+// Ensure the debug endpoints require an admin user when pprof-auth-enabled is set.
+func TestHandler_Debug_Auth(t *testing.T) {
+	config := httpd.NewConfig()
+	config.AuthEnabled = true
+	config.PprofAuthEnabled = true
+	h := NewHandlerWithConfig(config)
+	h.MetaClient.AdminUserExistsFn = func() bool { return true }
+	h.MetaClient.AuthenticateFn = func(u, p string) (meta.User, error) {
+		if p != "secret" {
+			return nil, meta.ErrAuthenticate
+		}
+		return &meta.UserInfo{Name: u, Admin: u == "admin"}, nil
+	}
+
+	for _, tt := range []struct {
+		user string
+		code int
+	}{
+		{user: "", code: http.StatusUnauthorized},
+		{user: "reader", code: http.StatusForbidden},
+		{user: "admin", code: http.StatusOK},
+	} {
+		req := MustNewRequest("GET", "/debug/pprof/cmdline", nil)
+		if tt.user != "" {
+			req.SetBasicAuth(tt.user, "secret")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("user %q: unexpected status: got %d, exp %d", tt.user, w.Code, tt.code)
+		}
+	}
+}
+
+// Ensure directory users are authenticated before any local admin user exists.
+func TestHandler_Query_DirectoryUserWithoutAdmin(t *testing.T) {
+	h := NewHandler(true)
+	h.MetaClient.AdminUserExistsFn = func() bool { return false }
+	h.Handler.Authenticator = &HandlerAuthenticator{
+		AuthenticateFn: func(u, p string) (meta.User, error) {
+			if p != "secret" {
+				return nil, meta.ErrAuthenticate
+			}
+			return &meta.UserInfo{Name: u}, nil
+		},
+	}
+	var user meta.User
+	h.QueryAuthorizer.AuthorizeQueryFn = func(u meta.User, query *influxql.Query, database string) error {
+		user = u
+		return nil
+	}
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		return nil
+	}
+
+	for _, tt := range []struct {
+		user, password string
+		code           int
+		exp            string
+	}{
+		{user: "bob", password: "secret", code: http.StatusOK, exp: "bob"},
+		{user: "bob", password: "wrong", code: http.StatusUnauthorized},
+		{code: http.StatusOK},
+	} {
+		user = nil
+		r := MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil)
+		if tt.user != "" {
+			r.SetBasicAuth(tt.user, tt.password)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.code {
+			t.Errorf("user %q: unexpected status: got %d, exp %d: %s", tt.user, w.Code, tt.code, w.Body.String())
+		} else if got := user; (got == nil) != (tt.exp == "") || (got != nil && got.ID() != tt.exp) {
+			t.Errorf("user %q: unexpected authorized user: %v", tt.user, got)
+		}
+	}
+}
+
+// Ensure users and their permissions can be managed idempotently over HTTP.
+func TestHandler_Users(t *testing.T) {
+	h := NewHandler(false)
+	users := make(map[string]*meta.UserInfo)
+	h.MetaClient.UsersFn = func() []meta.UserInfo {
+		var a []meta.UserInfo
+		for _, u := range users {
+			a = append(a, *u)
+		}
+		return a
+	}
+	h.MetaClient.UserFn = func(name string) (meta.User, error) {
+		if u := users[name]; u != nil {
+			return u, nil
+		}
+		return nil, meta.ErrUserNotFound
+	}
+	h.MetaClient.CreateUserFn = func(name, password string, admin bool) (meta.User, error) {
+		users[name] = &meta.UserInfo{Name: name, Hash: password, Admin: admin, Privileges: make(map[string]influxql.Privilege)}
+		return users[name], nil
+	}
+	h.MetaClient.AuthenticateFn = func(name, password string) (meta.User, error) {
+		if u := users[name]; u != nil && u.Hash == password {
+			return u, nil
+		}
+		return nil, meta.ErrAuthenticate
+	}
+	h.MetaClient.UpdateUserFn = func(name, password string) error {
+		users[name].Hash = password
+		return nil
+	}
+	h.MetaClient.SetAdminPrivilegeFn = func(name string, admin bool) error {
+		users[name].Admin = admin
+		return nil
+	}
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		if name == "db0" {
+			return &meta.DatabaseInfo{Name: name}
+		}
+		return nil
+	}
+	h.MetaClient.SetPrivilegeFn = func(name, database string, p influxql.Privilege) error {
+		users[name].Privileges[database] = p
+		return nil
+	}
+	h.MetaClient.UserPrivilegesFn = func(name string) (map[string]influxql.Privilege, error) {
+		return users[name].Privileges, nil
+	}
+	h.MetaClient.DropUserFn = func(name string) error {
+		if users[name] == nil {
+			return meta.ErrUserNotFound
+		}
+		delete(users, name)
+		return nil
+	}
+
+	for i, tt := range []struct {
+		method, url, body string
+		code              int
+		resp              string
+	}{
+		{method: "POST", url: "/users", body: `{"name":"bob","password":"pw"}`, code: http.StatusCreated, resp: `{"name":"bob","admin":false}`},
+		{method: "POST", url: "/users", body: `{"name":"bob","password":"pw"}`, code: http.StatusOK, resp: `{"name":"bob","admin":false}`},
+		{method: "POST", url: "/users", body: `{"name":"bob","password":"pw2","admin":true}`, code: http.StatusOK, resp: `{"name":"bob","admin":true}`},
+		{method: "GET", url: "/users", code: http.StatusOK, resp: `{"users":[{"name":"bob","admin":true}]}`},
+		{method: "POST", url: "/users/bob/permissions", body: `{"database":"db0","privilege":"READ"}`, code: http.StatusNoContent},
+		{method: "POST", url: "/users/bob/permissions", body: `{"database":"db1","privilege":"READ"}`, code: http.StatusNotFound},
+		{method: "POST", url: "/users/bob/permissions", body: `{"database":"db0","privilege":"bad"}`, code: http.StatusBadRequest},
+		{method: "GET", url: "/users/bob/permissions", code: http.StatusOK, resp: `{"name":"bob","admin":true,"privileges":{"db0":"READ"}}`},
+		{method: "GET", url: "/users/alice/permissions", code: http.StatusNotFound},
+		{method: "DELETE", url: "/users/bob", code: http.StatusNoContent},
+		{method: "DELETE", url: "/users/bob", code: http.StatusNoContent},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewRequest(tt.method, tt.url, strings.NewReader(tt.body)))
+		if w.Code != tt.code {
+			t.Fatalf("%d. %s %s: unexpected status: got %d, exp %d: %s", i, tt.method, tt.url, w.Code, tt.code, w.Body.String())
+		} else if tt.resp != "" && w.Body.String() != tt.resp {
+			t.Fatalf("%d. %s %s: unexpected body: %s", i, tt.method, tt.url, w.Body.String())
+		}
+	}
+	if users["bob"] != nil {
+		t.Fatal("expected user to be dropped")
+	}
+}
+
+// Ensure only admin users can manage users when authentication is enabled.
+func TestHandler_Users_Auth(t *testing.T) {
+	h := NewHandler(true)
+	h.MetaClient.AdminUserExistsFn = func() bool { return true }
+	h.MetaClient.AuthenticateFn = func(u, p string) (meta.User, error) {
+		return &meta.UserInfo{Name: u, Admin: u == "admin"}, nil
+	}
+	h.MetaClient.UsersFn = func() []meta.UserInfo { return nil }
+
+	for _, tt := range []struct {
+		user string
+		code int
+	}{
+		{user: "reader", code: http.StatusForbidden},
+		{user: "admin", code: http.StatusOK},
+	} {
+		req := MustNewRequest("GET", "/users", nil)
+		req.SetBasicAuth(tt.user, "secret")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("user %q: unexpected status: got %d, exp %d", tt.user, w.Code, tt.code)
+		}
+	}
+
+	// Without any user, only a new admin user may be created. Existing users
+	// cannot be taken over to become the first admin, and no admin can be
+	// created once a user exists, even if none of them is an admin.
+	var users []string
+	h.MetaClient.AdminUserExistsFn = func() bool { return false }
+	h.MetaClient.UserCountFn = func() int { return len(users) }
+	h.MetaClient.UserFn = func(name string) (meta.User, error) {
+		for _, u := range users {
+			if u == name {
+				return &meta.UserInfo{Name: name}, nil
+			}
+		}
+		return nil, meta.ErrUserNotFound
+	}
+	h.MetaClient.CreateUserFn = func(name, password string, admin bool) (meta.User, error) {
+		return &meta.UserInfo{Name: name, Admin: admin}, nil
+	}
+	h.MetaClient.UpdateUserFn = func(name, password string) error {
+		t.Fatal("unexpected password change")
+		return nil
+	}
+	h.MetaClient.SetAdminPrivilegeFn = func(name string, admin bool) error {
+		t.Fatal("unexpected admin change")
+		return nil
+	}
+
+	for _, tt := range []struct {
+		users []string
+		body  string
+		code  int
+	}{
+		{users: []string{"bob"}, body: `{"name":"bob","password":"x","admin":true}`, code: http.StatusForbidden},
+		{users: []string{"bob"}, body: `{"name":"alice","password":"x","admin":true}`, code: http.StatusForbidden},
+		{body: `{"name":"alice","password":"x"}`, code: http.StatusForbidden},
+		{body: `{"name":"alice","password":"x","admin":true}`, code: http.StatusCreated},
+	} {
+		users = tt.users
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, MustNewRequest("POST", "/users", strings.NewReader(tt.body)))
+		if w.Code != tt.code {
+			t.Errorf("%s: unexpected status: got %d, exp %d", tt.body, w.Code, tt.code)
+		}
+	}
+}
+
+// Ensure API tokens can only write to their database and retention policy.
+func TestHandler_Token_Write(t *testing.T) {
+	h := NewHandler(true)
+	h.MetaClient.AdminUserExistsFn = func() bool { return true }
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{}
+	}
+	h.MetaClient.AuthenticateTokenFn = func(token string) (meta.User, error) {
+		if token != "id.secret" {
+			return nil, meta.ErrAuthenticate
+		}
+		return &meta.TokenUser{TokenInfo: meta.TokenInfo{
+			ID:              "id",
+			Database:        "db0",
+			RetentionPolicy: "rp0",
+			Privilege:       influxql.WritePrivilege,
+		}}, nil
+	}
+	h.PointsWriter.WritePointsFn = func(_, _ string, _ models.ConsistencyLevel, _ meta.User, _ []models.Point) error {
+		return nil
+	}
+
+	for _, tt := range []struct {
+		url   string
+		token string
+		code  int
+	}{
+		{url: "/write?db=db0&rp=rp0", token: "id.secret", code: http.StatusNoContent},
+		{url: "/write?db=db0", token: "id.secret", code: http.StatusForbidden},
+		{url: "/write?db=db1&rp=rp0", token: "id.secret", code: http.StatusForbidden},
+		{url: "/write?db=db0&rp=rp0", token: "id.wrong", code: http.StatusUnauthorized},
+	} {
+		req := MustNewRequest("POST", tt.url, strings.NewReader("cpu v=1"))
+		req.Header.Set("Authorization", "Token "+tt.token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("%s with %q: unexpected status: got %d, exp %d", tt.url, tt.token, w.Code, tt.code)
+		}
+	}
+}
+
+// Ensure the access log can be written as JSON and can leave out writes.
+func TestHandler_AccessLog(t *testing.T) {
+	config := httpd.NewConfig()
+	config.AccessLogFormat = httpd.AccessLogFormatJSON
+	config.SuppressWriteLog = true
+	h := NewHandlerWithConfig(config)
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{}
+	}
+	h.PointsWriter.WritePointsFn = func(_, _ string, _ models.ConsistencyLevel, _ meta.User, _ []models.Point) error {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	h.CLFLogger = log.New(&buf, "", 0)
+
+	h.ServeHTTP(httptest.NewRecorder(), MustNewRequest("POST", "/write?db=foo", strings.NewReader("cpu v=1")))
+	if buf.Len() != 0 {
+		t.Fatalf("unexpected access log for write: %s", buf.String())
+	}
+
+	h.ServeHTTP(httptest.NewRecorder(), MustNewRequest("GET", "/ping", nil))
+	var line struct {
+		Method string `json:"method"`
+		URI    string `json:"uri"`
+		Status int    `json:"status"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("unexpected access log line %q: %s", buf.String(), err)
+	} else if line.Method != "GET" || line.URI != "/ping" || line.Status != http.StatusNoContent {
+		t.Fatalf("unexpected access log line: %s", buf.String())
+	}
+}
+
+// Ensure queries over the concurrent query limit are rejected.
+func TestHandler_Query_ConcurrentQuota(t *testing.T) {
+	config := httpd.NewConfig()
+	config.DatabaseMaxConcurrentQueries = 1
+	h := NewHandlerWithConfig(config)
+
+	started, unblock := make(chan struct{}), make(chan struct{})
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		close(started)
+		<-unblock
+		ctx.Results <- &query.Result{StatementID: 0}
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(httptest.NewRecorder(), MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil))
+	}()
+	<-started
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil))
+	close(unblock)
+	<-done
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("unexpected status: %d", w.Code)
+	} else if w.Header().Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After header")
+	}
+}
+
+// Ensure queries and writes are recorded in the audit log.
+func TestHandler_AuditLog(t *testing.T) {
+	h := NewHandler(false)
+	var events []audit.Event
+	h.AuditLog = AuditLogFunc(func(e audit.Event) { events = append(events, e) })
+
+	h.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{}
+	}
+	h.PointsWriter.WritePointsFn = func(_, _ string, _ models.ConsistencyLevel, _ meta.User, _ []models.Point) error {
+		return nil
+	}
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		return errors.New("user exists")
+	}
+	h.MetaClient.CreateTokenFn = func(database, retentionPolicy string, p influxql.Privilege) (*meta.TokenInfo, string, error) {
+		return &meta.TokenInfo{ID: "t1", Database: database, RetentionPolicy: retentionPolicy, Privilege: p}, "t1.secret", nil
+	}
+	h.MetaClient.DropTokenFn = func(id string) error {
+		return nil
+	}
+
+	h.ServeHTTP(httptest.NewRecorder(), MustNewRequest("POST", "/write?db=foo&rp=bar", strings.NewReader("cpu v=1\ncpu v=2")))
+	h.ServeHTTP(httptest.NewRecorder(), MustNewJSONRequest("POST", "/query?q=CREATE+USER+alice+WITH+PASSWORD+'secret'", nil))
+	h.ServeHTTP(httptest.NewRecorder(), MustNewJSONRequest("POST", "/tokens", strings.NewReader(`{"database":"foo","privilege":"read"}`)))
+	h.ServeHTTP(httptest.NewRecorder(), MustNewRequest("DELETE", "/tokens/t1", nil))
+
+	if len(events) != 4 {
+		t.Fatalf("unexpected events: %#v", events)
+	}
+	if e := events[0]; e.Action != audit.ActionWrite || e.Database != "foo" || e.RetentionPolicy != "bar" || e.Points != 2 || e.Status != http.StatusNoContent {
+		t.Fatalf("unexpected write event: %#v", e)
+	}
+	if e := events[1]; e.Action != audit.ActionAdmin || strings.Contains(e.Query, "secret") || e.Error != "user exists" || e.Status != http.StatusOK {
+		t.Fatalf("unexpected query event: %#v", e)
+	}
+	if e := events[2]; e.Action != audit.ActionAdmin || e.Database != "foo" || e.Status != http.StatusCreated {
+		t.Fatalf("unexpected token creation event: %#v", e)
+	}
+	if e := events[3]; e.Action != audit.ActionAdmin || e.Status != http.StatusNoContent {
+		t.Fatalf("unexpected token drop event: %#v", e)
+	}
+}
+
+// AuditLogFunc is an audit log calling a function for each event.
+type AuditLogFunc func(e audit.Event)
+
+func (fn AuditLogFunc) Log(e audit.Event) { fn(e) }
+
 // Ensure X-Forwarded-For header writes the correct log message.
 func TestHandler_XForwardedFor(t *testing.T) {
 	var buf bytes.Buffer
@@ -900,6 +1790,26 @@ func TestHandler_XRequestId(t *testing.T) {
 	}
 }
 
+// Ensure the request ID is passed to the query executor.
+func TestHandler_XRequestId_Query(t *testing.T) {
+	h := NewHandler(false)
+	h.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		if ctx.RequestID != "abc123" {
+			t.Fatalf("unexpected request id: %q", ctx.RequestID)
+		}
+		ctx.Results <- &query.Result{StatementID: 0}
+		return nil
+	}
+
+	req := MustNewJSONRequest("GET", "/query?db=foo&q=SELECT+*+FROM+bar", nil)
+	req.Header.Set("X-Request-Id", "abc123")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", w.Code)
+	}
+}
+
 // NewHandler represents a test wrapper for httpd.Handler.
 type Handler struct {
 	*httpd.Handler
@@ -914,7 +1824,11 @@ func NewHandler(requireAuthentication bool) *Handler {
 	config := httpd.NewConfig()
 	config.AuthEnabled = requireAuthentication
 	config.SharedSecret = "super secret key"
+	return NewHandlerWithConfig(config)
+}
 
+// NewHandlerWithConfig returns a new instance of Handler using config.
+func NewHandlerWithConfig(config httpd.Config) *Handler {
 	h := &Handler{
 		Handler: httpd.NewHandler(config),
 	}
@@ -931,6 +1845,20 @@ func NewHandler(requireAuthentication bool) *Handler {
 	return h
 }
 
+// HandlerMonitor is a mock implementation of Handler.Monitor.
+type HandlerMonitor struct {
+	StatisticsFn  func(tags map[string]string) ([]*monitor.Statistic, error)
+	DiagnosticsFn func() (map[string]*diagnostics.Diagnostics, error)
+}
+
+func (m *HandlerMonitor) Statistics(tags map[string]string) ([]*monitor.Statistic, error) {
+	return m.StatisticsFn(tags)
+}
+
+func (m *HandlerMonitor) Diagnostics() (map[string]*diagnostics.Diagnostics, error) {
+	return m.DiagnosticsFn()
+}
+
 // HandlerStatementExecutor is a mock implementation of Handler.StatementExecutor.
 type HandlerStatementExecutor struct {
 	ExecuteStatementFn func(stmt influxql.Statement, ctx query.ExecutionContext) error
@@ -949,14 +1877,28 @@ func (a *HandlerQueryAuthorizer) AuthorizeQuery(u meta.User, query *influxql.Que
 	return a.AuthorizeQueryFn(u, query, database)
 }
 
+// HandlerAuthenticator is a mock implementation of Handler.Authenticator.
+type HandlerAuthenticator struct {
+	AuthenticateFn func(username, password string) (meta.User, error)
+}
+
+func (a *HandlerAuthenticator) Authenticate(username, password string) (meta.User, error) {
+	return a.AuthenticateFn(username, password)
+}
+
 type HandlerPointsWriter struct {
-	WritePointsFn func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error
+	WritePointsFn    func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error
+	ValidatePointsFn func(database, retentionPolicy string, points []models.Point) error
 }
 
-func (h *HandlerPointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error {
+func (h *HandlerPointsWriter) WritePointsWithContext(ctx context.Context, database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error {
 	return h.WritePointsFn(database, retentionPolicy, consistencyLevel, user, points)
 }
 
+func (h *HandlerPointsWriter) ValidatePoints(database, retentionPolicy string, points []models.Point) error {
+	return h.ValidatePointsFn(database, retentionPolicy, points)
+}
+
 // MustNewRequest returns a new HTTP request. Panic on error.
 func MustNewRequest(method, urlStr string, body io.Reader) *http.Request {
 	r, err := http.NewRequest(method, urlStr, body)
@@ -0,0 +1,51 @@
+package httpd
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// HealthCheck reports whether one part of the server is ready to serve
+// requests.
+type HealthCheck struct {
+	Name  string
+	Check func() error
+}
+
+type healthCheckResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// serveHealth reports whether the server is ready to serve requests. Unlike
+// /ping, which only says the process is up, it fails with 503 Service
+// Unavailable until every health check passes.
+func (h *Handler) serveHealth(w http.ResponseWriter, r *http.Request) {
+	resp := struct {
+		Name    string              `json:"name"`
+		Status  string              `json:"status"`
+		Version string              `json:"version"`
+		Checks  []healthCheckResult `json:"checks"`
+	}{
+		Name:    "influxdb",
+		Status:  "pass",
+		Version: h.Version,
+		Checks:  make([]healthCheckResult, 0, len(h.HealthChecks)),
+	}
+
+	code := http.StatusOK
+	for _, c := range h.HealthChecks {
+		result := healthCheckResult{Name: c.Name, Status: "pass"}
+		if err := c.Check(); err != nil {
+			result.Status, result.Message = "fail", err.Error()
+			resp.Status, code = "fail", http.StatusServiceUnavailable
+		}
+		resp.Checks = append(resp.Checks, result)
+	}
+
+	b, _ := json.Marshal(resp)
+	w.Header().Set("Content-Type", "application/json")
+	h.writeHeader(w, code)
+	w.Write(b)
+}
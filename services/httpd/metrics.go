@@ -0,0 +1,188 @@
+package httpd
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/influxdata/influxdb/monitor"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// metricsNamespace prefixes the names of the metrics served on /metrics.
+const metricsNamespace = "influxdb"
+
+// metrics holds the metrics kept for /metrics that have no counterpart in
+// the internal statistics.
+type metrics struct {
+	queryDurations prometheus.Histogram
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		queryDurations: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "httpd",
+			Name:      "query_duration_seconds",
+			Help:      "Time taken to serve query requests.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+	}
+}
+
+// observeQuery records the duration of a query request started at start.
+func (m *metrics) observeQuery(start time.Time) {
+	m.queryDurations.Observe(time.Since(start).Seconds())
+}
+
+// families returns the metric families for the metrics in m.
+func (m *metrics) families() ([]*dto.MetricFamily, error) {
+	var metric dto.Metric
+	if err := m.queryDurations.Write(&metric); err != nil {
+		return nil, err
+	}
+
+	name := prometheus.BuildFQName(metricsNamespace, "httpd", "query_duration_seconds")
+	help := "Time taken to serve query requests."
+	typ := dto.MetricType_HISTOGRAM
+	return []*dto.MetricFamily{{
+		Name:   &name,
+		Help:   &help,
+		Type:   &typ,
+		Metric: []*dto.Metric{&metric},
+	}}, nil
+}
+
+// serveMetrics serves the Go runtime metrics and the internal statistics in
+// the Prometheus exposition format.
+func (h *Handler) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if h.Monitor != nil {
+		stats, err := h.Monitor.Statistics(nil)
+		if err != nil {
+			h.httpError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		families = append(families, statisticFamilies(stats)...)
+	}
+
+	a, err := h.metrics.families()
+	if err != nil {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	families = append(families, a...)
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].GetName() < families[j].GetName()
+	})
+
+	format := expfmt.Negotiate(r.Header)
+	w.Header().Set("Content-Type", string(format))
+	enc := expfmt.NewEncoder(w, format)
+	for _, f := range families {
+		if err := enc.Encode(f); err != nil {
+			h.Logger.Info(fmt.Sprintf("Failed to encode metrics: %s", err))
+			return
+		}
+	}
+}
+
+// statisticFamilies converts statistics to metric families. Each value of a
+// statistic becomes an untyped metric named after the statistic and the
+// value, such as influxdb_httpd_points_written_ok, labelled with the
+// statistic's tags. Values that are not numbers are skipped.
+func statisticFamilies(stats []*monitor.Statistic) []*dto.MetricFamily {
+	var families []*dto.MetricFamily
+	byName := make(map[string]*dto.MetricFamily)
+	for _, s := range stats {
+		labels := make([]*dto.LabelPair, 0, len(s.Tags))
+		for k, v := range s.Tags {
+			k, v := metricName(k), v
+			labels = append(labels, &dto.LabelPair{Name: &k, Value: &v})
+		}
+		sort.Slice(labels, func(i, j int) bool {
+			return labels[i].GetName() < labels[j].GetName()
+		})
+
+		for k, v := range s.Values {
+			value, ok := metricValue(v)
+			if !ok {
+				continue
+			}
+
+			name := metricsNamespace + "_" + metricName(s.Name) + "_" + metricName(k)
+			family := byName[name]
+			if family == nil {
+				typ := dto.MetricType_UNTYPED
+				family = &dto.MetricFamily{Name: &name, Type: &typ}
+				byName[name] = family
+				families = append(families, family)
+			}
+			family.Metric = append(family.Metric, &dto.Metric{
+				Label:   labels,
+				Untyped: &dto.Untyped{Value: &value},
+			})
+		}
+	}
+	return families
+}
+
+// metricValue returns v as a float64, or false if v is not a number.
+func metricValue(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// metricName converts a statistic or tag name such as "pointsWrittenOK" to
+// a valid Prometheus name in snake case, "points_written_ok".
+func metricName(s string) string {
+	isUpper := func(c byte) bool { return c >= 'A' && c <= 'Z' }
+	isLower := func(c byte) bool { return c >= 'a' && c <= 'z' }
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case isUpper(c):
+			if i > 0 && (isLower(s[i-1]) || isDigit(s[i-1]) ||
+				(isUpper(s[i-1]) && i+1 < len(s) && isLower(s[i+1]))) {
+				buf.WriteByte('_')
+			}
+			buf.WriteByte(c + 'a' - 'A')
+		case isLower(c), c == '_':
+			buf.WriteByte(c)
+		case isDigit(c):
+			if i == 0 {
+				buf.WriteByte('_')
+			}
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte('_')
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,21 @@
+package httpd
+
+import "testing"
+
+func TestMetricName(t *testing.T) {
+	for _, tt := range []struct {
+		s, exp string
+	}{
+		{s: "req", exp: "req"},
+		{s: "pointsWrittenOK", exp: "points_written_ok"},
+		{s: "reqDurationNs", exp: "req_duration_ns"},
+		{s: "HTTPRequests", exp: "http_requests"},
+		{s: "tsm1_filestore", exp: "tsm1_filestore"},
+		{s: "write-req", exp: "write_req"},
+		{s: "2xx", exp: "_2xx"},
+	} {
+		if got := metricName(tt.s); got != tt.exp {
+			t.Errorf("metricName(%q) = %q, exp %q", tt.s, got, tt.exp)
+		}
+	}
+}
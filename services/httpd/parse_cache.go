@@ -0,0 +1,98 @@
+package httpd
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/influxdata/influxql"
+)
+
+// parseCache keeps the most recently used parsed queries, so dashboards
+// sending the same queries again and again are not parsed each time.
+//
+// Statements are normalized in place when they are executed, so a query is
+// stored and returned as a copy. Only queries made of SELECT statements are
+// kept, since they are the ones dashboards repeat and the ones that can be
+// copied.
+type parseCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[parseCacheKey]*list.Element
+	lru     *list.List
+}
+
+// parseCacheKey identifies a parsed query by its text and the JSON of its
+// bind parameters.
+type parseCacheKey struct {
+	query  string
+	params string
+}
+
+type parseCacheEntry struct {
+	key   parseCacheKey
+	query *influxql.Query
+}
+
+// newParseCache returns a cache of at most size queries.
+func newParseCache(size int) *parseCache {
+	return &parseCache{
+		size:    size,
+		entries: make(map[parseCacheKey]*list.Element, size),
+		lru:     list.New(),
+	}
+}
+
+// get returns a copy of the query parsed from query and params, or nil if it
+// is not in the cache.
+func (c *parseCache) get(query, params string) *influxql.Query {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := c.entries[parseCacheKey{query: query, params: params}]
+	if e == nil {
+		return nil
+	}
+	c.lru.MoveToFront(e)
+	return cloneQuery(e.Value.(*parseCacheEntry).query)
+}
+
+// add stores a copy of q, the query parsed from query and params, evicting
+// the least recently used query if the cache is full. It returns false if q
+// cannot be cached.
+func (c *parseCache) add(query, params string, q *influxql.Query) bool {
+	q = cloneQuery(q)
+	if q == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := parseCacheKey{query: query, params: params}
+	if e := c.entries[key]; e != nil {
+		c.lru.MoveToFront(e)
+		return true
+	}
+	c.entries[key] = c.lru.PushFront(&parseCacheEntry{key: key, query: q})
+
+	for c.lru.Len() > c.size {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.entries, e.Value.(*parseCacheEntry).key)
+	}
+	return true
+}
+
+// cloneQuery returns a deep copy of q, or nil if q has statements other than
+// SELECT.
+func cloneQuery(q *influxql.Query) *influxql.Query {
+	other := &influxql.Query{Statements: make(influxql.Statements, len(q.Statements))}
+	for i, stmt := range q.Statements {
+		s, ok := stmt.(*influxql.SelectStatement)
+		if !ok {
+			return nil
+		}
+		other.Statements[i] = s.Clone()
+	}
+	return other
+}
@@ -0,0 +1,302 @@
+package httpd
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// quotaPruneInterval is how often idle rate limiter entries are removed.
+const quotaPruneInterval = time.Minute
+
+// quotas enforces the per-user and per-database request quotas. A nil
+// limiter imposes no limit.
+type quotas struct {
+	userPoints     *rateLimiter
+	userQueries    *rateLimiter
+	userConcurrent *concurrencyLimiter
+	dbPoints       *rateLimiter
+	dbQueries      *rateLimiter
+	dbConcurrent   *concurrencyLimiter
+}
+
+// newQuotas returns the quotas configured in c.
+func newQuotas(c *Config) *quotas {
+	return &quotas{
+		userPoints:     newRateLimiter(c.UserWritePointsPerSecond),
+		userQueries:    newRateLimiter(c.UserQueriesPerSecond),
+		userConcurrent: newConcurrencyLimiter(c.UserMaxConcurrentQueries),
+		dbPoints:       newRateLimiter(c.DatabaseWritePointsPerSecond),
+		dbQueries:      newRateLimiter(c.DatabaseQueriesPerSecond),
+		dbConcurrent:   newConcurrencyLimiter(c.DatabaseMaxConcurrentQueries),
+	}
+}
+
+// quotaError is returned when a request exceeds a quota.
+type quotaError struct {
+	msg        string
+	retryAfter time.Duration
+}
+
+func (e *quotaError) Error() string { return e.msg }
+
+// write checks that n points may be written to database by user. Every
+// quota is checked before any is charged, so a rejected write costs nothing.
+func (q *quotas) write(user meta.User, database string, n int) error {
+	return q.writeMulti(user, map[string]int{database: n})
+}
+
+// writeMulti checks that user may write points[db] points to each database
+// db in one request. As with write, nothing is charged unless every database
+// admits its points.
+func (q *quotas) writeMulti(user meta.User, points map[string]int) error {
+	databases := make([]string, 0, len(points))
+	total := 0
+	for db, n := range points {
+		databases = append(databases, db)
+		total += n
+	}
+	sort.Strings(databases)
+
+	if user != nil {
+		if d := q.userPoints.wait(user.ID()); d > 0 {
+			return &quotaError{msg: fmt.Sprintf("user %q exceeded write quota", user.ID()), retryAfter: d}
+		}
+	}
+	for _, db := range databases {
+		if d := q.dbPoints.wait(db); d > 0 {
+			return &quotaError{msg: fmt.Sprintf("database %q exceeded write quota", db), retryAfter: d}
+		}
+	}
+
+	if user != nil {
+		q.userPoints.take(user.ID(), total)
+	}
+	for _, db := range databases {
+		q.dbPoints.take(db, points[db])
+	}
+	return nil
+}
+
+// query checks that user may start a query against database. The returned
+// function must be called once the query finishes. As with writes, the rate
+// quotas are only charged once the query is admitted.
+func (q *quotas) query(user meta.User, database string) (release func(), err error) {
+	var username string
+	if user != nil {
+		username = user.ID()
+		if d := q.userQueries.wait(username); d > 0 {
+			return nil, &quotaError{msg: fmt.Sprintf("user %q exceeded query quota", username), retryAfter: d}
+		}
+	}
+	if database != "" {
+		if d := q.dbQueries.wait(database); d > 0 {
+			return nil, &quotaError{msg: fmt.Sprintf("database %q exceeded query quota", database), retryAfter: d}
+		}
+	}
+
+	// Concurrent queries are not rate based, so clients should back off for
+	// about as long as a query usually takes.
+	if user != nil && !q.userConcurrent.acquire(username) {
+		return nil, &quotaError{msg: fmt.Sprintf("user %q exceeded concurrent query limit", username), retryAfter: time.Second}
+	}
+	if database != "" && !q.dbConcurrent.acquire(database) {
+		if user != nil {
+			q.userConcurrent.release(username)
+		}
+		return nil, &quotaError{msg: fmt.Sprintf("database %q exceeded concurrent query limit", database), retryAfter: time.Second}
+	}
+
+	if user != nil {
+		q.userQueries.take(username, 1)
+	}
+	if database != "" {
+		q.dbQueries.take(database, 1)
+	}
+
+	return func() {
+		if user != nil {
+			q.userConcurrent.release(username)
+		}
+		if database != "" {
+			q.dbConcurrent.release(database)
+		}
+	}, nil
+}
+
+// WriteQuota checks that n points may be written to database by user through
+// another API of the server, such as the RPC API, and charges them to the
+// quotas of HTTP writes.
+func (h *Handler) WriteQuota(user meta.User, database string, n int) error {
+	if err := h.quotas.write(user, database, n); err != nil {
+		atomic.AddInt64(&h.stats.QuotaRejections, 1)
+		return err
+	}
+	return nil
+}
+
+// QueryQuota checks that user may start a query against database through
+// another API of the server, against the quotas of HTTP queries. The returned
+// function must be called once the query finishes.
+func (h *Handler) QueryQuota(user meta.User, database string) (release func(), err error) {
+	if release, err = h.quotas.query(user, database); err != nil {
+		atomic.AddInt64(&h.stats.QuotaRejections, 1)
+		return nil, err
+	}
+	return release, nil
+}
+
+// rateLimiter is a set of token buckets, one per key, that refill at a fixed
+// rate and hold at most one second worth of tokens.
+type rateLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	buckets   map[string]*bucket
+	lastPrune time.Time
+
+	now func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter allowing rate tokens per second, or nil if
+// rate is not positive.
+func newRateLimiter(rate int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:    float64(rate),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// wait returns how long to wait before a request for key is allowed, or 0 if
+// it is allowed now. A request is allowed as long as a token is available,
+// so a batch larger than the bucket is accepted and paid for by the requests
+// that follow.
+func (l *rateLimiter) wait(key string) time.Duration {
+	if l == nil {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(key)
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	return 0
+}
+
+// take removes n tokens from the bucket for key, once wait has allowed the
+// request.
+func (l *rateLimiter) take(key string, n int) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.bucket(key).tokens -= float64(n)
+}
+
+// bucket returns the bucket for key, refilled up to now. The caller must hold
+// l.mu.
+func (l *rateLimiter) bucket(key string) *bucket {
+	now := l.now()
+	if now.Sub(l.lastPrune) >= quotaPruneInterval {
+		l.prune(now)
+	}
+
+	b := l.buckets[key]
+	if b == nil {
+		b = &bucket{tokens: l.rate, last: now}
+		l.buckets[key] = b
+	} else {
+		b.tokens = math.Min(l.rate, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+		b.last = now
+	}
+	return b
+}
+
+// prune removes the buckets that have refilled, which are the same as new
+// buckets. The caller must hold l.mu.
+func (l *rateLimiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.rate {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastPrune = now
+}
+
+// concurrencyLimiter limits the number of operations running at once per key.
+type concurrencyLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	running map[string]int
+}
+
+// newConcurrencyLimiter returns a limiter allowing limit operations per key,
+// or nil if limit is not positive.
+func newConcurrencyLimiter(limit int) *concurrencyLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &concurrencyLimiter{limit: limit, running: make(map[string]int)}
+}
+
+// acquire returns true if another operation may start for key.
+func (l *concurrencyLimiter) acquire(key string) bool {
+	if l == nil {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.running[key] >= l.limit {
+		return false
+	}
+	l.running[key]++
+	return true
+}
+
+// release marks an operation for key as finished.
+func (l *concurrencyLimiter) release(key string) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.running[key] <= 1 {
+		delete(l.running, key)
+		return
+	}
+	l.running[key]--
+}
+
+// quotaExceeded responds with 429 Too Many Requests and a Retry-After hint
+// in whole seconds.
+func (h *Handler) quotaExceeded(w http.ResponseWriter, err error) {
+	atomic.AddInt64(&h.stats.QuotaRejections, 1)
+	secs := 1
+	if qerr, ok := err.(*quotaError); ok && qerr.retryAfter > time.Second {
+		secs = int(math.Ceil(qerr.retryAfter.Seconds()))
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	h.httpError(w, err.Error(), http.StatusTooManyRequests)
+}
@@ -0,0 +1,79 @@
+package httpd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// Ensure a request rejected by one quota is not charged to the others.
+func TestQuotas_ChargeOnAdmit(t *testing.T) {
+	q := newQuotas(&Config{
+		UserWritePointsPerSecond:     10,
+		DatabaseWritePointsPerSecond: 1,
+		UserQueriesPerSecond:         5,
+		DatabaseMaxConcurrentQueries: 1,
+	})
+	now := time.Unix(0, 0)
+	for _, l := range []*rateLimiter{q.userPoints, q.dbPoints, q.userQueries} {
+		l.now = func() time.Time { return now }
+	}
+	user := &meta.UserInfo{Name: "alice"}
+
+	if err := q.write(user, "db0", 1); err != nil {
+		t.Fatal(err)
+	} else if err := q.write(user, "db0", 1); err == nil {
+		t.Fatal("expected database write quota error")
+	} else if got := q.userPoints.buckets["alice"].tokens; got != 9 {
+		t.Fatalf("unexpected user write tokens: %v", got)
+	}
+
+	release, err := q.query(user, "db0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.query(user, "db0"); err == nil {
+		t.Fatal("expected concurrent query limit error")
+	} else if got := q.userQueries.buckets["alice"].tokens; got != 4 {
+		t.Fatalf("unexpected user query tokens: %v", got)
+	}
+	release()
+
+	if release, err := q.query(user, "db0"); err != nil {
+		t.Fatal(err)
+	} else {
+		release()
+	}
+}
+
+// Ensure a multi-database write is not charged to any database unless every
+// one of them admits its points.
+func TestQuotas_WriteMulti(t *testing.T) {
+	q := newQuotas(&Config{
+		UserWritePointsPerSecond:     10,
+		DatabaseWritePointsPerSecond: 1,
+	})
+	now := time.Unix(0, 0)
+	for _, l := range []*rateLimiter{q.userPoints, q.dbPoints} {
+		l.now = func() time.Time { return now }
+	}
+	user := &meta.UserInfo{Name: "alice"}
+
+	if err := q.write(user, "db1", 1); err != nil {
+		t.Fatal(err)
+	} else if err := q.writeMulti(user, map[string]int{"db0": 1, "db1": 1}); err == nil {
+		t.Fatal("expected database write quota error")
+	} else if got := q.dbPoints.buckets["db0"].tokens; got != 1 {
+		t.Fatalf("unexpected db0 write tokens: %v", got)
+	} else if got := q.userPoints.buckets["alice"].tokens; got != 9 {
+		t.Fatalf("unexpected user write tokens: %v", got)
+	}
+
+	now = now.Add(time.Second)
+	if err := q.writeMulti(user, map[string]int{"db0": 1, "db1": 1}); err != nil {
+		t.Fatal(err)
+	} else if got := q.userPoints.buckets["alice"].tokens; got != 8 {
+		t.Fatalf("unexpected user write tokens: %v", got)
+	}
+}
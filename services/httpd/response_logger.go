@@ -1,6 +1,7 @@
 package httpd
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
@@ -118,6 +119,54 @@ func buildLogLine(l *responseLogger, r *http.Request, start time.Time) string {
 		int64(time.Since(start)/time.Microsecond))
 }
 
+// jsonLogLine is an access log entry in the JSON format.
+type jsonLogLine struct {
+	Time      time.Time `json:"time"`
+	Host      string    `json:"host"`
+	User      string    `json:"user,omitempty"`
+	Method    string    `json:"method"`
+	URI       string    `json:"uri"`
+	Proto     string    `json:"proto"`
+	Status    int       `json:"status"`
+	Size      int       `json:"size"`
+	Referer   string    `json:"referer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	Duration  int64     `json:"duration_us"`
+}
+
+// buildJSONLogLine returns the same fields as buildLogLine encoded as JSON.
+func buildJSONLogLine(l *responseLogger, r *http.Request, start time.Time) string {
+	redactPassword(r)
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if xff := r.Header["X-Forwarded-For"]; xff != nil {
+		host = strings.Join(append(xff, host), ",")
+	}
+
+	b, err := json.Marshal(jsonLogLine{
+		Time:      start,
+		Host:      host,
+		User:      parseUsername(r),
+		Method:    r.Method,
+		URI:       r.URL.RequestURI(),
+		Proto:     r.Proto,
+		Status:    l.Status(),
+		Size:      l.Size(),
+		Referer:   r.Referer(),
+		UserAgent: r.UserAgent(),
+		RequestID: r.Header.Get("Request-Id"),
+		Duration:  int64(time.Since(start) / time.Microsecond),
+	})
+	if err != nil {
+		return buildLogLine(l, r, start)
+	}
+	return string(b)
+}
+
 // detect detects the first presence of a non blank string and returns it
 func detect(values ...string) string {
 	for _, v := range values {
@@ -32,6 +32,9 @@ func NewResponseWriter(w http.ResponseWriter, r *http.Request) ResponseWriter {
 	case "application/x-msgpack":
 		w.Header().Add("Content-Type", "application/x-msgpack")
 		rw.formatter = &msgpackFormatter{Writer: w}
+	case ArrowStreamContentType:
+		w.Header().Add("Content-Type", ArrowStreamContentType)
+		rw.formatter = &arrowFormatter{Writer: w}
 	case "application/json":
 		fallthrough
 	default:
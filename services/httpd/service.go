@@ -4,6 +4,7 @@ package httpd // import "github.com/influxdata/influxdb/services/httpd"
 import (
 	"crypto/tls"
 	"fmt"
+	"log"
 	"net"
 	"net/http"
 	"os"
@@ -14,7 +15,9 @@ import (
 	"time"
 
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/systemd"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 // statistics gathered by the httpd package.
@@ -29,6 +32,7 @@ const (
 	statPointsWrittenOK              = "pointsWrittenOK"      // Number of points written OK.
 	statPointsWrittenDropped         = "pointsWrittenDropped" // Number of points dropped by the storage engine.
 	statPointsWrittenFail            = "pointsWrittenFail"    // Number of points that failed to be written.
+	statPointsParseFail              = "pointsParseFail"      // Number of lines of write requests that failed to parse.
 	statAuthFail                     = "authFail"             // Number of authentication failures.
 	statRequestDuration              = "reqDurationNs"        // Number of (wall-time) nanoseconds spent inside requests.
 	statQueryRequestDuration         = "queryReqDurationNs"   // Number of (wall-time) nanoseconds spent inside query requests.
@@ -38,6 +42,9 @@ const (
 	statClientError                  = "clientError"          // Number of HTTP responses due to client error.
 	statServerError                  = "serverError"          // Number of HTTP responses due to server error.
 	statRecoveredPanics              = "recoveredPanics"      // Number of panics recovered by HTTP handler.
+	statQuotaRejections              = "quotaRejections"      // Number of requests rejected for exceeding a quota.
+	statParseCacheHits               = "parseCacheHits"       // Number of queries found in the parse cache.
+	statParseCacheMisses             = "parseCacheMisses"     // Number of queries parsed and added to the parse cache.
 
 	// Prometheus stats
 	statPromWriteRequest = "promWriteReq" // Number of write requests to the promtheus endpoint
@@ -48,6 +55,8 @@ const (
 type Service struct {
 	ln    net.Listener
 	addr  string
+	addrs []string
+	lns   []net.Listener
 	https bool
 	cert  string
 	key   string
@@ -58,6 +67,16 @@ type Service struct {
 	bindSocket         string
 	unixSocketListener net.Listener
 
+	acme         *autocert.Manager
+	acmeAddr     string
+	acmeCacheDir string
+	acmeListener net.Listener
+
+	accessLog *accessLogFile
+
+	debugAddr     string
+	debugListener net.Listener
+
 	Handler *Handler
 
 	Logger *zap.Logger
@@ -67,6 +86,7 @@ type Service struct {
 func NewService(c Config) *Service {
 	s := &Service{
 		addr:       c.BindAddress,
+		addrs:      c.AdditionalBindAddresses,
 		https:      c.HTTPSEnabled,
 		cert:       c.HTTPSCertificate,
 		key:        c.HTTPSPrivateKey,
@@ -74,45 +94,71 @@ func NewService(c Config) *Service {
 		err:        make(chan error),
 		unixSocket: c.UnixSocketEnabled,
 		bindSocket: c.BindSocket,
+		debugAddr:  c.DebugBindAddress,
 		Handler:    NewHandler(c),
 		Logger:     zap.NewNop(),
 	}
 	if s.key == "" {
 		s.key = s.cert
 	}
+	if c.HTTPSEnabled && c.HTTPSACMEEnabled {
+		s.acme = &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(c.HTTPSACMECacheDir),
+			HostPolicy: autocert.HostWhitelist(c.HTTPSACMEDomains...),
+			Email:      c.HTTPSACMEEmail,
+		}
+		s.acmeAddr = c.HTTPSACMEChallengeBindAddress
+		s.acmeCacheDir = c.HTTPSACMECacheDir
+	}
 	s.Handler.Logger = s.Logger
 	return s
 }
 
-// Open starts the service.
+// Open starts the service. If it fails, everything opened so far is closed.
 func (s *Service) Open() error {
+	if err := s.open(); err != nil {
+		s.Close()
+		return err
+	}
+	return nil
+}
+
+func (s *Service) open() error {
 	s.Logger.Info("Starting HTTP service")
 	s.Logger.Info(fmt.Sprint("Authentication enabled:", s.Handler.Config.AuthEnabled))
 
-	// Open listener.
-	if s.https {
-		cert, err := tls.LoadX509KeyPair(s.cert, s.key)
+	// Open the access log.
+	if c := s.Handler.Config; c.LogEnabled && c.AccessLogPath != "" {
+		l, err := openAccessLogFile(c.AccessLogPath, int64(c.AccessLogMaxSize), time.Duration(c.AccessLogRotateInterval), c.AccessLogMaxBackups)
 		if err != nil {
-			return err
+			return fmt.Errorf("open access log: %s", err)
 		}
+		s.Logger.Info(fmt.Sprint("Writing access log to:", c.AccessLogPath))
+		s.accessLog = l
+		s.Handler.CLFLogger = log.New(l, "", 0)
+	}
 
-		listener, err := tls.Listen("tcp", s.addr, &tls.Config{
-			Certificates: []tls.Certificate{cert},
-		})
+	// Open listeners.
+	var tlsConfig *tls.Config
+	if s.https {
+		config, err := s.tlsConfig()
 		if err != nil {
 			return err
 		}
-
-		s.Logger.Info(fmt.Sprint("Listening on HTTPS:", listener.Addr().String()))
-		s.ln = listener
-	} else {
-		listener, err := net.Listen("tcp", s.addr)
+		tlsConfig = config
+	}
+	listener, err := s.listen(s.addr, tlsConfig)
+	if err != nil {
+		return err
+	}
+	s.ln = listener
+	for _, addr := range s.addrs {
+		listener, err := s.listen(addr, tlsConfig)
 		if err != nil {
 			return err
 		}
-
-		s.Logger.Info(fmt.Sprint("Listening on HTTP:", listener.Addr().String()))
-		s.ln = listener
+		s.lns = append(s.lns, listener)
 	}
 
 	// Open unix socket listener.
@@ -135,12 +181,33 @@ func (s *Service) Open() error {
 		s.Logger.Info(fmt.Sprint("Listening on unix socket:", listener.Addr().String()))
 		s.unixSocketListener = listener
 
-		go s.serveUnixSocket()
+		go s.serve(listener)
 	}
 
-	// Enforce a connection limit if one has been given.
+	// Open the debug listener.
+	if s.debugAddr != "" {
+		listener, err := net.Listen("tcp", s.debugAddr)
+		if err != nil {
+			return fmt.Errorf("listen for debug requests: %s", err)
+		}
+		s.Logger.Info(fmt.Sprint("Listening for debug requests on:", listener.Addr().String()))
+		s.debugListener = listener
+
+		go func() {
+			err := http.Serve(listener, s.Handler.debugHandler())
+			if err != nil && !strings.Contains(err.Error(), "closed") {
+				s.err <- fmt.Errorf("debug listener failed: addr=%s, err=%s", listener.Addr(), err)
+			}
+		}()
+	}
+
+	// Enforce a connection limit if one has been given. Each listener has a
+	// limit of its own.
 	if s.limit > 0 {
 		s.ln = LimitListener(s.ln, s.limit)
+		for i := range s.lns {
+			s.lns[i] = LimitListener(s.lns[i], s.limit)
+		}
 	}
 
 	// wait for the listeners to start
@@ -157,23 +224,89 @@ func (s *Service) Open() error {
 	}
 
 	// Begin listening for requests in a separate goroutine.
-	go s.serveTCP()
+	go s.serve(s.ln)
+	for _, listener := range s.lns {
+		go s.serve(listener)
+	}
 	return nil
 }
 
-// Close closes the underlying listener.
+// listen opens a TCP listener on addr, or takes the one systemd passed for
+// it, with TLS if config is not nil.
+func (s *Service) listen(addr string, config *tls.Config) (net.Listener, error) {
+	listener, err := systemd.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if config != nil {
+		s.Logger.Info(fmt.Sprint("Listening on HTTPS:", listener.Addr().String()))
+		return tls.NewListener(listener, config), nil
+	}
+	s.Logger.Info(fmt.Sprint("Listening on HTTP:", listener.Addr().String()))
+	return listener, nil
+}
+
+// tlsConfig returns the TLS configuration for the HTTPS listener. When ACME
+// is enabled, certificates are obtained and renewed automatically and the
+// HTTP-01 challenge listener is started.
+func (s *Service) tlsConfig() (*tls.Config, error) {
+	if s.acme == nil {
+		cert, err := tls.LoadX509KeyPair(s.cert, s.key)
+		if err != nil {
+			return nil, err
+		}
+		return &tls.Config{
+			Certificates: []tls.Certificate{cert},
+		}, nil
+	}
+
+	if err := os.MkdirAll(s.acmeCacheDir, 0700); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", s.acmeAddr)
+	if err != nil {
+		return nil, fmt.Errorf("listen for acme challenges: %s", err)
+	}
+	s.Logger.Info(fmt.Sprint("Listening for ACME challenges on:", listener.Addr().String()))
+	s.acmeListener = listener
+
+	go func() {
+		err := http.Serve(listener, s.acme.HTTPHandler(nil))
+		if err != nil && !strings.Contains(err.Error(), "closed") {
+			s.err <- fmt.Errorf("acme challenge listener failed: addr=%s, err=%s", listener.Addr(), err)
+		}
+	}()
+
+	return &tls.Config{
+		GetCertificate: s.acme.GetCertificate,
+	}, nil
+}
+
+// Close closes the listeners and the access log. Everything is closed even
+// if closing one of them fails, and the first error is returned.
 func (s *Service) Close() error {
-	if s.ln != nil {
-		if err := s.ln.Close(); err != nil {
-			return err
+	var err error
+	listeners := append([]net.Listener{s.ln}, s.lns...)
+	listeners = append(listeners, s.acmeListener, s.unixSocketListener, s.debugListener)
+	for _, listener := range listeners {
+		if listener == nil {
+			continue
+		}
+		if e := listener.Close(); e != nil && err == nil {
+			err = e
 		}
 	}
-	if s.unixSocketListener != nil {
-		if err := s.unixSocketListener.Close(); err != nil {
-			return err
+	s.ln, s.lns, s.acmeListener, s.unixSocketListener, s.debugListener = nil, nil, nil, nil, nil
+
+	if s.accessLog != nil {
+		if e := s.accessLog.Close(); e != nil && err == nil {
+			err = e
 		}
+		s.accessLog = nil
 	}
-	return nil
+	return err
 }
 
 // WithLogger sets the logger for the service.
@@ -198,28 +331,27 @@ func (s *Service) Statistics(tags map[string]string) []models.Statistic {
 	return s.Handler.Statistics(models.NewTags(map[string]string{"bind": s.addr}).Merge(tags).Map())
 }
 
+// Addrs returns the addresses of the listeners for additional-bind-addresses.
+func (s *Service) Addrs() []net.Addr {
+	addrs := make([]net.Addr, len(s.lns))
+	for i, listener := range s.lns {
+		addrs[i] = listener.Addr()
+	}
+	return addrs
+}
+
 // BoundHTTPAddr returns the string version of the address that the HTTP server is listening on.
 // This is useful if you start an ephemeral server in test with bind address localhost:0.
 func (s *Service) BoundHTTPAddr() string {
 	return s.ln.Addr().String()
 }
 
-// serveTCP serves the handler from the TCP listener.
-func (s *Service) serveTCP() {
-	s.serve(s.ln)
-}
-
-// serveUnixSocket serves the handler from the unix socket listener.
-func (s *Service) serveUnixSocket() {
-	s.serve(s.unixSocketListener)
-}
-
 // serve serves the handler from the listener.
 func (s *Service) serve(listener net.Listener) {
 	// The listener was closed so exit
 	// See https://github.com/golang/go/issues/4373
 	err := http.Serve(listener, s.Handler)
 	if err != nil && !strings.Contains(err.Error(), "closed") {
-		s.err <- fmt.Errorf("listener failed: addr=%s, err=%s", s.Addr(), err)
+		s.err <- fmt.Errorf("listener failed: addr=%s, err=%s", listener.Addr(), err)
 	}
 }
@@ -0,0 +1,47 @@
+package httpd_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/influxdata/influxdb/services/httpd"
+)
+
+// Ensure the listeners opened before a failure are closed.
+func TestService_Open_Failure(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	c := httpd.NewConfig()
+	c.BindAddress = freeAddr(t)
+	c.AdditionalBindAddresses = []string{freeAddr(t), taken.Addr().String()}
+	s := httpd.NewService(c)
+	if err := s.Open(); err == nil {
+		s.Close()
+		t.Fatal("expected error")
+	}
+
+	for _, addr := range []string{c.BindAddress, c.AdditionalBindAddresses[0]} {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			t.Fatalf("expected %s to be closed: %s", addr, err)
+		}
+		ln.Close()
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error closing again: %s", err)
+	}
+}
+
+// freeAddr returns a local address that is not in use.
+func freeAddr(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
@@ -0,0 +1,116 @@
+package httpd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxql"
+)
+
+// tokenResponse is the JSON form of an API token. Token, the string to
+// authenticate with, is only set in the response creating the token.
+type tokenResponse struct {
+	ID              string `json:"id"`
+	Database        string `json:"database"`
+	RetentionPolicy string `json:"retention_policy,omitempty"`
+	Privilege       string `json:"privilege"`
+	Token           string `json:"token,omitempty"`
+}
+
+// tokenRequest is the body of a request creating an API token.
+type tokenRequest struct {
+	Database        string `json:"database"`
+	RetentionPolicy string `json:"retention_policy"`
+	Privilege       string `json:"privilege"`
+}
+
+func newTokenResponse(t *meta.TokenInfo) tokenResponse {
+	return tokenResponse{
+		ID:              t.ID,
+		Database:        t.Database,
+		RetentionPolicy: t.RetentionPolicy,
+		Privilege:       t.Privilege.String(),
+	}
+}
+
+// serveTokens lists the API tokens, without the strings to authenticate
+// with.
+func (h *Handler) serveTokens(w http.ResponseWriter, r *http.Request, user meta.User) {
+	if !h.authorizeUserAdmin(w, user) {
+		return
+	}
+
+	tokens := h.MetaClient.Tokens()
+	resp := struct {
+		Tokens []tokenResponse `json:"tokens"`
+	}{Tokens: make([]tokenResponse, 0, len(tokens))}
+	for i := range tokens {
+		resp.Tokens = append(resp.Tokens, newTokenResponse(&tokens[i]))
+	}
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+// serveCreateToken creates an API token that can only read from or only
+// write to a database, or one of its retention policies. The response is the
+// only one to include the token.
+func (h *Handler) serveCreateToken(w http.ResponseWriter, r *http.Request, user meta.User) {
+	if !h.authorizeUserAdmin(w, user) {
+		return
+	}
+
+	var req tokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.httpError(w, fmt.Sprintf("invalid token: %s", err), http.StatusBadRequest)
+		return
+	} else if req.Database == "" {
+		h.httpError(w, "database required", http.StatusBadRequest)
+		return
+	}
+	if e := auditEvent(r); e != nil {
+		e.Database = req.Database
+		e.RetentionPolicy = req.RetentionPolicy
+	}
+
+	p, err := parsePrivilege(req.Privilege)
+	if err != nil {
+		h.httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if p != influxql.ReadPrivilege && p != influxql.WritePrivilege {
+		h.httpError(w, meta.ErrTokenPrivilege.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if di := h.MetaClient.Database(req.Database); di == nil {
+		h.httpError(w, fmt.Sprintf("database not found: %s", req.Database), http.StatusNotFound)
+		return
+	} else if req.RetentionPolicy != "" && di.RetentionPolicy(req.RetentionPolicy) == nil {
+		h.httpError(w, fmt.Sprintf("retention policy not found: %s", req.RetentionPolicy), http.StatusNotFound)
+		return
+	}
+
+	t, token, err := h.MetaClient.CreateToken(req.Database, req.RetentionPolicy, p)
+	if err != nil {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := newTokenResponse(t)
+	resp.Token = token
+	h.writeJSON(w, http.StatusCreated, resp)
+}
+
+// serveDropToken drops an API token. Dropping a token that does not exist
+// succeeds.
+func (h *Handler) serveDropToken(w http.ResponseWriter, r *http.Request, user meta.User) {
+	if !h.authorizeUserAdmin(w, user) {
+		return
+	}
+
+	if err := h.MetaClient.DropToken(r.URL.Query().Get(":id")); err != nil && err != meta.ErrTokenNotFound {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.writeHeader(w, http.StatusNoContent)
+}
@@ -0,0 +1,234 @@
+package httpd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxql"
+)
+
+// userResponse is the JSON form of a user.
+type userResponse struct {
+	Name  string `json:"name"`
+	Admin bool   `json:"admin"`
+}
+
+// userRequest is the body of a request creating or updating a user.
+type userRequest struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	Admin    bool   `json:"admin"`
+}
+
+// privilegeRequest is the body of a request setting the privilege of a user
+// on a database.
+type privilegeRequest struct {
+	Database  string `json:"database"`
+	Privilege string `json:"privilege"`
+}
+
+// authorizeUserAdmin returns true if user may manage users. Otherwise it
+// writes the error response. As with queries, the only request allowed with
+// authentication enabled and no admin user is the creation of an admin.
+func (h *Handler) authorizeUserAdmin(w http.ResponseWriter, user meta.User) bool {
+	if h.Config.AuthEnabled && user == nil {
+		h.httpError(w, "create admin user first or disable authentication", http.StatusForbidden)
+		return false
+	} else if user != nil && !user.IsAdmin() {
+		h.httpError(w, "admin privileges required", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// serveUsers lists the users.
+func (h *Handler) serveUsers(w http.ResponseWriter, r *http.Request, user meta.User) {
+	if !h.authorizeUserAdmin(w, user) {
+		return
+	}
+
+	users := h.MetaClient.Users()
+	resp := struct {
+		Users []userResponse `json:"users"`
+	}{Users: make([]userResponse, 0, len(users))}
+	for _, u := range users {
+		resp.Users = append(resp.Users, userResponse{Name: u.Name, Admin: u.Admin})
+	}
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+// serveCreateUser creates a user, or updates the password and admin status
+// of an existing one, so provisioning tools can send the same request again.
+// It responds with 201 Created if the user did not exist.
+func (h *Handler) serveCreateUser(w http.ResponseWriter, r *http.Request, user meta.User) {
+	var req userRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.httpError(w, fmt.Sprintf("invalid user: %s", err), http.StatusBadRequest)
+		return
+	} else if req.Name == "" {
+		h.httpError(w, meta.ErrUsernameRequired.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The first admin user may be created while authentication is enabled
+	// and no user exists yet, as with CREATE USER ... WITH ALL PRIVILEGES.
+	// Changing an existing user always requires an admin.
+	firstAdmin := h.Config.AuthEnabled && user == nil && req.Admin && h.MetaClient.UserCount() == 0
+	if !firstAdmin && !h.authorizeUserAdmin(w, user) {
+		return
+	}
+
+	existing, err := h.MetaClient.User(req.Name)
+	if err == nil && firstAdmin && !h.authorizeUserAdmin(w, user) {
+		return
+	} else if err == meta.ErrUserNotFound {
+		if req.Password == "" {
+			h.httpError(w, "password required", http.StatusBadRequest)
+			return
+		}
+		if _, err := h.MetaClient.CreateUser(req.Name, req.Password, req.Admin); err != nil {
+			h.httpError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h.writeJSON(w, http.StatusCreated, userResponse{Name: req.Name, Admin: req.Admin})
+		return
+	} else if err != nil {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Only change the password if it is not already the one given, since
+	// updating it hashes it again.
+	if req.Password != "" {
+		if _, err := h.MetaClient.Authenticate(req.Name, req.Password); err != nil {
+			if err := h.MetaClient.UpdateUser(req.Name, req.Password); err != nil {
+				h.httpError(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	if existing.IsAdmin() != req.Admin {
+		if err := h.MetaClient.SetAdminPrivilege(req.Name, req.Admin); err != nil {
+			h.httpError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	h.writeJSON(w, http.StatusOK, userResponse{Name: req.Name, Admin: req.Admin})
+}
+
+// serveDropUser drops a user. Dropping a user that does not exist succeeds.
+func (h *Handler) serveDropUser(w http.ResponseWriter, r *http.Request, user meta.User) {
+	if !h.authorizeUserAdmin(w, user) {
+		return
+	}
+
+	if err := h.MetaClient.DropUser(r.URL.Query().Get(":name")); err != nil && err != meta.ErrUserNotFound {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.writeHeader(w, http.StatusNoContent)
+}
+
+// serveUserPermissions returns the admin status of a user and its
+// privileges on each database.
+func (h *Handler) serveUserPermissions(w http.ResponseWriter, r *http.Request, user meta.User) {
+	if !h.authorizeUserAdmin(w, user) {
+		return
+	}
+
+	name := r.URL.Query().Get(":name")
+	u, err := h.MetaClient.User(name)
+	if err == meta.ErrUserNotFound {
+		h.httpError(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	privs, err := h.MetaClient.UserPrivileges(name)
+	if err != nil {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := struct {
+		userResponse
+		Privileges map[string]string `json:"privileges"`
+	}{
+		userResponse: userResponse{Name: name, Admin: u.IsAdmin()},
+		Privileges:   make(map[string]string, len(privs)),
+	}
+	for db, p := range privs {
+		if p != influxql.NoPrivileges {
+			resp.Privileges[db] = p.String()
+		}
+	}
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+// serveSetUserPermission sets the privilege of a user on a database. An
+// empty privilege or NO PRIVILEGES revokes it.
+func (h *Handler) serveSetUserPermission(w http.ResponseWriter, r *http.Request, user meta.User) {
+	if !h.authorizeUserAdmin(w, user) {
+		return
+	}
+
+	var req privilegeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.httpError(w, fmt.Sprintf("invalid permission: %s", err), http.StatusBadRequest)
+		return
+	} else if req.Database == "" {
+		h.httpError(w, "database required", http.StatusBadRequest)
+		return
+	}
+	p, err := parsePrivilege(req.Privilege)
+	if err != nil {
+		h.httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	name := r.URL.Query().Get(":name")
+	if _, err := h.MetaClient.User(name); err == meta.ErrUserNotFound {
+		h.httpError(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if h.MetaClient.Database(req.Database) == nil {
+		h.httpError(w, fmt.Sprintf("database not found: %s", req.Database), http.StatusNotFound)
+		return
+	}
+
+	if err := h.MetaClient.SetPrivilege(name, req.Database, p); err != nil {
+		h.httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.writeHeader(w, http.StatusNoContent)
+}
+
+// parsePrivilege returns the privilege named s, as in GRANT statements.
+func parsePrivilege(s string) (influxql.Privilege, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "", "NO PRIVILEGES":
+		return influxql.NoPrivileges, nil
+	case "READ":
+		return influxql.ReadPrivilege, nil
+	case "WRITE":
+		return influxql.WritePrivilege, nil
+	case "ALL", "ALL PRIVILEGES":
+		return influxql.AllPrivileges, nil
+	}
+	return influxql.NoPrivileges, fmt.Errorf("invalid privilege: %q", s)
+}
+
+// writeJSON writes v as the JSON body of a response with status code.
+func (h *Handler) writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	b, _ := json.Marshal(v)
+	w.Header().Set("Content-Type", "application/json")
+	h.writeHeader(w, code)
+	w.Write(b)
+}
@@ -0,0 +1,252 @@
+package httpd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/internal/ingest"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+// writeSection is the points of a multi-database write destined for one
+// database and retention policy.
+type writeSection struct {
+	database        string
+	retentionPolicy string
+	lines           []byte
+	points          []models.Point
+}
+
+// splitMultiWrite splits the body of a multi-database write at its header
+// lines, which are "db=<name>" optionally followed by " rp=<name>", as in UDP
+// datagrams. Any other line, including the points of a measurement whose
+// name starts with "db=", belongs to the section before it. The lines before
+// the first header are written to database and retentionPolicy.
+func splitMultiWrite(body []byte, database, retentionPolicy string) ([]*writeSection, error) {
+	var sections []*writeSection
+	cur := &writeSection{database: database, retentionPolicy: retentionPolicy}
+	for len(body) > 0 {
+		line := body
+		if i := bytes.IndexByte(body, '\n'); i >= 0 {
+			line, body = body[:i+1], body[i+1:]
+		} else {
+			body = nil
+		}
+
+		dest, ok := ingest.ParseHeader(bytes.TrimRight(line, "\r\n"))
+		if !ok {
+			cur.lines = append(cur.lines, line...)
+			continue
+		}
+
+		sections = append(sections, cur)
+		cur = &writeSection{database: dest.Database, retentionPolicy: dest.RetentionPolicy}
+	}
+	sections = append(sections, cur)
+
+	// Drop the sections without points, such as the lines before the first
+	// header when there are only blank lines.
+	a := sections[:0]
+	for _, s := range sections {
+		if len(bytes.TrimSpace(s.lines)) == 0 {
+			continue
+		} else if s.database == "" {
+			return nil, errors.New("database is required for the lines before the first header")
+		}
+		a = append(a, s)
+	}
+	return a, nil
+}
+
+// serveWriteMulti receives line protocol destined for several databases and
+// retention policies in one request. Header lines name the destination of
+// the lines after them. Every section is validated, authorized and parsed
+// before any point is written, so a request with an invalid section writes
+// nothing. As with /write, dry_run=true validates the points without writing
+// them.
+func (h *Handler) serveWriteMulti(w http.ResponseWriter, r *http.Request, user meta.User) {
+	atomic.AddInt64(&h.stats.WriteRequests, 1)
+	atomic.AddInt64(&h.stats.ActiveWriteRequests, 1)
+	defer func(start time.Time) {
+		atomic.AddInt64(&h.stats.ActiveWriteRequests, -1)
+		atomic.AddInt64(&h.stats.WriteRequestDuration, time.Since(start).Nanoseconds())
+	}(time.Now())
+	h.requestTracker.Add(r, user)
+
+	precision, err := parsePrecision(r.URL.Query().Get("precision"))
+	if err != nil {
+		h.httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	consistency := models.ConsistencyLevelOne
+	if level := r.URL.Query().Get("consistency"); level != "" {
+		consistency, err = models.ParseConsistencyLevel(level)
+		if err != nil {
+			h.httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	buf, ok := h.readWriteBody(w, r)
+	if !ok {
+		return
+	}
+
+	sections, err := splitMultiWrite(buf.Bytes(), r.URL.Query().Get("db"), r.URL.Query().Get("rp"))
+	if err != nil {
+		h.httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	databases := make([]string, 0, len(sections))
+	seen := make(map[string]bool, len(sections))
+	for _, s := range sections {
+		if !seen[s.database] {
+			seen[s.database] = true
+			databases = append(databases, s.database)
+		}
+	}
+	auditWrite(r, user, strings.Join(databases, ","), "")
+
+	for _, db := range databases {
+		di := h.MetaClient.Database(db)
+		if di == nil {
+			h.httpError(w, fmt.Sprintf("database not found: %q", db), http.StatusNotFound)
+			return
+		}
+		for _, s := range sections {
+			if s.database == db && s.retentionPolicy != "" && di.RetentionPolicy(s.retentionPolicy) == nil {
+				h.httpError(w, fmt.Sprintf("retention policy not found: %q", s.retentionPolicy), http.StatusNotFound)
+				return
+			}
+		}
+
+		if h.Config.AuthEnabled && user == nil {
+			h.httpError(w, fmt.Sprintf("user is required to write to database %q", db), http.StatusForbidden)
+			return
+		}
+	}
+	if h.Config.AuthEnabled {
+		for _, s := range sections {
+			if err := h.authorizeWrite(user, s.database, s.retentionPolicy); err != nil {
+				h.httpError(w, fmt.Sprintf("%q user is not authorized to write to database %q", user.ID(), s.database), http.StatusForbidden)
+				return
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	n := 0
+	for _, s := range sections {
+		points, err := models.ParsePointsWithPrecision(s.lines, now, precision)
+		if err != nil {
+			atomic.AddInt64(&h.stats.PointsParseFail, int64(models.ParseFailures(err)))
+			h.httpError(w, fmt.Sprintf("database %q: %s", s.database, err), http.StatusBadRequest)
+			return
+		}
+		s.points = points
+		n += len(points)
+	}
+
+	if e := auditEvent(r); e != nil {
+		e.Points = n
+	}
+
+	if r.URL.Query().Get("dry_run") == "true" {
+		h.serveWriteMultiDryRun(w, sections, n)
+		return
+	}
+
+	points := make(map[string]int, len(databases))
+	for _, s := range sections {
+		points[s.database] += len(s.points)
+	}
+	if err := h.quotas.writeMulti(user, points); err != nil {
+		atomic.AddInt64(&h.stats.PointsWrittenFail, int64(n))
+		h.quotaExceeded(w, err)
+		return
+	}
+
+	// The sections are written in order. A section failing to be stored
+	// leaves the ones before it written. Points rejected by the storage,
+	// such as those with a conflicting field type, are reported once all of
+	// the sections are written.
+	written, dropped := 0, 0
+	var reasons []string
+	for i, s := range sections {
+		err := h.PointsWriter.WritePointsWithContext(r.Context(), s.database, s.retentionPolicy, consistency, user, s.points)
+		if werr, ok := err.(tsdb.PartialWriteError); ok {
+			written += len(s.points) - werr.Dropped
+			dropped += werr.Dropped
+			reasons = append(reasons, fmt.Sprintf("database %q: %s", s.database, werr.Reason))
+			continue
+		} else if err != nil {
+			atomic.AddInt64(&h.stats.PointsWrittenOK, int64(written))
+			atomic.AddInt64(&h.stats.PointsWrittenDropped, int64(dropped))
+			atomic.AddInt64(&h.stats.PointsWrittenFail, int64(n-written-dropped))
+
+			msg := fmt.Sprintf("database %q: %s", s.database, err)
+			if i > 0 {
+				msg = fmt.Sprintf("%s (%d points written before)", msg, written)
+			}
+			switch {
+			case influxdb.IsClientError(err):
+				h.httpError(w, msg, http.StatusBadRequest)
+			case influxdb.IsAuthorizationError(err):
+				h.httpError(w, msg, http.StatusForbidden)
+			case err == tsdb.ErrDiskFull:
+				h.httpError(w, msg, http.StatusInsufficientStorage)
+			default:
+				h.httpError(w, msg, http.StatusInternalServerError)
+			}
+			return
+		}
+		written += len(s.points)
+	}
+
+	atomic.AddInt64(&h.stats.PointsWrittenOK, int64(written))
+	if dropped > 0 {
+		atomic.AddInt64(&h.stats.PointsWrittenDropped, int64(dropped))
+		h.partialWrite(w, written, dropped, reasons)
+		return
+	}
+	h.writeHeader(w, http.StatusNoContent)
+}
+
+// serveWriteMultiDryRun responds to a dry run of a multi-database write with
+// the response the write would get, apart from errors storing the points.
+func (h *Handler) serveWriteMultiDryRun(w http.ResponseWriter, sections []*writeSection, n int) {
+	dropped := 0
+	var reasons []string
+	for _, s := range sections {
+		err := h.PointsWriter.ValidatePoints(s.database, s.retentionPolicy, s.points)
+		if werr, ok := err.(tsdb.PartialWriteError); ok {
+			dropped += werr.Dropped
+			reasons = append(reasons, fmt.Sprintf("database %q: %s", s.database, werr.Reason))
+		} else if err != nil {
+			code := http.StatusInternalServerError
+			if influxdb.IsClientError(err) {
+				code = http.StatusBadRequest
+			} else if err == tsdb.ErrDiskFull {
+				code = http.StatusInsufficientStorage
+			}
+			h.httpError(w, fmt.Sprintf("database %q: %s", s.database, err), code)
+			return
+		}
+	}
+
+	if dropped > 0 {
+		h.partialWrite(w, n-dropped, dropped, reasons)
+		return
+	}
+	h.writeHeader(w, http.StatusNoContent)
+}
@@ -0,0 +1,40 @@
+package ingest
+
+import "bytes"
+
+// Destination is the database and retention policy points are written to.
+// An empty retention policy is the default one of the database.
+type Destination struct {
+	Database        string
+	RetentionPolicy string
+}
+
+// headerPrefix starts a header line.
+var headerPrefix = []byte("db=")
+
+// ParseHeader returns the destination named by line if it is a header line,
+// "db=<name>" optionally followed by " rp=<name>", as used by UDP datagrams
+// and multi-database HTTP writes. ok is false for any other line. That
+// includes the points of a measurement whose name starts with "db=", which
+// have fields other than db and rp.
+func ParseHeader(line []byte) (dest Destination, ok bool) {
+	if !bytes.HasPrefix(line, headerPrefix) {
+		return Destination{}, false
+	}
+
+	for i, field := range bytes.Fields(line) {
+		kv := bytes.SplitN(field, []byte("="), 2)
+		if len(kv) != 2 || len(kv[1]) == 0 {
+			return Destination{}, false
+		}
+		switch {
+		case i == 0 && string(kv[0]) == "db":
+			dest.Database = string(kv[1])
+		case i == 1 && string(kv[0]) == "rp":
+			dest.RetentionPolicy = string(kv[1])
+		default:
+			return Destination{}, false
+		}
+	}
+	return dest, true
+}
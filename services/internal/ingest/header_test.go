@@ -0,0 +1,30 @@
+package ingest_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/influxdb/services/internal/ingest"
+)
+
+func TestParseHeader(t *testing.T) {
+	for _, tt := range []struct {
+		line string
+		dest ingest.Destination
+		ok   bool
+	}{
+		{line: "db=db0", dest: ingest.Destination{Database: "db0"}, ok: true},
+		{line: "db=db0 rp=rp0", dest: ingest.Destination{Database: "db0", RetentionPolicy: "rp0"}, ok: true},
+		{line: "cpu value=1"},
+		{line: "db=db0 rp="},
+		{line: "db=db0 precision=s"},
+		{line: "db=db0 rp=rp0 rp=rp1"},
+		{line: "rp=rp0 db=db0"},
+		{line: "db=cpu value=1"},
+		{line: "db=cpu,host=a value=1 10"},
+	} {
+		dest, ok := ingest.ParseHeader([]byte(tt.line))
+		if dest != tt.dest || ok != tt.ok {
+			t.Errorf("%q: unexpected header: %+v %v", tt.line, dest, ok)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+// Package ingest provides what the services writing points received over
+// other protocols, such as Graphite, collectd, OpenTSDB and UDP, share, along
+// with the database header lines UDP and multi-database HTTP writes accept.
+package ingest // import "github.com/influxdata/influxdb/services/internal/ingest"
+
+import (
+	"fmt"
+
+	"github.com/influxdata/influxdb/tsdb"
+	"go.uber.org/zap"
+)
+
+// PartialWrite returns the number of points of a batch dropped when writing
+// it to database returned err, and whether the other points were written.
+// That is the case when err is a partial write error, which is logged to log.
+func PartialWrite(log *zap.Logger, database string, err error) (dropped int, ok bool) {
+	werr, ok := err.(tsdb.PartialWriteError)
+	if !ok {
+		return 0, false
+	}
+	log.Info(fmt.Sprintf("dropped %d points writing to database %q: %s", werr.Dropped, database, werr))
+	return werr.Dropped, true
+}
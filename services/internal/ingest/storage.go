@@ -0,0 +1,124 @@
+package ingest
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// StorageRetryInterval is how long to wait after failing to create the
+// database before trying again.
+const StorageRetryInterval = time.Second
+
+// ErrRetryStorage is returned while waiting to retry creating the database.
+var ErrRetryStorage = errors.New("waiting to retry after an earlier failure")
+
+// Storage tracks whether the database and retention policy a service writes
+// to have been created. The zero value is ready to use.
+type Storage struct {
+	createMu sync.Mutex // Held while creating the database.
+
+	mu      sync.RWMutex
+	ready   bool      // Has the required database been created?
+	retryAt time.Time // When to try again after failing to create the database.
+}
+
+// Ensure calls create unless the storage is ready, and marks it ready once
+// create succeeds. After a failure, ErrRetryStorage is returned without
+// calling create until StorageRetryInterval has passed, so that a failing
+// meta store is not asked again for every batch. Only one create runs at a
+// time; concurrent callers wait for it and share its outcome.
+func (s *Storage) Ensure(create func() error) error {
+	if ok, err := s.check(); ok {
+		return err
+	}
+
+	s.createMu.Lock()
+	defer s.createMu.Unlock()
+
+	// Another caller may have created the storage, or failed to, while this
+	// one was waiting.
+	if ok, err := s.check(); ok {
+		return err
+	}
+
+	if err := create(); err != nil {
+		s.mu.Lock()
+		s.retryAt = time.Now().Add(StorageRetryInterval)
+		s.mu.Unlock()
+		return err
+	}
+
+	s.mu.Lock()
+	s.ready, s.retryAt = true, time.Time{}
+	s.mu.Unlock()
+	return nil
+}
+
+// check returns true, and the result of Ensure, if Ensure does not need to
+// call create.
+func (s *Storage) check() (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.ready {
+		return true, nil
+	} else if time.Now().Before(s.retryAt) {
+		return true, ErrRetryStorage
+	}
+	return false, nil
+}
+
+// Ready returns true if the storage has been created.
+func (s *Storage) Ready() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ready
+}
+
+// RetryAt returns when Ensure will next call create after a failure, or the
+// zero time if it is not waiting.
+func (s *Storage) RetryAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.retryAt
+}
+
+// Reset forgets whether the storage was created or failed to be, so the next
+// Ensure calls create.
+func (s *Storage) Reset() {
+	s.mu.Lock()
+	s.ready, s.retryAt = false, time.Time{}
+	s.mu.Unlock()
+}
+
+// MetaClient is the part of the meta client that creates the database and
+// retention policy a service writes to.
+type MetaClient interface {
+	Database(name string) *meta.DatabaseInfo
+	RetentionPolicy(database, name string) (*meta.RetentionPolicyInfo, error)
+	CreateDatabase(name string) (*meta.DatabaseInfo, error)
+	CreateDatabaseWithRetentionPolicy(name string, spec *meta.RetentionPolicySpec) (*meta.DatabaseInfo, error)
+	CreateRetentionPolicy(database string, spec *meta.RetentionPolicySpec, makeDefault bool) (*meta.RetentionPolicyInfo, error)
+}
+
+// CreateStorage creates database, and its retention policy rp unless rp is
+// empty, if they do not exist. A new database gets rp as its default.
+func CreateStorage(c MetaClient, database, rp string) error {
+	if rp == "" {
+		_, err := c.CreateDatabase(database)
+		return err
+	} else if c.Database(database) == nil {
+		spec := meta.RetentionPolicySpec{Name: rp}
+		_, err := c.CreateDatabaseWithRetentionPolicy(database, &spec)
+		return err
+	} else if info, _ := c.RetentionPolicy(database, rp); info == nil {
+		// Other writers may rely on the default retention policy of an
+		// existing database, so it is left as it is.
+		spec := meta.RetentionPolicySpec{Name: rp}
+		_, err := c.CreateRetentionPolicy(database, &spec, false)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package ingest_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/internal/ingest"
+)
+
+// Ensure a failure to create the storage delays the next attempt, and that
+// a reset storage is created again.
+func TestStorage_Ensure(t *testing.T) {
+	var s ingest.Storage
+	var calls int
+	errCreate := errors.New("create failed")
+	create := func() error {
+		calls++
+		return errCreate
+	}
+
+	if err := s.Ensure(create); err != errCreate {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := s.Ensure(create); err != ingest.ErrRetryStorage {
+		t.Fatalf("unexpected error while waiting to retry: %v", err)
+	} else if calls != 1 {
+		t.Fatalf("unexpected calls: %d", calls)
+	} else if s.Ready() || s.RetryAt().IsZero() {
+		t.Fatalf("unexpected state: ready=%v retry=%v", s.Ready(), s.RetryAt())
+	}
+
+	s.Reset()
+	create = func() error { calls++; return nil }
+	for i := 0; i < 2; i++ {
+		if err := s.Ensure(create); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("unexpected calls: %d", calls)
+	} else if !s.Ready() || !s.RetryAt().IsZero() {
+		t.Fatalf("unexpected state: ready=%v retry=%v", s.Ready(), s.RetryAt())
+	}
+}
+
+// Ensure concurrent callers share a single create.
+func TestStorage_Ensure_Concurrent(t *testing.T) {
+	var s ingest.Storage
+	var calls int32
+	release := make(chan struct{})
+	create := func() error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.Ensure(create)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("unexpected calls: %d", n)
+	}
+}
@@ -0,0 +1,32 @@
+package ingest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// DefaultTags returns the tags of a service's tags setting, a list of
+// key=value pairs checked by ValidateTags.
+func DefaultTags(tags []string) models.Tags {
+	m := make(map[string]string, len(tags))
+	for _, t := range tags {
+		if parts := strings.Split(t, "="); len(parts) == 2 {
+			m[parts[0]] = parts[1]
+		}
+	}
+	return models.NewTags(m)
+}
+
+// ValidateTags returns an error if a pair of a service's tags setting is not
+// a non-empty key and value separated by '='.
+func ValidateTags(tags []string) error {
+	for _, t := range tags {
+		parts := strings.Split(t, "=")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("invalid tag: '%s'", t)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package ingest_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/internal/ingest"
+)
+
+func TestDefaultTags(t *testing.T) {
+	tags := []string{"region=eu1", "source=udp"}
+	if err := ingest.ValidateTags(tags); err != nil {
+		t.Fatal(err)
+	} else if got, exp := ingest.DefaultTags(tags), models.NewTags(map[string]string{"region": "eu1", "source": "udp"}); !got.Equal(exp) {
+		t.Fatalf("unexpected tags: %v", got)
+	}
+
+	for _, tag := range []string{"region", "region=", "=eu1", "region=eu=1"} {
+		if err := ingest.ValidateTags([]string{tag}); err == nil {
+			t.Fatalf("expected error for %q", tag)
+		}
+	}
+}
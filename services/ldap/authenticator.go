@@ -0,0 +1,411 @@
+package ldap
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxql"
+	"go.uber.org/zap"
+)
+
+const (
+	// saltBytes is the number of bytes used for the salt of cached passwords.
+	saltBytes = 32
+
+	// maxCachedUsers is the most directory users kept at once. The user
+	// whose authentication expires first is dropped to make room.
+	maxCachedUsers = 10000
+
+	// cachePruneInterval is how often the directory users are pruned, and
+	// how long a user is kept once its authentication expires, so that the
+	// writes of a request can be authorized after it authenticates.
+	cachePruneInterval = time.Minute
+)
+
+var errUserNotFound = errors.New("ldap: user not found")
+
+// Authenticator authenticates users against an LDAP directory and maps the
+// directory groups they belong to onto database privileges. Users that exist
+// in the meta store are authenticated locally and take precedence.
+type Authenticator struct {
+	config Config
+	url    *url.URL
+
+	mu        sync.RWMutex
+	pool      chan *conn
+	users     map[string]*cachedUser
+	lastPrune time.Time
+	closed    bool
+
+	// MetaClient authenticates local users.
+	MetaClient interface {
+		Authenticate(username, password string) (meta.User, error)
+	}
+
+	// WriteAuthorizer authorizes writes for local users.
+	WriteAuthorizer interface {
+		AuthorizeWrite(username, database string) error
+	}
+
+	Logger *zap.Logger
+
+	now func() time.Time
+}
+
+// cachedUser is a directory user that recently authenticated.
+type cachedUser struct {
+	user    *meta.UserInfo
+	salt    []byte
+	hash    []byte
+	expires time.Time
+}
+
+// NewAuthenticator returns a new instance of Authenticator.
+func NewAuthenticator(c Config) *Authenticator {
+	return &Authenticator{
+		config: c,
+		users:  make(map[string]*cachedUser),
+		Logger: zap.NewNop(),
+		now:    time.Now,
+	}
+}
+
+// WithLogger sets the logger for the authenticator.
+func (a *Authenticator) WithLogger(log *zap.Logger) {
+	a.Logger = log.With(zap.String("service", "ldap"))
+}
+
+// Open prepares the connection pool. Connections are opened on demand.
+func (a *Authenticator) Open() error {
+	u, err := url.Parse(a.config.URL)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.url = u
+	a.pool = make(chan *conn, a.config.PoolSize)
+	a.closed = false
+
+	a.Logger.Info(fmt.Sprintf("Authenticating users against %s", u.Host))
+	return nil
+}
+
+// Close closes all idle connections.
+func (a *Authenticator) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed || a.pool == nil {
+		return nil
+	}
+	a.closed = true
+
+	for {
+		select {
+		case c := <-a.pool:
+			c.close()
+		default:
+			return nil
+		}
+	}
+}
+
+// Authenticate returns the user for username if password is valid.
+func (a *Authenticator) Authenticate(username, password string) (meta.User, error) {
+	if a.MetaClient != nil {
+		u, err := a.MetaClient.Authenticate(username, password)
+		if err != meta.ErrUserNotFound {
+			if err == nil {
+				a.forget(username)
+			}
+			return u, err
+		}
+	}
+
+	// A simple bind with an empty password is an unauthenticated bind, which
+	// most directories accept for any DN.
+	if password == "" {
+		return nil, meta.ErrAuthenticate
+	}
+
+	now := a.now()
+	a.mu.RLock()
+	cu := a.users[username]
+	a.mu.RUnlock()
+	if cu != nil && now.Before(cu.expires) && subtle.ConstantTimeCompare(hashWithSalt(cu.salt, password), cu.hash) == 1 {
+		return cu.user, nil
+	}
+
+	c, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := a.authenticate(c, username, password)
+	if e, ok := err.(*resultError); (ok && e.code == resultInvalidCredentials) || err == errUserNotFound {
+		a.release(c)
+		return nil, meta.ErrAuthenticate
+	} else if err != nil {
+		if _, ok := err.(*resultError); ok {
+			a.release(c)
+		} else {
+			c.close()
+		}
+		a.Logger.Info(fmt.Sprintf("Directory lookup failed for user %q: %s", username, err))
+		return nil, err
+	}
+	a.release(c)
+
+	salt, hash, err := saltedHash(password)
+	if err != nil {
+		return nil, err
+	}
+
+	// The user is kept even with caching disabled so that writes by the
+	// user can be authorized after authentication.
+	a.mu.Lock()
+	delete(a.users, username)
+	a.prune(now)
+	a.users[username] = &cachedUser{
+		user:    u,
+		salt:    salt,
+		hash:    hash,
+		expires: now.Add(time.Duration(a.config.CacheTTL)),
+	}
+	a.mu.Unlock()
+	return u, nil
+}
+
+// AuthorizeWrite returns nil if username may write to database.
+func (a *Authenticator) AuthorizeWrite(username, database string) error {
+	a.mu.RLock()
+	cu := a.users[username]
+	a.mu.RUnlock()
+
+	if cu == nil {
+		if a.WriteAuthorizer == nil {
+			return &meta.ErrAuthorize{
+				Database: database,
+				Message:  fmt.Sprintf("%s not authorized to write to %s", username, database),
+			}
+		}
+		return a.WriteAuthorizer.AuthorizeWrite(username, database)
+	}
+
+	if !cu.user.AuthorizeDatabase(influxql.WritePrivilege, database) {
+		return &meta.ErrAuthorize{
+			Database: database,
+			Message:  fmt.Sprintf("%s not authorized to write to %s", username, database),
+		}
+	}
+	return nil
+}
+
+// authenticate binds as the user on c and resolves the user's privileges.
+func (a *Authenticator) authenticate(c *conn, username, password string) (*meta.UserInfo, error) {
+	var dn string
+	if a.config.BindDN != "" {
+		if err := a.bindServiceAccount(c); err != nil {
+			return nil, err
+		}
+
+		dns, err := c.search(a.config.UserBaseDN, a.config.UserAttribute, username)
+		if err != nil {
+			return nil, err
+		} else if len(dns) != 1 {
+			return nil, errUserNotFound
+		}
+		dn = dns[0]
+	} else {
+		dn = fmt.Sprintf(a.config.UserDNTemplate, escapeDN(username))
+	}
+
+	if err := c.bind(dn, password); err != nil {
+		return nil, err
+	}
+
+	u := &meta.UserInfo{
+		Name:       username,
+		Privileges: make(map[string]influxql.Privilege),
+	}
+	if len(a.config.Groups) == 0 {
+		return u, nil
+	}
+
+	// Group membership is looked up as the service account, if there is one,
+	// so the connection goes back to the pool bound as the service account.
+	if a.config.BindDN != "" {
+		if err := a.bindServiceAccount(c); err != nil {
+			return nil, err
+		}
+	}
+
+	groups, err := c.search(a.config.GroupBaseDN, a.config.GroupMemberAttribute, dn)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, g := range a.config.Groups {
+		for _, group := range groups {
+			if !equalDN(g.DN, group) {
+				continue
+			}
+
+			if g.Admin {
+				u.Admin = true
+			}
+			if g.Database != "" {
+				p, _ := parsePrivilege(g.Privilege)
+				grant(u, g.Database, p)
+			}
+		}
+	}
+	return u, nil
+}
+
+// bindServiceAccount binds c as the configured service account.
+func (a *Authenticator) bindServiceAccount(c *conn) error {
+	if err := c.bind(a.config.BindDN, a.config.BindPassword); err != nil {
+		return fmt.Errorf("ldap: service account bind failed: %s", err)
+	}
+	return nil
+}
+
+// acquire returns an idle connection from the pool or opens a new one.
+func (a *Authenticator) acquire() (*conn, error) {
+	a.mu.RLock()
+	pool, u, closed := a.pool, a.url, a.closed
+	a.mu.RUnlock()
+	if pool == nil || closed {
+		return nil, errors.New("ldap: authenticator is not open")
+	}
+
+	select {
+	case c := <-pool:
+		return c, nil
+	default:
+		return dial(u, time.Duration(a.config.Timeout), a.config.StartTLS, a.config.InsecureSkipVerify)
+	}
+}
+
+// release returns c to the pool, closing it if the pool is full.
+func (a *Authenticator) release(c *conn) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		c.close()
+		return
+	}
+
+	select {
+	case a.pool <- c:
+	default:
+		c.close()
+	}
+}
+
+// forget removes username from the directory user cache.
+func (a *Authenticator) forget(username string) {
+	a.mu.Lock()
+	delete(a.users, username)
+	a.mu.Unlock()
+}
+
+// prune removes the directory users that expired more than
+// cachePruneInterval ago, and makes room for another user if the cache is
+// full. The caller must hold a.mu.
+func (a *Authenticator) prune(now time.Time) {
+	if now.Sub(a.lastPrune) >= cachePruneInterval || len(a.users) >= maxCachedUsers {
+		for username, cu := range a.users {
+			if now.Sub(cu.expires) >= cachePruneInterval {
+				delete(a.users, username)
+			}
+		}
+		a.lastPrune = now
+	}
+
+	for len(a.users) >= maxCachedUsers {
+		var oldest string
+		for username, cu := range a.users {
+			if oldest == "" || cu.expires.Before(a.users[oldest].expires) {
+				oldest = username
+			}
+		}
+		delete(a.users, oldest)
+	}
+}
+
+// grant adds privilege p on database to u. Read and write privileges granted
+// by different groups combine into all privileges.
+func grant(u *meta.UserInfo, database string, p influxql.Privilege) {
+	if cur, ok := u.Privileges[database]; ok && cur != p {
+		p = influxql.AllPrivileges
+	}
+	u.Privileges[database] = p
+}
+
+// escapeDN escapes s for use as an attribute value in a DN (RFC 4514).
+func escapeDN(s string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == 0:
+			buf.WriteString(`\00`)
+		case strings.IndexByte(`"+,;<>\=`, c) >= 0,
+			i == 0 && (c == ' ' || c == '#'),
+			i == len(s)-1 && c == ' ':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
+
+// equalDN returns true if a and b name the same entry, ignoring case and
+// spacing around the separators.
+func equalDN(a, b string) bool {
+	return normalizeDN(a) == normalizeDN(b)
+}
+
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, p := range parts {
+		kv := strings.SplitN(p, "=", 2)
+		for j := range kv {
+			kv[j] = strings.TrimSpace(kv[j])
+		}
+		parts[i] = strings.ToLower(strings.Join(kv, "="))
+	}
+	return strings.Join(parts, ",")
+}
+
+// hashWithSalt returns a salted hash of password using salt.
+func hashWithSalt(salt []byte, password string) []byte {
+	hasher := sha256.New()
+	hasher.Write(salt)
+	hasher.Write([]byte(password))
+	return hasher.Sum(nil)
+}
+
+// saltedHash returns a salt and salted hash of password.
+func saltedHash(password string) (salt, hash []byte, err error) {
+	salt = make([]byte, saltBytes)
+	if _, err := io.ReadFull(crand.Reader, salt); err != nil {
+		return nil, nil, err
+	}
+	return salt, hashWithSalt(salt, password), nil
+}
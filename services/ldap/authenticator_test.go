@@ -0,0 +1,359 @@
+package ldap
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/internal"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxql"
+)
+
+func TestAuthenticator_Authenticate_Template(t *testing.T) {
+	d := &directory{
+		passwords: map[string]string{"uid=alice,ou=people,dc=example,dc=com": "secret"},
+	}
+	a := NewTestAuthenticator(t, d, func(c *Config) {
+		c.UserDNTemplate = "uid=%s,ou=people,dc=example,dc=com"
+	})
+	defer a.Close()
+
+	if u, err := a.Authenticate("alice", "secret"); err != nil {
+		t.Fatal(err)
+	} else if u.ID() != "alice" || u.IsAdmin() {
+		t.Fatalf("unexpected user: %#v", u)
+	}
+
+	for _, password := range []string{"wrong", ""} {
+		if _, err := a.Authenticate("alice", password); err != meta.ErrAuthenticate {
+			t.Fatalf("unexpected error for password %q: %v", password, err)
+		}
+	}
+	if _, err := a.Authenticate("bob", "secret"); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestAuthenticator_Authenticate_Groups(t *testing.T) {
+	d := &directory{
+		passwords: map[string]string{
+			"cn=influxdb,dc=example,dc=com":         "service",
+			"uid=alice,ou=people,dc=example,dc=com": "alice",
+			"uid=bob,ou=people,dc=example,dc=com":   "bob",
+		},
+		users: map[string]string{
+			"alice": "uid=alice,ou=people,dc=example,dc=com",
+			"bob":   "uid=bob,ou=people,dc=example,dc=com",
+		},
+		groups: map[string][]string{
+			"cn=admins,ou=groups,dc=example,dc=com":  {"uid=bob,ou=people,dc=example,dc=com"},
+			"cn=readers,ou=groups,dc=example,dc=com": {"uid=alice,ou=people,dc=example,dc=com"},
+			"cn=writers,ou=groups,dc=example,dc=com": {"uid=alice,ou=people,dc=example,dc=com"},
+		},
+	}
+	a := NewTestAuthenticator(t, d, func(c *Config) {
+		c.BindDN = "cn=influxdb,dc=example,dc=com"
+		c.BindPassword = "service"
+		c.UserBaseDN = "ou=people,dc=example,dc=com"
+		c.GroupBaseDN = "ou=groups,dc=example,dc=com"
+		c.Groups = []GroupConfig{
+			{DN: "cn=admins,ou=groups,dc=example,dc=com", Admin: true},
+			{DN: "CN=Readers, OU=Groups, DC=example, DC=com", Database: "db0", Privilege: "READ"},
+			{DN: "cn=writers,ou=groups,dc=example,dc=com", Database: "db0", Privilege: "WRITE"},
+			{DN: "cn=writers,ou=groups,dc=example,dc=com", Database: "db1", Privilege: "WRITE"},
+		}
+	})
+	defer a.Close()
+
+	u, err := a.Authenticate("alice", "alice")
+	if err != nil {
+		t.Fatal(err)
+	} else if u.IsAdmin() {
+		t.Fatal("expected alice to not be an admin")
+	} else if p := u.(*meta.UserInfo).Privileges; p["db0"] != influxql.AllPrivileges || p["db1"] != influxql.WritePrivilege {
+		t.Fatalf("unexpected privileges: %v", p)
+	}
+
+	if err := a.AuthorizeWrite("alice", "db1"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := a.AuthorizeWrite("alice", "db2"); err == nil {
+		t.Fatal("expected write to db2 to be unauthorized")
+	}
+
+	if u, err := a.Authenticate("bob", "bob"); err != nil {
+		t.Fatal(err)
+	} else if !u.IsAdmin() {
+		t.Fatal("expected bob to be an admin")
+	}
+}
+
+func TestAuthenticator_Authenticate_Cache(t *testing.T) {
+	d := &directory{
+		passwords: map[string]string{"uid=alice,ou=people,dc=example,dc=com": "secret"},
+	}
+	a := NewTestAuthenticator(t, d, func(c *Config) {
+		c.UserDNTemplate = "uid=%s,ou=people,dc=example,dc=com"
+	})
+	defer a.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := a.Authenticate("alice", "secret"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt64(&d.binds); n != 1 {
+		t.Fatalf("unexpected binds: %d", n)
+	}
+
+	// A different password is always checked with the directory.
+	if _, err := a.Authenticate("alice", "wrong"); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n := atomic.LoadInt64(&d.binds); n != 2 {
+		t.Fatalf("unexpected binds: %d", n)
+	}
+}
+
+// Ensure expired directory users are removed and the cache stays bounded.
+func TestAuthenticator_Prune(t *testing.T) {
+	a := NewAuthenticator(NewConfig())
+	now := time.Unix(0, 0).Add(cachePruneInterval)
+	for i := 0; i < maxCachedUsers; i++ {
+		a.users[strconv.Itoa(i)] = &cachedUser{expires: now.Add(time.Duration(i+1) * time.Second)}
+	}
+	a.users["expired"] = &cachedUser{expires: now.Add(-cachePruneInterval)}
+
+	a.prune(now)
+	if _, ok := a.users["expired"]; ok {
+		t.Fatal("expected the expired user to be removed")
+	} else if n := len(a.users); n != maxCachedUsers-1 {
+		t.Fatalf("unexpected cached users: %d", n)
+	} else if _, ok := a.users["0"]; ok {
+		t.Fatal("expected the user expiring first to be removed")
+	}
+
+	// Users are kept for a while after they expire.
+	a.users["recent"] = &cachedUser{expires: now.Add(cachePruneInterval / 2)}
+	a.prune(now.Add(cachePruneInterval))
+	if _, ok := a.users["recent"]; !ok {
+		t.Fatal("expected the recently expired user to be kept")
+	}
+}
+
+func TestAuthenticator_Authenticate_LocalUser(t *testing.T) {
+	d := &directory{}
+	a := NewTestAuthenticator(t, d, func(c *Config) {
+		c.UserDNTemplate = "uid=%s,ou=people,dc=example,dc=com"
+	})
+	defer a.Close()
+
+	a.MetaClient = &internal.MetaClientMock{
+		AuthenticateFn: func(username, password string) (meta.User, error) {
+			if username == "admin" {
+				return &meta.UserInfo{Name: "admin", Admin: true}, nil
+			}
+			return nil, meta.ErrUserNotFound
+		},
+	}
+
+	if u, err := a.Authenticate("admin", "admin"); err != nil {
+		t.Fatal(err)
+	} else if !u.IsAdmin() {
+		t.Fatal("expected local admin user")
+	} else if n := atomic.LoadInt64(&d.binds); n != 0 {
+		t.Fatalf("unexpected binds: %d", n)
+	}
+}
+
+// Ensure binds are sent after the connection is upgraded with StartTLS.
+func TestAuthenticator_Authenticate_StartTLS(t *testing.T) {
+	d := &directory{
+		passwords: map[string]string{"uid=alice,ou=people,dc=example,dc=com": "secret"},
+		tls:       NewTestTLSConfig(t),
+	}
+	a := NewTestAuthenticator(t, d, func(c *Config) {
+		c.UserDNTemplate = "uid=%s,ou=people,dc=example,dc=com"
+		c.StartTLS = true
+		c.InsecurePlaintext = false
+		c.InsecureSkipVerify = true
+	})
+	defer a.Close()
+
+	if _, err := a.Authenticate("alice", "secret"); err != nil {
+		t.Fatal(err)
+	} else if n := atomic.LoadInt64(&d.binds); n != 1 {
+		t.Fatalf("unexpected binds: %d", n)
+	} else if n := atomic.LoadInt64(&d.tlsBinds); n != 1 {
+		t.Fatalf("unexpected binds over tls: %d", n)
+	}
+}
+
+func TestEscapeDN(t *testing.T) {
+	for s, exp := range map[string]string{
+		"alice":        "alice",
+		"a,b":          `a\,b`,
+		"x=y+z":        `x\=y\+z`,
+		" #leading":    `\ #leading`,
+		"#hash":        `\#hash`,
+		"trailing ":    `trailing\ `,
+		`quote"slash\`: `quote\"slash\\`,
+	} {
+		if got := escapeDN(s); got != exp {
+			t.Errorf("escapeDN(%q) = %q, expected %q", s, got, exp)
+		}
+	}
+}
+
+// NewTestAuthenticator returns an open Authenticator for a test directory.
+func NewTestAuthenticator(t *testing.T, d *directory, fn func(c *Config)) *Authenticator {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go d.listen(ln)
+
+	c := NewConfig()
+	c.Enabled = true
+	c.URL = "ldap://" + ln.Addr().String()
+	c.InsecurePlaintext = true
+	fn(&c)
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	a := NewAuthenticator(c)
+	if err := a.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// directory is a minimal in-memory LDAP server.
+type directory struct {
+	passwords map[string]string   // dn -> password
+	users     map[string]string   // uid -> dn
+	groups    map[string][]string // group dn -> member dns
+	tls       *tls.Config         // accepts StartTLS if set
+	binds     int64
+	tlsBinds  int64
+}
+
+// NewTestTLSConfig returns a server TLS config with a self-signed certificate.
+func NewTestTLSConfig(t *testing.T) *tls.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{cert}, PrivateKey: key}}}
+}
+
+func (d *directory) listen(ln net.Listener) {
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go d.serve(c)
+	}
+}
+
+func (d *directory) serve(nc net.Conn) {
+	defer nc.Close()
+
+	secure := false
+	r := bufio.NewReader(nc)
+	for {
+		p, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		id, _ := p.children[0].int()
+		op := p.children[1]
+
+		reply := func(b []byte) {
+			nc.Write(encodeSeq(tagSequence, encodeInt(tagInteger, id), b))
+		}
+		done := func(tag byte, code int64) {
+			reply(encodeSeq(tag,
+				encodeInt(tagEnumerated, code),
+				encodeString(tagOctetString, ""),
+				encodeString(tagOctetString, ""),
+			))
+		}
+
+		switch op.tag {
+		case opExtendedRequest:
+			if d.tls == nil || secure || string(op.children[0].value) != oidStartTLS {
+				done(opExtendedResponse, 2) // protocolError
+				continue
+			}
+			done(opExtendedResponse, resultSuccess)
+			tc := tls.Server(nc, d.tls)
+			defer tc.Close()
+			nc, r, secure = tc, bufio.NewReader(tc), true
+		case opBindRequest:
+			atomic.AddInt64(&d.binds, 1)
+			if secure {
+				atomic.AddInt64(&d.tlsBinds, 1)
+			}
+			dn, password := string(op.children[1].value), string(op.children[2].value)
+			if want, ok := d.passwords[dn]; ok && want == password {
+				done(opBindResponse, resultSuccess)
+			} else {
+				done(opBindResponse, resultInvalidCredentials)
+			}
+		case opSearchRequest:
+			base := string(op.children[0].value)
+			filter := op.children[6]
+			attr, value := string(filter.children[0].value), string(filter.children[1].value)
+
+			var dns []string
+			switch attr {
+			case DefaultUserAttribute:
+				if dn, ok := d.users[value]; ok {
+					dns = append(dns, dn)
+				}
+			case DefaultGroupMemberAttribute:
+				for dn, members := range d.groups {
+					for _, m := range members {
+						if m == value {
+							dns = append(dns, dn)
+						}
+					}
+				}
+			}
+
+			for _, dn := range dns {
+				if strings.HasSuffix(dn, base) {
+					reply(encodeSeq(opSearchResultEntry,
+						encodeString(tagOctetString, dn),
+						encodeSeq(tagSequence),
+					))
+				}
+			}
+			done(opSearchResultDone, resultSuccess)
+		case opUnbindRequest:
+			return
+		}
+	}
+}
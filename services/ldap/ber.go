@@ -0,0 +1,174 @@
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// BER tag classes and the universal types used by LDAP messages.
+const (
+	classApplication = 0x40
+	classContext     = 0x80
+	typeConstructed  = 0x20
+
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+)
+
+// maxPacketSize limits the size of a single message read from the server.
+const maxPacketSize = 16 << 20
+
+var (
+	errInvalidLength = errors.New("ldap: invalid BER length")
+	errMalformed     = errors.New("ldap: malformed message")
+)
+
+// packet is a decoded BER element. Constructed elements have their children
+// decoded as well.
+type packet struct {
+	tag      byte
+	value    []byte
+	children []*packet
+}
+
+// int returns the value of an INTEGER or ENUMERATED element.
+func (p *packet) int() (int64, error) {
+	if len(p.value) == 0 || len(p.value) > 8 {
+		return 0, errMalformed
+	}
+	v := int64(int8(p.value[0]))
+	for _, b := range p.value[1:] {
+		v = v<<8 | int64(b)
+	}
+	return v, nil
+}
+
+// encode returns the BER encoding of an element with the given tag and value.
+func encode(tag byte, value []byte) []byte {
+	n := len(value)
+	b := make([]byte, 0, n+6)
+	if n < 0x80 {
+		b = append(b, tag, byte(n))
+	} else {
+		var l []byte
+		for x := n; x > 0; x >>= 8 {
+			l = append([]byte{byte(x)}, l...)
+		}
+		b = append(b, tag, 0x80|byte(len(l)))
+		b = append(b, l...)
+	}
+	return append(b, value...)
+}
+
+// encodeInt returns the minimal two's complement encoding of v.
+func encodeInt(tag byte, v int64) []byte {
+	n := 1
+	for x := v; x > 127 || x < -128; x >>= 8 {
+		n++
+	}
+	b := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+	return encode(tag, b)
+}
+
+func encodeString(tag byte, s string) []byte {
+	return encode(tag, []byte(s))
+}
+
+func encodeBool(tag byte, v bool) []byte {
+	if v {
+		return encode(tag, []byte{0xff})
+	}
+	return encode(tag, []byte{0x00})
+}
+
+// encodeSeq returns a constructed element containing the encoded parts.
+func encodeSeq(tag byte, parts ...[]byte) []byte {
+	return encode(tag, bytes.Join(parts, nil))
+}
+
+// readPacket reads and decodes a single element from r.
+func readPacket(r *bufio.Reader) (*packet, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	n, err := readLength(r)
+	if err != nil {
+		return nil, err
+	} else if n > maxPacketSize {
+		return nil, errInvalidLength
+	}
+
+	value := make([]byte, n)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return nil, err
+	}
+	return parsePacket(tag, value)
+}
+
+// readLength reads a definite length. LDAP does not allow the indefinite form.
+func readLength(r io.ByteReader) (int, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	} else if b < 0x80 {
+		return int(b), nil
+	}
+
+	k := int(b & 0x7f)
+	if k == 0 || k > 4 {
+		return 0, errInvalidLength
+	}
+	n := 0
+	for i := 0; i < k; i++ {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		n = n<<8 | int(c)
+	}
+	return n, nil
+}
+
+// parsePacket decodes value and, for constructed elements, its children.
+func parsePacket(tag byte, value []byte) (*packet, error) {
+	p := &packet{tag: tag, value: value}
+	if tag&typeConstructed == 0 {
+		return p, nil
+	}
+
+	r := bytes.NewReader(value)
+	for r.Len() > 0 {
+		ctag, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		n, err := readLength(r)
+		if err != nil {
+			return nil, err
+		} else if n > r.Len() {
+			return nil, errMalformed
+		}
+
+		off := len(value) - r.Len()
+		child, err := parsePacket(ctag, value[off:off+n])
+		if err != nil {
+			return nil, err
+		}
+		p.children = append(p.children, child)
+
+		if _, err := r.Seek(int64(n), io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
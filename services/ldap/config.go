@@ -0,0 +1,178 @@
+// Package ldap authenticates users against an LDAP directory.
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/influxql"
+)
+
+const (
+	// DefaultUserAttribute is the attribute matched against the username
+	// when searching for a user.
+	DefaultUserAttribute = "uid"
+
+	// DefaultGroupMemberAttribute is the group attribute that lists the DNs
+	// of its members.
+	DefaultGroupMemberAttribute = "member"
+
+	// DefaultPoolSize is the number of idle directory connections kept open.
+	DefaultPoolSize = 4
+
+	// DefaultCacheTTL is how long a successful authentication is reused
+	// before the directory is asked again.
+	DefaultCacheTTL = 5 * time.Minute
+
+	// DefaultTimeout is the timeout for a single directory operation.
+	DefaultTimeout = 10 * time.Second
+)
+
+// Config represents the configuration for LDAP authentication.
+type Config struct {
+	Enabled            bool   `toml:"enabled"`
+	URL                string `toml:"url"`
+	InsecureSkipVerify bool   `toml:"insecure-skip-verify"`
+
+	// StartTLS upgrades connections to an ldap:// URL to TLS before binding.
+	// Without it, passwords are sent to an ldap:// URL in clear text, which
+	// is only allowed if InsecurePlaintext is set.
+	StartTLS          bool `toml:"start-tls"`
+	InsecurePlaintext bool `toml:"insecure-plaintext"`
+
+	// BindDN and BindPassword are the credentials of the service account used
+	// to look up users and groups. When they are not set, UserDNTemplate is
+	// used to build the DN of the user directly.
+	BindDN         string `toml:"bind-dn"`
+	BindPassword   string `toml:"bind-password"`
+	UserDNTemplate string `toml:"user-dn-template"`
+
+	UserBaseDN           string `toml:"user-base-dn"`
+	UserAttribute        string `toml:"user-attribute"`
+	GroupBaseDN          string `toml:"group-base-dn"`
+	GroupMemberAttribute string `toml:"group-member-attribute"`
+
+	Groups []GroupConfig `toml:"group"`
+
+	PoolSize int           `toml:"pool-size"`
+	CacheTTL toml.Duration `toml:"cache-ttl"`
+	Timeout  toml.Duration `toml:"timeout"`
+}
+
+// GroupConfig maps the members of a directory group to privileges.
+type GroupConfig struct {
+	DN        string `toml:"dn"`
+	Admin     bool   `toml:"admin"`
+	Database  string `toml:"database"`
+	Privilege string `toml:"privilege"`
+}
+
+// NewConfig returns an instance of Config with defaults.
+func NewConfig() Config {
+	return Config{
+		UserAttribute:        DefaultUserAttribute,
+		GroupMemberAttribute: DefaultGroupMemberAttribute,
+		PoolSize:             DefaultPoolSize,
+		CacheTTL:             toml.Duration(DefaultCacheTTL),
+		Timeout:              toml.Duration(DefaultTimeout),
+	}
+}
+
+// Validate returns an error if the Config is invalid.
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %s", err)
+	} else if u.Scheme != "ldap" && u.Scheme != "ldaps" {
+		return fmt.Errorf("url scheme must be ldap or ldaps: %q", c.URL)
+	} else if u.Hostname() == "" {
+		return fmt.Errorf("url must contain a host: %q", c.URL)
+	} else if u.Scheme == "ldaps" && c.StartTLS {
+		return errors.New("start-tls cannot be used with an ldaps url")
+	} else if u.Scheme == "ldap" && !c.StartTLS && !c.InsecurePlaintext {
+		return errors.New("an ldap url sends passwords in clear text: set start-tls, or insecure-plaintext to allow it")
+	}
+
+	if c.BindDN != "" {
+		if c.UserBaseDN == "" {
+			return errors.New("user-base-dn is required when bind-dn is set")
+		} else if c.UserAttribute == "" {
+			return errors.New("user-attribute is required when bind-dn is set")
+		}
+	} else if strings.Count(c.UserDNTemplate, "%s") != 1 {
+		return errors.New("user-dn-template must contain exactly one %s when bind-dn is not set")
+	}
+
+	if len(c.Groups) > 0 {
+		if c.GroupBaseDN == "" {
+			return errors.New("group-base-dn is required when groups are mapped")
+		} else if c.GroupMemberAttribute == "" {
+			return errors.New("group-member-attribute is required when groups are mapped")
+		}
+	}
+	for _, g := range c.Groups {
+		if g.DN == "" {
+			return errors.New("group dn is required")
+		}
+		if g.Database == "" {
+			if !g.Admin {
+				return fmt.Errorf("group %q must set admin or a database", g.DN)
+			}
+			continue
+		}
+		if _, err := parsePrivilege(g.Privilege); err != nil {
+			return fmt.Errorf("group %q: %s", g.DN, err)
+		}
+	}
+
+	if c.PoolSize < 0 {
+		return errors.New("pool-size must not be negative")
+	} else if c.CacheTTL < 0 {
+		return errors.New("cache-ttl must not be negative")
+	} else if c.Timeout <= 0 {
+		return errors.New("timeout must be positive")
+	}
+	return nil
+}
+
+// Diagnostics returns a diagnostics representation of a subset of the Config.
+func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
+	if !c.Enabled {
+		return diagnostics.RowFromMap(map[string]interface{}{
+			"enabled": false,
+		}), nil
+	}
+
+	return diagnostics.RowFromMap(map[string]interface{}{
+		"enabled":   true,
+		"url":       c.URL,
+		"start-tls": c.StartTLS,
+		"bind-dn":   c.BindDN,
+		"groups":    len(c.Groups),
+		"pool-size": c.PoolSize,
+		"cache-ttl": c.CacheTTL,
+		"timeout":   c.Timeout,
+	}), nil
+}
+
+// parsePrivilege returns the privilege named by s.
+func parsePrivilege(s string) (influxql.Privilege, error) {
+	switch strings.ToUpper(s) {
+	case "READ":
+		return influxql.ReadPrivilege, nil
+	case "WRITE":
+		return influxql.WritePrivilege, nil
+	case "ALL":
+		return influxql.AllPrivileges, nil
+	}
+	return influxql.NoPrivileges, fmt.Errorf("invalid privilege: %q", s)
+}
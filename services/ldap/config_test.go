@@ -0,0 +1,95 @@
+package ldap_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/influxdb/services/ldap"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	c := ldap.NewConfig()
+	if _, err := toml.Decode(`
+enabled = true
+url = "ldaps://ldap.example.com"
+bind-dn = "cn=influxdb,dc=example,dc=com"
+bind-password = "secret"
+user-base-dn = "ou=people,dc=example,dc=com"
+group-base-dn = "ou=groups,dc=example,dc=com"
+cache-ttl = "1m"
+
+[[group]]
+dn = "cn=admins,ou=groups,dc=example,dc=com"
+admin = true
+
+[[group]]
+dn = "cn=telegraf,ou=groups,dc=example,dc=com"
+database = "telegraf"
+privilege = "write"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if !c.Enabled {
+		t.Fatalf("unexpected enabled: %v", c.Enabled)
+	} else if c.URL != "ldaps://ldap.example.com" {
+		t.Fatalf("unexpected url: %s", c.URL)
+	} else if c.BindDN != "cn=influxdb,dc=example,dc=com" {
+		t.Fatalf("unexpected bind dn: %s", c.BindDN)
+	} else if c.UserAttribute != ldap.DefaultUserAttribute {
+		t.Fatalf("unexpected user attribute: %s", c.UserAttribute)
+	} else if time.Duration(c.CacheTTL) != time.Minute {
+		t.Fatalf("unexpected cache ttl: %s", c.CacheTTL)
+	} else if len(c.Groups) != 2 {
+		t.Fatalf("unexpected groups: %d", len(c.Groups))
+	} else if !c.Groups[0].Admin {
+		t.Fatalf("unexpected group admin: %v", c.Groups[0].Admin)
+	} else if c.Groups[1].Database != "telegraf" || c.Groups[1].Privilege != "write" {
+		t.Fatalf("unexpected group: %#v", c.Groups[1])
+	} else if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validate error: %s", err)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		fn   func(c *ldap.Config)
+		err  bool
+	}{
+		{name: "template", fn: func(c *ldap.Config) {}},
+		{name: "disabled", fn: func(c *ldap.Config) { c.Enabled = false; c.URL = "" }},
+		{name: "scheme", fn: func(c *ldap.Config) { c.URL = "http://ldap.example.com" }, err: true},
+		{name: "plaintext", fn: func(c *ldap.Config) { c.StartTLS = false }, err: true},
+		{name: "insecure plaintext", fn: func(c *ldap.Config) { c.StartTLS = false; c.InsecurePlaintext = true }},
+		{name: "ldaps", fn: func(c *ldap.Config) { c.URL = "ldaps://ldap.example.com"; c.StartTLS = false }},
+		{name: "start-tls with ldaps", fn: func(c *ldap.Config) { c.URL = "ldaps://ldap.example.com" }, err: true},
+		{name: "no template", fn: func(c *ldap.Config) { c.UserDNTemplate = "uid=alice" }, err: true},
+		{name: "no user base", fn: func(c *ldap.Config) { c.BindDN = "cn=influxdb" }, err: true},
+		{name: "no group base", fn: func(c *ldap.Config) {
+			c.Groups = []ldap.GroupConfig{{DN: "cn=admins", Admin: true}}
+		}, err: true},
+		{name: "privilege", fn: func(c *ldap.Config) {
+			c.GroupBaseDN = "ou=groups"
+			c.Groups = []ldap.GroupConfig{{DN: "cn=telegraf", Database: "telegraf", Privilege: "owner"}}
+		}, err: true},
+		{name: "no grant", fn: func(c *ldap.Config) {
+			c.GroupBaseDN = "ou=groups"
+			c.Groups = []ldap.GroupConfig{{DN: "cn=telegraf"}}
+		}, err: true},
+	} {
+		c := ldap.NewConfig()
+		c.Enabled = true
+		c.URL = "ldap://ldap.example.com"
+		c.StartTLS = true
+		c.UserDNTemplate = "uid=%s,ou=people,dc=example,dc=com"
+		tt.fn(&c)
+
+		if err := c.Validate(); (err != nil) != tt.err {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+	}
+}
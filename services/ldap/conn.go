@@ -0,0 +1,254 @@
+package ldap
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// LDAP protocol operations used by the authenticator.
+const (
+	opBindRequest           = classApplication | typeConstructed | 0
+	opBindResponse          = classApplication | typeConstructed | 1
+	opUnbindRequest         = classApplication | 2
+	opSearchRequest         = classApplication | typeConstructed | 3
+	opSearchResultEntry     = classApplication | typeConstructed | 4
+	opSearchResultDone      = classApplication | typeConstructed | 5
+	opSearchResultReference = classApplication | typeConstructed | 19
+	opExtendedRequest       = classApplication | typeConstructed | 23
+	opExtendedResponse      = classApplication | typeConstructed | 24
+
+	authSimple          = classContext | 0
+	filterEquality      = classContext | typeConstructed | 3
+	extendedRequestName = classContext | 0
+)
+
+const (
+	protocolVersion = 3
+
+	resultSuccess            = 0
+	resultInvalidCredentials = 49
+
+	scopeWholeSubtree = 2
+	derefNever        = 0
+
+	// noAttributes requests that search results carry no attributes.
+	noAttributes = "1.1"
+
+	// oidStartTLS names the extended operation that upgrades a connection
+	// to TLS.
+	oidStartTLS = "1.3.6.1.4.1.1466.20037"
+)
+
+var errUnexpectedResponse = errors.New("ldap: unexpected response")
+
+// resultError is a non-success result returned by the server. The
+// connection remains usable after a result error.
+type resultError struct {
+	code    int64
+	message string
+}
+
+func (e *resultError) Error() string {
+	return fmt.Sprintf("ldap: result code %d: %s", e.code, e.message)
+}
+
+// conn is a single connection to the directory.
+type conn struct {
+	nc      net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+	id      int64
+}
+
+// dial opens a connection to the server at u. A connection to an ldap://
+// URL is upgraded to TLS with StartTLS if startTLS is set.
+func dial(u *url.URL, timeout time.Duration, startTLS, insecureSkipVerify bool) (*conn, error) {
+	d := &net.Dialer{Timeout: timeout}
+	config := &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+
+	var nc net.Conn
+	var err error
+	switch u.Scheme {
+	case "ldaps":
+		host := u.Host
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "636")
+		}
+		nc, err = tls.DialWithDialer(d, "tcp", host, config)
+	default:
+		host := u.Host
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "389")
+		}
+		nc, err = d.Dial("tcp", host)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &conn{nc: nc, r: bufio.NewReader(nc), timeout: timeout}
+	if startTLS && u.Scheme != "ldaps" {
+		if err := c.startTLS(config); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// startTLS upgrades the connection to TLS with the StartTLS extended
+// operation. No other operation may be in progress.
+func (c *conn) startTLS(config *tls.Config) error {
+	id, err := c.send(encodeSeq(opExtendedRequest,
+		encodeString(extendedRequestName, oidStartTLS),
+	))
+	if err != nil {
+		return err
+	}
+
+	op, err := c.receive(id)
+	if err != nil {
+		return err
+	} else if op.tag != opExtendedResponse {
+		return errUnexpectedResponse
+	} else if err := result(op); err != nil {
+		return err
+	}
+
+	// The deadline set by send also bounds the handshake.
+	tc := tls.Client(c.nc, config)
+	if err := tc.Handshake(); err != nil {
+		return err
+	}
+	c.nc, c.r = tc, bufio.NewReader(tc)
+	return nil
+}
+
+// send writes op as a new message and returns its message ID.
+func (c *conn) send(op []byte) (int64, error) {
+	c.id++
+	if c.timeout > 0 {
+		c.nc.SetDeadline(time.Now().Add(c.timeout))
+	}
+	if _, err := c.nc.Write(encodeSeq(tagSequence, encodeInt(tagInteger, c.id), op)); err != nil {
+		return 0, err
+	}
+	return c.id, nil
+}
+
+// receive returns the protocol operation of the next message for id.
+func (c *conn) receive(id int64) (*packet, error) {
+	for {
+		p, err := readPacket(c.r)
+		if err != nil {
+			return nil, err
+		} else if p.tag != tagSequence || len(p.children) < 2 {
+			return nil, errMalformed
+		}
+
+		msgID, err := p.children[0].int()
+		if err != nil {
+			return nil, err
+		} else if msgID == 0 {
+			// Unsolicited notifications are only sent before the server
+			// drops the connection.
+			return nil, errors.New("ldap: connection closed by server")
+		} else if msgID != id {
+			continue
+		}
+		return p.children[1], nil
+	}
+}
+
+// bind authenticates the connection as dn with a simple bind.
+func (c *conn) bind(dn, password string) error {
+	id, err := c.send(encodeSeq(opBindRequest,
+		encodeInt(tagInteger, protocolVersion),
+		encodeString(tagOctetString, dn),
+		encodeString(authSimple, password),
+	))
+	if err != nil {
+		return err
+	}
+
+	op, err := c.receive(id)
+	if err != nil {
+		return err
+	} else if op.tag != opBindResponse {
+		return errUnexpectedResponse
+	}
+	return result(op)
+}
+
+// search returns the DNs of the entries below base whose attr equals value.
+func (c *conn) search(base, attr, value string) ([]string, error) {
+	id, err := c.send(encodeSeq(opSearchRequest,
+		encodeString(tagOctetString, base),
+		encodeInt(tagEnumerated, scopeWholeSubtree),
+		encodeInt(tagEnumerated, derefNever),
+		encodeInt(tagInteger, 0),
+		encodeInt(tagInteger, int64(c.timeout/time.Second)),
+		encodeBool(tagBoolean, false),
+		encodeSeq(filterEquality,
+			encodeString(tagOctetString, attr),
+			encodeString(tagOctetString, value),
+		),
+		encodeSeq(tagSequence, encodeString(tagOctetString, noAttributes)),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	var dns []string
+	for {
+		op, err := c.receive(id)
+		if err != nil {
+			return nil, err
+		}
+
+		switch op.tag {
+		case opSearchResultEntry:
+			if len(op.children) == 0 {
+				return nil, errMalformed
+			}
+			dns = append(dns, string(op.children[0].value))
+		case opSearchResultReference:
+			// Referrals to other servers are not followed.
+		case opSearchResultDone:
+			if err := result(op); err != nil {
+				return nil, err
+			}
+			return dns, nil
+		default:
+			return nil, errUnexpectedResponse
+		}
+	}
+}
+
+// close unbinds and closes the connection.
+func (c *conn) close() error {
+	c.send(encode(opUnbindRequest, nil))
+	return c.nc.Close()
+}
+
+// result returns the error held by an LDAPResult, if any.
+func result(op *packet) error {
+	if len(op.children) < 3 {
+		return errMalformed
+	}
+	code, err := op.children[0].int()
+	if err != nil {
+		return err
+	} else if code == resultSuccess {
+		return nil
+	}
+	return &resultError{code: code, message: string(op.children[2].value)}
+}
@@ -92,6 +92,8 @@ func (c *Client) Open() error {
 	// Try to load from disk
 	if err := c.Load(); err != nil {
 		return err
+	} else if err := c.importTokensFile(); err != nil {
+		return err
 	}
 
 	// If this is a brand new instance, persist to disk immediatly.
@@ -123,6 +125,21 @@ func (c *Client) Close() error {
 	return nil
 }
 
+// Ready returns an error if the client is closed or its meta directory can
+// no longer be read.
+func (c *Client) Ready() error {
+	select {
+	case <-c.closing:
+		return errors.New("meta client is closed")
+	default:
+	}
+
+	if _, err := os.Stat(c.path); err != nil {
+		return err
+	}
+	return nil
+}
+
 // AcquireLease attempts to acquire the specified lease.
 // TODO corylanou remove this for single node
 func (c *Client) AcquireLease(name string) (*Lease, error) {
@@ -758,6 +758,131 @@ func TestMetaClient_UpdateUser(t *testing.T) {
 	}
 }
 
+func TestMetaClient_Tokens(t *testing.T) {
+	t.Parallel()
+
+	d, c := newClient()
+	defer os.RemoveAll(d)
+	defer c.Close()
+
+	if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.CreateRetentionPolicy("db0", &meta.RetentionPolicySpec{Name: "rp1"}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := c.CreateToken("db0", "", influxql.AllPrivileges); err != meta.ErrTokenPrivilege {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, _, err := c.CreateToken("db0", "missing", influxql.ReadPrivilege); err == nil {
+		t.Fatal("expected error for a missing retention policy")
+	}
+
+	// A write token on rp1 cannot write to the default retention policy or
+	// read at all.
+	wt, wsecret, err := c.CreateToken("db0", "rp1", influxql.WritePrivilege)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := c.AuthenticateToken(wsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := u.(*meta.TokenUser)
+	if err := writer.AuthorizeWrite("db0", "rp1"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := writer.AuthorizeWrite("db0", ""); err == nil {
+		t.Fatal("expected error writing to the default retention policy")
+	} else if err := writer.AuthorizeQuery("db0", influxql.MustParseQuery(`SELECT * FROM rp1.cpu`)); err == nil {
+		t.Fatal("expected error reading with a write token")
+	}
+
+	// A read token can only run statements reading from its database.
+	_, rsecret, err := c.CreateToken("db0", "", influxql.ReadPrivilege)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := c.AuthenticateToken(rsecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reader.AuthorizeQuery("db0", influxql.MustParseQuery(`SELECT * FROM cpu`)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if err := reader.AuthorizeQuery("db0", influxql.MustParseQuery(`DROP SERIES FROM cpu`)); err == nil {
+		t.Fatal("expected error deleting with a read token")
+	} else if err := reader.AuthorizeQuery("db1", influxql.MustParseQuery(`SELECT * FROM cpu`)); err == nil {
+		t.Fatal("expected error reading another database")
+	}
+
+	if _, err := c.AuthenticateToken(wt.ID + ".wrong"); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Tokens are saved with the meta data and loaded again.
+	cfg := meta.NewConfig()
+	cfg.Dir = d
+	other := meta.NewClient(cfg)
+	if err := other.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if n := len(other.Tokens()); n != 2 {
+		t.Fatalf("unexpected number of tokens: %d", n)
+	}
+
+	if err := c.DropToken(wt.ID); err != nil {
+		t.Fatal(err)
+	} else if err := c.DropToken(wt.ID); err != meta.ErrTokenNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := c.AuthenticateToken(wsecret); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Dropping the database drops its tokens, which do not authorize a new
+	// database of the same name.
+	if err := c.DropDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	} else if _, err := c.AuthenticateToken(rsecret); err != meta.ErrAuthenticate {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n := len(c.Tokens()); n != 0 {
+		t.Fatalf("unexpected number of tokens: %d", n)
+	}
+}
+
+// Ensure the tokens of the file they were kept in before are imported.
+func TestMetaClient_Tokens_ImportFile(t *testing.T) {
+	t.Parallel()
+
+	d, c := newClient()
+	defer os.RemoveAll(d)
+	if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	file := path.Join(d, "tokens.json")
+	tokens := `[{"id":"t1","database":"db0","privilege":1,"hash":"h1"},{"id":"t2","database":"dropped","privilege":1,"hash":"h2"}]`
+	if err := ioutil.WriteFile(file, []byte(tokens), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := meta.NewConfig()
+	cfg.Dir = d
+	c = meta.NewClient(cfg)
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	exp := []meta.TokenInfo{{ID: "t1", Database: "db0", Privilege: influxql.ReadPrivilege, Hash: "h1"}}
+	if got := c.Tokens(); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected tokens: %v", got)
+	} else if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("unexpected tokens file: %v", err)
+	}
+}
+
 func TestMetaClient_ContinuousQueries(t *testing.T) {
 	t.Parallel()
 
@@ -43,6 +43,7 @@ type Data struct {
 	ClusterID uint64
 	Databases []DatabaseInfo
 	Users     []UserInfo
+	Tokens    []TokenInfo
 
 	// adminUserExists provides a constant time mechanism for determining
 	// if there is at least one admin user.
@@ -100,6 +101,10 @@ func (data *Data) DropDatabase(name string) error {
 			for i := range data.Users {
 				delete(data.Users[i].Privileges, name)
 			}
+
+			// Tokens of the database would authorize a new database of
+			// the same name.
+			data.dropTokens(name)
 			break
 		}
 	}
@@ -678,6 +683,7 @@ func (data *Data) Clone() *Data {
 
 	other.Databases = data.CloneDatabases()
 	other.Users = data.CloneUsers()
+	other.Tokens = data.CloneTokens()
 
 	return &other
 }
@@ -706,6 +712,11 @@ func (data *Data) marshal() *internal.Data {
 		pb.Users[i] = data.Users[i].marshal()
 	}
 
+	pb.Tokens = make([]*internal.TokenInfo, len(data.Tokens))
+	for i := range data.Tokens {
+		pb.Tokens[i] = data.Tokens[i].marshal()
+	}
+
 	return pb
 }
 
@@ -728,6 +739,11 @@ func (data *Data) unmarshal(pb *internal.Data) {
 		data.Users[i].unmarshal(x)
 	}
 
+	data.Tokens = make([]TokenInfo, len(pb.GetTokens()))
+	for i, x := range pb.GetTokens() {
+		data.Tokens[i].unmarshal(x)
+	}
+
 	// Exhaustively determine if there is an admin user. The marshalled cache
 	// value may not be correct.
 	data.adminUserExists = data.hasAdminUser()
@@ -882,6 +898,8 @@ func (data *Data) importOneDB(other Data, backupDBName, restoreDBName, backupRPN
 		}
 	}
 
+	data.importTokens(other, backupDBName, restoreDBName, backupRPName, restoreRPName)
+
 	return restoreDBName, nil
 }
 
@@ -24,6 +24,10 @@ func Test_Data_DropDatabase(t *testing.T) {
 			{Name: "user1", Privileges: map[string]influxql.Privilege{"db1": influxql.ReadPrivilege, "db2": influxql.ReadPrivilege}},
 			{Name: "user2", Privileges: map[string]influxql.Privilege{"db2": influxql.ReadPrivilege}},
 		},
+		Tokens: []meta.TokenInfo{
+			{ID: "t1", Database: "db1", Privilege: influxql.ReadPrivilege},
+			{ID: "t2", Database: "db2", Privilege: influxql.WritePrivilege},
+		},
 	}
 
 	// Dropping the first database removes it from the Data object.
@@ -62,6 +66,60 @@ func Test_Data_DropDatabase(t *testing.T) {
 	} else if got, exp := data.Users, expUsers; !reflect.DeepEqual(got, exp) {
 		t.Fatalf("got %v, expected %v", got, exp)
 	}
+
+	// Dropping a database drops its API tokens.
+	expTokens := []meta.TokenInfo{{ID: "t1", Database: "db1", Privilege: influxql.ReadPrivilege}}
+	if got, exp := data.Tokens, expTokens; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("got %v, expected %v", got, exp)
+	}
+}
+
+// Ensure API tokens are part of the meta data, and restored with their
+// database.
+func TestData_Tokens_ImportData(t *testing.T) {
+	backup := meta.Data{
+		Databases: []meta.DatabaseInfo{
+			{Name: "db0", DefaultRetentionPolicy: "rp0", RetentionPolicies: []meta.RetentionPolicyInfo{{Name: "rp0"}, {Name: "rp1"}}},
+			{Name: "db1"},
+		},
+		Tokens: []meta.TokenInfo{
+			{ID: "t1", Database: "db0", Privilege: influxql.ReadPrivilege, Hash: "h1"},
+			{ID: "t2", Database: "db0", RetentionPolicy: "rp1", Privilege: influxql.WritePrivilege, Hash: "h2"},
+			{ID: "t3", Database: "db0", RetentionPolicy: "rp0", Privilege: influxql.WritePrivilege, Hash: "h3"},
+			{ID: "t4", Database: "db1", Privilege: influxql.ReadPrivilege, Hash: "h4"},
+		},
+	}
+
+	buf, err := backup.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snapshot meta.Data
+	if err := snapshot.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(snapshot.Tokens, backup.Tokens) {
+		t.Fatalf("unexpected tokens: %v", snapshot.Tokens)
+	}
+
+	// Restoring rp1 of db0 as db2.rp2 only restores the tokens allowed on it.
+	var data meta.Data
+	if _, _, err := data.ImportData(snapshot, "db0", "db2", "rp1", "rp2"); err != nil {
+		t.Fatal(err)
+	}
+	exp := []meta.TokenInfo{
+		{ID: "t1", Database: "db2", Privilege: influxql.ReadPrivilege, Hash: "h1"},
+		{ID: "t2", Database: "db2", RetentionPolicy: "rp2", Privilege: influxql.WritePrivilege, Hash: "h2"},
+	}
+	if !reflect.DeepEqual(data.Tokens, exp) {
+		t.Fatalf("unexpected tokens: %v", data.Tokens)
+	}
+
+	// Tokens already restored are not duplicated.
+	if _, _, err := data.ImportData(snapshot, "", "", "", ""); err != nil {
+		t.Fatal(err)
+	} else if got := len(data.Tokens); got != 4 {
+		t.Fatalf("unexpected tokens: %v", data.Tokens)
+	}
 }
 
 func Test_Data_CreateRetentionPolicy(t *testing.T) {
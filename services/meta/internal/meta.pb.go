@@ -196,9 +196,11 @@ type Data struct {
 	MaxShardGroupID *uint64         `protobuf:"varint,8,req,name=MaxShardGroupID" json:"MaxShardGroupID,omitempty"`
 	MaxShardID      *uint64         `protobuf:"varint,9,req,name=MaxShardID" json:"MaxShardID,omitempty"`
 	// added for 0.10.0
-	DataNodes        []*NodeInfo `protobuf:"bytes,10,rep,name=DataNodes" json:"DataNodes,omitempty"`
-	MetaNodes        []*NodeInfo `protobuf:"bytes,11,rep,name=MetaNodes" json:"MetaNodes,omitempty"`
-	XXX_unrecognized []byte      `json:"-"`
+	DataNodes []*NodeInfo `protobuf:"bytes,10,rep,name=DataNodes" json:"DataNodes,omitempty"`
+	MetaNodes []*NodeInfo `protobuf:"bytes,11,rep,name=MetaNodes" json:"MetaNodes,omitempty"`
+	// added for API tokens
+	Tokens           []*TokenInfo `protobuf:"bytes,12,rep,name=Tokens" json:"Tokens,omitempty"`
+	XXX_unrecognized []byte       `json:"-"`
 }
 
 func (m *Data) Reset()                    { *m = Data{} }
@@ -283,6 +285,13 @@ func (m *Data) GetMetaNodes() []*NodeInfo {
 	return nil
 }
 
+func (m *Data) GetTokens() []*TokenInfo {
+	if m != nil {
+		return m.Tokens
+	}
+	return nil
+}
+
 type NodeInfo struct {
 	ID               *uint64 `protobuf:"varint,1,req,name=ID" json:"ID,omitempty"`
 	Host             *string `protobuf:"bytes,2,req,name=Host" json:"Host,omitempty"`
@@ -1815,6 +1824,54 @@ var E_DropShardCommand_Command = &proto.ExtensionDesc{
 	Filename:      "internal/meta.proto",
 }
 
+type TokenInfo struct {
+	ID               *string `protobuf:"bytes,1,req,name=ID" json:"ID,omitempty"`
+	Database         *string `protobuf:"bytes,2,req,name=Database" json:"Database,omitempty"`
+	RetentionPolicy  *string `protobuf:"bytes,3,opt,name=RetentionPolicy" json:"RetentionPolicy,omitempty"`
+	Privilege        *int32  `protobuf:"varint,4,req,name=Privilege" json:"Privilege,omitempty"`
+	Hash             *string `protobuf:"bytes,5,req,name=Hash" json:"Hash,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *TokenInfo) Reset()         { *m = TokenInfo{} }
+func (m *TokenInfo) String() string { return proto.CompactTextString(m) }
+func (*TokenInfo) ProtoMessage()    {}
+
+func (m *TokenInfo) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return ""
+}
+
+func (m *TokenInfo) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *TokenInfo) GetRetentionPolicy() string {
+	if m != nil && m.RetentionPolicy != nil {
+		return *m.RetentionPolicy
+	}
+	return ""
+}
+
+func (m *TokenInfo) GetPrivilege() int32 {
+	if m != nil && m.Privilege != nil {
+		return *m.Privilege
+	}
+	return 0
+}
+
+func (m *TokenInfo) GetHash() string {
+	if m != nil && m.Hash != nil {
+		return *m.Hash
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*Data)(nil), "meta.Data")
 	proto.RegisterType((*NodeInfo)(nil), "meta.NodeInfo")
@@ -1859,6 +1916,7 @@ func init() {
 	proto.RegisterType((*Response)(nil), "meta.Response")
 	proto.RegisterType((*SetMetaNodeCommand)(nil), "meta.SetMetaNodeCommand")
 	proto.RegisterType((*DropShardCommand)(nil), "meta.DropShardCommand")
+	proto.RegisterType((*TokenInfo)(nil), "meta.TokenInfo")
 	proto.RegisterEnum("meta.Command_Type", Command_Type_name, Command_Type_value)
 	proto.RegisterExtension(E_CreateNodeCommand_Command)
 	proto.RegisterExtension(E_DeleteNodeCommand_Command)
@@ -21,10 +21,11 @@ func NewQueryAuthorizer(c *Client) *QueryAuthorizer {
 // AuthorizeQuery authorizes u to execute q on database.
 // Database can be "" for queries that do not require a database.
 // If no user is provided it will return an error unless the query's first statement is to create
-// a root user.
+// a root user. Users authenticated elsewhere, such as by an LDAP directory, are authorized by their
+// privileges even if no user exists in the meta store.
 func (a *QueryAuthorizer) AuthorizeQuery(u User, query *influxql.Query, database string) error {
 	// Special case if no users exist.
-	if n := a.Client.UserCount(); n == 0 {
+	if u == nil && a.Client.UserCount() == 0 {
 		// Ensure there is at least one statement.
 		if len(query.Statements) > 0 {
 			// First statement in the query must create a user with admin privilege.
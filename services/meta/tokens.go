@@ -0,0 +1,386 @@
+package meta
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	internal "github.com/influxdata/influxdb/services/meta/internal"
+	"github.com/influxdata/influxql"
+)
+
+// tokensFile is the file API tokens were kept in before they were part of
+// the meta data. It is imported and removed when the client opens.
+const tokensFile = "tokens.json"
+
+var (
+	// ErrTokenNotFound is returned when dropping a token that doesn't exist.
+	ErrTokenNotFound = errors.New("token not found")
+
+	// ErrTokenPrivilege is returned when creating a token with a privilege
+	// other than READ or WRITE.
+	ErrTokenPrivilege = errors.New("token privilege must be READ or WRITE")
+)
+
+// TokenInfo represents an API token, which can only read from or only write
+// to a database, or a single retention policy of it.
+type TokenInfo struct {
+	ID              string             `json:"id"`
+	Database        string             `json:"database"`
+	RetentionPolicy string             `json:"retentionPolicy,omitempty"`
+	Privilege       influxql.Privilege `json:"privilege"`
+
+	// Hash is the SHA-256 hash of the secret of the token. Tokens are random,
+	// so a salted slow hash like the one of passwords is not needed.
+	Hash string `json:"hash"`
+}
+
+// marshal serializes to a protobuf representation.
+func (ti TokenInfo) marshal() *internal.TokenInfo {
+	return &internal.TokenInfo{
+		ID:              proto.String(ti.ID),
+		Database:        proto.String(ti.Database),
+		RetentionPolicy: proto.String(ti.RetentionPolicy),
+		Privilege:       proto.Int32(int32(ti.Privilege)),
+		Hash:            proto.String(ti.Hash),
+	}
+}
+
+// unmarshal deserializes from a protobuf representation.
+func (ti *TokenInfo) unmarshal(pb *internal.TokenInfo) {
+	ti.ID = pb.GetID()
+	ti.Database = pb.GetDatabase()
+	ti.RetentionPolicy = pb.GetRetentionPolicy()
+	ti.Privilege = influxql.Privilege(pb.GetPrivilege())
+	ti.Hash = pb.GetHash()
+}
+
+// CloneTokens returns a copy of the TokenInfo.
+func (data *Data) CloneTokens() []TokenInfo {
+	if data.Tokens == nil {
+		return nil
+	}
+	tokens := make([]TokenInfo, len(data.Tokens))
+	copy(tokens, data.Tokens)
+	return tokens
+}
+
+// CreateToken adds the API token t.
+func (data *Data) CreateToken(t TokenInfo) error {
+	db := data.Database(t.Database)
+	if db == nil {
+		return influxdb.ErrDatabaseNotFound(t.Database)
+	} else if t.RetentionPolicy != "" && db.RetentionPolicy(t.RetentionPolicy) == nil {
+		return influxdb.ErrRetentionPolicyNotFound(t.RetentionPolicy)
+	}
+	data.Tokens = append(data.Tokens, t)
+	return nil
+}
+
+// DropToken removes the API token id.
+func (data *Data) DropToken(id string) error {
+	for i := range data.Tokens {
+		if data.Tokens[i].ID == id {
+			data.Tokens = append(data.Tokens[:i], data.Tokens[i+1:]...)
+			return nil
+		}
+	}
+	return ErrTokenNotFound
+}
+
+// dropTokens removes the API tokens of database.
+func (data *Data) dropTokens(database string) {
+	tokens := data.Tokens[:0]
+	for _, t := range data.Tokens {
+		if t.Database != database {
+			tokens = append(tokens, t)
+		}
+	}
+	data.Tokens = tokens
+}
+
+// importTokens imports the API tokens of the database backupDBName of other,
+// or only of its retention policy backupRPName if it is not empty, as tokens
+// of the restored database. Tokens already known are skipped, so restoring a
+// copy of a database next to it keeps the tokens on the original.
+func (data *Data) importTokens(other Data, backupDBName, restoreDBName, backupRPName, restoreRPName string) {
+	known := make(map[string]struct{}, len(data.Tokens))
+	for _, t := range data.Tokens {
+		known[t.ID] = struct{}{}
+	}
+
+	for _, t := range other.Tokens {
+		if t.Database != backupDBName {
+			continue
+		} else if _, ok := known[t.ID]; ok {
+			continue
+		}
+
+		if backupRPName != "" {
+			if t.RetentionPolicy != "" && t.RetentionPolicy != backupRPName {
+				continue
+			} else if t.RetentionPolicy != "" {
+				t.RetentionPolicy = restoreRPName
+			}
+		}
+		t.Database = restoreDBName
+		data.Tokens = append(data.Tokens, t)
+	}
+}
+
+// Tokens returns the API tokens.
+func (c *Client) Tokens() []TokenInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tokens := c.cacheData.CloneTokens()
+	if tokens == nil {
+		return []TokenInfo{}
+	}
+	return tokens
+}
+
+// CreateToken creates an API token with privilege p on database, or only on
+// its retention policy retentionPolicy if it is not empty. It returns the
+// token and the string to authenticate with, which cannot be retrieved later.
+func (c *Client) CreateToken(database, retentionPolicy string, p influxql.Privilege) (*TokenInfo, string, error) {
+	if p != influxql.ReadPrivilege && p != influxql.WritePrivilege {
+		return nil, "", ErrTokenPrivilege
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var id, secret [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, "", err
+	} else if _, err := rand.Read(secret[:]); err != nil {
+		return nil, "", err
+	}
+
+	t := TokenInfo{
+		ID:              hex.EncodeToString(id[:]),
+		Database:        database,
+		RetentionPolicy: retentionPolicy,
+		Privilege:       p,
+		Hash:            hashToken(hex.EncodeToString(secret[:])),
+	}
+
+	data := c.cacheData.Clone()
+	if err := data.CreateToken(t); err != nil {
+		return nil, "", err
+	}
+
+	if err := c.commit(data); err != nil {
+		return nil, "", err
+	}
+
+	return &t, t.ID + "." + hex.EncodeToString(secret[:]), nil
+}
+
+// DropToken removes the API token id.
+func (c *Client) DropToken(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.cacheData.Clone()
+	if err := data.DropToken(id); err != nil {
+		return err
+	}
+
+	return c.commit(data)
+}
+
+// AuthenticateToken returns the user of the API token token, as returned by
+// CreateToken.
+func (c *Client) AuthenticateToken(token string) (User, error) {
+	i := strings.IndexByte(token, '.')
+	if i < 0 {
+		return nil, ErrAuthenticate
+	}
+	id, hash := token[:i], hashToken(token[i+1:])
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, t := range c.cacheData.Tokens {
+		if t.ID != id {
+			continue
+		} else if subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hash)) != 1 {
+			return nil, ErrAuthenticate
+		}
+
+		u := &TokenUser{TokenInfo: t}
+		if db := c.cacheData.Database(t.Database); db != nil {
+			u.defaultRetentionPolicy = db.DefaultRetentionPolicy
+		}
+		return u, nil
+	}
+	return nil, ErrAuthenticate
+}
+
+// importTokensFile moves the API tokens of the file they were kept in before
+// into the meta data, and removes the file.
+// This method assumes c's mutex is already locked.
+func (c *Client) importTokensFile() error {
+	file := filepath.Join(c.path, tokensFile)
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var tokens []TokenInfo
+	if err := json.Unmarshal(b, &tokens); err != nil {
+		return fmt.Errorf("invalid %s: %s", tokensFile, err)
+	}
+
+	data := c.cacheData.Clone()
+	data.Tokens = nil
+	for _, t := range tokens {
+		// The tokens of databases dropped since are not imported.
+		if data.Database(t.Database) != nil {
+			data.Tokens = append(data.Tokens, t)
+		}
+	}
+	if err := c.commit(data); err != nil {
+		return err
+	}
+	return os.Remove(file)
+}
+
+func hashToken(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+// TokenUser is the user of a request authenticated with an API token. It is
+// never an admin, and has only the privilege of the token on its database.
+type TokenUser struct {
+	TokenInfo
+
+	// defaultRetentionPolicy is the default retention policy of the database
+	// when the token was authenticated, which writes and queries naming no
+	// retention policy use.
+	defaultRetentionPolicy string
+}
+
+// ID returns the name the token is shown under in logs.
+func (u *TokenUser) ID() string {
+	return "token:" + u.TokenInfo.ID
+}
+
+// IsAdmin returns false, tokens are never admins.
+func (u *TokenUser) IsAdmin() bool {
+	return false
+}
+
+// AuthorizeDatabase returns true if the token has privilege p on database.
+func (u *TokenUser) AuthorizeDatabase(p influxql.Privilege, database string) bool {
+	return database == u.Database && p == u.Privilege
+}
+
+// AuthorizeWrite returns nil if the token may write to the retention policy
+// retentionPolicy of database, or to its default one if retentionPolicy is
+// empty.
+func (u *TokenUser) AuthorizeWrite(database, retentionPolicy string) error {
+	if !u.AuthorizeDatabase(influxql.WritePrivilege, database) || !u.authorizeRetentionPolicy(retentionPolicy) {
+		return &ErrAuthorize{
+			Database: database,
+			Message:  fmt.Sprintf("%s not authorized to write to %s", u.ID(), database),
+		}
+	}
+	return nil
+}
+
+// AuthorizeQuery returns an error unless the token may read from database
+// and every statement of query only reads from the retention policy of the
+// token.
+func (u *TokenUser) AuthorizeQuery(database string, query *influxql.Query) error {
+	for _, stmt := range query.Statements {
+		privs, err := stmt.RequiredPrivileges()
+		if err != nil {
+			return err
+		}
+
+		for _, p := range privs {
+			db := p.Name
+			if db == "" {
+				db = database
+			}
+			if p.Admin || p.Privilege != influxql.ReadPrivilege || !u.AuthorizeDatabase(p.Privilege, db) {
+				return &ErrAuthorize{
+					Query:    query,
+					User:     u.ID(),
+					Database: database,
+					Message:  fmt.Sprintf("statement '%s', requires %s on %s", stmt, p.Privilege.String(), db),
+				}
+			}
+		}
+
+		if err := u.authorizeSources(database, stmt); err != nil {
+			return &ErrAuthorize{
+				Query:    query,
+				User:     u.ID(),
+				Database: database,
+				Message:  fmt.Sprintf("statement '%s', %s", stmt, err),
+			}
+		}
+	}
+	return nil
+}
+
+// authorizeSources returns an error if stmt reads from a retention policy
+// other than the one of the token.
+func (u *TokenUser) authorizeSources(database string, stmt influxql.Statement) (err error) {
+	if u.RetentionPolicy == "" {
+		return nil
+	}
+
+	influxql.WalkFunc(stmt, func(n influxql.Node) {
+		m, ok := n.(*influxql.Measurement)
+		if !ok || err != nil {
+			return
+		}
+
+		if db := m.Database; db != "" && db != database && db != u.Database {
+			err = fmt.Errorf("reads database %s", db)
+		} else if !u.authorizeRetentionPolicy(m.RetentionPolicy) {
+			err = fmt.Errorf("reads retention policy %s", m.RetentionPolicy)
+		}
+	})
+	return err
+}
+
+// authorizeRetentionPolicy returns true if the token may use the retention
+// policy rp, where an empty rp is the default retention policy.
+func (u *TokenUser) authorizeRetentionPolicy(rp string) bool {
+	if u.RetentionPolicy == "" {
+		return true
+	} else if rp == "" {
+		rp = u.defaultRetentionPolicy
+	}
+	return rp == u.RetentionPolicy
+}
+
+// AuthorizeSeriesRead returns true, tokens are not limited per series.
+func (u *TokenUser) AuthorizeSeriesRead(database string, measurement []byte, tags models.Tags) bool {
+	return true
+}
+
+// AuthorizeSeriesWrite returns true, tokens are not limited per series.
+func (u *TokenUser) AuthorizeSeriesWrite(database string, measurement []byte, tags models.Tags) bool {
+	return true
+}
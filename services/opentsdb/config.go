@@ -3,7 +3,9 @@ package opentsdb
 import (
 	"time"
 
+	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/services/internal/ingest"
 	"github.com/influxdata/influxdb/toml"
 )
 
@@ -46,6 +48,7 @@ type Config struct {
 	BatchPending     int           `toml:"batch-pending"`
 	BatchTimeout     toml.Duration `toml:"batch-timeout"`
 	LogPointErrors   bool          `toml:"log-point-errors"`
+	Tags             []string      `toml:"tags"`
 }
 
 // NewConfig returns a new config for the service.
@@ -96,6 +99,16 @@ func (c *Config) WithDefaults() *Config {
 	return &d
 }
 
+// Validate returns an error if the Config is invalid.
+func (c *Config) Validate() error {
+	return ingest.ValidateTags(c.Tags)
+}
+
+// DefaultTags returns the config's tags.
+func (c *Config) DefaultTags() models.Tags {
+	return ingest.DefaultTags(c.Tags)
+}
+
 // Configs wraps a slice of Config to aggregate diagnostics.
 type Configs []Config
 
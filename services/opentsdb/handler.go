@@ -22,6 +22,7 @@ import (
 type Handler struct {
 	Database        string
 	RetentionPolicy string
+	Tags            models.Tags
 
 	PointsWriter interface {
 		WritePointsPrivileged(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
@@ -106,14 +107,19 @@ func (h *Handler) servePut(w http.ResponseWriter, r *http.Request) {
 		p := dps[i]
 
 		// Convert timestamp to Go time.
-		// If time value is over a billion then it's microseconds.
+		// If time value is over ten billion then it's milliseconds.
 		var ts time.Time
 		if p.Time < 10000000000 {
 			ts = time.Unix(p.Time, 0)
 		} else {
-			ts = time.Unix(p.Time/1000, (p.Time%1000)*1000)
+			ts = time.Unix(p.Time/1000, (p.Time%1000)*int64(time.Millisecond))
 		}
 
+		if p.Tags == nil {
+			p.Tags = make(map[string]string, len(h.Tags))
+		}
+		setDefaultTags(p.Tags, h.Tags)
+
 		pt, err := models.NewPoint(p.Metric, models.NewTags(p.Tags), map[string]interface{}{"value": p.Value}, ts)
 		if err != nil {
 			h.Logger.Info(fmt.Sprintf("Dropping point %v: %v", p.Metric, err))
@@ -196,3 +202,12 @@ type point struct {
 	Value  float64           `json:"value"`
 	Tags   map[string]string `json:"tags,omitempty"`
 }
+
+// setDefaultTags sets the default tags in tags if they are not already set.
+func setDefaultTags(tags map[string]string, defaults models.Tags) {
+	for _, t := range defaults {
+		if _, ok := tags[string(t.Key)]; !ok {
+			tags[string(t.Key)] = string(t.Value)
+		}
+	}
+}
@@ -17,7 +17,8 @@ import (
 	"time"
 
 	"github.com/influxdata/influxdb/models"
-	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/pkg/systemd"
+	"github.com/influxdata/influxdb/services/internal/ingest"
 	"github.com/influxdata/influxdb/tsdb"
 	"go.uber.org/zap"
 )
@@ -37,6 +38,7 @@ const (
 	statBatchesTransmitted       = "batchesTx"
 	statPointsTransmitted        = "pointsTx"
 	statBatchesTransmitFail      = "batchesTxFail"
+	statPointsDropped            = "pointsDropped"
 	statConnectionsActive        = "connsActive"
 	statConnectionsHandled       = "connsHandled"
 	statDroppedPointsInvalid     = "droppedPointsInvalid"
@@ -51,20 +53,19 @@ type Service struct {
 	tls  bool
 	cert string
 
-	mu    sync.RWMutex
-	ready bool          // Has the required database been created?
-	done  chan struct{} // Is the service closing or closed?
+	mu      sync.RWMutex
+	done    chan struct{}  // Is the service closing or closed?
+	storage ingest.Storage // Has the required database been created?
 
 	BindAddress     string
 	Database        string
 	RetentionPolicy string
+	Tags            models.Tags // Tags added to every point that doesn't have them.
 
 	PointsWriter interface {
 		WritePointsPrivileged(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
 	}
-	MetaClient interface {
-		CreateDatabase(name string) (*meta.DatabaseInfo, error)
-	}
+	MetaClient ingest.MetaClient
 
 	// Points received over the telnet protocol are batched.
 	batchSize    int
@@ -90,6 +91,7 @@ func NewService(c Config) (*Service, error) {
 		BindAddress:     d.BindAddress,
 		Database:        d.Database,
 		RetentionPolicy: d.RetentionPolicy,
+		Tags:            d.DefaultTags(),
 		batchSize:       d.BatchSize,
 		batchPending:    d.BatchPending,
 		batchTimeout:    time.Duration(d.BatchTimeout),
@@ -127,17 +129,17 @@ func (s *Service) Open() error {
 			return err
 		}
 
-		listener, err := tls.Listen("tcp", s.BindAddress, &tls.Config{
-			Certificates: []tls.Certificate{cert},
-		})
+		listener, err := systemd.Listen("tcp", s.BindAddress)
 		if err != nil {
 			return err
 		}
 
 		s.Logger.Info(fmt.Sprint("Listening on TLS: ", listener.Addr().String()))
-		s.ln = listener
+		s.ln = tls.NewListener(listener, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+		})
 	} else {
-		listener, err := net.Listen("tcp", s.BindAddress)
+		listener, err := systemd.Listen("tcp", s.BindAddress)
 		if err != nil {
 			return err
 		}
@@ -209,24 +211,12 @@ func (s *Service) closed() bool {
 	}
 }
 
-// createInternalStorage ensures that the required database has been created.
+// createInternalStorage ensures that the required database and retention
+// policy have been created.
 func (s *Service) createInternalStorage() error {
-	s.mu.RLock()
-	ready := s.ready
-	s.mu.RUnlock()
-	if ready {
-		return nil
-	}
-
-	if _, err := s.MetaClient.CreateDatabase(s.Database); err != nil {
-		return err
-	}
-
-	// The service is now ready.
-	s.mu.Lock()
-	s.ready = true
-	s.mu.Unlock()
-	return nil
+	return s.storage.Ensure(func() error {
+		return ingest.CreateStorage(s.MetaClient, s.Database, s.RetentionPolicy)
+	})
 }
 
 // WithLogger sets the logger for the service.
@@ -249,6 +239,7 @@ type Statistics struct {
 	BatchesTransmitted       int64
 	PointsTransmitted        int64
 	BatchesTransmitFail      int64
+	PointsDropped            int64
 	ActiveConnections        int64
 	HandledConnections       int64
 	InvalidDroppedPoints     int64
@@ -273,6 +264,7 @@ func (s *Service) Statistics(tags map[string]string) []models.Statistic {
 			statBatchesTransmitted:       atomic.LoadInt64(&s.stats.BatchesTransmitted),
 			statPointsTransmitted:        atomic.LoadInt64(&s.stats.PointsTransmitted),
 			statBatchesTransmitFail:      atomic.LoadInt64(&s.stats.BatchesTransmitFail),
+			statPointsDropped:            atomic.LoadInt64(&s.stats.PointsDropped),
 			statConnectionsActive:        atomic.LoadInt64(&s.stats.ActiveConnections),
 			statConnectionsHandled:       atomic.LoadInt64(&s.stats.HandledConnections),
 			statDroppedPointsInvalid:     atomic.LoadInt64(&s.stats.InvalidDroppedPoints),
@@ -389,13 +381,14 @@ func (s *Service) handleTelnetConn(conn net.Conn) {
 			if s.LogPointErrors {
 				s.Logger.Info(fmt.Sprintf("malformed time '%s' from %s", tsStr, remoteAddr))
 			}
+			continue
 		}
 
 		switch len(tsStr) {
 		case 10:
 			t = time.Unix(ts, 0)
 		case 13:
-			t = time.Unix(ts/1000, (ts%1000)*1000)
+			t = time.Unix(ts/1000, (ts%1000)*int64(time.Millisecond))
 		default:
 			atomic.AddInt64(&s.stats.TelnetBadTime, 1)
 			if s.LogPointErrors {
@@ -418,6 +411,7 @@ func (s *Service) handleTelnetConn(conn net.Conn) {
 
 			tags[k] = parts[1]
 		}
+		setDefaultTags(tags, s.Tags)
 
 		fields := make(map[string]interface{})
 		fv, err := strconv.ParseFloat(valueStr, 64)
@@ -447,6 +441,7 @@ func (s *Service) serveHTTP() {
 	handler := &Handler{
 		Database:        s.Database,
 		RetentionPolicy: s.RetentionPolicy,
+		Tags:            s.Tags,
 		PointsWriter:    s.PointsWriter,
 		Logger:          s.Logger,
 		stats:           s.stats,
@@ -468,9 +463,11 @@ func (s *Service) processBatches(batcher *tsdb.PointBatcher) {
 				continue
 			}
 
-			if err := s.PointsWriter.WritePointsPrivileged(s.Database, s.RetentionPolicy, models.ConsistencyLevelAny, batch); err == nil {
+			err := s.PointsWriter.WritePointsPrivileged(s.Database, s.RetentionPolicy, models.ConsistencyLevelAny, batch)
+			if dropped, ok := ingest.PartialWrite(s.Logger, s.Database, err); err == nil || ok {
 				atomic.AddInt64(&s.stats.BatchesTransmitted, 1)
-				atomic.AddInt64(&s.stats.PointsTransmitted, int64(len(batch)))
+				atomic.AddInt64(&s.stats.PointsTransmitted, int64(len(batch)-dropped))
+				atomic.AddInt64(&s.stats.PointsDropped, int64(dropped))
 			} else {
 				s.Logger.Info(fmt.Sprintf("failed to write point batch to database %q: %s", s.Database, err))
 				atomic.AddInt64(&s.stats.BatchesTransmitFail, 1)
@@ -98,14 +98,18 @@ func TestService_CreatesDatabase(t *testing.T) {
 	}
 
 	// ready status should not have been switched due to meta client error.
-	s.Service.mu.RLock()
-	ready := s.Service.ready
-	s.Service.mu.RUnlock()
+	ready := s.Service.storage.Ready()
 
 	if got, exp := ready, false; got != exp {
 		t.Fatalf("got %v, expected %v", got, exp)
 	}
 
+	// The failure delays the next attempt, so don't wait for it here.
+	if s.Service.storage.RetryAt().IsZero() {
+		t.Fatal("expected a retry time after the failure")
+	}
+	s.Service.storage.Reset()
+
 	// This time MC won't cause an error.
 	s.MetaClient.CreateDatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
 		// Allow some time for the caller to return and the ready status to
@@ -124,9 +128,7 @@ func TestService_CreatesDatabase(t *testing.T) {
 	}
 
 	// ready status should not have been switched due to meta client error.
-	s.Service.mu.RLock()
-	ready = s.Service.ready
-	s.Service.mu.RUnlock()
+	ready = s.Service.storage.Ready()
 
 	if got, exp := ready, true; got != exp {
 		t.Fatalf("got %v, expected %v", got, exp)
@@ -198,6 +200,60 @@ func TestService_Telnet(t *testing.T) {
 	}
 }
 
+// Ensure timestamps with 13 digits are read as milliseconds.
+func TestService_Telnet_Milliseconds(t *testing.T) {
+	t.Parallel()
+
+	s := NewTestService("db0", "127.0.0.1:0")
+	if err := s.Service.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Service.Close()
+
+	// Mock points writer.
+	var called int32
+	s.WritePointsFn = func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+		atomic.StoreInt32(&called, 1)
+
+		if len(points) != 1 {
+			t.Fatalf("unexpected points: %#v", points)
+		} else if exp := time.Unix(1356998400, 123*int64(time.Millisecond)); !points[0].Time().Equal(exp) {
+			t.Fatalf("unexpected time: %s, exp %s", points[0].Time(), exp)
+		}
+		return nil
+	}
+
+	// Open connection to the service.
+	conn, err := net.Dial("tcp", s.Service.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// A malformed time is dropped instead of being written at the epoch.
+	if _, err := conn.Write([]byte("put sys.cpu.user 13569984x0 1 host=a\nput sys.cpu.user 1356998400123 42.5 host=a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tick := time.Tick(10 * time.Millisecond)
+	timeout := time.After(10 * time.Second)
+
+	for {
+		select {
+		case <-tick:
+			// Verify that the writer was called.
+			if atomic.LoadInt32(&called) > 0 {
+				return
+			}
+		case <-timeout:
+			t.Fatal("points writer not called")
+		}
+	}
+}
+
 // Ensure a point can be written via the HTTP protocol.
 func TestService_HTTP(t *testing.T) {
 	t.Parallel()
@@ -248,6 +304,48 @@ func TestService_HTTP(t *testing.T) {
 	}
 }
 
+// Ensure the configured default tags are added to points written via HTTP
+// without overriding tags set on the point.
+func TestService_HTTP_DefaultTags(t *testing.T) {
+	t.Parallel()
+
+	s := NewTestService("db0", "127.0.0.1:0")
+	s.Service.Tags = models.NewTags(map[string]string{"dc": "ams", "source": "opentsdb"})
+	if err := s.Service.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Service.Close()
+
+	var called bool
+	s.WritePointsFn = func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+		called = true
+		if !reflect.DeepEqual(points, []models.Point{
+			models.MustNewPoint(
+				"sys.cpu.nice",
+				models.NewTags(map[string]string{"dc": "lga", "host": "web01", "source": "opentsdb"}),
+				map[string]interface{}{"value": 18.0},
+				time.Unix(1346846400, 0),
+			),
+		}) {
+			spew.Dump(points)
+			t.Fatalf("unexpected points: %#v", points)
+		}
+		return nil
+	}
+
+	resp, err := http.Post("http://"+s.Service.Addr().String()+"/api/put", "application/json", strings.NewReader(`{"metric":"sys.cpu.nice", "timestamp":1346846400, "value":18, "tags":{"host":"web01", "dc":"lga"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unexpected status code: %d", resp.StatusCode)
+	} else if !called {
+		t.Fatal("points writer not called")
+	}
+}
+
 type TestService struct {
 	Service       *Service
 	MetaClient    *internal.MetaClientMock
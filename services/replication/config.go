@@ -0,0 +1,106 @@
+package replication
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/toml"
+)
+
+const (
+	// DefaultBatchSize is the default number of points sent in one request.
+	DefaultBatchSize = 5000
+
+	// DefaultRetryInterval is the default time between two attempts to send
+	// a request to a target.
+	DefaultRetryInterval = 10 * time.Second
+)
+
+// Config represents a configuration for the replication service.
+type Config struct {
+	Enabled bool `toml:"enabled"`
+
+	// Dir holds the requests that were not accepted by their target yet.
+	Dir string `toml:"dir"`
+
+	// BatchSize is the number of points of consecutive writes sent to a
+	// target in one request.
+	BatchSize     int           `toml:"batch-size"`
+	RetryInterval toml.Duration `toml:"retry-interval"`
+
+	Targets []Target `toml:"target"`
+}
+
+// Target is a remote server that the writes to a database are replicated to.
+type Target struct {
+	// Database is the local database to replicate. When RetentionPolicy is
+	// set, only the writes to that retention policy are replicated.
+	Database        string `toml:"database"`
+	RetentionPolicy string `toml:"retention-policy"`
+
+	// URL is the address of the remote server, such as http://dr:8086.
+	URL string `toml:"url"`
+
+	// RemoteDatabase is the database written to on the remote server. It
+	// defaults to Database.
+	RemoteDatabase string `toml:"remote-database"`
+
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+}
+
+// NewConfig returns a new Config with defaults.
+func NewConfig() Config {
+	return Config{
+		BatchSize:     DefaultBatchSize,
+		RetryInterval: toml.Duration(DefaultRetryInterval),
+	}
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.Dir == "" {
+		return errors.New("dir must not be empty")
+	} else if c.BatchSize <= 0 {
+		return errors.New("batch-size must be greater than 0")
+	} else if c.RetryInterval <= 0 {
+		return errors.New("retry-interval must be greater than 0")
+	}
+
+	for _, t := range c.Targets {
+		if t.Database == "" {
+			return errors.New("target database must not be empty")
+		}
+		u, err := url.Parse(t.URL)
+		if err != nil {
+			return fmt.Errorf("target %s: invalid url: %s", t.Database, err)
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("target %s: invalid url %q: expected an http or https URL", t.Database, t.URL)
+		}
+	}
+	return nil
+}
+
+// Diagnostics returns a diagnostics representation of a subset of the Config.
+func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
+	if !c.Enabled {
+		return diagnostics.RowFromMap(map[string]interface{}{
+			"enabled": false,
+		}), nil
+	}
+
+	return diagnostics.RowFromMap(map[string]interface{}{
+		"enabled":        true,
+		"dir":            c.Dir,
+		"batch-size":     c.BatchSize,
+		"retry-interval": c.RetryInterval,
+		"targets":        len(c.Targets),
+	}), nil
+}
@@ -0,0 +1,59 @@
+package replication_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/influxdb/services/replication"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	c := replication.NewConfig()
+	if _, err := toml.Decode(`
+enabled = true
+dir = "/tmp/replication"
+batch-size = 100
+
+[[target]]
+database = "db0"
+url = "http://dr:8086"
+remote-database = "db1"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	} else if !c.Enabled || c.Dir != "/tmp/replication" {
+		t.Fatalf("unexpected config: %+v", c)
+	} else if c.BatchSize != 100 || time.Duration(c.RetryInterval) != replication.DefaultRetryInterval {
+		t.Fatalf("unexpected batching: %d, %v", c.BatchSize, c.RetryInterval)
+	} else if len(c.Targets) != 1 || c.Targets[0].Database != "db0" || c.Targets[0].URL != "http://dr:8086" || c.Targets[0].RemoteDatabase != "db1" {
+		t.Fatalf("unexpected targets: %+v", c.Targets)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	for _, target := range []replication.Target{
+		{URL: "http://dr:8086"},
+		{Database: "db0"},
+		{Database: "db0", URL: "udp://dr:8089"},
+	} {
+		c := replication.NewConfig()
+		c.Enabled = true
+		c.Dir = "/tmp/replication"
+		c.Targets = []replication.Target{target}
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected error for %+v", target)
+		}
+	}
+
+	c := replication.NewConfig()
+	c.Enabled = true
+	if err := c.Validate(); err == nil {
+		t.Error("expected error without dir")
+	}
+}
@@ -0,0 +1,427 @@
+package replication
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// requestExt is the extension of the files of the queued requests.
+const requestExt = ".write"
+
+// maxRetryBackoff is the longest time between two attempts to forward a
+// request. The time between attempts doubles from the retry interval up to
+// this.
+const maxRetryBackoff = 5 * time.Minute
+
+// tmpExt is the extension of the files of the requests being written. They
+// are renamed once complete, and removed if left by a crash.
+const tmpExt = ".tmp"
+
+// Request is a write request waiting to be forwarded to an upstream server.
+// Requests hold no credentials, since they are saved on disk. They are sent
+// with the credentials of the upstream URL.
+type Request struct {
+	// Query is the query string of the request, with the database, the
+	// retention policy and the precision.
+	Query string
+
+	// Body is the line protocol of the request, uncompressed.
+	Body []byte
+}
+
+// Queue keeps the write requests to forward to one upstream server in files
+// of a directory, so they survive a restart. Requests are forwarded in the
+// order they were added, and a request is only removed once the server
+// accepted or rejected it.
+type Queue struct {
+	// The counters come first to be 64-bit aligned for the atomic package.
+	sent, rejected, failures int64
+
+	mu     sync.Mutex
+	dir    string
+	seq    uint64
+	tmpSeq uint64
+	cond   *sync.Cond
+
+	upstream      *url.URL
+	user          *url.Userinfo
+	client        *http.Client
+	retryInterval time.Duration
+	batchSize     int
+	logger        *zap.Logger
+
+	closing chan struct{}
+	wg      sync.WaitGroup
+}
+
+// QueueStatistics are the statistics of a queue.
+type QueueStatistics struct {
+	// Pending is the number of requests waiting to be forwarded.
+	Pending int
+
+	// Lag is the age of the oldest request waiting to be forwarded.
+	Lag time.Duration
+
+	// Sent and Rejected are the numbers of requests the server accepted and
+	// rejected, and Failures the number of attempts that must be retried.
+	Sent, Rejected, Failures int64
+}
+
+// OpenQueue returns the queue of upstream in dir, creating dir if needed,
+// and starts forwarding the requests it holds. The user and password of
+// upstream, if any, are sent with every request with basic authentication.
+// Consecutive requests to the same query are forwarded in one request of up
+// to batchSize lines, if batchSize is more than one.
+func OpenQueue(dir string, upstream *url.URL, retryInterval time.Duration, batchSize int, logger *zap.Logger) (*Queue, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	// Requests interrupted by a crash were never acknowledged.
+	tmps, err := filepath.Glob(filepath.Join(dir, "*"+tmpExt))
+	if err != nil {
+		return nil, err
+	}
+	for _, tmp := range tmps {
+		if err := os.Remove(tmp); err != nil {
+			return nil, err
+		}
+	}
+
+	// The credentials are kept out of the URL, which is logged.
+	u := *upstream
+	u.User = nil
+	q := &Queue{
+		dir:           dir,
+		upstream:      &u,
+		user:          upstream.User,
+		client:        &http.Client{Timeout: 30 * time.Second},
+		retryInterval: retryInterval,
+		batchSize:     batchSize,
+		logger:        logger,
+		closing:       make(chan struct{}),
+	}
+	q.cond = sync.NewCond(&q.mu)
+
+	seqs, err := q.pending()
+	if err != nil {
+		return nil, err
+	} else if len(seqs) > 0 {
+		q.seq = seqs[len(seqs)-1]
+		q.logger.Info(fmt.Sprintf("%d requests pending for %s", len(seqs), upstream))
+	}
+
+	q.wg.Add(1)
+	go q.forward()
+	return q, nil
+}
+
+// Upstream returns the URL of the server the requests are forwarded to,
+// without its credentials.
+func (q *Queue) Upstream() *url.URL { return q.upstream }
+
+// Close stops forwarding requests. The requests not forwarded yet are kept.
+func (q *Queue) Close() {
+	close(q.closing)
+	q.mu.Lock()
+	q.cond.Broadcast()
+	q.mu.Unlock()
+	q.wg.Wait()
+}
+
+// Statistics returns the statistics of the queue.
+func (q *Queue) Statistics() QueueStatistics {
+	st := QueueStatistics{
+		Sent:     atomic.LoadInt64(&q.sent),
+		Rejected: atomic.LoadInt64(&q.rejected),
+		Failures: atomic.LoadInt64(&q.failures),
+	}
+
+	q.mu.Lock()
+	seqs, _ := q.pending()
+	q.mu.Unlock()
+	st.Pending = len(seqs)
+	if len(seqs) > 0 {
+		if fi, err := os.Stat(q.path(seqs[0])); err == nil {
+			st.Lag = time.Since(fi.ModTime())
+		}
+	}
+	return st
+}
+
+// Add saves r in the queue. It returns once the file of r is synced.
+func (q *Queue) Add(r *Request) error {
+	p, err := q.Prepare(r)
+	if err != nil {
+		return err
+	}
+	return p.Commit()
+}
+
+// PreparedRequest is a request saved in the files of a queue, but not added
+// to the queue yet.
+type PreparedRequest struct {
+	q   *Queue
+	tmp string
+}
+
+// Prepare saves r in a file of the queue without adding it to the queue. It
+// returns once the file is synced. Committing a request only renames its
+// file, so a request can be prepared in several queues and then committed to
+// all, or aborted in all if one of them fails.
+func (q *Queue) Prepare(r *Request) (*PreparedRequest, error) {
+	// The second line held the Authorization header of the request. It is
+	// left empty for the files of older versions to be read the same way.
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s\n\n", r.Query)
+	buf.Write(r.Body)
+
+	q.mu.Lock()
+	q.tmpSeq++
+	tmp := filepath.Join(q.dir, fmt.Sprintf("%020d%s", q.tmpSeq, tmpExt))
+	q.mu.Unlock()
+
+	if err := writeFileSync(tmp, buf.Bytes()); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	return &PreparedRequest{q: q, tmp: tmp}, nil
+}
+
+// Commit adds the request to the end of the queue.
+func (p *PreparedRequest) Commit() error {
+	q := p.q
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if err := os.Rename(p.tmp, q.path(q.seq+1)); err != nil {
+		return err
+	}
+	q.seq++
+	q.cond.Signal()
+	return nil
+}
+
+// Abort removes the file of the request without adding it to the queue.
+func (p *PreparedRequest) Abort() {
+	os.Remove(p.tmp)
+}
+
+// path returns the path of the file of request seq.
+func (q *Queue) path(seq uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, requestExt))
+}
+
+// pending returns the sequence numbers of the queued requests, in order.
+func (q *Queue) pending() ([]uint64, error) {
+	names, err := filepath.Glob(filepath.Join(q.dir, "*"+requestExt))
+	if err != nil {
+		return nil, err
+	}
+	seqs := make([]uint64, 0, len(names))
+	for _, name := range names {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(name), requestExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	return seqs, nil
+}
+
+// forward sends the queued requests to the upstream server until the queue
+// is closed. A request is retried until the server accepts or rejects it.
+func (q *Queue) forward() {
+	defer q.wg.Done()
+
+	for {
+		q.mu.Lock()
+		seqs, err := q.pending()
+		for err == nil && len(seqs) == 0 && !q.closed() {
+			q.cond.Wait()
+			seqs, err = q.pending()
+		}
+		q.mu.Unlock()
+
+		if q.closed() {
+			return
+		} else if err != nil {
+			q.logger.Info(fmt.Sprintf("error listing requests for %s: %s", q.upstream, err))
+			q.wait(q.retryInterval)
+			continue
+		}
+
+		for len(seqs) > 0 {
+			r, n, err := q.next(seqs)
+			if err != nil {
+				q.logger.Info(fmt.Sprintf("dropping unreadable request %s: %s", q.path(seqs[0]), err))
+			}
+			backoff := q.retryInterval
+			for r != nil {
+				err := q.send(r)
+				if err == nil {
+					break
+				}
+				atomic.AddInt64(&q.failures, 1)
+				q.logger.Info(fmt.Sprintf("error forwarding to %s, retrying in %s: %s", q.upstream, backoff, err))
+				if !q.wait(backoff) {
+					return
+				}
+				if backoff *= 2; backoff > maxRetryBackoff {
+					backoff = maxRetryBackoff
+				}
+			}
+			for _, seq := range seqs[:n] {
+				os.Remove(q.path(seq))
+			}
+			seqs = seqs[n:]
+		}
+	}
+}
+
+// next reads the request to forward for the first of seqs, and returns the
+// number of queued requests it holds. Consecutive requests to the same query
+// are appended to it, up to the batch size. The request is nil if the first
+// request cannot be read.
+func (q *Queue) next(seqs []uint64) (*Request, int, error) {
+	r, err := readRequest(q.path(seqs[0]))
+	if err != nil {
+		return nil, 1, err
+	}
+
+	n, lines := 1, countLines(r.Body)
+	for ; n < len(seqs) && q.batchSize > 1; n++ {
+		other, err := readRequest(q.path(seqs[n]))
+		if err != nil || other.Query != r.Query || lines+countLines(other.Body) > q.batchSize {
+			break
+		}
+		if len(r.Body) > 0 && r.Body[len(r.Body)-1] != '\n' {
+			r.Body = append(r.Body, '\n')
+		}
+		r.Body = append(r.Body, other.Body...)
+		lines += countLines(other.Body)
+	}
+	return r, n, nil
+}
+
+// countLines returns the number of lines of b.
+func countLines(b []byte) int {
+	n := bytes.Count(b, []byte{'\n'})
+	if len(b) > 0 && b[len(b)-1] != '\n' {
+		n++
+	}
+	return n
+}
+
+// send forwards r. It returns an error if the request should be sent again. Requests the server rejects as malformed or
+// too large are logged and dropped, since sending them again would fail as
+// well. Other rejections, such as an exceeded quota, a database not created
+// yet or changed credentials, can be resolved on the server and are retried.
+func (q *Queue) send(r *Request) error {
+	u := *q.upstream
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/write"
+	u.RawQuery = r.Query
+	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(r.Body))
+	if err != nil {
+		return err
+	}
+	if q.user != nil {
+		password, _ := q.user.Password()
+		req.SetBasicAuth(q.user.Username(), password)
+	}
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	switch {
+	case resp.StatusCode/100 == 2:
+		atomic.AddInt64(&q.sent, 1)
+		return nil
+	case resp.StatusCode == http.StatusBadRequest, resp.StatusCode == http.StatusRequestEntityTooLarge:
+		atomic.AddInt64(&q.rejected, 1)
+		q.logger.Info(fmt.Sprintf("dropping request rejected by %s: %s: %s", q.upstream, resp.Status, bytes.TrimSpace(body)))
+		return nil
+	}
+	return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+}
+
+// wait waits for d. It returns false if the queue was closed meanwhile.
+func (q *Queue) wait(d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-q.closing:
+		return false
+	}
+}
+
+// closed returns true if the queue is closed.
+func (q *Queue) closed() bool {
+	select {
+	case <-q.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// readRequest reads the request saved at path.
+func readRequest(path string) (*Request, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(bytes.NewReader(b))
+	query, err := br.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	// The credentials saved by older versions are not used anymore.
+	if _, err := br.ReadString('\n'); err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+	return &Request{
+		Query: strings.TrimSuffix(query, "\n"),
+		Body:  body,
+	}, nil
+}
+
+// writeFileSync writes b to a new file at path and syncs it.
+func writeFileSync(path string, b []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	} else if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
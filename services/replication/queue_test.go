@@ -0,0 +1,212 @@
+package replication
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Ensure queued requests are kept until the upstream server accepts them.
+func TestQueue_Forward(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	received := make(chan string, 10)
+	failures := 2
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		received <- r.URL.Path + "?" + r.URL.RawQuery + " " + r.Header.Get("Authorization") + " " + string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	logger := zap.NewNop()
+
+	// Queue a request while the queue of an unreachable server is open, so
+	// the request is left on disk.
+	down, _ := url.Parse("http://127.0.0.1:1")
+	q, err := OpenQueue(dir, down, time.Hour, 0, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Add(&Request{Query: "db=db0", Body: []byte("cpu v=1\n")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Aborted requests and files left by a crash are not forwarded.
+	p, err := q.Prepare(&Request{Query: "db=aborted", Body: []byte("cpu v=0\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Abort()
+	if err := ioutil.WriteFile(filepath.Join(dir, "partial.tmp"), []byte("db=crash\n\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+
+	// The request is forwarded once the queue is opened again, with the
+	// credentials of the upstream URL.
+	u.User = url.UserPassword("user", "pass")
+	q, err = OpenQueue(dir, u, 10*time.Millisecond, 0, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	if tmps, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(tmps) != 0 {
+		t.Fatalf("unexpected temporary files: %v", tmps)
+	}
+	if err := q.Add(&Request{Query: "db=db1", Body: []byte("mem v=2\n")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if q.Upstream().User != nil {
+		t.Fatal("unexpected credentials in upstream URL")
+	}
+
+	const auth = "Basic dXNlcjpwYXNz"
+	for _, exp := range []string{"/write?db=db0 " + auth + " cpu v=1\n", "/write?db=db1 " + auth + " mem v=2\n"} {
+		select {
+		case got := <-received:
+			if got != exp {
+				t.Fatalf("unexpected request: got %q, exp %q", got, exp)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", exp)
+		}
+	}
+
+	// The last request is only removed after the response is read.
+	deadline := time.Now().Add(5 * time.Second)
+	for st := q.Statistics(); st.Pending != 0 || st.Sent != 2; st = q.Statistics() {
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected statistics: %+v", st)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if st := q.Statistics(); st.Failures != 2 {
+		t.Fatalf("unexpected failures: %d", st.Failures)
+	}
+}
+
+// Ensure only malformed and too large requests are dropped, and other
+// rejections are retried.
+func TestQueue_Rejected(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Each request is answered with the next status code of its database.
+	codes := map[string][]int{
+		"db0": {http.StatusTooManyRequests, http.StatusNotFound, http.StatusUnauthorized, http.StatusForbidden, http.StatusNoContent},
+		"db1": {http.StatusBadRequest},
+		"db2": {http.StatusRequestEntityTooLarge},
+		"db3": {http.StatusNoContent},
+	}
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		db := r.URL.Query().Get("db")
+		code := codes[db][0]
+		codes[db] = codes[db][1:]
+		w.WriteHeader(code)
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	q, err := OpenQueue(dir, u, time.Millisecond, 0, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	for _, db := range []string{"db0", "db1", "db2", "db3"} {
+		if err := q.Add(&Request{Query: "db=" + db, Body: []byte("cpu v=1\n")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for st := q.Statistics(); st.Pending != 0; st = q.Statistics() {
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected statistics: %+v", st)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if st := q.Statistics(); st.Sent != 2 || st.Rejected != 2 || st.Failures != 4 {
+		t.Fatalf("unexpected statistics: %+v", st)
+	}
+}
+
+// Ensure consecutive requests to the same query are forwarded together, up
+// to the batch size.
+func TestQueue_Batch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Queue the requests before the server is reachable, so they are all
+	// pending when they are forwarded.
+	down, _ := url.Parse("http://127.0.0.1:1")
+	q, err := OpenQueue(dir, down, time.Hour, 0, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []*Request{
+		{Query: "db=db0", Body: []byte("cpu v=1\n")},
+		{Query: "db=db0", Body: []byte("cpu v=2")},
+		{Query: "db=db0", Body: []byte("cpu v=3\n")},
+		{Query: "db=db1", Body: []byte("cpu v=4\n")},
+	} {
+		if err := q.Add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	q.Close()
+
+	received := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received <- r.URL.RawQuery + " " + string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	q, err = OpenQueue(dir, u, time.Hour, 2, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	for _, exp := range []string{"db=db0 cpu v=1\ncpu v=2", "db=db0 cpu v=3\n", "db=db1 cpu v=4\n"} {
+		select {
+		case got := <-received:
+			if got != exp {
+				t.Fatalf("unexpected request: got %q, exp %q", got, exp)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", exp)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Package replication replicates the writes to local databases to remote
+// servers asynchronously, for disaster recovery.
+package replication // import "github.com/influxdata/influxdb/services/replication"
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"go.uber.org/zap"
+)
+
+// Statistics for the replication service.
+const (
+	statPointsQueued     = "pointsQueued"
+	statPointsDropped    = "pointsDropped"
+	statRequestsPending  = "requestsPending"
+	statRequestsSent     = "requestsSent"
+	statRequestsRejected = "requestsRejected"
+	statRequestFailures  = "requestFailures"
+	statLag              = "lagNs"
+)
+
+// Service replicates the points written to the local shards to the targets
+// of their database. The points of a write are saved in the queue of each
+// target before the write returns, and sent until the target accepts them,
+// so a target that is unreachable catches up once it is back, including
+// across restarts and crashes. The files left in a queue are the writes its
+// target has not accepted yet.
+type Service struct {
+	config Config
+
+	mu      sync.RWMutex
+	opened  bool
+	targets []*target
+
+	Logger *zap.Logger
+}
+
+// target is a Target with its queue.
+type target struct {
+	// The counters come first to be 64-bit aligned for the atomic package.
+	pointsQueued  int64
+	pointsDropped int64
+
+	Target
+	url   *url.URL
+	queue *Queue
+}
+
+// NewService returns a new instance of Service.
+func NewService(c Config) *Service {
+	s := &Service{
+		config: c,
+		Logger: zap.NewNop(),
+	}
+	for _, t := range c.Targets {
+		tt := &target{Target: t}
+		if tt.RemoteDatabase == "" {
+			tt.RemoteDatabase = tt.Database
+		}
+		s.targets = append(s.targets, tt)
+	}
+	return s
+}
+
+// Open opens the queues of the targets and starts replicating.
+func (s *Service) Open() error {
+	s.Logger.Info("Starting replication service")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.opened {
+		return nil
+	}
+
+	for i, t := range s.targets {
+		u, err := url.Parse(t.URL)
+		if err != nil {
+			s.closeQueues(s.targets[:i])
+			return err
+		}
+		t.url = u
+
+		// The queue sends the credentials with each request, so they are
+		// not saved with the requests.
+		qu := *u
+		if t.Username != "" {
+			qu.User = url.UserPassword(t.Username, t.Password)
+		}
+
+		// Each target has a queue of its own, so one being unreachable
+		// does not delay the others.
+		key := t.Database + "\x00" + t.RetentionPolicy + "\x00" + t.URL
+		dir := filepath.Join(s.config.Dir, fmt.Sprintf("%x", sha1.Sum([]byte(key))))
+		t.queue, err = OpenQueue(dir, &qu, time.Duration(s.config.RetryInterval), s.config.BatchSize, s.Logger)
+		if err != nil {
+			s.closeQueues(s.targets[:i])
+			return fmt.Errorf("open queue of %s: %s", u, err)
+		}
+	}
+	s.opened = true
+
+	s.Logger.Info(fmt.Sprintf("Replicating to %d targets", len(s.targets)))
+	return nil
+}
+
+// Close stops replicating. The points not sent yet are sent once the
+// service is opened again. The points written while it is closed are not
+// replicated, and are counted as dropped.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.opened {
+		s.closeQueues(s.targets)
+		s.opened = false
+	}
+	return nil
+}
+
+func (s *Service) closeQueues(targets []*target) {
+	for _, t := range targets {
+		t.queue.Close()
+		t.queue = nil
+	}
+}
+
+// WithLogger sets the logger on the service.
+func (s *Service) WithLogger(log *zap.Logger) {
+	s.Logger = log.With(zap.String("service", "replication"))
+}
+
+// Statistics returns statistics for periodic monitoring.
+func (s *Service) Statistics(tags map[string]string) []models.Statistic {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statistics := make([]models.Statistic, 0, len(s.targets))
+	for _, t := range s.targets {
+		values := map[string]interface{}{
+			statPointsQueued:  atomic.LoadInt64(&t.pointsQueued),
+			statPointsDropped: atomic.LoadInt64(&t.pointsDropped),
+		}
+		if t.queue != nil {
+			st := t.queue.Statistics()
+			values[statRequestsPending] = int64(st.Pending)
+			values[statRequestsSent] = st.Sent
+			values[statRequestsRejected] = st.Rejected
+			values[statRequestFailures] = st.Failures
+			values[statLag] = int64(st.Lag)
+		}
+		statistics = append(statistics, models.Statistic{
+			Name: "replication",
+			Tags: models.StatisticTags{
+				"database":        t.Database,
+				"retentionPolicy": t.RetentionPolicy,
+				"url":             t.URL,
+			}.Merge(tags),
+			Values: values,
+		})
+	}
+	return statistics
+}
+
+// ReplicatePoints saves points, written to retentionPolicy of database on
+// the local shards, in the queues of the targets of database. It returns
+// once they are on disk. The points that cannot be saved are logged and
+// counted as dropped, since the local write has already succeeded.
+func (s *Service) ReplicatePoints(database, retentionPolicy string, points []models.Point) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var body []byte
+	for _, t := range s.targets {
+		if t.Database != database || (t.RetentionPolicy != "" && t.RetentionPolicy != retentionPolicy) {
+			continue
+		} else if !s.opened {
+			atomic.AddInt64(&t.pointsDropped, int64(len(points)))
+			continue
+		}
+
+		if body == nil {
+			for _, p := range points {
+				body = p.AppendString(body)
+				body = append(body, '\n')
+			}
+		}
+		values := url.Values{"db": {t.RemoteDatabase}, "precision": {"n"}}
+		if retentionPolicy != "" {
+			values.Set("rp", retentionPolicy)
+		}
+
+		if err := t.queue.Add(&Request{Query: values.Encode(), Body: body}); err != nil {
+			s.Logger.Info(fmt.Sprintf("error queueing %d points for %s: %s", len(points), t.url, err))
+			atomic.AddInt64(&t.pointsDropped, int64(len(points)))
+		} else {
+			atomic.AddInt64(&t.pointsQueued, int64(len(points)))
+		}
+	}
+}
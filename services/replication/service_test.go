@@ -0,0 +1,79 @@
+package replication_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/replication"
+)
+
+// Ensure the writes to a replicated database are sent to its target.
+func TestService_Replicate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replication")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	received := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		user, pass, _ := r.BasicAuth()
+		received <- r.URL.RawQuery + " " + user + ":" + pass + " " + string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := replication.NewConfig()
+	c.Enabled = true
+	c.Dir = dir
+	c.Targets = []replication.Target{
+		{Database: "db0", RetentionPolicy: "rp0", URL: srv.URL, RemoteDatabase: "dr", Username: "u", Password: "p"},
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := replication.NewService(c)
+	pt := models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "a"}), models.Fields{"value": 1.0}, time.Unix(0, 10))
+
+	// Points written before the service is open are counted as dropped.
+	s.ReplicatePoints("db0", "rp0", []models.Point{pt})
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.ReplicatePoints("db1", "rp0", []models.Point{pt})
+	s.ReplicatePoints("db0", "rp1", []models.Point{pt})
+	s.ReplicatePoints("db0", "rp0", []models.Point{pt})
+
+	// Only the write to rp0 of db0 is sent.
+	select {
+	case got := <-received:
+		if exp := "db=dr&precision=n&rp=rp0 u:p cpu,host=a value=1 10\n"; got != exp {
+			t.Fatalf("unexpected request: got %q, exp %q", got, exp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the replicated write")
+	}
+	select {
+	case got := <-received:
+		t.Fatalf("unexpected request: %q", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	stats := s.Statistics(nil)
+	if len(stats) != 1 {
+		t.Fatalf("unexpected statistics: %v", stats)
+	} else if got := stats[0].Values["pointsQueued"]; got != int64(1) {
+		t.Fatalf("unexpected points queued: %v", got)
+	} else if got := stats[0].Values["pointsDropped"]; got != int64(1) {
+		t.Fatalf("unexpected points dropped: %v", got)
+	}
+}
@@ -2,9 +2,11 @@ package retention
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/toml"
 )
 
@@ -12,6 +14,27 @@ import (
 type Config struct {
 	Enabled       bool          `toml:"enabled"`
 	CheckInterval toml.Duration `toml:"check-interval"`
+
+	// GracePeriod is how long the shards of an expired shard group are kept
+	// on disk after the group has been removed from queries.
+	GracePeriod toml.Duration `toml:"grace-period"`
+
+	// DryRun logs the shard groups and shards each check would delete
+	// without deleting them.
+	DryRun bool `toml:"dry-run"`
+
+	// Measurements expire the data of single measurements before the
+	// retention policies of their database do.
+	Measurements []MeasurementRetention `toml:"measurement"`
+}
+
+// MeasurementRetention deletes the data of a measurement, in all retention
+// policies of its database, once it is older than Duration. The deleted
+// data is removed from disk by the compactions of the shards.
+type MeasurementRetention struct {
+	Database    string        `toml:"database"`
+	Measurement string        `toml:"measurement"`
+	Duration    toml.Duration `toml:"duration"`
 }
 
 // NewConfig returns an instance of Config with defaults.
@@ -31,6 +54,25 @@ func (c Config) Validate() error {
 		return errors.New("check-interval must be positive")
 	}
 
+	if c.GracePeriod < 0 {
+		return errors.New("grace-period must not be negative")
+	}
+
+	// Deleted shard groups are pruned from the meta store after a fixed
+	// expiration. The shards must be removed from disk before that happens or
+	// they would be orphaned.
+	if max := -meta.ShardGroupDeletedExpiration; time.Duration(c.GracePeriod) >= max {
+		return fmt.Errorf("grace-period must be less than %s", max)
+	}
+
+	for _, m := range c.Measurements {
+		if m.Database == "" || m.Measurement == "" {
+			return errors.New("measurement retention must name a database and a measurement")
+		} else if m.Duration <= 0 {
+			return fmt.Errorf("duration of measurement %s on %s must be positive", m.Measurement, m.Database)
+		}
+	}
+
 	return nil
 }
 
@@ -45,5 +87,8 @@ func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
 	return diagnostics.RowFromMap(map[string]interface{}{
 		"enabled":        true,
 		"check-interval": c.CheckInterval,
+		"grace-period":   c.GracePeriod,
+		"dry-run":        c.DryRun,
+		"measurements":   len(c.Measurements),
 	}), nil
 }
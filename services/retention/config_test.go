@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/services/retention"
+	itoml "github.com/influxdata/influxdb/toml"
 )
 
 func TestConfig_Parse(t *testing.T) {
@@ -14,6 +16,7 @@ func TestConfig_Parse(t *testing.T) {
 	if _, err := toml.Decode(`
 enabled = true
 check-interval = "1s"
+grace-period = "12h"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -23,6 +26,8 @@ check-interval = "1s"
 		t.Fatalf("unexpected enabled state: %v", c.Enabled)
 	} else if time.Duration(c.CheckInterval) != time.Second {
 		t.Fatalf("unexpected check interval: %v", c.CheckInterval)
+	} else if time.Duration(c.GracePeriod) != 12*time.Hour {
+		t.Fatalf("unexpected grace period: %v", c.GracePeriod)
 	}
 }
 
@@ -44,6 +49,18 @@ func TestConfig_Validate(t *testing.T) {
 		t.Fatal("expected error for negative check-interval, got nil")
 	}
 
+	c = retention.NewConfig()
+	c.GracePeriod = itoml.Duration(-time.Hour)
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for negative grace-period, got nil")
+	}
+
+	c = retention.NewConfig()
+	c.GracePeriod = itoml.Duration(-meta.ShardGroupDeletedExpiration)
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for grace-period beyond shard group expiration, got nil")
+	}
+
 	c.Enabled = false
 	if err := c.Validate(); err != nil {
 		t.Fatalf("unexpected validation fail from disabled config: %s", err)
@@ -6,7 +6,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxql"
 	"go.uber.org/zap"
 )
 
@@ -20,23 +22,48 @@ type Service struct {
 	TSDBStore interface {
 		ShardIDs() []uint64
 		DeleteShard(shardID uint64) error
+		DeleteSeriesFromShards(database string, shardIDs []uint64, sources []influxql.Source, condition influxql.Expr) error
+		ShardLastModified(shardID uint64) time.Time
+	}
+
+	// Monitor records the deleted shard groups in the monitor database.
+	Monitor interface {
+		Enabled() bool
+		WritePoints(models.Points) error
 	}
 
 	config Config
 	wg     sync.WaitGroup
 	done   chan struct{}
 
+	// expired holds the last modification time of the shards entirely
+	// before the cutoff of a measurement retention, once its data has been
+	// deleted from them. It is only used by the goroutine of run.
+	expired map[expiredShard]time.Time
+
 	logger *zap.Logger
 }
 
 // NewService returns a configured retention policy enforcement service.
 func NewService(c Config) *Service {
 	return &Service{
-		config: c,
-		logger: zap.NewNop(),
+		Monitor: nullMonitor{},
+		config:  c,
+		logger:  zap.NewNop(),
 	}
 }
 
+// expiredShard is a shard whose data of a measurement has been expired.
+type expiredShard struct {
+	database, measurement string
+	id                    uint64
+}
+
+type nullMonitor struct{}
+
+func (nullMonitor) Enabled() bool                   { return false }
+func (nullMonitor) WritePoints(models.Points) error { return nil }
+
 // Open starts retention policy enforcement.
 func (s *Service) Open() error {
 	if !s.config.Enabled || s.done != nil {
@@ -44,6 +71,9 @@ func (s *Service) Open() error {
 	}
 
 	s.logger.Info("Starting retention policy enforcement service", zap.String("check-interval", s.config.CheckInterval.String()))
+	if s.config.DryRun {
+		s.logger.Info("Retention policy enforcement is in dry-run mode, no data will be deleted.")
+	}
 	s.done = make(chan struct{})
 
 	s.wg.Add(1)
@@ -87,28 +117,63 @@ func (s *Service) run() {
 			}
 			deletedShardIDs := make(map[uint64]deletionInfo)
 
+			now := time.Now().UTC()
+			grace := time.Duration(s.config.GracePeriod)
+
 			dbs := s.MetaClient.Databases()
 			for _, d := range dbs {
 				for _, r := range d.RetentionPolicies {
-					for _, g := range r.ExpiredShardGroups(time.Now().UTC()) {
-						if err := s.MetaClient.DeleteShardGroup(d.Name, r.Name, g.ID); err != nil {
+					for _, g := range r.ExpiredShardGroups(now) {
+						if s.config.DryRun {
+							s.logger.Info(fmt.Sprintf("Dry run: shard group %d from database %s, retention policy %s, ending at %s would be deleted.", g.ID, d.Name, r.Name, g.EndTime.Format(time.RFC3339)))
+						} else if err := s.MetaClient.DeleteShardGroup(d.Name, r.Name, g.ID); err != nil {
 							s.logger.Info(fmt.Sprintf("Failed to delete shard group %d from database %s, retention policy %s: %v. Retry in %v.", g.ID, d.Name, r.Name, err, s.config.CheckInterval))
 							continue
+						} else {
+							s.logger.Info(fmt.Sprintf("Deleted shard group %d from database %s, retention policy %s.", g.ID, d.Name, r.Name))
+							s.auditDeletion(d.Name, r.Name, g, now)
 						}
 
-						s.logger.Info(fmt.Sprintf("Deleted shard group %d from database %s, retention policy %s.", g.ID, d.Name, r.Name))
+						// Without a grace period the shards are removed
+						// immediately, otherwise they are left on disk until
+						// the grace period has elapsed.
+						if grace > 0 {
+							if !s.config.DryRun {
+								s.logger.Info(fmt.Sprintf("Shards of shard group %d from database %s, retention policy %s, will be deleted after %v.", g.ID, d.Name, r.Name, s.config.GracePeriod))
+							}
+							continue
+						}
 
 						// Store all the shard IDs that may possibly need to be removed locally.
 						for _, sh := range g.Shards {
 							deletedShardIDs[sh.ID] = deletionInfo{db: d.Name, rp: r.Name}
 						}
 					}
+
+					// Shard groups deleted by a previous check whose grace
+					// period has elapsed. This also retries local deletions
+					// that failed during an earlier check.
+					for _, g := range r.DeletedShardGroups() {
+						if g.DeletedAt.Add(grace).After(now) {
+							continue
+						}
+						for _, sh := range g.Shards {
+							deletedShardIDs[sh.ID] = deletionInfo{db: d.Name, rp: r.Name}
+						}
+					}
 				}
 			}
 
-			// Remove shards if we store them locally
+			s.expireMeasurements(dbs, now)
+
+			// Remove shards if we store them locally. A dry run only logs
+			// them, so that shards already removed are not logged again.
 			for _, id := range s.TSDBStore.ShardIDs() {
 				if info, ok := deletedShardIDs[id]; ok {
+					if s.config.DryRun {
+						s.logger.Info(fmt.Sprintf("Dry run: shard ID %d from database %s, retention policy %s, would be deleted.", id, info.db, info.rp))
+						continue
+					}
 					if err := s.TSDBStore.DeleteShard(id); err != nil {
 						s.logger.Error(fmt.Sprintf("Failed to delete shard ID %d from database %s, retention policy %s: %v. Will retry in %v", id, info.db, info.rp, err, s.config.CheckInterval))
 						continue
@@ -117,9 +182,97 @@ func (s *Service) run() {
 				}
 			}
 
+			if s.config.DryRun {
+				continue
+			}
+
 			if err := s.MetaClient.PruneShardGroups(); err != nil {
 				s.logger.Info(fmt.Sprintf("Problem pruning shard groups: %s. Will retry in %v", err, s.config.CheckInterval))
 			}
 		}
 	}
 }
+
+// expireMeasurements deletes the data of the measurements with their own
+// retention that is older than their duration. Only the shards of dbs
+// starting before the cutoff are read, and the shards entirely before it are
+// skipped once their data was deleted, until they are written to again. The
+// other shards would otherwise be reopened at every check.
+func (s *Service) expireMeasurements(dbs []meta.DatabaseInfo, now time.Time) {
+	expired := make(map[expiredShard]time.Time)
+	defer func() { s.expired = expired }()
+
+	for _, m := range s.config.Measurements {
+		cutoff := now.Add(-time.Duration(m.Duration))
+
+		var ids, before []uint64
+		for _, d := range dbs {
+			if d.Name != m.Database {
+				continue
+			}
+			for _, r := range d.RetentionPolicies {
+				for _, g := range r.ShardGroups {
+					if g.Deleted() || !g.StartTime.Before(cutoff) {
+						continue
+					}
+					for _, sh := range g.Shards {
+						key := expiredShard{database: m.Database, measurement: m.Measurement, id: sh.ID}
+						if lm, ok := s.expired[key]; ok && s.TSDBStore.ShardLastModified(sh.ID).Equal(lm) {
+							expired[key] = lm
+							continue
+						}
+						ids = append(ids, sh.ID)
+						if !g.EndTime.After(cutoff) {
+							before = append(before, sh.ID)
+						}
+					}
+				}
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		if s.config.DryRun {
+			s.logger.Info(fmt.Sprintf("Dry run: data of measurement %s from database %s before %s would be deleted.", m.Measurement, m.Database, cutoff.Format(time.RFC3339)))
+			continue
+		}
+
+		sources := influxql.Sources{&influxql.Measurement{Database: m.Database, Name: m.Measurement}}
+		cond := &influxql.BinaryExpr{
+			Op:  influxql.LT,
+			LHS: &influxql.VarRef{Val: "time"},
+			RHS: &influxql.TimeLiteral{Val: cutoff},
+		}
+		if err := s.TSDBStore.DeleteSeriesFromShards(m.Database, ids, sources, cond); err != nil {
+			s.logger.Info(fmt.Sprintf("Failed to expire measurement %s from database %s: %v. Will retry in %v.", m.Measurement, m.Database, err, s.config.CheckInterval))
+			continue
+		}
+		for _, id := range before {
+			expired[expiredShard{database: m.Database, measurement: m.Measurement, id: id}] = s.TSDBStore.ShardLastModified(id)
+		}
+	}
+}
+
+// auditDeletion records the deletion of shard group g of database db and
+// retention policy rp in the monitor database.
+func (s *Service) auditDeletion(db, rp string, g *meta.ShardGroupInfo, now time.Time) {
+	if !s.Monitor.Enabled() {
+		return
+	}
+
+	tags := map[string]string{"db": db, "rp": rp}
+	fields := map[string]interface{}{
+		"shardGroupID":  int64(g.ID),
+		"startTime":     g.StartTime.UnixNano(),
+		"endTime":       g.EndTime.UnixNano(),
+		"shardN":        int64(len(g.Shards)),
+		"gracePeriodNs": int64(s.config.GracePeriod),
+	}
+	p, err := models.NewPoint("retention_deletion", models.NewTags(tags), fields, now)
+	if err != nil {
+		s.logger.Info(fmt.Sprintf("Failed to record deletion of shard group %d: %s", g.ID, err))
+		return
+	}
+	s.Monitor.WritePoints(models.Points{p})
+}
@@ -4,15 +4,18 @@ import (
 	"bytes"
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/influxdata/influxdb/internal"
 	"github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/services/retention"
 	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/influxql"
 )
 
 func TestService_OpenDisabled(t *testing.T) {
@@ -205,6 +208,391 @@ func TestService_CheckShards(t *testing.T) {
 	}
 }
 
+func TestService_CheckShards_GracePeriod(t *testing.T) {
+	now := time.Now().UTC()
+
+	var mu sync.Mutex
+	data := []meta.DatabaseInfo{
+		{
+			Name: "db0",
+			DefaultRetentionPolicy: "rp0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{
+				{
+					Name:               "rp0",
+					ReplicaN:           1,
+					Duration:           time.Hour,
+					ShardGroupDuration: time.Hour,
+					ShardGroups: []meta.ShardGroupInfo{
+						{
+							// Deleted long enough ago for the grace period to have elapsed.
+							ID:        1,
+							StartTime: now.Add(-6 * time.Hour),
+							EndTime:   now.Add(-5 * time.Hour),
+							DeletedAt: now.Add(-3 * time.Hour),
+							Shards:    []meta.ShardInfo{{ID: 2}},
+						},
+						{
+							// Deleted recently, still within the grace period.
+							ID:        3,
+							StartTime: now.Add(-5 * time.Hour),
+							EndTime:   now.Add(-4 * time.Hour),
+							DeletedAt: now.Add(-time.Hour),
+							Shards:    []meta.ShardInfo{{ID: 4}},
+						},
+						{
+							// Expired but not yet deleted.
+							ID:        5,
+							StartTime: now.Add(-4 * time.Hour),
+							EndTime:   now.Add(-3 * time.Hour),
+							Shards:    []meta.ShardInfo{{ID: 6}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	config := retention.NewConfig()
+	config.CheckInterval = toml.Duration(10 * time.Millisecond)
+	config.GracePeriod = toml.Duration(2 * time.Hour)
+	s := NewService(config)
+	s.MetaClient.DatabasesFn = func() []meta.DatabaseInfo {
+		mu.Lock()
+		defer mu.Unlock()
+		return data
+	}
+
+	deletedShardGroups := make(map[uint64]struct{})
+	s.MetaClient.DeleteShardGroupFn = func(database, policy string, id uint64) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sgs := data[0].RetentionPolicies[0].ShardGroups
+		for i := range sgs {
+			if sgs[i].ID == id {
+				sgs[i].DeletedAt = time.Now().UTC()
+			}
+		}
+		deletedShardGroups[id] = struct{}{}
+		return nil
+	}
+
+	checked := make(chan struct{})
+	var once sync.Once
+	s.MetaClient.PruneShardGroupsFn = func() error {
+		once.Do(func() { close(checked) })
+		return nil
+	}
+
+	deletedShards := make(map[uint64]struct{})
+	s.TSDBStore.ShardIDsFn = func() []uint64 {
+		return []uint64{2, 4, 6}
+	}
+	s.TSDBStore.DeleteShardFn = func(shardID uint64) error {
+		mu.Lock()
+		defer mu.Unlock()
+		deletedShards[shardID] = struct{}{}
+		return nil
+	}
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("unexpected open error: %s", err)
+	}
+
+	timer := time.NewTimer(100 * time.Millisecond)
+	select {
+	case <-checked:
+		timer.Stop()
+	case <-timer.C:
+		t.Fatal("timeout waiting for retention check")
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected close error: %s", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got, want := deletedShardGroups, map[uint64]struct{}{5: struct{}{}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected deleted shard groups: got=%#v want=%#v", got, want)
+	}
+	if got, want := deletedShards, map[uint64]struct{}{2: struct{}{}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected deleted shards: got=%#v want=%#v", got, want)
+	}
+}
+
+func TestService_CheckShards_DryRun(t *testing.T) {
+	now := time.Now().UTC()
+	data := []meta.DatabaseInfo{
+		{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{
+				{
+					Name:     "rp0",
+					Duration: time.Hour,
+					ShardGroups: []meta.ShardGroupInfo{
+						{
+							ID:        1,
+							StartTime: now.Add(-3 * time.Hour),
+							EndTime:   now.Add(-2 * time.Hour),
+							Shards:    []meta.ShardInfo{{ID: 2}},
+						},
+						{
+							ID:        3,
+							StartTime: now.Add(-4 * time.Hour),
+							EndTime:   now.Add(-3 * time.Hour),
+							DeletedAt: now.Add(-time.Hour),
+							Shards:    []meta.ShardInfo{{ID: 4}},
+						},
+						{
+							ID:        5,
+							StartTime: now.Add(-5 * time.Hour),
+							EndTime:   now.Add(-4 * time.Hour),
+							DeletedAt: now.Add(-time.Hour),
+							Shards:    []meta.ShardInfo{{ID: 6}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	config := retention.NewConfig()
+	config.CheckInterval = toml.Duration(10 * time.Millisecond)
+	config.DryRun = true
+	s := NewService(config)
+
+	// The first check has completed once the databases are read again.
+	var calls int
+	checked := make(chan struct{})
+	s.MetaClient.DatabasesFn = func() []meta.DatabaseInfo {
+		if calls++; calls == 2 {
+			close(checked)
+		}
+		return data
+	}
+	s.MetaClient.DeleteShardGroupFn = func(database, policy string, id uint64) error {
+		t.Errorf("unexpected deletion of shard group %d", id)
+		return nil
+	}
+	s.MetaClient.PruneShardGroupsFn = func() error {
+		t.Error("unexpected pruning of shard groups")
+		return nil
+	}
+	// The shards of deleted shard group 3 were already removed.
+	s.TSDBStore.ShardIDsFn = func() []uint64 { return []uint64{2, 6} }
+	s.TSDBStore.DeleteShardFn = func(shardID uint64) error {
+		t.Errorf("unexpected deletion of shard %d", shardID)
+		return nil
+	}
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("unexpected open error: %s", err)
+	}
+
+	timer := time.NewTimer(time.Second)
+	select {
+	case <-checked:
+		timer.Stop()
+	case <-timer.C:
+		t.Fatal("timeout waiting for retention check")
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected close error: %s", err)
+	}
+
+	out := s.LogBuf.String()
+	if !strings.Contains(out, "Dry run: shard group 1 from database db0, retention policy rp0") {
+		t.Errorf("expected expired shard group to be logged, got %q", out)
+	}
+	for _, id := range []int{2, 6} {
+		if !strings.Contains(out, fmt.Sprintf("Dry run: shard ID %d from database db0, retention policy rp0, would be deleted.", id)) {
+			t.Errorf("expected shard %d to be logged, got %q", id, out)
+		}
+	}
+	if strings.Contains(out, "shard ID 4") {
+		t.Errorf("unexpected log of removed shard 4, got %q", out)
+	}
+}
+
+func TestService_CheckShards_Audit(t *testing.T) {
+	now := time.Now().UTC()
+	data := []meta.DatabaseInfo{
+		{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{
+				{
+					Name:     "rp0",
+					Duration: time.Hour,
+					ShardGroups: []meta.ShardGroupInfo{
+						{
+							ID:        1,
+							StartTime: now.Add(-3 * time.Hour),
+							EndTime:   now.Add(-2 * time.Hour),
+							Shards:    []meta.ShardInfo{{ID: 2}, {ID: 3}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	config := retention.NewConfig()
+	config.CheckInterval = toml.Duration(10 * time.Millisecond)
+	s := NewService(config)
+
+	var mu sync.Mutex
+	s.MetaClient.DatabasesFn = func() []meta.DatabaseInfo {
+		mu.Lock()
+		defer mu.Unlock()
+		return data
+	}
+	s.MetaClient.DeleteShardGroupFn = func(database, policy string, id uint64) error {
+		mu.Lock()
+		defer mu.Unlock()
+		data[0].RetentionPolicies[0].ShardGroups[0].DeletedAt = time.Now().UTC()
+		return nil
+	}
+	s.MetaClient.PruneShardGroupsFn = func() error { return nil }
+	s.TSDBStore.ShardIDsFn = func() []uint64 { return nil }
+
+	points := make(chan models.Point, 10)
+	s.Service.Monitor = &monitor{points: points}
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("unexpected open error: %s", err)
+	}
+	defer s.Close()
+
+	timer := time.NewTimer(time.Second)
+	select {
+	case p := <-points:
+		timer.Stop()
+		if got, want := string(p.Name()), "retention_deletion"; got != want {
+			t.Errorf("unexpected measurement: got=%s want=%s", got, want)
+		} else if got, want := p.Tags().GetString("db")+"."+p.Tags().GetString("rp"), "db0.rp0"; got != want {
+			t.Errorf("unexpected tags: got=%s want=%s", got, want)
+		}
+		fields, err := p.Fields()
+		if err != nil {
+			t.Fatal(err)
+		} else if fields["shardGroupID"] != int64(1) || fields["shardN"] != int64(2) {
+			t.Errorf("unexpected fields: %v", fields)
+		}
+	case <-timer.C:
+		t.Fatal("timeout waiting for audit record")
+	}
+}
+
+func TestService_ExpireMeasurements(t *testing.T) {
+	config := retention.NewConfig()
+	config.CheckInterval = toml.Duration(10 * time.Millisecond)
+	config.Measurements = []retention.MeasurementRetention{
+		{Database: "db0", Measurement: "cpu", Duration: toml.Duration(time.Hour)},
+	}
+	s := NewService(config)
+
+	// Shard 1 is entirely before the cutoff, shard 2 contains it and shard 3
+	// is after it.
+	now := time.Now().UTC()
+	s.MetaClient.DatabasesFn = func() []meta.DatabaseInfo {
+		return []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name: "rp0",
+				ShardGroups: []meta.ShardGroupInfo{
+					{ID: 1, StartTime: now.Add(-3 * time.Hour), EndTime: now.Add(-2 * time.Hour), Shards: []meta.ShardInfo{{ID: 1}}},
+					{ID: 2, StartTime: now.Add(-90 * time.Minute), EndTime: now.Add(-30 * time.Minute), Shards: []meta.ShardInfo{{ID: 2}}},
+					{ID: 3, StartTime: now.Add(-30 * time.Minute), EndTime: now.Add(30 * time.Minute), Shards: []meta.ShardInfo{{ID: 3}}},
+				},
+			}},
+		}}
+	}
+	s.MetaClient.PruneShardGroupsFn = func() error { return nil }
+	s.TSDBStore.ShardIDsFn = func() []uint64 { return nil }
+
+	var mu sync.Mutex
+	modified := now.Add(-2 * time.Hour)
+	s.TSDBStore.ShardLastModifiedFn = func(id uint64) time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return modified
+	}
+
+	type deletion struct {
+		database string
+		shardIDs []uint64
+		sources  []influxql.Source
+		cond     influxql.Expr
+	}
+	deletions := make(chan deletion, 10)
+	s.TSDBStore.DeleteSeriesFromShardsFn = func(database string, shardIDs []uint64, sources []influxql.Source, condition influxql.Expr) error {
+		deletions <- deletion{database: database, shardIDs: shardIDs, sources: sources, cond: condition}
+		return nil
+	}
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("unexpected open error: %s", err)
+	}
+	defer s.Close()
+
+	next := func() deletion {
+		timer := time.NewTimer(time.Second)
+		defer timer.Stop()
+		select {
+		case d := <-deletions:
+			return d
+		case <-timer.C:
+			t.Fatal("timeout waiting for measurement expiry")
+		}
+		return deletion{}
+	}
+
+	d := next()
+	if d.database != "db0" {
+		t.Errorf("unexpected database: %s", d.database)
+	} else if got, want := d.shardIDs, []uint64{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected shards: got=%v want=%v", got, want)
+	} else if len(d.sources) != 1 || d.sources[0].(*influxql.Measurement).Name != "cpu" {
+		t.Errorf("unexpected sources: %v", d.sources)
+	}
+
+	_, tr, err := influxql.ConditionExpr(d.cond, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if cutoff := tr.Max.Add(time.Nanosecond); cutoff.Before(now.Add(-time.Hour)) || cutoff.After(time.Now().Add(-time.Hour)) {
+		t.Errorf("unexpected cutoff: %s", cutoff)
+	}
+
+	// The expired shard is skipped until it is written to again.
+	if got, want := next().shardIDs, []uint64{2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected shards: got=%v want=%v", got, want)
+	}
+
+	mu.Lock()
+	modified = now
+	mu.Unlock()
+	for {
+		if ids := next().shardIDs; len(ids) == 2 {
+			break
+		}
+	}
+}
+
+// monitor records the points written to the monitor database.
+type monitor struct {
+	points chan models.Point
+}
+
+func (m *monitor) Enabled() bool { return true }
+
+func (m *monitor) WritePoints(points models.Points) error {
+	for _, p := range points {
+		m.points <- p
+	}
+	return nil
+}
+
 // This reproduces https://github.com/influxdata/influxdb/issues/8819
 func TestService_8819_repro(t *testing.T) {
 	for i := 0; i < 1000; i++ {
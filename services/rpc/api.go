@@ -0,0 +1,241 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/influxdata/yarpc"
+)
+
+// The messages below match api.proto. They are encoded with the reflection
+// based protobuf marshaler, so clients in other languages can generate their
+// own types from api.proto.
+
+type WriteRequest struct {
+	Database        string `protobuf:"bytes,1,opt,name=database,proto3"`
+	RetentionPolicy string `protobuf:"bytes,2,opt,name=retention_policy,json=retentionPolicy,proto3"`
+	Precision       string `protobuf:"bytes,3,opt,name=precision,proto3"`
+	Username        string `protobuf:"bytes,4,opt,name=username,proto3"`
+	Password        string `protobuf:"bytes,5,opt,name=password,proto3"`
+	Points          []byte `protobuf:"bytes,6,opt,name=points,proto3"`
+	Token           string `protobuf:"bytes,7,opt,name=token,proto3"`
+}
+
+func (m *WriteRequest) Reset()         { *m = WriteRequest{} }
+func (m *WriteRequest) String() string { return proto.CompactTextString(m) }
+func (*WriteRequest) ProtoMessage()    {}
+
+type WriteResponse struct {
+	PointsWritten int64 `protobuf:"varint,1,opt,name=points_written,json=pointsWritten,proto3"`
+}
+
+func (m *WriteResponse) Reset()         { *m = WriteResponse{} }
+func (m *WriteResponse) String() string { return proto.CompactTextString(m) }
+func (*WriteResponse) ProtoMessage()    {}
+
+type QueryRequest struct {
+	Database  string `protobuf:"bytes,1,opt,name=database,proto3"`
+	Query     string `protobuf:"bytes,2,opt,name=query,proto3"`
+	Username  string `protobuf:"bytes,3,opt,name=username,proto3"`
+	Password  string `protobuf:"bytes,4,opt,name=password,proto3"`
+	ChunkSize int32  `protobuf:"varint,5,opt,name=chunk_size,json=chunkSize,proto3"`
+	Token     string `protobuf:"bytes,6,opt,name=token,proto3"`
+}
+
+func (m *QueryRequest) Reset()         { *m = QueryRequest{} }
+func (m *QueryRequest) String() string { return proto.CompactTextString(m) }
+func (*QueryRequest) ProtoMessage()    {}
+
+type QueryResponse struct {
+	StatementID int32     `protobuf:"varint,1,opt,name=statement_id,json=statementId,proto3"`
+	Series      []*Series `protobuf:"bytes,2,rep,name=series"`
+	Messages    []string  `protobuf:"bytes,3,rep,name=messages"`
+	Error       string    `protobuf:"bytes,4,opt,name=error,proto3"`
+	Partial     bool      `protobuf:"varint,5,opt,name=partial,proto3"`
+}
+
+func (m *QueryResponse) Reset()         { *m = QueryResponse{} }
+func (m *QueryResponse) String() string { return proto.CompactTextString(m) }
+func (*QueryResponse) ProtoMessage()    {}
+
+type Series struct {
+	Name    string            `protobuf:"bytes,1,opt,name=name,proto3"`
+	Tags    map[string]string `protobuf:"bytes,2,rep,name=tags" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Columns []string          `protobuf:"bytes,3,rep,name=columns"`
+	Rows    []*Row            `protobuf:"bytes,4,rep,name=rows"`
+	Partial bool              `protobuf:"varint,5,opt,name=partial,proto3"`
+}
+
+func (m *Series) Reset()         { *m = Series{} }
+func (m *Series) String() string { return proto.CompactTextString(m) }
+func (*Series) ProtoMessage()    {}
+
+type Row struct {
+	Values []*Value `protobuf:"bytes,1,rep,name=values"`
+}
+
+func (m *Row) Reset()         { *m = Row{} }
+func (m *Row) String() string { return proto.CompactTextString(m) }
+func (*Row) ProtoMessage()    {}
+
+// ValueType is the type of a Value.
+type ValueType int32
+
+const (
+	ValueTypeNull     ValueType = 0
+	ValueTypeFloat    ValueType = 1
+	ValueTypeInteger  ValueType = 2
+	ValueTypeUnsigned ValueType = 3
+	ValueTypeString   ValueType = 4
+	ValueTypeBoolean  ValueType = 5
+
+	// ValueTypeTime values are stored in IntegerValue as nanoseconds since
+	// the epoch.
+	ValueTypeTime ValueType = 6
+)
+
+var valueTypeNames = map[ValueType]string{
+	ValueTypeNull:     "NULL",
+	ValueTypeFloat:    "FLOAT",
+	ValueTypeInteger:  "INTEGER",
+	ValueTypeUnsigned: "UNSIGNED",
+	ValueTypeString:   "STRING",
+	ValueTypeBoolean:  "BOOLEAN",
+	ValueTypeTime:     "TIME",
+}
+
+func (t ValueType) String() string {
+	if s, ok := valueTypeNames[t]; ok {
+		return s
+	}
+	return "UNKNOWN"
+}
+
+type Value struct {
+	Type          ValueType `protobuf:"varint,1,opt,name=type,proto3,enum=influxdb.rpc.Value_ValueType"`
+	FloatValue    float64   `protobuf:"fixed64,2,opt,name=float_value,json=floatValue,proto3"`
+	IntegerValue  int64     `protobuf:"varint,3,opt,name=integer_value,json=integerValue,proto3"`
+	UnsignedValue uint64    `protobuf:"varint,4,opt,name=unsigned_value,json=unsignedValue,proto3"`
+	StringValue   string    `protobuf:"bytes,5,opt,name=string_value,json=stringValue,proto3"`
+	BooleanValue  bool      `protobuf:"varint,6,opt,name=boolean_value,json=booleanValue,proto3"`
+}
+
+func (m *Value) Reset()         { *m = Value{} }
+func (m *Value) String() string { return proto.CompactTextString(m) }
+func (*Value) ProtoMessage()    {}
+
+// APIClient is the client API for the API service.
+type APIClient interface {
+	Write(ctx context.Context, in *WriteRequest) (*WriteResponse, error)
+	Query(ctx context.Context, in *QueryRequest) (API_QueryClient, error)
+}
+
+type apiClient struct {
+	cc *yarpc.ClientConn
+}
+
+// NewAPIClient returns a client for the API service on cc.
+func NewAPIClient(cc *yarpc.ClientConn) APIClient {
+	return &apiClient{cc}
+}
+
+func (c *apiClient) Write(ctx context.Context, in *WriteRequest) (*WriteResponse, error) {
+	out := new(WriteResponse)
+	if err := yarpc.Invoke(ctx, 0x0000, in, out, c.cc); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) Query(ctx context.Context, in *QueryRequest) (API_QueryClient, error) {
+	stream, err := yarpc.NewClientStream(ctx, &_API_serviceDesc.Streams[0], c.cc, 0x0001)
+	if err != nil {
+		return nil, err
+	}
+	x := &apiQueryClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// API_QueryClient receives the responses to a query.
+type API_QueryClient interface {
+	Recv() (*QueryResponse, error)
+	yarpc.ClientStream
+}
+
+type apiQueryClient struct {
+	yarpc.ClientStream
+}
+
+func (x *apiQueryClient) Recv() (*QueryResponse, error) {
+	m := new(QueryResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// APIServer is the server API for the API service.
+type APIServer interface {
+	Write(context.Context, *WriteRequest) (*WriteResponse, error)
+	Query(*QueryRequest, API_QueryServer) error
+}
+
+// RegisterAPIServer registers srv with s.
+func RegisterAPIServer(s *yarpc.Server, srv APIServer) {
+	s.RegisterService(&_API_serviceDesc, srv)
+}
+
+func _API_Write_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error) (interface{}, error) {
+	in := new(WriteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	return srv.(APIServer).Write(ctx, in)
+}
+
+func _API_Query_Handler(srv interface{}, stream yarpc.ServerStream) error {
+	m := new(QueryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(APIServer).Query(m, &apiQueryServer{stream})
+}
+
+// API_QueryServer sends the responses to a query.
+type API_QueryServer interface {
+	Send(*QueryResponse) error
+	yarpc.ServerStream
+}
+
+type apiQueryServer struct {
+	yarpc.ServerStream
+}
+
+func (x *apiQueryServer) Send(m *QueryResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _API_serviceDesc = yarpc.ServiceDesc{
+	ServiceName: "influxdb.rpc.API",
+	Index:       0,
+	HandlerType: (*APIServer)(nil),
+	Methods: []yarpc.MethodDesc{
+		{
+			MethodName: "Write",
+			Index:      0,
+			Handler:    _API_Write_Handler,
+		},
+	},
+	Streams: []yarpc.StreamDesc{
+		{
+			StreamName:    "Query",
+			Index:         1,
+			Handler:       _API_Query_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api.proto",
+}
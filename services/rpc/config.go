@@ -0,0 +1,70 @@
+package rpc
+
+import (
+	"errors"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+)
+
+const (
+	// DefaultBindAddress is the default address to bind to.
+	DefaultBindAddress = ":8087"
+
+	// DefaultCertificate is the default location of the certificate used when TLS is enabled.
+	DefaultCertificate = "/etc/ssl/influxdb.pem"
+)
+
+// Config represents a configuration for the RPC API service.
+type Config struct {
+	Enabled     bool   `toml:"enabled"`
+	BindAddress string `toml:"bind-address"`
+	AuthEnabled bool   `toml:"auth-enabled"`
+	LogEnabled  bool   `toml:"log-enabled"`
+
+	// TLSEnabled serves the API over TLS with Certificate and PrivateKey.
+	// The private key is read from the certificate file if it is not set.
+	TLSEnabled  bool   `toml:"tls-enabled"`
+	Certificate string `toml:"certificate"`
+	PrivateKey  string `toml:"private-key"`
+}
+
+// NewConfig returns a new Config with default settings.
+func NewConfig() Config {
+	return Config{
+		Enabled:     false,
+		BindAddress: DefaultBindAddress,
+		LogEnabled:  true,
+		Certificate: DefaultCertificate,
+	}
+}
+
+// Validate returns an error if the config is invalid. Requests carry the
+// username and password of the user, so authentication requires TLS.
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.AuthEnabled && !c.TLSEnabled {
+		return errors.New("auth-enabled requires tls-enabled, since credentials are sent with every request")
+	} else if c.TLSEnabled && c.Certificate == "" {
+		return errors.New("certificate must be specified when tls-enabled is set")
+	}
+	return nil
+}
+
+// Diagnostics returns a diagnostics representation of a subset of the Config.
+func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
+	if !c.Enabled {
+		return diagnostics.RowFromMap(map[string]interface{}{
+			"enabled": false,
+		}), nil
+	}
+
+	return diagnostics.RowFromMap(map[string]interface{}{
+		"enabled":      true,
+		"bind-address": c.BindAddress,
+		"auth-enabled": c.AuthEnabled,
+		"log-enabled":  c.LogEnabled,
+		"tls-enabled":  c.TLSEnabled,
+	}), nil
+}
@@ -0,0 +1,30 @@
+package rpc_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/influxdb/services/rpc"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	c := rpc.NewConfig()
+	c.Enabled = true
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	c.AuthEnabled = true
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for auth-enabled without tls-enabled")
+	}
+
+	c.TLSEnabled = true
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	c.Certificate = ""
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for tls-enabled without a certificate")
+	}
+}
@@ -0,0 +1,349 @@
+// Package rpc provides an RPC API for writes and queries, alongside the
+// HTTP API, for clients that want a binary encoding and streaming results.
+// Requests are authenticated, authorized, limited by the quotas and recorded
+// in the audit log by the HTTP handler, as HTTP requests are.
+//
+// The API is served with yarpc, the RPC framework of the storage service,
+// rather than gRPC, which is not a dependency of the server. Clients need the
+// yarpc client library, or NewAPIClient for Go.
+package rpc // import "github.com/influxdata/influxdb/services/rpc"
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/services/audit"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxql"
+	"github.com/influxdata/yarpc"
+	"go.uber.org/zap"
+)
+
+// DefaultChunkSize is the maximum number of rows in each query response when
+// the request does not set a chunk size.
+const DefaultChunkSize = 10000
+
+// Service serves the RPC API.
+type Service struct {
+	config Config
+	ln     net.Listener
+	rpc    *yarpc.Server
+	Logger *zap.Logger
+
+	MetaClient interface {
+		Database(name string) *meta.DatabaseInfo
+	}
+
+	// HTTPHandler is the handler of the HTTP API, which authenticates and
+	// authorizes the requests and enforces the quotas.
+	HTTPHandler interface {
+		Authenticate(username, password, token string) (meta.User, error)
+		AuthorizeQuery(user meta.User, q *influxql.Query, database string) error
+		AuthorizeWrite(user meta.User, database, retentionPolicy string) error
+		QueryQuota(user meta.User, database string) (release func(), err error)
+		WriteQuota(user meta.User, database string, n int) error
+	}
+
+	// AuditLog records queries and writes. No audit log is kept when it is
+	// not set.
+	AuditLog interface {
+		Log(e audit.Event)
+	}
+
+	QueryExecutor *query.QueryExecutor
+
+	PointsWriter interface {
+		WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error
+	}
+}
+
+// NewService returns a new instance of Service.
+func NewService(c Config) *Service {
+	return &Service{
+		config: c,
+		Logger: zap.NewNop(),
+	}
+}
+
+// WithLogger sets the logger for the service.
+func (s *Service) WithLogger(log *zap.Logger) {
+	s.Logger = log.With(zap.String("service", "rpc"))
+}
+
+// Open starts the service.
+func (s *Service) Open() error {
+	s.Logger.Info("Starting RPC API service")
+
+	if s.config.AuthEnabled && !s.config.TLSEnabled {
+		return errors.New("rpc: auth-enabled requires tls-enabled")
+	} else if s.HTTPHandler == nil {
+		return errors.New("rpc: the http service must be enabled")
+	}
+
+	var config *tls.Config
+	if s.config.TLSEnabled {
+		key := s.config.PrivateKey
+		if key == "" {
+			key = s.config.Certificate
+		}
+		cert, err := tls.LoadX509KeyPair(s.config.Certificate, key)
+		if err != nil {
+			return err
+		}
+		config = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	ln, err := net.Listen("tcp", s.config.BindAddress)
+	if err != nil {
+		return err
+	}
+	if config != nil {
+		s.Logger.Info(fmt.Sprintf("Listening on TLS: %s", ln.Addr().String()))
+		ln = tls.NewListener(ln, config)
+	} else {
+		s.Logger.Info(fmt.Sprintf("Listening on %s", ln.Addr().String()))
+	}
+	s.ln = ln
+
+	s.rpc = yarpc.NewServer()
+	RegisterAPIServer(s.rpc, s)
+
+	go s.rpc.Serve(ln)
+	return nil
+}
+
+// Close stops the service.
+func (s *Service) Close() error {
+	if s.rpc != nil {
+		s.rpc.Stop()
+	}
+	return nil
+}
+
+// Addr returns the address the service is listening on.
+func (s *Service) Addr() net.Addr {
+	if s.ln == nil {
+		return nil
+	}
+	return s.ln.Addr()
+}
+
+// authenticate returns the user for a request when authentication is
+// enabled.
+func (s *Service) authenticate(username, password, token string) (meta.User, error) {
+	if !s.config.AuthEnabled {
+		return nil, nil
+	}
+	return s.HTTPHandler.Authenticate(username, password, token)
+}
+
+// audit records e in the audit log, if one is kept, once the request is
+// complete.
+func (s *Service) audit(e *audit.Event, err error) {
+	if s.AuditLog == nil {
+		return
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	e.Duration = time.Since(e.Time)
+	s.AuditLog.Log(*e)
+}
+
+// Write writes the points of a request to its database.
+func (s *Service) Write(ctx context.Context, req *WriteRequest) (*WriteResponse, error) {
+	e := &audit.Event{
+		Time:            time.Now().UTC(),
+		Action:          audit.ActionWrite,
+		User:            req.Username,
+		Database:        req.Database,
+		RetentionPolicy: req.RetentionPolicy,
+	}
+	resp, err := s.write(req, e)
+	s.audit(e, err)
+	return resp, err
+}
+
+// write writes the points of req, filling in the details of e.
+func (s *Service) write(req *WriteRequest, e *audit.Event) (*WriteResponse, error) {
+	user, err := s.authenticate(req.Username, req.Password, req.Token)
+	if err != nil {
+		return nil, err
+	} else if user != nil {
+		e.User = user.ID()
+	}
+
+	if req.Database == "" {
+		return nil, errors.New("database is required")
+	} else if di := s.MetaClient.Database(req.Database); di == nil {
+		return nil, fmt.Errorf("database not found: %q", req.Database)
+	}
+
+	if s.config.AuthEnabled {
+		if err := s.HTTPHandler.AuthorizeWrite(user, req.Database, req.RetentionPolicy); err != nil {
+			return nil, err
+		}
+	}
+
+	points, parseError := models.ParsePointsWithPrecision(req.Points, time.Now().UTC(), req.Precision)
+	if parseError != nil && len(points) == 0 {
+		if parseError.Error() == "EOF" {
+			return &WriteResponse{}, nil
+		}
+		return nil, parseError
+	}
+	e.Points = len(points)
+
+	if err := s.HTTPHandler.WriteQuota(user, req.Database, len(points)); err != nil {
+		return nil, err
+	}
+
+	if err := s.PointsWriter.WritePoints(req.Database, req.RetentionPolicy, models.ConsistencyLevelOne, user, points); err != nil {
+		return nil, err
+	} else if parseError != nil {
+		return nil, fmt.Errorf("partial write: %s", parseError)
+	}
+
+	if s.config.LogEnabled {
+		s.Logger.Info(fmt.Sprintf("Wrote %d points to %s", len(points), req.Database))
+	}
+	return &WriteResponse{PointsWritten: int64(len(points))}, nil
+}
+
+// Query executes the query of a request and sends a response for each chunk
+// of each result.
+func (s *Service) Query(req *QueryRequest, stream API_QueryServer) error {
+	e := &audit.Event{
+		Time:     time.Now().UTC(),
+		Action:   audit.ActionQuery,
+		User:     req.Username,
+		Database: req.Database,
+	}
+	err := s.query(req, stream, e)
+	s.audit(e, err)
+	return err
+}
+
+// query executes the query of req, filling in the details of e.
+func (s *Service) query(req *QueryRequest, stream API_QueryServer, e *audit.Event) error {
+	user, err := s.authenticate(req.Username, req.Password, req.Token)
+	if err != nil {
+		return err
+	} else if user != nil {
+		e.User = user.ID()
+	}
+
+	q, err := influxql.ParseQuery(req.Query)
+	if err != nil {
+		return fmt.Errorf("error parsing query: %s", err)
+	}
+	// The string form of a query redacts passwords.
+	e.Query = q.String()
+	e.Action = audit.QueryAction(q)
+
+	opts := query.ExecutionOptions{
+		Database:  req.Database,
+		ChunkSize: int(req.ChunkSize),
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+
+	if s.config.AuthEnabled {
+		if err := s.HTTPHandler.AuthorizeQuery(user, q, req.Database); err != nil {
+			return err
+		}
+		opts.Authorizer = user
+	} else {
+		opts.Authorizer = query.OpenAuthorizer
+	}
+
+	release, err := s.HTTPHandler.QueryQuota(user, req.Database)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if s.config.LogEnabled {
+		s.Logger.Info(fmt.Sprintf("Executing query: %s", q))
+	}
+
+	closing := make(chan struct{})
+	defer close(closing)
+
+	for r := range s.QueryExecutor.ExecuteQuery(q, opts, closing) {
+		if r == nil {
+			continue
+		}
+		if err := stream.Send(encodeResult(r)); err != nil {
+			// The client is gone. Closing aborts the rest of the query.
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeResult converts a query result to a response.
+func encodeResult(r *query.Result) *QueryResponse {
+	resp := &QueryResponse{
+		StatementID: int32(r.StatementID),
+		Partial:     r.Partial,
+	}
+	if r.Err != nil {
+		resp.Error = r.Err.Error()
+	}
+	for _, m := range r.Messages {
+		resp.Messages = append(resp.Messages, m.Level+": "+m.Text)
+	}
+
+	resp.Series = make([]*Series, 0, len(r.Series))
+	for _, row := range r.Series {
+		series := &Series{
+			Name:    row.Name,
+			Tags:    row.Tags,
+			Columns: row.Columns,
+			Rows:    make([]*Row, len(row.Values)),
+			Partial: row.Partial,
+		}
+		for i, values := range row.Values {
+			a := make([]*Value, len(values))
+			for j, v := range values {
+				a[j] = encodeValue(v)
+			}
+			series.Rows[i] = &Row{Values: a}
+		}
+		resp.Series = append(resp.Series, series)
+	}
+	return resp
+}
+
+// encodeValue converts a value of a row to a Value. Values of types the
+// query engine does not return are sent as strings.
+func encodeValue(v interface{}) *Value {
+	switch v := v.(type) {
+	case nil:
+		return &Value{Type: ValueTypeNull}
+	case float64:
+		return &Value{Type: ValueTypeFloat, FloatValue: v}
+	case int64:
+		return &Value{Type: ValueTypeInteger, IntegerValue: v}
+	case int:
+		return &Value{Type: ValueTypeInteger, IntegerValue: int64(v)}
+	case uint64:
+		return &Value{Type: ValueTypeUnsigned, UnsignedValue: v}
+	case string:
+		return &Value{Type: ValueTypeString, StringValue: v}
+	case bool:
+		return &Value{Type: ValueTypeBoolean, BooleanValue: v}
+	case time.Time:
+		return &Value{Type: ValueTypeTime, IntegerValue: v.UnixNano()}
+	default:
+		return &Value{Type: ValueTypeString, StringValue: fmt.Sprint(v)}
+	}
+}
@@ -0,0 +1,306 @@
+package rpc_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/internal"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/services/audit"
+	"github.com/influxdata/influxdb/services/httpd"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/services/rpc"
+	"github.com/influxdata/influxql"
+	"github.com/influxdata/yarpc"
+)
+
+func TestService_Write(t *testing.T) {
+	s := NewService(rpc.NewConfig(), httpd.NewConfig())
+
+	var written []models.Point
+	s.PointsWriter.WritePointsFn = func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error {
+		if database != "db0" || retentionPolicy != "rp0" {
+			t.Fatalf("unexpected target: %s.%s", database, retentionPolicy)
+		}
+		written = points
+		return nil
+	}
+
+	resp, err := s.Write(context.Background(), &rpc.WriteRequest{
+		Database:        "db0",
+		RetentionPolicy: "rp0",
+		Precision:       "s",
+		Points:          []byte("cpu value=1 10\ncpu value=2 20\n"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.PointsWritten != 2 {
+		t.Fatalf("unexpected points written: %d", resp.PointsWritten)
+	} else if len(written) != 2 || written[1].Time() != time.Unix(20, 0).UTC() {
+		t.Fatalf("unexpected points: %v", written)
+	}
+
+	if _, err := s.Write(context.Background(), &rpc.WriteRequest{Database: "missing"}); err == nil {
+		t.Fatal("expected error for a missing database")
+	}
+}
+
+func TestService_Write_Auth(t *testing.T) {
+	c := rpc.NewConfig()
+	c.AuthEnabled = true
+	s := NewService(c, httpd.NewConfig())
+	s.PointsWriter.WritePointsFn = func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error {
+		return nil
+	}
+
+	// As with the HTTP API, requests are not authenticated until an admin
+	// user exists, but writes still need a user.
+	if _, err := s.Write(context.Background(), &rpc.WriteRequest{Database: "db0"}); err == nil || err.Error() != `user is required to write to database "db0"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s.MetaClient.AdminUserExistsFn = func() bool { return true }
+	if _, err := s.Write(context.Background(), &rpc.WriteRequest{Database: "db0"}); err == nil || err.Error() != "username required" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.Write(context.Background(), &rpc.WriteRequest{Database: "db0", Username: "bob", Password: "bad"}); err == nil || err.Error() != "authorization failed" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// API tokens are accepted, and authorize their writes themselves.
+	s.MetaClient.AuthenticateTokenFn = func(token string) (meta.User, error) {
+		if token != "secret" {
+			return nil, meta.ErrTokenNotFound
+		}
+		return &meta.TokenUser{TokenInfo: meta.TokenInfo{ID: "t1", Database: "db0", Privilege: influxql.WritePrivilege}}, nil
+	}
+	if _, err := s.Write(context.Background(), &rpc.WriteRequest{Database: "db0", Token: "secret", Points: []byte("cpu value=1")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.Write(context.Background(), &rpc.WriteRequest{Database: "db1", Token: "secret", Points: []byte("cpu value=1")}); err == nil || err.Error() != `"token:t1" user is not authorized to write to database "db1"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure writes count against the quotas of the HTTP API.
+func TestService_Write_Quota(t *testing.T) {
+	hc := httpd.NewConfig()
+	hc.DatabaseWritePointsPerSecond = 1
+	s := NewService(rpc.NewConfig(), hc)
+	s.PointsWriter.WritePointsFn = func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error {
+		return nil
+	}
+
+	req := &rpc.WriteRequest{Database: "db0", Points: []byte("cpu value=1\ncpu value=2\n")}
+	if _, err := s.Write(context.Background(), req); err != nil {
+		t.Fatal(err)
+	} else if _, err := s.Write(context.Background(), req); err == nil || err.Error() != `database "db0" exceeded write quota` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure writes and queries are recorded in the audit log.
+func TestService_Audit(t *testing.T) {
+	s := NewService(rpc.NewConfig(), httpd.NewConfig())
+	var events []audit.Event
+	s.Service.AuditLog = AuditLogFunc(func(e audit.Event) { events = append(events, e) })
+	s.PointsWriter.WritePointsFn = func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error {
+		return nil
+	}
+	s.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		ctx.Results <- &query.Result{StatementID: ctx.StatementID}
+		return nil
+	}
+
+	if _, err := s.Write(context.Background(), &rpc.WriteRequest{Database: "db0", RetentionPolicy: "rp0", Points: []byte("cpu value=1")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Write(context.Background(), &rpc.WriteRequest{Database: "missing"}); err == nil {
+		t.Fatal("expected error for a missing database")
+	}
+	var stream QueryStream
+	if err := s.Query(&rpc.QueryRequest{Query: `DROP DATABASE db0`}, &stream); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("unexpected events: %v", events)
+	}
+	if e := events[0]; e.Action != audit.ActionWrite || e.Database != "db0" || e.RetentionPolicy != "rp0" || e.Points != 1 || e.Error != "" {
+		t.Errorf("unexpected write event: %+v", e)
+	}
+	if e := events[1]; e.Error != `database not found: "missing"` {
+		t.Errorf("unexpected failed write event: %+v", e)
+	}
+	if e := events[2]; e.Action != audit.ActionAdmin || e.Query != `DROP DATABASE db0` {
+		t.Errorf("unexpected query event: %+v", e)
+	}
+}
+
+// Ensure the first admin user can be created before any user exists.
+func TestService_Query_FirstUser(t *testing.T) {
+	c := rpc.NewConfig()
+	c.AuthEnabled = true
+	s := NewService(c, httpd.NewConfig())
+	var authorized bool
+	s.Handler.QueryAuthorizer = QueryAuthorizerFunc(func(u meta.User, q *influxql.Query, database string) error {
+		if u != nil {
+			t.Fatalf("unexpected user: %v", u)
+		}
+		authorized = true
+		return nil
+	})
+	s.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		ctx.Results <- &query.Result{StatementID: ctx.StatementID}
+		return nil
+	}
+
+	var stream QueryStream
+	if err := s.Query(&rpc.QueryRequest{Query: `CREATE USER admin WITH PASSWORD 'admin' WITH ALL PRIVILEGES`}, &stream); err != nil {
+		t.Fatal(err)
+	} else if !authorized {
+		t.Fatal("expected the query to be authorized")
+	}
+}
+
+// Ensure queries count against the quotas of the HTTP API.
+func TestService_Query_Quota(t *testing.T) {
+	hc := httpd.NewConfig()
+	hc.DatabaseQueriesPerSecond = 1
+	s := NewService(rpc.NewConfig(), hc)
+	s.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		ctx.Results <- &query.Result{StatementID: ctx.StatementID}
+		return nil
+	}
+
+	var stream QueryStream
+	req := &rpc.QueryRequest{Database: "db0", Query: `SELECT * FROM cpu`}
+	if err := s.Query(req, &stream); err != nil {
+		t.Fatal(err)
+	} else if err := s.Query(req, &stream); err == nil || err.Error() != `database "db0" exceeded query quota` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestService_Query(t *testing.T) {
+	s := NewService(rpc.NewConfig(), httpd.NewConfig())
+	s.StatementExecutor.ExecuteStatementFn = func(stmt influxql.Statement, ctx query.ExecutionContext) error {
+		if stmt.String() != `SELECT * FROM cpu` {
+			t.Fatalf("unexpected statement: %s", stmt)
+		} else if ctx.Database != "db0" {
+			t.Fatalf("unexpected database: %s", ctx.Database)
+		}
+		ctx.Results <- &query.Result{
+			StatementID: ctx.StatementID,
+			Series: models.Rows{{
+				Name:    "cpu",
+				Tags:    map[string]string{"host": "a"},
+				Columns: []string{"time", "value", "status"},
+				Values:  [][]interface{}{{time.Unix(0, 10).UTC(), 1.5, nil}},
+			}},
+		}
+		return nil
+	}
+
+	var stream QueryStream
+	if err := s.Query(&rpc.QueryRequest{Database: "db0", Query: `SELECT * FROM cpu`}, &stream); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []*rpc.QueryResponse{{
+		Series: []*rpc.Series{{
+			Name:    "cpu",
+			Tags:    map[string]string{"host": "a"},
+			Columns: []string{"time", "value", "status"},
+			Rows: []*rpc.Row{{Values: []*rpc.Value{
+				{Type: rpc.ValueTypeTime, IntegerValue: 10},
+				{Type: rpc.ValueTypeFloat, FloatValue: 1.5},
+				{Type: rpc.ValueTypeNull},
+			}}},
+		}},
+	}}
+	if !reflect.DeepEqual(stream.Responses, exp) {
+		t.Fatalf("unexpected responses: %v", stream.Responses)
+	}
+}
+
+// Service is a test wrapper for rpc.Service, authenticating its requests
+// with an HTTP handler.
+type Service struct {
+	*rpc.Service
+	Handler           *httpd.Handler
+	MetaClient        *internal.MetaClientMock
+	StatementExecutor StatementExecutor
+	PointsWriter      PointsWriter
+}
+
+// NewService returns a new instance of Service with mocks for db0 and no
+// users, and an HTTP handler configured with hc.
+func NewService(c rpc.Config, hc httpd.Config) *Service {
+	s := &Service{Service: rpc.NewService(c), Handler: httpd.NewHandler(hc)}
+	s.MetaClient = &internal.MetaClientMock{
+		AdminUserExistsFn: func() bool { return false },
+		AuthenticateFn: func(username, password string) (meta.User, error) {
+			return nil, meta.ErrUserNotFound
+		},
+		DatabaseFn: func(name string) *meta.DatabaseInfo {
+			if name != "db0" && name != "db1" {
+				return nil
+			}
+			return &meta.DatabaseInfo{Name: name}
+		},
+	}
+	s.Handler.MetaClient = s.MetaClient
+	s.Service.MetaClient = s.MetaClient
+	s.Service.HTTPHandler = s.Handler
+	s.Service.QueryExecutor = query.NewQueryExecutor()
+	s.Service.QueryExecutor.StatementExecutor = &s.StatementExecutor
+	s.Service.PointsWriter = &s.PointsWriter
+	return s
+}
+
+// AuditLogFunc is a mock audit log.
+type AuditLogFunc func(e audit.Event)
+
+func (fn AuditLogFunc) Log(e audit.Event) {
+	fn(e)
+}
+
+// QueryAuthorizerFunc is a mock query authorizer.
+type QueryAuthorizerFunc func(u meta.User, q *influxql.Query, database string) error
+
+func (fn QueryAuthorizerFunc) AuthorizeQuery(u meta.User, q *influxql.Query, database string) error {
+	return fn(u, q, database)
+}
+
+// StatementExecutor is a mock statement executor.
+type StatementExecutor struct {
+	ExecuteStatementFn func(stmt influxql.Statement, ctx query.ExecutionContext) error
+}
+
+func (e *StatementExecutor) ExecuteStatement(stmt influxql.Statement, ctx query.ExecutionContext) error {
+	return e.ExecuteStatementFn(stmt, ctx)
+}
+
+// PointsWriter is a mock points writer.
+type PointsWriter struct {
+	WritePointsFn func(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error
+}
+
+func (w *PointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, user meta.User, points []models.Point) error {
+	return w.WritePointsFn(database, retentionPolicy, consistencyLevel, user, points)
+}
+
+// QueryStream records the responses sent for a query.
+type QueryStream struct {
+	yarpc.ServerStream
+	Responses []*rpc.QueryResponse
+}
+
+func (s *QueryStream) Send(m *rpc.QueryResponse) error {
+	s.Responses = append(s.Responses, m)
+	return nil
+}
@@ -308,7 +308,8 @@ func (s *Service) writeDatabaseInfo(conn net.Conn, database string) error {
 			for _, sg := range rp.ShardGroups {
 				for _, sh := range sg.Shards {
 					// ignore if the shard isn't on the server
-					if s.TSDBStore.Shard(sh.ID) == nil {
+					shard := s.TSDBStore.Shard(sh.ID)
+					if shard == nil {
 						continue
 					}
 
@@ -318,6 +319,7 @@ func (s *Service) writeDatabaseInfo(conn net.Conn, database string) error {
 					}
 
 					res.Paths = append(res.Paths, path)
+					res.LastModified = append(res.LastModified, shardLastModified(shard))
 				}
 			}
 		}
@@ -354,7 +356,8 @@ func (s *Service) writeRetentionPolicyInfo(conn net.Conn, database, retentionPol
 	for _, sg := range ret.ShardGroups {
 		for _, sh := range sg.Shards {
 			// ignore if the shard isn't on the server
-			if s.TSDBStore.Shard(sh.ID) == nil {
+			shard := s.TSDBStore.Shard(sh.ID)
+			if shard == nil {
 				continue
 			}
 
@@ -364,6 +367,7 @@ func (s *Service) writeRetentionPolicyInfo(conn net.Conn, database, retentionPol
 			}
 
 			res.Paths = append(res.Paths, path)
+			res.LastModified = append(res.LastModified, shardLastModified(shard))
 		}
 	}
 
@@ -461,4 +465,19 @@ type Request struct {
 // that are in the requested database or retention policy.
 type Response struct {
 	Paths []string
+
+	// LastModified has the time, in nanoseconds, the shard of each path was
+	// last modified, or 0 if it is not known. A backup skips the shards that
+	// did not change since the previous backup.
+	LastModified []int64
+}
+
+// shardLastModified returns the time sh was last modified in nanoseconds, or
+// 0 if the shard is not open.
+func shardLastModified(sh *tsdb.Shard) int64 {
+	t := sh.LastModified()
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
 }
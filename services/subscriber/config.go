@@ -20,6 +20,22 @@ const (
 
 	// DefaultWriteBufferSize is the default write buffer size for a Config.
 	DefaultWriteBufferSize = 1000
+
+	// DefaultWriteRetryInterval is the default time to wait before the first
+	// retry of a failed write.
+	DefaultWriteRetryInterval = 100 * time.Millisecond
+
+	// DefaultWriteRetryMaxInterval is the default limit of the time between
+	// retries of a failed write.
+	DefaultWriteRetryMaxInterval = 10 * time.Second
+
+	// DefaultHTTPIdleTimeout is the default time an idle connection to an
+	// HTTP destination is kept open.
+	DefaultHTTPIdleTimeout = 90 * time.Second
+
+	// DefaultHTTPDialTimeout is the default limit of the time opening a
+	// connection to an HTTP destination takes.
+	DefaultHTTPDialTimeout = 30 * time.Second
 )
 
 // Config represents a configuration of the subscriber service.
@@ -42,6 +58,21 @@ type Config struct {
 
 	// The number of in-flight writes buffered in the write channel.
 	WriteBufferSize int `toml:"write-buffer-size"`
+
+	// The number of times a failed write to a destination is retried. The
+	// time between retries starts at WriteRetryInterval and doubles up to
+	// WriteRetryMaxInterval.
+	WriteRetries          int           `toml:"write-retries"`
+	WriteRetryInterval    toml.Duration `toml:"write-retry-interval"`
+	WriteRetryMaxInterval toml.Duration `toml:"write-retry-max-interval"`
+
+	// The number of idle connections kept open to each HTTP destination, or 0
+	// to keep as many as WriteConcurrency, and how long they are kept.
+	HTTPMaxIdleConns int           `toml:"http-max-idle-conns"`
+	HTTPIdleTimeout  toml.Duration `toml:"http-idle-timeout"`
+
+	// The limit of the time opening a connection to an HTTP destination takes.
+	HTTPDialTimeout toml.Duration `toml:"http-dial-timeout"`
 }
 
 // NewConfig returns a new instance of a subscriber config.
@@ -53,6 +84,12 @@ func NewConfig() Config {
 		CaCerts:            "",
 		WriteConcurrency:   DefaultWriteConcurrency,
 		WriteBufferSize:    DefaultWriteBufferSize,
+
+		WriteRetryInterval:    toml.Duration(DefaultWriteRetryInterval),
+		WriteRetryMaxInterval: toml.Duration(DefaultWriteRetryMaxInterval),
+
+		HTTPIdleTimeout: toml.Duration(DefaultHTTPIdleTimeout),
+		HTTPDialTimeout: toml.Duration(DefaultHTTPDialTimeout),
 	}
 }
 
@@ -78,6 +115,24 @@ func (c Config) Validate() error {
 		return errors.New("write-concurrency must be greater than 0")
 	}
 
+	if c.WriteRetries < 0 {
+		return errors.New("write-retries must be non-negative")
+	} else if c.WriteRetries > 0 {
+		if c.WriteRetryInterval <= 0 {
+			return errors.New("write-retry-interval must be greater than 0")
+		} else if c.WriteRetryMaxInterval < c.WriteRetryInterval {
+			return errors.New("write-retry-max-interval must be at least write-retry-interval")
+		}
+	}
+
+	if c.HTTPMaxIdleConns < 0 {
+		return errors.New("http-max-idle-conns must be non-negative")
+	} else if c.HTTPIdleTimeout < 0 {
+		return errors.New("http-idle-timeout must be non-negative")
+	} else if c.HTTPDialTimeout < 0 {
+		return errors.New("http-dial-timeout must be non-negative")
+	}
+
 	return nil
 }
 
@@ -99,5 +154,13 @@ func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
 		"http-timeout":      c.HTTPTimeout,
 		"write-concurrency": c.WriteConcurrency,
 		"write-buffer-size": c.WriteBufferSize,
+
+		"write-retries":            c.WriteRetries,
+		"write-retry-interval":     c.WriteRetryInterval,
+		"write-retry-max-interval": c.WriteRetryMaxInterval,
+
+		"http-max-idle-conns": c.HTTPMaxIdleConns,
+		"http-idle-timeout":   c.HTTPIdleTimeout,
+		"http-dial-timeout":   c.HTTPDialTimeout,
 	}), nil
 }
@@ -109,3 +109,21 @@ write-concurrency = 10
 		t.Errorf("Expected Validation to succeed. Instead was: %v", err)
 	}
 }
+
+func TestConfig_ValidateHTTPPool(t *testing.T) {
+	c := subscriber.NewConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	c.HTTPMaxIdleConns = -1
+	if err := c.Validate(); err == nil || err.Error() != "http-max-idle-conns must be non-negative" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c = subscriber.NewConfig()
+	c.HTTPDialTimeout = -1
+	if err := c.Validate(); err == nil || err.Error() != "http-dial-timeout must be non-negative" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -1,9 +1,12 @@
 package subscriber
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"io/ioutil"
+	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/influxdb/client/v2"
@@ -12,9 +15,27 @@ import (
 
 // HTTP supports writing points over HTTP using the line protocol.
 type HTTP struct {
+	// connectionsOpened comes first to be 64-bit aligned for the atomic package.
+	connectionsOpened int64
+
 	c client.Client
 }
 
+// HTTPPool configures the pool of connections an HTTP points writer keeps
+// open to its destination. The zero value uses the defaults of the client.
+type HTTPPool struct {
+	// MaxIdleConns is the number of idle connections kept open. Writers
+	// writing concurrently should keep as many, so connections are reused
+	// instead of opened, and left in TIME_WAIT, for each write.
+	MaxIdleConns int
+
+	// IdleTimeout is how long an idle connection is kept open.
+	IdleTimeout time.Duration
+
+	// DialTimeout is how long opening a connection may take.
+	DialTimeout time.Duration
+}
+
 // NewHTTP returns a new HTTP points writer with default options.
 func NewHTTP(addr string, timeout time.Duration) (*HTTP, error) {
 	return NewHTTPS(addr, timeout, false, "")
@@ -22,23 +43,49 @@ func NewHTTP(addr string, timeout time.Duration) (*HTTP, error) {
 
 // NewHTTPS returns a new HTTPS points writer with default options and HTTPS configured.
 func NewHTTPS(addr string, timeout time.Duration, unsafeSsl bool, caCerts string) (*HTTP, error) {
+	return NewHTTPWithPool(addr, timeout, unsafeSsl, caCerts, HTTPPool{})
+}
+
+// NewHTTPWithPool returns a new HTTP or HTTPS points writer keeping the
+// connections to addr open as configured by pool.
+func NewHTTPWithPool(addr string, timeout time.Duration, unsafeSsl bool, caCerts string, pool HTTPPool) (*HTTP, error) {
 	tlsConfig, err := createTLSConfig(caCerts)
 	if err != nil {
 		return nil, err
 	}
 
+	h := &HTTP{}
+	dialer := &net.Dialer{Timeout: pool.DialTimeout, KeepAlive: 30 * time.Second}
 	conf := client.HTTPConfig{
 		Addr:               addr,
 		Timeout:            timeout,
 		InsecureSkipVerify: unsafeSsl,
 		TLSConfig:          tlsConfig,
+
+		MaxIdleConns:    pool.MaxIdleConns,
+		IdleConnTimeout: pool.IdleTimeout,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err == nil {
+				atomic.AddInt64(&h.connectionsOpened, 1)
+			}
+			return conn, err
+		},
 	}
 
 	c, err := client.NewHTTPClient(conf)
 	if err != nil {
 		return nil, err
 	}
-	return &HTTP{c: c}, nil
+	h.c = c
+	return h, nil
+}
+
+// ConnectionsOpened returns the number of connections opened to the
+// destination. A number growing with the writes means connections are not
+// reused.
+func (h *HTTP) ConnectionsOpened() int64 {
+	return atomic.LoadInt64(&h.connectionsOpened)
 }
 
 // WritePoints writes points over HTTP transport.
@@ -13,6 +13,7 @@ import (
 	"github.com/influxdata/influxdb/coordinator"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/monitor"
+	"github.com/influxdata/influxdb/pkg/fault"
 	"github.com/influxdata/influxdb/services/meta"
 	"go.uber.org/zap"
 )
@@ -22,6 +23,9 @@ const (
 	statCreateFailures = "createFailures"
 	statPointsWritten  = "pointsWritten"
 	statWriteFailures  = "writeFailures"
+	statWriteRetries   = "writeRetries"
+
+	statConnectionsOpened = "connectionsOpened"
 )
 
 // PointsWriter is an interface for writing points to a subscription destination.
@@ -214,6 +218,11 @@ func (s *Service) createSubscription(se subEntry, mode string, destinations []st
 		bm:      bm,
 		writers: writers,
 		stats:   stats,
+		retry: retryPolicy{
+			retries:     s.conf.WriteRetries,
+			interval:    time.Duration(s.conf.WriteRetryInterval),
+			maxInterval: time.Duration(s.conf.WriteRetryMaxInterval),
+		},
 		defaultTags: models.StatisticTags{
 			"database":         se.db,
 			"retention_policy": se.rp,
@@ -338,17 +347,32 @@ func (s *Service) newPointsWriter(u url.URL) (PointsWriter, error) {
 	case "udp":
 		return NewUDP(u.Host), nil
 	case "http":
-		return NewHTTP(u.String(), time.Duration(s.conf.HTTPTimeout))
+		return NewHTTPWithPool(u.String(), time.Duration(s.conf.HTTPTimeout), false, "", s.httpPool())
 	case "https":
 		if s.conf.InsecureSkipVerify {
 			s.Logger.Info("WARNING: 'insecure-skip-verify' is true. This will skip all certificate verifications.")
 		}
-		return NewHTTPS(u.String(), time.Duration(s.conf.HTTPTimeout), s.conf.InsecureSkipVerify, s.conf.CaCerts)
+		return NewHTTPWithPool(u.String(), time.Duration(s.conf.HTTPTimeout), s.conf.InsecureSkipVerify, s.conf.CaCerts, s.httpPool())
 	default:
 		return nil, fmt.Errorf("unknown destination scheme %s", u.Scheme)
 	}
 }
 
+// httpPool returns the pool of connections of the HTTP destinations. Every
+// writer goroutine may write to a destination at once, so as many idle
+// connections are kept by default.
+func (s *Service) httpPool() HTTPPool {
+	pool := HTTPPool{
+		MaxIdleConns: s.conf.HTTPMaxIdleConns,
+		IdleTimeout:  time.Duration(s.conf.HTTPIdleTimeout),
+		DialTimeout:  time.Duration(s.conf.HTTPDialTimeout),
+	}
+	if pool.MaxIdleConns == 0 {
+		pool.MaxIdleConns = s.conf.WriteConcurrency
+	}
+	return pool
+}
+
 // chanWriter sends WritePointsRequest to a PointsWriter received over a channel.
 type chanWriter struct {
 	writeRequests chan *coordinator.WritePointsRequest
@@ -398,6 +422,32 @@ type writerStats struct {
 	dest          string
 	failures      int64
 	pointsWritten int64
+	retries       int64
+}
+
+// retryPolicy is how often and after how long a failed write is retried.
+type retryPolicy struct {
+	retries     int
+	interval    time.Duration
+	maxInterval time.Duration
+}
+
+// backoff returns the time to wait before retry n, counting from 0.
+func (r retryPolicy) backoff(n int) time.Duration {
+	d := r.interval
+	for i := 0; i < n && d < r.maxInterval; i++ {
+		d *= 2
+	}
+	if d > r.maxInterval {
+		d = r.maxInterval
+	}
+	return d
+}
+
+// connectionCounter is a PointsWriter counting the connections it opened to
+// its destination.
+type connectionCounter interface {
+	ConnectionsOpened() int64
 }
 
 // balances writes across PointsWriters according to BalanceMode
@@ -405,28 +455,59 @@ type balancewriter struct {
 	bm          BalanceMode
 	writers     []PointsWriter
 	stats       []writerStats
+	retry       retryPolicy
 	defaultTags models.StatisticTags
 	i           int
 }
 
+// WritePoints writes p to the destinations. When a write fails, the
+// destinations that failed are retried in ALL mode, and the next
+// destinations are tried first in ANY mode, so a retry only happens once
+// every destination failed.
 func (b *balancewriter) WritePoints(p *coordinator.WritePointsRequest) error {
+	var written []bool
+	if b.bm == ALL && b.retry.retries > 0 {
+		written = make([]bool, len(b.writers))
+	}
+
 	var lastErr error
-	for range b.writers {
-		// round robin through destinations.
-		i := b.i
-		w := b.writers[i]
-		b.i = (b.i + 1) % len(b.writers)
-
-		// write points to destination.
-		err := w.WritePoints(p)
-		if err != nil {
-			lastErr = err
-			atomic.AddInt64(&b.stats[i].failures, 1)
-		} else {
-			atomic.AddInt64(&b.stats[i].pointsWritten, int64(len(p.Points)))
-			if b.bm == ANY {
-				break
+	for attempt := 0; attempt <= b.retry.retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(b.retry.backoff(attempt - 1))
+		}
+
+		lastErr = nil
+		for range b.writers {
+			// round robin through destinations.
+			i := b.i
+			w := b.writers[i]
+			b.i = (b.i + 1) % len(b.writers)
+			if written != nil && written[i] {
+				continue
+			}
+
+			// write points to destination.
+			if attempt > 0 {
+				atomic.AddInt64(&b.stats[i].retries, 1)
+			}
+			err := fault.Inject(fault.SubscriberWrite)
+			if err == nil {
+				err = w.WritePoints(p)
 			}
+			if err != nil {
+				lastErr = err
+				atomic.AddInt64(&b.stats[i].failures, 1)
+			} else {
+				atomic.AddInt64(&b.stats[i].pointsWritten, int64(len(p.Points)))
+				if b.bm == ANY {
+					return nil
+				} else if written != nil {
+					written[i] = true
+				}
+			}
+		}
+		if lastErr == nil {
+			return nil
 		}
 	}
 	return lastErr
@@ -444,8 +525,12 @@ func (b *balancewriter) Statistics(tags map[string]string) []models.Statistic {
 			Values: map[string]interface{}{
 				statPointsWritten: atomic.LoadInt64(&b.stats[i].pointsWritten),
 				statWriteFailures: atomic.LoadInt64(&b.stats[i].failures),
+				statWriteRetries:  atomic.LoadInt64(&b.stats[i].retries),
 			},
 		}
+		if c, ok := b.writers[i].(connectionCounter); ok {
+			statistics[i].Values[statConnectionsOpened] = c.ConnectionsOpened()
+		}
 	}
 	return statistics
 }
@@ -1,13 +1,20 @@
 package subscriber_test
 
 import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/influxdata/influxdb/coordinator"
+	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/services/subscriber"
+	"github.com/influxdata/influxdb/toml"
 )
 
 type MetaClient struct {
@@ -266,6 +273,91 @@ func TestService_ModeANY(t *testing.T) {
 	close(dataChanged)
 }
 
+func TestService_WriteRetries(t *testing.T) {
+	dataChanged := make(chan struct{})
+	defer close(dataChanged)
+	ms := MetaClient{}
+	ms.WaitForDataChangedFn = func() chan struct{} {
+		return dataChanged
+	}
+	ms.DatabasesFn = func() []meta.DatabaseInfo {
+		return []meta.DatabaseInfo{
+			{
+				Name: "db0",
+				RetentionPolicies: []meta.RetentionPolicyInfo{
+					{
+						Name: "rp0",
+						Subscriptions: []meta.SubscriptionInfo{
+							{Name: "s0", Mode: "ALL", Destinations: []string{"udp://h0:9093", "udp://h1:9093"}},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	// h0 fails twice before it accepts the write.
+	attempts := make(chan string, 10)
+	var h0Failures int
+	newPointsWriter := func(u url.URL) (subscriber.PointsWriter, error) {
+		sub := Subscription{}
+		sub.WritePointsFn = func(p *coordinator.WritePointsRequest) error {
+			attempts <- u.Host
+			if u.Host == "h0:9093" && h0Failures < 2 {
+				h0Failures++
+				return errors.New("connection refused")
+			}
+			return nil
+		}
+		return sub, nil
+	}
+
+	c := subscriber.NewConfig()
+	c.WriteConcurrency = 1
+	c.WriteRetries = 3
+	c.WriteRetryInterval = toml.Duration(time.Millisecond)
+	s := subscriber.NewService(c)
+	s.MetaClient = ms
+	s.NewPointsWriter = newPointsWriter
+	s.Open()
+	defer s.Close()
+
+	dataChanged <- struct{}{}
+	s.Points() <- &coordinator.WritePointsRequest{Database: "db0", RetentionPolicy: "rp0"}
+
+	// Only the destination that failed is retried.
+	counts := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		select {
+		case host := <-attempts:
+			counts[host]++
+		case <-time.After(time.Second):
+			t.Fatalf("expected write attempt %d", i)
+		}
+	}
+	select {
+	case host := <-attempts:
+		t.Fatalf("unexpected write attempt to %s", host)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if counts["h0:9093"] != 3 || counts["h1:9093"] != 1 {
+		t.Fatalf("unexpected write attempts: %v", counts)
+	}
+
+	for _, stat := range s.Statistics(nil) {
+		if stat.Tags["destination"] != "udp://h0:9093" {
+			continue
+		}
+		if v := stat.Values["writeRetries"]; v != int64(2) {
+			t.Fatalf("unexpected retries: %v", v)
+		} else if v := stat.Values["writeFailures"]; v != int64(2) {
+			t.Fatalf("unexpected failures: %v", v)
+		}
+		return
+	}
+	t.Fatal("missing statistics for destination")
+}
+
 func TestService_Multiple(t *testing.T) {
 	dataChanged := make(chan struct{})
 	ms := MetaClient{}
@@ -441,3 +533,41 @@ func TestService_WaitForDataChanged(t *testing.T) {
 
 	close(dataChanged)
 }
+
+func TestHTTP_ReusesConnections(t *testing.T) {
+	var requests int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	const writers = 4
+	h, err := subscriber.NewHTTPWithPool(ts.URL, time.Second, false, "", subscriber.HTTPPool{MaxIdleConns: writers})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pt := models.MustNewPoint("cpu", nil, models.Fields{"value": 1.0}, time.Unix(0, 0))
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				if err := h.WritePoints(&coordinator.WritePointsRequest{Database: "db0", Points: []models.Point{pt}}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt64(&requests); got != 4*25 {
+		t.Fatalf("unexpected number of requests: got %d, exp %d", got, 4*25)
+	}
+	if got := h.ConnectionsOpened(); got < 1 || got > writers {
+		t.Fatalf("unexpected number of connections opened: got %d, exp at most %d", got, writers)
+	}
+}
@@ -1,9 +1,12 @@
 package udp
 
 import (
+	"errors"
 	"time"
 
+	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/services/internal/ingest"
 	"github.com/influxdata/influxdb/toml"
 )
 
@@ -55,6 +58,17 @@ type Config struct {
 	ReadBuffer      int           `toml:"read-buffer"`
 	BatchTimeout    toml.Duration `toml:"batch-timeout"`
 	Precision       string        `toml:"precision"`
+	Tags            []string      `toml:"tags"`
+
+	// DatabaseHeader allows a datagram to start with a "db=<name> rp=<name>"
+	// line giving the database and retention policy of its points, which
+	// must already exist. Datagrams without one go to Database.
+	DatabaseHeader bool `toml:"database-header"`
+
+	// DedupWindow drops the points identical to a point received less than
+	// DedupWindow ago, such as those of datagrams duplicated by relays. A
+	// value of 0 disables it.
+	DedupWindow toml.Duration `toml:"dedup-window"`
 }
 
 // NewConfig returns a new instance of Config with defaults.
@@ -94,13 +108,26 @@ func (c *Config) WithDefaults() *Config {
 	return &d
 }
 
+// Validate returns an error if the Config is invalid.
+func (c *Config) Validate() error {
+	if c.DedupWindow < 0 {
+		return errors.New("dedup-window must be non-negative")
+	}
+	return ingest.ValidateTags(c.Tags)
+}
+
+// DefaultTags returns the config's tags.
+func (c *Config) DefaultTags() models.Tags {
+	return ingest.DefaultTags(c.Tags)
+}
+
 // Configs wraps a slice of Config to aggregate diagnostics.
 type Configs []Config
 
 // Diagnostics returns one set of diagnostics for all of the Configs.
 func (c Configs) Diagnostics() (*diagnostics.Diagnostics, error) {
 	d := &diagnostics.Diagnostics{
-		Columns: []string{"enabled", "bind-address", "database", "retention-policy", "batch-size", "batch-pending", "batch-timeout"},
+		Columns: []string{"enabled", "bind-address", "database", "retention-policy", "batch-size", "batch-pending", "batch-timeout", "database-header", "dedup-window"},
 	}
 
 	for _, cc := range c {
@@ -109,7 +136,7 @@ func (c Configs) Diagnostics() (*diagnostics.Diagnostics, error) {
 			continue
 		}
 
-		r := []interface{}{true, cc.BindAddress, cc.Database, cc.RetentionPolicy, cc.BatchSize, cc.BatchPending, cc.BatchTimeout}
+		r := []interface{}{true, cc.BindAddress, cc.Database, cc.RetentionPolicy, cc.BatchSize, cc.BatchPending, cc.BatchTimeout, cc.DatabaseHeader, cc.DedupWindow}
 		d.AddRow(r)
 	}
 
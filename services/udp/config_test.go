@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/udp"
 )
 
@@ -20,6 +21,8 @@ batch-size = 100
 batch-pending = 9
 batch-timeout = "10ms"
 udp-payload-size = 1500
+tags = ["region=eu1", "source=udp"]
+dedup-window = "2s"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -39,5 +42,29 @@ udp-payload-size = 1500
 		t.Fatalf("unexpected batch pending: %d", c.BatchPending)
 	} else if time.Duration(c.BatchTimeout) != (10 * time.Millisecond) {
 		t.Fatalf("unexpected batch timeout: %v", c.BatchTimeout)
+	} else if got, exp := c.DefaultTags(), models.NewTags(map[string]string{"region": "eu1", "source": "udp"}); !got.Equal(exp) {
+		t.Fatalf("unexpected default tags: got %v, exp %v", got, exp)
+	} else if time.Duration(c.DedupWindow) != 2*time.Second {
+		t.Fatalf("unexpected dedup window: %v", c.DedupWindow)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	c := udp.NewConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validation fail from NewConfig: %s", err)
+	}
+
+	for _, tag := range []string{"region", "region=", "=eu1", "a=b=c"} {
+		c.Tags = []string{tag}
+		if err := c.Validate(); err == nil {
+			t.Fatalf("expected error for tag %q, got nil", tag)
+		}
+	}
+
+	c = udp.NewConfig()
+	c.DedupWindow = -1
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected error for a negative dedup-window, got nil")
 	}
 }
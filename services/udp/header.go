@@ -0,0 +1,63 @@
+package udp
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/influxdata/influxdb/services/internal/ingest"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+// parseHeader returns the destination given by the header line of buf, as
+// parsed by ingest.ParseHeader, and the rest of buf. ok is false if the first
+// line of buf is not a header, in which case it is parsed as points.
+func parseHeader(buf []byte) (dest ingest.Destination, rest []byte, ok bool) {
+	line := buf
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		line, rest = buf[:i], buf[i+1:]
+	}
+	if dest, ok := ingest.ParseHeader(line); ok {
+		return dest, rest, true
+	}
+	return ingest.Destination{}, buf, false
+}
+
+// errUnknownDestination is returned for a header naming a database or
+// retention policy that does not exist.
+var errUnknownDestination = errors.New("database or retention policy not found")
+
+// headerBatcher returns the batcher of the points of dest. Unlike the
+// configured database, the databases named by headers are not created,
+// since anyone who can send a datagram could otherwise create databases. It
+// returns nil if the service is closing.
+func (s *Service) headerBatcher(dest ingest.Destination) (*tsdb.PointBatcher, error) {
+	if dest.Database == s.config.Database && dest.RetentionPolicy == s.config.RetentionPolicy {
+		return s.batcher, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if b := s.batchers[dest]; b != nil {
+		return b, nil
+	} else if s.closed() {
+		return nil, nil
+	}
+
+	if s.MetaClient.Database(dest.Database) == nil {
+		return nil, errUnknownDestination
+	} else if rp, err := s.MetaClient.RetentionPolicy(dest.Database, dest.RetentionPolicy); err != nil || rp == nil {
+		return nil, errUnknownDestination
+	}
+
+	b := s.newBatcher()
+	b.Start()
+	if s.batchers == nil {
+		s.batchers = make(map[ingest.Destination]*tsdb.PointBatcher)
+	}
+	s.batchers[dest] = b
+
+	s.wg.Add(1)
+	go s.writer(b, dest)
+	return b, nil
+}
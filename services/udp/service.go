@@ -10,7 +10,7 @@ import (
 	"time"
 
 	"github.com/influxdata/influxdb/models"
-	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/services/internal/ingest"
 	"github.com/influxdata/influxdb/tsdb"
 	"go.uber.org/zap"
 )
@@ -32,6 +32,9 @@ const (
 	statBatchesTransmitted  = "batchesTx"
 	statPointsTransmitted   = "pointsTx"
 	statBatchesTransmitFail = "batchesTxFail"
+	statPointsDropped       = "pointsDropped"
+	statDatagramsDropped    = "datagramsDropped"
+	statPointsDeduplicated  = "pointsDeduplicated"
 )
 
 // Service is a UDP service that will listen for incoming packets of line protocol.
@@ -40,21 +43,24 @@ type Service struct {
 	addr *net.UDPAddr
 	wg   sync.WaitGroup
 
-	mu    sync.RWMutex
-	ready bool          // Has the required database been created?
-	done  chan struct{} // Is the service closing or closed?
+	mu      sync.RWMutex
+	done    chan struct{}  // Is the service closing or closed?
+	storage ingest.Storage // Has the required database been created?
 
 	parserChan chan []byte
 	batcher    *tsdb.PointBatcher
 	config     Config
+	tags       models.Tags // Tags added to every point that doesn't have them.
+
+	// batchers batch the points of the datagrams whose header names another
+	// destination than the configured one.
+	batchers map[ingest.Destination]*tsdb.PointBatcher
 
 	PointsWriter interface {
 		WritePointsPrivileged(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
 	}
 
-	MetaClient interface {
-		CreateDatabase(name string) (*meta.DatabaseInfo, error)
-	}
+	MetaClient ingest.MetaClient
 
 	Logger      *zap.Logger
 	stats       *Statistics
@@ -66,6 +72,7 @@ func NewService(c Config) *Service {
 	d := *c.WithDefaults()
 	return &Service{
 		config:      d,
+		tags:        d.DefaultTags(),
 		parserChan:  make(chan []byte, parserChanLen),
 		Logger:      zap.NewNop(),
 		stats:       &Statistics{},
@@ -110,7 +117,7 @@ func (s *Service) Open() (err error) {
 			return err
 		}
 	}
-	s.batcher = tsdb.NewPointBatcher(s.config.BatchSize, s.config.BatchPending, time.Duration(s.config.BatchTimeout))
+	s.batcher = s.newBatcher()
 	s.batcher.Start()
 
 	s.Logger.Info(fmt.Sprintf("Started listening on UDP: %s", s.config.BindAddress))
@@ -118,7 +125,7 @@ func (s *Service) Open() (err error) {
 	s.wg.Add(3)
 	go s.serve()
 	go s.parser()
-	go s.writer()
+	go s.writer(s.batcher, s.defaultDestination())
 
 	return nil
 }
@@ -132,10 +139,22 @@ type Statistics struct {
 	BatchesTransmitted  int64
 	PointsTransmitted   int64
 	BatchesTransmitFail int64
+	PointsDropped       int64
+	DatagramsDropped    int64
 }
 
 // Statistics returns statistics for periodic monitoring.
 func (s *Service) Statistics(tags map[string]string) []models.Statistic {
+	var deduplicated uint64
+	s.mu.RLock()
+	if s.batcher != nil {
+		deduplicated = s.batcher.Stats().DedupTotal
+	}
+	for _, b := range s.batchers {
+		deduplicated += b.Stats().DedupTotal
+	}
+	s.mu.RUnlock()
+
 	return []models.Statistic{{
 		Name: "udp",
 		Tags: s.defaultTags.Merge(tags),
@@ -147,27 +166,47 @@ func (s *Service) Statistics(tags map[string]string) []models.Statistic {
 			statBatchesTransmitted:  atomic.LoadInt64(&s.stats.BatchesTransmitted),
 			statPointsTransmitted:   atomic.LoadInt64(&s.stats.PointsTransmitted),
 			statBatchesTransmitFail: atomic.LoadInt64(&s.stats.BatchesTransmitFail),
+			statPointsDropped:       atomic.LoadInt64(&s.stats.PointsDropped),
+			statDatagramsDropped:    atomic.LoadInt64(&s.stats.DatagramsDropped),
+			statPointsDeduplicated:  int64(deduplicated),
 		},
 	}}
 }
 
-func (s *Service) writer() {
+// newBatcher returns a batcher for the points of a destination.
+func (s *Service) newBatcher() *tsdb.PointBatcher {
+	b := tsdb.NewPointBatcher(s.config.BatchSize, s.config.BatchPending, time.Duration(s.config.BatchTimeout))
+	b.DedupWindow = time.Duration(s.config.DedupWindow)
+	return b
+}
+
+// defaultDestination returns the configured database and retention policy.
+func (s *Service) defaultDestination() ingest.Destination {
+	return ingest.Destination{Database: s.config.Database, RetentionPolicy: s.config.RetentionPolicy}
+}
+
+// writer writes the batches of batcher to dest.
+func (s *Service) writer(batcher *tsdb.PointBatcher, dest ingest.Destination) {
 	defer s.wg.Done()
 
 	for {
 		select {
-		case batch := <-s.batcher.Out():
+		case batch := <-batcher.Out():
 			// Will attempt to create database if not yet created.
-			if err := s.createInternalStorage(); err != nil {
-				s.Logger.Info(fmt.Sprintf("Required database %s does not yet exist: %s", s.config.Database, err.Error()))
-				continue
+			if dest == s.defaultDestination() {
+				if err := s.createInternalStorage(); err != nil {
+					s.Logger.Info(fmt.Sprintf("Required database %s does not yet exist: %s", s.config.Database, err.Error()))
+					continue
+				}
 			}
 
-			if err := s.PointsWriter.WritePointsPrivileged(s.config.Database, s.config.RetentionPolicy, models.ConsistencyLevelAny, batch); err == nil {
+			err := s.PointsWriter.WritePointsPrivileged(dest.Database, dest.RetentionPolicy, models.ConsistencyLevelAny, batch)
+			if dropped, ok := ingest.PartialWrite(s.Logger, dest.Database, err); err == nil || ok {
 				atomic.AddInt64(&s.stats.BatchesTransmitted, 1)
-				atomic.AddInt64(&s.stats.PointsTransmitted, int64(len(batch)))
+				atomic.AddInt64(&s.stats.PointsTransmitted, int64(len(batch)-dropped))
+				atomic.AddInt64(&s.stats.PointsDropped, int64(dropped))
 			} else {
-				s.Logger.Info(fmt.Sprintf("failed to write point batch to database %q: %s", s.config.Database, err))
+				s.Logger.Info(fmt.Sprintf("failed to write point batch to database %q: %s", dest.Database, err))
 				atomic.AddInt64(&s.stats.BatchesTransmitFail, 1)
 			}
 
@@ -211,15 +250,37 @@ func (s *Service) parser() {
 		case <-s.done:
 			return
 		case buf := <-s.parserChan:
+			batcher := s.batcher
+			if s.config.DatabaseHeader {
+				if dest, rest, ok := parseHeader(buf); ok {
+					b, err := s.headerBatcher(dest)
+					if err != nil {
+						atomic.AddInt64(&s.stats.DatagramsDropped, 1)
+						s.Logger.Info(fmt.Sprintf("Dropped datagram with invalid header: %s", err))
+						continue
+					} else if b == nil {
+						continue // The service is closing.
+					}
+					batcher, buf = b, rest
+				}
+			}
+
+			// Lines that fail to parse are dropped; the rest of the
+			// packet is still written.
 			points, err := models.ParsePointsWithPrecision(buf, time.Now().UTC(), s.config.Precision)
 			if err != nil {
-				atomic.AddInt64(&s.stats.PointsParseFail, 1)
+				atomic.AddInt64(&s.stats.PointsParseFail, int64(models.ParseFailures(err)))
 				s.Logger.Info(fmt.Sprintf("Failed to parse points: %s", err))
-				continue
 			}
 
 			for _, point := range points {
-				s.batcher.In() <- point
+				// Set the default tags on the point if they are not already set
+				for _, t := range s.tags {
+					if !point.HasTag(t.Key) {
+						point.AddTag(string(t.Key), string(t.Value))
+					}
+				}
+				batcher.In() <- point
 			}
 			atomic.AddInt64(&s.stats.PointsReceived, int64(len(points)))
 		}
@@ -244,6 +305,9 @@ func (s *Service) Close() error {
 		if s.batcher != nil {
 			s.batcher.Stop()
 		}
+		for _, b := range s.batchers {
+			b.Stop()
+		}
 		return true
 	}(); !wait {
 		return nil
@@ -255,6 +319,7 @@ func (s *Service) Close() error {
 	s.done = nil
 	s.conn = nil
 	s.batcher = nil
+	s.batchers = nil
 	s.mu.Unlock()
 
 	s.Logger.Info("Service closed")
@@ -279,24 +344,12 @@ func (s *Service) closed() bool {
 	return s.done == nil
 }
 
-// createInternalStorage ensures that the required database has been created.
+// createInternalStorage ensures that the required database and retention
+// policy have been created.
 func (s *Service) createInternalStorage() error {
-	s.mu.RLock()
-	ready := s.ready
-	s.mu.RUnlock()
-	if ready {
-		return nil
-	}
-
-	if _, err := s.MetaClient.CreateDatabase(s.config.Database); err != nil {
-		return err
-	}
-
-	// The service is now ready.
-	s.mu.Lock()
-	s.ready = true
-	s.mu.Unlock()
-	return nil
+	return s.storage.Ensure(func() error {
+		return ingest.CreateStorage(s.MetaClient, s.config.Database, s.config.RetentionPolicy)
+	})
 }
 
 // WithLogger sets the logger on the service.
@@ -9,6 +9,7 @@ import (
 	"github.com/influxdata/influxdb/internal"
 	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/internal/ingest"
 	"github.com/influxdata/influxdb/services/meta"
 )
 
@@ -87,14 +88,18 @@ func TestService_CreatesDatabase(t *testing.T) {
 	}
 
 	// ready status should not have been switched due to meta client error.
-	s.Service.mu.RLock()
-	ready := s.Service.ready
-	s.Service.mu.RUnlock()
+	ready := s.Service.storage.Ready()
 
 	if got, exp := ready, false; got != exp {
 		t.Fatalf("got %v, expected %v", got, exp)
 	}
 
+	// The failure delays the next attempt, so don't wait for it here.
+	if s.Service.storage.RetryAt().IsZero() {
+		t.Fatal("expected a retry time after the failure")
+	}
+	s.Service.storage.Reset()
+
 	// This time MC won't cause an error.
 	s.MetaClient.CreateDatabaseFn = func(name string) (*meta.DatabaseInfo, error) {
 		// Allow some time for the caller to return and the ready status to
@@ -113,9 +118,7 @@ func TestService_CreatesDatabase(t *testing.T) {
 	}
 
 	// ready status should now be true.
-	s.Service.mu.RLock()
-	ready = s.Service.ready
-	s.Service.mu.RUnlock()
+	ready = s.Service.storage.Ready()
 
 	if got, exp := ready, true; got != exp {
 		t.Fatalf("got %v, expected %v", got, exp)
@@ -124,6 +127,92 @@ func TestService_CreatesDatabase(t *testing.T) {
 	s.Service.Close()
 }
 
+func TestService_CreatesRetentionPolicy(t *testing.T) {
+	c := NewConfig()
+	c.RetentionPolicy = "short"
+	s := NewTestService(&c)
+
+	var created []string
+	s.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		return &meta.DatabaseInfo{Name: name, DefaultRetentionPolicy: "autogen"}
+	}
+	s.MetaClient.RetentionPolicyFn = func(database, name string) (*meta.RetentionPolicyInfo, error) {
+		return nil, nil
+	}
+	s.MetaClient.CreateRetentionPolicyFn = func(database string, spec *meta.RetentionPolicySpec, makeDefault bool) (*meta.RetentionPolicyInfo, error) {
+		if makeDefault {
+			t.Error("expected the default retention policy to be left as it is")
+		}
+		created = append(created, database+"."+spec.Name)
+		return spec.NewRetentionPolicyInfo(), nil
+	}
+
+	if err := s.Service.createInternalStorage(); err != nil {
+		t.Fatal(err)
+	} else if len(created) != 1 || created[0] != c.Database+".short" {
+		t.Fatalf("unexpected retention policies created: %v", created)
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	for _, tt := range []struct {
+		buf  string
+		dest ingest.Destination
+		rest string
+		ok   bool
+	}{
+		{buf: "cpu value=1", rest: "cpu value=1"},
+		{buf: "db=db0\ncpu value=1", dest: ingest.Destination{Database: "db0"}, rest: "cpu value=1", ok: true},
+		{buf: "db=db0 rp=rp0\ncpu value=1\nmem value=2", dest: ingest.Destination{Database: "db0", RetentionPolicy: "rp0"}, rest: "cpu value=1\nmem value=2", ok: true},
+		{buf: "db=db0 rp=\ncpu value=1", rest: "db=db0 rp=\ncpu value=1"},
+		{buf: "db=db0 precision=s\ncpu value=1", rest: "db=db0 precision=s\ncpu value=1"},
+		{buf: "db=cpu value=1\nmem value=2", rest: "db=cpu value=1\nmem value=2"},
+	} {
+		dest, rest, ok := parseHeader([]byte(tt.buf))
+		if dest != tt.dest || string(rest) != tt.rest || ok != tt.ok {
+			t.Errorf("%q: unexpected header: %+v %q %v", tt.buf, dest, rest, ok)
+		}
+	}
+}
+
+func TestService_HeaderBatcher(t *testing.T) {
+	c := NewConfig()
+	c.BindAddress = "127.0.0.1:0"
+	c.DatabaseHeader = true
+	s := NewTestService(&c)
+	s.MetaClient.DatabaseFn = func(name string) *meta.DatabaseInfo {
+		if name != "db0" {
+			return nil
+		}
+		return &meta.DatabaseInfo{Name: name, DefaultRetentionPolicy: "autogen"}
+	}
+	s.MetaClient.RetentionPolicyFn = func(database, name string) (*meta.RetentionPolicyInfo, error) {
+		if name == "" || name == "autogen" {
+			return &meta.RetentionPolicyInfo{Name: "autogen"}, nil
+		}
+		return nil, nil
+	}
+	if err := s.Service.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Service.Close()
+
+	if b, err := s.Service.headerBatcher(ingest.Destination{Database: c.Database}); err != nil || b != s.Service.batcher {
+		t.Fatalf("expected the configured destination to use the default batcher: %v", err)
+	}
+	b, err := s.Service.headerBatcher(ingest.Destination{Database: "db0"})
+	if err != nil || b == nil || b == s.Service.batcher {
+		t.Fatalf("expected a new batcher: %v", err)
+	} else if other, _ := s.Service.headerBatcher(ingest.Destination{Database: "db0"}); other != b {
+		t.Fatal("expected the batcher to be reused")
+	}
+	for _, dest := range []ingest.Destination{{Database: "db1"}, {Database: "db0", RetentionPolicy: "rp0"}} {
+		if _, err := s.Service.headerBatcher(dest); err != errUnknownDestination {
+			t.Fatalf("%+v: unexpected error: %v", dest, err)
+		}
+	}
+}
+
 type TestService struct {
 	Service       *Service
 	Config        Config
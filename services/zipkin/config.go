@@ -0,0 +1,103 @@
+package zipkin
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/toml"
+)
+
+const (
+	// DefaultURL is the default address spans are sent to, the span
+	// endpoint of a Zipkin collector running on the same host.
+	DefaultURL = "http://localhost:9411/api/v2/spans"
+
+	// DefaultServiceName is the default service name of the spans.
+	DefaultServiceName = "influxdb"
+
+	// DefaultSampleRate is the default fraction of the queries traced.
+	DefaultSampleRate = 0.01
+
+	// DefaultBatchSize is the default number of spans sent in one request.
+	DefaultBatchSize = 1000
+
+	// DefaultBatchTimeout is the default time spans wait for a batch to fill
+	// up before it is sent.
+	DefaultBatchTimeout = 5 * time.Second
+)
+
+// Config represents a configuration for exporting the traces of queries to
+// a Zipkin collector.
+type Config struct {
+	Enabled bool `toml:"enabled"`
+
+	// URL is the span endpoint of the collector.
+	URL string `toml:"zipkin-url"`
+
+	// ServiceName is the name the collector shows the spans under, which
+	// tells the servers sending to the same collector apart.
+	ServiceName string `toml:"service-name"`
+
+	// SampleRate is the fraction of the queries traced, from 0 to 1.
+	SampleRate float64 `toml:"sample-rate"`
+
+	BatchSize    int           `toml:"batch-size"`
+	BatchTimeout toml.Duration `toml:"batch-timeout"`
+}
+
+// NewConfig returns a new Config with defaults.
+func NewConfig() Config {
+	return Config{
+		URL:          DefaultURL,
+		ServiceName:  DefaultServiceName,
+		SampleRate:   DefaultSampleRate,
+		BatchSize:    DefaultBatchSize,
+		BatchTimeout: toml.Duration(DefaultBatchTimeout),
+	}
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid zipkin-url: %s", err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid zipkin-url %q: expected an http or https URL", c.URL)
+	}
+
+	if c.ServiceName == "" {
+		return errors.New("service-name must not be empty")
+	} else if c.SampleRate < 0 || c.SampleRate > 1 {
+		return errors.New("sample-rate must be between 0 and 1")
+	} else if c.BatchSize <= 0 {
+		return errors.New("batch-size must be greater than 0")
+	} else if c.BatchTimeout <= 0 {
+		return errors.New("batch-timeout must be greater than 0")
+	}
+	return nil
+}
+
+// Diagnostics returns a diagnostics representation of a subset of the Config.
+func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
+	if !c.Enabled {
+		return diagnostics.RowFromMap(map[string]interface{}{
+			"enabled": false,
+		}), nil
+	}
+
+	return diagnostics.RowFromMap(map[string]interface{}{
+		"enabled":       true,
+		"zipkin-url":    c.URL,
+		"service-name":  c.ServiceName,
+		"sample-rate":   c.SampleRate,
+		"batch-size":    c.BatchSize,
+		"batch-timeout": c.BatchTimeout,
+	}), nil
+}
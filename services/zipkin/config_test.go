@@ -0,0 +1,49 @@
+package zipkin_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/influxdb/services/zipkin"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	// Parse configuration.
+	c := zipkin.NewConfig()
+	if _, err := toml.Decode(`
+enabled = true
+zipkin-url = "http://zipkin:9411/api/v2/spans"
+sample-rate = 0.5
+batch-timeout = "1s"
+`, &c); err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate configuration.
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	} else if !c.Enabled || c.URL != "http://zipkin:9411/api/v2/spans" || c.ServiceName != zipkin.DefaultServiceName {
+		t.Fatalf("unexpected config: %+v", c)
+	} else if c.SampleRate != 0.5 {
+		t.Fatalf("unexpected sample rate: %v", c.SampleRate)
+	} else if time.Duration(c.BatchTimeout) != time.Second || c.BatchSize != zipkin.DefaultBatchSize {
+		t.Fatalf("unexpected batching: %v, %d", c.BatchTimeout, c.BatchSize)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	for _, fn := range []func(c *zipkin.Config){
+		func(c *zipkin.Config) { c.URL = "udp://zipkin:9411" },
+		func(c *zipkin.Config) { c.ServiceName = "" },
+		func(c *zipkin.Config) { c.SampleRate = 1.5 },
+		func(c *zipkin.Config) { c.BatchSize = 0 },
+	} {
+		c := zipkin.NewConfig()
+		c.Enabled = true
+		fn(&c)
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected error for %+v", c)
+		}
+	}
+}
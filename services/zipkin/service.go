@@ -0,0 +1,238 @@
+// Package zipkin sends the traces of queries to a Zipkin collector.
+package zipkin // import "github.com/influxdata/influxdb/services/zipkin"
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/tracing"
+	"go.uber.org/zap"
+)
+
+// Statistics for the zipkin service.
+const (
+	statTracesSampled   = "tracesSampled"
+	statSpansSent       = "spansSent"
+	statSpansDropped    = "spansDropped"
+	statRequestFailures = "requestFailures"
+)
+
+// traceBufferSize is the number of traces waiting to be batched. Traces are
+// dropped while the buffer is full.
+const traceBufferSize = 1000
+
+// Service samples the queries to trace and sends their spans to a Zipkin
+// collector in batches. Spans are dropped when the collector cannot be
+// reached, so tracing never slows queries down.
+type Service struct {
+	// The counters come first to be 64-bit aligned for the atomic package.
+	tracesSampled   int64
+	spansSent       int64
+	spansDropped    int64
+	requestFailures int64
+
+	config Config
+	client *http.Client
+	traces chan *tracing.Trace
+
+	mu   sync.Mutex
+	rand *rand.Rand
+
+	closing chan struct{}
+	wg      sync.WaitGroup
+
+	Logger *zap.Logger
+}
+
+// NewService returns a new instance of Service. Traces can be exported
+// before the service is opened, and are sent once it is.
+func NewService(c Config) *Service {
+	return &Service{
+		config:  c,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		traces:  make(chan *tracing.Trace, traceBufferSize),
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		closing: make(chan struct{}),
+		Logger:  zap.NewNop(),
+	}
+}
+
+// Open starts sending the spans exported.
+func (s *Service) Open() error {
+	s.Logger.Info(fmt.Sprintf("Starting zipkin service, sending %g of the queries to %s", s.config.SampleRate, s.config.URL))
+
+	s.wg.Add(1)
+	go s.run()
+	return nil
+}
+
+// Close sends the spans batched and stops.
+func (s *Service) Close() error {
+	select {
+	case <-s.closing:
+		return nil
+	default:
+		close(s.closing)
+	}
+	s.wg.Wait()
+	return nil
+}
+
+// WithLogger sets the logger on the service.
+func (s *Service) WithLogger(log *zap.Logger) {
+	s.Logger = log.With(zap.String("service", "zipkin"))
+}
+
+// Sample returns true if the query about to be executed should be traced.
+func (s *Service) Sample() bool {
+	s.mu.Lock()
+	sampled := s.rand.Float64() < s.config.SampleRate
+	s.mu.Unlock()
+
+	if sampled {
+		atomic.AddInt64(&s.tracesSampled, 1)
+	}
+	return sampled
+}
+
+// Export queues the finished spans of t to be sent.
+func (s *Service) Export(t *tracing.Trace) {
+	select {
+	case s.traces <- t:
+	default:
+		atomic.AddInt64(&s.spansDropped, int64(len(t.Spans())))
+	}
+}
+
+// Statistics returns statistics for periodic monitoring.
+func (s *Service) Statistics(tags map[string]string) []models.Statistic {
+	return []models.Statistic{{
+		Name: "zipkin",
+		Tags: tags,
+		Values: map[string]interface{}{
+			statTracesSampled:   atomic.LoadInt64(&s.tracesSampled),
+			statSpansSent:       atomic.LoadInt64(&s.spansSent),
+			statSpansDropped:    atomic.LoadInt64(&s.spansDropped),
+			statRequestFailures: atomic.LoadInt64(&s.requestFailures),
+		},
+	}}
+}
+
+// run batches the spans of the traces exported, and sends the batches that
+// are full or older than the batch timeout.
+func (s *Service) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(s.config.BatchTimeout))
+	defer ticker.Stop()
+
+	var batch []span
+	for {
+		select {
+		case t := <-s.traces:
+			for _, raw := range t.Spans() {
+				batch = append(batch, newSpan(raw, s.config.ServiceName))
+			}
+			if len(batch) >= s.config.BatchSize {
+				s.send(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			s.send(batch)
+			batch = nil
+		case <-s.closing:
+			s.send(batch)
+			return
+		}
+	}
+}
+
+// send posts batch to the collector.
+func (s *Service) send(batch []span) {
+	if len(batch) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(batch)
+	if err != nil {
+		s.Logger.Info(fmt.Sprintf("error encoding %d spans: %s", len(batch), err))
+		atomic.AddInt64(&s.spansDropped, int64(len(batch)))
+		return
+	}
+
+	resp, err := s.client.Post(s.config.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		s.Logger.Info(fmt.Sprintf("error sending %d spans to %s: %s", len(batch), s.config.URL, err))
+		atomic.AddInt64(&s.requestFailures, 1)
+		atomic.AddInt64(&s.spansDropped, int64(len(batch)))
+		return
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		s.Logger.Info(fmt.Sprintf("error sending %d spans to %s: %s", len(batch), s.config.URL, resp.Status))
+		atomic.AddInt64(&s.requestFailures, 1)
+		atomic.AddInt64(&s.spansDropped, int64(len(batch)))
+		return
+	}
+	atomic.AddInt64(&s.spansSent, int64(len(batch)))
+}
+
+// span is a span in the JSON format of the Zipkin v2 API.
+type span struct {
+	TraceID       string            `json:"traceId"`
+	ID            string            `json:"id"`
+	ParentID      string            `json:"parentId,omitempty"`
+	Name          string            `json:"name"`
+	Timestamp     int64             `json:"timestamp"`
+	Duration      int64             `json:"duration"`
+	LocalEndpoint endpoint          `json:"localEndpoint"`
+	Tags          map[string]string `json:"tags,omitempty"`
+}
+
+type endpoint struct {
+	ServiceName string `json:"serviceName"`
+}
+
+// newSpan converts raw to a Zipkin span. The labels and the fields of raw
+// become the tags of the span.
+func newSpan(raw tracing.RawSpan, serviceName string) span {
+	sp := span{
+		TraceID:       fmt.Sprintf("%016x", raw.Context.TraceID),
+		ID:            fmt.Sprintf("%016x", raw.Context.SpanID),
+		Name:          raw.Name,
+		Timestamp:     raw.Start.UnixNano() / int64(time.Microsecond),
+		Duration:      int64(raw.Duration / time.Microsecond),
+		LocalEndpoint: endpoint{ServiceName: serviceName},
+	}
+	if raw.ParentSpanID != 0 {
+		sp.ParentID = fmt.Sprintf("%016x", raw.ParentSpanID)
+	}
+
+	// Zipkin reads a zero duration as unknown, so spans shorter than the
+	// microsecond it counts in are rounded up.
+	if sp.Duration == 0 {
+		sp.Duration = 1
+	}
+
+	if len(raw.Labels) > 0 || len(raw.Fields) > 0 {
+		sp.Tags = make(map[string]string, len(raw.Labels)+len(raw.Fields))
+		for _, l := range raw.Labels {
+			sp.Tags[l.Key] = l.Value
+		}
+		for _, f := range raw.Fields {
+			sp.Tags[f.Key()] = fmt.Sprint(f.Value())
+		}
+	}
+	return sp
+}
@@ -0,0 +1,87 @@
+package zipkin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/pkg/tracing"
+	"github.com/influxdata/influxdb/services/zipkin"
+	"github.com/influxdata/influxdb/toml"
+)
+
+// Ensure the spans of an exported trace are sent to the collector.
+func TestService_Export(t *testing.T) {
+	type span struct {
+		TraceID       string            `json:"traceId"`
+		ID            string            `json:"id"`
+		ParentID      string            `json:"parentId"`
+		Name          string            `json:"name"`
+		Duration      int64             `json:"duration"`
+		LocalEndpoint map[string]string `json:"localEndpoint"`
+		Tags          map[string]string `json:"tags"`
+	}
+
+	received := make(chan []span, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var spans []span
+		if err := json.NewDecoder(r.Body).Decode(&spans); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		received <- spans
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	c := zipkin.NewConfig()
+	c.Enabled = true
+	c.URL = srv.URL
+	c.SampleRate = 1
+	c.BatchTimeout = toml.Duration(10 * time.Millisecond)
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := zipkin.NewService(c)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if !s.Sample() {
+		t.Fatal("expected the trace to be sampled")
+	}
+	trace, root := tracing.NewTrace("select")
+	root.SetLabels("statement", "SELECT * FROM cpu")
+	child := root.StartSpan("plan")
+	child.Finish()
+	root.Finish()
+	s.Export(trace)
+
+	var spans []span
+	select {
+	case spans = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the spans")
+	}
+	if len(spans) != 2 {
+		t.Fatalf("unexpected spans: %+v", spans)
+	}
+
+	byName := make(map[string]span)
+	for _, sp := range spans {
+		byName[sp.Name] = sp
+		if sp.Duration <= 0 {
+			t.Errorf("unexpected duration of %s: %d", sp.Name, sp.Duration)
+		} else if sp.LocalEndpoint["serviceName"] != "influxdb" {
+			t.Errorf("unexpected endpoint of %s: %v", sp.Name, sp.LocalEndpoint)
+		}
+	}
+	if sp := byName["select"]; sp.ParentID != "" || sp.Tags["statement"] != "SELECT * FROM cpu" {
+		t.Fatalf("unexpected root span: %+v", sp)
+	} else if child := byName["plan"]; child.ParentID != sp.ID || child.TraceID != sp.TraceID {
+		t.Fatalf("unexpected child span: %+v", child)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -233,6 +234,123 @@ func TestServer_BackupAndRestore(t *testing.T) {
 
 }
 
+// Ensure an incremental portable backup only backs up the shards that changed
+// since the previous backup, and that the backups can be restored both as of
+// the previous backup and with everything backed up since.
+func TestServer_BackupAndRestore_Incremental(t *testing.T) {
+	config := NewConfig()
+	config.Data.Engine = "tsm1"
+	config.BindAddress = freePort()
+
+	// set the cache snapshot size low so that a single point will cause TSM file creation
+	config.Data.CacheSnapshotMemorySize = 1
+
+	backupDir, _ := ioutil.TempDir("", "backup")
+	defer os.RemoveAll(backupDir)
+
+	s := OpenServer(config)
+	defer s.Close()
+
+	if _, ok := s.(*RemoteServer); ok {
+		t.Skip("Skipping.  Cannot modify remote server config")
+	}
+
+	db := "mydb"
+	rp := "forever"
+	if err := s.CreateDatabaseAndRetentionPolicy(db, NewRetentionPolicySpec(rp, 1, 0), true); err != nil {
+		t.Fatal(err)
+	}
+
+	_, port, err := net.SplitHostPort(config.BindAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostAddress := net.JoinHostPort("localhost", port)
+
+	// The points are in two shards, one in 1970 and one in 2000.
+	if _, err := s.Write(db, rp, "myseries,host=A value=23 1000000\nmyseries,host=B value=24 946684800000000000", nil); err != nil {
+		t.Fatalf("failed to write: %s", err)
+	}
+
+	// wait for the snapshot to write
+	time.Sleep(time.Second)
+
+	full := backup.NewCommand()
+	if err := full.Run("-portable", "-host", hostAddress, "-database", db, backupDir); err != nil {
+		t.Fatalf("error backing up: %s, hostAddress: %s", err.Error(), hostAddress)
+	}
+	if n := countShardFiles(full.BackupFiles); n != 2 {
+		t.Fatalf("unexpected shards backed up: %d: %v", n, full.BackupFiles)
+	}
+
+	// Portable backups are named after the second they start, so the next
+	// one must start in a later second.
+	fullTime := time.Now().UTC()
+	time.Sleep(1100 * time.Millisecond)
+
+	// Only the shard of 2000 changes.
+	if _, err := s.Write(db, rp, "myseries,host=C value=25 946684801000000000", nil); err != nil {
+		t.Fatalf("failed to write: %s", err)
+	}
+
+	// wait for the snapshot to write
+	time.Sleep(time.Second)
+
+	incr := backup.NewCommand()
+	if err := incr.Run("-portable", "-incremental", "-host", hostAddress, "-database", db, backupDir); err != nil {
+		t.Fatalf("error backing up: %s, hostAddress: %s", err.Error(), hostAddress)
+	}
+	if n := countShardFiles(incr.BackupFiles); n != 1 {
+		t.Fatalf("unexpected shards backed up: %d: %v", n, incr.BackupFiles)
+	}
+
+	// Restoring as of the full backup leaves out the point written since.
+	if err := restore.NewCommand().Run("-host", hostAddress, "-portable", "-time", fullTime.Format(time.RFC3339), "-db", db, "-newdb", "mydb_then", backupDir); err != nil {
+		t.Fatalf("error restoring: %s", err.Error())
+	}
+
+	// Restoring everything uses the unchanged shard of the full backup.
+	if err := restore.NewCommand().Run("-host", hostAddress, "-portable", "-db", db, "-newdb", "mydb_now", backupDir); err != nil {
+		t.Fatalf("error restoring: %s", err.Error())
+	}
+
+	// wait for the import to finish, and unlock the shard engine.
+	time.Sleep(time.Second)
+
+	for _, tt := range []struct {
+		db  string
+		exp string
+	}{
+		{
+			db:  "mydb_then",
+			exp: `{"results":[{"statement_id":0,"series":[{"name":"myseries","columns":["time","host","value"],"values":[["1970-01-01T00:00:00.001Z","A",23],["2000-01-01T00:00:00Z","B",24]]}]}]}`,
+		},
+		{
+			db:  "mydb_now",
+			exp: `{"results":[{"statement_id":0,"series":[{"name":"myseries","columns":["time","host","value"],"values":[["1970-01-01T00:00:00.001Z","A",23],["2000-01-01T00:00:00Z","B",24],["2000-01-01T00:00:01Z","C",25]]}]}]}`,
+		},
+	} {
+		res, err := s.Query(fmt.Sprintf(`select * from "%s"."forever"."myseries"`, tt.db))
+		if err != nil {
+			t.Fatalf("error querying: %s", err.Error())
+		} else if res != tt.exp {
+			t.Fatalf("query results wrong for %s:\n\texp: %s\n\tgot: %s", tt.db, tt.exp, res)
+		}
+	}
+}
+
+// countShardFiles returns the number of shard files in the files of a
+// portable backup.
+func countShardFiles(files []string) int {
+	var n int
+	for _, f := range files {
+		if strings.HasSuffix(f, ".tar.gz") {
+			n++
+		}
+	}
+	return n
+}
+
 func freePort() string {
 	l, _ := net.Listen("tcp", "")
 	defer l.Close()
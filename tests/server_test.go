@@ -4203,6 +4203,119 @@ func TestServer_Query_Aggregates_String(t *testing.T) {
 	}
 }
 
+func TestServer_Query_Aggregates_Boolean(t *testing.T) {
+	t.Parallel()
+	s := OpenDefaultServer(NewConfig())
+	defer s.Close()
+
+	test := NewTest("db0", "rp0")
+	test.writes = Writes{
+		&Write{data: strings.Join([]string{
+			fmt.Sprintf(`booldata value=true %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:00Z").UnixNano()),
+			fmt.Sprintf(`booldata value=false %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:10Z").UnixNano()),
+			fmt.Sprintf(`booldata value=true %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:20Z").UnixNano()),
+		}, "\n")},
+	}
+
+	test.addQueries([]*Query{
+		&Query{
+			name:    "COUNT on boolean data",
+			params:  url.Values{"db": []string{"db0"}},
+			command: `SELECT COUNT(value) FROM booldata`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"booldata","columns":["time","count"],"values":[["1970-01-01T00:00:00Z",3]]}]}]}`,
+		},
+		&Query{
+			name:    "DISTINCT on boolean data",
+			params:  url.Values{"db": []string{"db0"}},
+			command: `SELECT DISTINCT(value) FROM booldata`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"booldata","columns":["time","distinct"],"values":[["1970-01-01T00:00:00Z",false],["1970-01-01T00:00:00Z",true]]}]}]}`,
+		},
+		&Query{
+			name:    "COUNT(DISTINCT) on boolean data",
+			params:  url.Values{"db": []string{"db0"}},
+			command: `SELECT COUNT(DISTINCT(value)) FROM booldata`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"booldata","columns":["time","count"],"values":[["1970-01-01T00:00:00Z",2]]}]}]}`,
+		},
+	}...)
+
+	for i, query := range test.queries {
+		t.Run(query.name, func(t *testing.T) {
+			if i == 0 {
+				if err := test.init(s); err != nil {
+					t.Fatalf("test init failed: %s", err)
+				}
+			}
+			if query.skip {
+				t.Skipf("SKIP:: %s", query.name)
+			}
+			if err := query.Execute(s); err != nil {
+				t.Error(query.Error(err))
+			} else if !query.success() {
+				t.Error(query.failureMessage())
+			}
+		})
+	}
+}
+
+func TestServer_Query_Aggregates_Unsigned(t *testing.T) {
+	t.Parallel()
+	s := OpenDefaultServer(NewConfig())
+	defer s.Close()
+
+	test := NewTest("db0", "rp0")
+	test.writes = Writes{
+		&Write{data: strings.Join([]string{
+			fmt.Sprintf(`uintdata value=18446744073709551610u %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:00Z").UnixNano()),
+			fmt.Sprintf(`uintdata value=3u %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:10Z").UnixNano()),
+		}, "\n")},
+	}
+
+	test.addQueries([]*Query{
+		&Query{
+			name:    "MAX on unsigned data",
+			params:  url.Values{"db": []string{"db0"}},
+			command: `SELECT MAX(value) FROM uintdata`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"uintdata","columns":["time","max"],"values":[["2000-01-01T00:00:00Z",18446744073709551610]]}]}]}`,
+		},
+		&Query{
+			name:    "MIN on unsigned data",
+			params:  url.Values{"db": []string{"db0"}},
+			command: `SELECT MIN(value) FROM uintdata`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"uintdata","columns":["time","min"],"values":[["2000-01-01T00:00:10Z",3]]}]}]}`,
+		},
+		&Query{
+			name:    "SUM on unsigned data",
+			params:  url.Values{"db": []string{"db0"}},
+			command: `SELECT SUM(value) FROM uintdata`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"uintdata","columns":["time","sum"],"values":[["1970-01-01T00:00:00Z",18446744073709551613]]}]}]}`,
+		},
+		&Query{
+			name:    "COUNT on unsigned data",
+			params:  url.Values{"db": []string{"db0"}},
+			command: `SELECT COUNT(value) FROM uintdata`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"uintdata","columns":["time","count"],"values":[["1970-01-01T00:00:00Z",2]]}]}]}`,
+		},
+	}...)
+
+	for i, query := range test.queries {
+		t.Run(query.name, func(t *testing.T) {
+			if i == 0 {
+				if err := test.init(s); err != nil {
+					t.Fatalf("test init failed: %s", err)
+				}
+			}
+			if query.skip {
+				t.Skipf("SKIP:: %s", query.name)
+			}
+			if err := query.Execute(s); err != nil {
+				t.Error(query.Error(err))
+			} else if !query.success() {
+				t.Error(query.failureMessage())
+			}
+		})
+	}
+}
+
 func TestServer_Query_Aggregates_Math(t *testing.T) {
 	t.Parallel()
 	s := OpenServer(NewConfig())
@@ -4270,6 +4383,64 @@ func TestServer_Query_Aggregates_Math(t *testing.T) {
 	}
 }
 
+// Ensure the server joins calls on fields qualified by their measurement.
+func TestServer_Query_Aggregates_Join(t *testing.T) {
+	t.Parallel()
+	s := OpenServer(NewConfig())
+	defer s.Close()
+
+	if err := s.CreateDatabaseAndRetentionPolicy("db0", NewRetentionPolicySpec("rp0", 1, 0), true); err != nil {
+		t.Fatal(err)
+	}
+
+	writes := []string{
+		fmt.Sprintf(`errors,host=A value=1 %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:00Z").UnixNano()),
+		fmt.Sprintf(`errors,host=A value=2 %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:05Z").UnixNano()),
+		fmt.Sprintf(`errors,host=A value=1 %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:12Z").UnixNano()),
+		fmt.Sprintf(`requests,host=A value=30,errors.value=3 %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:01Z").UnixNano()),
+		fmt.Sprintf(`requests,host=A value=10,errors.value=1 %d`, mustParseTime(time.RFC3339Nano, "2000-01-01T00:00:11Z").UnixNano()),
+	}
+
+	test := NewTest("db0", "rp0")
+	test.writes = Writes{
+		&Write{data: strings.Join(writes, "\n")},
+	}
+
+	test.addQueries([]*Query{
+		&Query{
+			name:    "join qualified fields",
+			params:  url.Values{"db": []string{"db0"}},
+			command: `SELECT sum(errors.value) / sum(requests.value) FROM errors, requests WHERE time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T00:00:20Z' GROUP BY time(10s), host fill(none)`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"errors","tags":{"host":"A"},"columns":["time","sum_sum"],"values":[["2000-01-01T00:00:00Z",0.1],["2000-01-01T00:00:10Z",0.1]]}]}]}`,
+		},
+		&Query{
+			name:    "field named after a measurement is not qualified",
+			params:  url.Values{"db": []string{"db0"}},
+			command: `SELECT sum(errors.value) FROM errors, requests WHERE time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T00:00:20Z' GROUP BY time(10s), host fill(none)`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"requests","tags":{"host":"A"},"columns":["time","sum"],"values":[["2000-01-01T00:00:00Z",3],["2000-01-01T00:00:10Z",1]]}]}]}`,
+		},
+	}...)
+
+	for i, query := range test.queries {
+		t.Run(query.name, func(t *testing.T) {
+			if i == 0 {
+				if err := test.init(s); err != nil {
+					t.Fatalf("test init failed: %s", err)
+				}
+			}
+			if query.skip {
+				t.Skipf("SKIP:: %s", query.name)
+			}
+
+			if err := query.Execute(s); err != nil {
+				t.Error(query.Error(err))
+			} else if !query.success() {
+				t.Error(query.failureMessage())
+			}
+		})
+	}
+}
+
 func TestServer_Query_AggregateSelectors(t *testing.T) {
 	t.Parallel()
 	s := OpenServer(NewConfig())
@@ -6150,6 +6321,24 @@ func TestServer_Query_Where_Fields(t *testing.T) {
 			command: `SELECT alert_id FROM cpu WHERE _cust='acme'`,
 			exp:     `{"results":[{"statement_id":0}]}`,
 		},
+		&Query{
+			name:    "string regex",
+			params:  url.Values{"db": []string{"db0"}},
+			command: `SELECT alert_id FROM cpu WHERE _cust =~ /^johnson/`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","alert_id"],"values":[["2015-02-28T01:03:36.703820946Z","alert"]]}]}]}`,
+		},
+		&Query{
+			name:    "string contains",
+			params:  url.Values{"db": []string{"db0"}},
+			command: `SELECT alert_id FROM cpu WHERE contains(_cust, 'n b')`,
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"cpu","columns":["time","alert_id"],"values":[["2015-02-28T01:03:36.703820946Z","alert"]]}]}]}`,
+		},
+		&Query{
+			name:    "string contains special characters",
+			params:  url.Values{"db": []string{"db0"}},
+			command: `SELECT alert_id FROM cpu WHERE contains(_cust, 'johnson.')`,
+			exp:     `{"results":[{"statement_id":0}]}`,
+		},
 
 		// float64
 		&Query{
@@ -7556,6 +7745,34 @@ func TestServer_Query_ShowMeasurements(t *testing.T) {
 			exp:     `{"results":[{"statement_id":0,"series":[{"name":"measurements","columns":["name"],"values":[["cpu"],["gpu"]]}]}]}`,
 			params:  url.Values{"db": []string{"db0"}},
 		},
+		&Query{
+			name:    `show measurements with limit 2 and offset 1`,
+			command: "SHOW MEASUREMENTS LIMIT 2 OFFSET 1",
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"measurements","columns":["name"],"values":[["gpu"],["other"]]}]}]}`,
+			params:  url.Values{"db": []string{"db0"}},
+		},
+		&Query{
+			name:    `show measurements with offset past the end`,
+			command: "SHOW MEASUREMENTS OFFSET 3",
+			exp:     `{"results":[{"statement_id":0}]}`,
+			params:  url.Values{"db": []string{"db0"}},
+		},
+		&Query{
+			name:    `show measurements using ON without db param`,
+			command: "SHOW MEASUREMENTS ON db0",
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"measurements","columns":["name"],"values":[["cpu"],["gpu"],["other"]]}]}]}`,
+		},
+		&Query{
+			name:    `show measurements using ON overrides db param`,
+			command: "SHOW MEASUREMENTS ON db0",
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"measurements","columns":["name"],"values":[["cpu"],["gpu"],["other"]]}]}]}`,
+			params:  url.Values{"db": []string{"db1"}},
+		},
+		&Query{
+			name:    `show measurements using ON with WITH regex and limit`,
+			command: "SHOW MEASUREMENTS ON db0 WITH MEASUREMENT =~ /[cg]pu/ LIMIT 1 OFFSET 1",
+			exp:     `{"results":[{"statement_id":0,"series":[{"name":"measurements","columns":["name"],"values":[["gpu"]]}]}]}`,
+		},
 		&Query{
 			name:    `show measurements using WITH`,
 			command: "SHOW MEASUREMENTS WITH MEASUREMENT = cpu",
@@ -9499,8 +9716,3 @@ func TestServer_NestedAggregateWithMathPanics(t *testing.T) {
 		})
 	}
 }
-
-func init() {
-	// Force uint support to be enabled for testing.
-	models.EnableUintSupport()
-}
@@ -5,6 +5,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/cespare/xxhash"
 	"github.com/influxdata/influxdb/models"
 )
 
@@ -16,6 +17,12 @@ type PointBatcher struct {
 	size     int
 	duration time.Duration
 
+	// DedupWindow, if set before the batcher is started, drops the points
+	// identical to a point received less than DedupWindow ago, such as those
+	// of a packet duplicated by a relay. Points are identical when their
+	// series, fields and time, at the precision they were written with, are.
+	DedupWindow time.Duration
+
 	stop  chan struct{}
 	in    chan models.Point
 	out   chan []models.Point
@@ -45,6 +52,7 @@ type PointBatcherStats struct {
 	PointTotal   uint64 // Total count of points processed.
 	SizeTotal    uint64 // Number of batches that reached size threshold.
 	TimeoutTotal uint64 // Number of timeouts that occurred.
+	DedupTotal   uint64 // Number of duplicate points dropped.
 }
 
 // Start starts the batching process. Returns the in and out channels for points
@@ -59,6 +67,7 @@ func (b *PointBatcher) Start() {
 	timer := time.NewTimer(time.Hour)
 	timer.Stop()
 	var batch []models.Point
+	dedup := pointDedup{window: b.DedupWindow}
 
 	emit := func() {
 
@@ -90,6 +99,11 @@ func (b *PointBatcher) Start() {
 				return
 			case p := <-b.in:
 				atomic.AddUint64(&b.stats.PointTotal, 1)
+				if dedup.window > 0 && dedup.seen(p, time.Now()) {
+					atomic.AddUint64(&b.stats.DedupTotal, 1)
+					continue
+				}
+
 				if batch == nil {
 					if b.size > 0 {
 						batch = make([]models.Point, 0, b.size)
@@ -153,5 +167,40 @@ func (b *PointBatcher) Stats() *PointBatcherStats {
 	stats.PointTotal = atomic.LoadUint64(&b.stats.PointTotal)
 	stats.SizeTotal = atomic.LoadUint64(&b.stats.SizeTotal)
 	stats.TimeoutTotal = atomic.LoadUint64(&b.stats.TimeoutTotal)
+	stats.DedupTotal = atomic.LoadUint64(&b.stats.DedupTotal)
 	return &stats
 }
+
+// pointDedup remembers the hashes of the points seen in the current and the
+// previous windows, so a point is found to be a duplicate for one to two
+// windows after it was seen, and memory does not grow past two windows of
+// points.
+type pointDedup struct {
+	window  time.Duration
+	rotated time.Time
+	cur     map[uint64]struct{}
+	prev    map[uint64]struct{}
+	buf     []byte
+}
+
+// seen returns true if p was seen in the window before now, and remembers p
+// otherwise.
+func (d *pointDedup) seen(p models.Point, now time.Time) bool {
+	if elapsed := now.Sub(d.rotated); elapsed >= 2*d.window {
+		d.cur, d.prev = make(map[uint64]struct{}), nil
+		d.rotated = now
+	} else if elapsed >= d.window {
+		d.cur, d.prev = make(map[uint64]struct{}, len(d.cur)), d.cur
+		d.rotated = now
+	}
+
+	d.buf = p.AppendString(d.buf[:0])
+	h := xxhash.Sum64(d.buf)
+	if _, ok := d.cur[h]; ok {
+		return true
+	} else if _, ok := d.prev[h]; ok {
+		return true
+	}
+	d.cur[h] = struct{}{}
+	return false
+}
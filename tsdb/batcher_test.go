@@ -128,6 +128,31 @@ func TestBatch_MultipleBatches(t *testing.T) {
 	checkPointBatcherStats(t, batcher, -1, 3, 1, 1)
 }
 
+// TestBatch_Dedup ensures that a batcher drops the points identical to a point it received in its dedup window.
+func TestBatch_Dedup(t *testing.T) {
+	batcher := tsdb.NewPointBatcher(3, 0, time.Hour)
+	batcher.DedupWindow = time.Hour
+	batcher.Start()
+	defer batcher.Stop()
+
+	p0 := models.MustNewPoint("cpu", nil, models.Fields{"value": 1.0}, time.Unix(0, 0))
+	p1 := models.MustNewPoint("cpu", nil, models.Fields{"value": 2.0}, time.Unix(0, 0))
+	p2 := models.MustNewPoint("cpu", nil, models.Fields{"value": 1.0}, time.Unix(1, 0))
+	go func() {
+		for _, p := range []models.Point{p0, p0, p1, p0, p2} {
+			batcher.In() <- p
+		}
+	}()
+	batch := <-batcher.Out()
+	if len(batch) != 3 || batch[0] != p0 || batch[1] != p1 || batch[2] != p2 {
+		t.Fatalf("unexpected batch: %v", batch)
+	}
+	checkPointBatcherStats(t, batcher, -1, 5, 1, 0)
+	if got := batcher.Stats().DedupTotal; got != 2 {
+		t.Errorf("dedup total stat is incorrect: %d", got)
+	}
+}
+
 func checkPointBatcherStats(t *testing.T, b *tsdb.PointBatcher, batchTotal, pointTotal, sizeTotal, timeoutTotal int) {
 	stats := b.Stats()
 
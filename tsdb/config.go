@@ -51,6 +51,54 @@ const (
 	// DefaultMaxConcurrentCompactions is the maximum number of concurrent full and level compactions
 	// that can run at one time.  A value of 0 results in 50% of runtime.GOMAXPROCS(0) used at runtime.
 	DefaultMaxConcurrentCompactions = 0
+
+	// DefaultMaxConcurrentShardOpens is the maximum number of shards opened at
+	// once on startup. A value of 0 results in runtime.GOMAXPROCS(0) used at
+	// runtime.
+	DefaultMaxConcurrentShardOpens = 0
+
+	// DefaultFieldTypeConflict is the default policy for fields written with
+	// a different type than they have in a shard.
+	DefaultFieldTypeConflict = FieldTypeConflictReject
+
+	// DefaultDuplicatePoints is the default policy for points written with
+	// the series and time of a point already in a shard.
+	DefaultDuplicatePoints = DuplicatePointsLastWriteWins
+)
+
+// Policies for fields written with a different type than they have in a
+// shard.
+const (
+	// FieldTypeConflictReject drops the point and returns a partial write
+	// error naming the field.
+	FieldTypeConflictReject = "reject"
+
+	// FieldTypeConflictCoerce converts the value to the type of the field
+	// when no information is lost, such as the integer 1 to the float 1.
+	// Other values are rejected.
+	FieldTypeConflictCoerce = "coerce"
+
+	// FieldTypeConflictDrop drops the point and logs it, without an error.
+	FieldTypeConflictDrop = "drop"
+)
+
+// Policies for points written with the series and time of a point already in
+// a shard.
+const (
+	// DuplicatePointsLastWriteWins replaces the values of the fields written,
+	// and keeps the other fields of the point. Only the cache is looked up
+	// to count the points replacing a value, so that writes do not read TSM
+	// files.
+	DuplicatePointsLastWriteWins = "last-write-wins"
+
+	// DuplicatePointsFirstWriteWins drops the point if any of its own fields
+	// has a value at its time. Other fields of the series are not looked up,
+	// so a point adding a new field at that time is written.
+	DuplicatePointsFirstWriteWins = "first-write-wins"
+
+	// DuplicatePointsMergeFields only writes the fields of the point that
+	// have no value at its time.
+	DuplicatePointsMergeFields = "merge-fields"
 )
 
 // Config holds the configuration for the tsbd package.
@@ -76,6 +124,17 @@ type Config struct {
 	CacheSnapshotWriteColdDuration toml.Duration `toml:"cache-snapshot-write-cold-duration"`
 	CompactFullWriteColdDuration   toml.Duration `toml:"compact-full-write-cold-duration"`
 
+	// TSMReadAheadSize is the number of bytes read ahead of a scan once it is
+	// found to be reading the blocks of a TSM file in order. Reading ahead
+	// helps cold queries on storage with high latency, such as network
+	// attached disks. A value of 0 disables read-ahead.
+	TSMReadAheadSize toml.Size `toml:"tsm-read-ahead-size"`
+
+	// BlockCacheMaxMemorySize is the maximum size of the decoded TSM blocks a
+	// shard keeps in memory for queries that read the same blocks again.
+	// Blocks of recent data are evicted last. A value of 0 disables the cache.
+	BlockCacheMaxMemorySize toml.Size `toml:"block-cache-max-memory-size"`
+
 	// Limits
 
 	// MaxSeriesPerDatabase is the maximum number of series a node can hold per database.
@@ -94,9 +153,87 @@ type Config struct {
 	// not affected by this limit.  A value of 0 limits compactions to runtime.GOMAXPROCS(0).
 	MaxConcurrentCompactions int `toml:"max-concurrent-compactions"`
 
+	// MaxConcurrentShardOpens is the maximum number of shards opened at once
+	// on startup. Opening a shard mostly waits on the disk, to replay its WAL
+	// and load its index, so more than runtime.GOMAXPROCS(0) can speed up
+	// opening on fast disks. A value of 0 uses runtime.GOMAXPROCS(0).
+	MaxConcurrentShardOpens int `toml:"max-concurrent-shard-opens"`
+
+	// DiskFreeSoftLimit is the free space, in bytes, below which level and full
+	// compactions are paused on the data and WAL filesystems. Compactions
+	// need temporary space for the files they rewrite. A value of 0 disables
+	// the limit.
+	DiskFreeSoftLimit toml.Size `toml:"disk-free-soft-limit"`
+
+	// DiskFreeHardLimit is the free space, in bytes, below which writes are
+	// rejected with an error. A value of 0 disables the limit.
+	DiskFreeHardLimit toml.Size `toml:"disk-free-hard-limit"`
+
+	// MaxDatabaseSize is the size on disk, in bytes, of the shards of a
+	// database above which a warning is logged. Writes are still accepted.
+	// A value of 0 disables the limit.
+	MaxDatabaseSize toml.Size `toml:"max-database-size"`
+
+	// ShardUnloadColdDuration is the time after which a fully compacted shard
+	// that was not written to or queried is closed, releasing its files and
+	// index. It is reopened on its next access. A value of 0 keeps shards
+	// open.
+	ShardUnloadColdDuration toml.Duration `toml:"shard-unload-cold-duration"`
+
+	// FieldTypeConflict is the policy for a field written with a different
+	// type than it has in the shard: "reject", "coerce" or "drop".
+	FieldTypeConflict string `toml:"field-type-conflict"`
+
+	// DuplicatePoints is the policy for a point written with the series and
+	// time of a point already in the shard: "last-write-wins",
+	// "first-write-wins" or "merge-fields".
+	DuplicatePoints string `toml:"duplicate-points"`
+
+	// DatabaseLimits override the limits for single databases, so databases
+	// sharing a node cannot use up all of its resources.
+	DatabaseLimits []DatabaseLimits `toml:"database-limits"`
+
 	TraceLoggingEnabled bool `toml:"trace-logging-enabled"`
 }
 
+// DatabaseLimits are the limits of a single database.
+type DatabaseLimits struct {
+	Database string `toml:"database"`
+
+	// MaxSeries overrides max-series-per-database for the database. A value
+	// of 0 keeps the limit of the node.
+	MaxSeries int `toml:"max-series"`
+
+	// MaxCacheMemorySize is the maximum total size of the caches of the
+	// shards of the database. When it is exceeded, writes to the database
+	// return an error until the caches are snapshotted. A value of 0
+	// disables the limit.
+	MaxCacheMemorySize toml.Size `toml:"max-cache-memory-size"`
+
+	// MaxSize overrides max-database-size for the database. A value of 0
+	// keeps the limit of the node.
+	MaxSize toml.Size `toml:"max-size"`
+}
+
+// databaseLimits returns the limits configured for database, if any.
+func (c *Config) databaseLimits(database string) DatabaseLimits {
+	for _, l := range c.DatabaseLimits {
+		if l.Database == database {
+			return l
+		}
+	}
+	return DatabaseLimits{Database: database}
+}
+
+// maxDatabaseSize returns the size on disk above which a warning is logged
+// for database, or 0 if there is no limit.
+func (c *Config) maxDatabaseSize(database string) uint64 {
+	if n := c.databaseLimits(database).MaxSize; n > 0 {
+		return uint64(n)
+	}
+	return uint64(c.MaxDatabaseSize)
+}
+
 // NewConfig returns the default configuration for tsdb.
 func NewConfig() Config {
 	return Config{
@@ -113,6 +250,10 @@ func NewConfig() Config {
 		MaxSeriesPerDatabase:     DefaultMaxSeriesPerDatabase,
 		MaxValuesPerTag:          DefaultMaxValuesPerTag,
 		MaxConcurrentCompactions: DefaultMaxConcurrentCompactions,
+		MaxConcurrentShardOpens:  DefaultMaxConcurrentShardOpens,
+
+		FieldTypeConflict: DefaultFieldTypeConflict,
+		DuplicatePoints:   DefaultDuplicatePoints,
 
 		TraceLoggingEnabled: false,
 	}
@@ -130,6 +271,42 @@ func (c *Config) Validate() error {
 		return errors.New("max-concurrent-compactions must be greater than 0")
 	}
 
+	if c.MaxConcurrentShardOpens < 0 {
+		return errors.New("max-concurrent-shard-opens must not be negative")
+	}
+
+	if c.ShardUnloadColdDuration < 0 {
+		return errors.New("shard-unload-cold-duration must not be negative")
+	}
+
+	if c.DiskFreeSoftLimit > 0 && c.DiskFreeHardLimit > c.DiskFreeSoftLimit {
+		return errors.New("disk-free-hard-limit must not be greater than disk-free-soft-limit")
+	}
+
+	switch c.FieldTypeConflict {
+	case "", FieldTypeConflictReject, FieldTypeConflictCoerce, FieldTypeConflictDrop:
+	default:
+		return fmt.Errorf("unrecognized field-type-conflict %s", c.FieldTypeConflict)
+	}
+
+	switch c.DuplicatePoints {
+	case "", DuplicatePointsLastWriteWins, DuplicatePointsFirstWriteWins, DuplicatePointsMergeFields:
+	default:
+		return fmt.Errorf("unrecognized duplicate-points %s", c.DuplicatePoints)
+	}
+
+	databases := make(map[string]bool, len(c.DatabaseLimits))
+	for _, l := range c.DatabaseLimits {
+		if l.Database == "" {
+			return errors.New("database-limits entries must name a database")
+		} else if databases[l.Database] {
+			return fmt.Errorf("duplicate database-limits entry for database %s", l.Database)
+		} else if l.MaxSeries < 0 {
+			return fmt.Errorf("max-series of database %s must not be negative", l.Database)
+		}
+		databases[l.Database] = true
+	}
+
 	valid := false
 	for _, e := range RegisteredEngines() {
 		if e == c.Engine {
@@ -165,8 +342,18 @@ func (c Config) Diagnostics() (*diagnostics.Diagnostics, error) {
 		"cache-snapshot-memory-size":         c.CacheSnapshotMemorySize,
 		"cache-snapshot-write-cold-duration": c.CacheSnapshotWriteColdDuration,
 		"compact-full-write-cold-duration":   c.CompactFullWriteColdDuration,
+		"tsm-read-ahead-size":                c.TSMReadAheadSize,
+		"block-cache-max-memory-size":        c.BlockCacheMaxMemorySize,
 		"max-series-per-database":            c.MaxSeriesPerDatabase,
 		"max-values-per-tag":                 c.MaxValuesPerTag,
 		"max-concurrent-compactions":         c.MaxConcurrentCompactions,
+		"max-concurrent-shard-opens":         c.MaxConcurrentShardOpens,
+		"disk-free-soft-limit":               c.DiskFreeSoftLimit,
+		"disk-free-hard-limit":               c.DiskFreeHardLimit,
+		"max-database-size":                  c.MaxDatabaseSize,
+		"shard-unload-cold-duration":         c.ShardUnloadColdDuration,
+		"field-type-conflict":                c.FieldTypeConflict,
+		"duplicate-points":                   c.DuplicatePoints,
+		"database-limits":                    len(c.DatabaseLimits),
 	}), nil
 }
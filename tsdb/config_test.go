@@ -66,6 +66,28 @@ func TestConfig_Validate_Error(t *testing.T) {
 	if err := c.Validate(); err != nil {
 		t.Error(err)
 	}
+
+	c.DiskFreeSoftLimit = 1 << 30
+	c.DiskFreeHardLimit = 2 << 30
+	if err := c.Validate(); err == nil || err.Error() != "disk-free-hard-limit must not be greater than disk-free-soft-limit" {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	c.DiskFreeSoftLimit = 0
+	if err := c.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	c.MaxConcurrentShardOpens = -1
+	if err := c.Validate(); err == nil || err.Error() != "max-concurrent-shard-opens must not be negative" {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	c.MaxConcurrentShardOpens = 0
+	c.ShardUnloadColdDuration = -1
+	if err := c.Validate(); err == nil || err.Error() != "shard-unload-cold-duration must not be negative" {
+		t.Errorf("unexpected error: %s", err)
+	}
 }
 
 func TestConfig_ByteSizes(t *testing.T) {
@@ -0,0 +1,234 @@
+package tsdb
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb/monitor/diagnostics"
+	"github.com/influxdata/influxdb/pkg/file"
+)
+
+const (
+	// growthWindow is the period over which growth rates are averaged.
+	growthWindow = time.Hour
+
+	secondsPerDay = 24 * 60 * 60
+)
+
+// growthRate is a moving average of how fast a value grows per second.
+// Recent samples weigh more than older ones, so the rate follows changes in
+// load over about growthWindow.
+type growthRate struct {
+	last  float64
+	at    time.Time
+	rate  float64
+	valid bool // true once two samples have been taken
+}
+
+// update records value v sampled at now. For a counter, a sample below the
+// previous one means part of what was counted has gone away, such as a
+// dropped shard, and is not counted as negative growth.
+func (r *growthRate) update(v float64, now time.Time, counter bool) {
+	defer func() { r.last, r.at = v, now }()
+
+	if r.at.IsZero() {
+		return
+	}
+	dt := now.Sub(r.at).Seconds()
+	if dt <= 0 || (counter && v < r.last) {
+		return
+	}
+
+	instant := (v - r.last) / dt
+	if !r.valid {
+		r.rate, r.valid = instant, true
+		return
+	}
+	r.rate += (1 - math.Exp(-dt/growthWindow.Seconds())) * (instant - r.rate)
+}
+
+// databaseGrowth tracks how fast a database grows on disk and how fast
+// points are written to it.
+type databaseGrowth struct {
+	disk   growthRate // bytes per second
+	writes growthRate // points per second
+}
+
+// sampleGrowth records the disk size and points written of every database.
+func (s *Store) sampleGrowth(now time.Time) {
+	s.mu.RLock()
+	shards := s.shardsSlice()
+	s.mu.RUnlock()
+
+	sizes := make(map[string]int64)
+	points := make(map[string]int64)
+	for _, sh := range shards {
+		size, err := sh.DiskSize()
+		if err != nil {
+			continue
+		}
+		sizes[sh.database] += size
+		points[sh.database] += atomic.LoadInt64(&sh.stats.WritePointsOK)
+	}
+
+	s.growthMu.Lock()
+	defer s.growthMu.Unlock()
+
+	if s.growth == nil {
+		s.growth = make(map[string]*databaseGrowth)
+	}
+	for db := range s.growth {
+		if _, ok := sizes[db]; !ok {
+			delete(s.growth, db)
+		}
+	}
+	for db, size := range sizes {
+		g := s.growth[db]
+		if g == nil {
+			g = &databaseGrowth{}
+			s.growth[db] = g
+		}
+		g.disk.update(float64(size), now, false)
+		g.writes.update(float64(points[db]), now, true)
+	}
+}
+
+// databaseGrowthRates returns the rate database grows on disk, in bytes per
+// second, and the rate points are written to it, in points per second.
+func (s *Store) databaseGrowthRates(database string) (disk, writes float64) {
+	s.growthMu.Lock()
+	defer s.growthMu.Unlock()
+
+	if g := s.growth[database]; g != nil {
+		return g.disk.rate, g.writes.rate
+	}
+	return 0, 0
+}
+
+// diskUsage is the size on disk of the shards of a retention policy.
+type diskUsage struct {
+	bytes  int64
+	shards int64
+}
+
+// diskUsageByRetentionPolicy returns the disk usage of shards, by database
+// and retention policy. Shards whose size cannot be read, such as closed
+// shards, are left out.
+func diskUsageByRetentionPolicy(shards []*Shard) map[string]map[string]*diskUsage {
+	usage := make(map[string]map[string]*diskUsage)
+	for _, sh := range shards {
+		size, err := sh.DiskSize()
+		if err != nil {
+			continue
+		}
+
+		rps := usage[sh.database]
+		if rps == nil {
+			rps = make(map[string]*diskUsage)
+			usage[sh.database] = rps
+		}
+		u := rps[sh.retentionPolicy]
+		if u == nil {
+			u = &diskUsage{}
+			rps[sh.retentionPolicy] = u
+		}
+		u.bytes += size
+		u.shards++
+	}
+	return usage
+}
+
+// databaseDiskSize returns the total size on disk of the retention policies
+// of a database.
+func databaseDiskSize(rps map[string]*diskUsage) int64 {
+	var n int64
+	for _, u := range rps {
+		n += u.bytes
+	}
+	return n
+}
+
+// checkDatabaseSizes compares the size on disk of every database with
+// max-database-size and logs whenever a database crosses its limit.
+func (s *Store) checkDatabaseSizes() {
+	s.mu.RLock()
+	shards := s.shardsSlice()
+	s.mu.RUnlock()
+
+	usage := diskUsageByRetentionPolicy(shards)
+
+	s.growthMu.Lock()
+	defer s.growthMu.Unlock()
+
+	for db := range s.oversized {
+		if _, ok := usage[db]; !ok {
+			delete(s.oversized, db)
+		}
+	}
+	for db, rps := range usage {
+		limit := s.EngineOptions.Config.maxDatabaseSize(db)
+		size := databaseDiskSize(rps)
+		over := limit > 0 && uint64(size) > limit
+		if over == s.oversized[db] {
+			continue
+		}
+
+		if over {
+			if s.oversized == nil {
+				s.oversized = make(map[string]bool)
+			}
+			s.oversized[db] = true
+			s.Logger.Warn(fmt.Sprintf("Size on disk of database %s is %d bytes, above the max-database-size of %d bytes", db, size, limit))
+		} else {
+			delete(s.oversized, db)
+			s.Logger.Info(fmt.Sprintf("Size on disk of database %s is %d bytes, below the max-database-size of %d bytes", db, size, limit))
+		}
+	}
+}
+
+// DiskForecast estimates when the disk holding the data directory will be
+// full. It returns the free space in bytes, the rate the store grows on disk
+// in bytes per second, and the days left until the disk is full at that
+// rate. The days left are negative if the store is not growing.
+func (s *Store) DiskForecast() (free uint64, rate float64, days float64, err error) {
+	free, err = file.DiskFree(s.path)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	s.growthMu.Lock()
+	for _, g := range s.growth {
+		rate += g.disk.rate
+	}
+	s.growthMu.Unlock()
+
+	if rate <= 0 {
+		return free, rate, -1, nil
+	}
+	return free, rate, float64(free) / rate / secondsPerDay, nil
+}
+
+// Diagnostics returns the growth of each database, followed by a row with
+// the total growth of the store and the disk usage forecast.
+func (s *Store) Diagnostics() (*diagnostics.Diagnostics, error) {
+	free, rate, days, err := s.DiskForecast()
+	if err != nil {
+		return nil, err
+	}
+
+	d := diagnostics.NewDiagnostics([]string{"database", "growthBytesPerDay", "writePointsPerSecond", "freeBytes", "daysUntilFull"})
+	databases := s.Databases()
+	sort.Strings(databases)
+
+	var writes float64
+	for _, db := range databases {
+		dbDisk, dbWrites := s.databaseGrowthRates(db)
+		d.AddRow([]interface{}{db, int64(dbDisk * secondsPerDay), dbWrites, nil, nil})
+		writes += dbWrites
+	}
+	d.AddRow([]interface{}{"", int64(rate * secondsPerDay), writes, int64(free), days})
+	return d, nil
+}
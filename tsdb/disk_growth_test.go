@@ -0,0 +1,57 @@
+package tsdb
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGrowthRate_Update(t *testing.T) {
+	var r growthRate
+	now := time.Unix(0, 0)
+
+	// A single sample has no rate.
+	r.update(100, now, false)
+	if r.valid {
+		t.Fatal("unexpected rate after one sample")
+	}
+
+	// The second sample sets the rate.
+	now = now.Add(10 * time.Second)
+	r.update(200, now, false)
+	if !r.valid || r.rate != 10 {
+		t.Fatalf("unexpected rate: %v", r.rate)
+	}
+
+	// Later samples move the rate towards the new one.
+	now = now.Add(10 * time.Second)
+	r.update(200, now, false)
+	if r.rate <= 0 || r.rate >= 10 {
+		t.Fatalf("unexpected rate: %v", r.rate)
+	}
+
+	// A counter going down does not change the rate.
+	rate := r.rate
+	now = now.Add(10 * time.Second)
+	r.update(50, now, true)
+	if r.rate != rate {
+		t.Fatalf("unexpected rate: %v, exp %v", r.rate, rate)
+	}
+}
+
+func TestGrowthRate_Window(t *testing.T) {
+	var r growthRate
+	now := time.Unix(0, 0)
+	r.update(0, now, false)
+	now = now.Add(time.Second)
+	r.update(100, now, false)
+
+	// After a window of constant size, the rate has decayed to about 1/e.
+	for i := 0; i < 360; i++ {
+		now = now.Add(10 * time.Second)
+		r.update(100, now, false)
+	}
+	if exp := 100 / math.E; math.Abs(r.rate-exp) > 0.01 {
+		t.Fatalf("unexpected rate: %v, exp %v", r.rate, exp)
+	}
+}
@@ -75,6 +75,7 @@ type Engine interface {
 	Statistics(tags map[string]string) []models.Statistic
 	LastModified() time.Time
 	DiskSize() int64
+	CacheSize() uint64
 	IsIdle() bool
 	Free() error
 
@@ -155,10 +156,28 @@ type EngineOptions struct {
 	CompactionLimiter           limiter.Fixed
 	CompactionThroughputLimiter limiter.Rate
 
+	// LevelCompactionsPaused, if set, returns true while level and full
+	// compactions should not start. Snapshot compactions are not affected.
+	LevelCompactionsPaused func() bool
+
+	// WALReplayProgress, if set, is told of the WAL segments replayed when
+	// the engine is opened.
+	WALReplayProgress WALReplayProgress
+
 	Config       Config
 	SeriesIDSets SeriesIDSets
 }
 
+// WALReplayProgress is told of the progress of replaying the WAL of shards
+// as they are opened.
+type WALReplayProgress interface {
+	// AddWALSegments adds n segments to the number to replay.
+	AddWALSegments(n int)
+
+	// WALSegmentReplayed is called after each segment is replayed.
+	WALSegmentReplayed()
+}
+
 // NewEngineOptions returns the default options.
 func NewEngineOptions() EngineOptions {
 	return EngineOptions{
@@ -0,0 +1,139 @@
+package tsm1
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// blockCacheRecentWindow is how far back from now a block must have values
+// to be kept in the recent list of the block cache.
+const blockCacheRecentWindow = 24 * time.Hour
+
+// blockCache keeps decoded TSM blocks in memory, so the blocks read by
+// repeated queries are not decoded again. The least recently used blocks are
+// evicted first, but blocks with values older than blockCacheRecentWindow
+// are all evicted before any recent block, since queries mostly read recent
+// data.
+type blockCache struct {
+	mu      sync.Mutex
+	maxSize uint64
+	size    uint64
+	entries map[blockCacheKey]*list.Element
+
+	// recent and old hold the entries, most recently used first.
+	recent list.List
+	old    list.List
+
+	hits, misses int64
+
+	now func() time.Time
+}
+
+// blockCacheKey identifies a block of a TSM file.
+type blockCacheKey struct {
+	r      *TSMReader
+	offset int64
+}
+
+type blockCacheEntry struct {
+	key    blockCacheKey
+	values interface{}
+	size   uint64
+	list   *list.List
+}
+
+// newBlockCache returns a new blockCache holding up to maxSize bytes of
+// decoded values.
+func newBlockCache(maxSize uint64) *blockCache {
+	return &blockCache{
+		maxSize: maxSize,
+		entries: make(map[blockCacheKey]*list.Element),
+		now:     time.Now,
+	}
+}
+
+// get returns the decoded values of the block of r at entry, or nil.
+func (c *blockCache) get(r *TSMReader, entry *IndexEntry) interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem := c.entries[blockCacheKey{r: r, offset: entry.Offset}]
+	if elem == nil {
+		c.misses++
+		return nil
+	}
+	c.hits++
+	e := elem.Value.(*blockCacheEntry)
+	e.list.MoveToFront(elem)
+	return e.values
+}
+
+// put adds the decoded values of the block of r at entry, evicting blocks
+// until the cache is within its size. values must not be changed afterwards.
+func (c *blockCache) put(r *TSMReader, entry *IndexEntry, values interface{}, size uint64) {
+	if size > c.maxSize {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := blockCacheKey{r: r, offset: entry.Offset}
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+
+	l := &c.old
+	if entry.MaxTime >= c.now().Add(-blockCacheRecentWindow).UnixNano() {
+		l = &c.recent
+	}
+	e := &blockCacheEntry{key: key, values: values, size: size, list: l}
+	c.entries[key] = l.PushFront(e)
+	c.size += size
+
+	for c.size > c.maxSize {
+		elem := c.old.Back()
+		if elem == nil {
+			elem = c.recent.Back()
+		}
+		c.remove(elem)
+	}
+}
+
+// purge removes the blocks of r, once it is closed.
+func (c *blockCache) purge(r *TSMReader) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, elem := range c.entries {
+		if key.r == r {
+			c.remove(elem)
+		}
+	}
+}
+
+// remove removes the entry of elem. The caller must hold the lock.
+func (c *blockCache) remove(elem *list.Element) {
+	e := elem.Value.(*blockCacheEntry)
+	e.list.Remove(elem)
+	delete(c.entries, e.key)
+	c.size -= e.size
+}
+
+// statistics returns the number of cache hits and misses and the size of
+// the cached values.
+func (c *blockCache) statistics() (hits, misses, size int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses, int64(c.size)
+}
+
+// stringValuesSize returns the approximate number of bytes used by values.
+func stringValuesSize(values []StringValue) uint64 {
+	n := uint64(len(values)) * 24
+	for _, v := range values {
+		n += uint64(len(v.value))
+	}
+	return n
+}
@@ -0,0 +1,42 @@
+package tsm1
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBlockCache_Evict(t *testing.T) {
+	now := time.Unix(0, 0).Add(100 * 24 * time.Hour)
+	c := newBlockCache(30)
+	c.now = func() time.Time { return now }
+
+	r := &TSMReader{}
+	recent := now.UnixNano()
+	old := now.Add(-48 * time.Hour).UnixNano()
+	c.put(r, &IndexEntry{Offset: 0, MaxTime: recent}, []FloatValue{}, 10)
+	c.put(r, &IndexEntry{Offset: 1, MaxTime: old}, []FloatValue{}, 10)
+	c.put(r, &IndexEntry{Offset: 2, MaxTime: recent}, []FloatValue{}, 10)
+
+	// The old block is evicted before the least recently used recent block.
+	c.put(r, &IndexEntry{Offset: 3, MaxTime: recent}, []FloatValue{}, 10)
+	if c.get(r, &IndexEntry{Offset: 1}) != nil {
+		t.Fatal("expected old block to be evicted")
+	} else if c.get(r, &IndexEntry{Offset: 0}) == nil {
+		t.Fatal("expected recent block to be cached")
+	}
+
+	// Block 2 is now the least recently used.
+	c.put(r, &IndexEntry{Offset: 4, MaxTime: recent}, []FloatValue{}, 10)
+	if c.get(r, &IndexEntry{Offset: 2}) != nil {
+		t.Fatal("expected least recently used block to be evicted")
+	}
+
+	if hits, misses, size := c.statistics(); hits != 1 || misses != 2 || size != 30 {
+		t.Fatalf("unexpected statistics: hits=%d misses=%d size=%d", hits, misses, size)
+	}
+
+	c.purge(r)
+	if _, _, size := c.statistics(); size != 0 {
+		t.Fatalf("unexpected size after purge: %d", size)
+	}
+}
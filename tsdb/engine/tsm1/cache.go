@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -37,17 +38,58 @@ type entry struct {
 	mu     sync.RWMutex
 	values Values // All stored values.
 
+	// unsorted is set while values may be out of order or hold duplicate
+	// times, and is cleared when they are deduplicated. Protected by mu.
+	unsorted bool
+
 	// The type of values stored. Read only so doesn't need to be protected by
 	// mu.
 	vtype byte
 }
 
+// contains returns true if the entry has a value at time t. The values are
+// deduplicated first if they are not already sorted.
+func (e *entry) contains(t int64) bool {
+	e.mu.RLock()
+	if !e.unsorted {
+		ok := e.values.contains(t)
+		e.mu.RUnlock()
+		return ok
+	}
+	e.mu.RUnlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.unsorted {
+		e.values = e.values.Deduplicate()
+		e.unsorted = false
+	}
+	return e.values.contains(t)
+}
+
+// contains returns true if a has a value at time t. a must be sorted.
+func (a Values) contains(t int64) bool {
+	i := sort.Search(len(a), func(i int) bool { return a[i].UnixNano() >= t })
+	return i < len(a) && a[i].UnixNano() == t
+}
+
+// ordered returns true if the times of values are strictly increasing.
+func ordered(values []Value) bool {
+	for i := 1; i < len(values); i++ {
+		if values[i-1].UnixNano() >= values[i].UnixNano() {
+			return false
+		}
+	}
+	return true
+}
+
 // newEntryValues returns a new instance of entry with the given values.  If the
 // values are not valid, an error is returned.
 func newEntryValues(values []Value) (*entry, error) {
 	e := &entry{}
 	e.values = make(Values, 0, len(values))
 	e.values = append(e.values, values...)
+	e.unsorted = !ordered(values)
 
 	// No values, don't check types and ordering
 	if len(values) == 0 {
@@ -88,11 +130,15 @@ func (e *entry) add(values []Value) error {
 	if len(e.values) == 0 {
 		e.values = values
 		e.vtype = valueType(values[0])
+		e.unsorted = !ordered(values)
 		e.mu.Unlock()
 		return nil
 	}
 
 	// Append the new values to the existing ones...
+	if !e.unsorted {
+		e.unsorted = values[0].UnixNano() <= e.values[len(e.values)-1].UnixNano() || !ordered(values)
+	}
 	e.values = append(e.values, values...)
 	e.mu.Unlock()
 	return nil
@@ -108,6 +154,7 @@ func (e *entry) deduplicate() {
 		return
 	}
 	e.values = e.values.Deduplicate()
+	e.unsorted = false
 }
 
 // count returns the number of values in this entry.
@@ -125,6 +172,7 @@ func (e *entry) filter(min, max int64) {
 		e.values = e.values.Deduplicate()
 	}
 	e.values = e.values.Exclude(min, max)
+	e.unsorted = false
 	e.mu.Unlock()
 }
 
@@ -514,6 +562,24 @@ func (c *Cache) Split(n int) []*Cache {
 	return caches
 }
 
+// Contains returns true if the cache or its snapshot has a value for key at
+// time t.
+func (c *Cache) Contains(key []byte, t int64) bool {
+	c.mu.RLock()
+	entries := []*entry{c.store.entry(key)}
+	if c.snapshot != nil {
+		entries = append(entries, c.snapshot.store.entry(key))
+	}
+	c.mu.RUnlock()
+
+	for _, e := range entries {
+		if e != nil && e.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
 // Values returns a copy of all values, deduped and sorted, for the given key.
 func (c *Cache) Values(key []byte) Values {
 	var snapshotEntries *entry
@@ -649,6 +715,9 @@ func (c *Cache) ApplyEntryFn(f func(key []byte, entry *entry) error) error {
 type CacheLoader struct {
 	files []string
 
+	// SegmentLoaded, if set, is called after each segment file is loaded.
+	SegmentLoaded func()
+
 	Logger *zap.Logger
 }
 
@@ -721,6 +790,10 @@ func (cl *CacheLoader) Load(cache *Cache) error {
 		}(); err != nil {
 			return err
 		}
+
+		if cl.SegmentLoaded != nil {
+			cl.SegmentLoaded()
+		}
 	}
 	return nil
 }
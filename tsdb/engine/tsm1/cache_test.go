@@ -360,6 +360,48 @@ func TestCache_CacheWriteMulti_Duplicates(t *testing.T) {
 	}
 }
 
+// Ensure Contains finds values written out of order and with duplicates.
+func TestCache_Contains(t *testing.T) {
+	c := NewCache(0, "")
+	for _, values := range []Values{
+		{NewValue(1, 1.0), NewValue(5, 5.0)},
+		{NewValue(3, 3.0), NewValue(3, 3.5)},
+		{NewValue(7, 7.0)},
+	} {
+		if err := c.Write([]byte("foo"), values); err != nil {
+			t.Fatalf("failed to write key foo to cache: %s", err.Error())
+		}
+	}
+
+	for _, tt := range []struct {
+		t   int64
+		exp bool
+	}{
+		{t: 0, exp: false},
+		{t: 1, exp: true},
+		{t: 3, exp: true},
+		{t: 4, exp: false},
+		{t: 5, exp: true},
+		{t: 7, exp: true},
+		{t: 8, exp: false},
+	} {
+		if got := c.Contains([]byte("foo"), tt.t); got != tt.exp {
+			t.Fatalf("unexpected contains at %d: got %v, exp %v", tt.t, got, tt.exp)
+		}
+	}
+	if c.Contains([]byte("bar"), 1) {
+		t.Fatal("unexpected contains for bar")
+	}
+
+	// Values written after the lookups are found too.
+	if err := c.Write([]byte("foo"), Values{NewValue(2, 2.0)}); err != nil {
+		t.Fatalf("failed to write key foo to cache: %s", err.Error())
+	}
+	if !c.Contains([]byte("foo"), 2) {
+		t.Fatal("expected contains at 2")
+	}
+}
+
 func TestCache_CacheValues(t *testing.T) {
 	v0 := NewValue(1, 0.0)
 	v1 := NewValue(2, 2.0)
@@ -23,6 +23,7 @@ import (
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/pkg/bytesutil"
 	"github.com/influxdata/influxdb/pkg/estimator"
+	"github.com/influxdata/influxdb/pkg/fault"
 	"github.com/influxdata/influxdb/pkg/limiter"
 	"github.com/influxdata/influxdb/pkg/metrics"
 	intar "github.com/influxdata/influxdb/pkg/tar"
@@ -121,6 +122,9 @@ const (
 	statTSMFullCompactionError    = "tsmFullCompactionErr"
 	statTSMFullCompactionDuration = "tsmFullCompactionDuration"
 	statTSMFullCompactionQueue    = "tsmFullCompactionQueue"
+
+	statWriteDuplicatePoints   = "writeDuplicatePoints"
+	statWriteOverwrittenPoints = "writeOverwrittenPoints"
 )
 
 // Engine represents a storage engine with compressed blocks.
@@ -183,6 +187,22 @@ type Engine struct {
 
 	// provides access to the total set of series IDs
 	seriesIDSets tsdb.SeriesIDSets
+
+	// compactionsPaused returns true while level and full compactions
+	// should not start, such as when the disk is low on space.
+	compactionsPaused func() bool
+
+	// duplicatePoints is the policy for points written with the series and
+	// time of a point already in the shard.
+	duplicatePoints string
+
+	// duplicatesMu serializes writes under the first-write-wins and
+	// merge-fields policies, so a point cannot be written between the check
+	// for its duplicates and the write of the points that passed it.
+	duplicatesMu sync.Mutex
+
+	// walReplayProgress, if set, is told of the WAL segments replayed on open.
+	walReplayProgress tsdb.WALReplayProgress
 }
 
 // NewEngine returns a new instance of Engine.
@@ -225,12 +245,21 @@ func NewEngine(id uint64, idx tsdb.Index, database, path string, walPath string,
 		compactionLimiter: opt.CompactionLimiter,
 		scheduler:         newScheduler(stats, opt.CompactionLimiter.Capacity()),
 		seriesIDSets:      opt.SeriesIDSets,
+		compactionsPaused: opt.LevelCompactionsPaused,
+		duplicatePoints:   opt.Config.DuplicatePoints,
+		walReplayProgress: opt.WALReplayProgress,
 	}
 
 	if e.traceLogging {
 		fs.enableTraceLogging(true)
 		w.enableTraceLogging(true)
 	}
+	if opt.Config.TSMReadAheadSize > 0 {
+		fs.enableReadAhead(int64(opt.Config.TSMReadAheadSize))
+	}
+	if opt.Config.BlockCacheMaxMemorySize > 0 {
+		fs.enableBlockCache(uint64(opt.Config.BlockCacheMaxMemorySize))
+	}
 
 	return e
 }
@@ -568,6 +597,9 @@ type EngineStatistics struct {
 	TSMFullCompactionErrors   int64 // Counter of full compactions that have failed due to error.
 	TSMFullCompactionDuration int64 // Counter of number of wall nanoseconds spent in full compactions.
 	TSMFullCompactionsQueue   int64 // Gauge of full compactions queue.
+
+	WriteDuplicatePoints   int64 // Counter of points dropped in full or in part by the duplicate points policy.
+	WriteOverwrittenPoints int64 // Counter of points replacing values in the cache with the last-write-wins policy.
 }
 
 // Statistics returns statistics for periodic monitoring.
@@ -611,6 +643,9 @@ func (e *Engine) Statistics(tags map[string]string) []models.Statistic {
 			statTSMFullCompactionError:    atomic.LoadInt64(&e.stats.TSMFullCompactionErrors),
 			statTSMFullCompactionDuration: atomic.LoadInt64(&e.stats.TSMFullCompactionDuration),
 			statTSMFullCompactionQueue:    atomic.LoadInt64(&e.stats.TSMFullCompactionsQueue),
+
+			statWriteDuplicatePoints:   atomic.LoadInt64(&e.stats.WriteDuplicatePoints),
+			statWriteOverwrittenPoints: atomic.LoadInt64(&e.stats.WriteOverwrittenPoints),
 		},
 	})
 
@@ -625,6 +660,11 @@ func (e *Engine) DiskSize() int64 {
 	return e.FileStore.DiskSizeBytes() + e.WAL.DiskSizeBytes() + e.index.DiskSizeBytes()
 }
 
+// CacheSize returns the size of the values held in the cache of the engine.
+func (e *Engine) CacheSize() uint64 {
+	return e.Cache.Size()
+}
+
 // Open opens and initializes the engine.
 func (e *Engine) Open() error {
 	if err := os.MkdirAll(e.path, 0777); err != nil {
@@ -1096,8 +1136,28 @@ func (e *Engine) addToIndexFromKey(key []byte, fieldType influxql.DataType) erro
 }
 
 // WritePoints writes metadata and point data into the engine.
-// It returns an error if new points are added to an existing key.
+// It returns an error if new points are added to an existing key. A
+// tsdb.PartialWriteError is returned, after the other points are written, if
+// the duplicate points policy drops points.
 func (e *Engine) WritePoints(points []models.Point) error {
+	// With the last-write-wins policy, the points replacing a value in the
+	// cache or earlier in points are counted. TSM files are not read, which
+	// would slow every write down.
+	countOverwrites := e.duplicatePoints == "" || e.duplicatePoints == tsdb.DuplicatePointsLastWriteWins
+	var overwritten int64
+
+	var dropped int
+	switch e.duplicatePoints {
+	case tsdb.DuplicatePointsFirstWriteWins, tsdb.DuplicatePointsMergeFields:
+		e.duplicatesMu.Lock()
+		defer e.duplicatesMu.Unlock()
+
+		var err error
+		if points, dropped, err = e.dropDuplicates(points); err != nil {
+			return err
+		}
+	}
+
 	values := make(map[string][]Value, len(points))
 	var keyBuf []byte
 	var baseLen int
@@ -1107,6 +1167,7 @@ func (e *Engine) WritePoints(points []models.Point) error {
 		baseLen = len(keyBuf)
 		iter := p.FieldIterator()
 		t := p.Time().UnixNano()
+		overwrite := false
 		for iter.Next() {
 			// Skip fields name "time", they are illegal
 			if bytes.Equal(iter.FieldKey(), timeBytes) {
@@ -1114,6 +1175,11 @@ func (e *Engine) WritePoints(points []models.Point) error {
 			}
 
 			keyBuf = append(keyBuf[:baseLen], iter.FieldKey()...)
+			if countOverwrites && !overwrite {
+				vs := values[string(keyBuf)]
+				overwrite = (len(vs) > 0 && vs[len(vs)-1].UnixNano() == t) || e.Cache.Contains(keyBuf, t)
+			}
+
 			var v Value
 			switch iter.Type() {
 			case models.Float:
@@ -1147,6 +1213,9 @@ func (e *Engine) WritePoints(points []models.Point) error {
 			}
 			values[string(keyBuf)] = append(values[string(keyBuf)], v)
 		}
+		if overwrite {
+			overwritten++
+		}
 	}
 
 	e.mu.RLock()
@@ -1158,8 +1227,91 @@ func (e *Engine) WritePoints(points []models.Point) error {
 		return err
 	}
 
-	_, err = e.WAL.WriteMulti(values)
-	return err
+	if _, err = e.WAL.WriteMulti(values); err != nil {
+		return err
+	}
+	if err := fault.Inject(fault.WALWrite); err != nil {
+		return err
+	}
+	atomic.AddInt64(&e.stats.WriteOverwrittenPoints, overwritten)
+
+	if dropped > 0 {
+		return tsdb.PartialWriteError{Reason: "duplicate points", Dropped: dropped}
+	}
+	return nil
+}
+
+// dropDuplicates returns points without the values whose series, field and
+// time already have a value in the shard or earlier in points. With the
+// first-write-wins policy a point with such a value is dropped, and with the
+// merge-fields policy only the value is. The number of points dropped in full
+// is returned with them.
+func (e *Engine) dropDuplicates(points []models.Point) ([]models.Point, int, error) {
+	type fieldTime struct {
+		key string
+		t   int64
+	}
+	written := make(map[fieldTime]struct{}, len(points))
+
+	var keyBuf []byte
+	var dropped int
+	other := make([]models.Point, 0, len(points))
+	for _, p := range points {
+		keyBuf = append(keyBuf[:0], p.Key()...)
+		keyBuf = append(keyBuf, keyFieldSeparator...)
+		baseLen := len(keyBuf)
+		t := p.Time().UnixNano()
+
+		var duplicates [][]byte
+		fields := 0
+		iter := p.FieldIterator()
+		for iter.Next() {
+			fields++
+			keyBuf = append(keyBuf[:baseLen], iter.FieldKey()...)
+			if _, ok := written[fieldTime{key: string(keyBuf), t: t}]; ok {
+				duplicates = append(duplicates, iter.FieldKey())
+				continue
+			}
+
+			exists := e.Cache.Contains(keyBuf, t)
+			if !exists {
+				var err error
+				if exists, err = e.FileStore.Contains(keyBuf, t); err != nil {
+					return nil, 0, err
+				}
+			}
+			if exists {
+				duplicates = append(duplicates, iter.FieldKey())
+			}
+		}
+
+		if len(duplicates) > 0 {
+			atomic.AddInt64(&e.stats.WriteDuplicatePoints, 1)
+			if e.duplicatePoints == tsdb.DuplicatePointsFirstWriteWins || len(duplicates) == fields {
+				dropped++
+				continue
+			}
+
+			pfields, err := p.Fields()
+			if err != nil {
+				return nil, 0, err
+			}
+			for _, name := range duplicates {
+				delete(pfields, string(name))
+			}
+			if p, err = models.NewPoint(string(p.Name()), p.Tags(), pfields, p.Time()); err != nil {
+				return nil, 0, err
+			}
+		}
+
+		iter = p.FieldIterator()
+		for iter.Next() {
+			keyBuf = append(keyBuf[:baseLen], iter.FieldKey()...)
+			written[fieldTime{key: string(keyBuf), t: t}] = struct{}{}
+		}
+		other = append(other, p)
+	}
+	return other, dropped, nil
 }
 
 // DeleteSeriesRange removes the values between min and max (inclusive) from all series
@@ -1704,6 +1856,11 @@ func (e *Engine) compact() {
 			return
 
 		case <-t.C:
+			// Compactions write new files before removing the ones they
+			// replace, so skip them while disk space is low.
+			if e.compactionsPaused != nil && e.compactionsPaused() {
+				continue
+			}
 
 			// Find our compaction plans
 			level1Groups := e.CompactionPlan.PlanLevel(1)
@@ -2017,6 +2174,10 @@ func (e *Engine) reloadCache() error {
 
 	loader := NewCacheLoader(files)
 	loader.WithLogger(e.logger)
+	if p := e.walReplayProgress; p != nil {
+		p.AddWALSegments(len(files))
+		loader.SegmentLoaded = p.WALSegmentReplayed
+	}
 	if err := loader.Load(e.Cache); err != nil {
 		return err
 	}
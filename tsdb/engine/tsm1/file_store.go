@@ -140,6 +140,10 @@ type TSMFile interface {
 const (
 	statFileStoreBytes = "diskBytes"
 	statFileStoreCount = "numFiles"
+
+	statBlockCacheHits   = "blockCacheHits"
+	statBlockCacheMisses = "blockCacheMisses"
+	statBlockCacheBytes  = "blockCacheBytes"
 )
 
 var (
@@ -172,6 +176,12 @@ type FileStore struct {
 	traceLogger  *zap.Logger // Logger to be used when trace-logging is on.
 	traceLogging bool
 
+	// readAheadSize is the number of bytes read ahead of sequential scans.
+	readAheadSize int64
+
+	// blockCache keeps the decoded blocks of the files, if set.
+	blockCache *blockCache
+
 	stats  *FileStoreStatistics
 	purger *purger
 
@@ -229,6 +239,16 @@ func (f *FileStore) enableTraceLogging(enabled bool) {
 	}
 }
 
+// enableReadAhead must be called before the FileStore is opened.
+func (f *FileStore) enableReadAhead(size int64) {
+	f.readAheadSize = size
+}
+
+// enableBlockCache must be called before the FileStore is opened.
+func (f *FileStore) enableBlockCache(size uint64) {
+	f.blockCache = newBlockCache(size)
+}
+
 // WithLogger sets the logger on the file store.
 func (f *FileStore) WithLogger(log *zap.Logger) {
 	f.logger = log.With(zap.String("service", "filestore"))
@@ -247,13 +267,17 @@ type FileStoreStatistics struct {
 
 // Statistics returns statistics for periodic monitoring.
 func (f *FileStore) Statistics(tags map[string]string) []models.Statistic {
+	values := map[string]interface{}{
+		statFileStoreBytes: atomic.LoadInt64(&f.stats.DiskBytes),
+		statFileStoreCount: atomic.LoadInt64(&f.stats.FileCount),
+	}
+	if f.blockCache != nil {
+		values[statBlockCacheHits], values[statBlockCacheMisses], values[statBlockCacheBytes] = f.blockCache.statistics()
+	}
 	return []models.Statistic{{
-		Name: "tsm1_filestore",
-		Tags: tags,
-		Values: map[string]interface{}{
-			statFileStoreBytes: atomic.LoadInt64(&f.stats.DiskBytes),
-			statFileStoreCount: atomic.LoadInt64(&f.stats.FileCount),
-		},
+		Name:   "tsm1_filestore",
+		Tags:   tags,
+		Values: values,
 	}}
 }
 
@@ -482,7 +506,7 @@ func (f *FileStore) Open() error {
 
 		go func(idx int, file *os.File) {
 			start := time.Now()
-			df, err := NewTSMReader(file)
+			df, err := NewTSMReader(file, withReadAhead(f.readAheadSize), withBlockCache(f.blockCache))
 			f.logger.Info(fmt.Sprintf("%s (#%d) opened in %v", file.Name(), idx, time.Since(start)))
 
 			if err != nil {
@@ -565,6 +589,40 @@ func (f *FileStore) Read(key []byte, t int64) ([]Value, error) {
 	return nil, nil
 }
 
+// Contains returns true if a file has a value for key at time t that was not
+// deleted.
+func (f *FileStore) Contains(key []byte, t int64) (bool, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for _, f := range f.files {
+		if !f.Contains(key) || timeRangesContain(f.TombstoneRange(key), t) {
+			continue
+		}
+
+		values, err := f.Read(key, t)
+		if err != nil {
+			return false, err
+		}
+		for _, v := range values {
+			if v.UnixNano() == t {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// timeRangesContain returns true if t is in one of ranges.
+func timeRangesContain(ranges []TimeRange, t int64) bool {
+	for _, r := range ranges {
+		if r.Overlaps(t, t) {
+			return true
+		}
+	}
+	return false
+}
+
 func (f *FileStore) Cost(key []byte, min, max int64) query.IteratorCost {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
@@ -669,7 +727,7 @@ func (f *FileStore) replace(oldFiles, newFiles []string, updatedFn func(r []TSMF
 			}
 		}
 
-		tsm, err := NewTSMReader(fd)
+		tsm, err := NewTSMReader(fd, withReadAhead(f.readAheadSize), withBlockCache(f.blockCache))
 		if err != nil {
 			return err
 		}
@@ -31,6 +31,10 @@ func madviseDontNeed(b []byte) error {
 	return madvise(b, syscall.MADV_DONTNEED)
 }
 
+func madviseWillNeed(b []byte) error {
+	return madvise(b, syscall.MADV_WILLNEED)
+}
+
 // From: github.com/boltdb/bolt/bolt_unix.go
 func madvise(b []byte, advice int) (err error) {
 	return unix.Madvise(b, advice)
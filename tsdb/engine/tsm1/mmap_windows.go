@@ -126,6 +126,11 @@ func madviseDontNeed(b []byte) error {
 	return nil
 }
 
+func madviseWillNeed(b []byte) error {
+	// Not supported
+	return nil
+}
+
 func madvise(b []byte, advice int) error {
 	// Not implemented
 	return nil
@@ -0,0 +1,86 @@
+package tsm1
+
+import (
+	"os"
+	"sync"
+)
+
+// readAheadMinRun is the number of blocks that must be read in order before
+// a scan is considered sequential.
+const readAheadMinRun = 2
+
+// readAhead detects scans reading the blocks of a TSM file in order and
+// decides which part of the file should be read ahead of them. Blocks for a
+// key are stored one after another, so a long time range scan reads a
+// contiguous region of the file.
+type readAhead struct {
+	size int64
+
+	mu    sync.Mutex
+	next  int64 // offset the next block of a sequential scan starts at
+	run   int   // number of blocks read in order
+	until int64 // end of the region that has already been read ahead
+}
+
+// newReadAhead returns a readAhead that reads size bytes ahead, or nil if
+// size is not positive.
+func newReadAhead(size int64) *readAhead {
+	if size <= 0 {
+		return nil
+	}
+	return &readAhead{size: size}
+}
+
+// access records a read of n bytes at offset. It returns the region to read
+// ahead, if any. More is read once the scan has used up half of the region
+// read ahead before it.
+func (r *readAhead) access(offset, n int64) (start, end int64, ok bool) {
+	if r == nil {
+		return 0, 0, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if offset == r.next {
+		r.run++
+	} else {
+		r.run, r.until = 0, 0
+	}
+	r.next = offset + n
+
+	if r.run < readAheadMinRun || r.next+r.size/2 < r.until {
+		return 0, 0, false
+	}
+
+	start, end = r.next, r.next+r.size
+	if start < r.until {
+		start = r.until
+	}
+	r.until = end
+	return start, end, true
+}
+
+// readAheadOf reads ahead of the block at entry if the blocks of the file
+// are being read in order. The caller must hold a read lock on m.mu.
+func (m *mmapAccessor) readAheadOf(entry *IndexEntry) {
+	if start, end, ok := m.readAhead.access(entry.Offset, int64(entry.Size)); ok {
+		go m.willNeed(start, end)
+	}
+}
+
+// willNeed asks the operating system to load a region of the file into the
+// page cache in the background.
+func (m *mmapAccessor) willNeed(start, end int64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if end > int64(len(m.b)) {
+		end = int64(len(m.b))
+	}
+	start -= start % int64(os.Getpagesize())
+	if start >= end {
+		return
+	}
+	madviseWillNeed(m.b[start:end])
+}
@@ -0,0 +1,44 @@
+package tsm1
+
+import "testing"
+
+func TestReadAhead_Access(t *testing.T) {
+	r := newReadAhead(100)
+
+	type result struct {
+		start, end int64
+		ok         bool
+	}
+	for i, tt := range []struct {
+		offset, n int64
+		exp       result
+	}{
+		// Reads out of order never read ahead.
+		{offset: 500, n: 10},
+		{offset: 0, n: 10},
+		// The scan becomes sequential after two more blocks in order.
+		{offset: 10, n: 10},
+		{offset: 20, n: 10, exp: result{30, 130, true}},
+		// Nothing more is read until half the region is used up.
+		{offset: 30, n: 40},
+		{offset: 70, n: 10, exp: result{130, 180, true}},
+		// A jump starts over.
+		{offset: 1000, n: 10},
+		{offset: 1010, n: 10},
+		{offset: 1020, n: 10, exp: result{1030, 1130, true}},
+	} {
+		start, end, ok := r.access(tt.offset, tt.n)
+		if got := (result{start, end, ok}); got != tt.exp {
+			t.Fatalf("%d. unexpected read-ahead: got %v, exp %v", i, got, tt.exp)
+		}
+	}
+}
+
+func TestReadAhead_Disabled(t *testing.T) {
+	r := newReadAhead(0)
+	for i := int64(0); i < 10; i++ {
+		if _, _, ok := r.access(i*10, 10); ok {
+			t.Fatal("unexpected read-ahead")
+		}
+	}
+}
@@ -46,6 +46,12 @@ type TSMReader struct {
 
 	// deleteMu limits concurrent deletes
 	deleteMu sync.Mutex
+
+	// readAheadSize is the number of bytes read ahead of sequential scans.
+	readAheadSize int64
+
+	// blockCache keeps the decoded blocks of the reader, if set.
+	blockCache *blockCache
 }
 
 // TSMIndex represent the index section of a TSM file.  The index records all
@@ -223,9 +229,29 @@ type blockAccessor interface {
 	free() error
 }
 
+// tsmReaderOption is a functional option for NewTSMReader.
+type tsmReaderOption func(*TSMReader)
+
+// withReadAhead sets the number of bytes read ahead of sequential scans.
+func withReadAhead(size int64) tsmReaderOption {
+	return func(r *TSMReader) {
+		r.readAheadSize = size
+	}
+}
+
+// withBlockCache sets the cache of the decoded blocks.
+func withBlockCache(c *blockCache) tsmReaderOption {
+	return func(r *TSMReader) {
+		r.blockCache = c
+	}
+}
+
 // NewTSMReader returns a new TSMReader from the given file.
-func NewTSMReader(f *os.File) (*TSMReader, error) {
+func NewTSMReader(f *os.File, options ...tsmReaderOption) (*TSMReader, error) {
 	t := &TSMReader{}
+	for _, option := range options {
+		option(t)
+	}
 
 	stat, err := f.Stat()
 	if err != nil {
@@ -234,7 +260,8 @@ func NewTSMReader(f *os.File) (*TSMReader, error) {
 	t.size = stat.Size()
 	t.lastModified = stat.ModTime().UnixNano()
 	t.accessor = &mmapAccessor{
-		f: f,
+		f:         f,
+		readAhead: newReadAhead(t.readAheadSize),
 	}
 
 	index, err := t.accessor.init()
@@ -330,41 +357,96 @@ func (t *TSMReader) ReadAt(entry *IndexEntry, vals []Value) ([]Value, error) {
 
 // ReadFloatBlockAt returns the float values corresponding to the given index entry.
 func (t *TSMReader) ReadFloatBlockAt(entry *IndexEntry, vals *[]FloatValue) ([]FloatValue, error) {
+	if t.blockCache != nil {
+		if v, ok := t.blockCache.get(t, entry).([]FloatValue); ok {
+			*vals = append((*vals)[:0], v...)
+			return *vals, nil
+		}
+	}
+
 	t.mu.RLock()
 	v, err := t.accessor.readFloatBlock(entry, vals)
 	t.mu.RUnlock()
+
+	if err == nil && t.blockCache != nil {
+		t.blockCache.put(t, entry, append([]FloatValue(nil), v...), uint64(len(v))*16)
+	}
 	return v, err
 }
 
 // ReadIntegerBlockAt returns the integer values corresponding to the given index entry.
 func (t *TSMReader) ReadIntegerBlockAt(entry *IndexEntry, vals *[]IntegerValue) ([]IntegerValue, error) {
+	if t.blockCache != nil {
+		if v, ok := t.blockCache.get(t, entry).([]IntegerValue); ok {
+			*vals = append((*vals)[:0], v...)
+			return *vals, nil
+		}
+	}
+
 	t.mu.RLock()
 	v, err := t.accessor.readIntegerBlock(entry, vals)
 	t.mu.RUnlock()
+
+	if err == nil && t.blockCache != nil {
+		t.blockCache.put(t, entry, append([]IntegerValue(nil), v...), uint64(len(v))*16)
+	}
 	return v, err
 }
 
 // ReadUnsignedBlockAt returns the unsigned integer values corresponding to the given index entry.
 func (t *TSMReader) ReadUnsignedBlockAt(entry *IndexEntry, vals *[]UnsignedValue) ([]UnsignedValue, error) {
+	if t.blockCache != nil {
+		if v, ok := t.blockCache.get(t, entry).([]UnsignedValue); ok {
+			*vals = append((*vals)[:0], v...)
+			return *vals, nil
+		}
+	}
+
 	t.mu.RLock()
 	v, err := t.accessor.readUnsignedBlock(entry, vals)
 	t.mu.RUnlock()
+
+	if err == nil && t.blockCache != nil {
+		t.blockCache.put(t, entry, append([]UnsignedValue(nil), v...), uint64(len(v))*16)
+	}
 	return v, err
 }
 
 // ReadStringBlockAt returns the string values corresponding to the given index entry.
 func (t *TSMReader) ReadStringBlockAt(entry *IndexEntry, vals *[]StringValue) ([]StringValue, error) {
+	if t.blockCache != nil {
+		if v, ok := t.blockCache.get(t, entry).([]StringValue); ok {
+			*vals = append((*vals)[:0], v...)
+			return *vals, nil
+		}
+	}
+
 	t.mu.RLock()
 	v, err := t.accessor.readStringBlock(entry, vals)
 	t.mu.RUnlock()
+
+	if err == nil && t.blockCache != nil {
+		t.blockCache.put(t, entry, append([]StringValue(nil), v...), stringValuesSize(v))
+	}
 	return v, err
 }
 
 // ReadBooleanBlockAt returns the boolean values corresponding to the given index entry.
 func (t *TSMReader) ReadBooleanBlockAt(entry *IndexEntry, vals *[]BooleanValue) ([]BooleanValue, error) {
+	if t.blockCache != nil {
+		if v, ok := t.blockCache.get(t, entry).([]BooleanValue); ok {
+			*vals = append((*vals)[:0], v...)
+			return *vals, nil
+		}
+	}
+
 	t.mu.RLock()
 	v, err := t.accessor.readBooleanBlock(entry, vals)
 	t.mu.RUnlock()
+
+	if err == nil && t.blockCache != nil {
+		t.blockCache.put(t, entry, append([]BooleanValue(nil), v...), uint64(len(v))*16)
+	}
 	return v, err
 }
 
@@ -408,6 +490,9 @@ func (t *TSMReader) Close() error {
 	if err := t.accessor.close(); err != nil {
 		return err
 	}
+	if t.blockCache != nil {
+		t.blockCache.purge(t)
+	}
 
 	return t.index.Close()
 }
@@ -1339,6 +1424,9 @@ type mmapAccessor struct {
 	f     *os.File
 	b     []byte
 	index *indirectIndex
+
+	// readAhead is nil when read-ahead is disabled.
+	readAhead *readAhead
 }
 
 func (m *mmapAccessor) init() (*indirectIndex, error) {
@@ -1472,6 +1560,7 @@ func (m *mmapAccessor) readBlock(entry *IndexEntry, values []Value) ([]Value, er
 	if int64(len(m.b)) < entry.Offset+int64(entry.Size) {
 		return nil, ErrTSMClosed
 	}
+	m.readAheadOf(entry)
 	//TODO: Validate checksum
 	var err error
 	values, err = DecodeBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
@@ -1490,6 +1579,7 @@ func (m *mmapAccessor) readFloatBlock(entry *IndexEntry, values *[]FloatValue) (
 		m.mu.RUnlock()
 		return nil, ErrTSMClosed
 	}
+	m.readAheadOf(entry)
 
 	a, err := DecodeFloatBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
 	m.mu.RUnlock()
@@ -1509,6 +1599,7 @@ func (m *mmapAccessor) readIntegerBlock(entry *IndexEntry, values *[]IntegerValu
 		m.mu.RUnlock()
 		return nil, ErrTSMClosed
 	}
+	m.readAheadOf(entry)
 
 	a, err := DecodeIntegerBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
 	m.mu.RUnlock()
@@ -1528,6 +1619,7 @@ func (m *mmapAccessor) readUnsignedBlock(entry *IndexEntry, values *[]UnsignedVa
 		m.mu.RUnlock()
 		return nil, ErrTSMClosed
 	}
+	m.readAheadOf(entry)
 
 	a, err := DecodeUnsignedBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
 	m.mu.RUnlock()
@@ -1547,6 +1639,7 @@ func (m *mmapAccessor) readStringBlock(entry *IndexEntry, values *[]StringValue)
 		m.mu.RUnlock()
 		return nil, ErrTSMClosed
 	}
+	m.readAheadOf(entry)
 
 	a, err := DecodeStringBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
 	m.mu.RUnlock()
@@ -1566,6 +1659,7 @@ func (m *mmapAccessor) readBooleanBlock(entry *IndexEntry, values *[]BooleanValu
 		m.mu.RUnlock()
 		return nil, ErrTSMClosed
 	}
+	m.readAheadOf(entry)
 
 	a, err := DecodeBooleanBlock(m.b[entry.Offset+4:entry.Offset+int64(entry.Size)], values)
 	m.mu.RUnlock()
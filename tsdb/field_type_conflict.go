@@ -0,0 +1,166 @@
+package tsdb
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxql"
+)
+
+// coerceField returns the value of the current field of iter converted to
+// typ, or false if the conversion would lose information.
+func coerceField(iter models.FieldIterator, typ influxql.DataType) (interface{}, bool) {
+	switch iter.Type() {
+	case models.Float:
+		v, err := iter.FloatValue()
+		if err != nil {
+			return nil, false
+		}
+		switch typ {
+		case influxql.Integer:
+			if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+				return int64(v), true
+			}
+		case influxql.Unsigned:
+			if v == math.Trunc(v) && v >= 0 && v < math.MaxUint64 {
+				return uint64(v), true
+			}
+		case influxql.String:
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		}
+	case models.Integer:
+		v, err := iter.IntegerValue()
+		if err != nil {
+			return nil, false
+		}
+		switch typ {
+		case influxql.Float:
+			return float64(v), true
+		case influxql.Unsigned:
+			if v >= 0 {
+				return uint64(v), true
+			}
+		case influxql.String:
+			return strconv.FormatInt(v, 10), true
+		}
+	case models.Unsigned:
+		v, err := iter.UnsignedValue()
+		if err != nil {
+			return nil, false
+		}
+		switch typ {
+		case influxql.Float:
+			return float64(v), true
+		case influxql.Integer:
+			if v <= math.MaxInt64 {
+				return int64(v), true
+			}
+		case influxql.String:
+			return strconv.FormatUint(v, 10), true
+		}
+	case models.Boolean:
+		v, err := iter.BooleanValue()
+		if err != nil {
+			return nil, false
+		}
+		if typ == influxql.String {
+			return strconv.FormatBool(v), true
+		}
+	}
+	return nil, false
+}
+
+// coercePoint returns p with the values of the fields in coerced replaced.
+func coercePoint(p models.Point, coerced map[string]interface{}) (models.Point, error) {
+	fields, err := p.Fields()
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range coerced {
+		fields[k] = v
+	}
+	return models.NewPoint(string(p.Name()), p.Tags(), fields, p.Time())
+}
+
+// pointCheck is the decision checkPoint makes for a point.
+type pointCheck struct {
+	point    models.Point // The point to write, with any coerced fields.
+	fields   []*Field     // Fields of the point not yet in its measurement.
+	coerced  bool         // Fields of the point were coerced.
+	reason   string       // Why the point is dropped, empty if it is not.
+	conflict bool         // The point is dropped for a field type conflict.
+	invalid  bool         // The point is dropped for having no valid field.
+}
+
+// checkPoint decides how p is written given mf, the fields of its
+// measurement, and the field-type-conflict policy. Writes and ValidatePoints
+// both decide through it, so a validation returns what a write would.
+func checkPoint(p models.Point, mf *MeasurementFields, policy string) pointCheck {
+	c := pointCheck{point: p}
+	name := p.Name()
+
+	var validField bool
+	var coerced map[string]interface{}
+	iter := p.FieldIterator()
+	for iter.Next() {
+		// Skip fields name "time", they are illegal
+		if bytes.Equal(iter.FieldKey(), timeBytes) {
+			continue
+		}
+		validField = true
+
+		var fieldType influxql.DataType
+		switch iter.Type() {
+		case models.Float:
+			fieldType = influxql.Float
+		case models.Integer:
+			fieldType = influxql.Integer
+		case models.Unsigned:
+			fieldType = influxql.Unsigned
+		case models.Boolean:
+			fieldType = influxql.Boolean
+		case models.String:
+			fieldType = influxql.String
+		default:
+			continue
+		}
+
+		f := mf.FieldBytes(iter.FieldKey())
+		if f == nil {
+			c.fields = append(c.fields, &Field{Name: string(iter.FieldKey()), Type: fieldType})
+			continue
+		} else if f.Type == fieldType {
+			continue // Field is present, and it's of the same type. Nothing more to do.
+		}
+
+		if policy == FieldTypeConflictCoerce {
+			if v, ok := coerceField(iter, f.Type); ok {
+				if coerced == nil {
+					coerced = make(map[string]interface{})
+				}
+				coerced[string(iter.FieldKey())] = v
+				continue
+			}
+		}
+		return pointCheck{
+			reason:   fmt.Sprintf("%s: input field \"%s\" on measurement \"%s\" is type %s, already exists as type %s", ErrFieldTypeConflict, iter.FieldKey(), name, fieldType, f.Type),
+			conflict: true,
+		}
+	}
+
+	if !validField {
+		return pointCheck{reason: fmt.Sprintf("invalid field name: input field \"%s\" on measurement \"%s\" is invalid", "time", name), invalid: true}
+	}
+
+	if coerced != nil {
+		pt, err := coercePoint(p, coerced)
+		if err != nil {
+			return pointCheck{reason: fmt.Sprintf("%s: unable to coerce fields on measurement \"%s\": %s", ErrFieldTypeConflict, name, err)}
+		}
+		c.point, c.coerced = pt, true
+	}
+	return c
+}
@@ -1765,8 +1765,22 @@ func (is IndexSet) seriesByExprIterator(name []byte, expr influxql.Expr, mf *Mea
 	case *influxql.ParenExpr:
 		return is.seriesByExprIterator(name, expr.Expr, mf)
 
-	default:
+	case *influxql.BooleanLiteral:
+		// A condition that is always true matches every series. This is what
+		// is left of time conditions once they have been removed.
+		if expr.Val {
+			return is.measurementSeriesIDIterator(name)
+		}
 		return nil, nil
+
+	default:
+		// Anything else, such as a boolean field on its own, can only be
+		// decided by looking at the points of each series.
+		itr, err := is.measurementSeriesIDIterator(name)
+		if err != nil {
+			return nil, err
+		}
+		return newSeriesIDExprIterator(itr, expr), nil
 	}
 }
 
@@ -1794,7 +1808,16 @@ func (is IndexSet) seriesByBinaryExprIterator(name []byte, n *influxql.BinaryExp
 	if !ok {
 		key, ok = n.RHS.(*influxql.VarRef)
 		if !ok {
-			return nil, fmt.Errorf("invalid expression: %s", n.String())
+			// Without a tag to look up, the comparison is either constant or
+			// has to be evaluated against the points of each series.
+			if b, ok := influxql.Reduce(n, nil).(*influxql.BooleanLiteral); ok {
+				return is.seriesByExprIterator(name, b, mf)
+			}
+			itr, err := is.measurementSeriesIDIterator(name)
+			if err != nil {
+				return nil, err
+			}
+			return newSeriesIDExprIterator(itr, n), nil
 		}
 		value = n.LHS
 	}
@@ -1808,7 +1831,7 @@ func (is IndexSet) seriesByBinaryExprIterator(name []byte, n *influxql.BinaryExp
 		return newSeriesIDExprIterator(itr, n), nil
 	} else if value, ok := value.(*influxql.VarRef); ok {
 		// Check if the RHS is a variable and if it is a field.
-		if value.Val != "_name" && ((value.Type == influxql.Unknown && mf.HasField(value.Val)) || key.Type == influxql.AnyField || (value.Type != influxql.Tag && value.Type != influxql.Unknown)) {
+		if value.Val != "_name" && ((value.Type == influxql.Unknown && mf.HasField(value.Val)) || value.Type == influxql.AnyField || (value.Type != influxql.Tag && value.Type != influxql.Unknown)) {
 			itr, err := is.measurementSeriesIDIterator(name)
 			if err != nil {
 				return nil, err
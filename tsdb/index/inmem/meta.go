@@ -634,7 +634,15 @@ func (m *measurement) idsForExpr(n *influxql.BinaryExpr) (seriesIDs, influxql.Ex
 	if !ok {
 		name, ok = n.RHS.(*influxql.VarRef)
 		if !ok {
-			return nil, nil, fmt.Errorf("invalid expression: %s", n.String())
+			// Without a tag to look up, the comparison is either constant or
+			// has to be evaluated against the points of each series.
+			if b, ok := influxql.Reduce(n, nil).(*influxql.BooleanLiteral); ok {
+				if b.Val {
+					return m.SeriesIDs(), nil, nil
+				}
+				return nil, nil, nil
+			}
+			return m.SeriesIDs(), n, nil
 		}
 		value = n.LHS
 	}
@@ -645,7 +653,7 @@ func (m *measurement) idsForExpr(n *influxql.BinaryExpr) (seriesIDs, influxql.Ex
 		return m.SeriesIDs(), n, nil
 	} else if value, ok := value.(*influxql.VarRef); ok {
 		// Check if the RHS is a variable and if it is a field.
-		if value.Val != "_name" && ((value.Type == influxql.Unknown && m.HasField(value.Val)) || name.Type == influxql.AnyField || (value.Type != influxql.Tag && value.Type != influxql.Unknown)) {
+		if value.Val != "_name" && ((value.Type == influxql.Unknown && m.HasField(value.Val)) || value.Type == influxql.AnyField || (value.Type != influxql.Tag && value.Type != influxql.Unknown)) {
 			return m.SeriesIDs(), n, nil
 		}
 	}
@@ -859,8 +867,22 @@ func (m *measurement) WalkWhereForSeriesIds(expr influxql.Expr) (seriesIDs, Filt
 	case *influxql.ParenExpr:
 		// walk down the tree
 		return m.WalkWhereForSeriesIds(n.Expr)
-	default:
+	case *influxql.BooleanLiteral:
+		// A condition that is always true matches every series. This is what
+		// is left of time conditions once they have been removed.
+		if n.Val {
+			return m.SeriesIDs(), nil, nil
+		}
 		return nil, nil, nil
+	default:
+		// Anything else, such as a boolean field on its own, can only be
+		// decided by looking at the points of each series.
+		ids := m.SeriesIDs()
+		filters := make(FilterExprs, len(ids))
+		for _, id := range ids {
+			filters[id] = n
+		}
+		return ids, filters, nil
 	}
 }
 
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/influxdata/influxdb/internal"
@@ -131,6 +132,62 @@ func TestIndexSet_MeasurementNamesByExpr(t *testing.T) {
 	}
 }
 
+// Ensure tag and field conditions can be combined freely.
+func TestIndexSet_MeasurementSeriesByExprIterator(t *testing.T) {
+	for _, name := range tsdb.RegisteredIndexes() {
+		t.Run(name, func(t *testing.T) {
+			idx := MustNewIndex(name)
+			defer idx.Close()
+			idx.AddSeries("cpu", map[string]string{"region": "east"})
+			idx.AddSeries("cpu", map[string]string{"region": "west"})
+
+			for _, tt := range []struct {
+				expr     string
+				expected []string // series key and filter
+			}{
+				{expr: `region = 'east' OR value::float > 5`, expected: []string{"cpu,region=east ", "cpu,region=west value::float > 5"}},
+				{expr: `region = 'east' AND value::float > 5`, expected: []string{"cpu,region=east value::float > 5"}},
+				{expr: `(region = 'east' AND value::float > 5) OR (region = 'west' AND value::float < 0)`, expected: []string{"cpu,region=east value::float > 5", "cpu,region=west value::float < 0"}},
+				{expr: `region = 'east' AND true`, expected: []string{"cpu,region=east "}},
+				{expr: `region = 'east' OR false`, expected: []string{"cpu,region=east "}},
+				{expr: `1 = 1`, expected: []string{"cpu,region=east ", "cpu,region=west "}},
+				{expr: `region = 'east' AND 1 = 2`},
+				{expr: `region = 'west' OR active::boolean`, expected: []string{"cpu,region=east active::boolean", "cpu,region=west "}},
+			} {
+				itr, err := idx.IndexSet().MeasurementSeriesByExprIterator([]byte("cpu"), influxql.MustParseExpr(tt.expr))
+				if err != nil {
+					t.Fatalf("%s: %s", tt.expr, err)
+				}
+
+				var got []string
+				for itr != nil {
+					e, err := itr.Next()
+					if err != nil {
+						t.Fatal(err)
+					} else if e.SeriesID == 0 {
+						break
+					}
+
+					name, tags := tsdb.ParseSeriesKey(idx.sfile.SeriesKey(e.SeriesID))
+					var filter string
+					if e.Expr != nil {
+						filter = e.Expr.String()
+					}
+					got = append(got, string(models.MakeKey(name, tags))+" "+filter)
+				}
+				if itr != nil {
+					itr.Close()
+				}
+
+				sort.Strings(got)
+				if !reflect.DeepEqual(got, tt.expected) {
+					t.Errorf("%s: got %q, expected %q", tt.expr, got, tt.expected)
+				}
+			}
+		})
+	}
+}
+
 type Index struct {
 	tsdb.Index
 	rootPath string
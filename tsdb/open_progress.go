@@ -0,0 +1,58 @@
+package tsdb
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// openProgressLogInterval is the time between two logs of the progress of
+// opening the shards.
+const openProgressLogInterval = 10 * time.Second
+
+// openProgress counts the shards opened and the WAL segments replayed while
+// a store opens, which takes minutes with many shards or a large WAL.
+type openProgress struct {
+	// The counters come first to be 64-bit aligned for the atomic package.
+	shardsTotal         int64
+	shardsOpened        int64
+	walSegmentsTotal    int64
+	walSegmentsReplayed int64
+
+	start time.Time
+}
+
+func newOpenProgress() *openProgress {
+	return &openProgress{start: time.Now()}
+}
+
+// AddWALSegments adds n segments to the number to replay.
+func (p *openProgress) AddWALSegments(n int) {
+	atomic.AddInt64(&p.walSegmentsTotal, int64(n))
+}
+
+// WALSegmentReplayed counts a segment replayed.
+func (p *openProgress) WALSegmentReplayed() {
+	atomic.AddInt64(&p.walSegmentsReplayed, 1)
+}
+
+// String returns the progress in a form for logs and health checks, such as
+// "opened 12 of 40 shards (30%), replayed 3 of 10 WAL segments, 1m10s left".
+// The time left is estimated from the rate shards are opened at.
+func (p *openProgress) String() string {
+	opened, total := atomic.LoadInt64(&p.shardsOpened), atomic.LoadInt64(&p.shardsTotal)
+	replayed, segments := atomic.LoadInt64(&p.walSegmentsReplayed), atomic.LoadInt64(&p.walSegmentsTotal)
+
+	var percent int64
+	if total > 0 {
+		percent = opened * 100 / total
+	}
+	s := fmt.Sprintf("opened %d of %d shards (%d%%), replayed %d of %d WAL segments", opened, total, percent, replayed, segments)
+
+	if opened > 0 && opened < total {
+		elapsed := time.Since(p.start)
+		left := time.Duration(int64(elapsed) / opened * (total - opened))
+		s += fmt.Sprintf(", %s left", left-left%time.Second)
+	}
+	return s
+}
@@ -224,6 +224,16 @@ func (f *SeriesFile) SeriesCount() uint64 {
 	return n
 }
 
+// SeriesCreated returns the number of series created since the file was
+// opened.
+func (f *SeriesFile) SeriesCreated() uint64 {
+	var n uint64
+	for _, p := range f.partitions {
+		n += p.SeriesCreated()
+	}
+	return n
+}
+
 // SeriesIterator returns an iterator over all the series.
 func (f *SeriesFile) SeriesIDIterator() SeriesIDIterator {
 	var ids []uint64
@@ -34,6 +34,7 @@ type SeriesPartition struct {
 	segments []*SeriesSegment
 	index    *SeriesIndex
 	seq      uint64 // series id sequence
+	created  uint64 // series created since the partition was opened
 
 	compacting          bool
 	compactionsDisabled int
@@ -248,6 +249,7 @@ func (p *SeriesPartition) CreateSeriesListIfNotExists(keys [][]byte, keyPartitio
 	for _, keyRange := range newKeyRanges {
 		p.index.Insert(p.seriesKeyByOffset(keyRange.offset), keyRange.id, keyRange.offset)
 	}
+	p.created += uint64(len(newKeyRanges))
 
 	// Check if we've crossed the compaction threshold.
 	if p.compactionsEnabled() && !p.compacting && p.CompactThreshold != 0 && p.index.InMemCount() >= uint64(p.CompactThreshold) {
@@ -363,6 +365,15 @@ func (p *SeriesPartition) SeriesCount() uint64 {
 	return n
 }
 
+// SeriesCreated returns the number of series created since the partition
+// was opened.
+func (p *SeriesPartition) SeriesCreated() uint64 {
+	p.mu.RLock()
+	n := p.created
+	p.mu.RUnlock()
+	return n
+}
+
 func (p *SeriesPartition) DisableCompactions() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -21,6 +21,8 @@ import (
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/pkg/bytesutil"
 	"github.com/influxdata/influxdb/pkg/estimator"
+	"github.com/influxdata/influxdb/pkg/estimator/hll"
+	"github.com/influxdata/influxdb/pkg/fault"
 	"github.com/influxdata/influxdb/pkg/file"
 	"github.com/influxdata/influxdb/pkg/limiter"
 	"github.com/influxdata/influxdb/query"
@@ -37,8 +39,10 @@ const (
 	statFieldsCreate       = "fieldsCreate"
 	statWritePointsErr     = "writePointsErr"
 	statWritePointsDropped = "writePointsDropped"
+	statWritePointsCoerced = "writePointsCoerced"
 	statWritePointsOK      = "writePointsOk"
 	statWriteBytes         = "writeBytes"
+	statWritePointsBytes   = "writePointsBytes"
 	statDiskBytes          = "diskBytes"
 )
 
@@ -120,6 +124,10 @@ func (e PartialWriteError) Error() string {
 // Data can be split across many shards. The query engine in TSDB is responsible
 // for combining the output of many shards into a single query result.
 type Shard struct {
+	// lastAccess is the time, in nanoseconds, the shard was last written to
+	// or queried. It comes first to be 64-bit aligned for the atomic package.
+	lastAccess int64
+
 	path    string
 	walPath string
 	id      uint64
@@ -137,6 +145,16 @@ type Shard struct {
 	closing chan struct{}
 	enabled bool
 
+	// unloaded is set while the shard is closed for being cold. It is
+	// reopened on its next access. The series IDs, measurement sketches and
+	// last modification time of an unloaded shard are kept, so cardinality
+	// statistics and backups don't reopen it.
+	unloaded             bool
+	unloadedSeriesIDs    *SeriesIDSet
+	unloadedSketches     estimator.Sketch
+	unloadedTSSketches   estimator.Sketch
+	unloadedLastModified time.Time
+
 	// expvar-based stats.
 	stats       *ShardStatistics
 	defaultTags models.StatisticTags
@@ -183,7 +201,7 @@ func NewShard(id uint64, path string, walPath string, sfile *SeriesFile, opt Eng
 // WithLogger sets the logger on the shard. It must be called before Open.
 func (s *Shard) WithLogger(log *zap.Logger) {
 	s.baseLogger = log
-	engine, err := s.engine()
+	engine, err := s.loadedEngine()
 	if err == nil {
 		engine.WithLogger(s.baseLogger)
 		s.index.WithLogger(s.baseLogger)
@@ -236,14 +254,18 @@ type ShardStatistics struct {
 	FieldsCreated      int64
 	WritePointsErr     int64
 	WritePointsDropped int64
+	WritePointsCoerced int64
 	WritePointsOK      int64
 	BytesWritten       int64
 	DiskBytes          int64
+
+	// PointBytesWritten is the size in line protocol of the points written.
+	PointBytesWritten int64
 }
 
 // Statistics returns statistics for periodic monitoring.
 func (s *Shard) Statistics(tags map[string]string) []models.Statistic {
-	engine, err := s.engine()
+	engine, err := s.loadedEngine()
 	if err != nil {
 		return nil
 	}
@@ -266,9 +288,11 @@ func (s *Shard) Statistics(tags map[string]string) []models.Statistic {
 			statFieldsCreate:       atomic.LoadInt64(&s.stats.FieldsCreated),
 			statWritePointsErr:     atomic.LoadInt64(&s.stats.WritePointsErr),
 			statWritePointsDropped: atomic.LoadInt64(&s.stats.WritePointsDropped),
+			statWritePointsCoerced: atomic.LoadInt64(&s.stats.WritePointsCoerced),
 			statWritePointsOK:      atomic.LoadInt64(&s.stats.WritePointsOK),
 			statWriteBytes:         atomic.LoadInt64(&s.stats.BytesWritten),
 			statDiskBytes:          atomic.LoadInt64(&s.stats.DiskBytes),
+			statWritePointsBytes:   atomic.LoadInt64(&s.stats.PointBytesWritten),
 		},
 	}}
 
@@ -285,61 +309,67 @@ func (s *Shard) Open() error {
 	if err := func() error {
 		s.mu.Lock()
 		defer s.mu.Unlock()
+		return s.open()
+	}(); err != nil {
+		s.close()
+		return NewShardError(s.id, err)
+	}
 
-		// Return if the shard is already open
-		if s._engine != nil {
-			return nil
-		}
+	if s.EnableOnOpen {
+		// enable writes, queries and compactions
+		s.SetEnabled(true)
+	}
 
-		seriesIDSet := NewSeriesIDSet()
+	return nil
+}
 
-		// Initialize underlying index.
-		ipath := filepath.Join(s.path, "index")
-		idx, err := NewIndex(s.id, s.database, ipath, seriesIDSet, s.sfile, s.options)
-		if err != nil {
-			return err
-		}
+// open opens the index and the engine of the shard, with compactions
+// disabled. This method assumes s.mu is locked.
+func (s *Shard) open() error {
+	// Return if the shard is already open
+	if s._engine != nil {
+		return nil
+	}
 
-		// Open index.
-		if err := idx.Open(); err != nil {
-			return err
-		}
-		s.index = idx
-		idx.WithLogger(s.baseLogger)
+	seriesIDSet := NewSeriesIDSet()
 
-		// Initialize underlying engine.
-		e, err := NewEngine(s.id, idx, s.database, s.path, s.walPath, s.sfile, s.options)
-		if err != nil {
-			return err
-		}
+	// Initialize underlying index.
+	ipath := filepath.Join(s.path, "index")
+	idx, err := NewIndex(s.id, s.database, ipath, seriesIDSet, s.sfile, s.options)
+	if err != nil {
+		return err
+	}
 
-		// Set log output on the engine.
-		e.WithLogger(s.baseLogger)
+	// Open index.
+	if err := idx.Open(); err != nil {
+		return err
+	}
+	s.index = idx
+	idx.WithLogger(s.baseLogger)
 
-		// Disable compactions while loading the index
-		e.SetEnabled(false)
+	// Initialize underlying engine.
+	e, err := NewEngine(s.id, idx, s.database, s.path, s.walPath, s.sfile, s.options)
+	if err != nil {
+		return err
+	}
 
-		// Open engine.
-		if err := e.Open(); err != nil {
-			return err
-		}
+	// Set log output on the engine.
+	e.WithLogger(s.baseLogger)
 
-		// Load metadata index for the inmem index only.
-		if err := e.LoadMetadataIndex(s.id, s.index); err != nil {
-			return err
-		}
-		s._engine = e
+	// Disable compactions while loading the index
+	e.SetEnabled(false)
 
-		return nil
-	}(); err != nil {
-		s.close()
-		return NewShardError(s.id, err)
+	// Open engine.
+	if err := e.Open(); err != nil {
+		return err
 	}
 
-	if s.EnableOnOpen {
-		// enable writes, queries and compactions
-		s.SetEnabled(true)
+	// Load metadata index for the inmem index only.
+	if err := e.LoadMetadataIndex(s.id, s.index); err != nil {
+		return err
 	}
+	s._engine = e
+	atomic.StoreInt64(&s.lastAccess, time.Now().UnixNano())
 
 	return nil
 }
@@ -376,6 +406,109 @@ func (s *Shard) close() error {
 	return err
 }
 
+// Unload closes the shard, releasing its files and index, if it has not been
+// written to or queried within idle and is fully compacted. The shard stays
+// in the store and is reopened on its next access. It returns true if the
+// shard was unloaded.
+func (s *Shard) Unload(idle time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// reload records an access before it takes the lock, so reading the
+	// access time under the lock keeps a shard found loaded from closing
+	// under that access.
+	if s._engine == nil || !s.enabled || s.unloaded {
+		return false, nil
+	} else if time.Since(time.Unix(0, atomic.LoadInt64(&s.lastAccess))) < idle {
+		return false, nil
+	} else if !s._engine.IsIdle() {
+		return false, nil
+	}
+
+	ss, ok := s.index.(interface {
+		SeriesIDSet() *SeriesIDSet
+	})
+	if !ok {
+		return false, nil
+	}
+	sketch, tsSketch, err := s._engine.MeasurementsSketches()
+	if err != nil {
+		return false, err
+	}
+	seriesIDs := ss.SeriesIDSet()
+	lastModified := s._engine.LastModified()
+
+	if err := s.close(); err != nil {
+		return false, err
+	}
+	s.unloaded = true
+	s.unloadedSeriesIDs, s.unloadedSketches, s.unloadedTSSketches = seriesIDs, sketch, tsSketch
+	s.unloadedLastModified = lastModified
+	return true, nil
+}
+
+// SeriesIDSet returns the set of the IDs of the series in the shard. The set
+// of an unloaded shard is returned without reopening it.
+func (s *Shard) SeriesIDSet() (*SeriesIDSet, error) {
+	s.mu.RLock()
+	if s.unloaded {
+		defer s.mu.RUnlock()
+		return s.unloadedSeriesIDs, nil
+	}
+	s.mu.RUnlock()
+
+	index, err := s.Index()
+	if err != nil {
+		return nil, err
+	}
+	if i, ok := index.(interface {
+		SeriesIDSet() *SeriesIDSet
+	}); ok {
+		return i.SeriesIDSet(), nil
+	}
+	return nil, fmt.Errorf("unable to get series id set for index in shard at %s", s.path)
+}
+
+// IsUnloaded returns true while the shard is closed for being cold.
+func (s *Shard) IsUnloaded() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.unloaded
+}
+
+// reload records an access to the shard, and reopens it if it was unloaded.
+func (s *Shard) reload() error {
+	atomic.StoreInt64(&s.lastAccess, time.Now().UnixNano())
+
+	s.mu.RLock()
+	unloaded := s.unloaded
+	s.mu.RUnlock()
+	if !unloaded {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.unloaded {
+		return nil
+	}
+
+	// The progress of opening the store was reported when it opened.
+	start := time.Now()
+	s.options.WALReplayProgress = nil
+	s.closing = make(chan struct{})
+	if err := s.open(); err != nil {
+		s.close()
+		return NewShardError(s.id, err)
+	}
+	s.unloaded = false
+	s.unloadedSeriesIDs, s.unloadedSketches, s.unloadedTSSketches = nil, nil, nil
+	s.unloadedLastModified = time.Time{}
+	s._engine.SetEnabled(s.enabled)
+	s.logger.Info(fmt.Sprintf("Reopened unloaded shard %d in %s", s.id, time.Since(start)))
+	return nil
+}
+
 func (s *Shard) IndexType() string {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -400,16 +533,46 @@ func (s *Shard) ready() error {
 
 // LastModified returns the time when this shard was last modified.
 func (s *Shard) LastModified() time.Time {
-	engine, err := s.engine()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.unloaded {
+		return s.unloadedLastModified
+	}
+	engine, err := s.engineNoLock()
 	if err != nil {
 		return time.Time{}
 	}
 	return engine.LastModified()
 }
 
+// CacheSize returns the size of the in-memory cache of the shard, or 0 if
+// the shard is not open.
+func (s *Shard) CacheSize() uint64 {
+	engine, err := s.loadedEngine()
+	if err != nil {
+		return 0
+	}
+	return engine.CacheSize()
+}
+
 // Index returns a reference to the underlying index. It returns an error if
 // the index is nil.
 func (s *Shard) Index() (Index, error) {
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := s.ready(); err != nil {
+		return nil, err
+	}
+	return s.index, nil
+}
+
+// loadedIndex is similar to calling Index(), but it neither reopens an
+// unloaded shard nor counts as an access.
+func (s *Shard) loadedIndex() (Index, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	if err := s.ready(); err != nil {
@@ -420,7 +583,7 @@ func (s *Shard) Index() (Index, error) {
 
 // IsIdle return true if the shard is not receiving writes and is fully compacted.
 func (s *Shard) IsIdle() bool {
-	engine, err := s.engine()
+	engine, err := s.loadedEngine()
 	if err != nil {
 		return true
 	}
@@ -428,7 +591,7 @@ func (s *Shard) IsIdle() bool {
 }
 
 func (s *Shard) Free() error {
-	engine, err := s.engine()
+	engine, err := s.loadedEngine()
 	if err != nil {
 		return err
 	}
@@ -441,7 +604,7 @@ func (s *Shard) Free() error {
 
 // SetCompactionsEnabled enables or disable shard background compactions.
 func (s *Shard) SetCompactionsEnabled(enabled bool) {
-	engine, err := s.engine()
+	engine, err := s.loadedEngine()
 	if err != nil {
 		return
 	}
@@ -453,8 +616,11 @@ func (s *Shard) DiskSize() (int64, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	// We don't use engine() becuase we still want to report the shard's disk
-	// size even if the shard has been disabled.
-	if s._engine == nil {
+	// size even if the shard has been disabled. The files of an unloaded shard
+	// don't change, so the size from before it was unloaded is reported.
+	if s.unloaded {
+		return atomic.LoadInt64(&s.stats.DiskBytes), nil
+	} else if s._engine == nil {
 		return 0, ErrEngineClosed
 	}
 	size := s._engine.DiskSize()
@@ -470,6 +636,10 @@ type FieldCreate struct {
 
 // WritePoints will write the raw data points and any new metadata to the index in the shard.
 func (s *Shard) WritePoints(points []models.Point) error {
+	if err := s.reload(); err != nil {
+		return err
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -497,15 +667,38 @@ func (s *Shard) WritePoints(points []models.Point) error {
 		return err
 	}
 
-	// Write to the engine.
+	if err := fault.Inject(fault.ShardWrite); err != nil {
+		return err
+	}
+
+	// Write to the engine. The engine may drop points, such as duplicates,
+	// and still write the others.
+	var dropped int
 	if err := engine.WritePoints(points); err != nil {
-		atomic.AddInt64(&s.stats.WritePointsErr, int64(len(points)))
-		atomic.AddInt64(&s.stats.WriteReqErr, 1)
-		return fmt.Errorf("engine: %s", err)
+		perr, ok := err.(PartialWriteError)
+		if !ok {
+			atomic.AddInt64(&s.stats.WritePointsErr, int64(len(points)))
+			atomic.AddInt64(&s.stats.WriteReqErr, 1)
+			return fmt.Errorf("engine: %s", err)
+		}
+		dropped = perr.Dropped
+		if prev, ok := writeError.(PartialWriteError); ok {
+			prev.Dropped += perr.Dropped
+			writeError = prev
+		} else {
+			writeError = perr
+		}
 	}
-	atomic.AddInt64(&s.stats.WritePointsOK, int64(len(points)))
+	atomic.AddInt64(&s.stats.WritePointsDropped, int64(dropped))
+	atomic.AddInt64(&s.stats.WritePointsOK, int64(len(points)-dropped))
 	atomic.AddInt64(&s.stats.WriteReqOK, 1)
 
+	var n int
+	for _, p := range points {
+		n += p.StringSize()
+	}
+	atomic.AddInt64(&s.stats.PointBytesWritten, int64(n))
+
 	return writeError
 }
 
@@ -516,7 +709,12 @@ func (s *Shard) validateSeriesAndFields(points []models.Point) ([]models.Point,
 		err            error
 		dropped        int
 		reason         string // only first error reason is set unless returned from CreateSeriesListIfNotExists
+
+		// Points with field type conflicts that are dropped without an error.
+		conflicts      int
+		conflictReason string
 	)
+	policy := s.options.Config.FieldTypeConflict
 
 	// Create all series against the index in bulk.
 	keys := make([][]byte, len(points))
@@ -561,34 +759,12 @@ func (s *Shard) validateSeriesAndFields(points []models.Point) ([]models.Point,
 		}
 	}
 
-	// get the shard mutex for locally defined fields
 	n := 0
 
 	// mfCache is a local cache of MeasurementFields to reduce lock contention when validating
-	// field types.
+	// field types. The clones also hold the fields that earlier points create.
 	mfCache := make(map[string]*MeasurementFields, 16)
 	for i, p := range points {
-		var skip bool
-		var validField bool
-		iter := p.FieldIterator()
-		for iter.Next() {
-			if bytes.Equal(iter.FieldKey(), timeBytes) {
-				continue
-			}
-			validField = true
-			break
-		}
-
-		if !validField {
-			dropped++
-			if reason == "" {
-				reason = fmt.Sprintf("invalid field name: input field \"%s\" on measurement \"%s\" is invalid", "time", string(p.Name()))
-			}
-			continue
-		}
-
-		iter.Reset()
-
 		// Skip points if keys have been dropped.
 		// The drop count has already been incremented during series creation.
 		if len(droppedKeys) > 0 && bytesutil.Contains(droppedKeys, keys[i]) {
@@ -596,64 +772,48 @@ func (s *Shard) validateSeriesAndFields(points []models.Point) ([]models.Point,
 		}
 
 		name := p.Name()
-		// see if the field definitions need to be saved to the shard
 		mf := mfCache[string(name)]
 		if mf == nil {
 			mf = engine.MeasurementFields(name).Clone()
 			mfCache[string(name)] = mf
 		}
-		iter.Reset()
-
-		// validate field types and encode data
-		for iter.Next() {
-
-			// Skip fields name "time", they are illegal
-			if bytes.Equal(iter.FieldKey(), timeBytes) {
-				continue
-			}
 
-			var fieldType influxql.DataType
-			switch iter.Type() {
-			case models.Float:
-				fieldType = influxql.Float
-			case models.Integer:
-				fieldType = influxql.Integer
-			case models.Unsigned:
-				fieldType = influxql.Unsigned
-			case models.Boolean:
-				fieldType = influxql.Boolean
-			case models.String:
-				fieldType = influxql.String
-			default:
-				continue
-			}
-
-			if f := mf.FieldBytes(iter.FieldKey()); f != nil {
-				// Field present in shard metadata, make sure there is no type conflict.
-				if f.Type != fieldType {
-					atomic.AddInt64(&s.stats.WritePointsDropped, 1)
-					dropped++
-					if reason == "" {
-						reason = fmt.Sprintf("%s: input field \"%s\" on measurement \"%s\" is type %s, already exists as type %s", ErrFieldTypeConflict, iter.FieldKey(), name, fieldType, f.Type)
-					}
-					skip = true
-				} else {
-					continue // Field is present, and it's of the same type. Nothing more to do.
+		c := checkPoint(p, mf, policy)
+		if c.reason != "" {
+			if c.conflict && policy == FieldTypeConflictDrop {
+				atomic.AddInt64(&s.stats.WritePointsDropped, 1)
+				conflicts++
+				if conflictReason == "" {
+					conflictReason = c.reason
 				}
+				continue
+			} else if !c.invalid {
+				atomic.AddInt64(&s.stats.WritePointsDropped, 1)
 			}
-
-			if !skip {
-				fieldsToCreate = append(fieldsToCreate, &FieldCreate{p.Name(), &Field{Name: string(iter.FieldKey()), Type: fieldType}})
+			dropped++
+			if reason == "" {
+				reason = c.reason
 			}
+			continue
+		}
+		if c.coerced {
+			atomic.AddInt64(&s.stats.WritePointsCoerced, 1)
 		}
 
-		if !skip {
-			points[n] = points[i]
-			n++
+		// see if the field definitions need to be saved to the shard
+		for _, f := range c.fields {
+			mf.CreateFieldIfNotExists([]byte(f.Name), f.Type)
+			fieldsToCreate = append(fieldsToCreate, &FieldCreate{name, f})
 		}
+		points[n] = c.point
+		n++
 	}
 	points = points[:n]
 
+	if conflicts > 0 {
+		s.logger.Info(fmt.Sprintf("dropped %d point(s) with field type conflicts, first: %s", conflicts, conflictReason))
+	}
+
 	if dropped > 0 {
 		err = PartialWriteError{Reason: reason, Dropped: dropped}
 	}
@@ -661,6 +821,66 @@ func (s *Shard) validateSeriesAndFields(points []models.Point) ([]models.Point,
 	return points, fieldsToCreate, err
 }
 
+// ValidatePoints returns the error writing points to the shard would return
+// because of the points themselves, without writing them or creating their
+// series or fields.
+func (s *Shard) ValidatePoints(points []models.Point) error {
+	if err := s.reload(); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	engine, err := s.engineNoLock()
+	if err != nil {
+		return err
+	}
+	policy := s.options.Config.FieldTypeConflict
+
+	var dropped int
+	var reason string
+	drop := func(msg string) {
+		dropped++
+		if reason == "" {
+			reason = msg
+		}
+	}
+
+	// The clones also hold the fields that earlier points would create.
+	mfCache := make(map[string]*MeasurementFields, 16)
+	for _, p := range points {
+		name := p.Name()
+		if v := p.Tags().Get(timeBytes); v != nil {
+			drop(fmt.Sprintf("invalid tag key: input tag \"%s\" on measurement \"%s\" is invalid", "time", name))
+			continue
+		}
+
+		mf := mfCache[string(name)]
+		if mf == nil {
+			mf = engine.MeasurementFields(name).Clone()
+			mfCache[string(name)] = mf
+		}
+
+		c := checkPoint(p, mf, policy)
+		if c.reason != "" {
+			// Writes drop these points without an error.
+			if !c.conflict || policy != FieldTypeConflictDrop {
+				drop(c.reason)
+			}
+			continue
+		}
+		for _, f := range c.fields {
+			mf.CreateFieldIfNotExists([]byte(f.Name), f.Type)
+		}
+	}
+
+	if dropped > 0 {
+		return PartialWriteError{Reason: reason, Dropped: dropped}
+	}
+	return nil
+}
+
 func (s *Shard) createFieldsAndMeasurements(fieldsToCreate []*FieldCreate) error {
 	if len(fieldsToCreate) == 0 {
 		return nil
@@ -717,6 +937,21 @@ func (s *Shard) SeriesN() int64 {
 
 // MeasurementsSketches returns the measurement sketches for the shard.
 func (s *Shard) MeasurementsSketches() (estimator.Sketch, estimator.Sketch, error) {
+	s.mu.RLock()
+	if s.unloaded {
+		defer s.mu.RUnlock()
+
+		// Callers merge into the sketches returned, so they are copied.
+		sketch, tsSketch := hll.NewDefaultPlus(), hll.NewDefaultPlus()
+		if err := sketch.Merge(s.unloadedSketches); err != nil {
+			return nil, nil, err
+		} else if err := tsSketch.Merge(s.unloadedTSSketches); err != nil {
+			return nil, nil, err
+		}
+		return sketch, tsSketch, nil
+	}
+	s.mu.RUnlock()
+
 	engine, err := s.engine()
 	if err != nil {
 		return nil, nil, err
@@ -1076,7 +1311,7 @@ func (s *Shard) ForEachMeasurementName(fn func(name []byte) error) error {
 }
 
 func (s *Shard) TagKeyCardinality(name, key []byte) int {
-	engine, err := s.engine()
+	engine, err := s.loadedEngine()
 	if err != nil {
 		return 0
 	}
@@ -1106,8 +1341,18 @@ func (s *Shard) Digest() (io.ReadCloser, int64, error) {
 // than directly referencing Shard.engine.
 //
 // If a caller needs an Engine reference but is already under a lock, then they
-// should use engineNoLock().
+// should use engineNoLock(). A shard unloaded for being cold is reopened.
 func (s *Shard) engine() (Engine, error) {
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s.loadedEngine()
+}
+
+// loadedEngine is similar to calling engine(), but it neither reopens an
+// unloaded shard nor counts as an access. It is for monitoring a shard, which
+// must not keep it from being unloaded.
+func (s *Shard) loadedEngine() (Engine, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return s.engineNoLock()
@@ -1198,6 +1443,7 @@ func (a Shards) FieldDimensions(measurements []string) (fields map[string]influx
 func (a Shards) MapType(measurement, field string) influxql.DataType {
 	var typ influxql.DataType
 	for _, sh := range a {
+		sh.reload()
 		sh.mu.RLock()
 		if t, err := sh.mapType(measurement, field); err == nil && typ.LessThan(t) {
 			typ = t
@@ -1295,6 +1541,9 @@ func (a Shards) ExpandSources(sources influxql.Sources) (influxql.Sources, error
 
 	// Iterate through every shard and expand the sources.
 	for _, sh := range a {
+		if err := sh.reload(); err != nil {
+			return nil, err
+		}
 		sh.mu.RLock()
 		expanded, err := sh.expandSources(sources)
 		sh.mu.RUnlock()
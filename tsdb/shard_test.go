@@ -400,6 +400,328 @@ func TestShardWriteAddNewField(t *testing.T) {
 	}
 }
 
+func TestShard_WritePoints_FieldTypeConflict(t *testing.T) {
+	for _, tt := range []struct {
+		policy  string
+		dropped int // points dropped with a partial write error
+		stats   map[string]int64
+	}{
+		{policy: tsdb.FieldTypeConflictReject, dropped: 2, stats: map[string]int64{"writePointsDropped": 2, "writePointsCoerced": 0}},
+		{policy: tsdb.FieldTypeConflictCoerce, dropped: 1, stats: map[string]int64{"writePointsDropped": 1, "writePointsCoerced": 1}},
+		{policy: tsdb.FieldTypeConflictDrop, dropped: 0, stats: map[string]int64{"writePointsDropped": 2, "writePointsCoerced": 0}},
+	} {
+		t.Run(tt.policy, func(t *testing.T) {
+			tmpDir, _ := ioutil.TempDir("", "shard_test")
+			defer os.RemoveAll(tmpDir)
+
+			sfile := MustOpenSeriesFile()
+			defer sfile.Close()
+
+			opts := tsdb.NewEngineOptions()
+			opts.Config.WALDir = filepath.Join(tmpDir, "wal")
+			opts.Config.FieldTypeConflict = tt.policy
+			opts.InmemIndex = inmem.NewIndex(path.Base(tmpDir), sfile.SeriesFile)
+
+			sh := tsdb.NewShard(1, path.Join(tmpDir, "shard"), path.Join(tmpDir, "wal"), sfile.SeriesFile, opts)
+			if err := sh.Open(); err != nil {
+				t.Fatalf("error opening shard: %s", err.Error())
+			}
+			defer sh.Close()
+
+			if err := sh.WritePoints([]models.Point{
+				models.MustNewPoint("cpu", nil, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			// An integer can be coerced to the float field, a string cannot.
+			points := []models.Point{
+				models.MustNewPoint("cpu", nil, map[string]interface{}{"value": int64(2)}, time.Unix(2, 0)),
+				models.MustNewPoint("cpu", nil, map[string]interface{}{"value": "three"}, time.Unix(3, 0)),
+				models.MustNewPoint("cpu", nil, map[string]interface{}{"value": 4.0}, time.Unix(4, 0)),
+			}
+
+			// Validating the points reports what writing them does.
+			err := sh.ValidatePoints(points)
+			if tt.dropped == 0 {
+				if err != nil {
+					t.Fatalf("unexpected validation error: %s", err)
+				}
+			} else if err, ok := err.(tsdb.PartialWriteError); !ok || err.Dropped != tt.dropped {
+				t.Fatalf("unexpected validation error: %v", err)
+			}
+
+			err = sh.WritePoints(points)
+			if tt.dropped == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			} else if err, ok := err.(tsdb.PartialWriteError); !ok || err.Dropped != tt.dropped {
+				t.Fatalf("unexpected error: %v", err)
+			} else if !strings.Contains(err.Reason, tsdb.ErrFieldTypeConflict.Error()) {
+				t.Fatalf("unexpected reason: %s", err.Reason)
+			}
+
+			stats := sh.Statistics(nil)[0].Values
+			for k, v := range tt.stats {
+				if stats[k] != v {
+					t.Fatalf("unexpected %s: got %v, exp %d", k, stats[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestShard_WritePoints_DuplicatePoints(t *testing.T) {
+	for _, tt := range []struct {
+		policy      string
+		value, new  interface{} // fields of the duplicate point after the writes
+		duplicates  int64
+		overwritten int64
+		dropped     int // points dropped in full by the second write
+	}{
+		{policy: tsdb.DuplicatePointsLastWriteWins, value: 2.0, new: 30.0, duplicates: 0, overwritten: 1},
+		{policy: tsdb.DuplicatePointsFirstWriteWins, value: 1.0, new: (*float64)(nil), duplicates: 1, dropped: 1},
+		{policy: tsdb.DuplicatePointsMergeFields, value: 1.0, new: 30.0, duplicates: 1},
+	} {
+		t.Run(tt.policy, func(t *testing.T) {
+			tmpDir, _ := ioutil.TempDir("", "shard_test")
+			defer os.RemoveAll(tmpDir)
+
+			sfile := MustOpenSeriesFile()
+			defer sfile.Close()
+
+			opts := tsdb.NewEngineOptions()
+			opts.Config.WALDir = filepath.Join(tmpDir, "wal")
+			opts.Config.DuplicatePoints = tt.policy
+			opts.InmemIndex = inmem.NewIndex(path.Base(tmpDir), sfile.SeriesFile)
+
+			sh := tsdb.NewShard(1, path.Join(tmpDir, "shard"), path.Join(tmpDir, "wal"), sfile.SeriesFile, opts)
+			if err := sh.Open(); err != nil {
+				t.Fatalf("error opening shard: %s", err.Error())
+			}
+			defer sh.Close()
+
+			if err := sh.WritePoints([]models.Point{
+				models.MustNewPoint("cpu", nil, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+			}); err != nil {
+				t.Fatal(err)
+			}
+			err := sh.WritePoints([]models.Point{
+				models.MustNewPoint("cpu", nil, map[string]interface{}{"value": 2.0, "new": 30.0}, time.Unix(1, 0)),
+				models.MustNewPoint("cpu", nil, map[string]interface{}{"value": 3.0}, time.Unix(2, 0)),
+			})
+			if tt.dropped == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else if perr, ok := err.(tsdb.PartialWriteError); !ok {
+				t.Fatalf("expected partial write error, got %v", err)
+			} else if perr.Dropped != tt.dropped {
+				t.Fatalf("unexpected dropped: got %d, exp %d", perr.Dropped, tt.dropped)
+			}
+
+			itr, err := sh.CreateIterator(context.Background(), &influxql.Measurement{Name: "cpu"}, query.IteratorOptions{
+				Expr:      influxql.MustParseExpr(`value`),
+				Aux:       []influxql.VarRef{{Val: "new"}},
+				Ascending: true,
+				StartTime: influxql.MinTime,
+				EndTime:   influxql.MaxTime,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer itr.Close()
+			fitr := itr.(query.FloatIterator)
+
+			if p, err := fitr.Next(); err != nil {
+				t.Fatalf("unexpected error(0): %s", err)
+			} else if p == nil || p.Value != tt.value || !deep.Equal(p.Aux, []interface{}{tt.new}) {
+				t.Fatalf("unexpected point(0): %s", spew.Sdump(p))
+			}
+			if p, err := fitr.Next(); err != nil {
+				t.Fatalf("unexpected error(1): %s", err)
+			} else if p == nil || p.Value != 3.0 {
+				t.Fatalf("unexpected point(1): %s", spew.Sdump(p))
+			}
+
+			for _, stat := range sh.Statistics(nil) {
+				if stat.Name != "tsm1_engine" {
+					continue
+				}
+				if got := stat.Values["writeDuplicatePoints"]; got != tt.duplicates {
+					t.Fatalf("unexpected writeDuplicatePoints: got %v, exp %d", got, tt.duplicates)
+				} else if got := stat.Values["writeOverwrittenPoints"]; got != tt.overwritten {
+					t.Fatalf("unexpected writeOverwrittenPoints: got %v, exp %d", got, tt.overwritten)
+				}
+			}
+		})
+	}
+}
+
+// Ensure the first-write-wins policy writes a point adding a field to the
+// series at a time that already has values.
+func TestShard_WritePoints_FirstWriteWins_NewField(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+
+	sfile := MustOpenSeriesFile()
+	defer sfile.Close()
+
+	opts := tsdb.NewEngineOptions()
+	opts.Config.WALDir = filepath.Join(tmpDir, "wal")
+	opts.Config.DuplicatePoints = tsdb.DuplicatePointsFirstWriteWins
+	opts.InmemIndex = inmem.NewIndex(path.Base(tmpDir), sfile.SeriesFile)
+
+	sh := tsdb.NewShard(1, path.Join(tmpDir, "shard"), path.Join(tmpDir, "wal"), sfile.SeriesFile, opts)
+	if err := sh.Open(); err != nil {
+		t.Fatalf("error opening shard: %s", err.Error())
+	}
+	defer sh.Close()
+
+	if err := sh.WritePoints([]models.Point{
+		models.MustNewPoint("cpu", nil, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+	}); err != nil {
+		t.Fatal(err)
+	} else if err := sh.WritePoints([]models.Point{
+		models.MustNewPoint("cpu", nil, map[string]interface{}{"new": 30.0}, time.Unix(1, 0)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	itr, err := sh.CreateIterator(context.Background(), &influxql.Measurement{Name: "cpu"}, query.IteratorOptions{
+		Expr:      influxql.MustParseExpr(`value`),
+		Aux:       []influxql.VarRef{{Val: "new"}},
+		Ascending: true,
+		StartTime: influxql.MinTime,
+		EndTime:   influxql.MaxTime,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer itr.Close()
+
+	if p, err := itr.(query.FloatIterator).Next(); err != nil {
+		t.Fatal(err)
+	} else if p == nil || p.Value != 1.0 || !deep.Equal(p.Aux, []interface{}{30.0}) {
+		t.Fatalf("unexpected point: %s", spew.Sdump(p))
+	}
+}
+
+// Ensure a cold shard can be unloaded and is reopened by a write.
+func TestShard_Unload(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+
+	sfile := MustOpenSeriesFile()
+	defer sfile.Close()
+
+	opts := tsdb.NewEngineOptions()
+	opts.Config.WALDir = filepath.Join(tmpDir, "wal")
+	opts.InmemIndex = inmem.NewIndex(path.Base(tmpDir), sfile.SeriesFile)
+
+	sh := tsdb.NewShard(1, path.Join(tmpDir, "shard"), path.Join(tmpDir, "wal"), sfile.SeriesFile, opts)
+	if err := sh.Open(); err != nil {
+		t.Fatalf("error opening shard: %s", err.Error())
+	}
+	defer sh.Close()
+
+	// A shard accessed within the idle time stays open.
+	if ok, err := sh.Unload(time.Hour); err != nil || ok {
+		t.Fatalf("unexpected unload: %v, %v", ok, err)
+	} else if ok, err := sh.Unload(0); err != nil || !ok {
+		t.Fatalf("expected unload: %v, %v", ok, err)
+	} else if !sh.IsUnloaded() {
+		t.Fatal("expected shard to be unloaded")
+	} else if _, err := sh.DiskSize(); err != nil {
+		t.Fatalf("unexpected disk size error: %s", err)
+	}
+
+	// Reading the state of an unloaded shard, as monitoring, backups and
+	// deleting shards do, does not reopen it.
+	sh.LastModified()
+	sh.TagKeyCardinality([]byte("cpu"), []byte("host"))
+	if _, err := sh.SeriesIDSet(); err != nil {
+		t.Fatal(err)
+	} else if !sh.IsUnloaded() {
+		t.Fatal("expected shard to stay unloaded")
+	}
+
+	if err := sh.WritePoints([]models.Point{
+		models.MustNewPoint("cpu", nil, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+	}); err != nil {
+		t.Fatal(err)
+	} else if sh.IsUnloaded() {
+		t.Fatal("expected shard to be reopened")
+	}
+
+	// The points in the cache keep the shard from being unloaded.
+	if ok, err := sh.Unload(0); err != nil || ok {
+		t.Fatalf("unexpected unload: %v, %v", ok, err)
+	}
+
+	itr, err := sh.CreateIterator(context.Background(), &influxql.Measurement{Name: "cpu"}, query.IteratorOptions{
+		Expr:      influxql.MustParseExpr(`value`),
+		Ascending: true,
+		StartTime: influxql.MinTime,
+		EndTime:   influxql.MaxTime,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer itr.Close()
+	if p, err := itr.(query.FloatIterator).Next(); err != nil {
+		t.Fatal(err)
+	} else if p == nil || p.Value != 1.0 {
+		t.Fatalf("unexpected point: %s", spew.Sdump(p))
+	}
+}
+
+// Ensure validating points reports the field type conflicts a write would,
+// including with fields the same batch would create, without writing.
+func TestShard_ValidatePoints(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "shard_test")
+	defer os.RemoveAll(tmpDir)
+
+	sfile := MustOpenSeriesFile()
+	defer sfile.Close()
+
+	opts := tsdb.NewEngineOptions()
+	opts.Config.WALDir = filepath.Join(tmpDir, "wal")
+	opts.InmemIndex = inmem.NewIndex(path.Base(tmpDir), sfile.SeriesFile)
+
+	sh := tsdb.NewShard(1, path.Join(tmpDir, "shard"), path.Join(tmpDir, "wal"), sfile.SeriesFile, opts)
+	if err := sh.Open(); err != nil {
+		t.Fatalf("error opening shard: %s", err.Error())
+	}
+	defer sh.Close()
+
+	if err := sh.WritePoints([]models.Point{
+		models.MustNewPoint("cpu", nil, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := sh.ValidatePoints([]models.Point{
+		models.MustNewPoint("cpu", nil, map[string]interface{}{"value": 2.0}, time.Unix(2, 0)),
+		models.MustNewPoint("cpu", nil, map[string]interface{}{"value": "three"}, time.Unix(3, 0)),
+		models.MustNewPoint("mem", nil, map[string]interface{}{"free": int64(4)}, time.Unix(4, 0)),
+		models.MustNewPoint("mem", nil, map[string]interface{}{"free": 5.0}, time.Unix(5, 0)),
+		models.MustNewPoint("disk", models.NewTags(map[string]string{"time": "now"}), map[string]interface{}{"used": 6.0}, time.Unix(6, 0)),
+	})
+	if err, ok := err.(tsdb.PartialWriteError); !ok || err.Dropped != 3 {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !strings.Contains(err.Reason, tsdb.ErrFieldTypeConflict.Error()) {
+		t.Fatalf("unexpected reason: %s", err.Reason)
+	}
+
+	if got, exp := sh.SeriesN(), int64(1); got != exp {
+		t.Fatalf("got %d series, exp %d series in index", got, exp)
+	} else if mf := sh.MeasurementFields([]byte("mem")); mf.FieldN() != 0 {
+		t.Fatalf("unexpected fields created: %d", mf.FieldN())
+	}
+}
+
 // Tests concurrently writing to the same shard with different field types which
 // can trigger a panic when the shard is snapshotted to TSM files.
 func TestShard_WritePoints_FieldConflictConcurrent(t *testing.T) {
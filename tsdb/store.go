@@ -13,10 +13,12 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/pkg/estimator"
+	"github.com/influxdata/influxdb/pkg/file"
 	"github.com/influxdata/influxdb/pkg/limiter"
 	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxql"
@@ -28,12 +30,37 @@ var (
 	ErrShardNotFound = fmt.Errorf("shard not found")
 	// ErrStoreClosed is returned when trying to use a closed Store.
 	ErrStoreClosed = fmt.Errorf("store is closed")
+	// ErrDiskFull is returned when writing while free disk space is below the
+	// hard limit.
+	ErrDiskFull = fmt.Errorf("disk free space below hard limit")
 )
 
+// ErrDatabaseCacheLimitExceeded returns an error indicating a write could not
+// be completed because the caches of a database exceed its
+// max-cache-memory-size limit.
+func ErrDatabaseCacheLimitExceeded(database string, n, limit uint64) error {
+	return fmt.Errorf("max-cache-memory-size exceeded for database %s: (%d/%d)", database, n, limit)
+}
+
 // Statistics gathered by the store.
 const (
 	statDatabaseSeries       = "numSeries"       // number of series in a database
 	statDatabaseMeasurements = "numMeasurements" // number of measurements in a database
+	statDatabaseDiskGrowth   = "diskGrowthRate"  // bytes per second the database grows on disk
+	statDatabaseWriteRate    = "writePointsRate" // points per second written to the database
+	statDatabaseCacheMemory  = "cacheBytes"      // size of the caches of the shards of a database
+	statDatabaseDiskBytes    = "diskBytes"       // size on disk of the shards of a database
+
+	statDatabaseWritePoints   = "writePointsOk"    // points written to the shards of a database
+	statDatabaseWriteBytes    = "writePointsBytes" // size in line protocol of the points written to a database
+	statDatabaseSeriesCreated = "seriesCreated"    // series created in a database since it was opened
+
+	statRetentionPolicyDiskBytes = "diskBytes" // size on disk of the shards of a retention policy
+	statRetentionPolicyShards    = "numShards" // number of shards of a retention policy
+
+	statDiskFree          = "freeBytes"     // free space of the disk holding the data directory
+	statDiskGrowth        = "growthRate"    // bytes per second the store grows on disk
+	statDiskDaysUntilFull = "daysUntilFull" // days until the disk is full at the current growth rate
 )
 
 // SeriesFileDirectory is the name of the directory containing series files for
@@ -60,6 +87,33 @@ type Store struct {
 	closing chan struct{}
 	wg      sync.WaitGroup
 	opened  bool
+
+	// diskLow and diskFull are set while free disk space is below the soft
+	// and hard limits, respectively.
+	diskLow  int32
+	diskFull int32
+
+	// progress holds the *openProgress of the shards while the store opens.
+	// It is not guarded by mu, which opening holds.
+	progress atomic.Value
+
+	// growth tracks the disk growth and write rates of each database.
+	growthMu sync.Mutex
+	growth   map[string]*databaseGrowth
+
+	// oversized holds the databases above max-database-size, so crossing the
+	// limit is logged once. It is guarded by growthMu.
+	oversized map[string]bool
+
+	// TopMeasurementsN is the number of measurements of each database, with
+	// the most points written, that have statistics of their own. The points
+	// written are not counted by measurement when it is zero. The counts are
+	// approximate for databases written to more than measurementWritesFactor
+	// times as many measurements.
+	TopMeasurementsN int
+
+	measurementWritesMu sync.RWMutex
+	measurementWrites   map[string]*topMeasurementWrites
 }
 
 // NewStore returns a new store with the given path and a default configuration.
@@ -94,6 +148,8 @@ func (s *Store) Statistics(tags map[string]string) []models.Statistic {
 
 	// Add all the series and measurements cardinality estimations.
 	databases := s.Databases()
+	points, bytes := s.databaseWrites(shards)
+	usage := diskUsageByRetentionPolicy(shards)
 	statistics := make([]models.Statistic, 0, len(databases))
 	for _, database := range databases {
 		sc, err := s.SeriesCardinality(database)
@@ -108,12 +164,45 @@ func (s *Store) Statistics(tags map[string]string) []models.Statistic {
 			continue
 		}
 
+		disk, writes := s.databaseGrowthRates(database)
 		statistics = append(statistics, models.Statistic{
 			Name: "database",
 			Tags: models.StatisticTags{"database": database}.Merge(tags),
 			Values: map[string]interface{}{
 				statDatabaseSeries:       sc,
 				statDatabaseMeasurements: mc,
+				statDatabaseDiskGrowth:   disk,
+				statDatabaseWriteRate:    writes,
+				statDatabaseCacheMemory:  int64(s.databaseCacheSize(database)),
+				statDatabaseDiskBytes:    databaseDiskSize(usage[database]),
+
+				statDatabaseWritePoints:   points[database],
+				statDatabaseWriteBytes:    bytes[database],
+				statDatabaseSeriesCreated: s.databaseSeriesCreated(database),
+			},
+		})
+
+		for rp, u := range usage[database] {
+			statistics = append(statistics, models.Statistic{
+				Name: "rp",
+				Tags: models.StatisticTags{"database": database, "retentionPolicy": rp}.Merge(tags),
+				Values: map[string]interface{}{
+					statRetentionPolicyDiskBytes: u.bytes,
+					statRetentionPolicyShards:    u.shards,
+				},
+			})
+		}
+	}
+	statistics = append(statistics, s.measurementWritesStatistics(tags)...)
+
+	if free, rate, days, err := s.DiskForecast(); err == nil {
+		statistics = append(statistics, models.Statistic{
+			Name: "disk",
+			Tags: models.StatisticTags{"path": s.path}.Merge(tags),
+			Values: map[string]interface{}{
+				statDiskFree:          int64(free),
+				statDiskGrowth:        rate,
+				statDiskDaysUntilFull: days,
 			},
 		})
 	}
@@ -149,9 +238,19 @@ func (s *Store) Open() error {
 		return err
 	}
 
-	if err := s.loadShards(); err != nil {
+	// Check free space before opening shards so compactions do not start on
+	// a disk that is already low.
+	s.checkDiskSpace()
+	s.EngineOptions.LevelCompactionsPaused = s.diskSpaceLow
+
+	progress := newOpenProgress()
+	s.progress.Store(progress)
+	defer s.progress.Store((*openProgress)(nil))
+
+	if err := s.loadShards(progress); err != nil {
 		return err
 	}
+	s.Logger.Info(fmt.Sprintf("Opened shards in %s: %s", time.Since(progress.start), progress))
 
 	s.opened = true
 	s.wg.Add(1)
@@ -160,7 +259,7 @@ func (s *Store) Open() error {
 	return nil
 }
 
-func (s *Store) loadShards() error {
+func (s *Store) loadShards(progress *openProgress) error {
 	// res holds the result from opening each shard in a goroutine
 	type res struct {
 		s   *Shard
@@ -196,7 +295,13 @@ func (s *Store) loadShards() error {
 		s.Logger.Info("Compaction throughput limit disabled")
 	}
 
-	t := limiter.NewFixed(runtime.GOMAXPROCS(0))
+	// Opening a shard mostly waits on the disk, so the shards are opened
+	// concurrently, up to MaxConcurrentShardOpens at once.
+	opens := s.EngineOptions.Config.MaxConcurrentShardOpens
+	if opens == 0 {
+		opens = runtime.GOMAXPROCS(0)
+	}
+	t := limiter.NewFixed(opens)
 	resC := make(chan *res)
 	var n int
 
@@ -248,6 +353,7 @@ func (s *Store) loadShards() error {
 
 			for _, sh := range shardDirs {
 				n++
+				atomic.AddInt64(&progress.shardsTotal, 1)
 				go func(db, rp, sh string) {
 					t.Take()
 					defer t.Release()
@@ -264,11 +370,12 @@ func (s *Store) loadShards() error {
 					}
 
 					// Copy options and assign shared index.
-					opt := s.EngineOptions
+					opt := s.shardEngineOptions(db)
 					opt.InmemIndex = idx
 
 					// Provide an implementation of the ShardIDSets
 					opt.SeriesIDSets = shardSet{store: s, db: db}
+					opt.WALReplayProgress = progress
 
 					// Existing shards should continue to use inmem index.
 					if _, err := os.Stat(filepath.Join(path, "index")); os.IsNotExist(err) {
@@ -295,10 +402,23 @@ func (s *Store) loadShards() error {
 		}
 	}
 
+	// Log the progress while shards are opened, so a store taking minutes to
+	// open is not taken for a hung one.
+	ticker := time.NewTicker(openProgressLogInterval)
+	defer ticker.Stop()
+
 	// Gather results of opening shards concurrently, keeping track of how
 	// many databases we are managing.
 	for i := 0; i < n; i++ {
-		res := <-resC
+		var res *res
+		for res == nil {
+			select {
+			case res = <-resC:
+			case <-ticker.C:
+				s.Logger.Info(fmt.Sprintf("Opening shards: %s", progress))
+			}
+		}
+		atomic.AddInt64(&progress.shardsOpened, 1)
 		if res.err != nil {
 			s.Logger.Info(res.err.Error())
 			continue
@@ -355,6 +475,26 @@ func (s *Store) Close() error {
 	return nil
 }
 
+// Ready returns an error if the store cannot accept writes, because it is
+// not open or free disk space is below the hard limit. While the store opens,
+// the error tells how far opening the shards has got.
+func (s *Store) Ready() error {
+	if p, _ := s.progress.Load().(*openProgress); p != nil {
+		return fmt.Errorf("store is opening: %s", p)
+	}
+
+	s.mu.RLock()
+	opened := s.opened
+	s.mu.RUnlock()
+
+	if !opened {
+		return ErrStoreClosed
+	} else if atomic.LoadInt32(&s.diskFull) == 1 {
+		return ErrDiskFull
+	}
+	return nil
+}
+
 // openSeriesFile either returns or creates a series file for the provided
 // database. It must be called under a full lock.
 func (s *Store) openSeriesFile(database string) (*SeriesFile, error) {
@@ -487,7 +627,7 @@ func (s *Store) CreateShard(database, retentionPolicy string, shardID uint64, en
 	}
 
 	// Copy index options and pass in shared index.
-	opt := s.EngineOptions
+	opt := s.shardEngineOptions(database)
 	opt.InmemIndex = idx
 	opt.SeriesIDSets = shardSet{store: s, db: database}
 
@@ -540,19 +680,13 @@ func (s *Store) DeleteShard(shardID uint64) error {
 	delete(s.shards, shardID)
 	s.mu.Unlock()
 
-	// Get the shard's local bitset of series IDs.
-	index, err := sh.Index()
+	// Get the shard's local bitset of series IDs. The set of an unloaded
+	// shard is kept, so the shard is not reopened only to be deleted.
+	ss, err := sh.SeriesIDSet()
 	if err != nil {
 		return err
 	}
 
-	var ss *SeriesIDSet
-	if i, ok := index.(interface {
-		SeriesIDSet() *SeriesIDSet
-	}); ok {
-		ss = i.SeriesIDSet()
-	}
-
 	db := sh.Database()
 	if err := sh.Close(); err != nil {
 		return err
@@ -563,18 +697,11 @@ func (s *Store) DeleteShard(shardID uint64) error {
 	shards := s.filterShards(byDatabase(db))
 
 	s.walkShards(shards, func(sh *Shard) error {
-		index, err := sh.Index()
+		other, err := sh.SeriesIDSet()
 		if err != nil {
 			return err
 		}
-
-		if i, ok := index.(interface {
-			SeriesIDSet() *SeriesIDSet
-		}); ok {
-			ss.Diff(i.SeriesIDSet())
-		} else {
-			return fmt.Errorf("unable to get series id set for index in shard at %s", sh.Path())
-		}
+		ss.Diff(other)
 		return nil
 	})
 
@@ -658,6 +785,7 @@ func (s *Store) DeleteDatabase(name string) error {
 	// Remove shared index for database if using inmem index.
 	delete(s.indexes, name)
 
+	s.deleteMeasurementWrites(name, "")
 	return nil
 }
 
@@ -723,12 +851,16 @@ func (s *Store) DeleteMeasurement(database, name string) error {
 	// Limit to 1 delete for each shard since expanding the measurement into the list
 	// of series keys can be very memory intensive if run concurrently.
 	limit := limiter.NewFixed(1)
-	return s.walkShards(shards, func(sh *Shard) error {
+	if err := s.walkShards(shards, func(sh *Shard) error {
 		limit.Take()
 		defer limit.Release()
 
 		return sh.DeleteMeasurement([]byte(name))
-	})
+	}); err != nil {
+		return err
+	}
+	s.deleteMeasurementWrites(database, name)
+	return nil
 }
 
 // filterShards returns a slice of shards where fn returns true
@@ -889,20 +1021,17 @@ func (s *Store) SeriesCardinality(database string) (int64, error) {
 	shards := s.filterShards(byDatabase(database))
 	s.mu.RUnlock()
 
+	var mu sync.Mutex
 	others := make([]*SeriesIDSet, 0, len(shards))
 	s.walkShards(shards, func(sh *Shard) error {
-		index, err := sh.Index()
+		ss, err := sh.SeriesIDSet()
 		if err != nil {
 			return err
 		}
 
-		if i, ok := index.(interface {
-			SeriesIDSet() *SeriesIDSet
-		}); ok {
-			others = append(others, i.SeriesIDSet())
-		} else {
-			return fmt.Errorf("unable to get series id set for index in shard at %s", sh.Path())
-		}
+		mu.Lock()
+		others = append(others, ss)
+		mu.Unlock()
 		return nil
 	})
 
@@ -998,11 +1127,58 @@ func (s *Store) ShardRelativePath(id uint64) (string, error) {
 	return relativePath(s.path, shard.path)
 }
 
+// ShardDiskSize returns the size on disk of a shard, in bytes.
+func (s *Store) ShardDiskSize(id uint64) (int64, error) {
+	shard := s.Shard(id)
+	if shard == nil {
+		return 0, fmt.Errorf("shard %d doesn't exist on this server", id)
+	}
+	return shard.DiskSize()
+}
+
+// ShardLastModified returns the time shard id was last modified, without
+// reopening it if it is unloaded. It returns the zero time if the shard does
+// not exist.
+func (s *Store) ShardLastModified(id uint64) time.Time {
+	shard := s.Shard(id)
+	if shard == nil {
+		return time.Time{}
+	}
+	return shard.LastModified()
+}
+
 // DeleteSeries loops through the local shards and deletes the series data for
 // the passed in series keys.
 func (s *Store) DeleteSeries(database string, sources []influxql.Source, condition influxql.Expr) error {
+	s.mu.RLock()
+	shards := s.filterShards(byDatabase(database))
+	s.mu.RUnlock()
+	return s.deleteSeries(database, shards, sources, condition)
+}
+
+// DeleteSeriesFromShards deletes the series data matched by sources and
+// condition from the shards of shardIDs that belong to database. The other
+// shards of the database are not read, so cold shards are not reopened.
+func (s *Store) DeleteSeriesFromShards(database string, shardIDs []uint64, sources []influxql.Source, condition influxql.Expr) error {
+	ids := make(map[uint64]struct{}, len(shardIDs))
+	for _, id := range shardIDs {
+		ids[id] = struct{}{}
+	}
+
+	s.mu.RLock()
+	shards := s.filterShards(func(sh *Shard) bool {
+		_, ok := ids[sh.id]
+		return ok && sh.database == database
+	})
+	s.mu.RUnlock()
+	return s.deleteSeries(database, shards, sources, condition)
+}
+
+// deleteSeries deletes the series data matched by sources and condition
+// from shards, the shards of database to delete from.
+func (s *Store) deleteSeries(database string, shards []*Shard, sources []influxql.Source, condition influxql.Expr) error {
 	// Expand regex expressions in the FROM clause.
-	a, err := s.ExpandSources(sources)
+	a, err := Shards(shards).ExpandSources(sources)
 	if err != nil {
 		return err
 	} else if len(sources) > 0 && len(a) == 0 {
@@ -1035,7 +1211,6 @@ func (s *Store) DeleteSeries(database string, sources []influxql.Source, conditi
 		// No series file means nothing has been written to this DB and thus nothing to delete.
 		return nil
 	}
-	shards := s.filterShards(byDatabase(database))
 	s.mu.RUnlock()
 
 	// Limit to 1 delete for each shard since expanding the measurement into the list
@@ -1116,13 +1291,74 @@ func (s *Store) WriteToShard(shardID uint64, points []models.Point) error {
 	}
 	s.mu.RUnlock()
 
+	if atomic.LoadInt32(&s.diskFull) == 1 {
+		return ErrDiskFull
+	}
+
+	if limit := uint64(s.EngineOptions.Config.databaseLimits(sh.database).MaxCacheMemorySize); limit > 0 {
+		if n := s.databaseCacheSize(sh.database); n > limit {
+			return ErrDatabaseCacheLimitExceeded(sh.database, n, limit)
+		}
+	}
+
 	// Ensure snapshot compactions are enabled since the shard might have been cold
 	// and disabled by the monitor.
 	if sh.IsIdle() {
 		sh.SetCompactionsEnabled(true)
 	}
 
-	return sh.WritePoints(points)
+	if err := sh.WritePoints(points); err != nil {
+		return err
+	}
+	s.addMeasurementWrites(sh.database, points)
+	return nil
+}
+
+// ValidateToShard returns the error writing points to a shard would return,
+// without writing them. It returns ErrShardNotFound if the shard is not open.
+func (s *Store) ValidateToShard(shardID uint64, points []models.Point) error {
+	s.mu.RLock()
+	sh := s.shards[shardID]
+	s.mu.RUnlock()
+	if sh == nil {
+		return ErrShardNotFound
+	}
+
+	if atomic.LoadInt32(&s.diskFull) == 1 {
+		return ErrDiskFull
+	}
+
+	if limit := uint64(s.EngineOptions.Config.databaseLimits(sh.database).MaxCacheMemorySize); limit > 0 {
+		if n := s.databaseCacheSize(sh.database); n > limit {
+			return ErrDatabaseCacheLimitExceeded(sh.database, n, limit)
+		}
+	}
+
+	return sh.ValidatePoints(points)
+}
+
+// shardEngineOptions returns the engine options of a new shard of database,
+// with the limits of the database applied.
+func (s *Store) shardEngineOptions(database string) EngineOptions {
+	opt := s.EngineOptions
+	if n := opt.Config.databaseLimits(database).MaxSeries; n > 0 {
+		opt.Config.MaxSeriesPerDatabase = n
+	}
+	return opt
+}
+
+// databaseCacheSize returns the total size of the caches of the shards of
+// database.
+func (s *Store) databaseCacheSize(database string) uint64 {
+	s.mu.RLock()
+	shards := s.filterShards(byDatabase(database))
+	s.mu.RUnlock()
+
+	var n uint64
+	for _, sh := range shards {
+		n += sh.CacheSize()
+	}
+	return n
 }
 
 // MeasurementNames returns a slice of all measurements. Measurements accepts an
@@ -1594,6 +1830,67 @@ func mergeTagValues(valueIdxs [][2]int, tvs ...tagValues) TagValues {
 	return result
 }
 
+// checkDiskSpace compares the free space of the data and WAL directories
+// with the configured limits and logs whenever a limit is crossed.
+func (s *Store) checkDiskSpace() {
+	soft, hard := uint64(s.EngineOptions.Config.DiskFreeSoftLimit), uint64(s.EngineOptions.Config.DiskFreeHardLimit)
+	if soft == 0 && hard == 0 {
+		return
+	}
+
+	var low, full bool
+	for _, path := range []string{s.path, s.EngineOptions.Config.WALDir} {
+		if path == "" {
+			continue
+		}
+
+		free, err := file.DiskFree(path)
+		if err != nil {
+			s.Logger.Info(fmt.Sprintf("Unable to check free disk space of %s: %s", path, err))
+			continue
+		}
+
+		if soft > 0 && free < soft {
+			low = true
+		}
+		if hard > 0 && free < hard {
+			full = true
+			if atomic.LoadInt32(&s.diskFull) == 0 {
+				s.Logger.Warn(fmt.Sprintf("Free disk space of %s is %d bytes, below the hard limit of %d bytes", path, free, hard))
+			}
+		}
+	}
+
+	if setFlag(&s.diskFull, full) {
+		if full {
+			s.Logger.Warn("Rejecting writes until free disk space is above the hard limit")
+		} else {
+			s.Logger.Info("Free disk space is above the hard limit, accepting writes")
+		}
+	}
+	if setFlag(&s.diskLow, low || full) {
+		if low || full {
+			s.Logger.Warn("Free disk space is below the soft limit, pausing level and full compactions")
+		} else {
+			s.Logger.Info("Free disk space is above the soft limit, resuming compactions")
+		}
+	}
+}
+
+// diskSpaceLow returns true while free disk space is below the soft limit.
+func (s *Store) diskSpaceLow() bool {
+	return atomic.LoadInt32(&s.diskLow) == 1
+}
+
+// setFlag stores v in flag and returns true if the value changed.
+func setFlag(flag *int32, v bool) bool {
+	var n int32
+	if v {
+		n = 1
+	}
+	return atomic.SwapInt32(flag, n) != n
+}
+
 func (s *Store) monitorShards() {
 	defer s.wg.Done()
 	t := time.NewTicker(10 * time.Second)
@@ -1604,10 +1901,16 @@ func (s *Store) monitorShards() {
 		select {
 		case <-s.closing:
 			return
-		case <-t.C:
+		case now := <-t.C:
+			s.checkDiskSpace()
+			s.sampleGrowth(now)
+			s.checkDatabaseSizes()
+
 			s.mu.RLock()
 			for _, sh := range s.shards {
-				if sh.IsIdle() {
+				if sh.IsUnloaded() {
+					continue
+				} else if sh.IsIdle() {
 					if err := sh.Free(); err != nil {
 						s.Logger.Warn("error free cold shard resources:", zap.Error(err))
 					}
@@ -1616,14 +1919,18 @@ func (s *Store) monitorShards() {
 				}
 			}
 			s.mu.RUnlock()
+
+			s.unloadColdShards()
 		case <-t2.C:
 			if s.EngineOptions.Config.MaxValuesPerTag == 0 {
 				continue
 			}
 
+			// Unloaded shards are left out, since reading their index
+			// would reopen them every minute.
 			s.mu.RLock()
 			shards := s.filterShards(func(sh *Shard) bool {
-				return sh.IndexType() == "inmem"
+				return !sh.IsUnloaded() && sh.IndexType() == "inmem"
 			})
 			s.mu.RUnlock()
 
@@ -1652,12 +1959,12 @@ func (s *Store) monitorShards() {
 					return nil
 				}
 
-				firstShardIndex, err := sh.Index()
+				firstShardIndex, err := sh.loadedIndex()
 				if err != nil {
 					return err
 				}
 
-				index, err := sh.Index()
+				index, err := sh.loadedIndex()
 				if err != nil {
 					return err
 				}
@@ -1694,6 +2001,32 @@ func (s *Store) monitorShards() {
 	}
 }
 
+// unloadColdShards closes the shards not written to or queried within
+// ShardUnloadColdDuration, releasing their files and indexes. They are
+// reopened on their next access.
+func (s *Store) unloadColdShards() {
+	idle := time.Duration(s.EngineOptions.Config.ShardUnloadColdDuration)
+	if idle <= 0 {
+		return
+	}
+
+	s.mu.RLock()
+	shards := s.shardsSlice()
+	s.mu.RUnlock()
+
+	var n int
+	for _, sh := range shards {
+		if ok, err := sh.Unload(idle); err != nil {
+			s.Logger.Warn(fmt.Sprintf("error unloading cold shard %d", sh.id), zap.Error(err))
+		} else if ok {
+			n++
+		}
+	}
+	if n > 0 {
+		s.Logger.Info(fmt.Sprintf("Unloaded %d cold shards", n))
+	}
+}
+
 // KeyValue holds a string key and a string value.
 type KeyValue struct {
 	Key, Value string
@@ -1763,16 +2096,11 @@ func (s shardSet) ForEach(f func(ids *SeriesIDSet)) error {
 	s.store.mu.RUnlock()
 
 	for _, sh := range shards {
-		idx, err := sh.Index()
+		ss, err := sh.SeriesIDSet()
 		if err != nil {
 			return err
 		}
-
-		if t, ok := idx.(interface {
-			SeriesIDSet() *SeriesIDSet
-		}); ok {
-			f(t.SeriesIDSet())
-		}
+		f(ss)
 	}
 	return nil
 }
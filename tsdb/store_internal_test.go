@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 )
 
 func TestStore_mergeTagValues(t *testing.T) {
@@ -165,3 +166,20 @@ func createTagValues(mname string, kvs map[string][]string) TagValues {
 
 	return out
 }
+
+func TestOpenProgress_String(t *testing.T) {
+	p := newOpenProgress()
+	p.shardsTotal = 4
+	p.AddWALSegments(3)
+	p.WALSegmentReplayed()
+	if got, exp := p.String(), "opened 0 of 4 shards (0%), replayed 1 of 3 WAL segments"; got != exp {
+		t.Fatalf("unexpected progress: got %q, exp %q", got, exp)
+	}
+
+	// The time left is estimated once a shard is opened.
+	p.shardsOpened = 1
+	p.start = time.Now().Add(-10 * time.Second)
+	if got, exp := p.String(), "opened 1 of 4 shards (25%), replayed 1 of 3 WAL segments, 30s left"; got != exp {
+		t.Fatalf("unexpected progress: got %q, exp %q", got, exp)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/pkg/deep"
 	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/toml"
 	"github.com/influxdata/influxdb/tsdb"
 	"github.com/influxdata/influxql"
 )
@@ -140,6 +141,188 @@ func TestStore_CreateShard(t *testing.T) {
 	}
 }
 
+// Ensure the store rejects writes while free disk space is below the hard limit.
+func TestStore_WriteToShard_DiskFull(t *testing.T) {
+	t.Parallel()
+
+	test := func(index string) {
+		s := NewStore()
+		s.EngineOptions.IndexVersion = index
+		s.EngineOptions.Config.DiskFreeSoftLimit = toml.Size(math.MaxInt64)
+		s.EngineOptions.Config.DiskFreeHardLimit = toml.Size(math.MaxInt64)
+		if err := s.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		if err := s.CreateShard("db0", "rp0", 1, true); err != nil {
+			t.Fatal(err)
+		}
+
+		pt := models.MustNewPoint("cpu", nil, map[string]interface{}{"value": 1.0}, time.Now())
+		if err := s.WriteToShard(1, []models.Point{pt}); err != tsdb.ErrDiskFull {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	for _, index := range tsdb.RegisteredIndexes() {
+		t.Run(index, func(t *testing.T) { test(index) })
+	}
+}
+
+// Ensure the store rejects writes to a database whose caches exceed its limit.
+func TestStore_WriteToShard_DatabaseCacheLimit(t *testing.T) {
+	t.Parallel()
+
+	test := func(index string) {
+		s := NewStore()
+		s.EngineOptions.IndexVersion = index
+		s.EngineOptions.Config.DatabaseLimits = []tsdb.DatabaseLimits{{Database: "db0", MaxCacheMemorySize: 1}}
+		if err := s.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		if err := s.CreateShard("db0", "rp0", 1, true); err != nil {
+			t.Fatal(err)
+		} else if err := s.CreateShard("db1", "rp0", 2, true); err != nil {
+			t.Fatal(err)
+		}
+
+		pt := models.MustNewPoint("cpu", nil, map[string]interface{}{"value": 1.0}, time.Now())
+		if err := s.WriteToShard(1, []models.Point{pt}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.WriteToShard(1, []models.Point{pt}); err == nil || !strings.Contains(err.Error(), "max-cache-memory-size exceeded for database db0") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// Other databases are not limited.
+		for i := 0; i < 2; i++ {
+			if err := s.WriteToShard(2, []models.Point{pt}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for _, index := range tsdb.RegisteredIndexes() {
+		t.Run(index, func(t *testing.T) { test(index) })
+	}
+}
+
+// Ensure the statistics of the store count the writes of each database and
+// of its top measurements.
+func TestStore_Statistics_Writes(t *testing.T) {
+	t.Parallel()
+
+	test := func(index string) {
+		s := NewStore()
+		s.EngineOptions.IndexVersion = index
+		s.TopMeasurementsN = 1
+		if err := s.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		s.MustCreateShardWithData("db0", "rp0", 1,
+			`cpu,host=a value=1 10`,
+			`cpu,host=b value=2 10`,
+			`mem,host=a value=3 10`,
+		)
+		s.MustCreateShardWithData("db1", "rp0", 2, `cpu,host=a value=4 10`)
+
+		stats := make(map[string]map[string]interface{})
+		for _, st := range s.Statistics(nil) {
+			switch st.Name {
+			case "database":
+				stats[st.Tags["database"]] = st.Values
+			case "measurement":
+				stats[st.Tags["database"]+"."+st.Tags["measurement"]] = st.Values
+			}
+		}
+
+		if got := stats["db0"]["writePointsOk"]; got != int64(3) {
+			t.Fatalf("unexpected points written to db0: %v", got)
+		} else if got := stats["db0"]["seriesCreated"]; got != int64(3) {
+			t.Fatalf("unexpected series created in db0: %v", got)
+		} else if got := stats["db1"]["writePointsOk"]; got != int64(1) {
+			t.Fatalf("unexpected points written to db1: %v", got)
+		}
+		if got, exp := stats["db0"]["writePointsBytes"], int64(len("cpu,host=a value=1 10000000000")*2+len("mem,host=a value=3 10000000000")); got != exp {
+			t.Fatalf("unexpected bytes written to db0: got %v, exp %d", got, exp)
+		}
+
+		// Only the measurement with the most points written is reported.
+		if got := stats["db0.cpu"]["writePointsOk"]; got != int64(2) {
+			t.Fatalf("unexpected points written to db0.cpu: %v", got)
+		} else if _, ok := stats["db0.mem"]; ok {
+			t.Fatal("unexpected statistics for db0.mem")
+		} else if got := stats["db1.cpu"]["writePointsOk"]; got != int64(1) {
+			t.Fatalf("unexpected points written to db1.cpu: %v", got)
+		}
+	}
+
+	for _, index := range tsdb.RegisteredIndexes() {
+		t.Run(index, func(t *testing.T) { test(index) })
+	}
+}
+
+// Ensure the statistics of the store report the size on disk of each
+// database and retention policy.
+func TestStore_Statistics_DiskUsage(t *testing.T) {
+	t.Parallel()
+
+	test := func(index string) {
+		s := MustOpenStore(index)
+		defer s.Close()
+
+		s.MustCreateShardWithData("db0", "rp0", 1, `cpu,host=a value=1 10`)
+		s.MustCreateShardWithData("db0", "rp0", 2, `cpu,host=b value=2 10`)
+		s.MustCreateShardWithData("db0", "rp1", 3, `mem,host=a value=3 10`)
+
+		sizes := make(map[uint64]int64)
+		for _, id := range []uint64{1, 2, 3} {
+			n, err := s.ShardDiskSize(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sizes[id] = n
+		}
+		if _, err := s.ShardDiskSize(4); err == nil {
+			t.Fatal("expected error for unknown shard")
+		}
+
+		var db map[string]interface{}
+		rps := make(map[string]map[string]interface{})
+		for _, st := range s.Statistics(nil) {
+			switch st.Name {
+			case "database":
+				db = st.Values
+			case "rp":
+				rps[st.Tags["retentionPolicy"]] = st.Values
+			}
+		}
+
+		if got, exp := db["diskBytes"], sizes[1]+sizes[2]+sizes[3]; got != exp {
+			t.Fatalf("unexpected size of db0: got %v, exp %d", got, exp)
+		}
+		if got, exp := rps["rp0"]["diskBytes"], sizes[1]+sizes[2]; got != exp {
+			t.Fatalf("unexpected size of rp0: got %v, exp %d", got, exp)
+		} else if got := rps["rp0"]["numShards"]; got != int64(2) {
+			t.Fatalf("unexpected shards of rp0: %v", got)
+		}
+		if got, exp := rps["rp1"]["diskBytes"], sizes[3]; got != exp {
+			t.Fatalf("unexpected size of rp1: got %v, exp %d", got, exp)
+		} else if got := rps["rp1"]["numShards"]; got != int64(1) {
+			t.Fatalf("unexpected shards of rp1: %v", got)
+		}
+	}
+
+	for _, index := range tsdb.RegisteredIndexes() {
+		t.Run(index, func(t *testing.T) { test(index) })
+	}
+}
+
 // Ensure the store does not return an error when delete from a non-existent db.
 func TestStore_DeleteSeries_NonExistentDB(t *testing.T) {
 	t.Parallel()
@@ -0,0 +1,226 @@
+package tsdb
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// measurementWritesFactor is the number of measurements of each database
+// counted for every measurement reported.
+const measurementWritesFactor = 10
+
+// measurementWrites counts the points written to a measurement.
+type measurementWrites struct {
+	name   string
+	points int64
+	bytes  int64
+	index  int // in the heap of its topMeasurementWrites
+}
+
+// topMeasurementWrites counts the points written to the measurements of a
+// database with the space-saving algorithm. It counts at most n measurements:
+// a measurement written to while all n are counted replaces the one with the
+// fewest points written, and starts from its counts. The counts of a
+// measurement are therefore overestimated by at most the points written to
+// the measurements it replaced, and a measurement with more than a 1/n share
+// of the points written is always counted.
+type topMeasurementWrites struct {
+	mu sync.Mutex
+	n  int
+	m  map[string]*measurementWrites
+	h  measurementWritesHeap // ordered by points written, fewest first
+}
+
+func newTopMeasurementWrites(n int) *topMeasurementWrites {
+	return &topMeasurementWrites{
+		n: n,
+		m: make(map[string]*measurementWrites, n),
+		h: make(measurementWritesHeap, 0, n),
+	}
+}
+
+// add counts points written to their measurements.
+func (t *topMeasurementWrites) add(points []models.Point) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, p := range points {
+		w := t.m[string(p.Name())]
+		if w == nil {
+			if len(t.h) < t.n {
+				w = &measurementWrites{}
+				heap.Push(&t.h, w)
+			} else {
+				// Replace the measurement with the fewest points written.
+				w = t.h[0]
+				delete(t.m, w.name)
+			}
+			w.name = string(p.Name())
+			t.m[w.name] = w
+		}
+		w.points++
+		w.bytes += int64(p.StringSize())
+		heap.Fix(&t.h, w.index)
+	}
+}
+
+// delete forgets the points written to the measurement name.
+func (t *topMeasurementWrites) delete(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if w := t.m[name]; w != nil {
+		heap.Remove(&t.h, w.index)
+		delete(t.m, name)
+	}
+}
+
+// top returns the counts of the n measurements with the most points written.
+func (t *topMeasurementWrites) top(n int) []measurementWrites {
+	t.mu.Lock()
+	a := make([]measurementWrites, 0, len(t.h))
+	for _, w := range t.h {
+		a = append(a, *w)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(a, func(i, j int) bool {
+		if a[i].points != a[j].points {
+			return a[i].points > a[j].points
+		}
+		return a[i].name < a[j].name
+	})
+	if len(a) > n {
+		a = a[:n]
+	}
+	return a
+}
+
+// measurementWritesHeap is a min-heap of measurements by points written.
+type measurementWritesHeap []*measurementWrites
+
+func (h measurementWritesHeap) Len() int           { return len(h) }
+func (h measurementWritesHeap) Less(i, j int) bool { return h[i].points < h[j].points }
+
+func (h measurementWritesHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *measurementWritesHeap) Push(x interface{}) {
+	w := x.(*measurementWrites)
+	w.index = len(*h)
+	*h = append(*h, w)
+}
+
+func (h *measurementWritesHeap) Pop() interface{} {
+	old := *h
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return w
+}
+
+// databaseWrites sums the points and bytes written to the shards of each
+// database. Like the shard counters they come from, the sums count from when
+// the shards were opened, and drop when a shard is deleted.
+func (s *Store) databaseWrites(shards []*Shard) (points, bytes map[string]int64) {
+	points, bytes = make(map[string]int64), make(map[string]int64)
+	for _, sh := range shards {
+		points[sh.database] += atomic.LoadInt64(&sh.stats.WritePointsOK)
+		bytes[sh.database] += atomic.LoadInt64(&sh.stats.PointBytesWritten)
+	}
+	return points, bytes
+}
+
+// databaseSeriesCreated returns the number of series created in database
+// since its series file was opened.
+func (s *Store) databaseSeriesCreated(database string) int64 {
+	s.mu.RLock()
+	sfile := s.sfiles[database]
+	s.mu.RUnlock()
+
+	if sfile == nil {
+		return 0
+	}
+	return int64(sfile.SeriesCreated())
+}
+
+// addMeasurementWrites counts points written to database by measurement,
+// if TopMeasurementsN is set. Only measurementWritesFactor times
+// TopMeasurementsN measurements of each database are counted.
+func (s *Store) addMeasurementWrites(database string, points []models.Point) {
+	if s.TopMeasurementsN <= 0 {
+		return
+	}
+
+	s.measurementWritesMu.RLock()
+	t := s.measurementWrites[database]
+	s.measurementWritesMu.RUnlock()
+
+	if t == nil {
+		s.measurementWritesMu.Lock()
+		if s.measurementWrites == nil {
+			s.measurementWrites = make(map[string]*topMeasurementWrites)
+		}
+		if t = s.measurementWrites[database]; t == nil {
+			t = newTopMeasurementWrites(s.TopMeasurementsN * measurementWritesFactor)
+			s.measurementWrites[database] = t
+		}
+		s.measurementWritesMu.Unlock()
+	}
+	t.add(points)
+}
+
+// deleteMeasurementWrites forgets the points written to the measurement name
+// of database, or to all of its measurements if name is empty.
+func (s *Store) deleteMeasurementWrites(database, name string) {
+	if name == "" {
+		s.measurementWritesMu.Lock()
+		delete(s.measurementWrites, database)
+		s.measurementWritesMu.Unlock()
+		return
+	}
+
+	s.measurementWritesMu.RLock()
+	t := s.measurementWrites[database]
+	s.measurementWritesMu.RUnlock()
+
+	if t != nil {
+		t.delete(name)
+	}
+}
+
+// measurementWritesStatistics returns the statistics of the TopMeasurementsN
+// measurements of each database with the most points written.
+func (s *Store) measurementWritesStatistics(tags map[string]string) []models.Statistic {
+	if s.TopMeasurementsN <= 0 {
+		return nil
+	}
+
+	s.measurementWritesMu.RLock()
+	databases := make(map[string]*topMeasurementWrites, len(s.measurementWrites))
+	for database, t := range s.measurementWrites {
+		databases[database] = t
+	}
+	s.measurementWritesMu.RUnlock()
+
+	var statistics []models.Statistic
+	for database, t := range databases {
+		for _, w := range t.top(s.TopMeasurementsN) {
+			statistics = append(statistics, models.Statistic{
+				Name: "measurement",
+				Tags: models.StatisticTags{"database": database, "measurement": w.name}.Merge(tags),
+				Values: map[string]interface{}{
+					statWritePointsOK:    w.points,
+					statWritePointsBytes: w.bytes,
+				},
+			})
+		}
+	}
+	return statistics
+}
@@ -0,0 +1,47 @@
+package tsdb
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// Ensure at most n measurements are counted, and that a measurement with
+// many of the points written stays counted as others replace each other.
+func TestTopMeasurementWrites(t *testing.T) {
+	w := newTopMeasurementWrites(3)
+	cpu := models.MustNewPoint("cpu", nil, models.Fields{"value": 1.0}, time.Unix(0, 0))
+	for i := 0; i < 100; i++ {
+		pt := models.MustNewPoint(fmt.Sprintf("m%d", i), nil, models.Fields{"value": 1.0}, time.Unix(0, 0))
+		w.add([]models.Point{cpu, cpu, pt})
+	}
+
+	if got := len(w.m); got != 3 {
+		t.Fatalf("unexpected measurements counted: %d", got)
+	}
+	top := w.top(1)
+	if len(top) != 1 || top[0].name != "cpu" || top[0].points != 200 {
+		t.Fatalf("unexpected top measurements: %+v", top)
+	} else if exp := int64(200 * cpu.StringSize()); top[0].bytes != exp {
+		t.Fatalf("unexpected bytes written to cpu: got %d, exp %d", top[0].bytes, exp)
+	}
+
+	// A replaced measurement starts from the counts of the one it replaced,
+	// so the counts sum to the points written.
+	var sum int64
+	for _, m := range w.top(3) {
+		sum += m.points
+	}
+	if sum != 300 {
+		t.Fatalf("unexpected sum of points written: %d", sum)
+	}
+
+	w.delete("cpu")
+	if _, ok := w.m["cpu"]; ok {
+		t.Fatal("expected cpu to be deleted")
+	} else if top := w.top(3); len(top) != 2 {
+		t.Fatalf("unexpected top measurements: %+v", top)
+	}
+}